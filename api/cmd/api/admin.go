@@ -10,73 +10,21 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"censo-api/internal/models"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// ─── Rate Limiter ────────────────────────────────────────────────────────────
-
-type rateLimiter struct {
-	mu       sync.Mutex
-	attempts map[string][]time.Time
-}
-
-var loginRL = &rateLimiter{attempts: make(map[string][]time.Time)}
-
-// Limitadores para os endpoints públicos de escrita. Os limites são
-// propositalmente generosos para não atrapalhar o preenchimento legítimo
-// do formulário (multi-step + autosave, possivelmente várias escolas atrás
-// do mesmo IP/NAT de uma DRE), mas cortam abuso/enumeração em massa.
-var (
-	censusWriteRL = &rateLimiter{attempts: make(map[string][]time.Time)}
-	uploadRL      = &rateLimiter{attempts: make(map[string][]time.Time)}
-)
-
 const (
-	maxLoginAttempts = 5
-	rlWindow         = 15 * time.Minute
-	jwtExpiry        = 2 * time.Hour
-
-	// Escrita de censo/escola: alto o suficiente para o formulário completo
-	// (11 passos + salvamentos automáticos) repetido por várias escolas.
-	maxCensusWrites = 300
-	censusWindow    = 10 * time.Minute
-
-	// Upload de foto: uma por escola na prática; margem para reenvios.
-	maxUploads   = 40
-	uploadWindow = 10 * time.Minute
+	// Access token curto: a sessão é mantida pelo refresh token rotativo
+	// (ver admin_sessions.go), e um token vazado vale por pouco tempo.
+	jwtExpiry = 15 * time.Minute
 )
 
-// allow implementa um rate limit de janela deslizante para o IP informado,
-// com limite e janela parametrizáveis.
-func (rl *rateLimiter) allow(ip string, max int, window time.Duration) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	cutoff := time.Now().Add(-window)
-	var recent []time.Time
-	for _, t := range rl.attempts[ip] {
-		if t.After(cutoff) {
-			recent = append(recent, t)
-		}
-	}
-	rl.attempts[ip] = recent
-
-	if len(recent) >= max {
-		return false
-	}
-	rl.attempts[ip] = append(rl.attempts[ip], time.Now())
-	return true
-}
-
-func (rl *rateLimiter) check(ip string) bool {
-	return rl.allow(ip, maxLoginAttempts, rlWindow)
-}
-
 // trustedProxyCount é o número de proxies reversos confiáveis à frente da
 // aplicação. Plataformas como Railway colocam 1 proxy. Default 1.
 func trustedProxyCount() int {
@@ -146,8 +94,16 @@ func (app *application) requirePublicAPIKey(next http.Handler) http.Handler {
 
 // ─── JWT ─────────────────────────────────────────────────────────────────────
 
+// adminClaims carrega a identidade da conta individual (admin_users): id,
+// username, papel e escopo de DRE (vazio = estadual). Subject repete o id em
+// texto, como pede o RFC 7519. SessionID aponta a sessão (admin_sessions) que
+// emitiu o token e ID (jti) identifica o token na lista de revogação.
 type adminClaims struct {
-	Username string `json:"username"`
+	UserID    int    `json:"uid"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	DRE       string `json:"dre,omitempty"`
+	SessionID int64  `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -179,8 +135,7 @@ func (app *application) AdminLogin(w http.ResponseWriter, r *http.Request) {
 	// Limit body to 1KB to prevent DoS
 	r.Body = http.MaxBytesReader(w, r.Body, 1024)
 
-	ip := clientIP(r)
-	if !loginRL.check(ip) {
+	if !app.allowRate(r, loginLimit, ipKey(r)) {
 		w.Header().Set("Retry-After", "900")
 		app.errorJSON(w, fmt.Errorf("muitas tentativas. Aguarde 15 minutos"), http.StatusTooManyRequests)
 		return
@@ -201,49 +156,63 @@ func (app *application) AdminLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	adminUser := os.Getenv("ADMIN_USERNAME")
-	adminHash := os.Getenv("ADMIN_PASSWORD_HASH") // bcrypt hash
-
-	if adminUser == "" || adminHash == "" {
-		app.logger.Println("AVISO SEGURANÇA: ADMIN_USERNAME ou ADMIN_PASSWORD_HASH não definidos")
-		app.errorJSON(w, fmt.Errorf("autenticação não configurada no servidor"), http.StatusInternalServerError)
+	user, err := app.models.AdminUsers.GetByUsername(req.Username)
+	if err != nil {
+		app.logger.Printf("AdminLogin: buscar usuário: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro interno ao autenticar"), http.StatusInternalServerError)
 		return
 	}
 
-	// Always run bcrypt (even on wrong username) to prevent timing attacks
-	hashToCheck := adminHash
-	usernameOK := req.Username == adminUser
-	pwErr := bcrypt.CompareHashAndPassword([]byte(hashToCheck), []byte(req.Password))
+	// Always run bcrypt (even on unknown username) to prevent timing attacks
+	hashToCheck := dummyPasswordHash()
+	if user != nil {
+		hashToCheck = []byte(user.PasswordHash)
+	}
+	pwErr := bcrypt.CompareHashAndPassword(hashToCheck, []byte(req.Password))
 
-	if !usernameOK || pwErr != nil {
+	if user == nil || !user.Active || pwErr != nil {
 		// Artificial delay discourages automated brute force
 		time.Sleep(600 * time.Millisecond)
 		app.errorJSON(w, fmt.Errorf("credenciais inválidas"), http.StatusUnauthorized)
 		return
 	}
 
-	claims := adminClaims{
-		Username: req.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(jwtExpiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "censo-admin",
-			Subject:   "admin",
-		},
+	// Com segundo fator ativo a senha só rende um pre-auth token; a sessão
+	// sai em POST /v1/admin/login/verify (ver admin_totp.go).
+	if user.TOTPEnabled {
+		mfaToken, err := signMFAToken(user.ID, time.Now())
+		if err != nil {
+			app.logger.Printf("AdminLogin: pre-auth token de %d: %v", user.ID, err)
+			app.errorJSON(w, fmt.Errorf("erro interno ao gerar token"), http.StatusInternalServerError)
+			return
+		}
+		app.writeJSON(w, http.StatusOK, jsonResponse{
+			Error:   false,
+			Message: "Informe o código do aplicativo autenticador",
+			Data: map[string]interface{}{
+				"mfa_required": true,
+				"mfa_token":    mfaToken,
+				"expires_in":   int(mfaTokenTTL.Seconds()),
+			},
+		})
+		return
 	}
-	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret())
+
+	data, err := app.startAdminSession(user, r)
 	if err != nil {
+		app.logger.Printf("AdminLogin: abrir sessão de %d: %v", user.ID, err)
 		app.errorJSON(w, fmt.Errorf("erro interno ao gerar token"), http.StatusInternalServerError)
 		return
 	}
 
+	if err := app.models.AdminUsers.TouchLastLogin(user.ID); err != nil {
+		app.logger.Printf("AdminLogin: registrar último login de %d: %v", user.ID, err)
+	}
+
 	app.writeJSON(w, http.StatusOK, jsonResponse{
 		Error:   false,
 		Message: "Login realizado com sucesso",
-		Data: map[string]interface{}{
-			"token":      tok,
-			"expires_in": int(jwtExpiry.Seconds()),
-		},
+		Data:    data,
 	})
 }
 
@@ -257,28 +226,109 @@ func (app *application) requireAdminAuth(next http.Handler) http.Handler {
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		claims := &adminClaims{}
+		claims, err := parseAdminToken(tokenStr)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("token inválido ou expirado"), http.StatusUnauthorized)
+			return
+		}
 
-		tok, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("algoritmo de assinatura inválido")
-			}
-			return jwtSecret(), nil
-		}, jwt.WithIssuer("censo-admin"), jwt.WithExpirationRequired())
+		// Token revogado (logout) ou de sessão encerrada perde o acesso
+		// imediatamente, sem esperar a expiração.
+		revoked, err := app.models.AdminSessions.AccessRevoked(claims.ID, claims.SessionID)
+		if err != nil {
+			app.logger.Printf("requireAdminAuth: checar revogação de %s: %v", claims.ID, err)
+			app.errorJSON(w, fmt.Errorf("erro interno ao autenticar"), http.StatusInternalServerError)
+			return
+		}
+		if revoked {
+			app.errorJSON(w, fmt.Errorf("token inválido ou expirado"), http.StatusUnauthorized)
+			return
+		}
 
-		if err != nil || !tok.Valid {
+		// Conta desativada (ou removida) perde o acesso imediatamente, sem
+		// esperar o token expirar.
+		user, err := app.models.AdminUsers.Get(claims.UserID)
+		if err != nil {
+			app.logger.Printf("requireAdminAuth: buscar usuário %d: %v", claims.UserID, err)
+			app.errorJSON(w, fmt.Errorf("erro interno ao autenticar"), http.StatusInternalServerError)
+			return
+		}
+		if user == nil || !user.Active {
 			app.errorJSON(w, fmt.Errorf("token inválido ou expirado"), http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), contextKeyAdminUser, claims.Username)
+		// Papel e escopo de DRE vêm do banco, não do token: uma troca de papel
+		// ou de DRE vale já na próxima requisição.
+		id := adminIdentity{
+			UserID:         user.ID,
+			Username:       user.Username,
+			Role:           user.Role,
+			DRE:            user.Dre,
+			SessionID:      claims.SessionID,
+			TokenID:        claims.ID,
+			TokenExpiresAt: claims.ExpiresAt.Time,
+		}
+		ctx := context.WithValue(r.Context(), contextKeyAdminUser, id.Username)
+		ctx = context.WithValue(ctx, contextKeyAdminIdentity, id)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// signAdminToken emite o access token da sessão sessionID para a conta
+// informada, com um jti aleatório.
+func signAdminToken(u *models.AdminUser, sessionID int64, now time.Time) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	claims := adminClaims{
+		UserID:    u.ID,
+		Username:  u.Username,
+		Role:      u.Role,
+		DRE:       u.Dre,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(jwtExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "censo-admin",
+			Subject:   strconv.Itoa(u.ID),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret())
+}
+
+// parseAdminToken valida assinatura (somente HMAC), emissor e expiração do
+// JWT e exige uma conta identificada (uid > 0), uma sessão (sid > 0) e um
+// jti — tokens da conta única anterior ou emitidos antes das sessões deixam
+// de ser aceitos.
+func parseAdminToken(tokenStr string) (*adminClaims, error) {
+	claims := &adminClaims{}
+	tok, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("algoritmo de assinatura inválido")
+		}
+		return jwtSecret(), nil
+	}, jwt.WithIssuer("censo-admin"), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if !tok.Valid || claims.UserID <= 0 {
+		return nil, fmt.Errorf("token sem identificação de usuário")
+	}
+	if claims.SessionID <= 0 || claims.ID == "" {
+		return nil, fmt.Errorf("token sem sessão")
+	}
+	return claims, nil
+}
+
 type contextKey string
 
-const contextKeyAdminUser contextKey = "admin_username"
+const (
+	contextKeyAdminUser     contextKey = "admin_username"
+	contextKeyAdminIdentity contextKey = "admin_identity"
+)
 
 // ─── Dashboard data types ─────────────────────────────────────────────────────
 
@@ -313,9 +363,12 @@ type CensusRow struct {
 
 // ─── AdminDashboard ───────────────────────────────────────────────────────────
 
+// AdminDashboard aceita ?dre= opcional (forçado para contas regionais por
+// enforceDREScope); sem dre, o painel cobre o estado inteiro.
 func (app *application) AdminDashboard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	db := app.models.Schools.DB // same *sql.DB for both models
+	dre := strings.TrimSpace(r.URL.Query().Get("dre"))
 
 	s := DashboardStats{
 		ByDre:  []DreStats{},
@@ -325,11 +378,13 @@ func (app *application) AdminDashboard(w http.ResponseWriter, r *http.Request) {
 	// Counts — single query avoids multiple round-trips
 	err := db.QueryRowContext(ctx, `
 		SELECT
-			(SELECT COUNT(*) FROM schools),
-			COUNT(*) FILTER (WHERE cr.status = 'completed'),
+			(SELECT COUNT(*) FROM schools WHERE active AND ($1 = '' OR UPPER(TRIM(dre)) = UPPER(TRIM($1)))),
+			COUNT(*) FILTER (WHERE censo_enviado(cr.status)),
 			COUNT(*) FILTER (WHERE cr.status = 'draft'),
-			COUNT(*) FILTER (WHERE cr.status = 'completed' AND cr.sheet_synced_at IS NULL)
-		FROM census_responses cr`).Scan(
+			COUNT(*) FILTER (WHERE censo_enviado(cr.status) AND cr.sheet_synced_at IS NULL)
+		FROM census_responses cr
+		JOIN schools s ON s.id = cr.school_id
+		WHERE ($1 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($1)))`, dre).Scan(
 		&s.TotalSchools, &s.CompletedCensuses, &s.DraftCensuses, &s.PendingSync)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("erro ao buscar totais"), http.StatusInternalServerError)
@@ -341,12 +396,13 @@ func (app *application) AdminDashboard(w http.ResponseWriter, r *http.Request) {
 		SELECT
 			s.dre,
 			COUNT(DISTINCT s.id)                                              AS total,
-			COUNT(DISTINCT s.id) FILTER (WHERE cr.status = 'completed')      AS completed,
+			COUNT(DISTINCT s.id) FILTER (WHERE censo_enviado(cr.status))      AS completed,
 			COUNT(DISTINCT s.id) FILTER (WHERE cr.status = 'draft')          AS draft
 		FROM schools s
 		LEFT JOIN census_responses cr ON cr.school_id = s.id
+		WHERE ($1 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($1)))
 		GROUP BY s.dre
-		ORDER BY s.dre`)
+		ORDER BY s.dre`, dre)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("erro ao buscar por DRE"), http.StatusInternalServerError)
 		return
@@ -369,8 +425,9 @@ func (app *application) AdminDashboard(w http.ResponseWriter, r *http.Request) {
 			(cr.sheet_synced_at IS NOT NULL)
 		FROM census_responses cr
 		JOIN schools s ON s.id = cr.school_id
+		WHERE ($1 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($1)))
 		ORDER BY cr.updated_at DESC
-		LIMIT 50`)
+		LIMIT 50`, dre)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("erro ao buscar censos recentes"), http.StatusInternalServerError)
 		return
@@ -459,8 +516,8 @@ func parseCensusListParams(q url.Values) censusListParams {
 // listagem de /v1/admin/census — uma única fonte evita divergência entre total
 // paginado e linhas exibidas. Todos os filtros combinam por AND; UPPER(TRIM())
 // tolera caixa e espaços, espelhando o padrão da Saúde Operacional. A Região de
-// Integração casa schools.municipio contra reg_integracao (mesma ressalva de
-// grafia documentada em saudeOperacionalSelectSQL). A busca textual ($7) roda
+// Integração vem do município de referência da escola (schools.municipio_id →
+// municipios.regiao_integracao), comparada por censo_chave. A busca textual ($7) roda
 // no banco, sobre escola, INEP, município, DRE, status e ano.
 // Argumentos: $1=status, $2=year, $3=dre, $4=municipio, $5=zona,
 // $6=regiao_integracao, $7=search.
@@ -470,10 +527,10 @@ const censusListWhereSQL = `
 	  AND ($3 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($4)))
 	  AND ($5 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($5)))
-	  AND ($6 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($6))
+	  AND ($6 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($6)
 	      ))
 	  AND ($7 = ''
 	       OR s.nome_escola ILIKE '%' || $7 || '%'
@@ -518,24 +575,24 @@ const censusSummarySQL = `
 		 WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 		   AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 		   AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-		   AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-		         SELECT UPPER(TRIM(municipio))
-		         FROM reg_integracao
-		         WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+		   AND ($5 = '' OR s.municipio_id IN (
+		         SELECT id
+		         FROM municipios
+		         WHERE censo_chave(regiao_integracao) = censo_chave($5)
 		       ))),
-		COUNT(*) FILTER (WHERE cr.status = 'completed'),
+		COUNT(*) FILTER (WHERE censo_enviado(cr.status)),
 		COUNT(*) FILTER (WHERE cr.status = 'draft'),
-		COUNT(*) FILTER (WHERE cr.status = 'completed' AND cr.sheet_synced_at IS NULL)
+		COUNT(*) FILTER (WHERE censo_enviado(cr.status) AND cr.sheet_synced_at IS NULL)
 	FROM census_responses cr
 	JOIN schools s ON s.id = cr.school_id
 	WHERE ($1 = 0 OR cr.year = $1)
 	  AND ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))`
 
 // summaryArgs devolve os argumentos posicionais de censusSummarySQL na ordem
@@ -670,6 +727,10 @@ func (app *application) AdminGetCensusByID(w http.ResponseWriter, r *http.Reques
 		app.errorJSON(w, fmt.Errorf("censo não encontrado"), http.StatusNotFound)
 		return
 	}
+	if scope := adminDREScope(r.Context()); scope != "" && !sameDRE(c.Dre, scope) {
+		app.errorJSON(w, errDREForaDoEscopo, http.StatusForbidden)
+		return
+	}
 
 	c.Data = json.RawMessage(rawData)
 	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: c})
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"censo-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// =====================================================================
+// Trilha de auditoria do painel (admin_audit_log)
+// =====================================================================
+// auditAdminAccess roda logo depois de requireAdminAuth em todas as rotas
+// protegidas e grava, ao fim de cada requisição, quem a fez (conta), de
+// onde (clientIP), o quê (método, padrão chi da rota, caminho real e os
+// filtros da query string), o status HTTP devolvido e quando. Requisições
+// recusadas depois da autenticação (403 de papel/escopo) também ficam
+// registradas. Falha ao gravar é logada e não afeta a resposta, que já
+// foi produzida.
+//
+// Consulta (seduc_admin): GET /v1/admin/audit, paginada e filtrável por
+// user_id, username, route (prefixo do padrão), method, status, ip e
+// período (from/to, YYYY-MM-DD). GET /v1/admin/audit/export devolve o
+// mesmo recorte em XLSX pelo gerador de relatórios.
+// =====================================================================
+
+const (
+	// Limites do que é copiado da query string para a coluna query.
+	maxAuditQueryKeys     = 30
+	maxAuditQueryKeyLen   = 64
+	maxAuditQueryValueLen = 200
+	maxAuditPathLen       = 500
+
+	// auditExportMaxRows limita o XLSX; recortes maiores devem ser
+	// estreitados pelos filtros.
+	auditExportMaxRows = 50000
+	auditDateLayout    = "2006-01-02"
+)
+
+// auditReportDefinition descreve a exportação XLSX da trilha. Fica fora de
+// reportsCatalog de propósito: /v1/admin/reports/{report_id} é aberto a
+// todos os perfis, e a trilha é restrita a seduc_admin.
+var auditReportDefinition = ReportDefinition{
+	ID:          "auditoria-painel",
+	Title:       "Trilha de Auditoria do Painel Administrativo",
+	Description: "Acessos autenticados ao painel: usuário, IP, rota, filtros, status HTTP e data/hora.",
+	SheetName:   "Auditoria",
+	FileBase:    "relatorio_auditoria_painel",
+}
+
+var auditReportColumns = []string{
+	"Data/Hora",
+	"Usuário",
+	"Perfil",
+	"IP",
+	"Método",
+	"Rota",
+	"Caminho",
+	"Filtros",
+	"Status HTTP",
+	"Duração (ms)",
+}
+
+// auditAdminAccess grava uma entrada em admin_audit_log por requisição. Deve
+// ser usado depois de requireAdminAuth.
+func (app *application) auditAdminAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		id, _ := adminFromContext(r.Context())
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		e := buildAuditEntry(r, id, route, ww.Status(), time.Since(start))
+		if err := app.models.AdminAudit.Insert(e); err != nil {
+			app.logger.Printf("auditAdminAccess: gravar %s %s de %q: %v", e.Method, e.Path, e.Username, err)
+		}
+	})
+}
+
+// buildAuditEntry monta a entrada da trilha. status 0 significa que o
+// handler não chamou WriteHeader explicitamente (200 implícito); route
+// vazia (rota não casada) cai no caminho real.
+func buildAuditEntry(r *http.Request, id adminIdentity, route string, status int, elapsed time.Duration) *models.AdminAuditEntry {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	path := truncateRunes(r.URL.Path, maxAuditPathLen)
+	if route == "" {
+		route = path
+	}
+	query, _ := json.Marshal(auditQueryFilters(r.URL.Query()))
+
+	e := &models.AdminAuditEntry{
+		Username:   id.Username,
+		Role:       id.Role,
+		IP:         truncateRunes(clientIP(r), 64),
+		Method:     r.Method,
+		Route:      truncateRunes(route, 255),
+		Path:       path,
+		Query:      query,
+		StatusCode: status,
+		DurationMs: int(elapsed.Milliseconds()),
+	}
+	if id.UserID > 0 {
+		uid := id.UserID
+		e.UserID = &uid
+	}
+	return e
+}
+
+// auditQueryFilters reduz a query string a {parametro: valor}, com valores
+// repetidos unidos por vírgula e tamanhos limitados para que uma URL
+// abusiva não infle a trilha.
+func auditQueryFilters(q url.Values) map[string]string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > maxAuditQueryKeys {
+		keys = keys[:maxAuditQueryKeys]
+	}
+
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		out[truncateRunes(k, maxAuditQueryKeyLen)] = truncateRunes(strings.Join(q[k], ","), maxAuditQueryValueLen)
+	}
+	return out
+}
+
+// formatAuditQuery exibe a coluna query como "chave=valor; ..." em ordem
+// alfabética, para o XLSX.
+func formatAuditQuery(raw json.RawMessage) string {
+	var m map[string]string
+	if err := json.Unmarshal(raw, &m); err != nil || len(m) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + m[k]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseAuditFilter lê os filtros de /v1/admin/audit. Ao contrário dos
+// filtros analíticos, valores malformados são erro (400): numa consulta de
+// auditoria, ignorar um filtro silenciosamente devolveria mais do que o
+// pedido.
+func parseAuditFilter(q url.Values) (models.AdminAuditFilter, error) {
+	f := models.AdminAuditFilter{
+		Username: strings.TrimSpace(q.Get("username")),
+		Route:    strings.TrimSpace(q.Get("route")),
+		Method:   strings.ToUpper(strings.TrimSpace(q.Get("method"))),
+		IP:       strings.TrimSpace(q.Get("ip")),
+		From:     strings.TrimSpace(q.Get("from")),
+		To:       strings.TrimSpace(q.Get("to")),
+	}
+	if v := strings.TrimSpace(q.Get("user_id")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("user_id inválido")
+		}
+		f.UserID = n
+	}
+	if v := strings.TrimSpace(q.Get("status")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 100 || n > 599 {
+			return f, fmt.Errorf("status inválido")
+		}
+		f.Status = n
+	}
+	var from, to time.Time
+	var err error
+	if f.From != "" {
+		if from, err = time.Parse(auditDateLayout, f.From); err != nil {
+			return f, fmt.Errorf("from inválido; use AAAA-MM-DD")
+		}
+	}
+	if f.To != "" {
+		if to, err = time.Parse(auditDateLayout, f.To); err != nil {
+			return f, fmt.Errorf("to inválido; use AAAA-MM-DD")
+		}
+	}
+	if f.From != "" && f.To != "" && to.Before(from) {
+		return f, fmt.Errorf("to anterior a from")
+	}
+	return f, nil
+}
+
+// describeAuditFilter monta a linha de filtros do XLSX.
+func describeAuditFilter(f models.AdminAuditFilter) string {
+	parts := []string{}
+	switch {
+	case f.From != "" && f.To != "":
+		parts = append(parts, "Período: "+f.From+" a "+f.To)
+	case f.From != "":
+		parts = append(parts, "Desde: "+f.From)
+	case f.To != "":
+		parts = append(parts, "Até: "+f.To)
+	default:
+		parts = append(parts, "Período: todo")
+	}
+	if f.UserID > 0 {
+		parts = append(parts, "Usuário #"+strconv.Itoa(f.UserID))
+	}
+	if f.Username != "" {
+		parts = append(parts, "Usuário: "+f.Username)
+	}
+	if f.Route != "" {
+		parts = append(parts, "Rota: "+f.Route)
+	}
+	if f.Method != "" {
+		parts = append(parts, "Método: "+f.Method)
+	}
+	if f.Status > 0 {
+		parts = append(parts, "Status: "+strconv.Itoa(f.Status))
+	}
+	if f.IP != "" {
+		parts = append(parts, "IP: "+f.IP)
+	}
+	return "Filtros aplicados — " + strings.Join(parts, " | ")
+}
+
+// AuditPageResponse é o payload de GET /v1/admin/audit.
+type AuditPageResponse struct {
+	Rows  []*models.AdminAuditEntry `json:"rows"`
+	Total int                       `json:"total"`
+	Page  int                       `json:"page"`
+	Limit int                       `json:"limit"`
+}
+
+// AdminListAudit lista a trilha, mais recentes primeiro. Paginação como em
+// /v1/admin/census: limit 10/50/100/1000 (default 50) e page (default 1).
+func (app *application) AdminListAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, err := parseAuditFilter(q)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	limit, page := 50, 1
+	if v, err := strconv.Atoi(strings.TrimSpace(q.Get("limit"))); err == nil && censusListAllowedLimits[v] {
+		limit = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(q.Get("page"))); err == nil && v > 0 {
+		page = v
+	}
+
+	total, err := app.models.AdminAudit.Count(r.Context(), f)
+	if err != nil {
+		app.logger.Printf("AdminListAudit: contar: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar auditoria"), http.StatusInternalServerError)
+		return
+	}
+	rows, err := app.models.AdminAudit.List(r.Context(), f, limit, (page-1)*limit)
+	if err != nil {
+		app.logger.Printf("AdminListAudit: listar: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar auditoria"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: AuditPageResponse{
+		Rows: rows, Total: total, Page: page, Limit: limit,
+	}})
+}
+
+// AdminExportAudit exporta o recorte filtrado da trilha em XLSX (até
+// auditExportMaxRows linhas, mais recentes primeiro).
+func (app *application) AdminExportAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if format := normalizeReportFormat(q.Get("format")); format != reportFormatXLSX {
+		app.errorJSON(w, fmt.Errorf("formato %q não suportado; use format=xlsx", format), http.StatusBadRequest)
+		return
+	}
+	f, err := parseAuditFilter(q)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	total, err := app.models.AdminAudit.Count(r.Context(), f)
+	if err != nil {
+		app.logger.Printf("AdminExportAudit: contar: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao gerar relatório"), http.StatusInternalServerError)
+		return
+	}
+	entries, err := app.models.AdminAudit.List(r.Context(), f, auditExportMaxRows, 0)
+	if err != nil {
+		app.logger.Printf("AdminExportAudit: listar: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao gerar relatório"), http.StatusInternalServerError)
+		return
+	}
+
+	filtersLine := describeAuditFilter(f)
+	if total > len(entries) {
+		filtersLine += fmt.Sprintf(" | %d de %d registros (refine os filtros)", len(entries), total)
+	}
+	data := make([][]any, 0, len(entries))
+	for _, e := range entries {
+		data = append(data, []any{
+			e.CreatedAt.Format(reportDateLayout),
+			e.Username,
+			e.Role,
+			e.IP,
+			e.Method,
+			e.Route,
+			e.Path,
+			formatAuditQuery(e.Query),
+			e.StatusCode,
+			e.DurationMs,
+		})
+	}
+
+	def := auditReportDefinition
+	rd := reportData{
+		Title:       def.Title,
+		SheetName:   def.SheetName,
+		FiltersLine: filtersLine,
+		Headers:     auditReportColumns,
+		Rows:        data,
+	}
+	app.sendReportXLSX(w, "AdminExportAudit", rd, auditReportFileName(def.FileBase, f))
+}
+
+// auditReportFileName inclui o período no nome do arquivo, quando houver.
+func auditReportFileName(fileBase string, f models.AdminAuditFilter) string {
+	parts := []string{sanitizeFileNamePart(fileBase)}
+	if f.From != "" {
+		parts = append(parts, "de", sanitizeFileNamePart(f.From))
+	}
+	if f.To != "" {
+		parts = append(parts, "ate", sanitizeFileNamePart(f.To))
+	}
+	if f.Username != "" {
+		parts = append(parts, sanitizeFileNamePart(f.Username))
+	}
+	return strings.Join(parts, "_") + ".xlsx"
+}
@@ -0,0 +1,139 @@
+package main
+
+// Testes da trilha de auditoria. Sem banco: cobrem a montagem da entrada
+// (status implícito, rota, identidade, filtros), a validação dos filtros de
+// consulta e a formatação usada no XLSX.
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildAuditEntry(t *testing.T) {
+	t.Setenv("TRUSTED_PROXY_COUNT", "0")
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/census/15?year=2026&dre=MARABA", nil)
+	req.RemoteAddr = "10.1.2.3:5555"
+	id := adminIdentity{UserID: 4, Username: "ana.dre", Role: roleDreGestor, DRE: "MARABA"}
+
+	e := buildAuditEntry(req, id, "/v1/admin/census/{id}", 0, 1500*time.Millisecond)
+	if e.StatusCode != http.StatusOK {
+		t.Errorf("status = %d; want 200 implícito", e.StatusCode)
+	}
+	if e.UserID == nil || *e.UserID != 4 || e.Username != "ana.dre" || e.Role != roleDreGestor {
+		t.Errorf("identidade = %v %q %q", e.UserID, e.Username, e.Role)
+	}
+	if e.IP != "10.1.2.3" {
+		t.Errorf("ip = %q; want 10.1.2.3", e.IP)
+	}
+	if e.Route != "/v1/admin/census/{id}" || e.Path != "/v1/admin/census/15" {
+		t.Errorf("route = %q, path = %q", e.Route, e.Path)
+	}
+	if e.DurationMs != 1500 {
+		t.Errorf("duration_ms = %d; want 1500", e.DurationMs)
+	}
+	var q map[string]string
+	if err := json.Unmarshal(e.Query, &q); err != nil || q["year"] != "2026" || q["dre"] != "MARABA" {
+		t.Errorf("query = %s (err %v)", e.Query, err)
+	}
+}
+
+func TestBuildAuditEntryFallbacks(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/sync-sheets", nil)
+	e := buildAuditEntry(req, adminIdentity{}, "", http.StatusForbidden, 0)
+	if e.Route != "/v1/admin/sync-sheets" {
+		t.Errorf("route = %q; want caminho real quando o padrão está vazio", e.Route)
+	}
+	if e.UserID != nil {
+		t.Errorf("user_id = %v; want nil sem identidade", *e.UserID)
+	}
+	if e.StatusCode != http.StatusForbidden || string(e.Query) != "{}" {
+		t.Errorf("status = %d, query = %s", e.StatusCode, e.Query)
+	}
+}
+
+func TestAuditQueryFiltersLimits(t *testing.T) {
+	q := url.Values{}
+	for i := 0; i < maxAuditQueryKeys+10; i++ {
+		q.Set("k"+strings.Repeat("x", i), "v")
+	}
+	q.Set("a", strings.Repeat("é", maxAuditQueryValueLen+50))
+	q.Add("b", "1")
+	q.Add("b", "2")
+
+	out := auditQueryFilters(q)
+	if len(out) != maxAuditQueryKeys {
+		t.Fatalf("len = %d; want %d", len(out), maxAuditQueryKeys)
+	}
+	if n := len([]rune(out["a"])); n != maxAuditQueryValueLen {
+		t.Errorf("valor com %d runas; want %d", n, maxAuditQueryValueLen)
+	}
+	if out["b"] != "1,2" {
+		t.Errorf("b = %q; want \"1,2\"", out["b"])
+	}
+}
+
+func TestParseAuditFilter(t *testing.T) {
+	f, err := parseAuditFilter(url.Values{
+		"user_id": {"3"}, "method": {"get"}, "status": {"403"},
+		"from": {"2026-03-01"}, "to": {"2026-03-31"}, "route": {" /v1/admin/reports "},
+	})
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if f.UserID != 3 || f.Method != "GET" || f.Status != 403 || f.Route != "/v1/admin/reports" ||
+		f.From != "2026-03-01" || f.To != "2026-03-31" {
+		t.Fatalf("filtro = %+v", f)
+	}
+
+	for name, q := range map[string]url.Values{
+		"user_id texto":  {"user_id": {"abc"}},
+		"user_id zero":   {"user_id": {"0"}},
+		"status fora":    {"status": {"99"}},
+		"from inválido":  {"from": {"01/03/2026"}},
+		"to antes from":  {"from": {"2026-03-10"}, "to": {"2026-03-01"}},
+		"to inválido":    {"to": {"2026-13-01"}},
+		"status textual": {"status": {"ok"}},
+	} {
+		if _, err := parseAuditFilter(q); err == nil {
+			t.Errorf("%s: aceito", name)
+		}
+	}
+}
+
+func TestFormatAuditQuery(t *testing.T) {
+	if got := formatAuditQuery(json.RawMessage(`{"year":"2026","dre":"MARABA"}`)); got != "dre=MARABA; year=2026" {
+		t.Errorf("formatAuditQuery = %q", got)
+	}
+	if got := formatAuditQuery(json.RawMessage(`{}`)); got != "" {
+		t.Errorf("formatAuditQuery({}) = %q; want vazio", got)
+	}
+}
+
+func TestAuditReportNotInPublicCatalog(t *testing.T) {
+	if _, ok := lookupReport(auditReportDefinition.ID); ok {
+		t.Fatal("auditoria exposta em /v1/admin/reports, aberto a todos os perfis")
+	}
+}
+
+func TestWriteAuditXLSX(t *testing.T) {
+	rd := reportData{
+		Title:       auditReportDefinition.Title,
+		SheetName:   auditReportDefinition.SheetName,
+		FiltersLine: "Filtros aplicados — Período: todo",
+		Headers:     auditReportColumns,
+		Rows:        [][]any{{"01/03/2026 10:00", "admin", roleSeducAdmin, "10.0.0.1", "GET", "/v1/admin/census/{id}", "/v1/admin/census/1", "", 200, 12}},
+	}
+	f, err := writeReportXLSX(rd)
+	if err != nil {
+		t.Fatalf("writeReportXLSX: %v", err)
+	}
+	v, err := f.GetCellValue(auditReportDefinition.SheetName, "F5")
+	if err != nil || v != "/v1/admin/census/{id}" {
+		t.Fatalf("F5 = %q (err %v)", v, err)
+	}
+}
@@ -221,8 +221,8 @@ func TestCensusListWhereShape(t *testing.T) {
 		`($3 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($3)))`,
 		`($4 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($4)))`,
 		`($5 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($5)))`,
-		`FROM reg_integracao`,
-		`UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($6))`,
+		`s.municipio_id IN (`,
+		`censo_chave(regiao_integracao) = censo_chave($6)`,
 	}
 	for _, fragment := range mustContain {
 		if !strings.Contains(censusListWhereSQL, fragment) {
@@ -293,7 +293,7 @@ func TestCensusSummaryRespectsGlobalFilters(t *testing.T) {
 		`($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))`,
 		`($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))`,
 		`($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))`,
-		`UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))`,
+		`censo_chave(regiao_integracao) = censo_chave($5)`,
 	}
 	for _, fragment := range mustContain {
 		if !strings.Contains(censusSummarySQL, fragment) {
@@ -339,9 +339,9 @@ func TestCensusSummaryIgnoresLocalFilters(t *testing.T) {
 	}
 
 	mustContain := []string{
-		`COUNT(*) FILTER (WHERE cr.status = 'completed')`,
+		`COUNT(*) FILTER (WHERE censo_enviado(cr.status))`,
 		`COUNT(*) FILTER (WHERE cr.status = 'draft')`,
-		`COUNT(*) FILTER (WHERE cr.status = 'completed' AND cr.sheet_synced_at IS NULL)`,
+		`COUNT(*) FILTER (WHERE censo_enviado(cr.status) AND cr.sheet_synced_at IS NULL)`,
 	}
 	for _, fragment := range mustContain {
 		if !strings.Contains(censusSummarySQL, fragment) {
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// =====================================================================
+// Escopo regional (DRE) das contas do painel
+// =====================================================================
+// Uma conta com admin_users.dre preenchido só enxerga a própria DRE.
+// requireAdminAuth injeta o escopo no contexto (adminIdentity.DRE) e
+// enforceDREScope o aplica na query string ANTES dos handlers: o parâmetro
+// dre é forçado para a DRE da conta, ou a requisição é recusada com 403
+// quando pede outra. Assim AnalyticsFilters, reportFilters,
+// censusListParams, prodepFilters, idebFilters e os handlers que leem
+// r.URL.Query() diretamente recebem o recorte já restrito, sem que cada
+// parser precise conhecer o escopo.
+//
+// Endpoints sem filtro de DRE tratam o escopo individualmente:
+// AdminDashboard e AdminAnalyticsOverview passam a aceitar ?dre=,
+// AdminGetCensusByID confere a DRE da escola e as leituras da planilha
+// (sheet-metrics, indicadores-metrics, sync-sheets) ficam restritas a
+// contas estaduais (requireStatewideScope).
+// =====================================================================
+
+// errDREForaDoEscopo é devolvido quando uma conta regional pede dados de
+// outra DRE.
+var errDREForaDoEscopo = fmt.Errorf("acesso restrito à DRE da sua conta")
+
+// adminDREScope devolve a DRE da conta autenticada ("" = acesso estadual).
+func adminDREScope(ctx context.Context) string {
+	id, _ := adminFromContext(ctx)
+	return id.DRE
+}
+
+// normalizeDREKey reduz um nome de DRE a uma chave de comparação: sem
+// acentos, maiúsculo, espaços colapsados e sem o prefixo opcional "DRE "
+// (mesma tolerância de sqlNormalizeProdep, que casa "ABAETETUBA" com
+// "DRE ABAETETUBA").
+func normalizeDREKey(s string) string {
+	s = strings.ToUpper(accentReplacer.Replace(strings.TrimSpace(s)))
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.TrimPrefix(s, "DRE ")
+}
+
+// sameDRE compara dois nomes de DRE com a tolerância de normalizeDREKey.
+func sameDRE(a, b string) bool {
+	return normalizeDREKey(a) == normalizeDREKey(b)
+}
+
+// applyDREScope força o parâmetro dre de q para scope. Sem escopo não altera
+// nada. Com escopo, dre ausente recebe a DRE da conta e dre de outra DRE
+// resulta em errDREForaDoEscopo.
+func applyDREScope(q url.Values, scope string) error {
+	if scope == "" {
+		return nil
+	}
+	if requested := strings.TrimSpace(q.Get("dre")); requested != "" && !sameDRE(requested, scope) {
+		return errDREForaDoEscopo
+	}
+	q.Set("dre", scope)
+	return nil
+}
+
+// enforceDREScope aplica applyDREScope à query string da requisição. Deve
+// ser usado depois de requireAdminAuth.
+func (app *application) enforceDREScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := adminDREScope(r.Context())
+		if scope == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		if err := applyDREScope(q, scope); err != nil {
+			app.errorJSON(w, err, http.StatusForbidden)
+			return
+		}
+		scoped := r.Clone(r.Context())
+		scoped.URL.RawQuery = q.Encode()
+		next.ServeHTTP(w, scoped)
+	})
+}
+
+// requireStatewideScope recusa contas regionais em rotas que só existem no
+// recorte estadual (leituras diretas da planilha e sincronização).
+func (app *application) requireStatewideScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminDREScope(r.Context()) != "" {
+			app.errorJSON(w, fmt.Errorf("disponível apenas para contas com acesso estadual"), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
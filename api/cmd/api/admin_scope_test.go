@@ -0,0 +1,164 @@
+package main
+
+// Testes do escopo regional (DRE) das contas do painel. Cobrem a regra pura
+// (applyDREScope/normalizeDREKey) e o middleware enforceDREScope encadeado
+// aos parsers reais de filtros, comprovando que cada um recebe o recorte
+// forçado sem precisar conhecer o escopo.
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNormalizeDREKey(t *testing.T) {
+	cases := map[string]string{
+		"Abaetetuba":            "ABAETETUBA",
+		"  DRE Abaetetuba ":     "ABAETETUBA",
+		"dre   marabá":          "MARABA",
+		"Conceição do Araguaia": "CONCEICAO DO ARAGUAIA",
+	}
+	for in, want := range cases {
+		if got := normalizeDREKey(in); got != want {
+			t.Errorf("normalizeDREKey(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestApplyDREScope(t *testing.T) {
+	t.Run("sem escopo não altera", func(t *testing.T) {
+		q := url.Values{"dre": {"MARABA"}}
+		if err := applyDREScope(q, ""); err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		if q.Get("dre") != "MARABA" {
+			t.Fatalf("dre = %q; want MARABA", q.Get("dre"))
+		}
+	})
+	t.Run("dre ausente recebe o escopo", func(t *testing.T) {
+		q := url.Values{"year": {"2026"}}
+		if err := applyDREScope(q, "CASTANHAL"); err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		if q.Get("dre") != "CASTANHAL" || q.Get("year") != "2026" {
+			t.Fatalf("q = %v; want dre=CASTANHAL year=2026", q)
+		}
+	})
+	t.Run("mesma DRE com outra grafia", func(t *testing.T) {
+		q := url.Values{"dre": {"dre castanhal"}}
+		if err := applyDREScope(q, "CASTANHAL"); err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		if q.Get("dre") != "CASTANHAL" {
+			t.Fatalf("dre = %q; want grafia canônica CASTANHAL", q.Get("dre"))
+		}
+	})
+	t.Run("outra DRE é recusada", func(t *testing.T) {
+		q := url.Values{"dre": {"MARABA"}}
+		if err := applyDREScope(q, "CASTANHAL"); err != errDREForaDoEscopo {
+			t.Fatalf("err = %v; want errDREForaDoEscopo", err)
+		}
+	})
+}
+
+// scopedRequest executa enforceDREScope com a identidade informada e devolve
+// o status e a query string vista pelo handler final.
+func scopedRequest(t *testing.T, id adminIdentity, rawQuery string) (int, url.Values) {
+	t.Helper()
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	var seen url.Values
+	h := app.enforceDREScope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Query()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/x?"+rawQuery, nil)
+	req = req.WithContext(context.WithValue(req.Context(), contextKeyAdminIdentity, id))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, seen
+}
+
+func TestEnforceDREScopeForcesEveryFilterParser(t *testing.T) {
+	gestor := adminIdentity{UserID: 3, Role: roleDreGestor, DRE: "CASTANHAL"}
+	code, q := scopedRequest(t, gestor, "year=2025&municipio=CASTANHAL")
+	if code != http.StatusNoContent {
+		t.Fatalf("status = %d; want 204", code)
+	}
+
+	if f := parseAnalyticsFiltersFromValues(q, time.Now()); f.DRE != "CASTANHAL" {
+		t.Errorf("AnalyticsFilters.DRE = %q", f.DRE)
+	}
+	if f := parseReportFilters(q); f.DRE != "CASTANHAL" {
+		t.Errorf("reportFilters.DRE = %q", f.DRE)
+	}
+	if p := parseCensusListParams(q); p.DRE != "CASTANHAL" {
+		t.Errorf("censusListParams.DRE = %q", p.DRE)
+	}
+	if f, err := parseProdepFilters(q); err != nil || f.DRE != "CASTANHAL" {
+		t.Errorf("prodepFilters.DRE = %q (err %v)", f.DRE, err)
+	}
+	if f, err := parseIdebFilters(q); err != nil || f.DRE != "CASTANHAL" {
+		t.Errorf("idebFilters.DRE = %q (err %v)", f.DRE, err)
+	}
+}
+
+func TestEnforceDREScopeRejectsOtherDRE(t *testing.T) {
+	gestor := adminIdentity{UserID: 3, Role: roleDreGestor, DRE: "CASTANHAL"}
+	if code, _ := scopedRequest(t, gestor, "dre=MARABA"); code != http.StatusForbidden {
+		t.Fatalf("status = %d; want 403", code)
+	}
+}
+
+func TestEnforceDREScopeStatewideUntouched(t *testing.T) {
+	admin := adminIdentity{UserID: 1, Role: roleSeducAdmin}
+	code, q := scopedRequest(t, admin, "dre=MARABA")
+	if code != http.StatusNoContent || q.Get("dre") != "MARABA" {
+		t.Fatalf("status = %d, dre = %q; want 204 e MARABA", code, q.Get("dre"))
+	}
+	code, q = scopedRequest(t, admin, "")
+	if code != http.StatusNoContent || q.Get("dre") != "" {
+		t.Fatalf("status = %d, dre = %q; want 204 e sem dre", code, q.Get("dre"))
+	}
+}
+
+func TestRequireStatewideScope(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	h := app.requireStatewideScope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for _, c := range []struct {
+		id   adminIdentity
+		want int
+	}{
+		{adminIdentity{UserID: 1, Role: roleSeducAdmin}, http.StatusNoContent},
+		{adminIdentity{UserID: 2, Role: roleAnalista}, http.StatusNoContent},
+		{adminIdentity{UserID: 3, Role: roleAnalista, DRE: "MARABA"}, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/sheet-metrics", nil)
+		req = req.WithContext(context.WithValue(req.Context(), contextKeyAdminIdentity, c.id))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%+v: status = %d; want %d", c.id, rec.Code, c.want)
+		}
+	}
+}
+
+func TestValidateAdminRoleDRE(t *testing.T) {
+	if err := validateAdminRoleDRE(roleDreGestor, ""); err == nil {
+		t.Error("dre_gestor sem DRE aceito")
+	}
+	if err := validateAdminRoleDRE(roleSeducAdmin, "MARABA"); err == nil {
+		t.Error("seduc_admin com DRE aceito")
+	}
+	for _, ok := range [][2]string{{roleDreGestor, "MARABA"}, {roleSeducAdmin, ""}, {roleAnalista, ""}, {roleAnalista, "MARABA"}} {
+		if err := validateAdminRoleDRE(ok[0], ok[1]); err != nil {
+			t.Errorf("validateAdminRoleDRE(%q, %q) = %v", ok[0], ok[1], err)
+		}
+	}
+}
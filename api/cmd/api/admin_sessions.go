@@ -0,0 +1,322 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"censo-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// =====================================================================
+// Sessões do painel: refresh tokens, logout e revogação
+// =====================================================================
+// O login abre uma sessão (admin_sessions) e devolve dois tokens:
+//   - access token (JWT, jwtExpiry) com sid = id da sessão e jti aleatório;
+//   - refresh token opaco, guardado no banco apenas como hash SHA-256.
+//
+// POST /v1/admin/refresh troca o refresh token por um novo par (rotação): o
+// token apresentado deixa de valer e a sessão desliza por refreshTokenTTL,
+// até o teto de sessionMaxAge. Reapresentar um refresh token já rotacionado
+// depois de refreshReuseGrace indica vazamento e encerra a sessão inteira.
+//
+// POST /v1/admin/logout encerra a sessão e inclui o jti do access token na
+// lista de revogação (admin_revoked_tokens). requireAdminAuth recusa tokens
+// revogados ou de sessões encerradas já na requisição seguinte.
+//
+// Gestão (seduc_admin): GET /v1/admin/users/{id}/sessions lista as sessões
+// ativas da conta; DELETE /v1/admin/users/{id}/sessions/{session_id} encerra
+// uma e DELETE /v1/admin/users/{id}/sessions encerra todas.
+// =====================================================================
+
+const (
+	// refreshTokenTTL é a inatividade máxima: sem renovar nesse intervalo, a
+	// sessão expira.
+	refreshTokenTTL = 12 * time.Hour
+	// sessionMaxAge é o teto absoluto de uma sessão, renovada ou não.
+	sessionMaxAge = 7 * 24 * time.Hour
+	// refreshReuseGrace tolera renovações concorrentes da mesma sessão (duas
+	// abas renovando ao mesmo tempo) sem tratá-las como reuso malicioso.
+	refreshReuseGrace = 30 * time.Second
+	// sessionRetention mantém sessões encerradas/expiradas no banco para que
+	// o reuso de um refresh token antigo ainda seja reconhecido.
+	sessionRetention = 30 * 24 * time.Hour
+
+	maxUserAgentLen = 255
+)
+
+// randomToken devolve n bytes aleatórios em base64 URL-safe sem padding.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("gerar token aleatório: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken é o único formato em que um refresh token é persistido.
+// O token já tem 256 bits de entropia, então SHA-256 sem salt basta.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncateRunes corta s em até max runas, sem quebrar caracteres UTF-8.
+func truncateRunes(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max])
+	}
+	return s
+}
+
+// refreshReuseSuspicious decide se a reapresentação de um refresh token já
+// rotacionado deve encerrar a sessão: só fora da janela de tolerância.
+func refreshReuseSuspicious(rotatedAt *time.Time, now time.Time) bool {
+	return rotatedAt == nil || now.Sub(*rotatedAt) > refreshReuseGrace
+}
+
+// sessionTokens monta a resposta de login/refresh.
+func sessionTokens(access, refresh string, s *models.AdminSession, u *models.AdminUser) map[string]interface{} {
+	refreshIn := int(time.Until(s.ExpiresAt).Seconds())
+	if refreshIn < 0 {
+		refreshIn = 0
+	}
+	return map[string]interface{}{
+		"token":              access,
+		"expires_in":         int(jwtExpiry.Seconds()),
+		"refresh_token":      refresh,
+		"refresh_expires_in": refreshIn,
+		"session_id":         s.ID,
+		"user":               u,
+	}
+}
+
+// startAdminSession abre uma sessão para a conta já autenticada e devolve o
+// corpo da resposta de login.
+func (app *application) startAdminSession(u *models.AdminUser, r *http.Request) (map[string]interface{}, error) {
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	s, err := app.models.AdminSessions.Create(u.ID, hashRefreshToken(refresh),
+		clientIP(r), truncateRunes(r.UserAgent(), maxUserAgentLen), refreshTokenTTL, sessionMaxAge)
+	if err != nil {
+		return nil, fmt.Errorf("criar sessão: %w", err)
+	}
+	access, err := signAdminToken(u, s.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return sessionTokens(access, refresh, s, u), nil
+}
+
+// AdminRefresh troca um refresh token válido por um novo par de tokens.
+func (app *application) AdminRefresh(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+
+	if !app.allowRate(r, refreshLimit, ipKey(r)) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(refreshWindow.Seconds())))
+		app.errorJSON(w, fmt.Errorf("muitas renovações de sessão. Aguarde alguns minutos"), http.StatusTooManyRequests)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := app.readJSON(w, r, &req); err != nil || strings.TrimSpace(req.RefreshToken) == "" {
+		app.errorJSON(w, fmt.Errorf("refresh_token é obrigatório"), http.StatusBadRequest)
+		return
+	}
+	oldHash := hashRefreshToken(strings.TrimSpace(req.RefreshToken))
+	invalid := fmt.Errorf("sessão inválida ou expirada")
+
+	s, err := app.models.AdminSessions.GetActiveByRefreshHash(oldHash)
+	if err != nil {
+		app.logger.Printf("AdminRefresh: buscar sessão: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro interno ao renovar sessão"), http.StatusInternalServerError)
+		return
+	}
+	if s == nil {
+		app.handleRefreshReuse(oldHash)
+		app.errorJSON(w, invalid, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := app.models.AdminUsers.Get(s.UserID)
+	if err != nil {
+		app.logger.Printf("AdminRefresh: buscar usuário %d: %v", s.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro interno ao renovar sessão"), http.StatusInternalServerError)
+		return
+	}
+	if user == nil || !user.Active {
+		if err := app.models.AdminSessions.Revoke(s.ID); err != nil {
+			app.logger.Printf("AdminRefresh: encerrar sessão %d: %v", s.ID, err)
+		}
+		app.errorJSON(w, invalid, http.StatusUnauthorized)
+		return
+	}
+
+	refresh, err := randomToken(32)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("erro interno ao renovar sessão"), http.StatusInternalServerError)
+		return
+	}
+	ok, err := app.models.AdminSessions.Rotate(s, oldHash, hashRefreshToken(refresh), refreshTokenTTL)
+	if err != nil {
+		app.logger.Printf("AdminRefresh: rotacionar sessão %d: %v", s.ID, err)
+		app.errorJSON(w, fmt.Errorf("erro interno ao renovar sessão"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		// Outra renovação concorrente venceu com o mesmo token.
+		app.errorJSON(w, invalid, http.StatusUnauthorized)
+		return
+	}
+
+	access, err := signAdminToken(user, s.ID, time.Now())
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("erro interno ao gerar token"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{
+		Error:   false,
+		Message: "Sessão renovada",
+		Data:    sessionTokens(access, refresh, s, user),
+	})
+}
+
+// handleRefreshReuse encerra a sessão quando o hash apresentado é o de um
+// refresh token já rotacionado fora da janela de tolerância.
+func (app *application) handleRefreshReuse(hash string) {
+	s, err := app.models.AdminSessions.GetByPreviousHash(hash)
+	if err != nil {
+		app.logger.Printf("AdminRefresh: checar reuso: %v", err)
+		return
+	}
+	if s == nil || s.RevokedAt != nil || !refreshReuseSuspicious(s.RotatedAt, time.Now()) {
+		return
+	}
+	app.logger.Printf("AVISO SEGURANÇA: reuso de refresh token na sessão %d (usuário %d) — sessão encerrada", s.ID, s.UserID)
+	if err := app.models.AdminSessions.Revoke(s.ID); err != nil {
+		app.logger.Printf("AdminRefresh: encerrar sessão %d: %v", s.ID, err)
+	}
+}
+
+// AdminLogout encerra a sessão do token atual e revoga o próprio token.
+func (app *application) AdminLogout(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminFromContext(r.Context())
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("token de autenticação necessário"), http.StatusUnauthorized)
+		return
+	}
+
+	if err := app.models.AdminSessions.Revoke(id.SessionID); err != nil {
+		app.logger.Printf("AdminLogout: encerrar sessão %d: %v", id.SessionID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao encerrar sessão"), http.StatusInternalServerError)
+		return
+	}
+	if ttl := time.Until(id.TokenExpiresAt); ttl > 0 {
+		if err := app.models.AdminSessions.RevokeToken(id.TokenID, id.UserID, ttl); err != nil {
+			app.logger.Printf("AdminLogout: revogar token %s: %v", id.TokenID, err)
+			app.errorJSON(w, fmt.Errorf("erro ao encerrar sessão"), http.StatusInternalServerError)
+			return
+		}
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "Sessão encerrada"})
+}
+
+// userIDParam lê {id} da rota de contas.
+func userIDParam(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("id inválido")
+	}
+	return id, nil
+}
+
+// AdminListUserSessions lista as sessões ativas de uma conta.
+func (app *application) AdminListUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDParam(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	sessions, err := app.models.AdminSessions.GetActiveForUser(userID)
+	if err != nil {
+		app.logger.Printf("AdminListUserSessions: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao listar sessões"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: sessions})
+}
+
+// AdminRevokeUserSession encerra uma sessão específica da conta.
+func (app *application) AdminRevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDParam(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	sessionID, err := strconv.ParseInt(chi.URLParam(r, "session_id"), 10, 64)
+	if err != nil || sessionID <= 0 {
+		app.errorJSON(w, fmt.Errorf("session_id inválido"), http.StatusBadRequest)
+		return
+	}
+
+	ok, err := app.models.AdminSessions.RevokeForUser(userID, sessionID)
+	if err != nil {
+		app.logger.Printf("AdminRevokeUserSession: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao encerrar sessão"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("sessão ativa não encontrada"), http.StatusNotFound)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "Sessão encerrada"})
+}
+
+// AdminRevokeUserSessions encerra todas as sessões ativas da conta.
+func (app *application) AdminRevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDParam(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	n, err := app.models.AdminSessions.RevokeAllForUser(userID)
+	if err != nil {
+		app.logger.Printf("AdminRevokeUserSessions: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao encerrar sessões"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{
+		Error:   false,
+		Message: fmt.Sprintf("%d sessão(ões) encerrada(s)", n),
+		Data:    map[string]int64{"revoked": n},
+	})
+}
+
+// adminSessionCleanupJob remove periodicamente jtis revogados já expirados e
+// sessões antigas, para que as tabelas não cresçam indefinidamente.
+func (app *application) adminSessionCleanupJob() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		tokens, sessions, err := app.models.AdminSessions.DeleteExpired(sessionRetention)
+		if err != nil {
+			app.logger.Printf("adminSessionCleanup: %v", err)
+			continue
+		}
+		if tokens > 0 || sessions > 0 {
+			app.logger.Printf("adminSessionCleanup: %d token(s) revogado(s) e %d sessão(ões) removidos", tokens, sessions)
+		}
+	}
+}
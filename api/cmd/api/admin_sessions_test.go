@@ -0,0 +1,114 @@
+package main
+
+// Testes das sessões do painel. Sem banco: cobrem os tokens (formato, hash,
+// jti único), a exigência de sid/jti no access token e a regra de tolerância
+// para renovações concorrentes.
+
+import (
+	"testing"
+	"time"
+
+	"censo-api/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRandomTokenUniqueAndURLSafe(t *testing.T) {
+	a, err := randomToken(32)
+	if err != nil {
+		t.Fatalf("randomToken: %v", err)
+	}
+	b, _ := randomToken(32)
+	if a == b {
+		t.Fatal("dois tokens aleatórios iguais")
+	}
+	if len(a) != 43 {
+		t.Fatalf("len = %d; want 43 (32 bytes em base64 sem padding)", len(a))
+	}
+	for _, r := range a {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			t.Fatalf("token %q contém %q fora do alfabeto base64 URL-safe", a, r)
+		}
+	}
+}
+
+func TestHashRefreshToken(t *testing.T) {
+	h := hashRefreshToken("abc")
+	if len(h) != 64 {
+		t.Fatalf("len = %d; want 64 (cabe em CHAR(64))", len(h))
+	}
+	if h != hashRefreshToken("abc") {
+		t.Fatal("hash não determinístico")
+	}
+	if h == hashRefreshToken("abd") {
+		t.Fatal("tokens diferentes com o mesmo hash")
+	}
+}
+
+func TestAdminTokenUniqueJTI(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", testJWTSecret)
+	u := &models.AdminUser{ID: 1, Username: "admin", Role: roleSeducAdmin}
+	now := time.Now()
+
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		tok, err := signAdminToken(u, 1, now)
+		if err != nil {
+			t.Fatalf("signAdminToken: %v", err)
+		}
+		claims, err := parseAdminToken(tok)
+		if err != nil {
+			t.Fatalf("parseAdminToken: %v", err)
+		}
+		if seen[claims.ID] {
+			t.Fatalf("jti repetido: %s", claims.ID)
+		}
+		seen[claims.ID] = true
+	}
+}
+
+// Tokens emitidos antes das sessões (sem sid ou sem jti) não podem ser
+// revogados e devem ser recusados.
+func TestAdminTokenWithoutSession(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", testJWTSecret)
+
+	for name, c := range map[string]adminClaims{
+		"sem sid": {UserID: 1, RegisteredClaims: jwt.RegisteredClaims{ID: "x"}},
+		"sem jti": {UserID: 1, SessionID: 9},
+	} {
+		c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		c.Issuer = "censo-admin"
+		tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(testJWTSecret))
+		if err != nil {
+			t.Fatalf("%s: assinar: %v", name, err)
+		}
+		if _, err := parseAdminToken(tok); err == nil {
+			t.Errorf("%s: token aceito", name)
+		}
+	}
+}
+
+func TestRefreshReuseSuspicious(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-refreshReuseGrace / 2)
+	old := now.Add(-refreshReuseGrace - time.Second)
+
+	if refreshReuseSuspicious(&recent, now) {
+		t.Error("renovação concorrente dentro da tolerância tratada como reuso")
+	}
+	if !refreshReuseSuspicious(&old, now) {
+		t.Error("reuso fora da tolerância não detectado")
+	}
+	if !refreshReuseSuspicious(nil, now) {
+		t.Error("sessão sem rotated_at deve ser tratada como suspeita")
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	if got := truncateRunes("ação", 2); got != "aç" {
+		t.Fatalf("truncateRunes = %q; want \"aç\"", got)
+	}
+	if got := truncateRunes("curto", 10); got != "curto" {
+		t.Fatalf("truncateRunes = %q; want \"curto\"", got)
+	}
+}
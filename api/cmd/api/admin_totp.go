@@ -0,0 +1,646 @@
+package main
+
+// Segundo fator do painel: TOTP (RFC 6238) opcional por conta.
+//
+// Ativação (rotas /v1/admin/me/totp, para a própria conta):
+//   - POST /setup gera um segredo pendente e devolve o URI otpauth:// (que o
+//     painel mostra como QR code) e o segredo em base32 para digitação;
+//   - POST /enable confirma com um código do aplicativo, ativa o segundo
+//     fator e devolve, uma única vez, os códigos de recuperação.
+//
+// Com o segundo fator ativo, POST /v1/admin/login não devolve a sessão: a
+// resposta traz mfa_required e um pre-auth token de 5 minutos, que só vale
+// em POST /v1/admin/login/verify junto do código do aplicativo (ou de um
+// código de recuperação). O segredo fica cifrado no banco (AES-GCM) com
+// chave derivada de ADMIN_TOTP_KEY, ou de ADMIN_JWT_SECRET quando ela não
+// está definida.
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpDigits    = 6
+	totpPeriod    = 30 // segundos por passo
+	totpSkew      = 1  // passos aceitos antes e depois do atual (relógio do celular)
+	totpSecretLen = 20 // bytes: 160 bits, o tamanho recomendado pela RFC 4226
+	totpIssuer    = "Censo SEDUC-PA"
+
+	totpRecoveryCodeCount = 10
+
+	// Pre-auth token: tempo para abrir o aplicativo e digitar o código.
+	mfaTokenTTL  = 5 * time.Minute
+	mfaAudience  = "censo-admin-mfa"
+	maxTOTPInput = 32
+)
+
+var totpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ─── TOTP ────────────────────────────────────────────────────────────────────
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// totpCode calcula o código HOTP (RFC 4226) do passo informado.
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, v%mod)
+}
+
+// normalizeTOTPCode descarta espaços e hífens ("123 456").
+func normalizeTOTPCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '\t':
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// isTOTPCode informa se a entrada tem a forma de um código do aplicativo;
+// o resto é tratado como código de recuperação.
+func isTOTPCode(code string) bool {
+	code = normalizeTOTPCode(code)
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyTOTP confere o código no passo atual e nos totpSkew vizinhos e
+// devolve o passo que casou, para o controle de reuso.
+func verifyTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	if !isTOTPCode(code) {
+		return 0, false
+	}
+	code = normalizeTOTPCode(code)
+	cur := totpStep(now)
+	for d := int64(-totpSkew); d <= totpSkew; d++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, cur+d)), []byte(code)) == 1 {
+			return cur + d, true
+		}
+	}
+	return 0, false
+}
+
+func generateTOTPSecret() ([]byte, error) {
+	b := make([]byte, totpSecretLen)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("gerar segredo TOTP: %w", err)
+	}
+	return b, nil
+}
+
+// totpURI monta o URI otpauth:// lido pelos aplicativos autenticadores
+// (Google Authenticator, Microsoft Authenticator, Authy…).
+func totpURI(username string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", totpBase32.EncodeToString(secret))
+	q.Set("issuer", totpIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", strconv.Itoa(totpDigits))
+	q.Set("period", strconv.Itoa(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(totpIssuer+":"+username) + "?" + q.Encode()
+}
+
+// ─── Cifra do segredo ────────────────────────────────────────────────────────
+
+func totpKey() []byte {
+	material := os.Getenv("ADMIN_TOTP_KEY")
+	if material == "" {
+		material = string(jwtSecret())
+	}
+	sum := sha256.Sum256([]byte("censo-admin-totp\x00" + material))
+	return sum[:]
+}
+
+func totpAEAD() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(totpKey())
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealTOTPSecret cifra o segredo para o banco: base64(nonce || ciphertext).
+func sealTOTPSecret(secret []byte) (string, error) {
+	aead, err := totpAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, secret, nil)), nil
+}
+
+func openTOTPSecret(sealed string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("segredo TOTP ilegível: %w", err)
+	}
+	aead, err := totpAEAD()
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < aead.NonceSize() {
+		return nil, fmt.Errorf("segredo TOTP truncado")
+	}
+	secret, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		// Chave trocada (ADMIN_TOTP_KEY/ADMIN_JWT_SECRET) sem reativação.
+		return nil, fmt.Errorf("decifrar segredo TOTP: %w", err)
+	}
+	return secret, nil
+}
+
+// ─── Códigos de recuperação ──────────────────────────────────────────────────
+
+// Os códigos de recuperação têm o formato e a normalização dos códigos de
+// acesso das escolas (XXXX-XXXX-XXXX, 60 bits) e, como eles, ficam no
+// banco só como hash.
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < totpRecoveryCodeCount; i++ {
+		c, err := generateSchoolAccessCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	return hashSchoolAccessCode(code)
+}
+
+// ─── Pre-auth token ──────────────────────────────────────────────────────────
+
+// mfaClaims é o token entregue entre a senha e o segundo fator. A audiência
+// própria e a ausência de sessão (sid) impedem que ele valha como access
+// token em requireAdminAuth.
+type mfaClaims struct {
+	UserID int `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+func signMFAToken(userID int, now time.Time) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	claims := mfaClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Audience:  jwt.ClaimStrings{mfaAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "censo-admin",
+			Subject:   strconv.Itoa(userID),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret())
+}
+
+func parseMFAToken(tokenStr string) (int, error) {
+	claims := &mfaClaims{}
+	tok, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("algoritmo de assinatura inválido")
+		}
+		return jwtSecret(), nil
+	}, jwt.WithIssuer("censo-admin"), jwt.WithAudience(mfaAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, err
+	}
+	if !tok.Valid || claims.UserID <= 0 {
+		return 0, fmt.Errorf("token sem identificação de usuário")
+	}
+	return claims.UserID, nil
+}
+
+// ─── Verificação ─────────────────────────────────────────────────────────────
+
+// checkSecondFactor confere um código do aplicativo (consumindo o passo,
+// para que não valha de novo) ou, se a entrada não tiver a forma de um,
+// um código de recuperação (consumindo-o). recovery indica qual dos dois
+// foi usado.
+func (app *application) checkSecondFactor(userID int, code string, allowRecovery bool) (ok, recovery bool, err error) {
+	t, err := app.models.AdminTOTP.Get(userID)
+	if err != nil || t == nil || t.EnabledAt == nil || t.Secret == "" {
+		return false, false, err
+	}
+	if isTOTPCode(code) {
+		secret, err := openTOTPSecret(t.Secret)
+		if err != nil {
+			return false, false, err
+		}
+		step, ok := verifyTOTP(secret, code, time.Now())
+		if !ok {
+			return false, false, nil
+		}
+		ok, err = app.models.AdminTOTP.AdvanceStep(userID, step)
+		return ok, false, err
+	}
+	if !allowRecovery {
+		return false, false, nil
+	}
+	ok, err = app.models.AdminTOTP.UseRecoveryCode(userID, hashRecoveryCode(code))
+	return ok, true, err
+}
+
+// ─── Handlers ────────────────────────────────────────────────────────────────
+
+// AdminLoginVerify é o segundo passo do login: troca o pre-auth token e o
+// código do aplicativo (ou de recuperação) pela sessão.
+func (app *application) AdminLoginVerify(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 2048)
+
+	if !app.allowRate(r, loginLimit, ipKey(r)) {
+		w.Header().Set("Retry-After", "900")
+		app.errorJSON(w, fmt.Errorf("muitas tentativas. Aguarde 15 minutos"), http.StatusTooManyRequests)
+		return
+	}
+
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := app.readJSON(w, r, &req); err != nil || len(req.Code) > maxTOTPInput {
+		app.errorJSON(w, fmt.Errorf("dados inválidos"), http.StatusBadRequest)
+		return
+	}
+
+	expired := fmt.Errorf("verificação expirada. Entre novamente")
+	userID, err := parseMFAToken(strings.TrimSpace(req.MFAToken))
+	if err != nil {
+		app.errorJSON(w, expired, http.StatusUnauthorized)
+		return
+	}
+
+	if !app.totpAttemptAllowed(w, r, userID) {
+		return
+	}
+
+	user, err := app.models.AdminUsers.Get(userID)
+	if err != nil {
+		app.logger.Printf("AdminLoginVerify: buscar usuário %d: %v", userID, err)
+		app.errorJSON(w, fmt.Errorf("erro interno ao autenticar"), http.StatusInternalServerError)
+		return
+	}
+	if user == nil || !user.Active || !user.TOTPEnabled {
+		app.errorJSON(w, expired, http.StatusUnauthorized)
+		return
+	}
+
+	ok, recovery, err := app.checkSecondFactor(user.ID, req.Code, true)
+	if err != nil {
+		app.logger.Printf("AdminLoginVerify: conferir código de %d: %v", user.ID, err)
+		app.errorJSON(w, fmt.Errorf("erro interno ao autenticar"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		time.Sleep(600 * time.Millisecond)
+		app.errorJSON(w, fmt.Errorf("código inválido"), http.StatusUnauthorized)
+		return
+	}
+
+	data, err := app.startAdminSession(user, r)
+	if err != nil {
+		app.logger.Printf("AdminLoginVerify: abrir sessão de %d: %v", user.ID, err)
+		app.errorJSON(w, fmt.Errorf("erro interno ao gerar token"), http.StatusInternalServerError)
+		return
+	}
+	if recovery {
+		if t, err := app.models.AdminTOTP.Get(user.ID); err == nil && t != nil {
+			data["recovery_codes_remaining"] = t.RecoveryLeft
+		}
+	}
+
+	if err := app.models.AdminUsers.TouchLastLogin(user.ID); err != nil {
+		app.logger.Printf("AdminLoginVerify: registrar último login de %d: %v", user.ID, err)
+	}
+
+	app.writeJSON(w, http.StatusOK, jsonResponse{
+		Error:   false,
+		Message: "Login realizado com sucesso",
+		Data:    data,
+	})
+}
+
+// AdminTOTPStatus informa a situação do segundo fator da própria conta.
+func (app *application) AdminTOTPStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminFromContext(r.Context())
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("token de autenticação necessário"), http.StatusUnauthorized)
+		return
+	}
+	t, err := app.models.AdminTOTP.Get(id.UserID)
+	if err != nil || t == nil {
+		app.logger.Printf("AdminTOTPStatus: %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: map[string]interface{}{
+		"enabled":                  t.EnabledAt != nil,
+		"enabled_at":               t.EnabledAt,
+		"pending":                  t.EnabledAt == nil && t.PendingSecret != "",
+		"recovery_codes_remaining": t.RecoveryLeft,
+	}})
+}
+
+// AdminTOTPSetup inicia a ativação: gera um segredo pendente e devolve o
+// URI otpauth:// para o QR code. Chamar de novo antes de confirmar troca o
+// segredo pendente.
+func (app *application) AdminTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminFromContext(r.Context())
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("token de autenticação necessário"), http.StatusUnauthorized)
+		return
+	}
+	t, err := app.models.AdminTOTP.Get(id.UserID)
+	if err != nil || t == nil {
+		app.logger.Printf("AdminTOTPSetup: %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao configurar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	if t.EnabledAt != nil {
+		app.errorJSON(w, fmt.Errorf("segundo fator já ativo; desative-o antes de configurar outro aplicativo"), http.StatusConflict)
+		return
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		app.logger.Printf("AdminTOTPSetup: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao configurar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	sealed, err := sealTOTPSecret(secret)
+	if err == nil {
+		err = app.models.AdminTOTP.SetPending(id.UserID, sealed)
+	}
+	if err != nil {
+		app.logger.Printf("AdminTOTPSetup: gravar segredo de %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao configurar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, jsonResponse{
+		Error:   false,
+		Message: "Leia o QR code no aplicativo autenticador e confirme com um código",
+		Data: map[string]interface{}{
+			"otpauth_uri": totpURI(id.Username, secret),
+			"secret":      totpBase32.EncodeToString(secret),
+			"issuer":      totpIssuer,
+			"digits":      totpDigits,
+			"period":      totpPeriod,
+		},
+	})
+}
+
+// readTOTPRequest lê o corpo das rotas de segundo fator.
+func (app *application) readTOTPRequest(w http.ResponseWriter, r *http.Request) (code, password string, err error) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	var req struct {
+		Code     string `json:"code"`
+		Password string `json:"password"`
+	}
+	if err := app.readJSON(w, r, &req); err != nil {
+		return "", "", fmt.Errorf("dados inválidos")
+	}
+	if strings.TrimSpace(req.Code) == "" || len(req.Code) > maxTOTPInput || len(req.Password) > 128 {
+		return "", "", fmt.Errorf("code é obrigatório")
+	}
+	return req.Code, req.Password, nil
+}
+
+// totpAttemptAllowed aplica o limite de tentativas de código por conta.
+func (app *application) totpAttemptAllowed(w http.ResponseWriter, r *http.Request, userID int) bool {
+	if app.allowRate(r, totpVerifyLimit, userKey(userID)) {
+		return true
+	}
+	w.Header().Set("Retry-After", "900")
+	app.errorJSON(w, fmt.Errorf("muitas tentativas. Aguarde 15 minutos"), http.StatusTooManyRequests)
+	return false
+}
+
+// AdminTOTPEnable confirma a ativação com um código do aplicativo e devolve
+// os códigos de recuperação — a única vez em que eles aparecem em claro.
+func (app *application) AdminTOTPEnable(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminFromContext(r.Context())
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("token de autenticação necessário"), http.StatusUnauthorized)
+		return
+	}
+	code, _, err := app.readTOTPRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.totpAttemptAllowed(w, r, id.UserID) {
+		return
+	}
+
+	t, err := app.models.AdminTOTP.Get(id.UserID)
+	if err != nil || t == nil {
+		app.logger.Printf("AdminTOTPEnable: %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao ativar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	if t.EnabledAt != nil {
+		app.errorJSON(w, fmt.Errorf("segundo fator já ativo"), http.StatusConflict)
+		return
+	}
+	if t.PendingSecret == "" {
+		app.errorJSON(w, fmt.Errorf("nenhuma configuração em andamento; chame /v1/admin/me/totp/setup antes"), http.StatusBadRequest)
+		return
+	}
+	secret, err := openTOTPSecret(t.PendingSecret)
+	if err != nil {
+		app.logger.Printf("AdminTOTPEnable: %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("configuração inválida; chame /v1/admin/me/totp/setup de novo"), http.StatusBadRequest)
+		return
+	}
+	step, ok := verifyTOTP(secret, code, time.Now())
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("código inválido; confira o relógio do celular"), http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err == nil {
+		err = app.models.AdminTOTP.Enable(r.Context(), id.UserID, t.PendingSecret, step, hashes)
+	}
+	if err != nil {
+		app.logger.Printf("AdminTOTPEnable: ativar %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao ativar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, jsonResponse{
+		Error:   false,
+		Message: "Segundo fator ativado. Guarde os códigos de recuperação em local seguro",
+		Data:    map[string]interface{}{"recovery_codes": codes},
+	})
+}
+
+// AdminTOTPRecoveryCodes gera um novo jogo de códigos de recuperação,
+// invalidando os anteriores. Exige um código do aplicativo.
+func (app *application) AdminTOTPRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminFromContext(r.Context())
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("token de autenticação necessário"), http.StatusUnauthorized)
+		return
+	}
+	code, _, err := app.readTOTPRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.totpAttemptAllowed(w, r, id.UserID) {
+		return
+	}
+
+	ok, _, err = app.checkSecondFactor(id.UserID, code, false)
+	if err != nil {
+		app.logger.Printf("AdminTOTPRecoveryCodes: conferir código de %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao gerar códigos de recuperação"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("código inválido"), http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err == nil {
+		err = app.models.AdminTOTP.ReplaceRecoveryCodes(r.Context(), id.UserID, hashes)
+	}
+	if err != nil {
+		app.logger.Printf("AdminTOTPRecoveryCodes: gravar códigos de %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao gerar códigos de recuperação"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{
+		Error:   false,
+		Message: "Novos códigos de recuperação gerados; os anteriores deixaram de valer",
+		Data:    map[string]interface{}{"recovery_codes": codes},
+	})
+}
+
+// AdminTOTPDisable desliga o segundo fator da própria conta. Exige a senha
+// e um código (do aplicativo ou de recuperação).
+func (app *application) AdminTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminFromContext(r.Context())
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("token de autenticação necessário"), http.StatusUnauthorized)
+		return
+	}
+	code, password, err := app.readTOTPRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.totpAttemptAllowed(w, r, id.UserID) {
+		return
+	}
+
+	user, err := app.models.AdminUsers.Get(id.UserID)
+	if err != nil || user == nil {
+		app.logger.Printf("AdminTOTPDisable: buscar usuário %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao desativar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	if !user.TOTPEnabled {
+		app.errorJSON(w, fmt.Errorf("segundo fator não está ativo"), http.StatusConflict)
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		app.errorJSON(w, fmt.Errorf("senha ou código inválido"), http.StatusBadRequest)
+		return
+	}
+	ok, _, err = app.checkSecondFactor(id.UserID, code, true)
+	if err != nil {
+		app.logger.Printf("AdminTOTPDisable: conferir código de %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao desativar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("senha ou código inválido"), http.StatusBadRequest)
+		return
+	}
+
+	if err := app.models.AdminTOTP.Disable(r.Context(), id.UserID); err != nil {
+		app.logger.Printf("AdminTOTPDisable: %d: %v", id.UserID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao desativar segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "Segundo fator desativado"})
+}
+
+// AdminResetUserTOTP desliga o segundo fator de outra conta (celular
+// perdido sem códigos de recuperação). A pessoa volta a entrar só com a
+// senha e pode reativar em seguida.
+func (app *application) AdminResetUserTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDParam(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	user, err := app.models.AdminUsers.Get(userID)
+	if err != nil {
+		app.logger.Printf("AdminResetUserTOTP: buscar usuário %d: %v", userID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao redefinir segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		app.errorJSON(w, fmt.Errorf("usuário não encontrado"), http.StatusNotFound)
+		return
+	}
+	if err := app.models.AdminTOTP.Disable(r.Context(), userID); err != nil {
+		app.logger.Printf("AdminResetUserTOTP: %d: %v", userID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao redefinir segundo fator"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "Segundo fator da conta desativado"})
+}
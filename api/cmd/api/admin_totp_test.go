@@ -0,0 +1,150 @@
+package main
+
+// Testes do segundo fator. Sem banco: cobrem o algoritmo TOTP contra os
+// vetores da RFC 6238, a tolerância de relógio, o URI otpauth://, a cifra
+// do segredo, os códigos de recuperação e a separação entre o pre-auth
+// token e o access token.
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"censo-api/internal/models"
+)
+
+// Vetores SHA-1 do apêndice B da RFC 6238, truncados para 6 dígitos.
+func TestTOTPCodeRFC6238(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		if got := totpCode(secret, totpStep(time.Unix(tc.unix, 0))); got != tc.want {
+			t.Errorf("T=%d: código %s; want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111111, 0)
+	step := totpStep(now)
+
+	if s, ok := verifyTOTP(secret, totpCode(secret, step-1), now); !ok || s != step-1 {
+		t.Errorf("código do passo anterior recusado (ok=%v, passo=%d)", ok, s)
+	}
+	if s, ok := verifyTOTP(secret, totpCode(secret, step+1)[:3]+" "+totpCode(secret, step+1)[3:], now); !ok || s != step+1 {
+		t.Errorf("código do passo seguinte com espaço recusado (ok=%v, passo=%d)", ok, s)
+	}
+	if _, ok := verifyTOTP(secret, totpCode(secret, step-2), now); ok {
+		t.Error("código de dois passos atrás aceito")
+	}
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := verifyTOTP(secret, bad, now); ok {
+			t.Errorf("%q aceito", bad)
+		}
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	raw := totpURI("ana.dre", secret)
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("URI inválido %q: %v", raw, err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("esquema/tipo = %s/%s", u.Scheme, u.Host)
+	}
+	if u.Path != "/"+totpIssuer+":ana.dre" {
+		t.Errorf("label = %q", u.Path)
+	}
+	q := u.Query()
+	if q.Get("secret") != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" || q.Get("issuer") != totpIssuer ||
+		q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("parâmetros = %v", q)
+	}
+}
+
+func TestSealTOTPSecret(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", strings.Repeat("a", 40))
+	t.Setenv("ADMIN_TOTP_KEY", "")
+	secret := []byte("12345678901234567890")
+
+	sealed, err := sealTOTPSecret(secret)
+	if err != nil {
+		t.Fatalf("sealTOTPSecret: %v", err)
+	}
+	if strings.Contains(sealed, totpBase32.EncodeToString(secret)) {
+		t.Fatal("segredo em claro no valor cifrado")
+	}
+	got, err := openTOTPSecret(sealed)
+	if err != nil || string(got) != string(secret) {
+		t.Fatalf("openTOTPSecret = %q, %v", got, err)
+	}
+
+	t.Setenv("ADMIN_TOTP_KEY", "outra-chave")
+	if _, err := openTOTPSecret(sealed); err == nil {
+		t.Error("segredo aberto com outra chave")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes: %v", err)
+	}
+	if len(codes) != totpRecoveryCodeCount || len(hashes) != totpRecoveryCodeCount {
+		t.Fatalf("%d códigos, %d hashes; want %d", len(codes), len(hashes), totpRecoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for i, c := range codes {
+		if isTOTPCode(c) {
+			t.Errorf("código de recuperação %q confundível com código do aplicativo", c)
+		}
+		if hashes[i] != hashRecoveryCode(strings.ToLower(c)) {
+			t.Errorf("hash de %q não tolera caixa baixa", c)
+		}
+		if seen[hashes[i]] {
+			t.Errorf("código repetido: %s", c)
+		}
+		seen[hashes[i]] = true
+	}
+}
+
+func TestMFATokenIsNotAnAccessToken(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", strings.Repeat("b", 40))
+	now := time.Now()
+
+	mfa, err := signMFAToken(7, now)
+	if err != nil {
+		t.Fatalf("signMFAToken: %v", err)
+	}
+	if id, err := parseMFAToken(mfa); err != nil || id != 7 {
+		t.Fatalf("parseMFAToken = %d, %v", id, err)
+	}
+	if _, err := parseAdminToken(mfa); err == nil {
+		t.Error("pre-auth token aceito como access token")
+	}
+
+	access, err := signAdminToken(&models.AdminUser{ID: 7, Username: "ana", Role: roleAnalista}, 3, now)
+	if err != nil {
+		t.Fatalf("signAdminToken: %v", err)
+	}
+	if _, err := parseMFAToken(access); err == nil {
+		t.Error("access token aceito como pre-auth token")
+	}
+
+	expired, _ := signMFAToken(7, now.Add(-mfaTokenTTL-time.Minute))
+	if _, err := parseMFAToken(expired); err == nil {
+		t.Error("pre-auth token expirado aceito")
+	}
+}
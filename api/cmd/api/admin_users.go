@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"censo-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// =====================================================================
+// Contas individuais do painel administrativo (admin_users)
+// =====================================================================
+// Cada pessoa da equipe (analistas, gestores de DRE, administração SEDUC)
+// tem login próprio. O JWT emitido em AdminLogin carrega id e papel; o
+// middleware requireAdminAuth recarrega a conta a cada requisição, então
+// desativar um usuário ou trocar seu papel vale imediatamente.
+//
+// Gestão das contas: GET/POST /v1/admin/users e PATCH /v1/admin/users/{id},
+// restritas ao papel seduc_admin. Sessões de cada conta: ver
+// admin_sessions.go.
+// =====================================================================
+
+// Papéis aceitos em admin_users.role (espelham admin_users_role_chk).
+const (
+	roleSeducAdmin = "seduc_admin"
+	roleDreGestor  = "dre_gestor"
+	roleAnalista   = "analista"
+)
+
+var validAdminRoles = map[string]bool{
+	roleSeducAdmin: true,
+	roleDreGestor:  true,
+	roleAnalista:   true,
+}
+
+// Limites de credenciais. A senha mínima segue cmd/genpasswd; as máximas
+// acompanham a sanitização de AdminLogin (bcrypt ignora além de 72 bytes).
+const (
+	minAdminPasswordLen = 12
+	maxAdminPasswordLen = 72
+	maxAdminUsernameLen = 64
+)
+
+// adminIdentity é a identidade da conta autenticada, injetada no contexto
+// por requireAdminAuth. DRE vazio = acesso estadual; preenchido = escopo
+// regional aplicado por enforceDREScope.
+//
+// SessionID, TokenID (jti) e TokenExpiresAt vêm do access token e são usados
+// pelo logout para revogar a sessão e o próprio token.
+type adminIdentity struct {
+	UserID         int
+	Username       string
+	Role           string
+	DRE            string
+	SessionID      int64
+	TokenID        string
+	TokenExpiresAt time.Time
+}
+
+// adminFromContext devolve a identidade autenticada e false quando a rota
+// não passou por requireAdminAuth.
+func adminFromContext(ctx context.Context) (adminIdentity, bool) {
+	id, ok := ctx.Value(contextKeyAdminIdentity).(adminIdentity)
+	return id, ok
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash é comparado quando o username não existe, para que o
+// tempo de resposta do login não revele quais contas existem.
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("censo-admin-usuario-inexistente"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+// requireAdminRole restringe a rota aos papéis informados. Deve ser usado
+// depois de requireAdminAuth.
+func (app *application) requireAdminRole(roles ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		allowed[r] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := adminFromContext(r.Context())
+			if !ok || !allowed[id.Role] {
+				app.errorJSON(w, fmt.Errorf("acesso negado para o perfil atual"), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bootstrapAdminUser semeia a conta definida por ADMIN_USERNAME e
+// ADMIN_PASSWORD_HASH como seduc_admin quando admin_users está vazia. Isso
+// preserva o acesso de instalações que só tinham a conta única do ambiente;
+// a partir daí as demais contas são criadas pelo painel.
+func bootstrapAdminUser(m models.AdminUserModel, logger *log.Logger) error {
+	n, err := m.Count()
+	if err != nil {
+		return fmt.Errorf("contar admin_users: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	username := strings.TrimSpace(os.Getenv("ADMIN_USERNAME"))
+	hash := os.Getenv("ADMIN_PASSWORD_HASH")
+	if username == "" || hash == "" {
+		logger.Println("AVISO SEGURANÇA: admin_users vazia e ADMIN_USERNAME/ADMIN_PASSWORD_HASH não definidos — ninguém consegue entrar no painel")
+		return nil
+	}
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return fmt.Errorf("ADMIN_PASSWORD_HASH não é um hash bcrypt válido: %w", err)
+	}
+
+	u := &models.AdminUser{
+		Username:     username,
+		Nome:         "Administrador SEDUC",
+		PasswordHash: hash,
+		Role:         roleSeducAdmin,
+		Active:       true,
+	}
+	if err := m.Insert(u); err != nil {
+		return fmt.Errorf("semear %s: %w", username, err)
+	}
+	logger.Printf("admin_users: conta inicial %q criada como %s", username, roleSeducAdmin)
+	return nil
+}
+
+// adminUserInput é o corpo aceito por POST e PATCH /v1/admin/users. Campos
+// nil no PATCH preservam o valor atual.
+type adminUserInput struct {
+	Username *string `json:"username"`
+	Nome     *string `json:"nome"`
+	Password *string `json:"password"`
+	Role     *string `json:"role"`
+	DRE      *string `json:"dre"`
+	Active   *bool   `json:"active"`
+}
+
+// validateAdminUsername aceita letras, dígitos, ponto, hífen, underscore e
+// arroba (e-mails institucionais), até maxAdminUsernameLen caracteres.
+func validateAdminUsername(username string) error {
+	if username == "" {
+		return fmt.Errorf("username é obrigatório")
+	}
+	if len(username) > maxAdminUsernameLen {
+		return fmt.Errorf("username deve ter no máximo %d caracteres", maxAdminUsernameLen)
+	}
+	for _, r := range username {
+		ok := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+			r == '.' || r == '-' || r == '_' || r == '@'
+		if !ok {
+			return fmt.Errorf("username contém caracteres inválidos")
+		}
+	}
+	return nil
+}
+
+func validateAdminPassword(password string) error {
+	if len(password) < minAdminPasswordLen {
+		return fmt.Errorf("senha deve ter ao menos %d caracteres", minAdminPasswordLen)
+	}
+	if len(password) > maxAdminPasswordLen {
+		return fmt.Errorf("senha deve ter no máximo %d caracteres", maxAdminPasswordLen)
+	}
+	return nil
+}
+
+func validateAdminRole(role string) error {
+	if !validAdminRoles[role] {
+		return fmt.Errorf("role inválido: use %s, %s ou %s", roleSeducAdmin, roleDreGestor, roleAnalista)
+	}
+	return nil
+}
+
+// validateAdminRoleDRE espelha admin_users_dre_role_chk: dre_gestor exige
+// DRE, seduc_admin não aceita DRE e analista aceita ambos.
+func validateAdminRoleDRE(role, dre string) error {
+	switch {
+	case role == roleDreGestor && dre == "":
+		return fmt.Errorf("dre é obrigatório para o perfil %s", roleDreGestor)
+	case role == roleSeducAdmin && dre != "":
+		return fmt.Errorf("o perfil %s tem acesso estadual e não aceita dre", roleSeducAdmin)
+	}
+	return nil
+}
+
+// AdminListUsers lista as contas do painel (sem hashes).
+func (app *application) AdminListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := app.models.AdminUsers.GetAll()
+	if err != nil {
+		app.logger.Printf("AdminListUsers: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao listar usuários"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: users})
+}
+
+// AdminCreateUser cria uma conta. username, password e role são obrigatórios;
+// dre é obrigatório para dre_gestor; active assume true quando omitido.
+func (app *application) AdminCreateUser(w http.ResponseWriter, r *http.Request) {
+	var in adminUserInput
+	if err := app.readJSON(w, r, &in); err != nil {
+		app.errorJSON(w, fmt.Errorf("dados inválidos"), http.StatusBadRequest)
+		return
+	}
+	if in.Username == nil || in.Password == nil || in.Role == nil {
+		app.errorJSON(w, fmt.Errorf("username, password e role são obrigatórios"), http.StatusBadRequest)
+		return
+	}
+
+	u := &models.AdminUser{
+		Username: strings.TrimSpace(*in.Username),
+		Role:     strings.TrimSpace(*in.Role),
+		Active:   true,
+	}
+	if in.Nome != nil {
+		u.Nome = strings.TrimSpace(*in.Nome)
+	}
+	if in.DRE != nil {
+		u.Dre = strings.TrimSpace(*in.DRE)
+	}
+	if in.Active != nil {
+		u.Active = *in.Active
+	}
+	for _, err := range []error{
+		validateAdminUsername(u.Username),
+		validateAdminPassword(*in.Password),
+		validateAdminRole(u.Role),
+		validateAdminRoleDRE(u.Role, u.Dre),
+	} {
+		if err != nil {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	existing, err := app.models.AdminUsers.GetByUsername(u.Username)
+	if err != nil {
+		app.logger.Printf("AdminCreateUser: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao criar usuário"), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		app.errorJSON(w, fmt.Errorf("username %q já existe", u.Username), http.StatusConflict)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(*in.Password), bcrypt.DefaultCost)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("erro ao gerar hash da senha"), http.StatusInternalServerError)
+		return
+	}
+	u.PasswordHash = string(hash)
+
+	if err := app.models.AdminUsers.Insert(u); err != nil {
+		app.logger.Printf("AdminCreateUser: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao criar usuário"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusCreated, jsonResponse{Error: false, Message: "Usuário criado com sucesso", Data: u})
+}
+
+// AdminUpdateUser altera nome, senha, papel, DRE e/ou flag de ativo de uma
+// conta. dre "" devolve a conta ao acesso estadual.
+// O username é imutável. Um seduc_admin não pode desativar nem rebaixar a
+// própria conta, para não trancar o painel sem administrador.
+func (app *application) AdminUpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		app.errorJSON(w, fmt.Errorf("id inválido"), http.StatusBadRequest)
+		return
+	}
+
+	var in adminUserInput
+	if err := app.readJSON(w, r, &in); err != nil {
+		app.errorJSON(w, fmt.Errorf("dados inválidos"), http.StatusBadRequest)
+		return
+	}
+	if in.Username != nil {
+		app.errorJSON(w, fmt.Errorf("username não pode ser alterado"), http.StatusBadRequest)
+		return
+	}
+
+	u, err := app.models.AdminUsers.Get(id)
+	if err != nil {
+		app.logger.Printf("AdminUpdateUser: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao buscar usuário"), http.StatusInternalServerError)
+		return
+	}
+	if u == nil {
+		app.errorJSON(w, fmt.Errorf("usuário não encontrado"), http.StatusNotFound)
+		return
+	}
+
+	if in.Nome != nil {
+		u.Nome = strings.TrimSpace(*in.Nome)
+	}
+	if in.Role != nil {
+		role := strings.TrimSpace(*in.Role)
+		if err := validateAdminRole(role); err != nil {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		u.Role = role
+	}
+	if in.DRE != nil {
+		u.Dre = strings.TrimSpace(*in.DRE)
+	}
+	if err := validateAdminRoleDRE(u.Role, u.Dre); err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if in.Active != nil {
+		u.Active = *in.Active
+	}
+	if in.Password != nil {
+		if err := validateAdminPassword(*in.Password); err != nil {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(*in.Password), bcrypt.DefaultCost)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("erro ao gerar hash da senha"), http.StatusInternalServerError)
+			return
+		}
+		u.PasswordHash = string(hash)
+	}
+
+	if self, ok := adminFromContext(r.Context()); ok && self.UserID == u.ID {
+		if !u.Active || u.Role != roleSeducAdmin {
+			app.errorJSON(w, fmt.Errorf("não é possível desativar ou rebaixar a própria conta"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := app.models.AdminUsers.Update(u); err != nil {
+		app.logger.Printf("AdminUpdateUser: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao atualizar usuário"), http.StatusInternalServerError)
+		return
+	}
+
+	// Desativação ou troca de senha encerra as sessões abertas: os refresh
+	// tokens emitidos com a credencial antiga deixam de renovar.
+	if !u.Active || in.Password != nil {
+		if _, err := app.models.AdminSessions.RevokeAllForUser(u.ID); err != nil {
+			app.logger.Printf("AdminUpdateUser: encerrar sessões de %d: %v", u.ID, err)
+		}
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "Usuário atualizado com sucesso", Data: u})
+}
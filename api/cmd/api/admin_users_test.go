@@ -0,0 +1,177 @@
+package main
+
+// Testes das contas individuais do painel. Como nos demais testes do pacote,
+// não há banco: cobrem emissão/validação do JWT, validação de entrada e o
+// middleware de papel (requireAdminRole), que só depende do contexto.
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"censo-api/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func TestAdminTokenRoundTrip(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", testJWTSecret)
+
+	u := &models.AdminUser{ID: 7, Username: "ana.dre", Role: roleDreGestor}
+	tok, err := signAdminToken(u, 42, time.Now())
+	if err != nil {
+		t.Fatalf("signAdminToken: %v", err)
+	}
+	claims, err := parseAdminToken(tok)
+	if err != nil {
+		t.Fatalf("parseAdminToken: %v", err)
+	}
+	if claims.UserID != 7 || claims.Username != "ana.dre" || claims.Role != roleDreGestor {
+		t.Fatalf("claims = %+v; want uid=7 username=ana.dre role=%s", claims, roleDreGestor)
+	}
+	if claims.Subject != "7" {
+		t.Fatalf("subject = %q; want \"7\"", claims.Subject)
+	}
+	if claims.SessionID != 42 || claims.ID == "" {
+		t.Fatalf("sid = %d, jti = %q; want sid=42 e jti preenchido", claims.SessionID, claims.ID)
+	}
+}
+
+func TestAdminTokenExpired(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", testJWTSecret)
+
+	u := &models.AdminUser{ID: 1, Username: "admin", Role: roleSeducAdmin}
+	tok, err := signAdminToken(u, 42, time.Now().Add(-jwtExpiry-time.Minute))
+	if err != nil {
+		t.Fatalf("signAdminToken: %v", err)
+	}
+	if _, err := parseAdminToken(tok); err == nil {
+		t.Fatal("token expirado foi aceito")
+	}
+}
+
+func TestAdminTokenWrongSecret(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", testJWTSecret)
+	u := &models.AdminUser{ID: 1, Username: "admin", Role: roleSeducAdmin}
+	tok, err := signAdminToken(u, 42, time.Now())
+	if err != nil {
+		t.Fatalf("signAdminToken: %v", err)
+	}
+
+	t.Setenv("ADMIN_JWT_SECRET", strings.Repeat("x", 32))
+	if _, err := parseAdminToken(tok); err == nil {
+		t.Fatal("token assinado com outro segredo foi aceito")
+	}
+}
+
+// Tokens da conta única anterior (sem uid) não identificam um usuário e
+// devem ser recusados.
+func TestAdminTokenWithoutUserID(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", testJWTSecret)
+
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, adminClaims{
+		Username: "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Issuer:    "censo-admin",
+			Subject:   "admin",
+		},
+	})
+	tok, err := legacy.SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("assinar token legado: %v", err)
+	}
+	if _, err := parseAdminToken(tok); err == nil {
+		t.Fatal("token sem uid foi aceito")
+	}
+}
+
+func TestAdminTokenRejectsNoneAlgorithm(t *testing.T) {
+	t.Setenv("ADMIN_JWT_SECRET", testJWTSecret)
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, adminClaims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Issuer:    "censo-admin",
+		},
+	})
+	tok, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("montar token alg=none: %v", err)
+	}
+	if _, err := parseAdminToken(tok); err == nil {
+		t.Fatal("token alg=none foi aceito")
+	}
+}
+
+func TestValidateAdminUsername(t *testing.T) {
+	for _, ok := range []string{"admin", "ana.silva", "joao_dre-01", "gestor@seduc.pa.gov.br"} {
+		if err := validateAdminUsername(ok); err != nil {
+			t.Errorf("validateAdminUsername(%q) = %v; want nil", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "com espaço", "acentuação", "a;drop", strings.Repeat("a", maxAdminUsernameLen+1)} {
+		if err := validateAdminUsername(bad); err == nil {
+			t.Errorf("validateAdminUsername(%q) = nil; want erro", bad)
+		}
+	}
+}
+
+func TestValidateAdminPassword(t *testing.T) {
+	if err := validateAdminPassword(strings.Repeat("a", minAdminPasswordLen-1)); err == nil {
+		t.Error("senha curta demais aceita")
+	}
+	if err := validateAdminPassword(strings.Repeat("a", maxAdminPasswordLen+1)); err == nil {
+		t.Error("senha longa demais aceita")
+	}
+	if err := validateAdminPassword("Seduc@PA#2026!Censo"); err != nil {
+		t.Errorf("senha válida recusada: %v", err)
+	}
+}
+
+func TestValidateAdminRole(t *testing.T) {
+	for _, role := range []string{roleSeducAdmin, roleDreGestor, roleAnalista} {
+		if err := validateAdminRole(role); err != nil {
+			t.Errorf("validateAdminRole(%q) = %v; want nil", role, err)
+		}
+	}
+	for _, role := range []string{"", "admin", "SEDUC_ADMIN"} {
+		if err := validateAdminRole(role); err == nil {
+			t.Errorf("validateAdminRole(%q) = nil; want erro", role)
+		}
+	}
+}
+
+func TestRequireAdminRole(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := app.requireAdminRole(roleSeducAdmin)(ok)
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"sem identidade", context.Background(), http.StatusForbidden},
+		{"analista", context.WithValue(context.Background(), contextKeyAdminIdentity, adminIdentity{UserID: 2, Role: roleAnalista}), http.StatusForbidden},
+		{"seduc_admin", context.WithValue(context.Background(), contextKeyAdminIdentity, adminIdentity{UserID: 1, Role: roleSeducAdmin}), http.StatusNoContent},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v1/admin/users", nil).WithContext(c.ctx)
+			h.ServeHTTP(rec, req)
+			if rec.Code != c.want {
+				t.Fatalf("status = %d; want %d", rec.Code, c.want)
+			}
+		})
+	}
+}
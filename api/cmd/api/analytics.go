@@ -15,7 +15,7 @@ import (
 // Endpoints abaixo consomem vw_censo_enriquecida (migration 0002),
 // derivada de vw_censo_base. Critérios provisórios herdados da Fase 1:
 //
-//   - status = 'completed';
+//   - censo_enviado(status);
 //   - ano corrente (EXTRACT(YEAR FROM CURRENT_DATE));
 //   - sem deduplicação automática por INEP;
 //   - COUNT(DISTINCT school_id) quando o indicador é "quantidade de
@@ -123,16 +123,19 @@ type ZonaStat struct {
 //     (Censos concluídos)" no painel admin.
 //   - Métricas QUANTITATIVAS DE ALUNOS ("total_alunos", "alunos_pcd",
 //     "media_alunos_por_escola") consideram somente:
-//        status = 'completed'  AND  year = EXTRACT(YEAR FROM CURRENT_DATE)::int
+//        censo_enviado(status)  AND  year = EXTRACT(YEAR FROM CURRENT_DATE)::int
 //     O filtro de ano corrente evita inflação caso a base já contenha
 //     censos completados de múltiplos anos (cenário futuro do ciclo
 //     anual). Filtros por ano via querystring serão tratados em fase
 //     futura — por ora o critério é fixo no ano corrente.
 //   - "por_zona" agrupa escolas (COUNT DISTINCT school_id) por s.zona;
 //     uma escola sem zona informada cai em "Não informado".
+//   - "dre" (query string, opcional) restringe todas as contagens a uma DRE;
+//     para contas regionais é forçado por enforceDREScope.
 func (app *application) AdminAnalyticsOverview(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
+	dre := strings.TrimSpace(r.URL.Query().Get("dre"))
 
 	out := AnalyticsOverview{
 		PorZona: []ZonaStat{},
@@ -147,28 +150,30 @@ func (app *application) AdminAnalyticsOverview(w http.ResponseWriter, r *http.Re
 	//    - COALESCE garante 0 quando não há linhas completed no ano.
 	err := db.QueryRowContext(ctx, `
 		SELECT
-			(SELECT COUNT(*) FROM schools)                                                       AS total_schools,
+			(SELECT COUNT(*) FROM vw_censo_escolas
+			 WHERE active AND ($1 = '' OR UPPER(TRIM(dre)) = UPPER(TRIM($1))))                  AS total_schools,
 			COUNT(*) FILTER (WHERE census_id IS NOT NULL)                                        AS total_censuses,
-			COUNT(DISTINCT school_id) FILTER (WHERE status = 'completed')                        AS completed,
+			COUNT(DISTINCT school_id) FILTER (WHERE censo_enviado(status))                        AS completed,
 			COUNT(DISTINCT school_id) FILTER (WHERE status = 'draft')                            AS drafts,
 			COALESCE(SUM(total_alunos)
 				FILTER (
-					WHERE status = 'completed'
+					WHERE censo_enviado(status)
 					  AND year = EXTRACT(YEAR FROM CURRENT_DATE)::int
 				), 0)::float8                                                                    AS total_alunos,
 			COALESCE(SUM(alunos_pcd)
 				FILTER (
-					WHERE status = 'completed'
+					WHERE censo_enviado(status)
 					  AND year = EXTRACT(YEAR FROM CURRENT_DATE)::int
 				), 0)::float8                                                                    AS alunos_pcd,
 			COALESCE(AVG(total_alunos)
 				FILTER (
-					WHERE status = 'completed'
+					WHERE censo_enviado(status)
 					  AND total_alunos IS NOT NULL
 					  AND year = EXTRACT(YEAR FROM CURRENT_DATE)::int
 				), 0)::float8                                                                    AS media_alunos
 		FROM vw_censo_base
-	`).Scan(
+		WHERE ($1 = '' OR UPPER(TRIM(dre)) = UPPER(TRIM($1)))
+	`, dre).Scan(
 		&out.TotalSchools,
 		&out.TotalCensuses,
 		&out.Completed,
@@ -188,9 +193,10 @@ func (app *application) AdminAnalyticsOverview(w http.ResponseWriter, r *http.Re
 			COALESCE(NULLIF(zona, ''), 'Não informado') AS zona,
 			COUNT(DISTINCT school_id)                   AS total
 		FROM vw_censo_base
+		WHERE ($1 = '' OR UPPER(TRIM(dre)) = UPPER(TRIM($1)))
 		GROUP BY 1
 		ORDER BY 2 DESC, 1
-	`)
+	`, dre)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("erro ao agrupar por zona: %v", err), http.StatusInternalServerError)
 		return
@@ -216,7 +222,7 @@ func (app *application) AdminAnalyticsOverview(w http.ResponseWriter, r *http.Re
 // KPIs + distribuições principais da aba "Caracterização da Rede".
 //
 // Critérios (Fase 2A — provisórios, herdados da Fase 1):
-//   - filtros analíticos:   status='completed' AND year=ano corrente;
+//   - filtros analíticos:   censo_enviado(status) AND year=ano corrente;
 //   - "total_escolas":      COUNT DISTINCT school_id;
 //   - "total_alunos":       SUM(total_alunos);
 //   - "media_alunos_por_escola": AVG(total_alunos) considerando apenas
@@ -227,7 +233,7 @@ func (app *application) AdminAnalyticsOverview(w http.ResponseWriter, r *http.Re
 //   - "matriculas_por_porte": SUM(total_alunos) GROUP BY porte_escola.
 func (app *application) AdminAnalyticsCaracterizacaoPerfil(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 	f := parseAnalyticsFilters(r)
 
 	out := CaracterizacaoPerfil{
@@ -376,7 +382,7 @@ func (app *application) AdminAnalyticsCaracterizacaoPerfil(w http.ResponseWriter
 // Endpoint GET /v1/admin/analytics/caracterizacao/infraestrutura-educacional.
 // Consome vw_censo_ambientes (formato longo: 1 linha por school_id/year/
 // ambiente) e vw_censo_enriquecida (porte). Mantém o mesmo recorte
-// analítico da Caracterização: status='completed', ano corrente e
+// analítico da Caracterização: censo_enviado(status), ano corrente e
 // census_id IS NOT NULL. O denominador dos percentuais é SEMPRE o total
 // de escolas concluídas no ano corrente (não apenas as que declararam
 // algum ambiente), para responder "percentual de escolas concluídas que
@@ -458,13 +464,13 @@ WITH escolas AS (
         MIN(e.porte_escola_cod)  AS porte_cod,
         MIN(e.porte_escola_nome) AS porte_nome
     FROM vw_censo_enriquecida e
-    WHERE e.status = 'completed'
+    WHERE censo_enviado(e.status)
       AND e.year   = $1
       AND e.census_id IS NOT NULL
       AND ($2 = '' OR e.dre = $2)
       AND ($3 = '' OR e.municipio = $3)
       AND ($4 = '' OR e.zona = $4)
-      AND ($5 = '' OR e.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $5))
+      AND ($5 = '' OR censo_municipio_id(e.municipio) IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($5)))
     GROUP BY e.school_id
 ),
 essenciais(nome) AS (
@@ -485,7 +491,7 @@ amb_ess AS (
     FROM escolas e
     LEFT JOIN vw_censo_ambientes a
         ON a.school_id = e.school_id
-       AND a.status    = 'completed'
+       AND censo_enviado(a.status)
        AND a.year      = $1
     LEFT JOIN essenciais ess
         ON TRIM(a.ambiente) = ess.nome
@@ -513,7 +519,7 @@ por_escola AS (
 // ambientes.
 func (app *application) AdminAnalyticsCaracterizacaoInfraEducacional(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 	f := parseAnalyticsFilters(r)
 
 	out := CaracterizacaoInfraEducacional{
@@ -554,13 +560,13 @@ func (app *application) AdminAnalyticsCaracterizacaoInfraEducacional(w http.Resp
 			TRIM(a.ambiente)            AS label,
 			COUNT(DISTINCT a.school_id) AS escolas
 		FROM vw_censo_ambientes a
-		WHERE a.status = 'completed'
+		WHERE censo_enviado(a.status)
 		  AND a.year   = $1
 		  AND a.census_id IS NOT NULL
 		  AND ($2 = '' OR a.dre = $2)
 		  AND ($3 = '' OR a.municipio = $3)
 		  AND ($4 = '' OR a.zona = $4)
-		  AND ($5 = '' OR a.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $5))
+		  AND ($5 = '' OR censo_municipio_id(a.municipio) IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($5)))
 		GROUP BY TRIM(a.ambiente)
 		ORDER BY escolas DESC, label
 	`, f.Args()...)
@@ -679,7 +685,7 @@ func round2(v float64) float64 {
 // barras "Escolas por DRE" da aba "Caracterização da Rede".
 //
 // Critérios (Fase 2A — provisórios, herdados da Fase 1):
-//   - status='completed' AND year=ano corrente;
+//   - censo_enviado(status) AND year=ano corrente;
 //   - "escolas":              COUNT DISTINCT school_id;
 //   - "total_alunos":         SUM(total_alunos);
 //   - "media_alunos_por_escola": AVG(total_alunos) restrita a escolas
@@ -688,7 +694,7 @@ func round2(v float64) float64 {
 //   - DREs vazias/NULL caem em 'Não informado' para não sumirem do top.
 func (app *application) AdminAnalyticsCaracterizacaoDRE(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 	f := parseAnalyticsFilters(r)
 
 	out := CaracterizacaoDRE{
@@ -763,7 +769,7 @@ type CaracterizacaoOfertaFuncionamento struct {
 // necessárias para o bloco "Organização da Oferta e Funcionamento".
 //
 // Critérios:
-//   - Escolas elegíveis: census_responses.status='completed' AND year=ano corrente.
+//   - Escolas elegíveis: censo_enviado(census_responses.status) AND year=ano corrente.
 //   - Denominador de percentual: COUNT(DISTINCT school_id) elegíveis.
 //   - Uma escola pode contribuir para múltiplas etapas/modalidades/turnos
 //     (campo multivalorado) — percentuais somam > 100%, o que é esperado.
@@ -772,7 +778,7 @@ type CaracterizacaoOfertaFuncionamento struct {
 //   - media_turnos_por_porte exclui escolas sem turnos declarados no banco.
 func (app *application) AdminAnalyticsCaracterizacaoOfertaFuncionamento(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 	f := parseAnalyticsFilters(r)
 
 	out := CaracterizacaoOfertaFuncionamento{
@@ -786,14 +792,14 @@ func (app *application) AdminAnalyticsCaracterizacaoOfertaFuncionamento(w http.R
 	rowsEtapas, err := db.QueryContext(ctx, `
 		WITH completed AS (
 			SELECT cr.school_id, cr.data
-			FROM census_responses cr
-			JOIN schools s ON s.id = cr.school_id
-			WHERE cr.status = 'completed'
+			FROM vw_censo_fonte cr
+			JOIN vw_censo_escolas s ON s.id = cr.school_id
+			WHERE censo_enviado(cr.status)
 			  AND cr.year = $1
 			  AND ($2 = '' OR s.dre = $2)
 			  AND ($3 = '' OR s.municipio = $3)
 			  AND ($4 = '' OR s.zona = $4)
-			  AND ($5 = '' OR s.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $5))
+			  AND ($5 = '' OR s.municipio_id IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($5)))
 		),
 		total AS (
 			SELECT COUNT(DISTINCT school_id)::numeric AS n FROM completed
@@ -844,14 +850,14 @@ func (app *application) AdminAnalyticsCaracterizacaoOfertaFuncionamento(w http.R
 	rowsMod, err := db.QueryContext(ctx, `
 		WITH completed AS (
 			SELECT cr.school_id, cr.data
-			FROM census_responses cr
-			JOIN schools s ON s.id = cr.school_id
-			WHERE cr.status = 'completed'
+			FROM vw_censo_fonte cr
+			JOIN vw_censo_escolas s ON s.id = cr.school_id
+			WHERE censo_enviado(cr.status)
 			  AND cr.year = $1
 			  AND ($2 = '' OR s.dre = $2)
 			  AND ($3 = '' OR s.municipio = $3)
 			  AND ($4 = '' OR s.zona = $4)
-			  AND ($5 = '' OR s.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $5))
+			  AND ($5 = '' OR s.municipio_id IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($5)))
 		),
 		total AS (
 			SELECT COUNT(DISTINCT school_id)::numeric AS n FROM completed
@@ -902,14 +908,14 @@ func (app *application) AdminAnalyticsCaracterizacaoOfertaFuncionamento(w http.R
 	baseCTE := `
 		WITH completed AS (
 			SELECT DISTINCT cr.school_id
-			FROM census_responses cr
-			JOIN schools s ON s.id = cr.school_id
-			WHERE cr.status = 'completed'
+			FROM vw_censo_fonte cr
+			JOIN vw_censo_escolas s ON s.id = cr.school_id
+			WHERE censo_enviado(cr.status)
 			  AND cr.year = $1
 			  AND ($2 = '' OR s.dre = $2)
 			  AND ($3 = '' OR s.municipio = $3)
 			  AND ($4 = '' OR s.zona = $4)
-			  AND ($5 = '' OR s.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $5))
+			  AND ($5 = '' OR s.municipio_id IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($5)))
 		),
 		total AS (
 			SELECT COUNT(*)::numeric AS n FROM completed
@@ -920,7 +926,7 @@ func (app *application) AdminAnalyticsCaracterizacaoOfertaFuncionamento(w http.R
 		expanded AS (
 			SELECT c.school_id, trim(t2.val) AS turno
 			FROM completed c
-			JOIN schools s ON s.id = c.school_id
+			JOIN vw_censo_escolas s ON s.id = c.school_id
 			CROSS JOIN jsonb_array_elements_text(
 				CASE
 					WHEN s.turnos IS NOT NULL
@@ -968,20 +974,20 @@ func (app *application) AdminAnalyticsCaracterizacaoOfertaFuncionamento(w http.R
 	rowsMedia, err := db.QueryContext(ctx, `
 		WITH completed AS (
 			SELECT DISTINCT cr.school_id
-			FROM census_responses cr
-			JOIN schools s ON s.id = cr.school_id
-			WHERE cr.status = 'completed'
+			FROM vw_censo_fonte cr
+			JOIN vw_censo_escolas s ON s.id = cr.school_id
+			WHERE censo_enviado(cr.status)
 			  AND cr.year = $1
 			  AND ($2 = '' OR s.dre = $2)
 			  AND ($3 = '' OR s.municipio = $3)
 			  AND ($4 = '' OR s.zona = $4)
-			  AND ($5 = '' OR s.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $5))
+			  AND ($5 = '' OR s.municipio_id IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($5)))
 		),
 		turnos_por_escola AS (
 			SELECT c.school_id,
 				   COUNT(DISTINCT trim(t.val))::numeric AS qtd_turnos
 			FROM completed c
-			JOIN schools s ON s.id = c.school_id
+			JOIN vw_censo_escolas s ON s.id = c.school_id
 			CROSS JOIN jsonb_array_elements_text(
 				CASE
 					WHEN s.turnos IS NOT NULL
@@ -1002,7 +1008,7 @@ func (app *application) AdminAnalyticsCaracterizacaoOfertaFuncionamento(w http.R
 		FROM turnos_por_escola tp
 		JOIN vw_censo_enriquecida e
 		  ON e.school_id = tp.school_id
-		 AND e.status    = 'completed'
+		 AND censo_enviado(e.status)
 		 AND e.year      = $1
 		GROUP BY e.porte_escola_nome
 		ORDER BY ord
@@ -1085,7 +1091,7 @@ type CaracterizacaoEscolasPayload struct {
 
 const caracterizacaoEscolasSelectSQL = `
 	SELECT
-		COALESCE(ri.regiao_de_integracao, '')                            AS regiao_integracao,
+		COALESCE(ri.regiao_integracao, '')                               AS regiao_integracao,
 		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado')              AS dre,
 		COALESCE(NULLIF(TRIM(s.municipio), ''), 'Não informado')        AS municipio,
 		COALESCE(NULLIF(TRIM(s.zona), ''), '')                          AS zona,
@@ -1096,17 +1102,17 @@ const caracterizacaoEscolasSelectSQL = `
 		COALESCE(NULLIF(s.turnos, ''), '')                              AS turnos_texto,
 		COALESCE(NULLIF(s.etapas_ofertadas, ''), '')                    AS etapas_texto,
 		COALESCE(NULLIF(s.modalidades_ofertadas, ''), '')               AS modalidades_texto
-	FROM schools s
-	LEFT JOIN census_responses cr
-		ON cr.school_id = s.id AND cr.year = $1 AND cr.status = 'completed'
-	LEFT JOIN reg_integracao ri ON UPPER(TRIM(ri.municipio)) = UPPER(TRIM(s.municipio))
+	FROM vw_censo_escolas s
+	LEFT JOIN vw_censo_fonte cr
+		ON cr.school_id = s.id AND cr.year = $1 AND censo_enviado(cr.status)
+	LEFT JOIN municipios ri ON ri.id = s.municipio_id
 	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	ORDER BY UPPER(TRIM(s.dre)), UPPER(TRIM(s.municipio)), UPPER(TRIM(s.nome_escola)), s.codigo_inep
 `
@@ -1147,7 +1153,7 @@ func (app *application) AdminAnalyticsCaracterizacaoEscolas(w http.ResponseWrite
 	direction := parseEscolasDirection(q.Get("direction"))
 
 	ctx := r.Context()
-	dbRows, err := app.models.Schools.DB.QueryContext(ctx, caracterizacaoEscolasSelectSQL,
+	dbRows, err := app.analyticsDB(ctx).QueryContext(ctx, caracterizacaoEscolasSelectSQL,
 		f.Year, f.DRE, f.Municipio, f.Zona, f.RegiaoIntegracao)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("caracterizacao escolas: %w", err), http.StatusInternalServerError)
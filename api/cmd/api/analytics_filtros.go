@@ -19,6 +19,9 @@ type AnalyticsFilters struct {
 	Municipio        string
 	Zona             string
 	RegiaoIntegracao string
+	// SomenteAprovados restringe WhereSQL aos censos aprovados na revisão
+	// da DRE (?somente_aprovados=true). Padrão: todo censo enviado.
+	SomenteAprovados bool
 }
 
 func parseAnalyticsFilters(r *http.Request) AnalyticsFilters {
@@ -40,24 +43,40 @@ func parseAnalyticsFiltersFromValues(q url.Values, now time.Time) AnalyticsFilte
 	if y, err := strconv.Atoi(strings.TrimSpace(q.Get("year"))); err == nil && y > 0 {
 		f.Year = y
 	}
+	if b, err := strconv.ParseBool(strings.TrimSpace(q.Get("somente_aprovados"))); err == nil {
+		f.SomenteAprovados = b
+	}
 	return f
 }
 
 // WhereSQL returns a parameterized WHERE fragment (no table alias prefix).
 // $1=year, $2=dre, $3=municipio, $4=zona, $5=regiao_integracao.
-// Empty string params disable the corresponding filter.
-// Pair with Args() to get the matching positional arguments.
+// Empty string params disable the corresponding filter. The status cut is
+// every submitted census (censo_enviado) or, with SomenteAprovados, only
+// those approved in the DRE review; it takes no positional argument.
+// Text filters compare by censo_chave (case, accents and punctuation
+// ignored); the region goes through the school's municipio_id, as in the
+// other analytics queries. Pair with Args() to get the matching positional
+// arguments.
 func (f AnalyticsFilters) WhereSQL() string {
-	return `status = 'completed'
+	status := "censo_enviado(status)"
+	if f.SomenteAprovados {
+		status = "status = 'aprovado'"
+	}
+	return status + `
       AND year = $1
       AND census_id IS NOT NULL
-      AND ($2 = '' OR UPPER(TRIM(dre)) = UPPER(TRIM($2)))
-      AND ($3 = '' OR UPPER(TRIM(municipio)) = UPPER(TRIM($3)))
-      AND ($4 = '' OR UPPER(TRIM(zona)) = UPPER(TRIM($4)))
-      AND ($5 = '' OR UPPER(TRIM(municipio)) IN (
-        SELECT UPPER(TRIM(municipio))
-        FROM reg_integracao
-        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+      AND ($2 = '' OR censo_chave(dre) = censo_chave($2))
+      AND ($3 = '' OR censo_chave(municipio) = censo_chave($3))
+      AND ($4 = '' OR censo_chave(zona) = censo_chave($4))
+      AND ($5 = '' OR school_id IN (
+        SELECT id
+        FROM vw_censo_escolas
+        WHERE municipio_id IN (
+          SELECT id
+          FROM municipios
+          WHERE censo_chave(regiao_integracao) = censo_chave($5)
+        )
       ))`
 }
 
@@ -67,7 +86,7 @@ func (f AnalyticsFilters) Args() []any {
 }
 
 func queryStringSlice(app *application, ctx context.Context, query string, args ...any) ([]string, error) {
-	rows, err := app.models.Schools.DB.QueryContext(ctx, query, args...)
+	rows, err := app.analyticsDB(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,15 +122,18 @@ type FiltrosOpcoes struct {
 
 // AdminAnalyticsFiltrosOpcoes retorna as listas para popular os selects
 // dos filtros globais do dashboard. Aceita os mesmos query params dos filtros
-// analíticos e aplica cascata: cada lista é filtrada pelos demais filtros ativos.
+// analíticos e aplica cascata: cada lista é filtrada pelos demais filtros ativos,
+// comparados por censo_chave como em WhereSQL.
+// Para contas regionais, a lista de DREs e a de escolas ficam restritas à DRE
+// da conta (as demais listas já recebem o dre forçado por enforceDREScope).
 func (app *application) AdminAnalyticsFiltrosOpcoes(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	f := parseAnalyticsFilters(r)
 
 	anos, err := queryStringSlice(app, ctx, `
 		SELECT DISTINCT year::text
-		FROM census_responses
-		WHERE status = 'completed'
+		FROM vw_censo_fonte
+		WHERE censo_enviado(status)
 		ORDER BY year::text DESC
 	`)
 	if err != nil {
@@ -128,12 +150,13 @@ func (app *application) AdminAnalyticsFiltrosOpcoes(w http.ResponseWriter, r *ht
 
 	// Regiões: filtradas por dre, municipio, zona (não pela própria regiao)
 	regioes, err := queryStringSlice(app, ctx, `
-		SELECT DISTINCT r.regiao_de_integracao
-		FROM reg_integracao r
-		JOIN schools s ON s.municipio = r.municipio
-		WHERE ($1 = '' OR s.dre = $1)
-		  AND ($2 = '' OR s.municipio = $2)
-		  AND ($3 = '' OR s.zona = $3)
+		SELECT DISTINCT m.regiao_integracao
+		FROM municipios m
+		JOIN vw_censo_escolas s ON s.municipio_id = m.id
+		WHERE m.regiao_integracao IS NOT NULL
+		  AND ($1 = '' OR censo_chave(s.dre) = censo_chave($1))
+		  AND ($2 = '' OR censo_chave(s.municipio) = censo_chave($2))
+		  AND ($3 = '' OR censo_chave(s.zona) = censo_chave($3))
 		ORDER BY 1
 	`, f.DRE, f.Municipio, f.Zona)
 	if err != nil {
@@ -144,24 +167,28 @@ func (app *application) AdminAnalyticsFiltrosOpcoes(w http.ResponseWriter, r *ht
 	// DREs: filtradas por municipio, zona, regiao (não pela própria dre)
 	dres, err := queryStringSlice(app, ctx, `
 		SELECT DISTINCT COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado') AS dre
-		FROM schools s
-		WHERE ($1 = '' OR s.municipio = $1)
-		  AND ($2 = '' OR s.zona = $2)
-		  AND ($3 = '' OR s.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $3))
+		FROM vw_censo_escolas s
+		WHERE ($1 = '' OR censo_chave(s.municipio) = censo_chave($1))
+		  AND ($2 = '' OR censo_chave(s.zona) = censo_chave($2))
+		  AND ($3 = '' OR s.municipio_id IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($3)))
 		ORDER BY 1
 	`, f.Municipio, f.Zona, f.RegiaoIntegracao)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("dres: %w", err), http.StatusInternalServerError)
 		return
 	}
+	scope := adminDREScope(ctx)
+	if scope != "" {
+		dres = []string{scope}
+	}
 
 	// Municípios: filtrados por dre, zona, regiao (não pelo próprio municipio)
 	municipios, err := queryStringSlice(app, ctx, `
 		SELECT DISTINCT COALESCE(NULLIF(TRIM(s.municipio), ''), 'Não informado') AS municipio
-		FROM schools s
-		WHERE ($1 = '' OR s.dre = $1)
-		  AND ($2 = '' OR s.zona = $2)
-		  AND ($3 = '' OR s.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $3))
+		FROM vw_censo_escolas s
+		WHERE ($1 = '' OR censo_chave(s.dre) = censo_chave($1))
+		  AND ($2 = '' OR censo_chave(s.zona) = censo_chave($2))
+		  AND ($3 = '' OR s.municipio_id IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($3)))
 		ORDER BY 1
 	`, f.DRE, f.Zona, f.RegiaoIntegracao)
 	if err != nil {
@@ -172,11 +199,11 @@ func (app *application) AdminAnalyticsFiltrosOpcoes(w http.ResponseWriter, r *ht
 	// Zonas: filtradas por dre, municipio, regiao (não pela própria zona)
 	zonas, err := queryStringSlice(app, ctx, `
 		SELECT DISTINCT s.zona
-		FROM schools s
+		FROM vw_censo_escolas s
 		WHERE s.zona IS NOT NULL AND TRIM(s.zona) <> ''
-		  AND ($1 = '' OR s.dre = $1)
-		  AND ($2 = '' OR s.municipio = $2)
-		  AND ($3 = '' OR s.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $3))
+		  AND ($1 = '' OR censo_chave(s.dre) = censo_chave($1))
+		  AND ($2 = '' OR censo_chave(s.municipio) = censo_chave($2))
+		  AND ($3 = '' OR s.municipio_id IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($3)))
 		ORDER BY 1
 	`, f.DRE, f.Municipio, f.RegiaoIntegracao)
 	if err != nil {
@@ -184,7 +211,7 @@ func (app *application) AdminAnalyticsFiltrosOpcoes(w http.ResponseWriter, r *ht
 		return
 	}
 
-	rows, err := app.models.Schools.DB.QueryContext(ctx, `
+	rows, err := app.analyticsDB(ctx).QueryContext(ctx, `
 		SELECT
 			id,
 			codigo_inep,
@@ -192,9 +219,10 @@ func (app *application) AdminAnalyticsFiltrosOpcoes(w http.ResponseWriter, r *ht
 			COALESCE(NULLIF(TRIM(municipio), ''), 'Não informado') AS municipio,
 			COALESCE(NULLIF(TRIM(dre), ''), 'Não informado') AS dre,
 			zona
-		FROM schools
+		FROM vw_censo_escolas
+		WHERE ($1 = '' OR censo_chave(dre) = censo_chave($1))
 		ORDER BY nome_escola
-	`)
+	`, scope)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("escolas: %w", err), http.StatusInternalServerError)
 		return
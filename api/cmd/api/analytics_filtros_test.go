@@ -89,14 +89,15 @@ func TestAnalyticsFilters_ArgsOrder(t *testing.T) {
 func TestAnalyticsFilters_WhereSQL(t *testing.T) {
 	sql := AnalyticsFilters{}.WhereSQL()
 	mustContain := []string{
-		"status = 'completed'",
+		"censo_enviado(status)",
 		"year = $1",
 		"census_id IS NOT NULL",
-		"UPPER(TRIM(dre)) = UPPER(TRIM($2))",
-		"UPPER(TRIM(municipio)) = UPPER(TRIM($3))",
-		"UPPER(TRIM(zona)) = UPPER(TRIM($4))",
-		"UPPER(TRIM(municipio)) IN",
-		"UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))",
+		"censo_chave(dre) = censo_chave($2)",
+		"censo_chave(municipio) = censo_chave($3)",
+		"censo_chave(zona) = censo_chave($4)",
+		"school_id IN (",
+		"WHERE municipio_id IN (",
+		"censo_chave(regiao_integracao) = censo_chave($5)",
 	}
 	for _, frag := range mustContain {
 		if !strings.Contains(sql, frag) {
@@ -104,3 +105,20 @@ func TestAnalyticsFilters_WhereSQL(t *testing.T) {
 		}
 	}
 }
+
+func TestAnalyticsFilters_SomenteAprovados(t *testing.T) {
+	f := parseAnalyticsFiltersFromValues(url.Values{"somente_aprovados": {"true"}}, fixedNow)
+	if !f.SomenteAprovados {
+		t.Fatalf("somente_aprovados=true not parsed: %+v", f)
+	}
+	if g := parseAnalyticsFiltersFromValues(url.Values{"somente_aprovados": {"talvez"}}, fixedNow); g.SomenteAprovados {
+		t.Fatalf("invalid somente_aprovados enabled the filter")
+	}
+	sql := f.WhereSQL()
+	if !strings.HasPrefix(sql, "status = 'aprovado'") || strings.Contains(sql, "censo_enviado") {
+		t.Fatalf("WhereSQL with SomenteAprovados:\n%s", sql)
+	}
+	if len(f.Args()) != 5 {
+		t.Fatalf("SomenteAprovados changed the positional args: %v", f.Args())
+	}
+}
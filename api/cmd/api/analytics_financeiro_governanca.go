@@ -277,7 +277,7 @@ func parseProdepFilters(q map[string][]string) (prodepFilters, error) {
 // válida com zeros/listas vazias (não quebra).
 func (app *application) AdminAnalyticsFinanceiroGovernancaProdep(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	filters, err := parseProdepFilters(r.URL.Query())
 	if err != nil {
@@ -499,7 +499,7 @@ func (app *application) AdminAnalyticsFinanceiroGovernancaProdep(w http.Response
 // orderCol é validado contra um allowlist; nunca vem de entrada do usuário.
 func (app *application) queryProdepRanking(
 	ctx context.Context,
-	db *sql.DB,
+	db analyticsQueryer,
 	orderCol string,
 	args []any,
 ) ([]ProdepEscolaRanking, error) {
@@ -581,7 +581,7 @@ func (app *application) queryProdepRanking(
 // contra um allowlist; nunca vem de entrada do usuário.
 func (app *application) queryProdepDistinct(
 	ctx context.Context,
-	db *sql.DB,
+	db analyticsQueryer,
 	col string,
 ) ([]string, error) {
 	switch col {
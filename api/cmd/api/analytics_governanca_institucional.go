@@ -15,7 +15,7 @@ import (
 // para a aba "Gestão Financeira e Governança":
 //   GET /v1/admin/analytics/financeiro-governanca/institucional
 //
-// Fonte: respostas CONCLUÍDAS do Censo (a view já filtra status = 'completed').
+// Fonte: respostas CONCLUÍDAS do Censo (a view já filtra censo_enviado(status)).
 // Por isso o filtro `ano` NÃO se aplica aqui — diferentemente do bloco PRODEP,
 // que tem seus próprios filtros. Os filtros globais aplicáveis são: dre,
 // municipio, zona. A nota/metadados deixa explícito que se trata do Censo atual.
@@ -56,7 +56,7 @@ func parseGovernancaInstitucionalFilters(q url.Values) governancaInstitucionalFi
 }
 
 // governancaInstitucionalWhereSQL é a cláusula WHERE parametrizada aplicada
-// sobre a view (que já restringe a status = 'completed'). Comparações
+// sobre a view (que já restringe a censo_enviado(status)). Comparações
 // case-insensitive com TRIM. $1=dre $2=municipio $3=zona.
 const governancaInstitucionalWhereSQL = `
 	WHERE ($1 = '' OR UPPER(TRIM(dre)) = UPPER(TRIM($1)))
@@ -121,7 +121,7 @@ const governancaInstitucionalObservacao = "Indicadores calculados a partir de re
 // estrutura válida com zeros (percentuais = 0), nunca erro.
 func (app *application) AdminAnalyticsFinanceiroGovernancaInstitucional(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	filters := parseGovernancaInstitucionalFilters(r.URL.Query())
 	args := filters.args()
@@ -235,7 +235,7 @@ func (app *application) scanCategoricRows(rows interface {
 
 func (app *application) AdminAnalyticsInfraCondicoes(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := InfraCondicoes{
 		PorTipoPredio:        []CategoricStat{},
@@ -358,7 +358,7 @@ func (app *application) AdminAnalyticsInfraCondicoes(w http.ResponseWriter, r *h
 
 func (app *application) AdminAnalyticsInfraSeguranca(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := InfraSeguranca{
 		DistCameras:           []CategoricStat{},
@@ -467,7 +467,7 @@ func (app *application) AdminAnalyticsInfraSeguranca(w http.ResponseWriter, r *h
 
 func (app *application) AdminAnalyticsInfraEnergia(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := InfraEnergia{
 		DistRedeEletrica:      []CategoricStat{},
@@ -579,7 +579,7 @@ func (app *application) AdminAnalyticsInfraEnergia(w http.ResponseWriter, r *htt
 
 func (app *application) AdminAnalyticsMerendaOferta(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := MerendaOferta{
 		DistOfertaRegular:      []CategoricStat{},
@@ -666,7 +666,7 @@ func (app *application) AdminAnalyticsMerendaOferta(w http.ResponseWriter, r *ht
 
 func (app *application) AdminAnalyticsMerendaEquipamentos(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := MerendaEquipamentos{
 		DistEstados:               []EstadoEquipStat{},
@@ -873,7 +873,7 @@ func (app *application) AdminAnalyticsMerendaEquipamentos(w http.ResponseWriter,
 
 func (app *application) AdminAnalyticsMerendaRH(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := MerendaRH{TopEmpresas: []EmpresaStat{}}
 
@@ -931,7 +931,7 @@ func (app *application) AdminAnalyticsMerendaRH(w http.ResponseWriter, r *http.R
 
 func (app *application) AdminAnalyticsMerendaCondicoesSanitarias(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := MerendaCondicoesSanitarias{
 		DistDespensaExclusiva:  []CategoricStat{},
@@ -1036,7 +1036,7 @@ func (app *application) AdminAnalyticsMerendaCondicoesSanitarias(w http.Response
 
 func (app *application) AdminAnalyticsServicosVisaoGeral(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := ServicosVisaoGeral{
 		PorArea:            []TerceirizacaoArea{},
@@ -1110,7 +1110,7 @@ func (app *application) AdminAnalyticsServicosVisaoGeral(w http.ResponseWriter,
 
 func (app *application) AdminAnalyticsServicosGerais(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := ServicosGerais{TopEmpresas: []EmpresaStat{}}
 
@@ -1174,7 +1174,7 @@ func (app *application) AdminAnalyticsServicosGerais(w http.ResponseWriter, r *h
 
 func (app *application) AdminAnalyticsServicosPortaria(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := ServicosPortaria{TopEmpresas: []EmpresaStat{}}
 
@@ -1225,7 +1225,7 @@ func (app *application) AdminAnalyticsServicosPortaria(w http.ResponseWriter, r
 
 func (app *application) AdminAnalyticsServicosManipuladoresAlimentos(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	out := ServicosManipuladoresAlimentos{
 		DistVinculo:           []CategoricStat{},
@@ -1420,7 +1420,7 @@ func (app *application) AdminAnalyticsInfraEscolas(w http.ResponseWriter, r *htt
 	direction := parseEscolasDirection(q.Get("direction"))
 
 	ctx := r.Context()
-	dbRows, err := app.models.Schools.DB.QueryContext(ctx, infraestruturaSelectSQL,
+	dbRows, err := app.analyticsDB(ctx).QueryContext(ctx, infraestruturaSelectSQL,
 		f.Year, f.DRE, f.Municipio, f.Zona, f.RegiaoIntegracao)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("infra escolas: %w", err), http.StatusInternalServerError)
@@ -1556,7 +1556,7 @@ type MerendaEscolasPayload struct {
 
 const merendaEscolasSelectSQL = `
 	SELECT
-		COALESCE(ri.regiao_de_integracao, '')                            AS regiao_integracao,
+		COALESCE(ri.regiao_integracao, '')                               AS regiao_integracao,
 		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado')              AS dre,
 		COALESCE(NULLIF(TRIM(s.municipio), ''), 'Não informado')        AS municipio,
 		COALESCE(NULLIF(TRIM(s.zona), ''), '')                          AS zona,
@@ -1572,17 +1572,17 @@ const merendaEscolasSelectSQL = `
 		COALESCE(cr.data->>'qtd_fogoes', '')                            AS qtd_fogoes,
 		COALESCE(cr.data->>'qtd_fornos', '')                            AS qtd_fornos,
 		COALESCE(NULLIF(cr.data->>'empresa_terceirizada_merenda', ''), '') AS empresa_terceirizada_merenda
-	FROM schools s
-	LEFT JOIN census_responses cr
-		ON cr.school_id = s.id AND cr.year = $1 AND cr.status = 'completed'
-	LEFT JOIN reg_integracao ri ON UPPER(TRIM(ri.municipio)) = UPPER(TRIM(s.municipio))
+	FROM vw_censo_escolas s
+	LEFT JOIN vw_censo_fonte cr
+		ON cr.school_id = s.id AND cr.year = $1 AND censo_enviado(cr.status)
+	LEFT JOIN municipios ri ON ri.id = s.municipio_id
 	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	ORDER BY UPPER(TRIM(s.dre)), UPPER(TRIM(s.municipio)), UPPER(TRIM(s.nome_escola)), s.codigo_inep
 `
@@ -1625,7 +1625,7 @@ func (app *application) AdminAnalyticsMerendaEscolas(w http.ResponseWriter, r *h
 	direction := parseEscolasDirection(q.Get("direction"))
 
 	ctx := r.Context()
-	dbRows, err := app.models.Schools.DB.QueryContext(ctx, merendaEscolasSelectSQL,
+	dbRows, err := app.analyticsDB(ctx).QueryContext(ctx, merendaEscolasSelectSQL,
 		f.Year, f.DRE, f.Municipio, f.Zona, f.RegiaoIntegracao)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("merenda escolas: %w", err), http.StatusInternalServerError)
@@ -1739,7 +1739,7 @@ type ServicosEscolasPayload struct {
 
 const servicosEscolasSelectSQL = `
 	SELECT
-		COALESCE(ri.regiao_de_integracao, '')                                 AS regiao_integracao,
+		COALESCE(ri.regiao_integracao, '')                                    AS regiao_integracao,
 		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado')                   AS dre,
 		COALESCE(NULLIF(TRIM(s.municipio), ''), 'Não informado')              AS municipio,
 		COALESCE(NULLIF(TRIM(s.zona), ''), '')                                AS zona,
@@ -1752,17 +1752,17 @@ const servicosEscolasSelectSQL = `
 		COALESCE(NULLIF(cr.data->>'empresa_terceirizada_merenda', ''), '')    AS empresa_terceirizada_merenda,
 		COALESCE(NULLIF(cr.data->>'avaliacao_portaria', ''), '')              AS avaliacao_portaria,
 		COALESCE(NULLIF(cr.data->>'avaliacao_limpeza', ''), '')               AS avaliacao_limpeza
-	FROM schools s
-	LEFT JOIN census_responses cr
-		ON cr.school_id = s.id AND cr.year = $1 AND cr.status = 'completed'
-	LEFT JOIN reg_integracao ri ON UPPER(TRIM(ri.municipio)) = UPPER(TRIM(s.municipio))
+	FROM vw_censo_escolas s
+	LEFT JOIN vw_censo_fonte cr
+		ON cr.school_id = s.id AND cr.year = $1 AND censo_enviado(cr.status)
+	LEFT JOIN municipios ri ON ri.id = s.municipio_id
 	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	ORDER BY UPPER(TRIM(s.dre)), UPPER(TRIM(s.municipio)), UPPER(TRIM(s.nome_escola)), s.codigo_inep
 `
@@ -1806,7 +1806,7 @@ func (app *application) AdminAnalyticsServicosTerceirizadosEscolas(w http.Respon
 	direction := parseEscolasDirection(q.Get("direction"))
 
 	ctx := r.Context()
-	dbRows, err := app.models.Schools.DB.QueryContext(ctx, servicosEscolasSelectSQL,
+	dbRows, err := app.analyticsDB(ctx).QueryContext(ctx, servicosEscolasSelectSQL,
 		f.Year, f.DRE, f.Municipio, f.Zona, f.RegiaoIntegracao)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("servicos escolas: %w", err), http.StatusInternalServerError)
@@ -176,15 +176,15 @@ func parseIdebFilters(q url.Values) (idebFilters, error) {
 // $7=status_ideb $8=detalhe_status_ideb $9=status_vinculo $10=somente_com_ideb.
 const idebFromWhere = `
 	FROM ideb_resultados ir
-	LEFT JOIN schools s ON s.id = ir.school_id
+	LEFT JOIN vw_censo_escolas s ON s.id = ir.school_id
 	WHERE ir.ano = $1
 	  AND ($2 = '' OR ir.etapa = $2)
 	  AND ($3 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($4)))
 	  AND ($5 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($5)))
-	  AND ($6 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio)) FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($6))))
+	  AND ($6 = '' OR s.municipio_id IN (
+	        SELECT id FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($6)))
 	  AND ($7 = '' OR ir.status_ideb = $7)
 	  AND ($8 = '' OR ir.detalhe_status_ideb = $8)
 	  AND ($9 = '' OR ir.status_vinculo = $9)
@@ -448,7 +448,7 @@ func (app *application) AdminAnalyticsPerfilAlunosResultadosIDEB(w http.Response
 }
 
 func (app *application) idebResumo(ctx context.Context, f idebFilters, res *IdebResumo) error {
-	db := app.models.Schools.DB
+	db := app.analyticsDB(ctx)
 	var (
 		mediaSimples sql.NullFloat64
 		somaProduto  float64
@@ -489,7 +489,7 @@ func (app *application) idebResumo(ctx context.Context, f idebFilters, res *Ideb
 }
 
 func (app *application) idebPorEtapa(ctx context.Context, f idebFilters) ([]IdebPorEtapa, error) {
-	db := app.models.Schools.DB
+	db := app.analyticsDB(ctx)
 	rows, err := db.QueryContext(ctx, `
 		SELECT
 			ir.etapa,
@@ -564,7 +564,7 @@ func (app *application) idebPorEtapa(ctx context.Context, f idebFilters) ([]Ideb
 // garantindo que a regra de faixas (incl. "Sem IDEB divulgado" para NULL) seja a
 // mesma testada por unidade. O percentual é relativo ao total da etapa.
 func (app *application) idebDistribuicaoFaixas(ctx context.Context, f idebFilters) ([]IdebFaixaItem, error) {
-	db := app.models.Schools.DB
+	db := app.analyticsDB(ctx)
 	rows, err := db.QueryContext(ctx, `SELECT ir.etapa, ir.ideb `+idebFromWhere, f.args()...)
 	if err != nil {
 		return nil, err
@@ -632,7 +632,7 @@ func sortEtapas(etapas []string) {
 // (school_id IS NOT NULL), pois recortes territoriais dependem de schools. São
 // agregações calculadas pelo dashboard, não IDEB oficial agregado do INEP.
 func (app *application) idebPorDre(ctx context.Context, f idebFilters) ([]IdebPorDre, error) {
-	db := app.models.Schools.DB
+	db := app.analyticsDB(ctx)
 	rows, err := db.QueryContext(ctx, `
 		SELECT
 			COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado') AS dre,
@@ -708,7 +708,7 @@ func (app *application) idebRankings(ctx context.Context, f idebFilters) (IdebRa
 // por etapa), nunca misturando etapas. orderBy e extraPredicate são expressões
 // controladas pelo servidor (constantes), não entrada de usuário.
 func (app *application) idebRankingQuery(ctx context.Context, f idebFilters, extraPredicate, orderBy string, limit int) ([]IdebRankingItem, error) {
-	db := app.models.Schools.DB
+	db := app.analyticsDB(ctx)
 	query := fmt.Sprintf(`
 		SELECT codigo_inep, nome_escola_origem, etapa, ideb, total_avaliado,
 		       percentual_avaliado, dre, municipio, status_ideb, status_vinculo
@@ -764,7 +764,7 @@ func (app *application) idebRankingQuery(ctx context.Context, f idebFilters, ext
 }
 
 func (app *application) idebQualidade(ctx context.Context, f idebFilters, q *IdebQualidade) error {
-	db := app.models.Schools.DB
+	db := app.analyticsDB(ctx)
 	err := db.QueryRowContext(ctx, `
 		SELECT
 			COUNT(*) FILTER (WHERE ir.detalhe_status_ideb = 'nd_proficiencia'),
@@ -796,7 +796,7 @@ func (app *application) idebQualidade(ctx context.Context, f idebFilters, q *Ide
 }
 
 func (app *application) idebMetadados(ctx context.Context, f idebFilters) (IdebMetadados, error) {
-	db := app.models.Schools.DB
+	db := app.analyticsDB(ctx)
 	var fonteArquivo sql.NullString
 	var batchID sql.NullString
 	err := db.QueryRowContext(ctx, `
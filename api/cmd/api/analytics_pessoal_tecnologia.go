@@ -30,7 +30,7 @@ type PessoalCoordenacao struct {
 // Suporta filtros: ?year=&dre=&municipio=&zona=&porte_escola=
 func (app *application) AdminAnalyticsPessoalEstrutura(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	// Captura de filtros da Query String
 	qs := r.URL.Query()
@@ -58,13 +58,13 @@ func (app *application) AdminAnalyticsPessoalEstrutura(w http.ResponseWriter, r
 	const baseQuery = `
 		FROM vw_censo_direcao_escolar v
 		JOIN vw_censo_enriquecida e ON e.census_id = v.census_id
-		WHERE v.status = 'completed'
+		WHERE censo_enviado(v.status)
 		  AND v.year = $1
 		  AND ($2 = '' OR v.dre = $2)
 		  AND ($3 = '' OR v.municipio = $3)
 		  AND ($4 = '' OR v.zona = $4)
 		  AND ($5 = '' OR e.porte_escola_nome = $5)
-		  AND ($6 = '' OR v.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $6))
+		  AND ($6 = '' OR censo_municipio_id(v.municipio) IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($6)))
 	`
 
 	// 1) Composição da Gestão (% de Sim por cargo)
@@ -110,15 +110,15 @@ func (app *application) AdminAnalyticsPessoalEstrutura(w http.ResponseWriter, r
 				END
 			), 0)::float8
 		FROM vw_censo_base b
-		JOIN census_responses cr ON cr.id = b.census_id
+		JOIN vw_censo_fonte cr ON cr.id = b.census_id
 		JOIN vw_censo_enriquecida e ON e.census_id = b.census_id
-		WHERE b.status = 'completed'
+		WHERE censo_enviado(b.status)
 		  AND b.year = $1
 		  AND ($2 = '' OR b.dre = $2)
 		  AND ($3 = '' OR b.municipio = $3)
 		  AND ($4 = '' OR b.zona = $4)
 		  AND ($5 = '' OR e.porte_escola_nome = $5)
-		  AND ($6 = '' OR b.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $6))
+		  AND ($6 = '' OR censo_municipio_id(b.municipio) IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($6)))
 	`, year, dre, municipio, zona, porte, regiaoIntegracao).Scan(&out.TotalCoordenadoresPedagog)
 
 	if err != nil {
@@ -133,7 +133,7 @@ func (app *application) AdminAnalyticsPessoalEstrutura(w http.ResponseWriter, r
 // Baseado na view vw_censo_coordenacao_area (Migration 0004).
 func (app *application) AdminAnalyticsPessoalCoordenacao(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	// Captura de filtros da Query String
 	qs := r.URL.Query()
@@ -160,13 +160,13 @@ func (app *application) AdminAnalyticsPessoalCoordenacao(w http.ResponseWriter,
 	const baseQuery = `
 		FROM vw_censo_coordenacao_area v
 		JOIN vw_censo_enriquecida e ON e.census_id = v.census_id
-		WHERE v.status = 'completed'
+		WHERE censo_enviado(v.status)
 		  AND v.year = $1
 		  AND ($2 = '' OR v.dre = $2)
 		  AND ($3 = '' OR v.municipio = $3)
 		  AND ($4 = '' OR v.zona = $4)
 		  AND ($5 = '' OR e.porte_escola_nome = $5)
-		  AND ($6 = '' OR v.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $6))
+		  AND ($6 = '' OR censo_municipio_id(v.municipio) IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($6)))
 	`
 
 	// 1) Distribuição por Área (% de Sim por área)
@@ -251,7 +251,7 @@ type QuadroPessoal struct {
 // Suporta filtros: ?year=&dre=&municipio=&zona=&porte_escola=
 func (app *application) AdminAnalyticsPessoalQuadro(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	qs := r.URL.Query()
 	yearStr := qs.Get("year")
@@ -273,13 +273,13 @@ func (app *application) AdminAnalyticsPessoalQuadro(w http.ResponseWriter, r *ht
 	const baseWhere = `
 		FROM vw_censo_quadro_pessoal v
 		JOIN vw_censo_enriquecida e ON e.census_id = v.census_id
-		WHERE v.status = 'completed'
+		WHERE censo_enviado(v.status)
 		  AND v.year = $1
 		  AND ($2 = '' OR v.dre = $2)
 		  AND ($3 = '' OR v.municipio = $3)
 		  AND ($4 = '' OR v.zona = $4)
 		  AND ($5 = '' OR e.porte_escola_nome = $5)
-		  AND ($6 = '' OR v.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $6))
+		  AND ($6 = '' OR censo_municipio_id(v.municipio) IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($6)))
 	`
 
 	// 1) Totais e médias globais
@@ -382,7 +382,7 @@ type TecnologiaUso struct {
 // Suporta filtros: ?year=&dre=&municipio=&zona=&porte_escola=
 func (app *application) AdminAnalyticsTecnologiaInfra(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	qs := r.URL.Query()
 	yearStr := qs.Get("year")
@@ -410,13 +410,13 @@ func (app *application) AdminAnalyticsTecnologiaInfra(w http.ResponseWriter, r *
 	const baseWhere = `
 		FROM vw_censo_equipamentos_tecnologia v
 		JOIN vw_censo_enriquecida e ON e.census_id = v.census_id
-		WHERE v.status = 'completed'
+		WHERE censo_enviado(v.status)
 		  AND v.year = $1
 		  AND ($2 = '' OR v.dre = $2)
 		  AND ($3 = '' OR v.municipio = $3)
 		  AND ($4 = '' OR v.zona = $4)
 		  AND ($5 = '' OR e.porte_escola_nome = $5)
-		  AND ($6 = '' OR v.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $6))
+		  AND ($6 = '' OR censo_municipio_id(v.municipio) IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($6)))
 	`
 
 	// 1) Totais de internet e equipamentos (inclui total absoluto de inoperantes)
@@ -585,7 +585,7 @@ func (app *application) AdminAnalyticsTecnologiaInfra(w http.ResponseWriter, r *
 // Suporta filtros: ?year=&dre=&municipio=&zona=&porte_escola=
 func (app *application) AdminAnalyticsTecnologiaUso(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	db := app.models.Schools.DB
+	db := app.analyticsDB(r.Context())
 
 	qs := r.URL.Query()
 	yearStr := qs.Get("year")
@@ -610,13 +610,13 @@ func (app *application) AdminAnalyticsTecnologiaUso(w http.ResponseWriter, r *ht
 	const baseWhere = `
 		FROM vw_censo_equipamentos_tecnologia v
 		JOIN vw_censo_enriquecida e ON e.census_id = v.census_id
-		WHERE v.status = 'completed'
+		WHERE censo_enviado(v.status)
 		  AND v.year = $1
 		  AND ($2 = '' OR v.dre = $2)
 		  AND ($3 = '' OR v.municipio = $3)
 		  AND ($4 = '' OR v.zona = $4)
 		  AND ($5 = '' OR e.porte_escola_nome = $5)
-		  AND ($6 = '' OR v.municipio IN (SELECT municipio FROM reg_integracao WHERE regiao_de_integracao = $6))
+		  AND ($6 = '' OR censo_municipio_id(v.municipio) IN (SELECT id FROM municipios WHERE censo_chave(regiao_integracao) = censo_chave($6)))
 	`
 
 	// 1) KPIs de projetor/lousa e média de projetores por escola.
@@ -722,7 +722,7 @@ type PessoalEscolasPayload struct {
 
 const pessoalEscolasSelectSQL = `
 	SELECT
-		COALESCE(ri.regiao_de_integracao, '')                                 AS regiao_integracao,
+		COALESCE(ri.regiao_integracao, '')                                    AS regiao_integracao,
 		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado')                   AS dre,
 		COALESCE(NULLIF(TRIM(s.municipio), ''), 'Não informado')              AS municipio,
 		COALESCE(NULLIF(TRIM(s.zona), ''), '')                                AS zona,
@@ -735,17 +735,17 @@ const pessoalEscolasSelectSQL = `
 		COALESCE(cr.data->>'qtd_professores_efetivos', '')                    AS qtd_professores_efetivos,
 		COALESCE(cr.data->>'qtd_professores_temporarios', '')                 AS qtd_professores_temporarios,
 		COALESCE(cr.data->>'qtd_servidores_administrativos', '')              AS qtd_servidores_administrativos
-	FROM schools s
-	LEFT JOIN census_responses cr
-		ON cr.school_id = s.id AND cr.year = $1 AND cr.status = 'completed'
-	LEFT JOIN reg_integracao ri ON UPPER(TRIM(ri.municipio)) = UPPER(TRIM(s.municipio))
+	FROM vw_censo_escolas s
+	LEFT JOIN vw_censo_fonte cr
+		ON cr.school_id = s.id AND cr.year = $1 AND censo_enviado(cr.status)
+	LEFT JOIN municipios ri ON ri.id = s.municipio_id
 	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	ORDER BY UPPER(TRIM(s.dre)), UPPER(TRIM(s.municipio)), UPPER(TRIM(s.nome_escola)), s.codigo_inep
 `
@@ -786,7 +786,7 @@ func (app *application) AdminAnalyticsPessoalEscolas(w http.ResponseWriter, r *h
 	direction := parseEscolasDirection(q.Get("direction"))
 
 	ctx := r.Context()
-	dbRows, err := app.models.Schools.DB.QueryContext(ctx, pessoalEscolasSelectSQL,
+	dbRows, err := app.analyticsDB(ctx).QueryContext(ctx, pessoalEscolasSelectSQL,
 		f.Year, f.DRE, f.Municipio, f.Zona, f.RegiaoIntegracao)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("pessoal escolas: %w", err), http.StatusInternalServerError)
@@ -901,7 +901,7 @@ type TecnologiaEscolasPayload struct {
 
 const tecnologiaEscolasSelectSQL = `
 	SELECT
-		COALESCE(ri.regiao_de_integracao, '')                            AS regiao_integracao,
+		COALESCE(ri.regiao_integracao, '')                               AS regiao_integracao,
 		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado')              AS dre,
 		COALESCE(NULLIF(TRIM(s.municipio), ''), 'Não informado')        AS municipio,
 		COALESCE(NULLIF(TRIM(s.zona), ''), '')                          AS zona,
@@ -916,17 +916,17 @@ const tecnologiaEscolasSelectSQL = `
 		COALESCE(cr.data->>'qtd_chromebooks', '')                       AS qtd_chromebooks,
 		COALESCE(NULLIF(cr.data->>'possui_projetor', ''), '')           AS possui_projetor,
 		COALESCE(NULLIF(cr.data->>'possui_lousa_digital', ''), '')      AS possui_lousa_digital
-	FROM schools s
-	LEFT JOIN census_responses cr
-		ON cr.school_id = s.id AND cr.year = $1 AND cr.status = 'completed'
-	LEFT JOIN reg_integracao ri ON UPPER(TRIM(ri.municipio)) = UPPER(TRIM(s.municipio))
+	FROM vw_censo_escolas s
+	LEFT JOIN vw_censo_fonte cr
+		ON cr.school_id = s.id AND cr.year = $1 AND censo_enviado(cr.status)
+	LEFT JOIN municipios ri ON ri.id = s.municipio_id
 	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	ORDER BY UPPER(TRIM(s.dre)), UPPER(TRIM(s.municipio)), UPPER(TRIM(s.nome_escola)), s.codigo_inep
 `
@@ -969,7 +969,7 @@ func (app *application) AdminAnalyticsTecnologiaEscolas(w http.ResponseWriter, r
 	direction := parseEscolasDirection(q.Get("direction"))
 
 	ctx := r.Context()
-	dbRows, err := app.models.Schools.DB.QueryContext(ctx, tecnologiaEscolasSelectSQL,
+	dbRows, err := app.analyticsDB(ctx).QueryContext(ctx, tecnologiaEscolasSelectSQL,
 		f.Year, f.DRE, f.Municipio, f.Zona, f.RegiaoIntegracao)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("tecnologia escolas: %w", err), http.StatusInternalServerError)
@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"censo-api/internal/models"
 )
 
 // PreenchimentoDreRow descreve o andamento do preenchimento do censo de uma DRE
@@ -20,17 +22,46 @@ type PreenchimentoDreRow struct {
 	Draft                int    `json:"draft"`
 	Pending              int    `json:"pending"`
 	CompletionPercentage int    `json:"completion_percentage"`
+	// Prazo e DiasRestantes vêm da campanha do ano (com a prorrogação da
+	// DRE); Overdue conta as escolas sem censo enviado depois do prazo.
+	Prazo         string `json:"prazo,omitempty"`
+	DiasRestantes *int   `json:"dias_restantes,omitempty"`
+	Overdue       int    `json:"overdue"`
 }
 
 // PreenchimentoDrePayload é a resposta do endpoint de andamento por DRE. Os
 // totais consolidam todas as DREs do recorte. ano_referencia ecoa o ano usado.
 type PreenchimentoDrePayload struct {
-	AnoReferencia  int                   `json:"ano_referencia"`
-	TotalEscolas   int                   `json:"total_escolas"`
-	TotalCompleted int                   `json:"total_completed"`
-	TotalDraft     int                   `json:"total_draft"`
-	TotalPending   int                   `json:"total_pending"`
-	DREs           []PreenchimentoDreRow `json:"dres"`
+	AnoReferencia  int                    `json:"ano_referencia"`
+	TotalEscolas   int                    `json:"total_escolas"`
+	TotalCompleted int                    `json:"total_completed"`
+	TotalDraft     int                    `json:"total_draft"`
+	TotalPending   int                    `json:"total_pending"`
+	TotalOverdue   int                    `json:"total_overdue"`
+	Campanha       *preenchimentoCampanha `json:"campanha"`
+	DREs           []PreenchimentoDreRow  `json:"dres"`
+}
+
+// preenchimentoCampanha é a campanha do ano de referência com a situação
+// de hoje pelo encerramento geral (sem prorrogações). Nula quando o ano
+// não tem campanha.
+type preenchimentoCampanha struct {
+	*models.CensusCampaign
+	campaignWindow
+}
+
+// applyCampaignToRow preenche prazo, dias restantes e atrasadas da linha a
+// partir da campanha c (nil deixa a linha como está).
+func applyCampaignToRow(row *PreenchimentoDreRow, c *models.CensusCampaign, now time.Time) {
+	if c == nil {
+		return
+	}
+	w := windowFor(c, row.DRE, now)
+	row.Prazo = w.Prazo
+	row.DiasRestantes = w.DiasRestantes
+	if w.Situacao == campaignClosed {
+		row.Overdue = row.Total - row.Completed
+	}
 }
 
 // preenchimentoDreFilters reúne os filtros globais do dashboard aplicados sobre
@@ -72,32 +103,35 @@ func parsePreenchimentoDreFilters(q url.Values, now time.Time) preenchimentoDreF
 // salvaguarda defensiva caso isso mude.
 //
 // Os filtros globais incidem sobre schools s e por isso este endpoint NÃO
-// reutiliza AnalyticsFilters.WhereSQL(), que exige status = 'completed' AND
+// reutiliza AnalyticsFilters.WhereSQL(), que exige censo_enviado(status) AND
 // census_id IS NOT NULL — o que excluiria rascunhos e pendentes que precisamos
 // contar. A comparação usa UPPER(TRIM(...)) para tolerar caixa e espaços.
+//
+// Escolas desativadas (schools.active) só entram se enviaram o censo do ano.
 const preenchimentoDreSelectSQL = `
 	WITH latest_census AS (
 		SELECT DISTINCT ON (school_id)
 			school_id,
 			status
-		FROM census_responses
+		FROM vw_censo_fonte
 		WHERE year = $1
 		ORDER BY school_id, updated_at DESC, id DESC
 	)
 	SELECT
 		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado') AS dre,
 		COUNT(*) AS total,
-		COUNT(*) FILTER (WHERE cr.status = 'completed') AS completed,
+		COUNT(*) FILTER (WHERE censo_enviado(cr.status)) AS completed,
 		COUNT(*) FILTER (WHERE cr.status = 'draft') AS draft
-	FROM schools s
+	FROM vw_censo_escolas s
 	LEFT JOIN latest_census cr ON cr.school_id = s.id
-	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
+	WHERE (s.active OR censo_enviado(cr.status))
+	  AND ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	GROUP BY COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado')
 	ORDER BY dre
@@ -147,10 +181,17 @@ func buildPreenchimentoDreRow(dre string, total, completed, draft int) Preenchim
 // por DRE, respeitando os filtros globais (year, dre, municipio, zona,
 // regiao_integracao). Recorte vazio devolve payload válido com totais zerados.
 func (app *application) AdminAnalyticsPreenchimentoDre(w http.ResponseWriter, r *http.Request) {
-	filters := parsePreenchimentoDreFilters(r.URL.Query(), time.Now())
+	now := time.Now()
+	filters := parsePreenchimentoDreFilters(r.URL.Query(), now)
 	query, args := buildPreenchimentoDreQuery(filters)
 
-	rows, err := app.models.Schools.DB.QueryContext(r.Context(), query, args...)
+	campaign, err := app.models.CensusCampaigns.Get(r.Context(), filters.Year)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("consultar campanha do censo: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := app.analyticsDB(r.Context()).QueryContext(r.Context(), query, args...)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("consultar preenchimento por DRE: %v", err), http.StatusInternalServerError)
 		return
@@ -161,6 +202,9 @@ func (app *application) AdminAnalyticsPreenchimentoDre(w http.ResponseWriter, r
 		AnoReferencia: filters.Year,
 		DREs:          make([]PreenchimentoDreRow, 0),
 	}
+	if campaign != nil {
+		payload.Campanha = &preenchimentoCampanha{campaign, windowFor(campaign, "", now)}
+	}
 
 	for rows.Next() {
 		var dre string
@@ -170,7 +214,9 @@ func (app *application) AdminAnalyticsPreenchimentoDre(w http.ResponseWriter, r
 			return
 		}
 		row := buildPreenchimentoDreRow(dre, total, completed, draft)
+		applyCampaignToRow(&row, campaign, now)
 		payload.DREs = append(payload.DREs, row)
+		payload.TotalOverdue += row.Overdue
 		payload.TotalEscolas += row.Total
 		payload.TotalCompleted += row.Completed
 		payload.TotalDraft += row.Draft
@@ -183,3 +229,105 @@ func (app *application) AdminAnalyticsPreenchimentoDre(w http.ResponseWriter, r
 
 	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: payload})
 }
+
+// PreenchimentoAtrasadaRow é uma escola sem censo enviado depois do prazo
+// da campanha para a sua DRE.
+type PreenchimentoAtrasadaRow struct {
+	SchoolID   int    `json:"school_id"`
+	NomeEscola string `json:"nome_escola"`
+	CodigoINEP string `json:"codigo_inep"`
+	DRE        string `json:"dre"`
+	Municipio  string `json:"municipio"`
+	// Status do censo no ano ("" quando a escola nem começou).
+	Status     string `json:"status"`
+	Prazo      string `json:"prazo"`
+	DiasAtraso int    `json:"dias_atraso"`
+}
+
+// preenchimentoAtrasadasSQL lista as escolas do recorte sem censo enviado
+// no ano ($1), com os mesmos filtros globais ($2..$5) de
+// preenchimentoDreSelectSQL. O prazo por DRE é aplicado em Go
+// (prorrogações comparadas com sameDRE). Escolas desativadas não atrasam.
+const preenchimentoAtrasadasSQL = `
+	WITH latest_census AS (
+		SELECT DISTINCT ON (school_id)
+			school_id,
+			status
+		FROM vw_censo_fonte
+		WHERE year = $1
+		ORDER BY school_id, updated_at DESC, id DESC
+	)
+	SELECT
+		s.id,
+		COALESCE(s.nome_escola, ''),
+		COALESCE(s.codigo_inep, ''),
+		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado') AS dre,
+		COALESCE(s.municipio, ''),
+		COALESCE(cr.status, '')
+	FROM vw_censo_escolas s
+	LEFT JOIN latest_census cr ON cr.school_id = s.id
+	WHERE s.active
+	  AND (cr.status IS NULL OR NOT censo_enviado(cr.status))
+	  AND ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
+	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
+	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
+	      ))
+	ORDER BY dre, s.nome_escola, s.id
+`
+
+// AdminAnalyticsPreenchimentoAtrasadas lista as escolas atrasadas na
+// campanha do ano de referência: sem censo enviado e com o prazo da DRE
+// vencido. Ano sem campanha devolve lista vazia e campanha nula.
+func (app *application) AdminAnalyticsPreenchimentoAtrasadas(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	filters := parsePreenchimentoDreFilters(r.URL.Query(), now)
+	_, args := buildPreenchimentoDreQuery(filters)
+
+	campaign, err := app.models.CensusCampaigns.Get(r.Context(), filters.Year)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("consultar campanha do censo: %v", err), http.StatusInternalServerError)
+		return
+	}
+	payload := struct {
+		AnoReferencia int                        `json:"ano_referencia"`
+		Campanha      *preenchimentoCampanha     `json:"campanha"`
+		Escolas       []PreenchimentoAtrasadaRow `json:"escolas"`
+	}{AnoReferencia: filters.Year, Escolas: make([]PreenchimentoAtrasadaRow, 0)}
+	if campaign == nil {
+		app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: payload})
+		return
+	}
+	payload.Campanha = &preenchimentoCampanha{campaign, windowFor(campaign, "", now)}
+
+	rows, err := app.analyticsDB(r.Context()).QueryContext(r.Context(), preenchimentoAtrasadasSQL, args...)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("consultar escolas atrasadas: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row PreenchimentoAtrasadaRow
+		if err := rows.Scan(&row.SchoolID, &row.NomeEscola, &row.CodigoINEP, &row.DRE, &row.Municipio, &row.Status); err != nil {
+			app.errorJSON(w, fmt.Errorf("ler escola atrasada: %v", err), http.StatusInternalServerError)
+			return
+		}
+		win := windowFor(campaign, row.DRE, now)
+		if win.Situacao != campaignClosed {
+			continue
+		}
+		row.Prazo = win.Prazo
+		row.DiasAtraso = -*win.DiasRestantes
+		payload.Escolas = append(payload.Escolas, row)
+	}
+	if err := rows.Err(); err != nil {
+		app.errorJSON(w, fmt.Errorf("iterar escolas atrasadas: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: payload})
+}
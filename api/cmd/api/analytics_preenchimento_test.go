@@ -86,7 +86,7 @@ func TestPreenchimentoParseFiltersStrings(t *testing.T) {
 
 // TestPreenchimentoBuildQueryArgs garante que cada filtro global é posicionado
 // no argumento correto ($1=year, $2=dre, $3=municipio, $4=zona,
-// $5=regiao_integracao) e combinado por AND sobre schools s.
+// $5=regiao_integracao) e combinado por AND sobre vw_censo_escolas s.
 func TestPreenchimentoBuildQueryArgs(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -149,8 +149,8 @@ func TestPreenchimentoBuildQueryArgs(t *testing.T) {
 	}
 }
 
-// TestPreenchimentoQueryShape valida o formato da query: parte de schools s, usa
-// LEFT JOIN, limita census_responses ao ano, conta completed e draft, agrupa por
+// TestPreenchimentoQueryShape valida o formato da query: parte de vw_censo_escolas s, usa
+// LEFT JOIN, limita vw_censo_fonte ao ano, conta completed e draft, agrupa por
 // DRE com fallback "Não informado", combina filtros por AND e usa DISTINCT ON
 // como salvaguarda contra múltiplos censos por escola/ano. Não pode exigir
 // status='completed' no WHERE geral nem census_id IS NOT NULL.
@@ -158,19 +158,19 @@ func TestPreenchimentoQueryShape(t *testing.T) {
 	query := preenchimentoDreSelectSQL
 
 	mustContain := []string{
-		"FROM schools s",
+		"FROM vw_censo_escolas s",
 		"LEFT JOIN latest_census cr",
-		"FROM census_responses",
+		"FROM vw_censo_fonte",
 		"WHERE year = $1",
 		"DISTINCT ON (school_id)",
-		"FILTER (WHERE cr.status = 'completed')",
+		"FILTER (WHERE censo_enviado(cr.status))",
 		"FILTER (WHERE cr.status = 'draft')",
 		"COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado')",
 		"($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))",
 		"($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))",
 		"($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))",
-		"FROM reg_integracao",
-		"UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))",
+		"s.municipio_id IN (",
+		"censo_chave(regiao_integracao) = censo_chave($5)",
 	}
 	for _, fragment := range mustContain {
 		if !strings.Contains(query, fragment) {
@@ -588,7 +588,7 @@ const saudeOperacionalPedagogicoSQL = `
 // sem IDEB válido não entram no mapa — o lookup de uma chave ausente devolve nil
 // naturalmente, preservando o padrão "sem dados" da dimensão.
 func (app *application) loadPedagogicoPorEscola(ctx context.Context) (map[int]*float64, error) {
-	rows, err := app.models.Schools.DB.QueryContext(ctx, saudeOperacionalPedagogicoSQL)
+	rows, err := app.analyticsDB(ctx).QueryContext(ctx, saudeOperacionalPedagogicoSQL)
 	if err != nil {
 		return nil, fmt.Errorf("consultar pedagógico/IDEB por escola: %w", err)
 	}
@@ -1188,12 +1188,12 @@ const saudeOperacionalDataProjectionSQL = `jsonb_build_object(
 // que total_escolas, resumo e paginação reflitam o recorte global e que as
 // escolas pendentes de censo permaneçam visíveis dentro dele. Por isso este
 // endpoint NÃO reutiliza AnalyticsFilters.WhereSQL(), que exige
-// status = 'completed' AND census_id IS NOT NULL e excluiria os pendentes.
+// censo_enviado(status) AND census_id IS NOT NULL e excluiria os pendentes.
 //
 // A comparação usa UPPER(TRIM(...)) para tolerar caixa e espaços. O filtro de
-// Região de Integração depende da compatibilidade entre schools.municipio e
-// reg_integracao.municipio (sem unaccent nesta etapa): municípios com grafia
-// divergente de acentuação podem não casar.
+// Região de Integração usa o município de referência da escola
+// (schools.municipio_id, resolvido por censo_chave com acentos e aliases) e
+// compara a região também por censo_chave.
 const saudeOperacionalSelectSQL = `
 	SELECT
 		s.id,
@@ -1204,18 +1204,18 @@ const saudeOperacionalSelectSQL = `
 		s.zona,
 		cr.id,
 		CASE WHEN cr.id IS NULL THEN NULL ELSE ` + saudeOperacionalDataProjectionSQL + ` END AS data
-	FROM schools s
-	LEFT JOIN census_responses cr
+	FROM vw_censo_escolas s
+	LEFT JOIN vw_censo_fonte cr
 	  ON cr.school_id = s.id
 	 AND cr.year = $1
-	 AND cr.status = 'completed'
+	 AND censo_enviado(cr.status)
 	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR s.municipio IN (
-	        SELECT municipio
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	ORDER BY s.nome_escola, s.id
 `
@@ -1277,7 +1277,7 @@ func (app *application) buildSaudeOperacionalDataset(
 	query, args := buildSaudeOperacionalQuery(year, filters)
 
 	queryStart := time.Now()
-	dbRows, err := app.models.Schools.DB.QueryContext(ctx, query, args...)
+	dbRows, err := app.analyticsDB(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		app.logger.Printf("saude_operacional_perf_error: stage=query elapsed_ms=%d error=%q",
 			time.Since(queryStart).Milliseconds(), err.Error())
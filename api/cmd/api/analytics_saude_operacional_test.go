@@ -894,7 +894,7 @@ func TestSaudeOperacionalParseFilters(t *testing.T) {
 
 // TestSaudeOperacionalBuildQueryArgs garante que cada filtro global é
 // posicionado no argumento correto ($1=year, $2=dre, $3=municipio, $4=zona,
-// $5=regiao_integracao). Como a filtragem ocorre em SQL sobre schools s, a
+// $5=regiao_integracao). Como a filtragem ocorre em SQL sobre vw_censo_escolas s, a
 // presença do valor no argumento correto comprova que o filtro reduz o
 // universo carregado (que alimenta total_escolas, resumo e paginação).
 func TestSaudeOperacionalBuildQueryArgs(t *testing.T) {
@@ -965,21 +965,22 @@ func TestSaudeOperacionalBuildQueryArgs(t *testing.T) {
 }
 
 // TestSaudeOperacionalQueryShape valida que a query preserva o LEFT JOIN
-// (escolas sem censo continuam no resultado), aplica os filtros sobre schools s
-// e combina-os por AND. A Região de Integração usa subconsulta em reg_integracao.
+// (escolas sem censo continuam no resultado), aplica os filtros sobre vw_censo_escolas s
+// e combina-os por AND. A Região de Integração usa subconsulta em municipios
+// pelo schools.municipio_id.
 func TestSaudeOperacionalQueryShape(t *testing.T) {
 	query := saudeOperacionalSelectSQL
 
 	mustContain := []string{
-		"FROM schools s",
-		"LEFT JOIN census_responses cr",
+		"FROM vw_censo_escolas s",
+		"LEFT JOIN vw_censo_fonte cr",
 		"AND cr.year = $1",
-		"AND cr.status = 'completed'",
+		"AND censo_enviado(cr.status)",
 		"($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))",
 		"($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))",
 		"($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))",
-		"FROM reg_integracao",
-		"UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))",
+		"s.municipio_id IN (",
+		"censo_chave(regiao_integracao) = censo_chave($5)",
 	}
 	for _, fragment := range mustContain {
 		if !strings.Contains(query, fragment) {
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"censo-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// =====================================================================
+// Campanhas do censo
+// =====================================================================
+// census_campaigns define, por ano, a janela de preenchimento (datas
+// inclusivas no fuso de Belém), a versão do formulário e prorrogações do
+// encerramento por DRE.
+//
+//   - GET    /v1/census/campaign?year=&school_id=  (público) janela do ano,
+//     com o prazo da DRE da escola e os dias restantes.
+//   - GET    /v1/admin/campaigns                  campanhas cadastradas.
+//   - PUT    /v1/admin/campaigns/{year}           cria ou substitui (seduc_admin).
+//   - DELETE /v1/admin/campaigns/{year}           remove (seduc_admin).
+//
+// Com ao menos uma campanha cadastrada, as escritas do formulário (POST,
+// PATCH e pré-preenchimento do censo) só são aceitas para anos com
+// campanha e dentro da janela; fora dela respondem 403. Sem nenhuma
+// campanha, seguem livres como antes.
+// =====================================================================
+
+// campaignTZ é o fuso das datas de campanha (Pará, sem horário de verão).
+var campaignTZ = time.FixedZone("America/Belem", -3*60*60)
+
+// Situações de uma campanha em relação a hoje.
+const (
+	campaignNotConfigured = "nao_configurada"
+	campaignScheduled     = "agendada"
+	campaignOpen          = "aberta"
+	campaignClosed        = "encerrada"
+)
+
+const maxFormVersion = 32
+
+// parseCampaignDate lê uma data AAAA-MM-DD no fuso da campanha.
+func parseCampaignDate(s string) (time.Time, error) {
+	return time.ParseInLocation(models.CampaignDateLayout, strings.TrimSpace(s), campaignTZ)
+}
+
+// campaignDay reduz now ao dia corrente no fuso da campanha.
+func campaignDay(now time.Time) time.Time {
+	y, m, d := now.In(campaignTZ).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, campaignTZ)
+}
+
+// campaignDeadline devolve o último dia de preenchimento para a DRE: o
+// encerramento da campanha ou a prorrogação da DRE, se posterior.
+func campaignDeadline(c *models.CensusCampaign, dre string) string {
+	deadline := c.ClosesOn
+	for _, e := range c.Extensions {
+		if sameDRE(e.DRE, dre) && e.ClosesOn > deadline {
+			deadline = e.ClosesOn
+		}
+	}
+	return deadline
+}
+
+// campaignWindow é a situação da campanha para uma DRE num dado momento.
+type campaignWindow struct {
+	Situacao string `json:"situacao"`
+	Prazo    string `json:"prazo,omitempty"`
+	// DiasRestantes conta do dia corrente até o prazo (0 no último dia,
+	// negativo depois do encerramento: dias de atraso).
+	DiasRestantes *int `json:"dias_restantes,omitempty"`
+}
+
+// windowFor calcula a situação da campanha c para a DRE em now. c nil é
+// campanha não configurada.
+func windowFor(c *models.CensusCampaign, dre string, now time.Time) campaignWindow {
+	if c == nil {
+		return campaignWindow{Situacao: campaignNotConfigured}
+	}
+	today := campaignDay(now)
+	prazo := campaignDeadline(c, dre)
+	w := campaignWindow{Situacao: campaignOpen, Prazo: prazo}
+	if opens, err := parseCampaignDate(c.OpensOn); err == nil && today.Before(opens) {
+		w.Situacao = campaignScheduled
+	}
+	if deadline, err := parseCampaignDate(prazo); err == nil {
+		days := int(deadline.Sub(today).Hours() / 24)
+		w.DiasRestantes = &days
+		if days < 0 {
+			w.Situacao = campaignClosed
+		}
+	}
+	return w
+}
+
+// campaignWriteError diz por que uma escrita do censo de year está fora da
+// janela, ou nil se é aceita. configured indica se há alguma campanha
+// cadastrada; sem nenhuma, tudo é aceito.
+func campaignWriteError(c *models.CensusCampaign, configured bool, year int, dre string, now time.Time) error {
+	if !configured {
+		return nil
+	}
+	if c == nil {
+		return fmt.Errorf("não há campanha do censo aberta para %d", year)
+	}
+	w := windowFor(c, dre, now)
+	switch w.Situacao {
+	case campaignScheduled:
+		return fmt.Errorf("a campanha do censo %d abre em %s", year, formatCampaignDate(c.OpensOn))
+	case campaignClosed:
+		return fmt.Errorf("a campanha do censo %d encerrou em %s; procure a sua DRE", year, formatCampaignDate(w.Prazo))
+	}
+	return nil
+}
+
+// formatCampaignDate converte AAAA-MM-DD para DD/MM/AAAA nas mensagens.
+func formatCampaignDate(s string) string {
+	t, err := parseCampaignDate(s)
+	if err != nil {
+		return s
+	}
+	return t.Format("02/01/2006")
+}
+
+// campaignState carrega a campanha do ano e se há alguma cadastrada.
+func (app *application) campaignState(ctx context.Context, year int) (*models.CensusCampaign, bool, error) {
+	c, err := app.models.CensusCampaigns.Get(ctx, year)
+	if err != nil || c != nil {
+		return c, c != nil, err
+	}
+	configured, err := app.models.CensusCampaigns.Any(ctx)
+	return nil, configured, err
+}
+
+// requireCampaignOpen recusa (403) a escrita do formulário fora da janela
+// da campanha do ano, considerando a prorrogação da DRE da escola.
+func (app *application) requireCampaignOpen(w http.ResponseWriter, r *http.Request, schoolID, year int) bool {
+	c, configured, err := app.campaignState(r.Context(), year)
+	if err != nil {
+		app.logger.Printf("requireCampaignOpen: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar campanha do censo"), http.StatusInternalServerError)
+		return false
+	}
+	if !configured {
+		return true
+	}
+	dre := ""
+	if c != nil && len(c.Extensions) > 0 {
+		school, err := app.models.Schools.Get(schoolID)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("escola não encontrada"), http.StatusNotFound)
+			return false
+		}
+		dre = school.Dre
+	}
+	if err := campaignWriteError(c, configured, year, dre, time.Now()); err != nil {
+		app.errorJSON(w, err, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// campaignRequest é o corpo de PUT /v1/admin/campaigns/{year}.
+type campaignRequest struct {
+	OpensOn     string                           `json:"opens_on"`
+	ClosesOn    string                           `json:"closes_on"`
+	FormVersion string                           `json:"form_version"`
+	Extensions  []models.CensusCampaignExtension `json:"extensions"`
+}
+
+// campaign valida o pedido e monta a campanha do ano.
+func (req campaignRequest) campaign(year int) (*models.CensusCampaign, error) {
+	if year < 2000 || year > 2100 {
+		return nil, fmt.Errorf("ano inválido")
+	}
+	opens, err := parseCampaignDate(req.OpensOn)
+	if err != nil {
+		return nil, fmt.Errorf("opens_on inválido: use AAAA-MM-DD")
+	}
+	closes, err := parseCampaignDate(req.ClosesOn)
+	if err != nil {
+		return nil, fmt.Errorf("closes_on inválido: use AAAA-MM-DD")
+	}
+	if closes.Before(opens) {
+		return nil, fmt.Errorf("closes_on deve ser igual ou posterior a opens_on")
+	}
+	version := strings.TrimSpace(req.FormVersion)
+	if utf8.RuneCountInString(version) > maxFormVersion {
+		return nil, fmt.Errorf("form_version excede %d caracteres", maxFormVersion)
+	}
+
+	c := &models.CensusCampaign{
+		Year:        year,
+		OpensOn:     opens.Format(models.CampaignDateLayout),
+		ClosesOn:    closes.Format(models.CampaignDateLayout),
+		FormVersion: version,
+		Extensions:  []models.CensusCampaignExtension{},
+	}
+	seen := map[string]bool{}
+	for _, e := range req.Extensions {
+		dre := strings.TrimSpace(e.DRE)
+		if dre == "" {
+			return nil, fmt.Errorf("prorrogação sem dre")
+		}
+		if seen[normalizeDREKey(dre)] {
+			return nil, fmt.Errorf("prorrogação repetida para a DRE %s", dre)
+		}
+		seen[normalizeDREKey(dre)] = true
+		until, err := parseCampaignDate(e.ClosesOn)
+		if err != nil {
+			return nil, fmt.Errorf("closes_on da prorrogação de %s inválido: use AAAA-MM-DD", dre)
+		}
+		if !until.After(closes) {
+			return nil, fmt.Errorf("a prorrogação de %s deve terminar depois de %s", dre, c.ClosesOn)
+		}
+		c.Extensions = append(c.Extensions, models.CensusCampaignExtension{
+			DRE:      dre,
+			ClosesOn: until.Format(models.CampaignDateLayout),
+			Reason:   strings.TrimSpace(e.Reason),
+		})
+	}
+	return c, nil
+}
+
+func campaignYearParam(r *http.Request) (int, error) {
+	year, err := strconv.Atoi(chi.URLParam(r, "year"))
+	if err != nil || year <= 0 {
+		return 0, fmt.Errorf("ano inválido")
+	}
+	return year, nil
+}
+
+// AdminListCampaigns lista as campanhas com a situação de hoje.
+func (app *application) AdminListCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := app.models.CensusCampaigns.List(r.Context())
+	if err != nil {
+		app.logger.Printf("AdminListCampaigns: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar campanhas"), http.StatusInternalServerError)
+		return
+	}
+	type item struct {
+		*models.CensusCampaign
+		campaignWindow
+	}
+	now := time.Now()
+	out := make([]item, 0, len(campaigns))
+	for _, c := range campaigns {
+		out = append(out, item{c, windowFor(c, adminDREScope(r.Context()), now)})
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: out})
+}
+
+// AdminSaveCampaign cria ou substitui a campanha do ano.
+func (app *application) AdminSaveCampaign(w http.ResponseWriter, r *http.Request) {
+	year, err := campaignYearParam(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	var req campaignRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	c, err := req.campaign(year)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := app.models.CensusCampaigns.Save(r.Context(), c); err != nil {
+		app.logger.Printf("AdminSaveCampaign: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao gravar campanha"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "campanha gravada", Data: c})
+}
+
+// AdminDeleteCampaign remove a campanha do ano.
+func (app *application) AdminDeleteCampaign(w http.ResponseWriter, r *http.Request) {
+	year, err := campaignYearParam(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	err = app.models.CensusCampaigns.Delete(r.Context(), year)
+	if err == sql.ErrNoRows {
+		app.errorJSON(w, fmt.Errorf("campanha não encontrada"), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		app.logger.Printf("AdminDeleteCampaign: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao remover campanha"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "campanha removida"})
+}
+
+// GetCensoCampaign devolve a janela do censo do ano para o formulário, com
+// o prazo da DRE da escola quando school_id é informado.
+func (app *application) GetCensoCampaign(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil || year == 0 {
+		year = time.Now().Year()
+	}
+	c, configured, err := app.campaignState(r.Context(), year)
+	if err != nil {
+		app.logger.Printf("GetCensoCampaign: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar campanha do censo"), http.StatusInternalServerError)
+		return
+	}
+
+	dre := ""
+	if schoolID, err := strconv.Atoi(r.URL.Query().Get("school_id")); err == nil && schoolID > 0 && c != nil {
+		if school, err := app.models.Schools.Get(schoolID); err == nil {
+			dre = school.Dre
+		}
+	}
+	out := struct {
+		Year        int    `json:"year"`
+		Enforced    bool   `json:"enforced"`
+		OpensOn     string `json:"opens_on,omitempty"`
+		FormVersion string `json:"form_version,omitempty"`
+		campaignWindow
+	}{Year: year, Enforced: configured, campaignWindow: windowFor(c, dre, time.Now())}
+	if c != nil {
+		out.OpensOn = c.OpensOn
+		out.FormVersion = c.FormVersion
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: out})
+}
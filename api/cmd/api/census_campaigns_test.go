@@ -0,0 +1,119 @@
+package main
+
+// Testes das campanhas do censo. Sem banco: cobrem a situação da janela
+// (datas inclusivas no fuso de Belém, prorrogação por DRE), a recusa de
+// escritas fora dela e a validação do cadastro.
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"censo-api/internal/models"
+)
+
+func testCampaign() *models.CensusCampaign {
+	return &models.CensusCampaign{
+		Year: 2026, OpensOn: "2026-03-01", ClosesOn: "2026-04-30",
+		Extensions: []models.CensusCampaignExtension{{DRE: "DRE Castanhal", ClosesOn: "2026-05-15"}},
+	}
+}
+
+func belem(y int, m time.Month, d, h int) time.Time {
+	return time.Date(y, m, d, h, 0, 0, 0, campaignTZ)
+}
+
+func TestWindowFor(t *testing.T) {
+	c := testCampaign()
+	for _, tc := range []struct {
+		name     string
+		dre      string
+		now      time.Time
+		situacao string
+		dias     int
+		prazo    string
+	}{
+		{"antes da abertura", "BELEM", belem(2026, 2, 28, 23), campaignScheduled, 61, "2026-04-30"},
+		{"primeiro dia", "BELEM", belem(2026, 3, 1, 0), campaignOpen, 60, "2026-04-30"},
+		{"último dia", "BELEM", belem(2026, 4, 30, 23), campaignOpen, 0, "2026-04-30"},
+		{"encerrada", "BELEM", belem(2026, 5, 3, 9), campaignClosed, -3, "2026-04-30"},
+		{"prorrogação da DRE", "CASTANHAL", belem(2026, 5, 3, 9), campaignOpen, 12, "2026-05-15"},
+		// 02:00 UTC de 1º/05 ainda é 30/04 em Belém.
+		{"fuso de Belém", "BELEM", time.Date(2026, 5, 1, 2, 0, 0, 0, time.UTC), campaignOpen, 0, "2026-04-30"},
+	} {
+		w := windowFor(c, tc.dre, tc.now)
+		if w.Situacao != tc.situacao || w.Prazo != tc.prazo || w.DiasRestantes == nil || *w.DiasRestantes != tc.dias {
+			t.Errorf("%s: %+v (dias %v); want %s/%s/%d", tc.name, w, w.DiasRestantes, tc.situacao, tc.prazo, tc.dias)
+		}
+	}
+	if w := windowFor(nil, "", time.Now()); w.Situacao != campaignNotConfigured || w.DiasRestantes != nil {
+		t.Errorf("sem campanha = %+v", w)
+	}
+}
+
+func TestCampaignWriteError(t *testing.T) {
+	c := testCampaign()
+	now := belem(2026, 5, 3, 9)
+	if err := campaignWriteError(nil, false, 2026, "", now); err != nil {
+		t.Errorf("sem campanhas cadastradas: %v", err)
+	}
+	if err := campaignWriteError(nil, true, 2027, "", now); err == nil {
+		t.Error("ano sem campanha aceito")
+	}
+	err := campaignWriteError(c, true, 2026, "BELEM", now)
+	if err == nil || !strings.Contains(err.Error(), "30/04/2026") {
+		t.Errorf("encerrada: %v", err)
+	}
+	if err := campaignWriteError(c, true, 2026, "Castanhal", now); err != nil {
+		t.Errorf("prorrogada: %v", err)
+	}
+	if err := campaignWriteError(c, true, 2026, "BELEM", belem(2026, 1, 10, 9)); err == nil || !strings.Contains(err.Error(), "01/03/2026") {
+		t.Errorf("agendada: %v", err)
+	}
+}
+
+func TestCampaignRequest(t *testing.T) {
+	ok := campaignRequest{OpensOn: "2026-03-01", ClosesOn: "2026-04-30", FormVersion: " v2 ",
+		Extensions: []models.CensusCampaignExtension{{DRE: " Castanhal ", ClosesOn: "2026-05-15", Reason: " enchente "}}}
+	c, err := ok.campaign(2026)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.FormVersion != "v2" || c.Extensions[0].DRE != "Castanhal" || c.Extensions[0].Reason != "enchente" {
+		t.Errorf("campanha = %+v", c)
+	}
+
+	for name, req := range map[string]campaignRequest{
+		"data inválida":       {OpensOn: "01/03/2026", ClosesOn: "2026-04-30"},
+		"janela invertida":    {OpensOn: "2026-05-01", ClosesOn: "2026-04-30"},
+		"versão longa":        {OpensOn: "2026-03-01", ClosesOn: "2026-04-30", FormVersion: strings.Repeat("v", 33)},
+		"prorrogação sem dre": {OpensOn: "2026-03-01", ClosesOn: "2026-04-30", Extensions: []models.CensusCampaignExtension{{ClosesOn: "2026-05-15"}}},
+		"prorrogação antes":   {OpensOn: "2026-03-01", ClosesOn: "2026-04-30", Extensions: []models.CensusCampaignExtension{{DRE: "X", ClosesOn: "2026-04-30"}}},
+		"prorrogação repetida": {OpensOn: "2026-03-01", ClosesOn: "2026-04-30", Extensions: []models.CensusCampaignExtension{
+			{DRE: "DRE Castanhal", ClosesOn: "2026-05-15"}, {DRE: "castanhal", ClosesOn: "2026-05-20"}}},
+	} {
+		if _, err := req.campaign(2026); err == nil {
+			t.Errorf("%s: aceito", name)
+		}
+	}
+}
+
+func TestApplyCampaignToRow(t *testing.T) {
+	row := buildPreenchimentoDreRow("BELEM", 10, 6, 1)
+	applyCampaignToRow(&row, testCampaign(), belem(2026, 5, 3, 9))
+	if row.Overdue != 4 || row.Prazo != "2026-04-30" || *row.DiasRestantes != -3 {
+		t.Errorf("linha encerrada = %+v", row)
+	}
+
+	row = buildPreenchimentoDreRow("DRE CASTANHAL", 10, 6, 1)
+	applyCampaignToRow(&row, testCampaign(), belem(2026, 5, 3, 9))
+	if row.Overdue != 0 || row.Prazo != "2026-05-15" {
+		t.Errorf("linha prorrogada = %+v", row)
+	}
+
+	row = buildPreenchimentoDreRow("BELEM", 10, 6, 1)
+	applyCampaignToRow(&row, nil, time.Now())
+	if row.Overdue != 0 || row.Prazo != "" || row.DiasRestantes != nil {
+		t.Errorf("sem campanha = %+v", row)
+	}
+}
@@ -0,0 +1,577 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =====================================================================
+// Catálogo de campos do censo
+// =====================================================================
+// census_responses.data é um JSON livre, lido campo a campo pela linha de
+// Base_dados (services.censoRow) e pelas views de 0001_vw_censo_base.sql.
+// Este catálogo descreve cada chave aceita: tipo, opções válidas, limites e se a
+// resposta é obrigatória para concluir o censo. É o espelho, no servidor,
+// dos schemas zod de web/src/schemas/steps — uma opção nova no formulário
+// precisa entrar aqui também.
+//
+// POST /v1/census valida os dados já mesclados com o rascunho salvo:
+//   - status=draft: salva sempre; problemas voltam em "warnings";
+//   - status=completed: campos obrigatórios ausentes, valores fora do tipo,
+//     das opções ou dos limites e inconsistências entre campos recusam a
+//     conclusão com 422 e a lista em "errors".
+//
+// Chaves fora do catálogo nunca bloqueiam: são gravadas e sinalizadas como
+// aviso (campo_desconhecido). null e "" contam como resposta ausente.
+// =====================================================================
+
+// censusFieldType é o tipo esperado do valor de um campo.
+type censusFieldType string
+
+const (
+	fieldText     censusFieldType = "texto"
+	fieldNumber   censusFieldType = "numero"
+	fieldInteger  censusFieldType = "inteiro"
+	fieldOption   censusFieldType = "opcao"
+	fieldMultiple censusFieldType = "multipla"
+	fieldBool     censusFieldType = "booleano"
+	fieldDate     censusFieldType = "data"
+)
+
+// Códigos de problema devolvidos em censusFieldIssue.Code.
+const (
+	issueRequired     = "obrigatorio"
+	issueInvalidType  = "tipo_invalido"
+	issueInvalidOpt   = "opcao_invalida"
+	issueBelowMin     = "abaixo_do_minimo"
+	issueAboveMax     = "acima_do_maximo"
+	issueTooShort     = "muito_curto"
+	issueInconsistent = "inconsistente"
+	issueUnknownField = "campo_desconhecido"
+)
+
+// censusField descreve uma chave de census_responses.data.
+type censusField struct {
+	Key  string
+	Step string // id da etapa em web/src/config/steps.ts
+	Type censusFieldType
+	// Options restringe fieldOption e os itens de fieldMultiple.
+	Options []string
+	Min     *float64
+	Max     *float64
+	// MinLen é o tamanho mínimo de fieldText, em caracteres.
+	MinLen int
+	// Required exige resposta para concluir o censo. Em fieldBool, exige
+	// true (declarações).
+	Required bool
+	// RequiredIf torna o campo obrigatório na conclusão conforme as demais
+	// respostas (ex.: qtd_anexos quando possui_anexos = "Sim").
+	RequiredIf func(data map[string]any) bool
+	// DependsOn é a resposta que controla RequiredIf. Com ela respondida e a
+	// condição falsa, o campo deixa de fazer sentido e é descartado na
+	// gravação (ver pruneDependentAnswers).
+	DependsOn string
+}
+
+// censusCondition é uma condição sobre outra resposta do censo.
+type censusCondition struct {
+	Key   string
+	Holds func(data map[string]any) bool
+}
+
+// censusFieldIssue é um problema de um campo, devolvido ao formulário.
+type censusFieldIssue struct {
+	Field   string `json:"field"`
+	Step    string `json:"step,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// censusValidation separa o que impede a conclusão (Errors) do que é só
+// sinalizado (Warnings). Em rascunho, Errors fica sempre vazio.
+type censusValidation struct {
+	Errors   []censusFieldIssue `json:"errors,omitempty"`
+	Warnings []censusFieldIssue `json:"warnings,omitempty"`
+}
+
+var (
+	optSimNao       = []string{"Sim", "Não"}
+	optSimParcNao   = []string{"Sim", "Parcialmente", "Não"}
+	optTipoPredio   = []string{"Próprio", "Alugado", "Compartilhado", "Cedido"}
+	optExecucao     = []string{"Sim, totalmente", "Parcialmente", "Não executados"}
+	optPendencias   = []string{"Não", "Sim, em regularização", "Sim, pendente/atrasada"}
+	optAvaliacao    = []string{"Ruim", "Regular", "Bom", "Excelente", "Não se aplica"}
+	optEstadoEquip  = []string{"Bom – funcionando plenamente", "Regular – funciona, com limitações", "Ruim – funcionamento comprometido", "Inoperante"}
+	optTerceirizada = []string{"AJ LOURENÇO", "DIAMOND", "E.B CARDOSO", "J.R LIMPEZA", "KAPA CAPITAL", "LG SERVIÇOS", "LIMPAR", "SAP - SERVICE ALIANCA PARA", "Outra"}
+)
+
+func bound(v float64) *float64 { return &v }
+
+// Construtores do catálogo. Todos criam campos opcionais; obrigatorio e
+// quando ajustam a exigência na conclusão.
+
+func textField(step, key string) censusField {
+	return censusField{Key: key, Step: step, Type: fieldText}
+}
+
+func optionField(step, key string, opts []string) censusField {
+	return censusField{Key: key, Step: step, Type: fieldOption, Options: opts}
+}
+
+func multipleField(step, key string, opts []string) censusField {
+	return censusField{Key: key, Step: step, Type: fieldMultiple, Options: opts}
+}
+
+// countField é uma quantidade inteira ≥ min.
+func countField(step, key string, min float64) censusField {
+	return censusField{Key: key, Step: step, Type: fieldInteger, Min: bound(min)}
+}
+
+func numberField(step, key string, min, max float64) censusField {
+	return censusField{Key: key, Step: step, Type: fieldNumber, Min: bound(min), Max: bound(max)}
+}
+
+func (f censusField) obrigatorio() censusField {
+	f.Required = true
+	return f
+}
+
+// quando torna o campo dependente de outra resposta: obrigatório na
+// conclusão se cond valer, descartado se ela não valer.
+func (f censusField) quando(cond censusCondition) censusField {
+	f.RequiredIf = cond.Holds
+	f.DependsOn = cond.Key
+	return f
+}
+
+func (f censusField) minLen(n int) censusField {
+	f.MinLen = n
+	return f
+}
+
+// answerEquals, answeredExcept e answerIncludes são condições para
+// quando.
+func answerEquals(key, want string) censusCondition {
+	return censusCondition{Key: key, Holds: func(d map[string]any) bool {
+		s, ok := d[key].(string)
+		return ok && strings.TrimSpace(s) == want
+	}}
+}
+
+// answeredExcept vale quando key foi respondida com algo diferente de not.
+func answeredExcept(key, not string) censusCondition {
+	return censusCondition{Key: key, Holds: func(d map[string]any) bool {
+		s, ok := d[key].(string)
+		s = strings.TrimSpace(s)
+		return ok && s != "" && s != not
+	}}
+}
+
+func answerIncludes(key, want string) censusCondition {
+	return censusCondition{Key: key, Holds: func(d map[string]any) bool {
+		items, _ := d[key].([]any)
+		for _, it := range items {
+			if s, ok := it.(string); ok && s == want {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// censusCatalog lista os campos na ordem das etapas do formulário. A
+// identificação (etapa 1) vai para a tabela schools e não aparece aqui.
+var censusCatalog = []censusField{
+	// 2. Dados Gerais e Infra
+	optionField("general", "tipo_predio", optTipoPredio).obrigatorio(),
+	optionField("general", "possui_anexos", optSimNao).obrigatorio(),
+	countField("general", "qtd_anexos", 0).quando(answerEquals("possui_anexos", "Sim")),
+	optionField("general", "tipo_predio_anexo", optTipoPredio).quando(answerEquals("possui_anexos", "Sim")),
+	multipleField("general", "etapas_ofertadas", []string{"Ensino Infantil", "Ensino Fundamental I", "Ensino Fundamental II", "Ensino Médio"}).obrigatorio(),
+	multipleField("general", "modalidades_ofertadas", []string{"Ensino Regular", "Ensino Integral", "Educação de Jovens e Adultos (EJA)", "Educação Profissional e Tecnológica", "PPL"}).obrigatorio(),
+	countField("general", "qtd_salas_aula", 1).obrigatorio(),
+	countField("general", "turmas_manha", 0),
+	countField("general", "turmas_tarde", 0),
+	countField("general", "turmas_noite", 0),
+	countField("general", "turmas_integral", 0),
+	countField("general", "total_alunos", 1).obrigatorio(),
+	countField("general", "alunos_pcd", 0).obrigatorio(),
+	countField("general", "alunos_rural", 0).obrigatorio(),
+	countField("general", "alunos_urbana", 0).obrigatorio(),
+	optionField("general", "muro_cerca", []string{"Sim, muro", "Sim, cerca", "Não possui"}).obrigatorio(),
+	optionField("general", "perimetro_fechado", []string{"Sim, totalmente", "Parcialmente", "Não"}).quando(answeredExcept("muro_cerca", "Não possui")),
+	optionField("general", "situacao_estrutura", []string{
+		"Necessita de reforma geral",
+		"Necessita de reforma parcial (melhoria pontual)",
+		"Reforma em andamento",
+		"Está em reforma, porém a obra está parada",
+		"Foi reformada recentemente",
+		"Não necessita de reforma.",
+	}).obrigatorio(),
+	{Key: "data_ultima_reforma", Step: "general", Type: fieldDate},
+	multipleField("general", "ambientes", []string{
+		"Biblioteca", "Laboratório de Ciências", "Laboratório de Informática", "Quadra Esportiva",
+		"Refeitório", "Cozinha", "Sala dos Professores", "Auditório", "Secretaria",
+		"Sala de leitura", "SAEE", "Sala de reunião",
+	}),
+	optionField("general", "quadra_coberta", optSimNao).quando(answerIncludes("ambientes", "Quadra Esportiva")),
+	countField("general", "qtd_quadras", 0).quando(answerIncludes("ambientes", "Quadra Esportiva")),
+	optionField("general", "banda_fanfarra", optSimNao).obrigatorio(),
+	countField("general", "banheiros_alunos", 0).obrigatorio(),
+	countField("general", "banheiros_prof", 0).obrigatorio(),
+	countField("general", "banheiros_chuveiro", 0).obrigatorio(),
+	optionField("general", "banheiros_vasos_funcionais", []string{"Todos", "Alguns", "Nenhum"}).obrigatorio(),
+	countField("general", "salas_climatizadas", 0).obrigatorio(),
+	optionField("general", "energia", []string{"Concessionária de energia - Equatorial", "Geração própria", "Outro"}).obrigatorio(),
+	// transformador: chave de versões antigas do formulário, ainda lida
+	// pelo censoRow.
+	textField("general", "transformador"),
+	optionField("general", "rede_eletrica_atende", optSimParcNao).obrigatorio(),
+	multipleField("general", "problemas_eletricos", []string{"Quedas frequentes", "Sobrecarga", "Fiação antiga", "Quadro elétrico inadequado", "Não há problemas aparentes"}).obrigatorio(),
+	optionField("general", "estrutura_climatizacao", []string{"Sim", "Não", "Não, somente com adequações", "Não, todas as salas são climatizadas"}).obrigatorio(),
+	optionField("general", "suporta_novos_equipamentos", optSimParcNao).obrigatorio(),
+	optionField("general", "cameras_funcionamento", []string{"Sim, funcionando plenamente", "Sim, parcialmente", "Não possui"}).obrigatorio(),
+	optionField("general", "cameras_cobrem", optSimParcNao).quando(answeredExcept("cameras_funcionamento", "Não possui")),
+
+	// 3. Merenda Escolar
+	optionField("food", "condicoes_cozinha", []string{"Boa", "Regular", "Precária"}).obrigatorio(),
+	optionField("food", "tamanho_cozinha", []string{"Pequena", "Média", "Grande"}).obrigatorio(),
+	optionField("food", "oferta_regular", []string{"Sim", "Sim, com falhas", "Não"}).obrigatorio(),
+	optionField("food", "qualidade_merenda", []string{"Boa", "Regular", "Ruim"}).obrigatorio(),
+	optionField("food", "atende_necessidades", optSimParcNao).obrigatorio(),
+	optionField("food", "possui_refeitorio", optSimNao).obrigatorio(),
+	optionField("food", "refeitorio_adequado", optSimNao),
+	optionField("food", "possui_balanca", optSimNao).obrigatorio(),
+	countField("food", "qtd_freezers", 0).obrigatorio(),
+	optionField("food", "estado_freezers", optEstadoEquip),
+	countField("food", "qtd_geladeiras", 0).obrigatorio(),
+	optionField("food", "estado_geladeiras", optEstadoEquip),
+	countField("food", "qtd_fogoes", 0).obrigatorio(),
+	optionField("food", "estado_fogoes", optEstadoEquip),
+	countField("food", "qtd_fornos", 0).obrigatorio(),
+	optionField("food", "estado_fornos", optEstadoEquip),
+	countField("food", "qtd_bebedouros", 0).obrigatorio(),
+	optionField("food", "estado_bebedouros", optEstadoEquip),
+	optionField("food", "bancadas_inox", optSimNao).obrigatorio(),
+	optionField("food", "sistema_exaustao", optSimNao).obrigatorio(),
+	optionField("food", "despensa_exclusiva", optSimNao).obrigatorio(),
+	optionField("food", "deposito_conserva", optSimParcNao).obrigatorio(),
+	optionField("food", "estoque_epi_extintor", []string{"Completo", "Parcial", "Inexistente"}),
+	optionField("food", "manutencao_extintores", []string{"Está na validade", "Validade vencida"}),
+	countField("food", "qtd_merendeiras_estatutaria", 0).obrigatorio(),
+	countField("food", "qtd_merendeiras_terceirizada", 0).obrigatorio(),
+	countField("food", "qtd_merendeiras_temporaria", 0).obrigatorio(),
+	optionField("food", "qtd_atende_necessidade_merenda", optSimNao),
+	countField("food", "quantitativo_necessario_merenda", 0),
+	optionField("food", "empresa_terceirizada_merenda", optTerceirizada),
+	optionField("food", "possui_supervisor_merenda", optSimNao),
+	textField("food", "nome_supervisor_merenda"),
+	textField("food", "contato_supervisor_merenda"),
+
+	// 4. Serviços Gerais
+	countField("cleaning", "qtd_servicos_gerais_efetivo", 0).obrigatorio(),
+	countField("cleaning", "qtd_servicos_gerais_temporario", 0).obrigatorio(),
+	countField("cleaning", "qtd_servicos_gerais_terceirizado", 0).obrigatorio(),
+	optionField("cleaning", "qtd_atende_necessidade_sg", optSimNao),
+	countField("cleaning", "quantitativo_necessario_sg", 0),
+	optionField("cleaning", "empresa_terceirizada_sg", optTerceirizada),
+	optionField("cleaning", "possui_supervisor_sg", optSimNao),
+	textField("cleaning", "nome_supervisor_sg"),
+	textField("cleaning", "contato_supervisor_sg"),
+
+	// 5. Portaria
+	optionField("security", "possui_guarita", optSimNao).obrigatorio(),
+	optionField("security", "controle_portao", []string{"Manual", "Fechadura", "Eletrônica"}).obrigatorio(),
+	optionField("security", "iluminacao_externa", []string{"Adequada", "Regular", "Insuficiente"}).obrigatorio(),
+	optionField("security", "possui_botao_panico", optSimNao).obrigatorio(),
+	countField("security", "qtd_agentes_portaria", 0).obrigatorio(),
+	optionField("security", "qtd_atende_necessidade_portaria", optSimNao),
+	countField("security", "quantitativo_necessario_portaria", 0),
+	optionField("security", "empresa_terceirizada_portaria", optTerceirizada),
+	optionField("security", "possui_supervisor_portaria", optSimNao),
+	textField("security", "nome_supervisor_portaria"),
+	textField("security", "contato_supervisor_portaria"),
+
+	// 6. Equipamentos e Tecnologia
+	optionField("tech", "internet_disponivel", optSimNao).obrigatorio(),
+	optionField("tech", "provedor_internet", []string{"Prodepa", "Starlink", "Outro"}),
+	optionField("tech", "qualidade_internet", []string{
+		"A internet não funciona ou está indisponível com frequência",
+		"A internet apresenta lentidão frequente e compromete as atividades",
+		"A internet possui velocidade aceitável, com eventuais oscilações",
+		"A internet é estável e atende plenamente às necessidades da escola",
+		"Não sei avaliar",
+		"Não se aplica",
+	}),
+	countField("tech", "qtd_desktop_adm", 0).obrigatorio(),
+	countField("tech", "qtd_desktop_alunos", 0).obrigatorio(),
+	countField("tech", "qtd_notebooks", 0).obrigatorio(),
+	countField("tech", "qtd_chromebooks", 0).obrigatorio(),
+	optionField("tech", "computadores_atendem", optSimParcNao),
+	countField("tech", "qtd_computadores_inoperantes", 0).obrigatorio(),
+	optionField("tech", "possui_projetor", optSimNao).obrigatorio(),
+	countField("tech", "qtd_projetores", 0),
+	optionField("tech", "possui_lousa_digital", optSimNao).obrigatorio(),
+
+	// 7. Servidores
+	optionField("staff", "possui_direcao", optSimNao).obrigatorio(),
+	optionField("staff", "possui_vice_pedagogico", optSimNao).obrigatorio(),
+	optionField("staff", "possui_vice_administrativo", optSimNao).obrigatorio(),
+	optionField("staff", "possui_secretario", optSimNao).obrigatorio(),
+	optionField("staff", "possui_coord_pedagogico", optSimNao).obrigatorio(),
+	countField("staff", "qtd_coord_pedagogico", 0),
+	optionField("staff", "possui_coord_area_matematica", optSimNao).obrigatorio(),
+	optionField("staff", "possui_coord_area_linguagem", optSimNao).obrigatorio(),
+	optionField("staff", "possui_coord_area_humanas", optSimNao).obrigatorio(),
+	optionField("staff", "possui_coord_area_natureza", optSimNao).obrigatorio(),
+	countField("staff", "qtd_professores_efetivos", 0).obrigatorio(),
+	countField("staff", "qtd_professores_temporarios", 0).obrigatorio(),
+	countField("staff", "qtd_servidores_administrativos", 0).obrigatorio(),
+	optionField("staff", "possui_professor_readaptado", optSimNao).obrigatorio(),
+	countField("staff", "qtd_professor_readaptado", 0),
+
+	// 8. Perfil dos Alunos (taxas em %, decimal com vírgula aceito)
+	countField("students", "total_beneficiarios", 0).obrigatorio(),
+	numberField("students", "taxa_abandono", 0, 100).obrigatorio(),
+	numberField("students", "taxa_reprovacao_fund1", 0, 100).obrigatorio(),
+	numberField("students", "taxa_reprovacao_fund2", 0, 100).obrigatorio(),
+	numberField("students", "taxa_reprovacao_medio", 0, 100).obrigatorio(),
+	numberField("students", "ideb_anos_iniciais", 0, 10),
+	numberField("students", "ideb_anos_finais", 0, 10),
+	numberField("students", "ideb_ensino_medio", 0, 10),
+
+	// 9. Gestão e Política
+	optionField("management", "regularizada_cee", optSimNao).obrigatorio(),
+	optionField("management", "conselho_escolar", optSimNao).obrigatorio(),
+	optionField("management", "conselho_ativo", optSimParcNao),
+	optionField("management", "recursos_prodep", []string{"Sim", "Não", "Não sabe informar"}).obrigatorio(),
+	{Key: "valor_prodep", Step: "management", Type: fieldNumber, Min: bound(0)},
+	optionField("management", "execucao_prodep", optExecucao),
+	optionField("management", "pendencias_prodep", optPendencias),
+	optionField("management", "recursos_federais", optSimNao).obrigatorio(),
+	{Key: "valor_federais", Step: "management", Type: fieldNumber, Min: bound(0)},
+	optionField("management", "execucao_federais", optExecucao),
+	optionField("management", "pendencias_federais", optPendencias),
+	optionField("management", "gremio_estudantil", optSimNao).obrigatorio(),
+	optionField("management", "reunioes_comunidade", []string{"Não ocorrem", "Eventuais (1–2 por ano)", "Regulares (semestrais)", "Frequentes (mensais ou mais)"}).obrigatorio(),
+	optionField("management", "plano_evacuacao", optSimNao).obrigatorio(),
+	optionField("management", "politica_bullying", []string{"Sim, formalizada e aplicada", "Parcialmente (ações pontuais)", "Não possui"}).obrigatorio(),
+
+	// 10. Avaliação e Notas
+	optionField("rating", "avaliacao_merendeiras", optAvaliacao).obrigatorio(),
+	optionField("rating", "avaliacao_portaria", optAvaliacao).obrigatorio(),
+	optionField("rating", "avaliacao_limpeza", optAvaliacao).obrigatorio(),
+	optionField("rating", "avaliacao_comunicacao", optAvaliacao).obrigatorio(),
+	optionField("rating", "avaliacao_supervisao", optAvaliacao).obrigatorio(),
+
+	// 11. Observações Finais
+	textField("observations", "prioridade_1").obrigatorio(),
+	textField("observations", "prioridade_2").obrigatorio(),
+	textField("observations", "prioridade_3").obrigatorio(),
+	optionField("observations", "demanda_urgente", optSimNao).obrigatorio(),
+	textField("observations", "descricao_urgencia"),
+	optionField("observations", "sugestao_melhoria", optSimNao).obrigatorio(),
+	textField("observations", "descricao_sugestao"),
+	textField("observations", "nome_responsavel").minLen(3).obrigatorio(),
+	textField("observations", "cargo_funcao").minLen(3).obrigatorio(),
+	textField("observations", "matricula_funcional").obrigatorio(),
+	{Key: "declaracao_verdadeira", Step: "observations", Type: fieldBool, Required: true},
+}
+
+// censusCatalogIndex indexa censusCatalog por chave.
+var censusCatalogIndex = func() map[string]censusField {
+	idx := make(map[string]censusField, len(censusCatalog))
+	for _, f := range censusCatalog {
+		if _, dup := idx[f.Key]; dup {
+			panic("census_catalog: chave duplicada " + f.Key)
+		}
+		idx[f.Key] = f
+	}
+	return idx
+}()
+
+// validateCensusData confere data contra o catálogo. completing indica
+// status=completed: só então a ausência de respostas obrigatórias conta e
+// os problemas de valor viram Errors; em rascunho tudo vai para Warnings.
+func validateCensusData(data map[string]any, completing bool) censusValidation {
+	var v censusValidation
+	report := func(f censusField, code, msg string) {
+		issue := censusFieldIssue{Field: f.Key, Step: f.Step, Code: code, Message: msg}
+		if completing {
+			v.Errors = append(v.Errors, issue)
+		} else {
+			v.Warnings = append(v.Warnings, issue)
+		}
+	}
+
+	numbers := map[string]float64{}
+	for _, f := range censusCatalog {
+		raw, present := data[f.Key]
+		if !present || isBlankAnswer(raw) {
+			if completing && (f.Required || (f.RequiredIf != nil && f.RequiredIf(data))) {
+				report(f, issueRequired, "resposta obrigatória para concluir o censo")
+			}
+			continue
+		}
+		n, code, msg := checkCensusValue(f, raw)
+		if code != "" {
+			report(f, code, msg)
+			continue
+		}
+		if f.Type == fieldNumber || f.Type == fieldInteger {
+			numbers[f.Key] = n
+		}
+		if completing && f.Type == fieldBool && f.Required && raw != true {
+			report(f, issueRequired, "é preciso confirmar a declaração para concluir o censo")
+		}
+	}
+
+	// Regras entre campos, espelhando o superRefine de general-data.ts.
+	if total, ok := numbers["total_alunos"]; ok {
+		rural, okR := numbers["alunos_rural"]
+		urbana, okU := numbers["alunos_urbana"]
+		if okR && okU && total != rural+urbana {
+			report(censusCatalogIndex["total_alunos"], issueInconsistent,
+				fmt.Sprintf("total de alunos (%s) difere da soma de rural e urbana (%s)",
+					formatCensusNumber(total), formatCensusNumber(rural+urbana)))
+		}
+	}
+	for _, key := range []string{"qtd_anexos", "qtd_quadras"} {
+		f := censusCatalogIndex[key]
+		if n, ok := numbers[key]; ok && n < 1 && f.RequiredIf(data) {
+			report(f, issueBelowMin, "informe pelo menos 1")
+		}
+	}
+
+	var unknown []string
+	for k := range data {
+		if _, ok := censusCatalogIndex[k]; !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		v.Warnings = append(v.Warnings, censusFieldIssue{
+			Field: k, Code: issueUnknownField, Message: "campo fora do catálogo do censo; gravado sem validação",
+		})
+	}
+	return v
+}
+
+func isBlankAnswer(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(x) == ""
+	case []any:
+		return len(x) == 0
+	}
+	return false
+}
+
+// checkCensusValue valida um valor presente. Devolve o número lido (campos
+// numéricos) ou o código e a mensagem do problema.
+func checkCensusValue(f censusField, raw any) (float64, string, string) {
+	switch f.Type {
+	case fieldText:
+		var s string
+		switch x := raw.(type) {
+		case string:
+			s = strings.TrimSpace(x)
+		case float64:
+			s = formatCensusNumber(x)
+		default:
+			return 0, issueInvalidType, "esperado texto"
+		}
+		if f.MinLen > 0 && len([]rune(s)) < f.MinLen {
+			return 0, issueTooShort, fmt.Sprintf("informe ao menos %d caracteres", f.MinLen)
+		}
+
+	case fieldNumber, fieldInteger:
+		n, ok := parseCensusNumber(raw)
+		if !ok {
+			return 0, issueInvalidType, "esperado número"
+		}
+		if f.Type == fieldInteger && n != math.Trunc(n) {
+			return 0, issueInvalidType, "esperado número inteiro"
+		}
+		if f.Min != nil && n < *f.Min {
+			return 0, issueBelowMin, "mínimo " + formatCensusNumber(*f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return 0, issueAboveMax, "máximo " + formatCensusNumber(*f.Max)
+		}
+		return n, "", ""
+
+	case fieldOption:
+		s, ok := raw.(string)
+		if !ok {
+			return 0, issueInvalidType, "esperada uma das opções do formulário"
+		}
+		if !containsString(f.Options, strings.TrimSpace(s)) {
+			return 0, issueInvalidOpt, fmt.Sprintf("opção %q não existe para este campo", s)
+		}
+
+	case fieldMultiple:
+		items, ok := raw.([]any)
+		if !ok {
+			return 0, issueInvalidType, "esperada uma lista de opções"
+		}
+		for _, it := range items {
+			s, ok := it.(string)
+			if !ok || !containsString(f.Options, s) {
+				return 0, issueInvalidOpt, fmt.Sprintf("opção %v não existe para este campo", it)
+			}
+		}
+
+	case fieldBool:
+		if _, ok := raw.(bool); !ok {
+			return 0, issueInvalidType, "esperado verdadeiro ou falso"
+		}
+
+	case fieldDate:
+		s, ok := raw.(string)
+		if !ok {
+			return 0, issueInvalidType, "esperada data dd/mm/aaaa"
+		}
+		if _, err := time.Parse("02/01/2006", strings.TrimSpace(s)); err != nil {
+			return 0, issueInvalidType, "esperada data dd/mm/aaaa"
+		}
+	}
+	return 0, "", ""
+}
+
+// parseCensusNumber aceita número JSON ou texto numérico, com vírgula ou
+// ponto decimal — o formulário envia as taxas como texto ("2,5").
+func parseCensusNumber(raw any) (float64, bool) {
+	switch x := raw.(type) {
+	case float64:
+		return x, true
+	case string:
+		s := strings.ReplaceAll(strings.TrimSpace(x), ",", ".")
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return 0, false
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+func formatCensusNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func containsString(list []string, s string) bool {
+	for _, it := range list {
+		if it == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package main
+
+// Testes do catálogo de campos do censo. Sem banco: cobrem a cobertura das
+// chaves lidas pela linha da planilha e pelas views, a diferença entre
+// rascunho (avisos) e conclusão (erros), as regras condicionais e a leitura
+// de números enviados como texto.
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// completeCensusData devolve um censo que passa na validação de conclusão.
+func completeCensusData() map[string]any {
+	return map[string]any{
+		"tipo_predio": "Próprio", "possui_anexos": "Não",
+		"etapas_ofertadas": []any{"Ensino Fundamental I"}, "modalidades_ofertadas": []any{"Ensino Regular"},
+		"qtd_salas_aula": 8.0, "turmas_manha": 4.0, "turmas_tarde": 4.0,
+		"total_alunos": 300.0, "alunos_pcd": 6.0, "alunos_rural": 100.0, "alunos_urbana": 200.0,
+		"muro_cerca": "Sim, muro", "perimetro_fechado": "Sim, totalmente",
+		"situacao_estrutura": "Foi reformada recentemente", "data_ultima_reforma": "15/03/2024",
+		"ambientes": []any{"Biblioteca"}, "banda_fanfarra": "Não",
+		"banheiros_alunos": 4.0, "banheiros_prof": 2.0, "banheiros_chuveiro": 0.0, "banheiros_vasos_funcionais": "Todos",
+		"salas_climatizadas": 8.0, "energia": "Concessionária de energia - Equatorial",
+		"rede_eletrica_atende": "Sim", "problemas_eletricos": []any{"Não há problemas aparentes"},
+		"estrutura_climatizacao": "Não, todas as salas são climatizadas", "suporta_novos_equipamentos": "Sim",
+		"cameras_funcionamento": "Não possui",
+
+		"condicoes_cozinha": "Boa", "tamanho_cozinha": "Média", "oferta_regular": "Sim", "qualidade_merenda": "Boa",
+		"atende_necessidades": "Sim", "possui_refeitorio": "Sim", "possui_balanca": "Sim",
+		"qtd_freezers": 1.0, "qtd_geladeiras": 1.0, "qtd_fogoes": 1.0, "qtd_fornos": 0.0, "qtd_bebedouros": 2.0,
+		"estado_freezers": "Bom – funcionando plenamente", "estado_fogoes": "Regular – funciona, com limitações",
+		"bancadas_inox": "Sim", "sistema_exaustao": "Não", "despensa_exclusiva": "Sim", "deposito_conserva": "Sim",
+		"qtd_merendeiras_estatutaria": 2.0, "qtd_merendeiras_terceirizada": 0.0, "qtd_merendeiras_temporaria": 0.0,
+
+		"qtd_servicos_gerais_efetivo": 2.0, "qtd_servicos_gerais_temporario": 0.0, "qtd_servicos_gerais_terceirizado": 1.0,
+
+		"possui_guarita": "Não", "controle_portao": "Manual", "iluminacao_externa": "Regular",
+		"possui_botao_panico": "Não", "qtd_agentes_portaria": 1.0,
+
+		"internet_disponivel": "Sim", "provedor_internet": "Prodepa",
+		"qualidade_internet": "A internet possui velocidade aceitável, com eventuais oscilações", "computadores_atendem": "Parcialmente",
+		"qtd_desktop_adm": 2.0, "qtd_desktop_alunos": 10.0, "qtd_notebooks": 0.0, "qtd_chromebooks": 0.0,
+		"qtd_computadores_inoperantes": 1.0, "possui_projetor": "Não", "possui_lousa_digital": "Não",
+
+		"possui_direcao": "Sim", "possui_vice_pedagogico": "Sim", "possui_vice_administrativo": "Não",
+		"possui_secretario": "Sim", "possui_coord_pedagogico": "Não",
+		"possui_coord_area_matematica": "Não", "possui_coord_area_linguagem": "Não",
+		"possui_coord_area_humanas": "Não", "possui_coord_area_natureza": "Não",
+		"qtd_professores_efetivos": 12.0, "qtd_professores_temporarios": 3.0, "qtd_servidores_administrativos": 4.0,
+		"possui_professor_readaptado": "Não",
+
+		"total_beneficiarios": 280.0, "taxa_abandono": "2,5", "taxa_reprovacao_fund1": 4.0,
+		"taxa_reprovacao_fund2": 0.0, "taxa_reprovacao_medio": 0.0, "ideb_anos_iniciais": "5,1",
+
+		"regularizada_cee": "Sim", "conselho_escolar": "Sim", "conselho_ativo": "Sim",
+		"recursos_prodep": "Sim", "valor_prodep": 15000.0, "recursos_federais": "Não",
+		"gremio_estudantil": "Não", "reunioes_comunidade": "Regulares (semestrais)",
+		"plano_evacuacao": "Não", "politica_bullying": "Parcialmente (ações pontuais)",
+
+		"avaliacao_merendeiras": "Bom", "avaliacao_portaria": "Regular", "avaliacao_limpeza": "Bom",
+		"avaliacao_comunicacao": "Excelente", "avaliacao_supervisao": "Não se aplica",
+
+		"prioridade_1": "Reforma da cozinha", "prioridade_2": "Climatização", "prioridade_3": "Internet",
+		"demanda_urgente": "Não", "sugestao_melhoria": "Não",
+		"nome_responsavel": "MARIA DA SILVA", "cargo_funcao": "Diretora", "matricula_funcional": "123456",
+		"declaracao_verdadeira": true,
+	}
+}
+
+func issueCodes(issues []censusFieldIssue) map[string]string {
+	out := map[string]string{}
+	for _, is := range issues {
+		out[is.Field] = is.Code
+	}
+	return out
+}
+
+func TestCensusCatalogCoversReadKeys(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("..", "..", "internal", "services", "sheets.go"))
+	if err != nil {
+		t.Fatalf("lendo sheets.go: %v", err)
+	}
+	for _, m := range regexp.MustCompile(`val\("([a-z0-9_]+)"\)`).FindAllStringSubmatch(string(src), -1) {
+		if _, ok := censusCatalogIndex[m[1]]; !ok {
+			t.Errorf("censoRow lê %q, ausente do catálogo", m[1])
+		}
+	}
+
+	migs, err := loadMigrations(os.DirFS("."), "migrations")
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	dataKey := regexp.MustCompile(`data ?->>? ?'([a-z0-9_]+)'`)
+	for _, mig := range migs {
+		for _, m := range dataKey.FindAllStringSubmatch(normalizeMigrationSQL(mig.SQL), -1) {
+			if _, ok := censusCatalogIndex[m[1]]; !ok {
+				t.Errorf("%s lê %q, ausente do catálogo", mig.Name, m[1])
+			}
+		}
+	}
+}
+
+func TestValidateCensusDataComplete(t *testing.T) {
+	v := validateCensusData(completeCensusData(), true)
+	if len(v.Errors) > 0 || len(v.Warnings) > 0 {
+		t.Fatalf("censo completo recusado: erros=%v avisos=%v", v.Errors, v.Warnings)
+	}
+}
+
+func TestValidateCensusDataDraftOnlyWarns(t *testing.T) {
+	data := map[string]any{
+		"tipo_predio":    "Prédio próprio",
+		"qtd_salas_aula": "três",
+		"total_alunos":   -1.0,
+		"chave_nova":     "x",
+	}
+	v := validateCensusData(data, false)
+	if len(v.Errors) > 0 {
+		t.Fatalf("rascunho com erros: %v", v.Errors)
+	}
+	got := issueCodes(v.Warnings)
+	want := map[string]string{
+		"tipo_predio":    issueInvalidOpt,
+		"qtd_salas_aula": issueInvalidType,
+		"total_alunos":   issueBelowMin,
+		"chave_nova":     issueUnknownField,
+	}
+	for k, code := range want {
+		if got[k] != code {
+			t.Errorf("aviso de %s = %q; want %q", k, got[k], code)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("avisos = %v; rascunho não deve cobrar campos obrigatórios ausentes", got)
+	}
+}
+
+func TestValidateCensusDataCompletion(t *testing.T) {
+	data := completeCensusData()
+	data["energia"] = nil
+	data["prioridade_2"] = "  "
+	data["cargo_funcao"] = "Di"
+	data["taxa_abandono"] = "120"
+	data["declaracao_verdadeira"] = false
+	data["possui_anexos"] = "Sim"
+	data["qtd_anexos"] = 0.0
+	data["alunos_urbana"] = 150.0
+	data["legado"] = 1.0
+
+	v := validateCensusData(data, true)
+	got := issueCodes(v.Errors)
+	want := map[string]string{
+		"energia":               issueRequired,
+		"prioridade_2":          issueRequired,
+		"cargo_funcao":          issueTooShort,
+		"taxa_abandono":         issueAboveMax,
+		"declaracao_verdadeira": issueRequired,
+		"qtd_anexos":            issueBelowMin,
+		"tipo_predio_anexo":     issueRequired,
+		"total_alunos":          issueInconsistent,
+	}
+	for k, code := range want {
+		if got[k] != code {
+			t.Errorf("erro de %s = %q; want %q", k, got[k], code)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("erros = %v", got)
+	}
+	if w := issueCodes(v.Warnings); w["legado"] != issueUnknownField || len(w) != 1 {
+		t.Errorf("avisos = %v; chave desconhecida não deve bloquear", w)
+	}
+}
+
+func TestValidateCensusDataConditionalFields(t *testing.T) {
+	data := completeCensusData()
+	data["muro_cerca"] = "Não possui"
+	delete(data, "perimetro_fechado")
+	data["cameras_funcionamento"] = "Sim, parcialmente"
+	data["ambientes"] = []any{"Quadra Esportiva"}
+
+	got := issueCodes(validateCensusData(data, true).Errors)
+	if _, ok := got["perimetro_fechado"]; ok {
+		t.Error("perimetro_fechado cobrado sem muro/cerca")
+	}
+	for _, k := range []string{"cameras_cobrem", "quadra_coberta", "qtd_quadras"} {
+		if got[k] != issueRequired {
+			t.Errorf("%s = %q; want obrigatorio", k, got[k])
+		}
+	}
+}
+
+func TestParseCensusNumber(t *testing.T) {
+	for _, tc := range []struct {
+		in   any
+		want float64
+		ok   bool
+	}{
+		{3.0, 3, true},
+		{"2,5", 2.5, true},
+		{" 10 ", 10, true},
+		{"1.5", 1.5, true},
+		{"abc", 0, false},
+		{"NaN", 0, false},
+		{true, 0, false},
+	} {
+		got, ok := parseCensusNumber(tc.in)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("parseCensusNumber(%v) = %v, %v; want %v, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"censo-api/internal/models"
+)
+
+// =====================================================================
+// Concorrência otimista no censo
+// =====================================================================
+// Cada censo tem uma versão (census_responses.version), igual ao número da
+// última revisão em census_revisions. GET /v1/census a devolve no header
+// ETag ("0" quando o censo ainda não existe) e POST /v1/census devolve a
+// nova.
+//
+// POST /v1/census com If-Match: "<versão>":
+//   - versão atual igual: grava normalmente;
+//   - versão atual diferente: 409 com os campos em conflito em "errors"
+//     (code "conflito") e o ETag atual;
+//   - com "merge": true no corpo, a versão antiga só é recusada se algum
+//     campo enviado divergiu de fato — alterado nos dois lados para valores
+//     diferentes. Campos que o cliente reenviou sem mudar e que mudaram no
+//     servidor ficam com o valor do servidor.
+//
+// Sem If-Match a escrita segue como antes (última escrita vence), para não
+// quebrar clientes antigos. A checagem final acontece dentro da transação
+// do Upsert; uma escrita concorrente entre a leitura e a gravação também
+// resulta em 409.
+// =====================================================================
+
+const issueConflict = "conflito"
+
+// censusETag formata a versão como ETag forte.
+func censusETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch lê o header If-Match. wildcard = "*" (qualquer versão de um
+// censo existente). Aceita a forma fraca (W/"3") e o número sem aspas.
+func parseIfMatch(h string) (version int, wildcard bool, present bool, err error) {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		return 0, false, false, nil
+	}
+	if h == "*" {
+		return 0, true, true, nil
+	}
+	v := strings.Trim(strings.TrimPrefix(h, "W/"), `"`)
+	n, convErr := strconv.Atoi(v)
+	if convErr != nil || n < 0 {
+		return 0, false, true, fmt.Errorf("If-Match inválido: use o ETag devolvido por GET /v1/census")
+	}
+	return n, false, true, nil
+}
+
+// sameAnswer compara dois valores de census_responses.data; null e
+// ausência são equivalentes.
+func sameAnswer(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// censusConflicts compara os campos enviados (incoming) com o estado em
+// que o cliente se baseou (base) e o estado gravado (current). base nil =
+// desconhecido (revisão ausente): qualquer campo que difira do gravado
+// conta como conflito.
+//
+// conflicts são os campos alterados no servidor desde base que a escrita
+// sobrescreveria. stale é o subconjunto que o cliente apenas reenviou sem
+// alterar (valor igual ao de base) — em merge, esses campos são
+// descartados da escrita e não contam como conflito.
+func censusConflicts(base, current, incoming map[string]any) (conflicts, stale []string) {
+	for k, n := range incoming {
+		c := current[k]
+		if sameAnswer(n, c) {
+			continue
+		}
+		if base != nil {
+			b := base[k]
+			if sameAnswer(c, b) {
+				continue // só o cliente mudou
+			}
+			if sameAnswer(n, b) {
+				stale = append(stale, k)
+			}
+		}
+		conflicts = append(conflicts, k)
+	}
+	sort.Strings(conflicts)
+	sort.Strings(stale)
+	return conflicts, stale
+}
+
+// conflictIssues converte campos em conflito para o formato de erros por
+// campo do catálogo.
+func conflictIssues(keys []string) []censusFieldIssue {
+	out := make([]censusFieldIssue, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, censusFieldIssue{
+			Field:   k,
+			Step:    censusCatalogIndex[k].Step,
+			Code:    issueConflict,
+			Message: "alterado em outra aba ou dispositivo depois que o formulário foi carregado",
+		})
+	}
+	return out
+}
+
+// checkCensusVersion aplica If-Match a uma escrita em existing (nil =
+// censo novo). Devolve a versão que o Upsert deve exigir (nil = sem
+// checagem) ou false quando a resposta já foi escrita. Em merge, remove de
+// incoming os campos apenas reenviados que mudaram no servidor.
+func (app *application) checkCensusVersion(w http.ResponseWriter, r *http.Request, existing *models.CensusResponse,
+	incoming map[string]any, merge bool) (*int, bool) {
+	want, wildcard, present, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return nil, false
+	}
+	if !present {
+		return nil, true
+	}
+
+	current := 0
+	if existing != nil {
+		current = existing.Version
+	}
+	if wildcard {
+		if existing == nil {
+			app.errorJSON(w, fmt.Errorf("censo ainda não existe; If-Match: * exige um censo gravado"), http.StatusPreconditionFailed)
+			return nil, false
+		}
+		return &current, true
+	}
+	if want == current {
+		return &current, true
+	}
+
+	currentData := map[string]any{}
+	var base map[string]any
+	if existing != nil {
+		if m, err := decodeCensusData(existing.Data); err == nil {
+			currentData = m
+		}
+		if want > 0 {
+			rev, err := app.models.CensusRevisions.Get(r.Context(), existing.ID, want)
+			if err != nil {
+				app.logger.Printf("checkCensusVersion: revisão %d do censo %d: %v", want, existing.ID, err)
+			} else if rev != nil {
+				base, _ = decodeCensusData(rev.Data)
+			}
+		} else {
+			base = map[string]any{}
+		}
+	}
+
+	conflicts, stale := censusConflicts(base, currentData, incoming)
+	if merge {
+		for _, k := range stale {
+			delete(incoming, k)
+		}
+		conflicts = subtractKeys(conflicts, stale)
+		if len(conflicts) == 0 {
+			return &current, true
+		}
+	}
+
+	w.Header().Set("ETag", censusETag(current))
+	app.writeJSON(w, http.StatusConflict, jsonResponse{
+		Error: true,
+		Message: fmt.Sprintf("o censo foi alterado em outra aba ou dispositivo (versão %d, formulário na %d); recarregue antes de salvar",
+			current, want),
+		Errors: conflictIssues(conflicts),
+	})
+	return nil, false
+}
+
+func subtractKeys(keys, remove []string) []string {
+	drop := make(map[string]bool, len(remove))
+	for _, k := range remove {
+		drop[k] = true
+	}
+	out := keys[:0]
+	for _, k := range keys {
+		if !drop[k] {
+			out = append(out, k)
+		}
+	}
+	return out
+}
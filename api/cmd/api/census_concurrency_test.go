@@ -0,0 +1,130 @@
+package main
+
+// Testes da concorrência otimista do censo. Sem banco: cobrem a leitura do
+// If-Match, a comparação em três vias (base, gravado, enviado) e as
+// respostas de checkCensusVersion quando a revisão base não precisa ser
+// buscada.
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"censo-api/internal/models"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	for _, tc := range []struct {
+		in               string
+		version          int
+		wildcard, exists bool
+		bad              bool
+	}{
+		{"", 0, false, false, false},
+		{`"7"`, 7, false, true, false},
+		{`W/"3"`, 3, false, true, false},
+		{"12", 12, false, true, false},
+		{"*", 0, true, true, false},
+		{`"abc"`, 0, false, true, true},
+		{`"-1"`, 0, false, true, true},
+	} {
+		v, wc, present, err := parseIfMatch(tc.in)
+		if (err != nil) != tc.bad || present != tc.exists || (err == nil && (v != tc.version || wc != tc.wildcard)) {
+			t.Errorf("parseIfMatch(%q) = %d, %v, %v, %v", tc.in, v, wc, present, err)
+		}
+	}
+	if censusETag(4) != `"4"` {
+		t.Errorf("censusETag(4) = %s", censusETag(4))
+	}
+}
+
+func TestCensusConflicts(t *testing.T) {
+	base := map[string]any{"a": "Sim", "b": "Não", "c": 1.0, "d": "x"}
+	current := map[string]any{"a": "Não", "b": "Sim", "c": 1.0, "d": "x"}
+	incoming := map[string]any{
+		"a": "Sim",    // reenviado sem mudar; mudou no servidor → stale
+		"b": "Talvez", // mudou dos dois lados → conflito de fato
+		"c": 2.0,      // só o cliente mudou
+		"d": "x",      // igual
+		"e": "novo",   // campo novo só no cliente
+	}
+	conflicts, stale := censusConflicts(base, current, incoming)
+	if !reflect.DeepEqual(conflicts, []string{"a", "b"}) || !reflect.DeepEqual(stale, []string{"a"}) {
+		t.Errorf("conflicts=%v stale=%v", conflicts, stale)
+	}
+
+	// Sem base conhecida, qualquer diferença para o gravado é conflito.
+	conflicts, stale = censusConflicts(nil, current, incoming)
+	if !reflect.DeepEqual(conflicts, []string{"a", "b", "c", "e"}) || len(stale) != 0 {
+		t.Errorf("sem base: conflicts=%v stale=%v", conflicts, stale)
+	}
+}
+
+func TestCheckCensusVersion(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	existing := &models.CensusResponse{ID: 1, Version: 2, Data: json.RawMessage(`{"tipo_predio":"Alugado","energia":"Outro"}`)}
+	call := func(ifMatch string, existing *models.CensusResponse, incoming map[string]any, merge bool) (*int, bool, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/census", nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		v, ok := app.checkCensusVersion(rec, req, existing, incoming, merge)
+		return v, ok, rec
+	}
+
+	if v, ok, _ := call("", existing, map[string]any{}, false); !ok || v != nil {
+		t.Errorf("sem If-Match: v=%v ok=%v", v, ok)
+	}
+	if v, ok, _ := call(`"2"`, existing, map[string]any{}, false); !ok || v == nil || *v != 2 {
+		t.Errorf("versão atual: v=%v ok=%v", v, ok)
+	}
+	if v, ok, _ := call(`"0"`, nil, map[string]any{}, false); !ok || v == nil || *v != 0 {
+		t.Errorf("censo novo com If-Match 0: v=%v ok=%v", v, ok)
+	}
+	if _, ok, rec := call("*", nil, map[string]any{}, false); ok || rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("If-Match * sem censo: ok=%v status=%d", ok, rec.Code)
+	}
+
+	// Formulário carregado antes de existir censo (versão 0): energia foi
+	// gravada por outra aba e o cliente tenta outro valor.
+	_, ok, rec := call(`"0"`, existing, map[string]any{"energia": "Geração própria", "tipo_predio": "Alugado"}, true)
+	if ok || rec.Code != http.StatusConflict || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("conflito: ok=%v status=%d etag=%s", ok, rec.Code, rec.Header().Get("ETag"))
+	}
+	var body jsonResponse
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if len(body.Errors) != 1 || body.Errors[0].Field != "energia" || body.Errors[0].Code != issueConflict {
+		t.Errorf("erros = %+v", body.Errors)
+	}
+
+	// Em merge, só campo novo: aceito contra a versão atual.
+	incoming := map[string]any{"possui_anexos": "Não"}
+	if v, ok, _ := call(`"0"`, existing, incoming, true); !ok || v == nil || *v != 2 {
+		t.Errorf("merge sem divergência: v=%v ok=%v", v, ok)
+	}
+
+	// Em merge, campo reenviado sem mudar sai da escrita e fica o do servidor.
+	incoming = map[string]any{"energia": nil, "possui_anexos": "Sim"}
+	if _, ok, _ := call(`"0"`, existing, incoming, true); !ok {
+		t.Fatal("merge com campo apenas reenviado recusado")
+	}
+	if _, kept := incoming["energia"]; kept {
+		t.Errorf("campo reenviado não foi descartado: %v", incoming)
+	}
+
+	if _, ok, rec := call(`"abc"`, existing, map[string]any{}, false); ok || rec.Code != http.StatusBadRequest {
+		t.Errorf("If-Match inválido: ok=%v status=%d", ok, rec.Code)
+	}
+}
+
+func TestSubtractKeys(t *testing.T) {
+	got := subtractKeys([]string{"a", "b", "c"}, []string{"b"})
+	if !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("subtractKeys = %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =====================================================================
+// PATCH /v1/census — JSON Merge Patch (RFC 7396)
+// =====================================================================
+// POST /v1/census só acrescenta chaves ao JSON gravado: uma resposta nunca
+// é apagada. PATCH /v1/census?school_id=&year= recebe um merge patch dos
+// dados do censo (Content-Type application/merge-patch+json ou
+// application/json):
+//   - chave com null é removida;
+//   - objeto é aplicado recursivamente;
+//   - qualquer outro valor (inclusive arrays) substitui o atual.
+//
+// O status não muda (censo novo nasce como rascunho). If-Match funciona
+// como no POST em modo merge: só campos alterados nos dois lados geram 409.
+//
+// Em POST e PATCH, respostas dependentes cuja condição deixou de valer
+// (campos com quando no catálogo, ex.: qtd_anexos e tipo_predio_anexo com
+// possui_anexos = "Não") são descartadas e avisadas em "warnings".
+// =====================================================================
+
+const issueDependentCleared = "descartado"
+
+// applyMergePatch aplica patch sobre target segundo a RFC 7396 e devolve o
+// resultado. target é alterado.
+func applyMergePatch(target, patch map[string]any) map[string]any {
+	if target == nil {
+		target = map[string]any{}
+	}
+	for k, v := range patch {
+		switch pv := v.(type) {
+		case nil:
+			delete(target, k)
+		case map[string]any:
+			child, _ := target[k].(map[string]any)
+			target[k] = applyMergePatch(child, pv)
+		default:
+			target[k] = v
+		}
+	}
+	return target
+}
+
+// pruneDependentAnswers remove de data as respostas dependentes (DependsOn
+// no catálogo) cuja resposta controladora foi dada e não satisfaz a
+// condição. Sem a controladora o campo fica, para não perder rascunhos
+// parciais. Devolve, em ordem do catálogo, as chaves removidas que tinham
+// valor.
+func pruneDependentAnswers(data map[string]any) []string {
+	var removed []string
+	for changed := true; changed; {
+		changed = false
+		for _, f := range censusCatalog {
+			v, has := data[f.Key]
+			if f.DependsOn == "" || !has || isBlankAnswer(data[f.DependsOn]) || f.RequiredIf(data) {
+				continue
+			}
+			delete(data, f.Key)
+			changed = true
+			if !isBlankAnswer(v) {
+				removed = append(removed, f.Key)
+			}
+		}
+	}
+	return removed
+}
+
+// dependentIssues descreve as respostas descartadas por pruneDependentAnswers.
+func dependentIssues(keys []string) []censusFieldIssue {
+	out := make([]censusFieldIssue, 0, len(keys))
+	for _, k := range keys {
+		f := censusCatalogIndex[k]
+		out = append(out, censusFieldIssue{
+			Field:   k,
+			Step:    f.Step,
+			Code:    issueDependentCleared,
+			Message: fmt.Sprintf("removido: não se aplica com a resposta atual de %s", f.DependsOn),
+		})
+	}
+	return out
+}
+
+// PatchCenso aplica um JSON Merge Patch aos dados do censo da escola.
+func (app *application) PatchCenso(w http.ResponseWriter, r *http.Request) {
+	if !app.allowRate(r, censusWriteLimit, ipKey(r)) {
+		w.Header().Set("Retry-After", "600")
+		app.errorJSON(w, fmt.Errorf("muitas requisições. Aguarde alguns minutos"), http.StatusTooManyRequests)
+		return
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, _ := mime.ParseMediaType(ct)
+		if mt != "application/merge-patch+json" && mt != "application/json" {
+			app.errorJSON(w, fmt.Errorf("use Content-Type application/merge-patch+json"), http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	q := r.URL.Query()
+	schoolID, err := strconv.Atoi(strings.TrimSpace(q.Get("school_id")))
+	if err != nil || schoolID <= 0 {
+		app.errorJSON(w, fmt.Errorf("school_id inválido"), http.StatusBadRequest)
+		return
+	}
+	year, err := strconv.Atoi(q.Get("year"))
+	if err != nil || year == 0 {
+		year = time.Now().Year()
+	}
+
+	var raw json.RawMessage
+	if err := app.readJSON(w, r, &raw); err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	var patch map[string]any
+	if err := json.Unmarshal(raw, &patch); err != nil || patch == nil {
+		app.errorJSON(w, fmt.Errorf("o patch deve ser um objeto JSON"), http.StatusBadRequest)
+		return
+	}
+
+	if !app.authorizeSchoolWrite(w, r, schoolID) {
+		return
+	}
+	if !app.allowRate(r, schoolWriteLimit, schoolKey(schoolID)) {
+		w.Header().Set("Retry-After", "600")
+		app.errorJSON(w, fmt.Errorf("muitas requisições para esta escola. Aguarde alguns minutos"), http.StatusTooManyRequests)
+		return
+	}
+	if !app.requireCampaignOpen(w, r, schoolID, year) {
+		return
+	}
+
+	existing, err := app.models.Census.GetBySchoolID(schoolID, year)
+	if err != nil {
+		app.logger.Printf("PatchCenso: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar censo"), http.StatusInternalServerError)
+		return
+	}
+
+	ifVersion, ok := app.checkCensusVersion(w, r, existing, patch, true)
+	if !ok {
+		return
+	}
+
+	status := "draft"
+	data := map[string]any{}
+	if existing != nil {
+		status = existing.Status
+		if data, err = decodeCensusData(existing.Data); err != nil {
+			app.errorJSON(w, fmt.Errorf("JSON do censo gravado ilegível"), http.StatusInternalServerError)
+			return
+		}
+	}
+	data = applyMergePatch(data, patch)
+	pruned := pruneDependentAnswers(data)
+
+	var seeded []string
+	if existing != nil {
+		seeded = pendingSeeded(existing.SeededFields, data, patch)
+	}
+	app.saveCensus(w, r, schoolID, year, status, data, ifVersion, pruned, seeded)
+}
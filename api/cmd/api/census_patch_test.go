@@ -0,0 +1,86 @@
+package main
+
+// Testes do PATCH do censo. Sem banco: cobrem a aplicação do merge patch
+// (exemplos do apêndice A da RFC 7396) e o descarte declarativo de
+// respostas dependentes.
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	for _, tc := range []struct{ target, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		var target, patch, want map[string]any
+		json.Unmarshal([]byte(tc.target), &target)
+		json.Unmarshal([]byte(tc.patch), &patch)
+		json.Unmarshal([]byte(tc.want), &want)
+		if got := applyMergePatch(target, patch); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s + %s = %v; want %v", tc.target, tc.patch, got, want)
+		}
+	}
+	if got := applyMergePatch(nil, map[string]any{"a": nil}); got == nil || len(got) != 0 {
+		t.Errorf("patch sobre censo novo = %v", got)
+	}
+}
+
+func TestPruneDependentAnswers(t *testing.T) {
+	data := map[string]any{
+		"possui_anexos":         "Não",
+		"qtd_anexos":            2.0,
+		"tipo_predio_anexo":     "",
+		"ambientes":             []any{"Biblioteca"},
+		"qtd_quadras":           1.0,
+		"cameras_funcionamento": "Sim",
+		"cameras_cobrem":        "Parcialmente",
+	}
+	removed := pruneDependentAnswers(data)
+	if !reflect.DeepEqual(removed, []string{"qtd_anexos", "qtd_quadras"}) {
+		t.Errorf("removidos = %v", removed)
+	}
+	for _, k := range []string{"qtd_anexos", "tipo_predio_anexo", "qtd_quadras"} {
+		if _, ok := data[k]; ok {
+			t.Errorf("%s continua no censo", k)
+		}
+	}
+	if data["cameras_cobrem"] != "Parcialmente" {
+		t.Error("resposta dependente válida foi descartada")
+	}
+
+	// Sem a resposta controladora (rascunho parcial), nada é descartado.
+	partial := map[string]any{"qtd_anexos": 3.0}
+	if removed := pruneDependentAnswers(partial); len(removed) != 0 || partial["qtd_anexos"] != 3.0 {
+		t.Errorf("rascunho parcial: removidos = %v", removed)
+	}
+
+	issues := dependentIssues([]string{"qtd_anexos"})
+	if len(issues) != 1 || issues[0].Code != issueDependentCleared || issues[0].Step != "general" {
+		t.Errorf("avisos = %+v", issues)
+	}
+}
+
+func TestCatalogDependenciesExist(t *testing.T) {
+	for _, f := range censusCatalog {
+		if f.DependsOn == "" {
+			continue
+		}
+		if _, ok := censusCatalogIndex[f.DependsOn]; !ok {
+			t.Errorf("%s depende de %s, fora do catálogo", f.Key, f.DependsOn)
+		}
+		if f.RequiredIf == nil {
+			t.Errorf("%s tem DependsOn sem condição", f.Key)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"censo-api/internal/models"
+)
+
+// =====================================================================
+// Revisão do censo pela DRE
+// =====================================================================
+// census_responses.status segue a máquina de estados:
+//
+//	draft ⇄ completed (enviado) → em_revisao → devolvido | aprovado
+//	                                           devolvido → completed
+//
+// O formulário só grava draft e completed ("submitted" é aceito como
+// sinônimo). Em revisão ou aprovado o censo fica travado para o formulário
+// (409); devolvido volta a ser editável e continua devolvido até o reenvio.
+//
+//   - POST /v1/admin/census/{id}/review   {action, comment}
+//   - POST /v1/admin/census/review        {ids, action, comment} (em lote)
+//   - GET  /v1/admin/census/{id}/reviews  transições com revisor e comentário
+//   - GET  /v1/census/review?school_id=&year=  (público) status e
+//     comentários de devolução, sem o revisor, para o diretor corrigir.
+//
+// action: iniciar (→ em_revisao), devolver (→ devolvido, comentário
+// obrigatório) ou aprovar (→ aprovado). Revisam seduc_admin e dre_gestor,
+// este só na própria DRE.
+// =====================================================================
+
+// reviewActions mapeia a ação da revisão para o status de destino.
+var reviewActions = map[string]string{
+	"iniciar":  models.CensusStatusInReview,
+	"devolver": models.CensusStatusReturned,
+	"aprovar":  models.CensusStatusApproved,
+}
+
+const (
+	maxReviewComment = 2000
+	maxReviewBatch   = 200
+)
+
+// publicCensusStatus normaliza o status enviado pelo formulário; os
+// estados da revisão não podem ser gravados por ele.
+func publicCensusStatus(s string) (string, error) {
+	switch strings.TrimSpace(strings.ToLower(s)) {
+	case "", models.CensusStatusDraft:
+		return models.CensusStatusDraft, nil
+	case models.CensusStatusSubmitted, "submitted":
+		return models.CensusStatusSubmitted, nil
+	}
+	return "", fmt.Errorf("status inválido: use draft ou completed")
+}
+
+type censusReviewRequest struct {
+	IDs     []int  `json:"ids"`
+	Action  string `json:"action"`
+	Comment string `json:"comment"`
+}
+
+// reviewTarget valida ação e comentário e devolve o status de destino.
+func (req *censusReviewRequest) reviewTarget() (string, error) {
+	to, ok := reviewActions[strings.TrimSpace(strings.ToLower(req.Action))]
+	if !ok {
+		return "", fmt.Errorf("action inválida: use iniciar, devolver ou aprovar")
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if to == models.CensusStatusReturned && req.Comment == "" {
+		return "", fmt.Errorf("comment é obrigatório ao devolver o censo")
+	}
+	if utf8.RuneCountInString(req.Comment) > maxReviewComment {
+		return "", fmt.Errorf("comment excede %d caracteres", maxReviewComment)
+	}
+	return to, nil
+}
+
+// censusReviewResult é o resultado da revisão de um censo.
+type censusReviewResult struct {
+	ID      int    `json:"id"`
+	Status  string `json:"status,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// censusDRE devolve a DRE da escola do censo (sql.ErrNoRows se não existe).
+func (app *application) censusDRE(ctx context.Context, id int) (string, error) {
+	var dre string
+	err := app.models.Schools.DB.QueryRowContext(ctx, `
+		SELECT s.dre FROM census_responses cr JOIN schools s ON s.id = cr.school_id
+		WHERE cr.id = $1`, id).Scan(&dre)
+	return dre, err
+}
+
+// reviewCensus aplica uma transição a um censo, conferindo o recorte de
+// DRE da conta. O erro já vem em português para o painel.
+func (app *application) reviewCensus(ctx context.Context, id int, to, comment string) (int, int, error) {
+	dre, err := app.censusDRE(ctx, id)
+	if err == sql.ErrNoRows {
+		return 0, http.StatusNotFound, fmt.Errorf("censo não encontrado")
+	}
+	if err != nil {
+		app.logger.Printf("reviewCensus: %v", err)
+		return 0, http.StatusInternalServerError, fmt.Errorf("erro ao consultar censo")
+	}
+	if scope := adminDREScope(ctx); scope != "" && !sameDRE(dre, scope) {
+		return 0, http.StatusForbidden, errDREForaDoEscopo
+	}
+
+	admin, _ := adminFromContext(ctx)
+	version, err := app.models.CensusReviews.Transition(ctx, id, to,
+		models.CensusActor{Kind: models.CensusActorAdmin, Name: admin.Username}, comment)
+	switch {
+	case errors.Is(err, models.ErrCensusTransition):
+		return 0, http.StatusConflict, fmt.Errorf("o status atual do censo não permite %s", reviewActionLabel(to))
+	case err == sql.ErrNoRows:
+		return 0, http.StatusNotFound, fmt.Errorf("censo não encontrado")
+	case err != nil:
+		app.logger.Printf("reviewCensus: censo %d: %v", id, err)
+		return 0, http.StatusInternalServerError, fmt.Errorf("erro ao gravar revisão")
+	}
+	return version, http.StatusOK, nil
+}
+
+func reviewActionLabel(to string) string {
+	switch to {
+	case models.CensusStatusInReview:
+		return "iniciar a revisão"
+	case models.CensusStatusReturned:
+		return "devolver"
+	default:
+		return "aprovar"
+	}
+}
+
+// AdminReviewCensus aplica uma ação de revisão a um censo.
+func (app *application) AdminReviewCensus(w http.ResponseWriter, r *http.Request) {
+	id, err := censusIDParam(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	var req censusReviewRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	to, err := req.reviewTarget()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	version, status, err := app.reviewCensus(r.Context(), id, to, req.Comment)
+	if err != nil {
+		app.errorJSON(w, err, status)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "revisão registrada",
+		Data: censusReviewResult{ID: id, Status: to, Version: version}})
+}
+
+// AdminBulkReviewCensus aplica a mesma ação a vários censos. Cada censo é
+// uma transação: falhas individuais voltam no resultado sem desfazer os
+// demais.
+func (app *application) AdminBulkReviewCensus(w http.ResponseWriter, r *http.Request) {
+	var req censusReviewRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	to, err := req.reviewTarget()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxReviewBatch {
+		app.errorJSON(w, fmt.Errorf("ids deve ter entre 1 e %d censos", maxReviewBatch), http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[int]bool, len(req.IDs))
+	results := make([]censusReviewResult, 0, len(req.IDs))
+	ok := 0
+	for _, id := range req.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		version, _, err := app.reviewCensus(r.Context(), id, to, req.Comment)
+		if err != nil {
+			results = append(results, censusReviewResult{ID: id, Error: err.Error()})
+			continue
+		}
+		ok++
+		results = append(results, censusReviewResult{ID: id, Status: to, Version: version})
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false,
+		Message: fmt.Sprintf("%d de %d censo(s) atualizado(s)", ok, len(results)), Data: results})
+}
+
+// AdminListCensusReviews lista as transições de revisão de um censo.
+func (app *application) AdminListCensusReviews(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.scopedCensusID(w, r)
+	if !ok {
+		return
+	}
+	reviews, err := app.models.CensusReviews.List(r.Context(), id)
+	if err != nil {
+		app.logger.Printf("AdminListCensusReviews: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar revisões"), http.StatusInternalServerError)
+		return
+	}
+	if reviews == nil {
+		reviews = []*models.CensusReview{}
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: reviews})
+}
+
+// censusReturnNote é um comentário de devolução visto pela escola.
+type censusReturnNote struct {
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetCensoReview devolve o status de revisão do censo da escola e os
+// comentários de devolução, do mais recente ao mais antigo.
+func (app *application) GetCensoReview(w http.ResponseWriter, r *http.Request) {
+	schoolID, err := strconv.Atoi(r.URL.Query().Get("school_id"))
+	if err != nil || schoolID <= 0 {
+		app.errorJSON(w, fmt.Errorf("school_id inválido"), http.StatusBadRequest)
+		return
+	}
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil || year == 0 {
+		year = time.Now().Year()
+	}
+
+	censo, err := app.models.Census.GetBySchoolID(schoolID, year)
+	if err != nil {
+		app.logger.Printf("GetCensoReview: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar censo"), http.StatusInternalServerError)
+		return
+	}
+	out := struct {
+		Status  string             `json:"status"`
+		Returns []censusReturnNote `json:"returns"`
+	}{Returns: []censusReturnNote{}}
+	if censo != nil {
+		out.Status = censo.Status
+		returns, err := app.models.CensusReviews.Returns(r.Context(), censo.ID)
+		if err != nil {
+			app.logger.Printf("GetCensoReview: %v", err)
+			app.errorJSON(w, fmt.Errorf("erro ao consultar revisões"), http.StatusInternalServerError)
+			return
+		}
+		for _, rv := range returns {
+			out.Returns = append(out.Returns, censusReturnNote{Comment: rv.Comment, CreatedAt: rv.CreatedAt})
+		}
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: out})
+}
@@ -0,0 +1,89 @@
+package main
+
+// Testes da revisão do censo. Sem banco: cobrem o status aceito do
+// formulário, a validação das ações de revisão e a máquina de estados.
+
+import (
+	"strings"
+	"testing"
+
+	"censo-api/internal/models"
+)
+
+func TestPublicCensusStatus(t *testing.T) {
+	for in, want := range map[string]string{
+		"":          models.CensusStatusDraft,
+		"draft":     models.CensusStatusDraft,
+		"completed": models.CensusStatusSubmitted,
+		"Submitted": models.CensusStatusSubmitted,
+	} {
+		if got, err := publicCensusStatus(in); err != nil || got != want {
+			t.Errorf("publicCensusStatus(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"aprovado", "em_revisao", "devolvido", "qualquer"} {
+		if _, err := publicCensusStatus(in); err == nil {
+			t.Errorf("formulário pôde gravar status %q", in)
+		}
+	}
+}
+
+func TestCensusReviewTarget(t *testing.T) {
+	for _, tc := range []struct {
+		action, comment, want string
+		bad                   bool
+	}{
+		{"iniciar", "", models.CensusStatusInReview, false},
+		{"Aprovar", "", models.CensusStatusApproved, false},
+		{"devolver", "  faltou o quadro de pessoal  ", models.CensusStatusReturned, false},
+		{"devolver", "   ", "", true},
+		{"publicar", "", "", true},
+		{"aprovar", strings.Repeat("a", maxReviewComment+1), "", true},
+	} {
+		req := censusReviewRequest{Action: tc.action, Comment: tc.comment}
+		got, err := req.reviewTarget()
+		if (err != nil) != tc.bad || got != tc.want {
+			t.Errorf("reviewTarget(%q, %d chars) = %q, %v", tc.action, len(tc.comment), got, err)
+		}
+		if !tc.bad && req.Comment != strings.TrimSpace(tc.comment) {
+			t.Errorf("comentário não normalizado: %q", req.Comment)
+		}
+	}
+}
+
+func TestCensusReviewStateMachine(t *testing.T) {
+	allowed := [][2]string{
+		{models.CensusStatusSubmitted, models.CensusStatusInReview},
+		{models.CensusStatusInReview, models.CensusStatusReturned},
+		{models.CensusStatusInReview, models.CensusStatusApproved},
+	}
+	for _, tr := range allowed {
+		if !models.CensusReviewAllowed(tr[0], tr[1]) {
+			t.Errorf("%s → %s recusada", tr[0], tr[1])
+		}
+	}
+	denied := [][2]string{
+		{models.CensusStatusDraft, models.CensusStatusInReview},
+		{models.CensusStatusSubmitted, models.CensusStatusApproved},
+		{models.CensusStatusReturned, models.CensusStatusApproved},
+		{models.CensusStatusApproved, models.CensusStatusReturned},
+		{models.CensusStatusInReview, models.CensusStatusInReview},
+	}
+	for _, tr := range denied {
+		if models.CensusReviewAllowed(tr[0], tr[1]) {
+			t.Errorf("%s → %s aceita", tr[0], tr[1])
+		}
+	}
+
+	for status, locked := range map[string]bool{
+		models.CensusStatusDraft:     false,
+		models.CensusStatusSubmitted: false,
+		models.CensusStatusReturned:  false,
+		models.CensusStatusInReview:  true,
+		models.CensusStatusApproved:  true,
+	} {
+		if models.CensusLockedForForm(status) != locked {
+			t.Errorf("CensusLockedForForm(%s) = %v", status, !locked)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"censo-api/internal/models"
+)
+
+// =====================================================================
+// Histórico de revisões do censo
+// =====================================================================
+// Toda escrita aceita em census_responses entra em census_revisions (ver
+// CensusModel.Upsert): JSON anterior e novo, transição de status, origem
+// (escola com código, formulário sem código ou painel) e IP.
+//
+//   - GET /v1/admin/census/{id}/revisions
+//     lista as revisões, da mais antiga à mais recente, com os campos
+//     alterados em cada uma (sem os JSONs);
+//   - GET /v1/admin/census/{id}/revisions/diff?from=&to=
+//     diferença campo a campo entre duas revisões. to padrão = a mais
+//     recente; from padrão = o estado anterior a to; from=0 compara com o
+//     censo vazio.
+//
+// Mesmo recorte de AdminGetCensusByID: contas de DRE só veem censos da
+// própria DRE.
+// =====================================================================
+
+// Tipos de alteração em censusFieldChange.Change.
+const (
+	changeAdded   = "adicionado"
+	changeRemoved = "removido"
+	changeUpdated = "alterado"
+)
+
+// censusFieldChange é a diferença de um campo entre dois estados do censo.
+type censusFieldChange struct {
+	Field  string `json:"field"`
+	Step   string `json:"step,omitempty"`
+	Change string `json:"change"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// censusRevisionSummary é uma linha de GET .../revisions.
+type censusRevisionSummary struct {
+	Revision       int       `json:"revision"`
+	PreviousStatus *string   `json:"previous_status"`
+	Status         string    `json:"status"`
+	ActorKind      string    `json:"actor_kind"`
+	Actor          string    `json:"actor,omitempty"`
+	ClientIP       string    `json:"client_ip,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	ChangedFields  []string  `json:"changed_fields"`
+}
+
+// censusRevisionDiff é a resposta de GET .../revisions/diff.
+type censusRevisionDiff struct {
+	CensusID int                 `json:"census_id"`
+	From     int                 `json:"from"`
+	To       int                 `json:"to"`
+	Changes  []censusFieldChange `json:"changes"`
+}
+
+// censusWriteActor identifica a origem de uma escrita pública no censo. O
+// código de acesso, quando presente, já foi verificado por
+// authorizeSchoolWrite.
+func censusWriteActor(r *http.Request) models.CensusActor {
+	kind := models.CensusActorForm
+	if strings.TrimSpace(r.Header.Get(headerSchoolAccess)) != "" {
+		kind = models.CensusActorSchool
+	}
+	return models.CensusActor{Kind: kind, IP: clientIP(r)}
+}
+
+// decodeCensusData lê um JSON de census_responses.data; vazio ou null
+// viram mapa vazio.
+func decodeCensusData(raw json.RawMessage) (map[string]any, error) {
+	out := map[string]any{}
+	if len(raw) == 0 {
+		return out, nil
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = map[string]any{}
+	}
+	return out, nil
+}
+
+// diffCensusData compara dois estados do censo campo a campo. A ordem segue
+// o catálogo (etapas do formulário); chaves fora dele vêm no fim, em ordem
+// alfabética. null e ausência são equivalentes.
+func diffCensusData(before, after map[string]any) []censusFieldChange {
+	keys := map[string]bool{}
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+
+	changes := []censusFieldChange{}
+	add := func(k string) {
+		b, a := before[k], after[k]
+		var kind string
+		switch {
+		case b == nil && a == nil:
+			return
+		case b == nil:
+			kind = changeAdded
+		case a == nil:
+			kind = changeRemoved
+		case reflect.DeepEqual(a, b):
+			return
+		default:
+			kind = changeUpdated
+		}
+		changes = append(changes, censusFieldChange{
+			Field: k, Step: censusCatalogIndex[k].Step, Change: kind, Before: b, After: a,
+		})
+	}
+
+	for _, f := range censusCatalog {
+		if keys[f.Key] {
+			add(f.Key)
+			delete(keys, f.Key)
+		}
+	}
+	rest := make([]string, 0, len(keys))
+	for k := range keys {
+		rest = append(rest, k)
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		add(k)
+	}
+	return changes
+}
+
+// censusIDParam lê o {id} (census_responses.id) das rotas de censo.
+func censusIDParam(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("id inválido")
+	}
+	return id, nil
+}
+
+// scopedCensusID lê o {id} da rota e confere o recorte de DRE da conta.
+// Em caso de falha a resposta já foi escrita.
+func (app *application) scopedCensusID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := censusIDParam(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return 0, false
+	}
+	dre, err := app.censusDRE(r.Context(), id)
+	if err == sql.ErrNoRows {
+		app.errorJSON(w, fmt.Errorf("censo não encontrado"), http.StatusNotFound)
+		return 0, false
+	}
+	if err != nil {
+		app.logger.Printf("scopedCensusID: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar censo"), http.StatusInternalServerError)
+		return 0, false
+	}
+	if scope := adminDREScope(r.Context()); scope != "" && !sameDRE(dre, scope) {
+		app.errorJSON(w, errDREForaDoEscopo, http.StatusForbidden)
+		return 0, false
+	}
+	return id, true
+}
+
+// AdminListCensusRevisions lista o histórico de escritas de um censo.
+func (app *application) AdminListCensusRevisions(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.scopedCensusID(w, r)
+	if !ok {
+		return
+	}
+	revs, err := app.models.CensusRevisions.List(r.Context(), id)
+	if err != nil {
+		app.logger.Printf("AdminListCensusRevisions: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar revisões"), http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]censusRevisionSummary, 0, len(revs))
+	for _, rev := range revs {
+		before, err1 := decodeCensusData(rev.PreviousData)
+		after, err2 := decodeCensusData(rev.Data)
+		fields := []string{}
+		if err1 == nil && err2 == nil {
+			for _, c := range diffCensusData(before, after) {
+				fields = append(fields, c.Field)
+			}
+		}
+		out = append(out, censusRevisionSummary{
+			Revision:       rev.Revision,
+			PreviousStatus: rev.PreviousStatus,
+			Status:         rev.Status,
+			ActorKind:      rev.ActorKind,
+			Actor:          rev.Actor,
+			ClientIP:       rev.ClientIP,
+			CreatedAt:      rev.CreatedAt,
+			ChangedFields:  fields,
+		})
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: out})
+}
+
+// AdminDiffCensusRevisions compara duas revisões de um censo campo a campo.
+func (app *application) AdminDiffCensusRevisions(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.scopedCensusID(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	parseRev := func(name string) (int, bool, error) {
+		v := strings.TrimSpace(q.Get(name))
+		if v == "" {
+			return 0, false, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, false, fmt.Errorf("%s inválido", name)
+		}
+		return n, true, nil
+	}
+	from, hasFrom, err := parseRev("from")
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	to, hasTo, err := parseRev("to")
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	revs, err := app.models.CensusRevisions.List(r.Context(), id)
+	if err != nil {
+		app.logger.Printf("AdminDiffCensusRevisions: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar revisões"), http.StatusInternalServerError)
+		return
+	}
+	if len(revs) == 0 {
+		app.errorJSON(w, fmt.Errorf("censo sem revisões registradas"), http.StatusNotFound)
+		return
+	}
+	byNumber := make(map[int]*models.CensusRevision, len(revs))
+	for _, rev := range revs {
+		byNumber[rev.Revision] = rev
+	}
+
+	if !hasTo {
+		to = revs[len(revs)-1].Revision
+	}
+	toRev := byNumber[to]
+	if toRev == nil {
+		app.errorJSON(w, fmt.Errorf("revisão %d não encontrada", to), http.StatusNotFound)
+		return
+	}
+
+	var beforeRaw json.RawMessage
+	switch {
+	case !hasFrom:
+		from = to - 1
+		beforeRaw = toRev.PreviousData
+	case from == 0:
+	default:
+		fromRev := byNumber[from]
+		if fromRev == nil {
+			app.errorJSON(w, fmt.Errorf("revisão %d não encontrada", from), http.StatusNotFound)
+			return
+		}
+		beforeRaw = fromRev.Data
+	}
+
+	before, err1 := decodeCensusData(beforeRaw)
+	after, err2 := decodeCensusData(toRev.Data)
+	if err1 != nil || err2 != nil {
+		app.errorJSON(w, fmt.Errorf("JSON da revisão ilegível"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: censusRevisionDiff{
+		CensusID: id, From: from, To: to, Changes: diffCensusData(before, after),
+	}})
+}
@@ -0,0 +1,79 @@
+package main
+
+// Testes do histórico de revisões do censo. Sem banco: cobrem a diferença
+// campo a campo (tipos de alteração, ordem do catálogo, null como ausência)
+// e a identificação da origem de uma escrita pública.
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"censo-api/internal/models"
+)
+
+func TestDiffCensusData(t *testing.T) {
+	var before, after map[string]any
+	json.Unmarshal([]byte(`{
+		"zz_legado": 1, "nome_responsavel": "ANA", "tipo_predio": "Alugado",
+		"ambientes": ["Biblioteca"], "qtd_anexos": null, "total_alunos": 10
+	}`), &before)
+	json.Unmarshal([]byte(`{
+		"tipo_predio": "Próprio", "ambientes": ["Biblioteca", "Cozinha"],
+		"total_alunos": 10, "qtd_anexos": null, "possui_anexos": "Não",
+		"aa_extra": true
+	}`), &after)
+
+	got := diffCensusData(before, after)
+	want := []struct{ field, change string }{
+		{"tipo_predio", changeUpdated},
+		{"possui_anexos", changeAdded},
+		{"ambientes", changeUpdated},
+		{"nome_responsavel", changeRemoved},
+		{"aa_extra", changeAdded},
+		{"zz_legado", changeRemoved},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("alterações = %+v; want %d", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Field != w.field || got[i].Change != w.change {
+			t.Errorf("[%d] = %s/%s; want %s/%s", i, got[i].Field, got[i].Change, w.field, w.change)
+		}
+	}
+	if got[0].Step != "general" || got[0].Before != "Alugado" || got[0].After != "Próprio" {
+		t.Errorf("tipo_predio = %+v", got[0])
+	}
+	if got[4].Step != "" {
+		t.Errorf("chave fora do catálogo com etapa %q", got[4].Step)
+	}
+	if d := diffCensusData(after, after); len(d) != 0 {
+		t.Errorf("estado igual gerou %d alteração(ões)", len(d))
+	}
+}
+
+func TestDecodeCensusData(t *testing.T) {
+	for _, raw := range []string{"", "null", "{}"} {
+		m, err := decodeCensusData(json.RawMessage(raw))
+		if err != nil || m == nil || len(m) != 0 {
+			t.Errorf("decodeCensusData(%q) = %v, %v", raw, m, err)
+		}
+	}
+	if _, err := decodeCensusData(json.RawMessage(`[1]`)); err == nil {
+		t.Error("array aceito como dados do censo")
+	}
+}
+
+func TestCensusWriteActor(t *testing.T) {
+	t.Setenv("TRUSTED_PROXY_COUNT", "0")
+	req := httptest.NewRequest(http.MethodPost, "/v1/census", nil)
+	req.RemoteAddr = "10.1.2.3:5555"
+	if a := censusWriteActor(req); a.Kind != models.CensusActorForm || a.IP != "10.1.2.3" {
+		t.Errorf("sem código = %+v", a)
+	}
+	req.Header.Set(headerSchoolAccess, "ABCD-EFGH-JKLM")
+	if a := censusWriteActor(req); a.Kind != models.CensusActorSchool {
+		t.Errorf("com código = %+v", a)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"censo-api/internal/models"
+)
+
+// =====================================================================
+// Pré-preenchimento do censo a partir do ano anterior
+// =====================================================================
+// Boa parte do censo descreve o prédio e mudou pouco de um ano para o
+// outro. POST /v1/census/seed cria o rascunho do ano N com as respostas
+// "estruturais" do censo N-1 da escola; o diretor confirma ou corrige em
+// vez de redigitar.
+//
+//   - GET  /v1/census/seed?school_id=&year=  prévia: o que seria copiado
+//     ou, se o rascunho já existe, os campos copiados ainda pendentes.
+//   - POST /v1/census/seed  {school_id, year}  cria o rascunho (409 se o
+//     censo do ano já existe, 404 se não há censo do ano anterior).
+//
+// As chaves copiadas ficam em census_responses.seeded_fields e saem de lá
+// quando uma escrita do formulário as inclui (confirmação ou edição). A
+// lista de chaves vem de CENSUS_CARRY_FORWARD_KEYS (separadas por
+// vírgula); sem ela vale defaultCarryForwardKeys.
+// =====================================================================
+
+// defaultCarryForwardKeys são respostas sobre a estrutura da escola, que
+// tendem a se repetir de um ano para o outro. Matrícula, turmas, pessoal e
+// avaliações de estado ficam de fora: precisam ser respondidas de novo.
+var defaultCarryForwardKeys = []string{
+	"tipo_predio", "possui_anexos", "qtd_anexos", "tipo_predio_anexo",
+	"etapas_ofertadas", "modalidades_ofertadas", "qtd_salas_aula",
+	"muro_cerca", "perimetro_fechado", "ambientes", "quadra_coberta", "qtd_quadras",
+	"banheiros_alunos", "banheiros_prof", "banheiros_chuveiro",
+	"energia", "transformador", "estrutura_climatizacao",
+	"tamanho_cozinha", "possui_refeitorio",
+	"empresa_terceirizada_merenda", "empresa_terceirizada_sg",
+	"possui_guarita", "empresa_terceirizada_portaria",
+	"internet_disponivel", "provedor_internet",
+}
+
+// carryForwardKeys devolve as chaves copiadas do ano anterior. Chaves fora
+// do catálogo são ignoradas: o rascunho semeado precisa passar na mesma
+// validação de uma escrita do formulário.
+func carryForwardKeys() []string {
+	keys := defaultCarryForwardKeys
+	if v := strings.TrimSpace(os.Getenv("CENSUS_CARRY_FORWARD_KEYS")); v != "" {
+		keys = strings.Split(v, ",")
+	}
+	seen := make(map[string]bool, len(keys))
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		if _, ok := censusCatalogIndex[k]; !ok || seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, k)
+	}
+	return out
+}
+
+// seedCensusData copia de prev as respostas das chaves em keys que estão
+// preenchidas e continuam válidas no catálogo atual. Respostas dependentes
+// cuja condição não vale mais são descartadas. Devolve os dados do
+// rascunho e as chaves copiadas, na ordem de keys.
+func seedCensusData(prev map[string]any, keys []string) (map[string]any, []string) {
+	data := map[string]any{}
+	for _, k := range keys {
+		raw, ok := prev[k]
+		if !ok || isBlankAnswer(raw) {
+			continue
+		}
+		if _, code, _ := checkCensusValue(censusCatalogIndex[k], raw); code != "" {
+			continue
+		}
+		data[k] = raw
+	}
+	pruneDependentAnswers(data)
+
+	seeded := make([]string, 0, len(data))
+	for _, k := range keys {
+		if _, ok := data[k]; ok {
+			seeded = append(seeded, k)
+		}
+	}
+	return data, seeded
+}
+
+// pendingSeeded devolve os campos semeados que o diretor ainda não
+// confirmou: os que a escrita não tocou (written) e que continuam no
+// censo (data).
+func pendingSeeded(seeded []string, data, written map[string]any) []string {
+	out := []string{}
+	for _, k := range seeded {
+		if _, ok := written[k]; ok {
+			continue
+		}
+		if _, ok := data[k]; !ok {
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// seedFieldRefs anota cada chave com a etapa do formulário.
+func seedFieldRefs(keys []string) []censusFieldRef {
+	out := make([]censusFieldRef, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, censusFieldRef{Field: k, Step: censusCatalogIndex[k].Step})
+	}
+	return out
+}
+
+// censusFieldRef identifica um campo do censo e a etapa em que aparece.
+type censusFieldRef struct {
+	Field string `json:"field"`
+	Step  string `json:"step,omitempty"`
+}
+
+// censusSeedPreview é a resposta de GET /v1/census/seed.
+type censusSeedPreview struct {
+	Year      int              `json:"year"`
+	FromYear  int              `json:"from_year"`
+	Exists    bool             `json:"exists"`
+	Available bool             `json:"available"`
+	Fields    []censusFieldRef `json:"fields"`
+}
+
+// previousCensusSeed carrega o censo N-1 da escola e calcula o rascunho
+// semeado. prev é nil quando não há censo do ano anterior.
+func (app *application) previousCensusSeed(schoolID, year int) (prev *models.CensusResponse, data map[string]any, seeded []string, err error) {
+	prev, err = app.models.Census.GetBySchoolID(schoolID, year-1)
+	if err != nil || prev == nil {
+		return prev, nil, nil, err
+	}
+	prevData, err := decodeCensusData(prev.Data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, seeded = seedCensusData(prevData, carryForwardKeys())
+	return prev, data, seeded, nil
+}
+
+// GetCensoSeed mostra o que o pré-preenchimento copiaria ou, se o censo do
+// ano já existe, os campos semeados ainda pendentes de confirmação.
+func (app *application) GetCensoSeed(w http.ResponseWriter, r *http.Request) {
+	schoolID, err := strconv.Atoi(r.URL.Query().Get("school_id"))
+	if err != nil || schoolID <= 0 {
+		app.errorJSON(w, fmt.Errorf("school_id inválido"), http.StatusBadRequest)
+		return
+	}
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil || year == 0 {
+		year = time.Now().Year()
+	}
+
+	out := censusSeedPreview{Year: year, FromYear: year - 1, Fields: []censusFieldRef{}}
+	censo, err := app.models.Census.GetBySchoolID(schoolID, year)
+	if err != nil {
+		app.logger.Printf("GetCensoSeed: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar censo"), http.StatusInternalServerError)
+		return
+	}
+	if censo != nil {
+		out.Exists = true
+		if censo.SeededFromYear != nil {
+			out.FromYear = *censo.SeededFromYear
+		}
+		out.Fields = seedFieldRefs(censo.SeededFields)
+		app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: out})
+		return
+	}
+
+	prev, _, seeded, err := app.previousCensusSeed(schoolID, year)
+	if err != nil {
+		app.logger.Printf("GetCensoSeed: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar censo do ano anterior"), http.StatusInternalServerError)
+		return
+	}
+	if prev != nil && len(seeded) > 0 {
+		out.Available = true
+		out.Fields = seedFieldRefs(seeded)
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: out})
+}
+
+// SeedCenso cria o rascunho do ano a partir do censo do ano anterior.
+func (app *application) SeedCenso(w http.ResponseWriter, r *http.Request) {
+	if !app.allowRate(r, censusWriteLimit, ipKey(r)) {
+		w.Header().Set("Retry-After", "600")
+		app.errorJSON(w, fmt.Errorf("muitas requisições. Aguarde alguns minutos"), http.StatusTooManyRequests)
+		return
+	}
+
+	var req struct {
+		SchoolID int `json:"school_id"`
+		Year     int `json:"year"`
+	}
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	if req.SchoolID <= 0 {
+		app.errorJSON(w, fmt.Errorf("school_id inválido"), http.StatusBadRequest)
+		return
+	}
+	if !app.authorizeSchoolWrite(w, r, req.SchoolID) {
+		return
+	}
+	if !app.allowRate(r, schoolWriteLimit, schoolKey(req.SchoolID)) {
+		w.Header().Set("Retry-After", "600")
+		app.errorJSON(w, fmt.Errorf("muitas requisições para esta escola. Aguarde alguns minutos"), http.StatusTooManyRequests)
+		return
+	}
+
+	year := req.Year
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	if !app.requireCampaignOpen(w, r, req.SchoolID, year) {
+		return
+	}
+
+	prev, data, seeded, err := app.previousCensusSeed(req.SchoolID, year)
+	if err != nil {
+		app.logger.Printf("SeedCenso: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar censo do ano anterior"), http.StatusInternalServerError)
+		return
+	}
+	if prev == nil {
+		app.errorJSON(w, fmt.Errorf("a escola não tem censo de %d para copiar", year-1), http.StatusNotFound)
+		return
+	}
+	if len(seeded) == 0 {
+		app.errorJSON(w, fmt.Errorf("o censo de %d não tem respostas a copiar", year-1), http.StatusUnprocessableEntity)
+		return
+	}
+
+	finalData, err := json.Marshal(data)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	fromYear := year - 1
+	censo := models.CensusResponse{
+		SchoolID:       req.SchoolID,
+		Year:           year,
+		Status:         models.CensusStatusDraft,
+		Data:           finalData,
+		SeededFromYear: &fromYear,
+		SeededFields:   seeded,
+	}
+	// Versão 0 = o censo do ano ainda não pode existir: o pré-preenchimento
+	// nunca sobrescreve respostas já dadas.
+	noCensus := 0
+	err = app.models.Census.Upsert(&censo, censusWriteActor(r), &noCensus)
+	if errors.Is(err, models.ErrCensusVersionConflict) || errors.Is(err, models.ErrCensusLocked) {
+		app.errorJSON(w, fmt.Errorf("o censo de %d já foi iniciado; o pré-preenchimento só cria rascunhos novos", year), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		app.logger.Printf("SeedCenso: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao gravar censo"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", censusETag(censo.Version))
+	app.writeJSON(w, http.StatusCreated, jsonResponse{Error: false,
+		Message: fmt.Sprintf("%d resposta(s) copiada(s) do censo de %d", len(seeded), fromYear),
+		Data:    censo})
+}
@@ -0,0 +1,63 @@
+package main
+
+// Testes do pré-preenchimento do censo. Sem banco: cobrem a lista de
+// chaves (padrão e CENSUS_CARRY_FORWARD_KEYS), a seleção das respostas
+// copiadas e o desconto dos campos confirmados pelo diretor.
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCarryForwardKeys(t *testing.T) {
+	t.Setenv("CENSUS_CARRY_FORWARD_KEYS", "")
+	keys := carryForwardKeys()
+	if len(keys) != len(defaultCarryForwardKeys) {
+		t.Errorf("padrão com chaves fora do catálogo: %v", keys)
+	}
+
+	t.Setenv("CENSUS_CARRY_FORWARD_KEYS", " energia, nao_existe,energia ,tipo_predio")
+	if got := carryForwardKeys(); !reflect.DeepEqual(got, []string{"energia", "tipo_predio"}) {
+		t.Errorf("carryForwardKeys = %v", got)
+	}
+}
+
+func TestSeedCensusData(t *testing.T) {
+	prev := map[string]any{
+		"tipo_predio":       "Próprio",
+		"possui_anexos":     "Não",
+		"qtd_anexos":        2.0,
+		"qtd_salas_aula":    "",
+		"energia":           "Geração própria",
+		"total_alunos":      300.0,
+		"qtd_quadras":       -1.0,
+		"tipo_predio_anexo": nil,
+	}
+	keys := []string{"tipo_predio", "possui_anexos", "qtd_anexos", "qtd_salas_aula", "energia", "qtd_quadras"}
+	data, seeded := seedCensusData(prev, keys)
+
+	if !reflect.DeepEqual(seeded, []string{"tipo_predio", "possui_anexos", "energia"}) {
+		t.Errorf("seeded = %v", seeded)
+	}
+	if _, ok := data["total_alunos"]; ok {
+		t.Error("chave fora da lista foi copiada")
+	}
+	if _, ok := data["qtd_anexos"]; ok {
+		t.Error("resposta dependente sem condição foi copiada")
+	}
+	if _, ok := data["qtd_quadras"]; ok {
+		t.Error("resposta inválida no catálogo atual foi copiada")
+	}
+}
+
+func TestPendingSeeded(t *testing.T) {
+	seeded := []string{"tipo_predio", "energia", "qtd_anexos"}
+	data := map[string]any{"tipo_predio": "Próprio", "energia": "Geração própria"}
+	written := map[string]any{"energia": "Outro"}
+	if got := pendingSeeded(seeded, data, written); !reflect.DeepEqual(got, []string{"tipo_predio"}) {
+		t.Errorf("pendingSeeded = %v", got)
+	}
+	if got := pendingSeeded(nil, data, written); got == nil || len(got) != 0 {
+		t.Errorf("sem semeados = %v", got)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"censo-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// =====================================================================
+// Fotografia oficial do censo (snapshots)
+// =====================================================================
+// Encerrada a campanha, o congelamento copia todos os censos do ano para
+// census_snapshots sob um lote com o hash do conteúdo. Os lotes são
+// imutáveis; os números publicados a partir deles não mudam com edições
+// tardias.
+//
+//   - POST /v1/admin/snapshots            {year, label}  congela (seduc_admin)
+//   - GET  /v1/admin/snapshots?year=      lotes
+//   - GET  /v1/admin/snapshots/{id}       lote com a conferência do hash
+//
+// Análises (/v1/admin/analytics/...) e relatórios (/v1/admin/reports/...)
+// aceitam ?snapshot=<id>: withCensusSnapshot abre uma transação somente
+// leitura com censo.snapshot definido, e vw_censo_fonte — a fonte de
+// censos das views e consultas analíticas — passa a devolver as linhas do
+// lote. O ano do recorte vira o ano do lote.
+// =====================================================================
+
+const (
+	contextKeyCensusSnapshot contextKey = "census_snapshot"
+	maxSnapshotLabel                    = 120
+)
+
+// analyticsQueryer é o que as consultas analíticas usam do banco: o pool
+// (*sql.DB) ou a transação do snapshot (*sql.Tx).
+type analyticsQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// analyticsDB devolve a transação do snapshot da requisição, se houver, ou
+// o pool.
+func (app *application) analyticsDB(ctx context.Context) analyticsQueryer {
+	if tx, ok := ctx.Value(contextKeyCensusSnapshot).(*sql.Tx); ok {
+		return tx
+	}
+	return app.models.Schools.DB
+}
+
+// parseSnapshotID lê ?snapshot=; ok=false quando ausente.
+func parseSnapshotID(raw string) (id int64, ok bool, err error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, false, nil
+	}
+	id, err = strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, true, fmt.Errorf("snapshot inválido")
+	}
+	return id, true, nil
+}
+
+// withCensusSnapshot faz a requisição ler o lote de ?snapshot=<id> em vez
+// do censo vivo. Sem o parâmetro, não altera nada.
+func (app *application) withCensusSnapshot(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok, err := parseSnapshotID(r.URL.Query().Get("snapshot"))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		snap, err := app.models.CensusSnapshots.Get(r.Context(), id)
+		if err != nil {
+			app.logger.Printf("withCensusSnapshot: %v", err)
+			app.errorJSON(w, fmt.Errorf("erro ao consultar snapshot"), http.StatusInternalServerError)
+			return
+		}
+		if snap == nil {
+			app.errorJSON(w, fmt.Errorf("snapshot não encontrado"), http.StatusNotFound)
+			return
+		}
+
+		tx, err := app.models.Schools.DB.BeginTx(r.Context(), &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			app.logger.Printf("withCensusSnapshot: %v", err)
+			app.errorJSON(w, fmt.Errorf("erro ao abrir snapshot"), http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(r.Context(), `SELECT set_config('censo.snapshot', $1, true)`,
+			strconv.FormatInt(snap.ID, 10)); err != nil {
+			app.logger.Printf("withCensusSnapshot: %v", err)
+			app.errorJSON(w, fmt.Errorf("erro ao abrir snapshot"), http.StatusInternalServerError)
+			return
+		}
+
+		q := r.URL.Query()
+		q.Set("year", strconv.Itoa(snap.Year))
+		r.URL.RawQuery = q.Encode()
+		w.Header().Set("X-Census-Snapshot", fmt.Sprintf("%d; sha256=%s", snap.ID, snap.ContentHash))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyCensusSnapshot, tx)))
+	})
+}
+
+// AdminFreezeCensus congela os censos do ano num novo lote. Com campanha
+// cadastrada, só depois do encerramento (prorrogações incluídas).
+func (app *application) AdminFreezeCensus(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Year  int    `json:"year"`
+		Label string `json:"label"`
+	}
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	if req.Year <= 0 {
+		app.errorJSON(w, fmt.Errorf("ano inválido"), http.StatusBadRequest)
+		return
+	}
+	req.Label = strings.TrimSpace(req.Label)
+	if utf8.RuneCountInString(req.Label) > maxSnapshotLabel {
+		app.errorJSON(w, fmt.Errorf("label excede %d caracteres", maxSnapshotLabel), http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := app.models.CensusCampaigns.Get(r.Context(), req.Year)
+	if err != nil {
+		app.logger.Printf("AdminFreezeCensus: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar campanha do censo"), http.StatusInternalServerError)
+		return
+	}
+	if until := campaignOpenUntil(campaign, time.Now()); until != "" {
+		app.errorJSON(w, fmt.Errorf("a campanha do censo %d segue aberta até %s", req.Year, formatCampaignDate(until)),
+			http.StatusConflict)
+		return
+	}
+
+	admin, _ := adminFromContext(r.Context())
+	snap, err := app.models.CensusSnapshots.Freeze(r.Context(), req.Year, req.Label, admin.Username)
+	if errors.Is(err, models.ErrSnapshotEmpty) {
+		app.errorJSON(w, fmt.Errorf("não há censos de %d para congelar", req.Year), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		app.logger.Printf("AdminFreezeCensus: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao congelar censos"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusCreated, jsonResponse{Error: false,
+		Message: fmt.Sprintf("%d censo(s) de %d congelado(s) no snapshot %d", snap.RowCount, snap.Year, snap.ID),
+		Data:    snap})
+}
+
+// campaignOpenUntil devolve o último prazo ainda não vencido da campanha
+// (encerramento ou prorrogação), ou "" se já encerrou para todas as DREs.
+func campaignOpenUntil(c *models.CensusCampaign, now time.Time) string {
+	if c == nil {
+		return ""
+	}
+	last := c.ClosesOn
+	for _, e := range c.Extensions {
+		if e.ClosesOn > last {
+			last = e.ClosesOn
+		}
+	}
+	deadline, err := parseCampaignDate(last)
+	if err != nil || campaignDay(now).After(deadline) {
+		return ""
+	}
+	return last
+}
+
+// AdminListSnapshots lista os lotes congelados (?year= filtra o ano).
+func (app *application) AdminListSnapshots(w http.ResponseWriter, r *http.Request) {
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+	snaps, err := app.models.CensusSnapshots.List(r.Context(), year)
+	if err != nil {
+		app.logger.Printf("AdminListSnapshots: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar snapshots"), http.StatusInternalServerError)
+		return
+	}
+	if snaps == nil {
+		snaps = []*models.CensusSnapshot{}
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: snaps})
+}
+
+// AdminGetSnapshot devolve o lote e confere o hash das linhas gravadas
+// contra o registrado no congelamento.
+func (app *application) AdminGetSnapshot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		app.errorJSON(w, fmt.Errorf("id inválido"), http.StatusBadRequest)
+		return
+	}
+	snap, err := app.models.CensusSnapshots.Get(r.Context(), id)
+	if err != nil {
+		app.logger.Printf("AdminGetSnapshot: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar snapshot"), http.StatusInternalServerError)
+		return
+	}
+	if snap == nil {
+		app.errorJSON(w, fmt.Errorf("snapshot não encontrado"), http.StatusNotFound)
+		return
+	}
+	n, hash, err := app.models.CensusSnapshots.Verify(r.Context(), id)
+	if err != nil {
+		app.logger.Printf("AdminGetSnapshot: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao conferir snapshot"), http.StatusInternalServerError)
+		return
+	}
+	out := struct {
+		*models.CensusSnapshot
+		Intact bool `json:"intact"`
+	}{snap, n == snap.RowCount && hash == snap.ContentHash}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: out})
+}
@@ -0,0 +1,95 @@
+package main
+
+// Testes da fotografia oficial do censo. Sem banco: cobrem a leitura de
+// ?snapshot=, a escolha entre pool e transação do snapshot, a passagem do
+// middleware sem o parâmetro e a trava de congelamento com a campanha aberta.
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseSnapshotID(t *testing.T) {
+	for raw, want := range map[string]struct {
+		id      int64
+		ok, bad bool
+	}{
+		"":    {},
+		"  ":  {},
+		"42":  {id: 42, ok: true},
+		" 7 ": {id: 7, ok: true},
+		"0":   {ok: true, bad: true},
+		"-3":  {ok: true, bad: true},
+		"abc": {ok: true, bad: true},
+		"1.5": {ok: true, bad: true},
+	} {
+		id, ok, err := parseSnapshotID(raw)
+		if id != want.id || ok != want.ok || (err != nil) != want.bad {
+			t.Errorf("parseSnapshotID(%q) = %d, %v, %v", raw, id, ok, err)
+		}
+	}
+}
+
+func TestAnalyticsDB(t *testing.T) {
+	app := &application{}
+	if _, ok := app.analyticsDB(context.Background()).(*sql.DB); !ok {
+		t.Error("sem snapshot deveria usar o pool")
+	}
+	tx := &sql.Tx{}
+	ctx := context.WithValue(context.Background(), contextKeyCensusSnapshot, tx)
+	if got := app.analyticsDB(ctx); got != tx {
+		t.Errorf("com snapshot = %T", got)
+	}
+}
+
+func TestWithCensusSnapshot(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	called := false
+	h := app.withCensusSnapshot(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if r.Context().Value(contextKeyCensusSnapshot) != nil {
+			t.Error("transação de snapshot sem ?snapshot=")
+		}
+		if r.URL.Query().Get("year") != "2025" {
+			t.Errorf("year alterado: %s", r.URL.RawQuery)
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/x?year=2025", nil))
+	if !called || rec.Header().Get("X-Census-Snapshot") != "" {
+		t.Errorf("sem snapshot: called=%v header=%q", called, rec.Header().Get("X-Census-Snapshot"))
+	}
+
+	called = false
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/x?year=2025&snapshot=abc", nil))
+	if called || rec.Code != http.StatusBadRequest {
+		t.Errorf("snapshot inválido: called=%v status=%d", called, rec.Code)
+	}
+}
+
+func TestCampaignOpenUntil(t *testing.T) {
+	c := testCampaign()
+	if got := campaignOpenUntil(nil, belem(2026, 4, 1, 9)); got != "" {
+		t.Errorf("sem campanha = %q", got)
+	}
+	if got := campaignOpenUntil(c, belem(2026, 4, 1, 9)); got != "2026-05-15" {
+		t.Errorf("aberta = %q; a prorrogação mais longa vale", got)
+	}
+	if got := campaignOpenUntil(c, belem(2026, 5, 15, 23)); got != "2026-05-15" {
+		t.Errorf("último dia da prorrogação = %q", got)
+	}
+	if got := campaignOpenUntil(c, belem(2026, 5, 16, 0)); got != "" {
+		t.Errorf("encerrada = %q", got)
+	}
+	c.Extensions = nil
+	if got := campaignOpenUntil(c, belem(2026, 5, 3, 9)); got != "" {
+		t.Errorf("encerrada sem prorrogação = %q", got)
+	}
+}
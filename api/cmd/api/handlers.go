@@ -2,38 +2,16 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"censo-api/internal/models"
 )
 
-func (app *application) GetLocations(w http.ResponseWriter, r *http.Request) {
-	if app.sheets == nil {
-		app.errorJSON(w, fmt.Errorf("serviço de planilhas indisponível"), http.StatusInternalServerError)
-		return
-	}
-
-	locations, err := app.sheets.GetLocations()
-	if err != nil {
-		app.logger.Printf("GetLocations: %v", err)
-		app.errorJSON(w, fmt.Errorf("erro ao buscar locais"), http.StatusInternalServerError)
-		return
-	}
-
-	payload := jsonResponse{
-		Error: false,
-		Data:  locations,
-	}
-	app.writeJSON(w, http.StatusOK, payload)
-}
-
 func (app *application) GetSchools(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 
@@ -81,7 +59,7 @@ func (app *application) GetSchools(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) CreateSchool(w http.ResponseWriter, r *http.Request) {
-	if !censusWriteRL.allow(clientIP(r), maxCensusWrites, censusWindow) {
+	if !app.allowRate(r, censusWriteLimit, ipKey(r)) {
 		w.Header().Set("Retry-After", "600")
 		app.errorJSON(w, fmt.Errorf("muitas requisições. Aguarde alguns minutos"), http.StatusTooManyRequests)
 		return
@@ -95,17 +73,62 @@ func (app *application) CreateSchool(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := app.models.Schools.Insert(req)
+	// O cadastro é um upsert por codigo_inep: o código de acesso precisa ser
+	// o da escola já cadastrada com esse INEP. INEP novo, sem código, cria a
+	// escola e a vincula a um código emitido na hora (ver
+	// createSchoolWithAccess); um código de outra escola não cria escola.
+	existingID, err := app.models.Schools.IDByINEP(req.INEP)
+	if errors.Is(err, models.ErrSchoolMerged) {
+		app.errorJSON(w, fmt.Errorf("o INEP informado é de uma escola fundida em outra"), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("erro ao verificar escola"), http.StatusInternalServerError)
+		return
+	}
+
+	var id int
+	var access *issuedSchoolAccess
+	if existingID == 0 && r.Header.Get(headerSchoolAccess) == "" {
+		id, access, err = app.createSchoolWithAccess(r.Context(), req)
+	} else {
+		if !app.authorizeSchoolWrite(w, r, existingID) {
+			return
+		}
+		id, err = app.models.Schools.Insert(req)
+	}
+	if errors.Is(err, models.ErrSchoolINEPInUse) {
+		app.errorJSON(w, fmt.Errorf("escola já cadastrada com este INEP; use o código de acesso dela"), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errSchoolAccessNotIssued) {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
 	if err != nil {
 		app.errorJSON(w, err)
 		return
 	}
 
 	req.ID = id
+	// municipio_id/dre_id são resolvidos pelo trigger a partir do texto;
+	// texto sem correspondência não impede o cadastro, só gera aviso.
+	var warnings []censusFieldIssue
+	req.MunicipioID, req.DreID, err = app.models.References.SchoolReferences(r.Context(), id)
+	if err != nil {
+		app.logger.Printf("CreateSchool: %v", err)
+	} else {
+		warnings = schoolReferenceWarnings(req)
+	}
+	var data interface{} = req
+	if access != nil {
+		data = createdSchool{School: req, AccessCode: access.Code, AccessLink: access.Link}
+	}
 	payload := jsonResponse{
-		Error:   false,
-		Message: "Escola criada com sucesso",
-		Data:    req,
+		Error:    false,
+		Message:  "Escola criada com sucesso",
+		Data:     data,
+		Warnings: warnings,
 	}
 
 	app.writeJSON(w, http.StatusCreated, payload)
@@ -133,17 +156,21 @@ func (app *application) GetCenso(w http.ResponseWriter, r *http.Request) {
 	censo, err := app.models.Census.GetBySchoolID(schoolID, year)
 
 	if err != nil || censo == nil {
+		if err == nil {
+			w.Header().Set("ETag", censusETag(0))
+		}
 		payload := jsonResponse{Error: false, Data: nil}
 		app.writeJSON(w, http.StatusOK, payload)
 		return
 	}
 
+	w.Header().Set("ETag", censusETag(censo.Version))
 	payload := jsonResponse{Error: false, Data: censo.Data}
 	app.writeJSON(w, http.StatusOK, payload)
 }
 
 func (app *application) CreateOrUpdateCenso(w http.ResponseWriter, r *http.Request) {
-	if !censusWriteRL.allow(clientIP(r), maxCensusWrites, censusWindow) {
+	if !app.allowRate(r, censusWriteLimit, ipKey(r)) {
 		w.Header().Set("Retry-After", "600")
 		app.errorJSON(w, fmt.Errorf("muitas requisições. Aguarde alguns minutos"), http.StatusTooManyRequests)
 		return
@@ -154,6 +181,9 @@ func (app *application) CreateOrUpdateCenso(w http.ResponseWriter, r *http.Reque
 		Year     int             `json:"year"`
 		Status   string          `json:"status"`
 		Data     json.RawMessage `json:"data"`
+		// Merge relaxa o If-Match: só campos que divergiram de fato são
+		// recusados (ver census_concurrency.go).
+		Merge bool `json:"merge"`
 	}
 
 	err := app.readJSON(w, r, &req)
@@ -162,19 +192,49 @@ func (app *application) CreateOrUpdateCenso(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !app.authorizeSchoolWrite(w, r, req.SchoolID) {
+		return
+	}
+	if !app.allowRate(r, schoolWriteLimit, schoolKey(req.SchoolID)) {
+		w.Header().Set("Retry-After", "600")
+		app.errorJSON(w, fmt.Errorf("muitas requisições para esta escola. Aguarde alguns minutos"), http.StatusTooManyRequests)
+		return
+	}
+
 	year := req.Year
 	if year == 0 {
 		year = time.Now().Year()
 	}
+	status, err := publicCensusStatus(req.Status)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.requireCampaignOpen(w, r, req.SchoolID, year) {
+		return
+	}
+
+	var newMap map[string]interface{}
+	if err := json.Unmarshal(req.Data, &newMap); err != nil {
+		app.errorJSON(w, fmt.Errorf("data deve ser um objeto JSON"), http.StatusBadRequest)
+		return
+	}
 
 	existingCenso, err := app.models.Census.GetBySchoolID(req.SchoolID, year)
-	var finalData []byte
+	if err != nil {
+		existingCenso = nil
+	}
 
-	if err == nil && existingCenso != nil {
+	// Concorrência otimista: If-Match contra a versão gravada.
+	ifVersion, ok := app.checkCensusVersion(w, r, existingCenso, newMap, req.Merge)
+	if !ok {
+		return
+	}
+
+	written := newMap
+	if existingCenso != nil {
 		var oldMap map[string]interface{}
-		var newMap map[string]interface{}
 		_ = json.Unmarshal(existingCenso.Data, &oldMap)
-		_ = json.Unmarshal(req.Data, &newMap)
 
 		if oldMap == nil {
 			oldMap = make(map[string]interface{})
@@ -182,229 +242,84 @@ func (app *application) CreateOrUpdateCenso(w http.ResponseWriter, r *http.Reque
 		for k, v := range newMap {
 			oldMap[k] = v
 		}
-		finalData, _ = json.Marshal(oldMap)
-	} else {
-		finalData = req.Data
+		newMap = oldMap
 	}
+	// Respostas que dependem de outra e deixaram de fazer sentido (ex.:
+	// qtd_anexos com possui_anexos = "Não") saem do censo.
+	pruned := pruneDependentAnswers(newMap)
 
-	censo := models.CensusResponse{
-		SchoolID:  req.SchoolID,
-		Year:      year,
-		Status:    req.Status,
-		Data:      finalData,
-		UpdatedAt: time.Now(),
+	var seeded []string
+	if existingCenso != nil {
+		seeded = pendingSeeded(existingCenso.SeededFields, newMap, written)
 	}
-
-	err = app.models.Census.Upsert(&censo)
-	if err != nil {
-		app.errorJSON(w, err, http.StatusInternalServerError)
-		return
-	}
-
-	uploadMsg := ""
-
-	// LÓGICA DE FINALIZAÇÃO: Planilha e Google Drive
-	if req.Status == "completed" {
-		// 1. Enviar para Planilha — sempre que status for completed.
-		if app.sheets != nil {
-			// Busca a escola aqui (request context, conexão saudável) para não
-			// depender de DB dentro da goroutine onde a conexão pode estar stale.
-			school, err := app.models.Schools.Get(censo.SchoolID)
-			if err != nil {
-				app.logger.Println("Erro ao buscar escola para planilha:", err)
-			} else {
-				censoCopy := censo
-				go func(c models.CensusResponse, s models.School) {
-					// Usa variável local (não a 'err' da função externa) para
-					// evitar data race com o handler que segue executando.
-					if e := app.sheets.AppendCenso(c, s); e != nil {
-						app.logger.Println("Erro ao salvar na planilha:", e)
-						return
-					}
-					if e := app.models.Census.MarkSheetSynced(c.ID); e != nil {
-						app.logger.Println("Erro ao marcar sheet_synced_at:", e)
-					}
-				}(censoCopy, *school)
-			}
-		}
-
-		// 2. Processar Upload da Foto
-		tempDir := "./tmp"
-		pattern := fmt.Sprintf("%d_*", req.SchoolID)
-		matches, _ := filepath.Glob(filepath.Join(tempDir, pattern))
-
-		if len(matches) > 0 {
-			tempFilePath := matches[0]
-			
-			school, err := app.models.Schools.Get(req.SchoolID)
-			if err == nil && app.drive != nil {
-				// Função anônima para garantir fechamento do arquivo
-				errUpload := func() error {
-					file, err := os.Open(tempFilePath)
-					if err != nil {
-						return err
-					}
-					defer file.Close()
-
-					// Verifica se o arquivo tem conteúdo
-					stat, err := file.Stat()
-					if err != nil {
-						return fmt.Errorf("erro ao verificar arquivo: %v", err)
-					}
-					if stat.Size() == 0 {
-						return fmt.Errorf("arquivo vazio")
-					}
-
-					// Sanitização
-					sanitize := func(s string) string {
-						return strings.Map(func(r rune) rune {
-							if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == ' ' {
-								return r
-							}
-							return '-'
-						}, s)
-					}
-					folderName := fmt.Sprintf("%s - %s - %s", sanitize(school.Nome), sanitize(school.Dre), sanitize(school.NomeDiretor))
-					
-					// Detecta Content-Type e reseta ponteiro
-					buffer := make([]byte, 512)
-					n, _ := file.Read(buffer)
-					contentType := http.DetectContentType(buffer[:n])
-					
-					// RESET CRÍTICO: Garante que o arquivo seja lido do início no upload
-					if _, err := file.Seek(0, 0); err != nil {
-						return fmt.Errorf("falha ao resetar arquivo: %v", err)
-					}
-
-					_, filename := filepath.Split(tempFilePath)
-					originalName := strings.TrimPrefix(filename, fmt.Sprintf("%d_", req.SchoolID))
-
-					// Upload
-					link, err := app.drive.UploadSchoolPhoto(folderName, originalName, contentType, file)
-					if err != nil {
-						return err
-					}
-					app.logger.Printf("Sucesso upload Drive: %s", link)
-					return nil
-				}()
-
-				if errUpload != nil {
-					app.logger.Println("ERRO CRÍTICO DRIVE:", errUpload)
-					uploadMsg = fmt.Sprintf(" (Erro ao salvar foto: %v)", errUpload)
-				} else {
-					os.Remove(tempFilePath) // Remove apenas se sucesso
-				}
-			}
-		}
-	}
-
-	payload := jsonResponse{
-		Error:   false,
-		Message: "Censo salvo com sucesso" + uploadMsg,
-		Data:    censo,
-	}
-
-	app.writeJSON(w, http.StatusOK, payload)
+	app.saveCensus(w, r, req.SchoolID, year, status, newMap, ifVersion, pruned, seeded)
 }
 
-func (app *application) uploadPhoto(w http.ResponseWriter, r *http.Request) {
-	if !uploadRL.allow(clientIP(r), maxUploads, uploadWindow) {
-		w.Header().Set("Retry-After", "600")
-		app.errorJSON(w, fmt.Errorf("muitos uploads. Aguarde alguns minutos"), http.StatusTooManyRequests)
-		return
-	}
-
-	// Limite total do corpo da requisição a 10MB (defesa contra DoS por disco).
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		app.errorJSON(w, fmt.Errorf("arquivo muito grande ou inválido (máx. 10MB)"), http.StatusBadRequest)
-		return
-	}
-
-	file, handler, err := r.FormFile("photo")
-	if err != nil {
-		app.errorJSON(w, fmt.Errorf("arquivo inválido"), http.StatusBadRequest)
-		return
-	}
-	defer file.Close()
-
-	schoolIDStr := r.FormValue("school_id")
-	if schoolIDStr == "" {
-		app.errorJSON(w, fmt.Errorf("school_id obrigatório"), http.StatusBadRequest)
-		return
-	}
-	if _, err := strconv.Atoi(schoolIDStr); err != nil {
-		app.errorJSON(w, fmt.Errorf("school_id inválido"), http.StatusBadRequest)
+// saveCensus valida e grava os dados finais de uma escrita pública no censo
+// (POST ou PATCH /v1/census) e, na conclusão, acorda o envio à planilha.
+// pruned são as respostas dependentes descartadas, devolvidas como aviso;
+// seeded, os campos pré-preenchidos ainda não confirmados (census_seed.go).
+func (app *application) saveCensus(w http.ResponseWriter, r *http.Request, schoolID, year int, status string,
+	data map[string]interface{}, ifVersion *int, pruned, seeded []string) {
+	// Validação contra o catálogo (census_catalog.go) sobre os dados já
+	// mesclados: a conclusão exige o censo inteiro, não só a última etapa.
+	validation := validateCensusData(data, status == "completed")
+	validation.Warnings = append(validation.Warnings, dependentIssues(pruned)...)
+	if len(validation.Errors) > 0 {
+		app.writeJSON(w, http.StatusUnprocessableEntity, jsonResponse{
+			Error:    true,
+			Message:  fmt.Sprintf("censo incompleto ou inválido: %d campo(s) com problema", len(validation.Errors)),
+			Errors:   validation.Errors,
+			Warnings: validation.Warnings,
+		})
 		return
 	}
 
-	// Valida extensão (apenas imagens)
-	safeBase := filepath.Base(handler.Filename)
-	ext := strings.ToLower(filepath.Ext(safeBase))
-	allowedExts := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".gif": true}
-	if !allowedExts[ext] {
-		app.errorJSON(w, fmt.Errorf("tipo de arquivo não permitido. Use: jpg, jpeg, png, webp ou gif"), http.StatusBadRequest)
-		return
+	finalData, _ := json.Marshal(data)
+	censo := models.CensusResponse{
+		SchoolID:     schoolID,
+		Year:         year,
+		Status:       status,
+		Data:         finalData,
+		UpdatedAt:    time.Now(),
+		SeededFields: seeded,
 	}
 
-	// Valida o CONTEÚDO real (magic bytes), não só a extensão do nome — impede
-	// que um arquivo arbitrário seja salvo apenas renomeado com extensão de imagem.
-	sniff := make([]byte, 512)
-	n, _ := file.Read(sniff)
-	detected := http.DetectContentType(sniff[:n])
-	allowedTypes := map[string]bool{
-		"image/jpeg": true, "image/png": true, "image/webp": true, "image/gif": true,
-	}
-	if !allowedTypes[detected] {
-		app.errorJSON(w, fmt.Errorf("conteúdo do arquivo não é uma imagem válida"), http.StatusBadRequest)
+	err := app.models.Census.Upsert(&censo, censusWriteActor(r), ifVersion)
+	if errors.Is(err, models.ErrCensusVersionConflict) {
+		app.errorJSON(w, fmt.Errorf("o censo foi alterado durante a gravação; recarregue antes de salvar"), http.StatusConflict)
 		return
 	}
-	// Rebobina para que o io.Copy abaixo grave o arquivo inteiro.
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		app.errorJSON(w, fmt.Errorf("erro ao processar arquivo"), http.StatusInternalServerError)
+	if errors.Is(err, models.ErrCensusLocked) {
+		app.errorJSON(w, fmt.Errorf("o censo está em revisão ou foi aprovado pela DRE e não pode ser alterado"), http.StatusConflict)
 		return
 	}
-
-	// Sanitiza o nome: mantém apenas caracteres seguros
-	safeBase = strings.Map(func(r rune) rune {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
-			return r
-		}
-		return '_'
-	}, safeBase)
-
-	// Garante diretório temporário
-	tempDir := "./tmp"
-	if _, err := os.Stat(tempDir); os.IsNotExist(err) {
-		os.Mkdir(tempDir, 0700)
-	}
-
-	// Formato: ID_NomeOriginal
-	tempFilename := fmt.Sprintf("%s_%s", schoolIDStr, safeBase)
-	dstPath := filepath.Join(tempDir, tempFilename)
-
-	dst, err := os.Create(dstPath)
 	if err != nil {
-		app.errorJSON(w, fmt.Errorf("erro interno ao salvar temp: %v", err), http.StatusInternalServerError)
+		app.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	defer dst.Close()
+	w.Header().Set("ETag", censusETag(censo.Version))
 
-	if _, err := io.Copy(dst, file); err != nil {
-		app.errorJSON(w, fmt.Errorf("erro ao escrever arquivo: %v", err), http.StatusInternalServerError)
-		return
+	// Na conclusão, a planilha: o Upsert já enfileirou o envio em
+	// sync_outbox e o worker é acordado para não esperar o próximo ciclo.
+	// As fotos já foram gravadas no upload (POST /v1/upload).
+	if status == "completed" {
+		app.wakeSyncOutbox()
 	}
 
 	payload := jsonResponse{
-		Error:   false,
-		Message: "Upload recebido (será processado ao finalizar)",
-		Data:    "temp_ok",
+		Error:    false,
+		Message:  "Censo salvo com sucesso",
+		Data:     censo,
+		Warnings: validation.Warnings,
 	}
-	app.writeJSON(w, http.StatusCreated, payload)
+
+	app.writeJSON(w, http.StatusOK, payload)
 }
 
-// AdminSyncSheets força a re-sincronização imediata de todos os censos
-// completed que ainda não foram gravados na planilha.
+// AdminSyncSheets força a re-sincronização imediata: enfileira os censos
+// enviados que ainda não chegaram a cada destino, antecipa os itens
+// pendentes e processa a fila na hora (ver sync_outbox.go).
 // Protegido por SYNC_SECRET para evitar uso não autorizado.
 func (app *application) AdminSyncSheets(w http.ResponseWriter, r *http.Request) {
 	secret := os.Getenv("SYNC_SECRET")
@@ -412,37 +327,28 @@ func (app *application) AdminSyncSheets(w http.ResponseWriter, r *http.Request)
 		app.errorJSON(w, fmt.Errorf("não autorizado"), http.StatusUnauthorized)
 		return
 	}
+	if len(app.sinks) == 0 {
+		app.errorJSON(w, fmt.Errorf("nenhum destino de sincronização configurado"), http.StatusServiceUnavailable)
+		return
+	}
 
-	pending, err := app.models.Census.GetPendingSheetSync()
+	pending, err := app.models.SyncOutbox.RequeueUnsynced(r.Context())
 	if err != nil {
 		app.logger.Printf("AdminSyncSheets: %v", err)
 		app.errorJSON(w, fmt.Errorf("erro ao buscar pendentes"), http.StatusInternalServerError)
 		return
 	}
 
-	synced := 0
-	failed := 0
-	for _, c := range pending {
-		school, err := app.models.Schools.Get(c.SchoolID)
-		if err != nil {
-			app.logger.Printf("adminSync: erro escola %d: %v", c.SchoolID, err)
-			failed++
-			continue
-		}
-		if err = app.sheets.AppendCenso(*c, *school); err != nil {
-			app.logger.Printf("adminSync: erro ao enviar escola %d: %v", c.SchoolID, err)
-			failed++
-			continue
-		}
-		if err = app.models.Census.MarkSheetSynced(c.ID); err != nil {
-			app.logger.Printf("adminSync: erro ao marcar sincronizado %d: %v", c.ID, err)
-		}
-		synced++
+	synced, failed, err := app.processSyncOutbox(r.Context())
+	if err != nil {
+		app.logger.Printf("AdminSyncSheets: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao processar a fila de sincronização"), http.StatusInternalServerError)
+		return
 	}
 
 	app.writeJSON(w, http.StatusOK, jsonResponse{
 		Error:   false,
 		Message: fmt.Sprintf("Sync concluído: %d sincronizados, %d falhas", synced, failed),
-		Data:    map[string]int{"pending": len(pending), "synced": synced, "failed": failed},
+		Data:    map[string]int{"pending": pending, "synced": synced, "failed": failed},
 	})
 }
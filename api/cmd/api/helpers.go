@@ -9,6 +9,10 @@ type jsonResponse struct {
 	Error   bool        `json:"error"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
+	// Errors e Warnings trazem problemas por campo do censo
+	// (ver census_catalog.go).
+	Errors   []censusFieldIssue `json:"errors,omitempty"`
+	Warnings []censusFieldIssue `json:"warnings,omitempty"`
 }
 
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, data interface{}) error {
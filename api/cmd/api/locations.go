@@ -0,0 +1,600 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"censo-api/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// =====================================================================
+// Hierarquia de locais (DRE → município → escola)
+// =====================================================================
+// Os seletores do formulário vêm da tabela locations. No primeiro startup
+// com a tabela vazia, seedLocations a carrega de data/locations.xlsx
+// (LOCATIONS_SEED_FILE); depois, a hierarquia só muda pelo painel.
+//
+//   - GET  /v1/locations                 mapa dre → município → [escolas]
+//   - GET  /v1/locations?format=tree     árvore com o codigo_inep de cada escola
+//   - POST /v1/admin/locations           substitui a hierarquia (seduc_admin)
+//
+// A resposta pública fica em memória por locationsCacheTTL e leva ETag (o
+// SHA-256 do corpo): If-None-Match igual responde 304. A substituição pelo
+// painel invalida o cache desta instância; as demais o renovam no TTL.
+//
+// O upload (multipart "file", XLSX ou CSV com as colunas DRE, MUNICIPIO,
+// ESCOLA e, opcional, CODIGO_INEP) é validado por inteiro e comparado com
+// a hierarquia atual. Com ?dry_run=true, ou com erros, nada é gravado e a
+// resposta traz o diff e os problemas por linha. Escolas sem INEP no
+// arquivo recebem o de schools quando nome e município casam com uma única
+// escola ativa.
+// =====================================================================
+
+const (
+	locationsCacheTTL     = 5 * time.Minute
+	maxLocationsUploadMem = 10 << 20
+	defaultLocationsSeed  = "data/locations.xlsx"
+)
+
+// locationColumns mapeia cada campo aos cabeçalhos aceitos, já normalizados
+// por locationKey.
+var locationColumns = map[string][]string{
+	"dre":         {"dre", "ure", "diretoria regional"},
+	"municipio":   {"municipio"},
+	"escola":      {"escola", "nome escola", "nome_escola"},
+	"codigo_inep": {"codigo_inep", "codigo inep", "inep"},
+}
+
+// locationsCache guarda os corpos já serializados de GET /v1/locations,
+// por formato. O valor zero está pronto para uso.
+type locationsCache struct {
+	mu       sync.Mutex
+	loadedAt time.Time
+	bodies   map[string]cachedLocations
+}
+
+type cachedLocations struct {
+	body []byte
+	etag string
+}
+
+// get devolve o corpo do formato, recarregando todos os formatos com load
+// quando o cache está vazio ou venceu.
+func (c *locationsCache) get(format string, now time.Time, load func() (map[string][]byte, error)) (cachedLocations, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bodies == nil || now.Sub(c.loadedAt) >= locationsCacheTTL {
+		bodies, err := load()
+		if err != nil {
+			return cachedLocations{}, err
+		}
+		c.bodies = make(map[string]cachedLocations, len(bodies))
+		for f, body := range bodies {
+			sum := sha256.Sum256(body)
+			c.bodies[f] = cachedLocations{body: body, etag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+		}
+		c.loadedAt = now
+	}
+	return c.bodies[format], nil
+}
+
+func (c *locationsCache) invalidate() {
+	c.mu.Lock()
+	c.bodies = nil
+	c.mu.Unlock()
+}
+
+// locationTreeDRE, locationTreeMunicipio e locationTreeEscola formam a
+// resposta de ?format=tree.
+type locationTreeDRE struct {
+	Dre        string                  `json:"dre"`
+	Municipios []locationTreeMunicipio `json:"municipios"`
+}
+
+type locationTreeMunicipio struct {
+	Municipio string               `json:"municipio"`
+	Escolas   []locationTreeEscola `json:"escolas"`
+}
+
+type locationTreeEscola struct {
+	Escola string  `json:"escola"`
+	INEP   *string `json:"codigo_inep"`
+}
+
+// locationsMap monta o formato legado dos seletores: dre → município →
+// escolas em ordem alfabética.
+func locationsMap(rows []models.Location) map[string]map[string][]string {
+	out := map[string]map[string][]string{}
+	for _, l := range rows {
+		if out[l.Dre] == nil {
+			out[l.Dre] = map[string][]string{}
+		}
+		out[l.Dre][l.Municipio] = append(out[l.Dre][l.Municipio], l.Escola)
+	}
+	for _, cities := range out {
+		for _, schools := range cities {
+			sort.Strings(schools)
+		}
+	}
+	return out
+}
+
+// locationsTree monta a árvore; rows vem ordenado por DRE, município e
+// escola (LocationModel.All).
+func locationsTree(rows []models.Location) []locationTreeDRE {
+	out := []locationTreeDRE{}
+	for _, l := range rows {
+		if len(out) == 0 || out[len(out)-1].Dre != l.Dre {
+			out = append(out, locationTreeDRE{Dre: l.Dre})
+		}
+		d := &out[len(out)-1]
+		if len(d.Municipios) == 0 || d.Municipios[len(d.Municipios)-1].Municipio != l.Municipio {
+			d.Municipios = append(d.Municipios, locationTreeMunicipio{Municipio: l.Municipio})
+		}
+		m := &d.Municipios[len(d.Municipios)-1]
+		m.Escolas = append(m.Escolas, locationTreeEscola{Escola: l.Escola, INEP: l.INEP})
+	}
+	return out
+}
+
+// loadLocationBodies serializa os dois formatos de GET /v1/locations.
+func (app *application) loadLocationBodies(ctx context.Context) (map[string][]byte, error) {
+	rows, err := app.models.Locations.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bodies := map[string][]byte{}
+	for format, data := range map[string]any{"map": locationsMap(rows), "tree": locationsTree(rows)} {
+		body, err := json.Marshal(jsonResponse{Error: false, Data: data})
+		if err != nil {
+			return nil, err
+		}
+		bodies[format] = body
+	}
+	return bodies, nil
+}
+
+func (app *application) GetLocations(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "map":
+		format = "map"
+	case "tree":
+	default:
+		app.errorJSON(w, fmt.Errorf("format inválido: use map ou tree"), http.StatusBadRequest)
+		return
+	}
+
+	cached, err := app.locations.get(format, time.Now(), func() (map[string][]byte, error) {
+		return app.loadLocationBodies(r.Context())
+	})
+	if err != nil {
+		app.logger.Printf("GetLocations: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao buscar locais"), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", cached.etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(locationsCacheTTL.Seconds())))
+	if etagMatches(r.Header.Get("If-None-Match"), cached.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(cached.body)
+}
+
+// etagMatches confere If-None-Match (lista separada por vírgulas, "*" ou
+// forma fraca) contra o ETag atual.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// locationKey normaliza nomes para comparação: minúsculas, sem acento e
+// com espaços colapsados.
+func locationKey(s string) string {
+	return strings.Join(strings.Fields(normalizeSaudeSearch(s)), " ")
+}
+
+// readLocationRecords lê a primeira aba do XLSX ou o CSV (vírgula ou ponto
+// e vírgula).
+func readLocationRecords(name string, raw []byte) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".xlsx":
+		f, err := excelize.OpenReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("planilha inválida: %v", err)
+		}
+		defer f.Close()
+		return f.GetRows(f.GetSheetName(0))
+	case ".csv":
+		text := strings.TrimPrefix(string(raw), "\uFEFF")
+		cr := csv.NewReader(strings.NewReader(text))
+		cr.FieldsPerRecord = -1
+		first, _, _ := strings.Cut(text, "\n")
+		if strings.Count(first, ";") > strings.Count(first, ",") {
+			cr.Comma = ';'
+		}
+		records, err := cr.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("CSV inválido: %v", err)
+		}
+		return records, nil
+	}
+	return nil, fmt.Errorf("formato não suportado: envie .xlsx ou .csv")
+}
+
+// parseLocations valida as linhas do arquivo. Linhas em branco são
+// ignoradas; repetições exatas viram aviso e são descartadas. Campos
+// vazios, INEP fora do formato e o mesmo INEP em escolas diferentes são
+// erros.
+func parseLocations(records [][]string) (rows []models.Location, errs, warnings []censusFieldIssue) {
+	if len(records) == 0 {
+		return nil, []censusFieldIssue{{Field: "arquivo", Code: issueRequired, Message: "arquivo vazio"}}, nil
+	}
+	idx := map[string]int{}
+	for i, h := range records[0] {
+		key := locationKey(strings.TrimPrefix(h, "\uFEFF"))
+		for field, aliases := range locationColumns {
+			for _, a := range aliases {
+				if _, seen := idx[field]; !seen && key == a {
+					idx[field] = i
+				}
+			}
+		}
+	}
+	for _, col := range []string{"dre", "municipio", "escola"} {
+		if _, ok := idx[col]; !ok {
+			errs = append(errs, censusFieldIssue{Field: col, Code: issueRequired,
+				Message: fmt.Sprintf("coluna %s ausente no cabeçalho", strings.ToUpper(col))})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	get := func(rec []string, field string) string {
+		i, ok := idx[field]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.Join(strings.Fields(rec[i]), " ")
+	}
+	seen := map[models.Location]int{}
+	inepLine := map[string]int{}
+	for n, rec := range records[1:] {
+		line := n + 2
+		l := models.Location{Dre: get(rec, "dre"), Municipio: get(rec, "municipio"), Escola: get(rec, "escola")}
+		inep := get(rec, "codigo_inep")
+		if l.Dre == "" && l.Municipio == "" && l.Escola == "" && inep == "" {
+			continue
+		}
+		valid := true
+		for _, f := range []struct{ name, value string }{{"dre", l.Dre}, {"municipio", l.Municipio}, {"escola", l.Escola}} {
+			if f.value == "" {
+				errs = append(errs, censusFieldIssue{Field: f.name, Code: issueRequired,
+					Message: fmt.Sprintf("linha %d: %s vazio", line, f.name)})
+				valid = false
+			}
+		}
+		if inep != "" {
+			if !schoolINEPPattern.MatchString(inep) {
+				errs = append(errs, censusFieldIssue{Field: "codigo_inep", Code: issueInvalidFormat,
+					Message: fmt.Sprintf("linha %d: codigo_inep %q deve ter 8 dígitos", line, inep)})
+				valid = false
+			} else if prev, dup := inepLine[inep]; dup {
+				errs = append(errs, censusFieldIssue{Field: "codigo_inep", Code: issueInconsistent,
+					Message: fmt.Sprintf("linha %d: codigo_inep %s já usado na linha %d", line, inep, prev)})
+				valid = false
+			} else {
+				inepLine[inep] = line
+				l.INEP = &inep
+			}
+		}
+		if !valid {
+			continue
+		}
+		key := models.Location{Dre: l.Dre, Municipio: l.Municipio, Escola: l.Escola}
+		if prev, dup := seen[key]; dup {
+			warnings = append(warnings, censusFieldIssue{Field: "escola", Code: issueInconsistent,
+				Message: fmt.Sprintf("linha %d: repete a linha %d e foi descartada", line, prev)})
+			continue
+		}
+		seen[key] = line
+		rows = append(rows, l)
+	}
+	if len(rows) == 0 && len(errs) == 0 {
+		errs = append(errs, censusFieldIssue{Field: "arquivo", Code: issueRequired, Message: "nenhuma escola no arquivo"})
+	}
+	return rows, errs, warnings
+}
+
+// resolveLocationINEP completa o INEP das escolas sem código com o de
+// schools quando nome e município casam com uma única escola. Devolve
+// quantas foram resolvidas.
+func resolveLocationINEP(rows []models.Location, schools []*models.School) int {
+	byName := map[string][]string{}
+	for _, s := range schools {
+		if s.INEP == "" {
+			continue
+		}
+		k := locationKey(s.Municipio) + "|" + locationKey(s.Nome)
+		byName[k] = append(byName[k], s.INEP)
+	}
+	used := map[string]bool{}
+	for _, l := range rows {
+		if l.INEP != nil {
+			used[*l.INEP] = true
+		}
+	}
+	resolved := 0
+	for i := range rows {
+		if rows[i].INEP != nil {
+			continue
+		}
+		matches := byName[locationKey(rows[i].Municipio)+"|"+locationKey(rows[i].Escola)]
+		if len(matches) != 1 || used[matches[0]] {
+			continue
+		}
+		inep := matches[0]
+		rows[i].INEP = &inep
+		used[inep] = true
+		resolved++
+	}
+	return resolved
+}
+
+// locationDiff compara a hierarquia atual com a nova. Uma escola cujo INEP
+// aparece nos dois lados com DRE, município ou nome diferentes conta como
+// movida, não como removida e incluída.
+type locationDiff struct {
+	Added       []models.Location `json:"added"`
+	Removed     []models.Location `json:"removed"`
+	Moved       []locationMove    `json:"moved"`
+	INEPChanged []locationMove    `json:"inep_changed"`
+	Unchanged   int               `json:"unchanged"`
+}
+
+type locationMove struct {
+	From models.Location `json:"from"`
+	To   models.Location `json:"to"`
+}
+
+func diffLocations(current, incoming []models.Location) locationDiff {
+	type key struct{ dre, municipio, escola string }
+	keyOf := func(l models.Location) key { return key{l.Dre, l.Municipio, l.Escola} }
+	inepOf := func(l models.Location) string {
+		if l.INEP == nil {
+			return ""
+		}
+		return *l.INEP
+	}
+
+	d := locationDiff{Added: []models.Location{}, Removed: []models.Location{},
+		Moved: []locationMove{}, INEPChanged: []locationMove{}}
+	cur := map[key]models.Location{}
+	for _, l := range current {
+		cur[keyOf(l)] = l
+	}
+	next := map[key]bool{}
+	var added []models.Location
+	for _, l := range incoming {
+		next[keyOf(l)] = true
+		prev, ok := cur[keyOf(l)]
+		switch {
+		case !ok:
+			added = append(added, l)
+		case inepOf(prev) != inepOf(l):
+			d.INEPChanged = append(d.INEPChanged, locationMove{From: prev, To: l})
+		default:
+			d.Unchanged++
+		}
+	}
+	removedByINEP := map[string]models.Location{}
+	for _, l := range current {
+		if next[keyOf(l)] {
+			continue
+		}
+		if inep := inepOf(l); inep != "" {
+			removedByINEP[inep] = l
+		} else {
+			d.Removed = append(d.Removed, l)
+		}
+	}
+	for _, l := range added {
+		if prev, ok := removedByINEP[inepOf(l)]; ok && inepOf(l) != "" {
+			d.Moved = append(d.Moved, locationMove{From: prev, To: l})
+			delete(removedByINEP, inepOf(l))
+			continue
+		}
+		d.Added = append(d.Added, l)
+	}
+	for _, l := range current {
+		if _, ok := removedByINEP[inepOf(l)]; ok && inepOf(l) != "" && !next[keyOf(l)] {
+			d.Removed = append(d.Removed, l)
+		}
+	}
+	return d
+}
+
+// locationUploadResult é a resposta de POST /v1/admin/locations.
+type locationUploadResult struct {
+	DryRun       bool                   `json:"dry_run"`
+	RowsTotal    int                    `json:"rows_total"`
+	INEPResolved int                    `json:"inep_resolved"`
+	INEPMissing  int                    `json:"inep_missing"`
+	Diff         locationDiff           `json:"diff"`
+	Upload       *models.LocationUpload `json:"upload,omitempty"`
+}
+
+// prepareLocations lê, valida e completa o INEP do arquivo, e compara com a
+// hierarquia atual.
+func (app *application) prepareLocations(ctx context.Context, name string, raw []byte) (
+	rows []models.Location, res locationUploadResult, errs, warnings []censusFieldIssue, err error) {
+	records, err := readLocationRecords(name, raw)
+	if err != nil {
+		return nil, res, []censusFieldIssue{{Field: "arquivo", Code: issueInvalidType, Message: err.Error()}}, nil, nil
+	}
+	rows, errs, warnings = parseLocations(records)
+	if len(errs) > 0 {
+		return nil, res, errs, warnings, nil
+	}
+	schools, err := app.models.Schools.GetAll()
+	if err != nil {
+		return nil, res, nil, nil, err
+	}
+	current, err := app.models.Locations.All(ctx)
+	if err != nil {
+		return nil, res, nil, nil, err
+	}
+	res.RowsTotal = len(rows)
+	res.INEPResolved = resolveLocationINEP(rows, schools)
+	for _, l := range rows {
+		if l.INEP == nil {
+			res.INEPMissing++
+		}
+	}
+	res.Diff = diffLocations(current, rows)
+	return rows, res, nil, warnings, nil
+}
+
+// AdminUploadLocations substitui a hierarquia pelo arquivo enviado
+// (?dry_run=true só valida e mostra o diff).
+func (app *application) AdminUploadLocations(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLocationsUploadMem)
+	if err := r.ParseMultipartForm(maxLocationsUploadMem); err != nil {
+		app.errorJSON(w, fmt.Errorf("arquivo muito grande ou inválido (máx. 10MB)"), http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("envie a planilha no campo file"), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("arquivo inválido"), http.StatusBadRequest)
+		return
+	}
+
+	rows, res, errs, warnings, err := app.prepareLocations(r.Context(), header.Filename, raw)
+	if err != nil {
+		app.logger.Printf("AdminUploadLocations: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao comparar a hierarquia de locais"), http.StatusInternalServerError)
+		return
+	}
+	if len(errs) > 0 {
+		app.writeJSON(w, http.StatusUnprocessableEntity, jsonResponse{Error: true,
+			Message: "arquivo com problemas; nada foi gravado", Errors: errs, Warnings: warnings})
+		return
+	}
+
+	res.DryRun = r.URL.Query().Get("dry_run") == "true"
+	if res.DryRun {
+		app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "simulação: nada foi gravado",
+			Data: res, Warnings: warnings})
+		return
+	}
+
+	admin, _ := adminFromContext(r.Context())
+	upload, err := app.replaceLocations(r.Context(), rows, res.Diff, filepath.Base(header.Filename), raw, admin.Username)
+	if err != nil {
+		app.logger.Printf("AdminUploadLocations: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao gravar a hierarquia de locais"), http.StatusInternalServerError)
+		return
+	}
+	res.Upload = upload
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false,
+		Message: fmt.Sprintf("hierarquia substituída: %d escola(s), %d incluída(s), %d removida(s), %d movida(s)",
+			res.RowsTotal, len(res.Diff.Added), len(res.Diff.Removed), len(res.Diff.Moved)),
+		Data: res, Warnings: warnings})
+}
+
+// replaceLocations grava a nova hierarquia com o registro do upload e
+// invalida o cache.
+func (app *application) replaceLocations(ctx context.Context, rows []models.Location, diff locationDiff,
+	sourceFile string, raw []byte, actor string) (*models.LocationUpload, error) {
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	upload := &models.LocationUpload{
+		SourceFile:  sourceFile,
+		SourceHash:  hex.EncodeToString(sum[:]),
+		RowsTotal:   len(rows),
+		Added:       len(diff.Added),
+		Removed:     len(diff.Removed),
+		INEPChanged: len(diff.INEPChanged),
+		CreatedBy:   actor,
+	}
+	if err := app.models.Locations.Replace(ctx, rows, upload, diffJSON); err != nil {
+		return nil, err
+	}
+	app.locations.invalidate()
+	return upload, nil
+}
+
+// seedLocations carrega LOCATIONS_SEED_FILE (padrão data/locations.xlsx)
+// quando a tabela locations está vazia. Linhas com problema são ignoradas
+// e registradas no log.
+func (app *application) seedLocations() error {
+	ctx := context.Background()
+	n, err := app.models.Locations.Count(ctx)
+	if err != nil || n > 0 {
+		return err
+	}
+	path := os.Getenv("LOCATIONS_SEED_FILE")
+	if path == "" {
+		path = defaultLocationsSeed
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("locations vazia e sem arquivo de carga: %w", err)
+	}
+	records, err := readLocationRecords(path, raw)
+	if err != nil {
+		return err
+	}
+	rows, errs, warnings := parseLocations(records)
+	for _, issue := range append(errs, warnings...) {
+		app.logger.Printf("seedLocations: %s", issue.Message)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("%s sem escolas válidas", path)
+	}
+	schools, err := app.models.Schools.GetAll()
+	if err != nil {
+		return err
+	}
+	resolveLocationINEP(rows, schools)
+	upload, err := app.replaceLocations(ctx, rows, diffLocations(nil, rows), filepath.Base(path), raw, "seed")
+	if err != nil {
+		return err
+	}
+	app.logger.Printf("Hierarquia de locais semeada de %s: %d escola(s)", path, upload.RowsTotal)
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+// Testes da hierarquia de locais. Sem banco: cobrem a validação do arquivo
+// (cabeçalho, campos vazios, INEP, repetições), o casamento do INEP com o
+// cadastro, o diff com escolas movidas e o cache com ETag/304.
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"censo-api/internal/models"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestParseLocations(t *testing.T) {
+	rows, errs, warnings := parseLocations([][]string{
+		{"DRE", "Município", "ESCOLA", "Código INEP"},
+		{"ABAETETUBA", "ABAETETUBA", "EE  SAO MIGUEL", "15012345"},
+		{"ABAETETUBA", "ABAETETUBA", "EE SAO MIGUEL", ""},
+		{"", "", "", ""},
+		{"ABAETETUBA", "MOJU", "EE MOJU", ""},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("errs = %+v", errs)
+	}
+	if len(rows) != 2 || len(warnings) != 1 {
+		t.Fatalf("rows = %+v, warnings = %+v", rows, warnings)
+	}
+	if rows[0].Escola != "EE SAO MIGUEL" || rows[0].INEP == nil || *rows[0].INEP != "15012345" {
+		t.Errorf("linha = %+v", rows[0])
+	}
+
+	_, errs, _ = parseLocations([][]string{
+		{"DRE", "MUNICIPIO", "ESCOLA", "INEP"},
+		{"ABAETETUBA", "", "EE A", "1501234"},
+		{"ABAETETUBA", "MOJU", "EE B", "15012345"},
+		{"ABAETETUBA", "MOJU", "EE C", "15012345"},
+	})
+	got := map[string]bool{}
+	for _, e := range errs {
+		got[e.Field+"/"+e.Code] = true
+	}
+	for _, want := range []string{"municipio/" + issueRequired, "codigo_inep/" + issueInvalidFormat,
+		"codigo_inep/" + issueInconsistent} {
+		if !got[want] {
+			t.Errorf("falta %s em %+v", want, errs)
+		}
+	}
+
+	if _, errs, _ := parseLocations([][]string{{"DRE", "ESCOLA"}}); len(errs) != 1 || errs[0].Field != "municipio" {
+		t.Errorf("cabeçalho sem MUNICIPIO: %+v", errs)
+	}
+}
+
+func TestResolveLocationINEP(t *testing.T) {
+	schools := []*models.School{
+		{INEP: "15000001", Nome: "EE São Miguel", Municipio: "Abaetetuba"},
+		{INEP: "15000002", Nome: "EE Dom Pedro", Municipio: "Belém"},
+		{INEP: "15000003", Nome: "EE Dom Pedro", Municipio: "Belém"},
+		{INEP: "15000004", Nome: "EE Moju", Municipio: "Moju"},
+	}
+	rows := []models.Location{
+		{Dre: "ABAETETUBA", Municipio: "ABAETETUBA", Escola: "EE SAO MIGUEL"},
+		{Dre: "BELEM", Municipio: "BELEM", Escola: "EE DOM PEDRO"},
+		{Dre: "ABAETETUBA", Municipio: "MOJU", Escola: "EE MOJU", INEP: strPtr("15999999")},
+	}
+	if n := resolveLocationINEP(rows, schools); n != 1 {
+		t.Errorf("resolvidas = %d", n)
+	}
+	if rows[0].INEP == nil || *rows[0].INEP != "15000001" {
+		t.Errorf("sem acento não casou: %+v", rows[0])
+	}
+	if rows[1].INEP != nil {
+		t.Errorf("nome ambíguo recebeu INEP %s", *rows[1].INEP)
+	}
+	if *rows[2].INEP != "15999999" {
+		t.Errorf("INEP do arquivo sobrescrito: %s", *rows[2].INEP)
+	}
+}
+
+func TestDiffLocations(t *testing.T) {
+	current := []models.Location{
+		{Dre: "A", Municipio: "M1", Escola: "E1", INEP: strPtr("15000001")},
+		{Dre: "A", Municipio: "M1", Escola: "E2", INEP: strPtr("15000002")},
+		{Dre: "A", Municipio: "M1", Escola: "E3"},
+		{Dre: "A", Municipio: "M1", Escola: "E4"},
+	}
+	incoming := []models.Location{
+		{Dre: "A", Municipio: "M1", Escola: "E1", INEP: strPtr("15000001")},
+		{Dre: "B", Municipio: "M2", Escola: "E2", INEP: strPtr("15000002")},
+		{Dre: "A", Municipio: "M1", Escola: "E3", INEP: strPtr("15000003")},
+		{Dre: "A", Municipio: "M1", Escola: "E5"},
+	}
+	d := diffLocations(current, incoming)
+	if d.Unchanged != 1 || len(d.Moved) != 1 || len(d.INEPChanged) != 1 || len(d.Added) != 1 || len(d.Removed) != 1 {
+		t.Fatalf("diff = %+v", d)
+	}
+	if d.Moved[0].To.Dre != "B" || d.Added[0].Escola != "E5" || d.Removed[0].Escola != "E4" {
+		t.Errorf("diff = %+v", d)
+	}
+}
+
+func TestLocationsTree(t *testing.T) {
+	tree := locationsTree([]models.Location{
+		{Dre: "A", Municipio: "M1", Escola: "E1"},
+		{Dre: "A", Municipio: "M1", Escola: "E2"},
+		{Dre: "A", Municipio: "M2", Escola: "E3"},
+		{Dre: "B", Municipio: "M3", Escola: "E4"},
+	})
+	if len(tree) != 2 || len(tree[0].Municipios) != 2 || len(tree[0].Municipios[0].Escolas) != 2 {
+		t.Errorf("tree = %+v", tree)
+	}
+	m := locationsMap([]models.Location{{Dre: "A", Municipio: "M1", Escola: "E2"}, {Dre: "A", Municipio: "M1", Escola: "E1"}})
+	if got := m["A"]["M1"]; len(got) != 2 || got[0] != "E1" {
+		t.Errorf("map = %v", m)
+	}
+}
+
+func TestLocationsCache(t *testing.T) {
+	var c locationsCache
+	loads := 0
+	load := func() (map[string][]byte, error) {
+		loads++
+		return map[string][]byte{"map": []byte(`{"error":false}`)}, nil
+	}
+	now := time.Now()
+	a, _ := c.get("map", now, load)
+	b, _ := c.get("map", now.Add(time.Minute), load)
+	if loads != 1 || a.etag == "" || a.etag != b.etag {
+		t.Errorf("loads = %d, etags %s %s", loads, a.etag, b.etag)
+	}
+	c.get("map", now.Add(locationsCacheTTL), load)
+	c.invalidate()
+	c.get("map", now.Add(locationsCacheTTL), load)
+	if loads != 3 {
+		t.Errorf("loads = %d", loads)
+	}
+	if _, err := c.get("map", now.Add(3*locationsCacheTTL), func() (map[string][]byte, error) {
+		return nil, errors.New("banco fora")
+	}); err == nil {
+		t.Error("erro do banco engolido")
+	}
+
+	if !etagMatches(`W/"x", `+a.etag, a.etag) || !etagMatches("*", a.etag) || etagMatches(`"outro"`, a.etag) {
+		t.Error("etagMatches")
+	}
+}
+
+func TestGetLocationsNotModified(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	app.locations.get("map", time.Now(), func() (map[string][]byte, error) {
+		return map[string][]byte{"map": []byte(`{"error":false,"data":{}}`), "tree": []byte(`{"error":false,"data":[]}`)}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	app.GetLocations(rec, httptest.NewRequest(http.MethodGet, "/v1/locations", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || rec.Body.String() != `{"error":false,"data":{}}` {
+		t.Fatalf("status = %d, etag = %q, body = %s", rec.Code, etag, rec.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/locations", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	app.GetLocations(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("revalidação: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.GetLocations(rec, httptest.NewRequest(http.MethodGet, "/v1/locations?format=tree", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("tree: status = %d, etag = %s", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	rec = httptest.NewRecorder()
+	app.GetLocations(rec, httptest.NewRequest(http.MethodGet, "/v1/locations?format=csv", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("format inválido: status = %d", rec.Code)
+	}
+}
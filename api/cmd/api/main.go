@@ -4,12 +4,10 @@ import (
 	"database/sql"
 	"embed"
 	"fmt"
-	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
 	"time"
 
 	"censo-api/internal/models"
@@ -29,7 +27,7 @@ import (
 //
 // A cópia em infra/migrations/ é mantida como referência operacional
 // e fonte de verdade documental — qualquer mudança numa view deve ser
-// refletida nas DUAS pastas.
+// refletida nas DUAS pastas (`go run ./cmd/api migrate verify` confere).
 //
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
@@ -50,6 +48,16 @@ type application struct {
 	models models.Models
 	sheets *services.SheetsService
 	drive  *services.DriveService
+	// photos grava as fotos enviadas pelas escolas (ver photo_store.go).
+	photos services.PhotoStore
+	// limiter guarda as tentativas dos limites de taxa (ver ratelimit.go).
+	limiter rateLimiter
+	// locations guarda as respostas de GET /v1/locations (ver locations.go).
+	locations locationsCache
+	// sinks são os destinos da sincronização por kind (ver sync_sinks.go).
+	sinks map[string]services.CensusSink
+	// syncWake acorda o worker de sync_outbox (ver sync_outbox.go).
+	syncWake chan struct{}
 }
 
 func main() {
@@ -82,6 +90,11 @@ func main() {
 		logger.Println("Credenciais de ADMIN carregadas no ambiente com sucesso.")
 	}
 
+	// Subcomando de manutenção: `api migrate status|up|verify` (migrate.go).
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:], os.Stdout, logger))
+	}
+
 	var cfg config
 
 	// Valida configuração de segurança crítica antes de subir o servidor.
@@ -100,24 +113,7 @@ func main() {
 		cfg.env = "production"
 	}
 
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = os.Getenv("DB_DSN")
-	}
-
-	if dsn == "" {
-		dbHost := os.Getenv("DB_HOST")
-		if dbHost != "" {
-			// sslmode configurável: padrão "disable" para o docker local sem TLS,
-			// mas permite exigir TLS (DB_SSLMODE=require) em produção.
-			sslmode := os.Getenv("DB_SSLMODE")
-			if sslmode == "" {
-				sslmode = "disable"
-			}
-			dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s timezone=UTC connect_timeout=5",
-				os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"), sslmode)
-		}
-	}
+	dsn := dsnFromEnv()
 
 	if dsn == "" {
 		logger.Fatal("ERRO FATAL: Variáveis de banco (DB_HOST ou DSN) não foram encontradas.")
@@ -140,14 +136,24 @@ func main() {
 		logger.Println("Migração sheet_synced_at OK")
 	}
 
-	// Aplica as migrations idempotentes embarcadas no binário via
-	// go:embed (api/cmd/api/migrations/*.sql). Todas devem usar
-	// CREATE OR REPLACE / IF NOT EXISTS e poder rodar várias vezes
-	// sem efeito colateral.
-	if err = applyMigrations(db, logger); err != nil {
+	// Aplica as migrations pendentes embarcadas no binário via go:embed
+	// (api/cmd/api/migrations/*.sql), registradas em schema_migrations
+	// (ver migrate.go). Todas devem usar CREATE OR REPLACE / IF NOT EXISTS
+	// e poder rodar várias vezes sem efeito colateral.
+	failOnError := migrationsFailOnError()
+	if err = applyMigrations(db, logger, failOnError); err != nil {
+		if failOnError {
+			logger.Fatal("ERRO FATAL MIGRATIONS (MIGRATIONS_FAIL_ON_ERROR=false para subir mesmo assim): ", err)
+		}
 		logger.Printf("AVISO: applyMigrations: %v", err)
 	}
 
+	// Conta inicial do painel: semeia ADMIN_USERNAME/ADMIN_PASSWORD_HASH em
+	// admin_users quando a tabela ainda está vazia.
+	if err = bootstrapAdminUser(models.AdminUserModel{DB: db}, logger); err != nil {
+		logger.Printf("AVISO: bootstrapAdminUser: %v", err)
+	}
+
 	// ... (Resto do seu código permanece igual)
 	sheetsService, err := services.NewSheetsService()
 	if err != nil {
@@ -164,16 +170,43 @@ func main() {
 	}
 
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: models.NewModels(db),
-		sheets: sheetsService,
-		drive:  driveService,
+		config:   cfg,
+		logger:   logger,
+		models:   models.NewModels(db),
+		sheets:   sheetsService,
+		drive:    driveService,
+		syncWake: make(chan struct{}, 1),
+	}
+	app.limiter = newRateLimiter(os.Getenv("RATE_LIMIT_STORE"), &app.models.RateLimits)
+
+	// Hierarquia de locais do formulário: semeada de data/locations.xlsx
+	// enquanto a tabela locations está vazia.
+	if err = app.seedLocations(); err != nil {
+		logger.Printf("AVISO: seedLocations: %v", err)
+	}
+
+	// Armazenamento das fotos (ver photo_store.go): sem ele, POST
+	// /v1/upload responde 503.
+	if photos, err := newPhotoStore(driveService); err != nil {
+		logger.Printf("AVISO: armazenamento de fotos: %v", err)
+	} else {
+		app.photos = photos
+		logger.Printf("Armazenamento de fotos: %s", photos.Kind())
 	}
 
-	// Job de retry: a cada 10 minutos re-sincroniza censos completed que
-	// não chegaram à planilha (goroutine falhou silenciosamente antes).
-	go app.sheetSyncRetryJob()
+	// Destinos da sincronização e worker da fila (ver sync_sinks.go e
+	// sync_outbox.go).
+	if err = app.setupSyncSinks(); err != nil {
+		logger.Printf("AVISO: setupSyncSinks: %v", err)
+	}
+	go app.syncOutboxJob()
+
+	// Limpeza horária de tokens revogados expirados e sessões antigas do
+	// painel.
+	go app.adminSessionCleanupJob()
+
+	// Limpeza das tentativas expiradas dos limites de taxa.
+	go app.rateLimitCleanupJob()
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("0.0.0.0:%s", cfg.port),
@@ -188,6 +221,30 @@ func main() {
 	logger.Fatal(err)
 }
 
+// dsnFromEnv monta a string de conexão: DATABASE_URL, DB_DSN ou as
+// variáveis DB_* separadas. Vazia quando nenhuma está definida.
+func dsnFromEnv() string {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		dsn = os.Getenv("DB_DSN")
+	}
+
+	if dsn == "" {
+		dbHost := os.Getenv("DB_HOST")
+		if dbHost != "" {
+			// sslmode configurável: padrão "disable" para o docker local sem TLS,
+			// mas permite exigir TLS (DB_SSLMODE=require) em produção.
+			sslmode := os.Getenv("DB_SSLMODE")
+			if sslmode == "" {
+				sslmode = "disable"
+			}
+			dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s timezone=UTC connect_timeout=5",
+				os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"), sslmode)
+		}
+	}
+	return dsn
+}
+
 func openDB(cfg config) (*sql.DB, error) {
 	db, err := sql.Open("pgx", cfg.db.dsn)
 	if err != nil {
@@ -205,97 +262,6 @@ func openDB(cfg config) (*sql.DB, error) {
 	return db, nil
 }
 
-// applyMigrations executa, em ordem alfabética, todos os arquivos .sql
-// embarcados em migrationsFS (api/cmd/api/migrations/*.sql). Cada arquivo
-// deve ser idempotente (CREATE OR REPLACE VIEW, IF NOT EXISTS, etc.) —
-// não há tabela de controle de versão nesta fase.
-//
-// Como o conteúdo está embarcado no binário via go:embed, o resultado é
-// independente do working directory do processo — funciona igual no
-// docker local, no Railway e em qualquer outro ambiente.
-//
-// Erros ao aplicar uma migration individual são logados com detalhe e
-// não derrubam o servidor: o startup segue, e o operador vê no log
-// qual arquivo falhou e por quê.
-func applyMigrations(db *sql.DB, logger *log.Logger) error {
-	entries, err := fs.ReadDir(migrationsFS, "migrations")
-	if err != nil {
-		return fmt.Errorf("applyMigrations: ler embed migrations/: %w", err)
-	}
-
-	files := make([]string, 0, len(entries))
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		if filepath.Ext(e.Name()) != ".sql" {
-			continue
-		}
-		files = append(files, e.Name())
-	}
-	sort.Strings(files)
-
-	if len(files) == 0 {
-		logger.Println("applyMigrations: nenhum .sql embarcado, pulando.")
-		return nil
-	}
-
-	logger.Printf("applyMigrations: %d migration(s) encontrada(s): %v", len(files), files)
-
-	for _, name := range files {
-		content, err := fs.ReadFile(migrationsFS, "migrations/"+name)
-		if err != nil {
-			logger.Printf("applyMigrations: ERRO lendo %s do embed: %v", name, err)
-			continue
-		}
-		if _, err := db.Exec(string(content)); err != nil {
-			logger.Printf("applyMigrations: ERRO aplicando %s: %v", name, err)
-			continue
-		}
-		logger.Printf("applyMigrations: %s aplicada com sucesso", name)
-	}
-	return nil
-}
-
-func (app *application) sheetSyncRetryJob() {
-	ticker := time.NewTicker(10 * time.Minute)
-	defer ticker.Stop()
-	for range ticker.C {
-		app.syncPendingToSheets()
-	}
-}
-
-func (app *application) syncPendingToSheets() {
-	if app.sheets == nil {
-		return
-	}
-	pending, err := app.models.Census.GetPendingSheetSync()
-	if err != nil {
-		app.logger.Println("sheetSync: erro ao buscar pendentes:", err)
-		return
-	}
-	if len(pending) == 0 {
-		return
-	}
-	app.logger.Printf("sheetSync: %d censo(s) pendente(s) para planilha", len(pending))
-	for _, c := range pending {
-		school, err := app.models.Schools.Get(c.SchoolID)
-		if err != nil {
-			app.logger.Printf("sheetSync: erro escola %d: %v", c.SchoolID, err)
-			continue
-		}
-		if err = app.sheets.AppendCenso(*c, *school); err != nil {
-			app.logger.Printf("sheetSync: erro ao enviar escola %d: %v", c.SchoolID, err)
-			continue
-		}
-		if err = app.models.Census.MarkSheetSynced(c.ID); err != nil {
-			app.logger.Printf("sheetSync: erro ao marcar sincronizado %d: %v", c.ID, err)
-		} else {
-			app.logger.Printf("sheetSync: escola %d sincronizada", c.SchoolID)
-		}
-	}
-}
-
 func (app *application) routes() http.Handler {
 	mux := chi.NewRouter()
 	mux.Use(middleware.Recoverer)
@@ -320,78 +286,183 @@ func (app *application) routes() http.Handler {
 			pub.Post("/schools", app.CreateSchool)
 			pub.Get("/census", app.GetCenso)
 			pub.Post("/census", app.CreateOrUpdateCenso)
+			pub.Patch("/census", app.PatchCenso)
+			pub.Get("/census/review", app.GetCensoReview)
+			pub.Get("/census/seed", app.GetCensoSeed)
+			pub.Post("/census/seed", app.SeedCenso)
+			pub.Get("/census/campaign", app.GetCensoCampaign)
 			pub.Post("/upload", app.uploadPhoto)
 		})
 
 		// Admin: login público + rotas protegidas por JWT
 		r.Post("/admin/login", app.AdminLogin)
+		r.Post("/admin/login/verify", app.AdminLoginVerify)
+		r.Post("/admin/refresh", app.AdminRefresh)
 		r.Group(func(protected chi.Router) {
 			protected.Use(app.requireAdminAuth)
+			// Trilha de auditoria (LGPD): toda requisição autenticada, inclusive
+			// as recusadas por papel/escopo, é gravada em admin_audit_log.
+			protected.Use(app.auditAdminAccess)
+			// Contas regionais: dre da query string forçado para a DRE da conta.
+			protected.Use(app.enforceDREScope)
+			protected.Post("/admin/logout", app.AdminLogout)
+			protected.Get("/admin/me/totp", app.AdminTOTPStatus)
+			protected.Post("/admin/me/totp/setup", app.AdminTOTPSetup)
+			protected.Post("/admin/me/totp/enable", app.AdminTOTPEnable)
+			protected.Post("/admin/me/totp/recovery-codes", app.AdminTOTPRecoveryCodes)
+			protected.Post("/admin/me/totp/disable", app.AdminTOTPDisable)
 			protected.Get("/admin/dashboard", app.AdminDashboard)
-			protected.Get("/admin/sheet-metrics", app.AdminSheetMetrics)
-			protected.Get("/admin/indicadores-metrics", app.AdminIndicadoresMetrics)
 			protected.Get("/admin/census", app.AdminGetCensus)
 			protected.Get("/admin/census/{id}", app.AdminGetCensusByID)
-			protected.Post("/admin/sync-sheets", app.AdminSyncSheets)
-
-			// Fase 1 — camada analítica baseada em PostgreSQL.
-			// Endpoints adicionais; não substituem sheet-metrics nem indicadores-metrics.
-			protected.Get("/admin/analytics/overview", app.AdminAnalyticsOverview)
-
-			// Fase 2A — backend analítico da Caracterização da Rede.
-			// Adicionais; a UI segue consumindo sheet-metrics até a Fase 2B.
-			protected.Get("/admin/analytics/caracterizacao/perfil", app.AdminAnalyticsCaracterizacaoPerfil)
-			protected.Get("/admin/analytics/caracterizacao/dre", app.AdminAnalyticsCaracterizacaoDRE)
-			protected.Get("/admin/analytics/caracterizacao/oferta-funcionamento", app.AdminAnalyticsCaracterizacaoOfertaFuncionamento)
-			protected.Get("/admin/analytics/caracterizacao/infraestrutura-educacional", app.AdminAnalyticsCaracterizacaoInfraEducacional)
-
-			// Frente 1 — Pessoal e Gestão Escolar + Tecnologia
-			protected.Get("/admin/analytics/pessoal-gestao/estrutura", app.AdminAnalyticsPessoalEstrutura)
-			protected.Get("/admin/analytics/pessoal-gestao/coordenacao", app.AdminAnalyticsPessoalCoordenacao)
-			protected.Get("/admin/analytics/pessoal-gestao/quadro-pessoal", app.AdminAnalyticsPessoalQuadro)
-			protected.Get("/admin/analytics/tecnologia/infraestrutura", app.AdminAnalyticsTecnologiaInfra)
-			protected.Get("/admin/analytics/tecnologia/uso-pedagogico", app.AdminAnalyticsTecnologiaUso)
-
-			// Frente 2 — Infraestrutura/Segurança + Merenda + Serviços Terceirizados.
-			protected.Get("/admin/analytics/infraestrutura/condicoes", app.AdminAnalyticsInfraCondicoes)
-			protected.Get("/admin/analytics/infraestrutura/seguranca", app.AdminAnalyticsInfraSeguranca)
-			protected.Get("/admin/analytics/infraestrutura/energia", app.AdminAnalyticsInfraEnergia)
-			protected.Get("/admin/analytics/merenda/oferta", app.AdminAnalyticsMerendaOferta)
-			protected.Get("/admin/analytics/merenda/equipamentos", app.AdminAnalyticsMerendaEquipamentos)
-			protected.Get("/admin/analytics/merenda/recursos-humanos", app.AdminAnalyticsMerendaRH)
-			protected.Get("/admin/analytics/merenda/condicoes-sanitarias", app.AdminAnalyticsMerendaCondicoesSanitarias)
-			protected.Get("/admin/analytics/servicos-terceirizados/visao-geral", app.AdminAnalyticsServicosVisaoGeral)
-			protected.Get("/admin/analytics/servicos-terceirizados/servicos-gerais", app.AdminAnalyticsServicosGerais)
-			protected.Get("/admin/analytics/servicos-terceirizados/portaria", app.AdminAnalyticsServicosPortaria)
-			protected.Get("/admin/analytics/servicos-terceirizados/manipuladores-alimentos", app.AdminAnalyticsServicosManipuladoresAlimentos)
-			protected.Get("/admin/analytics/escolas/saude-operacional", app.AdminAnalyticsSaudeOperacionalEscolas)
-
-			// tabelas escola-a-escola para todas as abas analíticas
-			protected.Get("/admin/analytics/infraestrutura/escolas", app.AdminAnalyticsInfraEscolas)
-			protected.Get("/admin/analytics/merenda/escolas", app.AdminAnalyticsMerendaEscolas)
-			protected.Get("/admin/analytics/servicos-terceirizados/escolas", app.AdminAnalyticsServicosTerceirizadosEscolas)
-			protected.Get("/admin/analytics/pessoal-gestao/escolas", app.AdminAnalyticsPessoalEscolas)
-			protected.Get("/admin/analytics/tecnologia/escolas", app.AdminAnalyticsTecnologiaEscolas)
-			protected.Get("/admin/analytics/caracterizacao/escolas", app.AdminAnalyticsCaracterizacaoEscolas)
-
-			// Gestão Financeira e Governança — repasses PRODEP (PR técnico 2).
-			protected.Get("/admin/analytics/financeiro-governanca/prodep", app.AdminAnalyticsFinanceiroGovernancaProdep)
-
-			// Gestão Financeira e Governança — Governança Institucional (Censo, PR 1).
-			protected.Get("/admin/analytics/financeiro-governanca/institucional", app.AdminAnalyticsFinanceiroGovernancaInstitucional)
-
-			// Perfil dos Alunos e Resultados — IDEB 2023 (IDEB-04, lê ideb_resultados).
-			protected.Get("/admin/analytics/perfil-alunos-resultados/ideb", app.AdminAnalyticsPerfilAlunosResultadosIDEB)
-
-			// Andamento do preenchimento do censo por DRE.
-			protected.Get("/admin/analytics/preenchimento/dre", app.AdminAnalyticsPreenchimentoDre)
-
-			// Filtros globais do dashboard.
-			protected.Get("/admin/analytics/filtros/opcoes", app.AdminAnalyticsFiltrosOpcoes)
-
-			// Relatórios gerenciais por aba (XLSX). Camada extensível; o
-			// report_id é resolvido contra reportsCatalog.
-			protected.Get("/admin/reports/{report_id}", app.AdminGetReport)
+			protected.Get("/admin/census/{id}/revisions", app.AdminListCensusRevisions)
+			protected.Get("/admin/census/{id}/revisions/diff", app.AdminDiffCensusRevisions)
+			protected.Get("/admin/census/{id}/reviews", app.AdminListCensusReviews)
+			protected.Get("/admin/campaigns", app.AdminListCampaigns)
+
+			// Revisão do censo: administração estadual e gestores de DRE (só
+			// censos da própria DRE). Analistas apenas consultam.
+			protected.Group(func(rev chi.Router) {
+				rev.Use(app.requireAdminRole(roleSeducAdmin, roleDreGestor))
+				rev.Post("/admin/census/review", app.AdminBulkReviewCensus)
+				rev.Post("/admin/census/{id}/review", app.AdminReviewCensus)
+			})
+
+			// Leituras da planilha e sincronização: recorte estadual apenas.
+			protected.Group(func(state chi.Router) {
+				state.Use(app.requireStatewideScope)
+				state.Get("/admin/sheet-metrics", app.AdminSheetMetrics)
+				state.Get("/admin/indicadores-metrics", app.AdminIndicadoresMetrics)
+				state.Post("/admin/sync-sheets", app.AdminSyncSheets)
+				state.Get("/admin/reference/unmatched", app.AdminListUnmatchedReferences)
+			})
+
+			// Fila de sincronização e limpeza da planilha: somente seduc_admin.
+			protected.Group(func(ob chi.Router) {
+				ob.Use(app.requireAdminRole(roleSeducAdmin))
+				ob.Get("/admin/sync/outbox", app.AdminListSyncOutbox)
+				ob.Post("/admin/sync/outbox/{id}/retry", app.AdminRetrySyncOutbox)
+				ob.Post("/admin/sync/outbox/{id}/discard", app.AdminDiscardSyncOutbox)
+				ob.Post("/admin/sync-sheets/dedup", app.AdminDedupSheets)
+			})
+
+			// Contas individuais do painel (somente seduc_admin).
+			protected.Group(func(adm chi.Router) {
+				adm.Use(app.requireAdminRole(roleSeducAdmin))
+				adm.Get("/admin/users", app.AdminListUsers)
+				adm.Post("/admin/users", app.AdminCreateUser)
+				adm.Patch("/admin/users/{id}", app.AdminUpdateUser)
+				adm.Get("/admin/users/{id}/sessions", app.AdminListUserSessions)
+				adm.Delete("/admin/users/{id}/sessions", app.AdminRevokeUserSessions)
+				adm.Delete("/admin/users/{id}/sessions/{session_id}", app.AdminRevokeUserSession)
+				adm.Delete("/admin/users/{id}/totp", app.AdminResetUserTOTP)
+				adm.Get("/admin/audit", app.AdminListAudit)
+				adm.Get("/admin/audit/export", app.AdminExportAudit)
+			})
+
+			// Campanhas do censo: janelas definidas pela administração estadual.
+			protected.Group(func(camp chi.Router) {
+				camp.Use(app.requireAdminRole(roleSeducAdmin))
+				camp.Put("/admin/campaigns/{year}", app.AdminSaveCampaign)
+				camp.Delete("/admin/campaigns/{year}", app.AdminDeleteCampaign)
+			})
+
+			// Fotografias oficiais do censo: congelar é da administração estadual.
+			protected.Get("/admin/snapshots", app.AdminListSnapshots)
+			protected.Get("/admin/snapshots/{id}", app.AdminGetSnapshot)
+			protected.With(app.requireAdminRole(roleSeducAdmin)).Post("/admin/snapshots", app.AdminFreezeCensus)
+
+			// Hierarquia de locais do formulário: substituição só seduc_admin.
+			protected.With(app.requireAdminRole(roleSeducAdmin)).Post("/admin/locations", app.AdminUploadLocations)
+
+			// Cadastro de escolas: leitura para qualquer perfil, edição e
+			// desativação para administração estadual e gestores de DRE (escola
+			// da própria DRE, conferida no handler), fusão só seduc_admin.
+			protected.Get("/admin/schools/{id}", app.AdminGetSchool)
+			protected.Get("/admin/schools/{id}/photos", app.AdminListSchoolPhotos)
+			protected.Get("/admin/schools/{id}/photos/{photo_id}/file", app.AdminGetSchoolPhotoFile)
+			protected.Group(func(sch chi.Router) {
+				sch.Use(app.requireAdminRole(roleSeducAdmin, roleDreGestor))
+				sch.Put("/admin/schools/{id}", app.AdminPutSchool)
+				sch.Patch("/admin/schools/{id}", app.AdminPatchSchool)
+				sch.Delete("/admin/schools/{id}", app.AdminDeleteSchool)
+				sch.With(app.requireAdminRole(roleSeducAdmin)).Post("/admin/schools/{id}/merge", app.AdminMergeSchool)
+			})
+
+			// Códigos de acesso das escolas: administração estadual e gestores
+			// de DRE (lote restrito à DRE da conta por enforceDREScope).
+			protected.Group(func(acc chi.Router) {
+				acc.Use(app.requireAdminRole(roleSeducAdmin, roleDreGestor))
+				acc.Get("/admin/school-access", app.AdminListSchoolAccess)
+				acc.Post("/admin/school-access/issue", app.AdminIssueSchoolAccess)
+				acc.Post("/admin/school-access/rotate", app.AdminRotateSchoolAccess)
+				acc.Post("/admin/school-access/revoke", app.AdminRevokeSchoolAccess)
+			})
+
+			// Análises e relatórios: ?snapshot=<id> lê o lote congelado em vez
+			// do censo vivo (withCensusSnapshot, census_snapshots.go).
+			protected.Group(func(an chi.Router) {
+				an.Use(app.withCensusSnapshot)
+
+				// Fase 1 — camada analítica baseada em PostgreSQL.
+				// Endpoints adicionais; não substituem sheet-metrics nem indicadores-metrics.
+				an.Get("/admin/analytics/overview", app.AdminAnalyticsOverview)
+
+				// Fase 2A — backend analítico da Caracterização da Rede.
+				// Adicionais; a UI segue consumindo sheet-metrics até a Fase 2B.
+				an.Get("/admin/analytics/caracterizacao/perfil", app.AdminAnalyticsCaracterizacaoPerfil)
+				an.Get("/admin/analytics/caracterizacao/dre", app.AdminAnalyticsCaracterizacaoDRE)
+				an.Get("/admin/analytics/caracterizacao/oferta-funcionamento", app.AdminAnalyticsCaracterizacaoOfertaFuncionamento)
+				an.Get("/admin/analytics/caracterizacao/infraestrutura-educacional", app.AdminAnalyticsCaracterizacaoInfraEducacional)
+
+				// Frente 1 — Pessoal e Gestão Escolar + Tecnologia
+				an.Get("/admin/analytics/pessoal-gestao/estrutura", app.AdminAnalyticsPessoalEstrutura)
+				an.Get("/admin/analytics/pessoal-gestao/coordenacao", app.AdminAnalyticsPessoalCoordenacao)
+				an.Get("/admin/analytics/pessoal-gestao/quadro-pessoal", app.AdminAnalyticsPessoalQuadro)
+				an.Get("/admin/analytics/tecnologia/infraestrutura", app.AdminAnalyticsTecnologiaInfra)
+				an.Get("/admin/analytics/tecnologia/uso-pedagogico", app.AdminAnalyticsTecnologiaUso)
+
+				// Frente 2 — Infraestrutura/Segurança + Merenda + Serviços Terceirizados.
+				an.Get("/admin/analytics/infraestrutura/condicoes", app.AdminAnalyticsInfraCondicoes)
+				an.Get("/admin/analytics/infraestrutura/seguranca", app.AdminAnalyticsInfraSeguranca)
+				an.Get("/admin/analytics/infraestrutura/energia", app.AdminAnalyticsInfraEnergia)
+				an.Get("/admin/analytics/merenda/oferta", app.AdminAnalyticsMerendaOferta)
+				an.Get("/admin/analytics/merenda/equipamentos", app.AdminAnalyticsMerendaEquipamentos)
+				an.Get("/admin/analytics/merenda/recursos-humanos", app.AdminAnalyticsMerendaRH)
+				an.Get("/admin/analytics/merenda/condicoes-sanitarias", app.AdminAnalyticsMerendaCondicoesSanitarias)
+				an.Get("/admin/analytics/servicos-terceirizados/visao-geral", app.AdminAnalyticsServicosVisaoGeral)
+				an.Get("/admin/analytics/servicos-terceirizados/servicos-gerais", app.AdminAnalyticsServicosGerais)
+				an.Get("/admin/analytics/servicos-terceirizados/portaria", app.AdminAnalyticsServicosPortaria)
+				an.Get("/admin/analytics/servicos-terceirizados/manipuladores-alimentos", app.AdminAnalyticsServicosManipuladoresAlimentos)
+				an.Get("/admin/analytics/escolas/saude-operacional", app.AdminAnalyticsSaudeOperacionalEscolas)
+
+				// tabelas escola-a-escola para todas as abas analíticas
+				an.Get("/admin/analytics/infraestrutura/escolas", app.AdminAnalyticsInfraEscolas)
+				an.Get("/admin/analytics/merenda/escolas", app.AdminAnalyticsMerendaEscolas)
+				an.Get("/admin/analytics/servicos-terceirizados/escolas", app.AdminAnalyticsServicosTerceirizadosEscolas)
+				an.Get("/admin/analytics/pessoal-gestao/escolas", app.AdminAnalyticsPessoalEscolas)
+				an.Get("/admin/analytics/tecnologia/escolas", app.AdminAnalyticsTecnologiaEscolas)
+				an.Get("/admin/analytics/caracterizacao/escolas", app.AdminAnalyticsCaracterizacaoEscolas)
+
+				// Gestão Financeira e Governança — repasses PRODEP (PR técnico 2).
+				an.Get("/admin/analytics/financeiro-governanca/prodep", app.AdminAnalyticsFinanceiroGovernancaProdep)
+
+				// Gestão Financeira e Governança — Governança Institucional (Censo, PR 1).
+				an.Get("/admin/analytics/financeiro-governanca/institucional", app.AdminAnalyticsFinanceiroGovernancaInstitucional)
+
+				// Perfil dos Alunos e Resultados — IDEB 2023 (IDEB-04, lê ideb_resultados).
+				an.Get("/admin/analytics/perfil-alunos-resultados/ideb", app.AdminAnalyticsPerfilAlunosResultadosIDEB)
+
+				// Andamento do preenchimento do censo por DRE.
+				an.Get("/admin/analytics/preenchimento/dre", app.AdminAnalyticsPreenchimentoDre)
+				an.Get("/admin/analytics/preenchimento/atrasadas", app.AdminAnalyticsPreenchimentoAtrasadas)
+
+				// Filtros globais do dashboard.
+				an.Get("/admin/analytics/filtros/opcoes", app.AdminAnalyticsFiltrosOpcoes)
+
+				// Relatórios gerenciais por aba (XLSX). Camada extensível; o
+				// report_id é resolvido contra reportsCatalog.
+				an.Get("/admin/reports/{report_id}", app.AdminGetReport)
+			})
 		})
 	})
 
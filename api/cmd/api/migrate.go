@@ -0,0 +1,438 @@
+package main
+
+// Controle de versão das migrations embarcadas (api/cmd/api/migrations).
+//
+// Cada arquivo aplicado é registrado em schema_migrations com o checksum do
+// seu SQL. No startup só rodam os arquivos pendentes — nunca aplicados ou
+// alterados desde a aplicação —, cada um numa transação junto do seu
+// registro. Como as migrations seguem a convenção de serem idempotentes
+// (CREATE OR REPLACE VIEW, IF NOT EXISTS), reaplicar um arquivo alterado é
+// seguro, e é assim que a mudança numa view chega ao banco.
+//
+// O checksum ignora linhas de comentário e linhas em branco: ajustar a
+// documentação de uma migration não a torna pendente, e as cópias em
+// infra/migrations podem ter cabeçalhos próprios sem acusar divergência.
+//
+// Subcomando de linha de comando (a partir da pasta api/):
+//
+//	go run ./cmd/api migrate status   # situação de cada arquivo no banco
+//	go run ./cmd/api migrate up       # aplica as pendentes
+//	go run ./cmd/api migrate verify   # compara com infra/migrations (sem banco)
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationsLockKey serializa réplicas que sobem ao mesmo tempo
+// (pg_advisory_xact_lock): uma aplica, as outras encontram o registro.
+const migrationsLockKey = 7_300_240_018
+
+type migrationFile struct {
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+type appliedMigration struct {
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Situação de um arquivo em relação a schema_migrations.
+const (
+	migrationApplied = "aplicada"
+	migrationPending = "pendente"
+	migrationChanged = "alterada"
+)
+
+type migrationStatus struct {
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+// migrationsFailOnError lê MIGRATIONS_FAIL_ON_ERROR. Por padrão uma
+// migration com erro aborta o startup, para o painel não subir com o banco
+// pela metade; "false" volta a só logar o erro e seguir.
+func migrationsFailOnError() bool {
+	v, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("MIGRATIONS_FAIL_ON_ERROR")))
+	return err != nil || v
+}
+
+// normalizeMigrationSQL descarta linhas de comentário (--), linhas em
+// branco e espaços no fim de linha (inclusive \r).
+func normalizeMigrationSQL(content string) string {
+	var out []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func migrationChecksum(content string) string {
+	sum := sha256.Sum256([]byte(normalizeMigrationSQL(content)))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadMigrations lê os .sql de dir em ordem alfabética.
+func loadMigrations(fsys fs.FS, dir string) ([]migrationFile, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("ler %s: %w", dir, err)
+	}
+	var files []migrationFile
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("ler %s: %w", e.Name(), err)
+		}
+		files = append(files, migrationFile{
+			Name:     e.Name(),
+			SQL:      string(content),
+			Checksum: migrationChecksum(string(content)),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+func embeddedMigrations() ([]migrationFile, error) {
+	return loadMigrations(migrationsFS, "migrations")
+}
+
+// planMigrations cruza os arquivos com o que já foi registrado. orphans são
+// registros sem arquivo correspondente (migration renomeada ou removida).
+func planMigrations(files []migrationFile, applied map[string]appliedMigration) (statuses []migrationStatus, orphans []string) {
+	known := make(map[string]bool, len(files))
+	for _, f := range files {
+		known[f.Name] = true
+		st := migrationStatus{Name: f.Name, State: migrationPending}
+		if a, ok := applied[f.Name]; ok {
+			at := a.AppliedAt
+			st.AppliedAt = &at
+			st.State = migrationApplied
+			if a.Checksum != f.Checksum {
+				st.State = migrationChanged
+			}
+		}
+		statuses = append(statuses, st)
+	}
+	for name := range applied {
+		if !known[name] {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	return statuses, orphans
+}
+
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		    filename    VARCHAR(255) PRIMARY KEY,
+		    checksum    CHAR(64)     NOT NULL,
+		    applied_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	return err
+}
+
+func loadAppliedMigrations(db *sql.DB) (map[string]appliedMigration, error) {
+	rows, err := db.Query(`SELECT filename, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[string]appliedMigration{}
+	for rows.Next() {
+		var name string
+		var a appliedMigration
+		if err := rows.Scan(&name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[name] = a
+	}
+	return applied, rows.Err()
+}
+
+// applyMigrationFile aplica um arquivo e grava o registro na mesma
+// transação. Se outra réplica já o aplicou enquanto esta esperava o lock,
+// devolve applied=false sem reexecutar.
+func applyMigrationFile(db *sql.DB, f migrationFile) (applied bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationsLockKey); err != nil {
+		return false, err
+	}
+	var current string
+	err = tx.QueryRow(`SELECT checksum FROM schema_migrations WHERE filename = $1`, f.Name).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if current == f.Checksum {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(f.SQL); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO schema_migrations (filename, checksum, applied_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (filename) DO UPDATE SET checksum = EXCLUDED.checksum, applied_at = EXCLUDED.applied_at`,
+		f.Name, f.Checksum); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// applyMigrations aplica, em ordem alfabética, as migrations embarcadas
+// pendentes. Como o conteúdo está embarcado no binário via go:embed, o
+// resultado é independente do working directory do processo — funciona
+// igual no docker local, no Railway e em qualquer outro ambiente.
+//
+// Com stopOnError, a primeira falha interrompe a sequência (as seguintes
+// podem depender dela) e é devolvida. Sem ele, a falha é logada, as demais
+// seguem e o erro devolvido resume as que falharam.
+func applyMigrations(db *sql.DB, logger *log.Logger, stopOnError bool) error {
+	files, err := embeddedMigrations()
+	if err != nil {
+		return fmt.Errorf("applyMigrations: %w", err)
+	}
+	if len(files) == 0 {
+		logger.Println("applyMigrations: nenhum .sql embarcado, pulando.")
+		return nil
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return fmt.Errorf("applyMigrations: criar schema_migrations: %w", err)
+	}
+	applied, err := loadAppliedMigrations(db)
+	if err != nil {
+		return fmt.Errorf("applyMigrations: ler schema_migrations: %w", err)
+	}
+
+	statuses, orphans := planMigrations(files, applied)
+	for _, name := range orphans {
+		logger.Printf("applyMigrations: AVISO %s registrada em schema_migrations sem arquivo correspondente", name)
+	}
+
+	byName := make(map[string]migrationFile, len(files))
+	for _, f := range files {
+		byName[f.Name] = f
+	}
+
+	var failed []string
+	count := 0
+	for _, st := range statuses {
+		if st.State == migrationApplied {
+			continue
+		}
+		if st.State == migrationChanged {
+			logger.Printf("applyMigrations: %s alterada desde a aplicação; reaplicando", st.Name)
+		}
+		ok, err := applyMigrationFile(db, byName[st.Name])
+		if err != nil {
+			logger.Printf("applyMigrations: ERRO aplicando %s: %v", st.Name, err)
+			if stopOnError {
+				return fmt.Errorf("applyMigrations: %s: %w", st.Name, err)
+			}
+			failed = append(failed, st.Name)
+			continue
+		}
+		if ok {
+			count++
+			logger.Printf("applyMigrations: %s aplicada com sucesso", st.Name)
+		}
+	}
+
+	logger.Printf("applyMigrations: %d aplicada(s) agora, %d arquivo(s) embarcado(s)", count, len(files))
+	if len(failed) > 0 {
+		return fmt.Errorf("applyMigrations: falharam %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// ─── Divergência entre as cópias ─────────────────────────────────────────────
+
+type migrationDrift struct {
+	Name    string
+	Problem string
+}
+
+// compareMigrationDirs compara as migrations embarcadas com a cópia de
+// infra/migrations. commentOnly lista os arquivos cujo SQL coincide mas o
+// texto não (comentários diferentes), que não contam como divergência.
+func compareMigrationDirs(embedded, infra []migrationFile) (drift []migrationDrift, commentOnly []string) {
+	other := make(map[string]migrationFile, len(infra))
+	for _, f := range infra {
+		other[f.Name] = f
+	}
+	for _, f := range embedded {
+		o, ok := other[f.Name]
+		delete(other, f.Name)
+		switch {
+		case !ok:
+			drift = append(drift, migrationDrift{f.Name, "ausente em infra/migrations"})
+		case o.Checksum != f.Checksum:
+			drift = append(drift, migrationDrift{f.Name, "SQL diferente entre as duas cópias"})
+		case o.SQL != f.SQL:
+			commentOnly = append(commentOnly, f.Name)
+		}
+	}
+	for name := range other {
+		drift = append(drift, migrationDrift{name, "ausente em api/cmd/api/migrations"})
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Name < drift[j].Name })
+	return drift, commentOnly
+}
+
+// findInfraMigrationsDir procura infra/migrations a partir do diretório
+// atual (raiz do monorepo, api/ ou api/cmd/api).
+func findInfraMigrationsDir() string {
+	for _, p := range []string{
+		filepath.Join("infra", "migrations"),
+		filepath.Join("..", "infra", "migrations"),
+		filepath.Join("..", "..", "..", "infra", "migrations"),
+	} {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// ─── Subcomando migrate ──────────────────────────────────────────────────────
+
+// runMigrateCommand executa `migrate status|up|verify` e devolve o código
+// de saída do processo.
+func runMigrateCommand(args []string, stdout io.Writer, logger *log.Logger) int {
+	usage := func() {
+		fmt.Fprintln(stdout, "uso: api migrate status|up|verify [--infra DIR]")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	cmd := args[0]
+	fset := flag.NewFlagSet("migrate "+cmd, flag.ContinueOnError)
+	fset.SetOutput(stdout)
+	infraDir := fset.String("infra", "", "pasta infra/migrations (verify; padrão: procurada a partir do diretório atual)")
+	if err := fset.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	files, err := embeddedMigrations()
+	if err != nil {
+		fmt.Fprintln(stdout, "ERRO:", err)
+		return 1
+	}
+
+	switch cmd {
+	case "verify":
+		dir := *infraDir
+		if dir == "" {
+			dir = findInfraMigrationsDir()
+		}
+		if dir == "" {
+			fmt.Fprintln(stdout, "ERRO: infra/migrations não encontrada; informe --infra")
+			return 1
+		}
+		infra, err := loadMigrations(os.DirFS(dir), ".")
+		if err != nil {
+			fmt.Fprintln(stdout, "ERRO:", err)
+			return 1
+		}
+		drift, commentOnly := compareMigrationDirs(files, infra)
+		for _, name := range commentOnly {
+			fmt.Fprintf(stdout, "ok        %s (só comentários diferem)\n", name)
+		}
+		for _, d := range drift {
+			fmt.Fprintf(stdout, "DIVERGE   %s: %s\n", d.Name, d.Problem)
+		}
+		if len(drift) > 0 {
+			fmt.Fprintf(stdout, "%d divergência(s) entre api/cmd/api/migrations e %s\n", len(drift), dir)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%d migration(s) iguais em api/cmd/api/migrations e %s\n", len(files), dir)
+		return 0
+
+	case "status", "up":
+		dsn := dsnFromEnv()
+		if dsn == "" {
+			fmt.Fprintln(stdout, "ERRO: variáveis de banco (DB_HOST ou DSN) não encontradas")
+			return 1
+		}
+		var cfg config
+		cfg.db.dsn = dsn
+		db, err := openDB(cfg)
+		if err != nil {
+			fmt.Fprintln(stdout, "ERRO banco:", err)
+			return 1
+		}
+		defer db.Close()
+
+		if cmd == "up" {
+			if err := applyMigrations(db, logger, true); err != nil {
+				fmt.Fprintln(stdout, "ERRO:", err)
+				return 1
+			}
+			return 0
+		}
+
+		if err := ensureMigrationsTable(db); err != nil {
+			fmt.Fprintln(stdout, "ERRO:", err)
+			return 1
+		}
+		applied, err := loadAppliedMigrations(db)
+		if err != nil {
+			fmt.Fprintln(stdout, "ERRO:", err)
+			return 1
+		}
+		statuses, orphans := planMigrations(files, applied)
+		pending := 0
+		for _, st := range statuses {
+			when := ""
+			if st.AppliedAt != nil {
+				when = st.AppliedAt.Format("02/01/2006 15:04")
+			}
+			if st.State != migrationApplied {
+				pending++
+			}
+			fmt.Fprintf(stdout, "%-9s %-50s %s\n", st.State, st.Name, when)
+		}
+		for _, name := range orphans {
+			fmt.Fprintf(stdout, "%-9s %s\n", "sem-arquivo", name)
+		}
+		fmt.Fprintf(stdout, "%d pendente(s) de %d\n", pending, len(statuses))
+		return 0
+	}
+
+	usage()
+	return 2
+}
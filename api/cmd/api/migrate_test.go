@@ -0,0 +1,155 @@
+package main
+
+// Testes do controle de migrations. Sem banco: cobrem o checksum
+// (insensível a comentários), o plano de aplicação a partir de
+// schema_migrations, a detecção de divergência entre as duas cópias e a
+// fonte lida pelas views do censo.
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMigrationChecksumIgnoresComments(t *testing.T) {
+	a := "-- 0001_x\n-- cabeçalho A\nCREATE TABLE x (id INT);\n\n"
+	b := "-- 0001_x\r\n-- cabeçalho B, bem diferente\r\n\r\nCREATE TABLE x (id INT);  \r\n"
+	if migrationChecksum(a) != migrationChecksum(b) {
+		t.Error("comentários/CRLF alteraram o checksum")
+	}
+	c := "CREATE TABLE x (id BIGINT);"
+	if migrationChecksum(a) == migrationChecksum(c) {
+		t.Error("SQL diferente com o mesmo checksum")
+	}
+	// Comentário no fim de uma linha de SQL faz parte da linha.
+	if migrationChecksum("SELECT 1; -- a") == migrationChecksum("SELECT 1; -- b") {
+		t.Error("comentário de fim de linha ignorado")
+	}
+}
+
+func TestLoadMigrationsSorted(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_b.sql":   {Data: []byte("SELECT 2;")},
+		"m/0001_a.sql":   {Data: []byte("SELECT 1;")},
+		"m/README.md":    {Data: []byte("ignorado")},
+		"m/sub/0003.sql": {Data: []byte("SELECT 3;")},
+	}
+	files, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(files) != 2 || files[0].Name != "0001_a.sql" || files[1].Name != "0002_b.sql" {
+		t.Fatalf("arquivos = %+v", files)
+	}
+}
+
+func TestPlanMigrations(t *testing.T) {
+	files := []migrationFile{
+		{Name: "0001.sql", Checksum: "aaa"},
+		{Name: "0002.sql", Checksum: "bbb"},
+		{Name: "0003.sql", Checksum: "ccc"},
+	}
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	applied := map[string]appliedMigration{
+		"0001.sql": {Checksum: "aaa", AppliedAt: at},
+		"0002.sql": {Checksum: "old", AppliedAt: at},
+		"0000.sql": {Checksum: "zzz", AppliedAt: at},
+	}
+	statuses, orphans := planMigrations(files, applied)
+	want := []string{migrationApplied, migrationChanged, migrationPending}
+	for i, st := range statuses {
+		if st.State != want[i] {
+			t.Errorf("%s: %s; want %s", st.Name, st.State, want[i])
+		}
+	}
+	if statuses[2].AppliedAt != nil {
+		t.Error("pendente com data de aplicação")
+	}
+	if len(orphans) != 1 || orphans[0] != "0000.sql" {
+		t.Errorf("orphans = %v", orphans)
+	}
+}
+
+func TestCompareMigrationDirs(t *testing.T) {
+	mk := func(name, sql string) migrationFile {
+		return migrationFile{Name: name, SQL: sql, Checksum: migrationChecksum(sql)}
+	}
+	embedded := []migrationFile{
+		mk("0001.sql", "-- api\nSELECT 1;"),
+		mk("0002.sql", "SELECT 2;"),
+		mk("0003.sql", "SELECT 3;"),
+	}
+	infra := []migrationFile{
+		mk("0001.sql", "-- infra\nSELECT 1;"),
+		mk("0002.sql", "SELECT 22;"),
+		mk("0004.sql", "SELECT 4;"),
+	}
+	drift, commentOnly := compareMigrationDirs(embedded, infra)
+	if len(commentOnly) != 1 || commentOnly[0] != "0001.sql" {
+		t.Errorf("commentOnly = %v", commentOnly)
+	}
+	if len(drift) != 3 || drift[0].Name != "0002.sql" || drift[1].Name != "0003.sql" || drift[2].Name != "0004.sql" {
+		t.Errorf("drift = %+v", drift)
+	}
+}
+
+// As cópias versionadas no repositório não podem divergir.
+func TestEmbeddedMigrationsMatchInfra(t *testing.T) {
+	dir := findInfraMigrationsDir()
+	if dir == "" {
+		t.Skip("infra/migrations fora da árvore")
+	}
+	embedded, err := embeddedMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	infra, err := loadMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift, _ := compareMigrationDirs(embedded, infra); len(drift) > 0 {
+		t.Fatalf("divergências: %+v", drift)
+	}
+}
+
+// As views do censo precisam ler vw_censo_fonte e vw_censo_escolas na
+// última migration que as define; senão a consulta sob snapshot volta a ver
+// as respostas e o cadastro vivos.
+func TestCensusViewsReadSnapshotSource(t *testing.T) {
+	files, err := embeddedMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewRe := regexp.MustCompile(`(?i)CREATE OR REPLACE VIEW (vw_censo_\w+)`)
+	liveRe := regexp.MustCompile(`(?i)\bcensus_responses\b|\bschools\s+s\b`)
+
+	last := map[string]string{}
+	for _, f := range files {
+		for _, stmt := range strings.Split(f.SQL, ";") {
+			var body []string
+			for _, line := range strings.Split(stmt, "\n") {
+				if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+					body = append(body, line)
+				}
+			}
+			sql := strings.Join(body, "\n")
+			if m := viewRe.FindStringSubmatch(sql); m != nil {
+				last[m[1]] = f.Name + "\n" + sql
+			}
+		}
+	}
+	if len(last) == 0 {
+		t.Fatal("nenhuma view vw_censo_* encontrada")
+	}
+	for name, def := range last {
+		if name == "vw_censo_fonte" || name == "vw_censo_escolas" {
+			continue
+		}
+		if liveRe.MatchString(def) {
+			t.Errorf("%s ainda lê as tabelas vivas (%s)", name, strings.SplitN(def, "\n", 2)[0])
+		}
+	}
+}
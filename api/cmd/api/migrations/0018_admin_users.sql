@@ -0,0 +1,43 @@
+-- 0018_admin_users
+-- Contas individuais do painel administrativo. Substitui a conta única
+-- definida por ADMIN_USERNAME/ADMIN_PASSWORD_HASH: cada analista, gestor de
+-- DRE e administrador SEDUC passa a ter login próprio, com hash bcrypt,
+-- flag de ativo/desativado e um papel (role).
+--
+-- Papéis:
+--   * seduc_admin — administração total do painel e das contas;
+--   * dre_gestor  — coordenação regional (escopo de DRE em fase posterior);
+--   * analista    — leitura dos painéis e relatórios.
+--
+-- A conta definida no ambiente (ADMIN_USERNAME/ADMIN_PASSWORD_HASH) é semeada
+-- no boot como seduc_admin apenas quando esta tabela está vazia — ver
+-- bootstrapAdminUser em api/cmd/api/admin_users.go.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0018_admin_users.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS admin_users (
+    id             SERIAL PRIMARY KEY,
+    username       VARCHAR(64)  NOT NULL,
+    nome           VARCHAR(150) NOT NULL DEFAULT '',
+    password_hash  TEXT         NOT NULL,
+    role           VARCHAR(30)  NOT NULL,
+    active         BOOLEAN      NOT NULL DEFAULT TRUE,
+    last_login_at  TIMESTAMP    NULL,
+    created_at     TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$ BEGIN
+    ALTER TABLE admin_users
+        ADD CONSTRAINT admin_users_username_uniq
+        UNIQUE (username);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+    ALTER TABLE admin_users
+        ADD CONSTRAINT admin_users_role_chk
+        CHECK (role IN ('seduc_admin', 'dre_gestor', 'analista'));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_admin_users_role ON admin_users (role);
@@ -0,0 +1,24 @@
+-- 0019_admin_users_dre
+-- Escopo regional das contas do painel. admin_users.dre restringe todos os
+-- endpoints analíticos, de censo e de relatórios àquela DRE (ver
+-- enforceDREScope em api/cmd/api/admin_scope.go). NULL = acesso estadual.
+--
+-- Regras de consistência:
+--   * dre_gestor sempre tem DRE (coordenação regional);
+--   * seduc_admin nunca tem DRE (administração estadual);
+--   * analista pode ou não ter DRE.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0019_admin_users_dre.sql e infra/init.sql.
+
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS dre VARCHAR(100) NULL;
+
+DO $$ BEGIN
+    ALTER TABLE admin_users
+        ADD CONSTRAINT admin_users_dre_role_chk
+        CHECK (
+            (role = 'dre_gestor' AND dre IS NOT NULL AND TRIM(dre) <> '')
+            OR (role = 'seduc_admin' AND dre IS NULL)
+            OR role = 'analista'
+        );
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
@@ -0,0 +1,31 @@
+-- 0024_admin_totp
+-- Segundo fator (TOTP, RFC 6238) opcional por conta do painel. O segredo
+-- fica cifrado (AES-GCM) em admin_users.totp_secret; totp_pending_secret
+-- guarda o segredo de uma ativação ainda não confirmada com um código.
+-- totp_last_step é o último passo de 30 s aceito, para que um mesmo código
+-- não sirva duas vezes.
+--
+-- admin_totp_recovery_codes guarda só o hash SHA-256 dos códigos de
+-- recuperação; cada um vale uma vez (used_at).
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0024_admin_totp.sql e infra/init.sql.
+
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_secret         TEXT      NULL;
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_pending_secret TEXT      NULL;
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_enabled_at     TIMESTAMP NULL;
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_last_step      BIGINT    NULL;
+
+CREATE TABLE IF NOT EXISTS admin_totp_recovery_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     INTEGER   NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    code_hash   CHAR(64)  NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at     TIMESTAMP NULL
+);
+
+DO $$ BEGIN
+    ALTER TABLE admin_totp_recovery_codes
+        ADD CONSTRAINT admin_totp_recovery_codes_uniq
+        UNIQUE (user_id, code_hash);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
@@ -0,0 +1,35 @@
+-- 0025_census_revisions
+-- Histórico de escritas em census_responses. Cada escrita aceita por
+-- CensusModel.Upsert grava, na mesma transação, uma linha com o JSON
+-- anterior e o novo, a transição de status, quem escreveu (actor_kind:
+-- escola = formulário com código de acesso verificado, formulario = sem
+-- código, admin = painel; actor = username quando admin), o IP (via
+-- clientIP) e quando. revision é sequencial por censo, começando em 1.
+--
+-- Registros não são alterados nem removidos pela API; somem só com o censo
+-- (ON DELETE CASCADE).
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0025_census_revisions.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS census_revisions (
+    id               BIGSERIAL PRIMARY KEY,
+    census_id        INTEGER     NOT NULL REFERENCES census_responses (id) ON DELETE CASCADE,
+    revision         INTEGER     NOT NULL,
+    previous_status  VARCHAR(50) NULL,
+    status           VARCHAR(50) NOT NULL,
+    previous_data    JSONB       NULL,
+    data             JSONB       NOT NULL,
+    actor_kind       VARCHAR(20) NOT NULL DEFAULT 'formulario',
+    actor            VARCHAR(64) NOT NULL DEFAULT '',
+    client_ip        VARCHAR(64) NOT NULL DEFAULT '',
+    created_at       TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$ BEGIN
+    ALTER TABLE census_revisions
+        ADD CONSTRAINT census_revisions_census_revision_uniq
+        UNIQUE (census_id, revision);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_census_revisions_created_at ON census_revisions (created_at DESC);
@@ -0,0 +1,19 @@
+-- 0026_census_version
+-- Versão de cada censo para controle de concorrência otimista. version é
+-- incrementada a cada escrita e coincide com o número da revisão gravada
+-- em census_revisions (0 = censo anterior ao histórico, sem escrita desde
+-- então). GET /v1/census a devolve como ETag; POST /v1/census com If-Match
+-- recusa com 409 a escrita baseada numa versão antiga.
+--
+-- O UPDATE alinha censos que já têm revisões (version nunca diminui).
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0026_census_version.sql e infra/init.sql.
+
+ALTER TABLE census_responses ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0;
+
+UPDATE census_responses cr
+SET version = r.last_revision
+FROM (SELECT census_id, MAX(revision) AS last_revision
+      FROM census_revisions GROUP BY census_id) r
+WHERE r.census_id = cr.id AND cr.version < r.last_revision;
@@ -0,0 +1,37 @@
+-- 0033_locations
+-- Hierarquia DRE → município → escola que alimenta os seletores do
+-- formulário (GET /v1/locations). Antes lida de data/locations.xlsx a cada
+-- requisição; agora fica no banco, semeada desse arquivo no primeiro
+-- startup com a tabela vazia e substituída pelo painel
+-- (POST /v1/admin/locations). codigo_inep vem da coluna do arquivo ou do
+-- casamento com schools por nome e município; fica NULL quando ambíguo.
+-- Cada substituição grava um registro em location_uploads com o hash do
+-- arquivo e o diff aplicado.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0033_locations.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS locations (
+    id          SERIAL       PRIMARY KEY,
+    dre         TEXT         NOT NULL,
+    municipio   TEXT         NOT NULL,
+    escola      TEXT         NOT NULL,
+    codigo_inep VARCHAR(20)  NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT locations_escola_uniq UNIQUE (dre, municipio, escola)
+);
+
+CREATE INDEX IF NOT EXISTS idx_locations_codigo_inep ON locations (codigo_inep);
+
+CREATE TABLE IF NOT EXISTS location_uploads (
+    id           BIGSERIAL PRIMARY KEY,
+    source_file  TEXT      NOT NULL,
+    source_hash  CHAR(64)  NOT NULL,
+    rows_total   INTEGER   NOT NULL,
+    added        INTEGER   NOT NULL DEFAULT 0,
+    removed      INTEGER   NOT NULL DEFAULT 0,
+    inep_changed INTEGER   NOT NULL DEFAULT 0,
+    diff         JSONB     NOT NULL DEFAULT '{}'::jsonb,
+    created_by   TEXT      NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,330 @@
+-- 0034_municipios_dres
+-- Tabelas de referência de municípios (códigos IBGE) e DREs. Até aqui o
+-- município e a DRE das escolas eram texto livre, casado com reg_integracao
+-- por UPPER(TRIM()) — "IGARAPE MIRI" e "IGARAPE-MIRI" contavam como
+-- municípios distintos. Agora cada nome tem uma chave normalizada
+-- (censo_chave: minúsculas, sem acento via unaccent, sem apóstrofo,
+-- hífens e pontuação como espaço) e schools ganha municipio_id e dre_id,
+-- resolvidos por trigger a cada INSERT ou UPDATE de municipio/dre.
+--
+-- Grafias que a chave não alcança (distritos, nomes antigos) ficam em
+-- municipio_aliases. Valores sem correspondência deixam o id NULL e
+-- aparecem em GET /v1/admin/reference/unmatched; depois de cadastrar um
+-- alias, "UPDATE schools SET municipio = municipio WHERE municipio_id IS
+-- NULL" refaz o casamento.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0034_municipios_dres.sql e infra/init.sql.
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- Chave de comparação de nomes. IMMUTABLE com o dicionário explícito, para
+-- poder ser usada em índices e constraints.
+CREATE OR REPLACE FUNCTION censo_chave(valor TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT NULLIF(btrim(regexp_replace(
+        regexp_replace(lower(public.unaccent('public.unaccent'::regdictionary, COALESCE(valor, ''))),
+                       '[''’´`]', '', 'g'),
+        '[^a-z0-9]+', ' ', 'g')), '')
+$$;
+
+-- Chave de DRE: como censo_chave, sem o prefixo "DRE"/"URE" ("DRE Belém 1"
+-- = "BELEM 1").
+CREATE OR REPLACE FUNCTION censo_chave_dre(valor TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT NULLIF(regexp_replace(censo_chave(valor), '^(dre|ure) ', ''), '')
+$$;
+
+CREATE TABLE IF NOT EXISTS municipios (
+    id                SERIAL  PRIMARY KEY,
+    codigo_ibge       CHAR(7) NOT NULL UNIQUE,
+    nome              TEXT    NOT NULL,
+    chave             TEXT    NOT NULL UNIQUE,
+    regiao_integracao TEXT    NULL
+);
+
+CREATE TABLE IF NOT EXISTS municipio_aliases (
+    chave        TEXT    PRIMARY KEY,
+    municipio_id INTEGER NOT NULL REFERENCES municipios(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS dres (
+    id                SERIAL  PRIMARY KEY,
+    nome              TEXT    NOT NULL UNIQUE,
+    chave             TEXT    NOT NULL UNIQUE,
+    sede_municipio_id INTEGER NULL REFERENCES municipios(id) ON DELETE SET NULL
+);
+
+-- Os 144 municípios do Pará (IBGE, tabela DTB).
+INSERT INTO municipios (codigo_ibge, nome, chave)
+SELECT v.codigo_ibge, v.nome, censo_chave(v.nome)
+FROM (VALUES
+    ('1500107', 'Abaetetuba'),
+    ('1500131', 'Abel Figueiredo'),
+    ('1500206', 'Acará'),
+    ('1500305', 'Afuá'),
+    ('1500347', 'Água Azul do Norte'),
+    ('1500404', 'Alenquer'),
+    ('1500503', 'Almeirim'),
+    ('1500602', 'Altamira'),
+    ('1500701', 'Anajás'),
+    ('1500800', 'Ananindeua'),
+    ('1500859', 'Anapu'),
+    ('1500909', 'Augusto Corrêa'),
+    ('1500958', 'Aurora do Pará'),
+    ('1501006', 'Aveiro'),
+    ('1501105', 'Bagre'),
+    ('1501204', 'Baião'),
+    ('1501253', 'Bannach'),
+    ('1501303', 'Barcarena'),
+    ('1501402', 'Belém'),
+    ('1501451', 'Belterra'),
+    ('1501501', 'Benevides'),
+    ('1501576', 'Bom Jesus do Tocantins'),
+    ('1501600', 'Bonito'),
+    ('1501709', 'Bragança'),
+    ('1501725', 'Brasil Novo'),
+    ('1501758', 'Brejo Grande do Araguaia'),
+    ('1501782', 'Breu Branco'),
+    ('1501808', 'Breves'),
+    ('1501907', 'Bujaru'),
+    ('1501956', 'Cachoeira do Piriá'),
+    ('1502004', 'Cachoeira do Arari'),
+    ('1502103', 'Cametá'),
+    ('1502152', 'Canaã dos Carajás'),
+    ('1502202', 'Capanema'),
+    ('1502301', 'Capitão Poço'),
+    ('1502400', 'Castanhal'),
+    ('1502509', 'Chaves'),
+    ('1502608', 'Colares'),
+    ('1502707', 'Conceição do Araguaia'),
+    ('1502756', 'Concórdia do Pará'),
+    ('1502764', 'Cumaru do Norte'),
+    ('1502772', 'Curionópolis'),
+    ('1502806', 'Curralinho'),
+    ('1502855', 'Curuá'),
+    ('1502905', 'Curuçá'),
+    ('1502939', 'Dom Eliseu'),
+    ('1502954', 'Eldorado do Carajás'),
+    ('1503002', 'Faro'),
+    ('1503044', 'Floresta do Araguaia'),
+    ('1503077', 'Garrafão do Norte'),
+    ('1503093', 'Goianésia do Pará'),
+    ('1503101', 'Gurupá'),
+    ('1503200', 'Igarapé-Açu'),
+    ('1503309', 'Igarapé-Miri'),
+    ('1503408', 'Inhangapi'),
+    ('1503457', 'Ipixuna do Pará'),
+    ('1503507', 'Irituia'),
+    ('1503606', 'Itaituba'),
+    ('1503705', 'Itupiranga'),
+    ('1503754', 'Jacareacanga'),
+    ('1503804', 'Jacundá'),
+    ('1503903', 'Juruti'),
+    ('1504000', 'Limoeiro do Ajuru'),
+    ('1504059', 'Mãe do Rio'),
+    ('1504109', 'Magalhães Barata'),
+    ('1504208', 'Marabá'),
+    ('1504307', 'Maracanã'),
+    ('1504406', 'Marapanim'),
+    ('1504422', 'Marituba'),
+    ('1504455', 'Medicilândia'),
+    ('1504505', 'Melgaço'),
+    ('1504604', 'Mocajuba'),
+    ('1504703', 'Moju'),
+    ('1504752', 'Mojuí dos Campos'),
+    ('1504802', 'Monte Alegre'),
+    ('1504901', 'Muaná'),
+    ('1504950', 'Nova Esperança do Piriá'),
+    ('1504976', 'Nova Ipixuna'),
+    ('1505007', 'Nova Timboteua'),
+    ('1505031', 'Novo Progresso'),
+    ('1505064', 'Novo Repartimento'),
+    ('1505106', 'Óbidos'),
+    ('1505205', 'Oeiras do Pará'),
+    ('1505304', 'Oriximiná'),
+    ('1505403', 'Ourém'),
+    ('1505437', 'Ourilândia do Norte'),
+    ('1505486', 'Pacajá'),
+    ('1505494', 'Palestina do Pará'),
+    ('1505502', 'Paragominas'),
+    ('1505536', 'Parauapebas'),
+    ('1505551', 'Pau D''Arco'),
+    ('1505601', 'Peixe-Boi'),
+    ('1505635', 'Piçarra'),
+    ('1505650', 'Placas'),
+    ('1505700', 'Ponta de Pedras'),
+    ('1505809', 'Portel'),
+    ('1505908', 'Porto de Moz'),
+    ('1506005', 'Prainha'),
+    ('1506104', 'Primavera'),
+    ('1506112', 'Quatipuru'),
+    ('1506138', 'Redenção'),
+    ('1506161', 'Rio Maria'),
+    ('1506187', 'Rondon do Pará'),
+    ('1506195', 'Rurópolis'),
+    ('1506203', 'Salinópolis'),
+    ('1506302', 'Salvaterra'),
+    ('1506351', 'Santa Bárbara do Pará'),
+    ('1506401', 'Santa Cruz do Arari'),
+    ('1506500', 'Santa Izabel do Pará'),
+    ('1506559', 'Santa Luzia do Pará'),
+    ('1506583', 'Santa Maria das Barreiras'),
+    ('1506609', 'Santa Maria do Pará'),
+    ('1506708', 'Santana do Araguaia'),
+    ('1506807', 'Santarém'),
+    ('1506906', 'Santarém Novo'),
+    ('1507003', 'Santo Antônio do Tauá'),
+    ('1507102', 'São Caetano de Odivelas'),
+    ('1507151', 'São Domingos do Araguaia'),
+    ('1507201', 'São Domingos do Capim'),
+    ('1507300', 'São Félix do Xingu'),
+    ('1507409', 'São Francisco do Pará'),
+    ('1507458', 'São Geraldo do Araguaia'),
+    ('1507466', 'São João da Ponta'),
+    ('1507474', 'São João de Pirabas'),
+    ('1507508', 'São João do Araguaia'),
+    ('1507607', 'São Miguel do Guamá'),
+    ('1507706', 'São Sebastião da Boa Vista'),
+    ('1507755', 'Sapucaia'),
+    ('1507805', 'Senador José Porfírio'),
+    ('1507904', 'Soure'),
+    ('1507953', 'Tailândia'),
+    ('1507961', 'Terra Alta'),
+    ('1507979', 'Terra Santa'),
+    ('1508001', 'Tomé-Açu'),
+    ('1508035', 'Tracuateua'),
+    ('1508050', 'Trairão'),
+    ('1508084', 'Tucumã'),
+    ('1508100', 'Tucuruí'),
+    ('1508126', 'Ulianópolis'),
+    ('1508159', 'Uruará'),
+    ('1508209', 'Vigia'),
+    ('1508308', 'Viseu'),
+    ('1508357', 'Vitória do Xingu'),
+    ('1508407', 'Xinguara')
+) AS v (codigo_ibge, nome)
+ON CONFLICT (codigo_ibge) DO NOTHING;
+
+-- Grafias que aparecem nas planilhas e em reg_integracao.
+INSERT INTO municipio_aliases (chave, municipio_id)
+SELECT censo_chave(v.alias), m.id
+FROM (VALUES
+    ('Eldorado dos Carajás',      '1502954'),
+    ('Santa Isabel do Pará',      '1506500'),
+    ('Santa Bárbara',             '1506351'),
+    ('Icoaraci',                  '1501402'),
+    ('Distrito de Icoaraci',      '1501402'),
+    ('Outeiro',                   '1501402'),
+    ('Distrito de Outeiro',       '1501402'),
+    ('Mosqueiro',                 '1501402'),
+    ('Distrito de Mosqueiro',     '1501402'),
+    ('Monte Dourado',             '1500503'),
+    ('Distrito de Monte Dourado', '1500503')
+) AS v (alias, codigo_ibge)
+JOIN municipios m ON m.codigo_ibge = v.codigo_ibge
+ON CONFLICT (chave) DO NOTHING;
+
+-- Município canônico de um texto livre: chave do nome ou alias.
+CREATE OR REPLACE FUNCTION censo_municipio_id(valor TEXT) RETURNS INTEGER
+LANGUAGE sql STABLE AS $$
+    SELECT COALESCE(
+        (SELECT id FROM municipios WHERE chave = censo_chave(valor)),
+        (SELECT municipio_id FROM municipio_aliases WHERE chave = censo_chave(valor)))
+$$;
+
+CREATE OR REPLACE FUNCTION censo_dre_id(valor TEXT) RETURNS INTEGER
+LANGUAGE sql STABLE AS $$
+    SELECT id FROM dres WHERE chave = censo_chave_dre(valor)
+$$;
+
+UPDATE municipios m
+SET regiao_integracao = r.regiao_de_integracao
+FROM reg_integracao r
+WHERE censo_municipio_id(r.municipio) = m.id
+  AND m.regiao_integracao IS NULL;
+
+-- DREs da SEDUC (setores de data/locations.xlsx) com o município sede.
+INSERT INTO dres (nome, chave, sede_municipio_id)
+SELECT v.nome, censo_chave_dre(v.nome), m.id
+FROM (VALUES
+    ('ABAETETUBA',            '1500107'),
+    ('AFUA',                  '1500305'),
+    ('ALTAMIRA',              '1500602'),
+    ('ANANINDEUA 1',          '1500800'),
+    ('ANANINDEUA 2',          '1500800'),
+    ('ANANINDEUA 3',          '1500800'),
+    ('ANANINDEUA 4',          '1500800'),
+    ('ANANINDEUA 5',          '1500800'),
+    ('BELEM 1',               '1501402'),
+    ('BELEM 2',               '1501402'),
+    ('BELEM 3',               '1501402'),
+    ('BELEM 4',               '1501402'),
+    ('BELEM 5',               '1501402'),
+    ('BELEM 6',               '1501402'),
+    ('BELEM 7',               '1501402'),
+    ('BELEM 8',               '1501402'),
+    ('BELEM 9',               '1501402'),
+    ('BELEM 10',              '1501402'),
+    ('BENEVIDES',             '1501501'),
+    ('BRAGANCA',              '1501709'),
+    ('BREVES',                '1501808'),
+    ('CACHOEIRA DO ARARI',    '1502004'),
+    ('CAMETA',                '1502103'),
+    ('CAPANEMA',              '1502202'),
+    ('CAPITAO POCO',          '1502301'),
+    ('CASTANHAL',             '1502400'),
+    ('CONCEICAO DO ARAGUAIA', '1502707'),
+    ('CURRALINHO',            '1502806'),
+    ('ITAITUBA',              '1503606'),
+    ('MAE DO RIO',            '1504059'),
+    ('MARABA',                '1504208'),
+    ('MARACANA',              '1504307'),
+    ('MONTE ALEGRE',          '1504802'),
+    ('OBIDOS',                '1505106'),
+    ('PARAUAPEBAS',           '1505536'),
+    ('SANTA BARBARA',         '1506351'),
+    ('SANTA IZABEL DO PARA',  '1506500'),
+    ('SANTAREM',              '1506807'),
+    ('TUCURUI',               '1508100'),
+    ('XINGUARA',              '1508407')
+) AS v (nome, codigo_ibge)
+LEFT JOIN municipios m ON m.codigo_ibge = v.codigo_ibge
+ON CONFLICT (nome) DO NOTHING;
+
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS municipio_id INTEGER NULL;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS dre_id       INTEGER NULL;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_municipio_fk
+        FOREIGN KEY (municipio_id) REFERENCES municipios(id) ON DELETE SET NULL;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_dre_fk
+        FOREIGN KEY (dre_id) REFERENCES dres(id) ON DELETE SET NULL;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_schools_municipio_id ON schools (municipio_id);
+CREATE INDEX IF NOT EXISTS idx_schools_dre_id       ON schools (dre_id);
+
+-- Resolve municipio_id e dre_id a partir do texto, em qualquer caminho de
+-- escrita (formulário, painel, cmd/import-schools).
+CREATE OR REPLACE FUNCTION schools_resolve_referencias() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.municipio_id := censo_municipio_id(NEW.municipio);
+    NEW.dre_id := censo_dre_id(NEW.dre);
+    RETURN NEW;
+END $$;
+
+DROP TRIGGER IF EXISTS schools_resolve_referencias ON schools;
+CREATE TRIGGER schools_resolve_referencias
+    BEFORE INSERT OR UPDATE OF municipio, dre ON schools
+    FOR EACH ROW EXECUTE FUNCTION schools_resolve_referencias();
+
+UPDATE schools
+SET municipio = municipio
+WHERE (municipio_id IS NULL AND censo_municipio_id(municipio) IS NOT NULL)
+   OR (dre_id IS NULL AND censo_dre_id(dre) IS NOT NULL);
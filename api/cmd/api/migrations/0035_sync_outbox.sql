@@ -0,0 +1,57 @@
+-- 0035_sync_outbox
+-- Fila durável da sincronização com a planilha do Google Sheets. Antes, o
+-- envio era uma goroutine disparada pelo POST /v1/census mais um job que
+-- varria sheet_synced_at IS NULL a cada 10 minutos, sem contagem de
+-- tentativas, erro gravado ou backoff; falhas nas abas de déficit eram
+-- descartadas.
+--
+-- Cada envio é um item: kind identifica o destino ('sheets') e target a
+-- aba ('Base_dados', 'Deficit_Portaria', ...). A conclusão do censo
+-- enfileira a linha de Base_dados na mesma transação da gravação; o worker
+-- da API enfileira as abas de déficit quando a linha principal é enviada.
+-- Falhas voltam para 'pending' com next_attempt_at em backoff exponencial;
+-- esgotadas as tentativas, o item vai para 'dead' e espera retry ou
+-- descarte pelo painel (/v1/admin/sync/outbox).
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0035_sync_outbox.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS sync_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    census_id       INTEGER   NOT NULL REFERENCES census_responses(id) ON DELETE CASCADE,
+    kind            TEXT      NOT NULL,
+    target          TEXT      NOT NULL,
+    status          TEXT      NOT NULL DEFAULT 'pending',
+    attempts        INTEGER   NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error      TEXT      NULL,
+    locked_at       TIMESTAMP NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    done_at         TIMESTAMP NULL,
+    CONSTRAINT sync_outbox_status_chk
+        CHECK (status IN ('pending', 'processing', 'done', 'dead', 'discarded'))
+);
+
+-- No máximo um item pendente por censo e destino: concluir o censo de novo
+-- antes do envio não duplica a linha na planilha.
+CREATE UNIQUE INDEX IF NOT EXISTS sync_outbox_pending_uniq
+    ON sync_outbox (census_id, kind, target) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_sync_outbox_due
+    ON sync_outbox (next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_sync_outbox_status
+    ON sync_outbox (status, updated_at DESC);
+
+-- Censos concluídos ainda não enviados entram na fila. Censos que já têm
+-- item (inclusive 'dead' ou descartado) ficam como estão: a reaplicação
+-- no startup não ressuscita o que o painel tirou da fila.
+INSERT INTO sync_outbox (census_id, kind, target)
+SELECT cr.id, 'sheets', 'Base_dados'
+FROM census_responses cr
+WHERE censo_enviado(cr.status) AND cr.sheet_synced_at IS NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM sync_outbox o
+      WHERE o.census_id = cr.id AND o.kind = 'sheets' AND o.target = 'Base_dados')
+ON CONFLICT (census_id, kind, target) WHERE status = 'pending' DO NOTHING;
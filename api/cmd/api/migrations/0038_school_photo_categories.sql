@@ -0,0 +1,28 @@
+-- 0038_school_photo_categories
+-- Fotos categorizadas e miniaturas. Cada escola envia várias fotos por
+-- categoria (fachada, cozinha, banheiros, salas ou outras), para conferir
+-- o estado do prédio contra situacao_estrutura e condicoes_cozinha do
+-- censo. As fotos gravadas antes da categoria ficam em 'outras'.
+--
+-- thumb_path/thumb_url localizam a miniatura JPEG no mesmo armazenamento
+-- da foto (NULL quando o formato não permite gerá-la, como WebP); width e
+-- height são as dimensões da foto já sem metadados.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0038_school_photo_categories.sql e infra/init.sql.
+
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS category   TEXT    NOT NULL DEFAULT 'outras';
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS thumb_path TEXT    NULL;
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS thumb_url  TEXT    NULL;
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS width      INTEGER NULL;
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS height     INTEGER NULL;
+
+DO $$ BEGIN
+    ALTER TABLE school_photos
+        ADD CONSTRAINT school_photos_category_chk
+        CHECK (category IN ('fachada', 'cozinha', 'banheiros', 'salas', 'outras'));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+-- Galeria do painel: fotos da escola por ano e categoria.
+CREATE INDEX IF NOT EXISTS idx_school_photos_school
+    ON school_photos (school_id, year, category, created_at);
@@ -0,0 +1,33 @@
+-- 0040_schools_inep_fundido
+-- INEP da escola fundida. A fusão libera codigo_inep (UNIQUE) da linha
+-- fundida e o guarda em codigo_inep_fundido: o código passa à sobrevivente
+-- que não tem INEP e, de todo modo, a busca por INEP segue merged_into até
+-- a escola viva.
+--
+-- Fusões feitas antes desta migration são acertadas aqui: o INEP da linha
+-- fundida é liberado e entregue à sobrevivente sem INEP (a de menor id, se
+-- várias foram fundidas nela).
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0040_schools_inep_fundido.sql e infra/init.sql.
+
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS codigo_inep_fundido VARCHAR(20) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_schools_inep_fundido ON schools (codigo_inep_fundido) WHERE codigo_inep_fundido IS NOT NULL;
+
+UPDATE schools
+SET codigo_inep_fundido = NULLIF(codigo_inep, ''), codigo_inep = NULL
+WHERE merged_into IS NOT NULL AND codigo_inep IS NOT NULL;
+
+UPDATE schools t
+SET codigo_inep = f.codigo_inep_fundido, updated_at = NOW()
+FROM (
+    SELECT DISTINCT ON (merged_into) merged_into, codigo_inep_fundido
+    FROM schools
+    WHERE merged_into IS NOT NULL AND NULLIF(codigo_inep_fundido, '') IS NOT NULL
+    ORDER BY merged_into, id
+) f
+WHERE t.id = f.merged_into
+  AND t.merged_into IS NULL
+  AND NULLIF(t.codigo_inep, '') IS NULL
+  AND NOT EXISTS (SELECT 1 FROM schools o WHERE o.codigo_inep = f.codigo_inep_fundido);
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"censo-api/internal/services"
+)
+
+// =====================================================================
+// Armazenamento das fotos das escolas
+// =====================================================================
+// PHOTO_STORE escolhe onde POST /v1/upload grava cada foto:
+//
+//   - drive  pasta da escola no Google Drive (DRIVE_ROOT_FOLDER_ID);
+//     padrão quando o Drive está configurado
+//   - local  PHOTO_STORE_DIR (padrão data/photos); padrão sem o Drive
+//   - s3     bucket compatível com S3: PHOTO_S3_ENDPOINT, PHOTO_S3_BUCKET,
+//     PHOTO_S3_REGION (padrão us-east-1), PHOTO_S3_ACCESS_KEY_ID,
+//     PHOTO_S3_SECRET_ACCESS_KEY e PHOTO_S3_PREFIX opcional
+//
+// Os metadados de cada foto ficam em school_photos com o kind do
+// armazenamento, então trocar PHOTO_STORE não perde as fotos já gravadas.
+// =====================================================================
+
+const defaultPhotoStoreDir = "data/photos"
+
+// newPhotoStore monta o armazenamento de PHOTO_STORE.
+func newPhotoStore(drive *services.DriveService) (services.PhotoStore, error) {
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv("PHOTO_STORE")))
+	if kind == "" {
+		kind = "local"
+		if drive != nil {
+			kind = "drive"
+		}
+	}
+	switch kind {
+	case "drive":
+		if drive == nil {
+			return nil, fmt.Errorf("PHOTO_STORE=drive: Drive não configurado")
+		}
+		return drive, nil
+	case "local":
+		dir := strings.TrimSpace(os.Getenv("PHOTO_STORE_DIR"))
+		if dir == "" {
+			dir = defaultPhotoStoreDir
+		}
+		return services.NewLocalPhotoStore(dir)
+	case "s3":
+		return services.NewS3PhotoStore(services.S3Config{
+			Endpoint:  strings.TrimSpace(os.Getenv("PHOTO_S3_ENDPOINT")),
+			Bucket:    strings.TrimSpace(os.Getenv("PHOTO_S3_BUCKET")),
+			Region:    strings.TrimSpace(os.Getenv("PHOTO_S3_REGION")),
+			AccessKey: strings.TrimSpace(os.Getenv("PHOTO_S3_ACCESS_KEY_ID")),
+			SecretKey: os.Getenv("PHOTO_S3_SECRET_ACCESS_KEY"),
+			Prefix:    strings.TrimSpace(os.Getenv("PHOTO_S3_PREFIX")),
+		})
+	}
+	return nil, fmt.Errorf("PHOTO_STORE: armazenamento desconhecido %q", kind)
+}
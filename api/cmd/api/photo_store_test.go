@@ -0,0 +1,188 @@
+package main
+
+// Testes do armazenamento das fotos. Sem banco e sem Google: cobrem a
+// leitura de PHOTO_STORE, a chave da foto, a gravação em disco (repetível,
+// sem arquivo temporário sobrando, leitura só dentro do diretório) e o PUT e
+// o GET assinados no S3.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"censo-api/internal/models"
+	"censo-api/internal/services"
+)
+
+func photoObject(content string) services.PhotoObject {
+	sum := sha256.Sum256([]byte(content))
+	return services.PhotoObject{
+		School:      models.School{ID: 9, Nome: "EEEM Teste", Dre: "DRE Belém 1"},
+		Year:        2026,
+		Name:        "fachada.JPG",
+		ContentType: "image/jpeg",
+		Size:        int64(len(content)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+}
+
+func TestNewPhotoStore(t *testing.T) {
+	t.Setenv("PHOTO_STORE", "")
+	t.Setenv("PHOTO_STORE_DIR", t.TempDir())
+	store, err := newPhotoStore(nil)
+	if err != nil || store.Kind() != "local" {
+		t.Fatalf("padrão sem Drive: %v %v", store, err)
+	}
+
+	t.Setenv("PHOTO_STORE", "drive")
+	if _, err := newPhotoStore(nil); err == nil {
+		t.Error("drive sem Drive configurado aceito")
+	}
+
+	t.Setenv("PHOTO_STORE", "S3")
+	t.Setenv("PHOTO_S3_ENDPOINT", "https://s3.exemplo.gov.br")
+	t.Setenv("PHOTO_S3_BUCKET", "fotos")
+	t.Setenv("PHOTO_S3_ACCESS_KEY_ID", "AK")
+	t.Setenv("PHOTO_S3_SECRET_ACCESS_KEY", "segredo")
+	if store, err = newPhotoStore(nil); err != nil || store.Kind() != "s3" {
+		t.Errorf("s3: %v %v", store, err)
+	}
+	t.Setenv("PHOTO_S3_BUCKET", "")
+	if _, err := newPhotoStore(nil); err == nil {
+		t.Error("s3 sem bucket aceito")
+	}
+
+	t.Setenv("PHOTO_STORE", "ftp")
+	if _, err := newPhotoStore(nil); err == nil {
+		t.Error("armazenamento desconhecido aceito")
+	}
+}
+
+func TestPhotoKey(t *testing.T) {
+	obj := photoObject("abc")
+	if got, want := services.PhotoKey(obj), "9/2026/"+obj.SHA256+".jpg"; got != want {
+		t.Errorf("PhotoKey = %q; want %q", got, want)
+	}
+	obj.ContentType = "application/octet-stream"
+	if got := services.PhotoKey(obj); !strings.HasSuffix(got, ".jpg") {
+		t.Errorf("sem tipo conhecido: %q", got)
+	}
+	if got := services.DriveFolderName(models.School{ID: 9, INEP: "15000001", Nome: "EEEM D'Ávila", NomeDiretor: "Ana"}); got != "15000001 - EEEM D--vila" {
+		t.Errorf("pasta = %q", got)
+	}
+	if got := services.DriveFolderName(models.School{ID: 9, Nome: "EEEM Teste"}); got != "Escola 9 - EEEM Teste" {
+		t.Errorf("pasta sem INEP = %q", got)
+	}
+}
+
+func TestLocalPhotoStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := services.NewLocalPhotoStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := photoObject("conteúdo da foto")
+	for i := 0; i < 2; i++ {
+		stored, err := store.Put(context.Background(), obj, strings.NewReader("conteúdo da foto"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Path != services.PhotoKey(obj) || stored.URL != "" {
+			t.Errorf("stored = %+v", stored)
+		}
+	}
+	got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(services.PhotoKey(obj))))
+	if err != nil || string(got) != "conteúdo da foto" {
+		t.Fatalf("arquivo = %q %v", got, err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "9", "2026"))
+	if len(entries) != 1 {
+		t.Errorf("arquivos = %d; want 1", len(entries))
+	}
+
+	rc, err := store.Open(context.Background(), services.PhotoKey(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "conteúdo da foto" {
+		t.Errorf("Open = %q", got)
+	}
+	if _, err := store.Open(context.Background(), "../fora.jpg"); err == nil {
+		t.Error("caminho fora do diretório aceito")
+	}
+}
+
+func TestS3PhotoStore(t *testing.T) {
+	var gotMethod, gotPath, gotAuth, gotSHA string
+	var gotBody []byte
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		gotSHA = r.Header.Get("X-Amz-Content-Sha256")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write([]byte("jpeg"))
+		}
+	}))
+	defer srv.Close()
+
+	store, err := services.NewS3PhotoStore(services.S3Config{
+		Endpoint: srv.URL, Bucket: "fotos", Region: "sa-east-1",
+		AccessKey: "AK", SecretKey: "segredo", Prefix: "censo/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := photoObject("jpeg")
+	stored, err := store.Put(context.Background(), obj, strings.NewReader("jpeg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "censo/" + services.PhotoKey(obj)
+	if gotPath != "/fotos/"+key || stored.Path != key || stored.URL != srv.URL+"/fotos/"+key {
+		t.Errorf("path = %q, stored = %+v", gotPath, stored)
+	}
+	if string(gotBody) != "jpeg" || gotSHA != obj.SHA256 {
+		t.Errorf("corpo = %q, sha = %q", gotBody, gotSHA)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AK/") ||
+		!strings.Contains(gotAuth, "/sa-east-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature=") {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+
+	rc, err := store.Open(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if gotMethod != http.MethodGet || string(got) != "jpeg" ||
+		!strings.Contains(gotAuth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date,") {
+		t.Errorf("GET: %s %q %q", gotMethod, got, gotAuth)
+	}
+
+	status = http.StatusForbidden
+	if _, err := store.Put(context.Background(), obj, strings.NewReader("jpeg")); err == nil {
+		t.Error("403 não virou falha")
+	}
+}
+
+// Vetor de derivação da chave de assinatura V4 da documentação da AWS.
+func TestS3SigningKey(t *testing.T) {
+	key := services.S3SigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	if got := hex.EncodeToString(key); got != "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d" {
+		t.Errorf("chave = %s", got)
+	}
+}
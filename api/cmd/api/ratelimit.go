@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"censo-api/internal/models"
+)
+
+// ─── Rate Limiter ────────────────────────────────────────────────────────────
+//
+// Os limites de taxa contam tentativas por chave (ip:<addr>, school:<id>
+// ou user:<id>) dentro de uma janela deslizante. O armazenamento é plugável:
+// em produção fica no Postgres (rate_limit_hits), compartilhado entre as
+// réplicas e preservado entre deploys; RATE_LIMIT_STORE=memory usa um mapa
+// em processo, útil em desenvolvimento e nos testes.
+
+// rateLimiter é o armazenamento das tentativas.
+type rateLimiter interface {
+	// Allow registra uma tentativa de key no balde se ela couber em max
+	// dentro da janela e informa se coube. Tentativas recusadas não contam.
+	Allow(ctx context.Context, bucket, key string, max int, window time.Duration) (bool, error)
+	// Cleanup descarta as tentativas que já saíram da janela.
+	Cleanup(ctx context.Context) (int64, error)
+}
+
+var _ rateLimiter = (*models.RateLimitModel)(nil)
+
+// rateLimit descreve um limite: balde, máximo de tentativas e janela.
+type rateLimit struct {
+	bucket string
+	max    int
+	window time.Duration
+}
+
+const (
+	maxLoginAttempts = 5
+	rlWindow         = 15 * time.Minute
+
+	// Códigos do segundo fator por conta: o pre-auth token já prova a senha,
+	// e 6 dígitos não podem ser varridos a partir de vários IPs.
+	maxTOTPAttempts = 5
+
+	// Escrita de censo/escola: alto o suficiente para o formulário completo
+	// (11 passos + salvamentos automáticos) repetido por várias escolas.
+	maxCensusWrites = 300
+	censusWindow    = 10 * time.Minute
+
+	// Escrita de censo por escola: o formulário de uma escola inteira cabe
+	// com folga; mais que isso é repetição automatizada.
+	maxSchoolCensusWrites = 120
+
+	// Upload de foto: uma por escola na prática; margem para reenvios.
+	maxUploads       = 40
+	uploadWindow     = 10 * time.Minute
+	maxSchoolUploads = 10
+
+	// Renovação de sessão: cada aba renova a cada ~15 min; margem para
+	// várias abas e contas atrás do mesmo IP.
+	maxRefreshes  = 120
+	refreshWindow = 15 * time.Minute
+
+	// Intervalo da limpeza das tentativas expiradas.
+	rateLimitCleanupInterval = 5 * time.Minute
+)
+
+// Limites dos endpoints. Os de escrita pública são propositalmente generosos
+// por IP para não atrapalhar o preenchimento legítimo (várias escolas atrás
+// do mesmo IP/NAT de uma DRE); o limite por escola corta o abuso que vem de
+// IPs variados contra uma mesma escola.
+var (
+	loginLimit        = rateLimit{bucket: "login", max: maxLoginAttempts, window: rlWindow}
+	totpVerifyLimit   = rateLimit{bucket: "totp_verify", max: maxTOTPAttempts, window: rlWindow}
+	refreshLimit      = rateLimit{bucket: "refresh", max: maxRefreshes, window: refreshWindow}
+	censusWriteLimit  = rateLimit{bucket: "census_write", max: maxCensusWrites, window: censusWindow}
+	schoolWriteLimit  = rateLimit{bucket: "school_census_write", max: maxSchoolCensusWrites, window: censusWindow}
+	uploadLimit       = rateLimit{bucket: "upload", max: maxUploads, window: uploadWindow}
+	schoolUploadLimit = rateLimit{bucket: "school_upload", max: maxSchoolUploads, window: uploadWindow}
+)
+
+// ipKey, schoolKey e userKey montam as chaves dos limites; o prefixo evita
+// colisão entre os tipos no mesmo balde.
+func ipKey(r *http.Request) string { return "ip:" + clientIP(r) }
+
+func schoolKey(schoolID int) string { return "school:" + strconv.Itoa(schoolID) }
+
+func userKey(userID int) string { return "user:" + strconv.Itoa(userID) }
+
+// newRateLimiter escolhe o armazenamento conforme RATE_LIMIT_STORE
+// ("postgres", default, ou "memory").
+func newRateLimiter(store string, m *models.RateLimitModel) rateLimiter {
+	if strings.EqualFold(strings.TrimSpace(store), "memory") {
+		return newMemoryRateLimiter()
+	}
+	return m
+}
+
+// fallbackLimiter atende uma application montada sem limiter (testes).
+var fallbackLimiter = newMemoryRateLimiter()
+
+// allowRate aplica o limite à chave. Falha do armazenamento libera a
+// requisição (e é logada): o limite protege contra abuso, mas não deve
+// derrubar o preenchimento do censo se o banco oscilar.
+func (app *application) allowRate(r *http.Request, l rateLimit, key string) bool {
+	limiter := app.limiter
+	if limiter == nil {
+		limiter = fallbackLimiter
+	}
+	ok, err := limiter.Allow(r.Context(), l.bucket, key, l.max, l.window)
+	if err != nil {
+		app.logger.Printf("rateLimit %s: %v", l.bucket, err)
+		return true
+	}
+	return ok
+}
+
+// rateLimitCleanupJob apaga periodicamente as tentativas expiradas.
+func (app *application) rateLimitCleanupJob() {
+	ticker := time.NewTicker(rateLimitCleanupInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := app.limiter.Cleanup(context.Background()); err != nil {
+			app.logger.Printf("rateLimitCleanup: %v", err)
+		}
+	}
+}
+
+// memoryRateLimiter guarda as tentativas num mapa em processo. Não é
+// compartilhado entre réplicas; Cleanup descarta as chaves ociosas para que
+// o mapa não cresça sem limite.
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]*memoryRateEntry
+}
+
+type memoryRateEntry struct {
+	hits   []time.Time
+	window time.Duration
+}
+
+func newMemoryRateLimiter() *memoryRateLimiter {
+	return &memoryRateLimiter{now: time.Now, entries: make(map[string]*memoryRateEntry)}
+}
+
+func (rl *memoryRateLimiter) Allow(_ context.Context, bucket, key string, max int, window time.Duration) (bool, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	k := bucket + "|" + key
+	e := rl.entries[k]
+	if e == nil {
+		e = &memoryRateEntry{}
+		rl.entries[k] = e
+	}
+	e.window = window
+	e.hits = pruneHits(e.hits, now.Add(-window))
+
+	if len(e.hits) >= max {
+		return false, nil
+	}
+	e.hits = append(e.hits, now)
+	return true, nil
+}
+
+func (rl *memoryRateLimiter) Cleanup(_ context.Context) (int64, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	var removed int64
+	for k, e := range rl.entries {
+		before := len(e.hits)
+		e.hits = pruneHits(e.hits, now.Add(-e.window))
+		removed += int64(before - len(e.hits))
+		if len(e.hits) == 0 {
+			delete(rl.entries, k)
+		}
+	}
+	return removed, nil
+}
+
+// pruneHits descarta as tentativas anteriores ao corte. hits está em ordem
+// cronológica.
+func pruneHits(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
@@ -0,0 +1,129 @@
+package main
+
+// Testes dos limites de taxa. Sem banco: cobrem a implementação em memória
+// (janela deslizante, chaves independentes, limpeza das ociosas), a escolha
+// do armazenamento e o comportamento de allowRate quando o armazenamento
+// falha.
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"censo-api/internal/models"
+)
+
+func newTestMemoryLimiter(now *time.Time) *memoryRateLimiter {
+	rl := newMemoryRateLimiter()
+	rl.now = func() time.Time { return *now }
+	return rl
+}
+
+func TestMemoryRateLimiterSlidingWindow(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	rl := newTestMemoryLimiter(&now)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.Allow(ctx, "login", "ip:1.1.1.1", 3, time.Minute); !ok {
+			t.Fatalf("tentativa %d recusada dentro do limite", i+1)
+		}
+		now = now.Add(10 * time.Second)
+	}
+	if ok, _ := rl.Allow(ctx, "login", "ip:1.1.1.1", 3, time.Minute); ok {
+		t.Fatal("quarta tentativa aceita")
+	}
+
+	// A primeira tentativa (t=0) sai da janela em t=60s; a recusada em
+	// t=30s não conta.
+	now = now.Add(31 * time.Second)
+	if ok, _ := rl.Allow(ctx, "login", "ip:1.1.1.1", 3, time.Minute); !ok {
+		t.Fatal("tentativa recusada depois que a mais antiga saiu da janela")
+	}
+	if ok, _ := rl.Allow(ctx, "login", "ip:1.1.1.1", 3, time.Minute); ok {
+		t.Fatal("janela cheia de novo, mas tentativa aceita")
+	}
+}
+
+func TestMemoryRateLimiterKeysAreIndependent(t *testing.T) {
+	now := time.Now()
+	rl := newTestMemoryLimiter(&now)
+	ctx := context.Background()
+
+	if ok, _ := rl.Allow(ctx, "upload", schoolKey(7), 1, time.Minute); !ok {
+		t.Fatal("primeira tentativa recusada")
+	}
+	if ok, _ := rl.Allow(ctx, "upload", schoolKey(8), 1, time.Minute); !ok {
+		t.Error("escola diferente bloqueada")
+	}
+	if ok, _ := rl.Allow(ctx, "census_write", schoolKey(7), 1, time.Minute); !ok {
+		t.Error("balde diferente bloqueado")
+	}
+	if ok, _ := rl.Allow(ctx, "upload", schoolKey(7), 1, time.Minute); ok {
+		t.Error("mesma escola e balde aceitos acima do limite")
+	}
+}
+
+func TestMemoryRateLimiterCleanup(t *testing.T) {
+	now := time.Now()
+	rl := newTestMemoryLimiter(&now)
+	ctx := context.Background()
+
+	rl.Allow(ctx, "login", "ip:a", 5, time.Minute)
+	rl.Allow(ctx, "login", "ip:a", 5, time.Minute)
+	rl.Allow(ctx, "upload", "ip:b", 5, time.Hour)
+
+	now = now.Add(2 * time.Minute)
+	removed, err := rl.Cleanup(ctx)
+	if err != nil || removed != 2 {
+		t.Fatalf("Cleanup = %d, %v; want 2", removed, err)
+	}
+	if len(rl.entries) != 1 {
+		t.Fatalf("%d chave(s) após limpeza; want 1 (a de janela longa)", len(rl.entries))
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	pg := &models.RateLimitModel{}
+	if _, ok := newRateLimiter(" Memory ", pg).(*memoryRateLimiter); !ok {
+		t.Error("RATE_LIMIT_STORE=memory não usa o mapa em processo")
+	}
+	for _, store := range []string{"", "postgres"} {
+		if got := newRateLimiter(store, pg); got != rateLimiter(pg) {
+			t.Errorf("RATE_LIMIT_STORE=%q não usa o Postgres", store)
+		}
+	}
+}
+
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Allow(context.Context, string, string, int, time.Duration) (bool, error) {
+	return false, errors.New("banco indisponível")
+}
+
+func (failingRateLimiter) Cleanup(context.Context) (int64, error) { return 0, nil }
+
+func TestAllowRate(t *testing.T) {
+	t.Setenv("TRUSTED_PROXY_COUNT", "0")
+	req := httptest.NewRequest(http.MethodPost, "/v1/census", nil)
+	req.RemoteAddr = "10.0.0.9:1234"
+	if got := ipKey(req); got != "ip:10.0.0.9" {
+		t.Errorf("ipKey = %q", got)
+	}
+
+	app := &application{logger: log.New(io.Discard, "", 0), limiter: failingRateLimiter{}}
+	if !app.allowRate(req, loginLimit, ipKey(req)) {
+		t.Error("falha do armazenamento bloqueou a requisição")
+	}
+
+	app.limiter = newMemoryRateLimiter()
+	limit := rateLimit{bucket: "t", max: 1, window: time.Minute}
+	if !app.allowRate(req, limit, ipKey(req)) || app.allowRate(req, limit, ipKey(req)) {
+		t.Error("limite de 1 tentativa não aplicado")
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"censo-api/internal/models"
+)
+
+// =====================================================================
+// Municípios e DREs de referência
+// =====================================================================
+// municipios (códigos IBGE) e dres são as tabelas canônicas; o texto livre
+// de schools.municipio/dre é casado por chave normalizada (censo_chave, na
+// migration 0034) e o trigger schools_resolve_referencias grava
+// municipio_id e dre_id em toda escrita. POST /v1/schools devolve os ids
+// resolvidos e avisa (warnings, code "sem_correspondencia") quando o texto
+// não casou — a escola é gravada mesmo assim.
+//
+//   - GET /v1/admin/reference/unmatched   textos sem correspondência em
+//     schools e locations, com a sugestão mais próxima
+// =====================================================================
+
+const issueUnmatched = "sem_correspondencia"
+
+var (
+	referenceApostrophes = regexp.MustCompile("['’´`]")
+	referenceSeparators  = regexp.MustCompile(`[^a-z0-9]+`)
+	referenceDREPrefix   = regexp.MustCompile(`^(dre|ure) `)
+)
+
+// referenceKey espelha censo_chave: minúsculas, sem acento e apóstrofo,
+// demais sinais como espaço.
+func referenceKey(s string) string {
+	s = referenceApostrophes.ReplaceAllString(normalizeSaudeSearch(s), "")
+	return strings.TrimSpace(referenceSeparators.ReplaceAllString(s, " "))
+}
+
+// referenceDREKey espelha censo_chave_dre: referenceKey sem o prefixo
+// "DRE"/"URE".
+func referenceDREKey(s string) string {
+	return referenceDREPrefix.ReplaceAllString(referenceKey(s), "")
+}
+
+// referenceOption é um candidato canônico para sugestão.
+type referenceOption struct {
+	ID   int    `json:"id"`
+	Nome string `json:"nome"`
+	key  string
+}
+
+// unmatchedReference é um texto sem correspondência com a sugestão mais
+// próxima, quando há uma razoável.
+type unmatchedReference struct {
+	models.UnmatchedValue
+	Suggestion *referenceOption `json:"suggestion,omitempty"`
+}
+
+// suggestReference escolhe a opção de chave mais próxima de key (distância
+// de edição até um quinto do tamanho, mínimo 2). Empate não sugere nada.
+func suggestReference(key string, options []referenceOption) *referenceOption {
+	limit := len(key) / 5
+	if limit < 2 {
+		limit = 2
+	}
+	var best *referenceOption
+	bestDist, tie := limit+1, false
+	for i := range options {
+		d := editDistance(key, options[i].key)
+		switch {
+		case d < bestDist:
+			best, bestDist, tie = &options[i], d, false
+		case d == bestDist:
+			tie = true
+		}
+	}
+	if best == nil || tie {
+		return nil
+	}
+	return best
+}
+
+// editDistance é a distância de Levenshtein entre duas chaves (ASCII).
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func withSuggestions(values []models.UnmatchedValue, options []referenceOption, key func(string) string) []unmatchedReference {
+	out := make([]unmatchedReference, len(values))
+	for i, v := range values {
+		out[i] = unmatchedReference{UnmatchedValue: v, Suggestion: suggestReference(key(v.Value), options)}
+	}
+	return out
+}
+
+// unmatchedReferences monta a resposta de GET /v1/admin/reference/unmatched.
+func (app *application) unmatchedReferences(ctx context.Context) (map[string][]unmatchedReference, error) {
+	municipios, dres, err := app.models.References.Unmatched(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mus, err := app.models.References.Municipios(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := app.models.References.Dres(ctx)
+	if err != nil {
+		return nil, err
+	}
+	munOptions := make([]referenceOption, len(mus))
+	for i, m := range mus {
+		munOptions[i] = referenceOption{ID: m.ID, Nome: m.Nome, key: m.Chave}
+	}
+	dreOptions := make([]referenceOption, len(ds))
+	for i, d := range ds {
+		dreOptions[i] = referenceOption{ID: d.ID, Nome: d.Nome, key: d.Chave}
+	}
+	return map[string][]unmatchedReference{
+		"municipios": withSuggestions(municipios, munOptions, referenceKey),
+		"dres":       withSuggestions(dres, dreOptions, referenceDREKey),
+	}, nil
+}
+
+// AdminListUnmatchedReferences lista os municípios e DREs em texto livre
+// (schools e locations) sem correspondência nas tabelas de referência.
+func (app *application) AdminListUnmatchedReferences(w http.ResponseWriter, r *http.Request) {
+	out, err := app.unmatchedReferences(r.Context())
+	if err != nil {
+		app.logger.Printf("AdminListUnmatchedReferences: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar municípios e DREs sem correspondência"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: out})
+}
+
+// schoolReferenceWarnings avisa quando município ou DRE informados não
+// casaram com as tabelas de referência.
+func schoolReferenceWarnings(s models.School) []censusFieldIssue {
+	var out []censusFieldIssue
+	if s.MunicipioID == nil && strings.TrimSpace(s.Municipio) != "" {
+		out = append(out, censusFieldIssue{Field: "municipio", Code: issueUnmatched,
+			Message: fmt.Sprintf("município %q não encontrado na tabela de referência", s.Municipio)})
+	}
+	if s.DreID == nil && strings.TrimSpace(s.Dre) != "" {
+		out = append(out, censusFieldIssue{Field: "dre", Code: issueUnmatched,
+			Message: fmt.Sprintf("DRE %q não encontrada na tabela de referência", s.Dre)})
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package main
+
+// Testes das referências de município e DRE. Sem banco: cobrem a chave
+// normalizada (espelho de censo_chave), a sugestão do valor canônico mais
+// próximo e os avisos de POST /v1/schools para texto sem correspondência.
+
+import (
+	"testing"
+
+	"censo-api/internal/models"
+)
+
+func TestReferenceKey(t *testing.T) {
+	for _, group := range [][]string{
+		{"IGARAPE MIRI", "IGARAPE-MIRI", "Igarapé-Miri", " igarapé  miri "},
+		{"PAU DARCO", "Pau D'Arco", "PAU D´ARCO"},
+		{"São Félix do Xingu", "SAO FELIX DO XINGU"},
+	} {
+		want := referenceKey(group[0])
+		for _, v := range group[1:] {
+			if got := referenceKey(v); got != want {
+				t.Errorf("referenceKey(%q) = %q; want %q", v, got, want)
+			}
+		}
+	}
+	if got := referenceKey("Tomé-Açu"); got != "tome acu" {
+		t.Errorf("referenceKey = %q", got)
+	}
+	if got := referenceDREKey("DRE Belém 1"); got != "belem 1" {
+		t.Errorf("referenceDREKey = %q", got)
+	}
+	if got := referenceDREKey("URE-Marabá"); got != "maraba" {
+		t.Errorf("referenceDREKey = %q", got)
+	}
+}
+
+func TestSuggestReference(t *testing.T) {
+	options := []referenceOption{
+		{ID: 1, Nome: "Santa Izabel do Pará", key: "santa izabel do para"},
+		{ID: 2, Nome: "Santa Maria do Pará", key: "santa maria do para"},
+		{ID: 3, Nome: "Belém 1", key: "belem 1"},
+		{ID: 4, Nome: "Belém 2", key: "belem 2"},
+	}
+	if s := suggestReference(referenceKey("STA IZABEL DO PARA"), options); s == nil || s.ID != 1 {
+		t.Errorf("sugestão = %+v", s)
+	}
+	if s := suggestReference(referenceKey("Belém"), options); s != nil {
+		t.Errorf("empate sugeriu %+v", s)
+	}
+	if s := suggestReference(referenceKey("Oriximiná"), options); s != nil {
+		t.Errorf("nome distante sugeriu %+v", s)
+	}
+	if d := editDistance("kitten", "sitting"); d != 3 {
+		t.Errorf("editDistance = %d", d)
+	}
+}
+
+func TestSchoolReferenceWarnings(t *testing.T) {
+	id := 10
+	s := models.School{Municipio: "Belém", Dre: "DRE Belém 9", MunicipioID: &id}
+	got := schoolReferenceWarnings(s)
+	if len(got) != 1 || got[0].Field != "dre" || got[0].Code != issueUnmatched {
+		t.Errorf("avisos = %+v", got)
+	}
+	s.DreID = &id
+	if got := schoolReferenceWarnings(s); len(got) != 0 {
+		t.Errorf("avisos = %+v", got)
+	}
+	if got := schoolReferenceWarnings(models.School{}); len(got) != 0 {
+		t.Errorf("texto vazio gerou aviso: %+v", got)
+	}
+}
@@ -219,6 +219,9 @@ func normalizeReportFormat(raw string) string {
 //	draft                                          -> Rascunho
 //	completed + sheet_synced_at IS NULL            -> Pendente de Sincronização
 //	completed + sheet_synced_at IS NOT NULL        -> Concluído
+//	em_revisao                                     -> Em Revisão
+//	devolvido                                      -> Devolvido
+//	aprovado                                       -> Aprovado
 //	qualquer outro                                 -> Verificar
 func censoStatusLabel(status string, hasCensus, synced bool) string {
 	if !hasCensus {
@@ -232,6 +235,12 @@ func censoStatusLabel(status string, hasCensus, synced bool) string {
 			return "Concluído"
 		}
 		return "Pendente de Sincronização"
+	case "em_revisao":
+		return "Em Revisão"
+	case "devolvido":
+		return "Devolvido"
+	case "aprovado":
+		return "Aprovado"
 	default:
 		return "Verificar"
 	}
@@ -250,6 +259,12 @@ func situacaoOperacional(statusLabel string) string {
 		return "Concluído, aguardando sincronização"
 	case "Concluído":
 		return "Concluído e sincronizado"
+	case "Em Revisão":
+		return "Concluído, em revisão pela DRE"
+	case "Devolvido":
+		return "Devolvido à escola para correção"
+	case "Aprovado":
+		return "Aprovado pela DRE"
 	default:
 		return "Verificar manualmente"
 	}
@@ -307,26 +322,31 @@ func (app *application) AdminGetReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	app.sendReportXLSX(w, "AdminGetReport "+def.ID, rd, buildReportFileName(def.FileBase, filters))
+}
+
+// sendReportXLSX gera o XLSX de rd e o devolve como anexo com o nome
+// informado. logTag identifica o chamador nos logs de erro.
+func (app *application) sendReportXLSX(w http.ResponseWriter, logTag string, rd reportData, filename string) {
 	f, err := writeReportXLSX(rd)
 	if err != nil {
-		app.logger.Printf("AdminGetReport %s: gerar xlsx: %v", def.ID, err)
+		app.logger.Printf("%s: gerar xlsx: %v", logTag, err)
 		app.errorJSON(w, fmt.Errorf("erro ao gerar arquivo"), http.StatusInternalServerError)
 		return
 	}
 
 	buf, err := f.WriteToBuffer()
 	if err != nil {
-		app.logger.Printf("AdminGetReport %s: serializar xlsx: %v", def.ID, err)
+		app.logger.Printf("%s: serializar xlsx: %v", logTag, err)
 		app.errorJSON(w, fmt.Errorf("erro ao gerar arquivo"), http.StatusInternalServerError)
 		return
 	}
 
-	filename := buildReportFileName(def.FileBase, filters)
 	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write(buf.Bytes()); err != nil {
-		app.logger.Printf("AdminGetReport %s: escrever resposta: %v", def.ID, err)
+		app.logger.Printf("%s: escrever resposta: %v", logTag, err)
 	}
 }
 
@@ -351,8 +371,8 @@ var censoPreenchimentoReportColumns = []string{
 // censoPreenchimentoSelectSQL parte de schools s com LEFT JOIN na resposta
 // de censo mais recente (CTE latest_census) para que escolas sem resposta
 // permaneçam no recorte e apareçam como pendentes. A Região de Integração
-// vem de um LEFT JOIN em reg_integracao (mesma grafia por município usada
-// nos demais endpoints). NÃO reutiliza censusListSelectSQL/AnalyticsFilters,
+// vem de um LEFT JOIN em municipios pelo schools.municipio_id, como nos
+// demais endpoints. NÃO reutiliza censusListSelectSQL/AnalyticsFilters,
 // que fazem JOIN obrigatório com census_responses e excluiriam pendentes.
 //
 // Ano: $1 = 0 considera todas as respostas (pega a mais recente por escola);
@@ -365,12 +385,12 @@ const censoPreenchimentoSelectSQL = `
 	WITH latest_census AS (
 		SELECT DISTINCT ON (school_id)
 			school_id, status, year, updated_at, sheet_synced_at
-		FROM census_responses
+		FROM vw_censo_fonte
 		WHERE ($1 = 0 OR year = $1)
 		ORDER BY school_id, updated_at DESC, id DESC
 	)
 	SELECT
-		COALESCE(ri.regiao_de_integracao, '') AS regiao_integracao,
+		COALESCE(ri.regiao_integracao, '') AS regiao_integracao,
 		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado') AS dre,
 		COALESCE(NULLIF(TRIM(s.municipio), ''), 'Não informado') AS municipio,
 		COALESCE(NULLIF(TRIM(s.zona), ''), '') AS zona,
@@ -381,23 +401,23 @@ const censoPreenchimentoSelectSQL = `
 		cr.updated_at,
 		(cr.sheet_synced_at IS NOT NULL) AS synced,
 		cr.sheet_synced_at
-	FROM schools s
+	FROM vw_censo_escolas s
 	LEFT JOIN latest_census cr ON cr.school_id = s.id
-	LEFT JOIN reg_integracao ri ON UPPER(TRIM(ri.municipio)) = UPPER(TRIM(s.municipio))
+	LEFT JOIN municipios ri ON ri.id = s.municipio_id
 	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	ORDER BY
 		CASE
 			WHEN cr.status IS NULL THEN 1
-			WHEN cr.status = 'draft' THEN 2
-			WHEN cr.status = 'completed' AND cr.sheet_synced_at IS NULL THEN 3
-			WHEN cr.status = 'completed' AND cr.sheet_synced_at IS NOT NULL THEN 4
+			WHEN cr.status IN ('draft', 'devolvido') THEN 2
+			WHEN censo_enviado(cr.status) AND cr.sheet_synced_at IS NULL THEN 3
+			WHEN censo_enviado(cr.status) AND cr.sheet_synced_at IS NOT NULL THEN 4
 			ELSE 5
 		END,
 		UPPER(TRIM(s.dre)),
@@ -413,7 +433,7 @@ const reportDateLayout = "02/01/2006 15:04"
 // e monta o reportData (sem paginar). Datas chegam formatadas em pt-BR; o
 // ano vem como inteiro quando há resposta, ou célula vazia quando pendente.
 func (app *application) buildCensoPreenchimentoReportData(ctx context.Context, def ReportDefinition, f reportFilters) (reportData, error) {
-	rows, err := app.models.Schools.DB.QueryContext(ctx, censoPreenchimentoSelectSQL, f.args()...)
+	rows, err := app.analyticsDB(ctx).QueryContext(ctx, censoPreenchimentoSelectSQL, f.args()...)
 	if err != nil {
 		return reportData{}, fmt.Errorf("consultar preenchimento: %w", err)
 	}
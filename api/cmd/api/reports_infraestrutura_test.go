@@ -227,16 +227,16 @@ func TestWriteInfraestruturaXLSX(t *testing.T) {
 	}
 }
 
-// TestInfraestruturaSelectSQLShape valida que a consulta parte de schools s com
+// TestInfraestruturaSelectSQLShape valida que a consulta parte de vw_censo_escolas s com
 // LEFT JOIN no censo concluído do ano (mantendo escolas sem dados) e aplica os
-// filtros globais sobre schools s.
+// filtros globais sobre vw_censo_escolas s.
 func TestInfraestruturaSelectSQLShape(t *testing.T) {
 	q := infraestruturaSelectSQL
 	mustContain := []string{
-		"FROM schools s",
-		"LEFT JOIN census_responses cr",
-		"cr.year = $1 AND cr.status = 'completed'",
-		"LEFT JOIN reg_integracao ri",
+		"FROM vw_censo_escolas s",
+		"LEFT JOIN vw_censo_fonte cr",
+		"cr.year = $1 AND censo_enviado(cr.status)",
+		"LEFT JOIN municipios ri ON ri.id = s.municipio_id",
 		"(cr.id IS NOT NULL) AS has_censo",
 		"cr.data->>'situacao_estrutura'",
 		"cr.data->>'plano_evacuacao'",
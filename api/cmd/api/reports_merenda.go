@@ -14,8 +14,8 @@ import (
 // Operacional simples para priorização. Escolas sem censo concluído no
 // ano permanecem no recorte e aparecem como "Sem dados".
 //
-// Fonte: schools s LEFT JOIN census_responses cr (cr.year = $1 AND
-// cr.status = 'completed') + LEFT JOIN reg_integracao. Os filtros globais
+// Fonte: schools s LEFT JOIN vw_censo_fonte cr (cr.year = $1 AND
+// censo_enviado(cr.status)) + LEFT JOIN municipios. Os filtros globais
 // incidem sobre schools s. Campos do JSONB são lidos com NULLIF/cast
 // seguro, conforme a convenção do projeto.
 //
@@ -162,14 +162,14 @@ func merendaSemRefrigeracao(r merendaReportRow) bool {
 }
 
 // merendaSelectSQL parte de schools s, com LEFT JOIN na resposta do ano
-// (status='completed') para manter escolas sem censo no recorte, e LEFT JOIN em
-// reg_integracao para a Região de Integração. Filtros globais incidem sobre
+// (censo_enviado(status)) para manter escolas sem censo no recorte, e LEFT JOIN em
+// municipios (schools.municipio_id) para a Região de Integração. Filtros globais incidem sobre
 // schools s. Não pagina; a ordenação final por prioridade operacional é em Go.
 //
 // $1=year (sempre específico), $2=dre, $3=municipio, $4=zona, $5=regiao.
 const merendaSelectSQL = `
 	SELECT
-		COALESCE(ri.regiao_de_integracao, '') AS regiao_integracao,
+		COALESCE(ri.regiao_integracao, '') AS regiao_integracao,
 		COALESCE(NULLIF(TRIM(s.dre), ''), 'Não informado') AS dre,
 		COALESCE(NULLIF(TRIM(s.municipio), ''), 'Não informado') AS municipio,
 		COALESCE(NULLIF(TRIM(s.zona), ''), '') AS zona,
@@ -195,17 +195,17 @@ const merendaSelectSQL = `
 		COALESCE(NULLIF(cr.data->>'deposito_conserva', ''), '')    AS deposito_conserva,
 		COALESCE(NULLIF(cr.data->>'estoque_epi_extintor', ''), '')  AS estoque_epi_extintor,
 		COALESCE(NULLIF(cr.data->>'manutencao_extintores', ''), '') AS manutencao_extintores
-	FROM schools s
-	LEFT JOIN census_responses cr
-		ON cr.school_id = s.id AND cr.year = $1 AND cr.status = 'completed'
-	LEFT JOIN reg_integracao ri ON UPPER(TRIM(ri.municipio)) = UPPER(TRIM(s.municipio))
+	FROM vw_censo_escolas s
+	LEFT JOIN vw_censo_fonte cr
+		ON cr.school_id = s.id AND cr.year = $1 AND censo_enviado(cr.status)
+	LEFT JOIN municipios ri ON ri.id = s.municipio_id
 	WHERE ($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))
 	  AND ($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))
 	  AND ($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))
-	  AND ($5 = '' OR UPPER(TRIM(s.municipio)) IN (
-	        SELECT UPPER(TRIM(municipio))
-	        FROM reg_integracao
-	        WHERE UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))
+	  AND ($5 = '' OR s.municipio_id IN (
+	        SELECT id
+	        FROM municipios
+	        WHERE censo_chave(regiao_integracao) = censo_chave($5)
 	      ))
 	ORDER BY
 		UPPER(TRIM(s.dre)),
@@ -218,7 +218,7 @@ const merendaSelectSQL = `
 // cada escola, ordena por prioridade operacional e projeta as colunas do XLSX.
 // Não pagina.
 func (app *application) buildMerendaReportData(ctx context.Context, def ReportDefinition, f reportFilters) (reportData, error) {
-	dbRows, err := app.models.Schools.DB.QueryContext(ctx, merendaSelectSQL, f.args()...)
+	dbRows, err := app.analyticsDB(ctx).QueryContext(ctx, merendaSelectSQL, f.args()...)
 	if err != nil {
 		return reportData{}, fmt.Errorf("consultar merenda: %w", err)
 	}
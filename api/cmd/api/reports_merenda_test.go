@@ -168,10 +168,10 @@ func TestWriteMerendaXLSX(t *testing.T) {
 func TestMerendaSelectSQLShape(t *testing.T) {
 	q := merendaSelectSQL
 	mustContain := []string{
-		"FROM schools s",
-		"LEFT JOIN census_responses cr",
-		"cr.year = $1 AND cr.status = 'completed'",
-		"LEFT JOIN reg_integracao ri",
+		"FROM vw_censo_escolas s",
+		"LEFT JOIN vw_censo_fonte cr",
+		"cr.year = $1 AND censo_enviado(cr.status)",
+		"LEFT JOIN municipios ri ON ri.id = s.municipio_id",
 		"(cr.id IS NOT NULL) AS has_censo",
 		"cr.data->>'oferta_regular'",
 		"cr.data->>'manutencao_extintores'",
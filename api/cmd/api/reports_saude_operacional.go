@@ -77,19 +77,20 @@ func saudeOperacionalStatusLabel(status string) string {
 	}
 }
 
-// reportRegiaoSQL carrega o mapa município → Região de Integração a partir de
-// reg_integracao. A chave usa UPPER(TRIM(municipio)) para casar com schools.
-// municipio do mesmo jeito que os demais endpoints (sem unaccent nesta etapa).
+// reportRegiaoSQL carrega o mapa município → Região de Integração a partir do
+// município de referência de cada escola (schools.municipio_id). A chave é o
+// texto da escola em UPPER(TRIM()), o mesmo que a Saúde Operacional devolve.
 const reportRegiaoSQL = `
-	SELECT UPPER(TRIM(municipio)) AS municipio, COALESCE(regiao_de_integracao, '') AS regiao
-	FROM reg_integracao
+	SELECT DISTINCT UPPER(TRIM(s.municipio)) AS municipio, COALESCE(m.regiao_integracao, '') AS regiao
+	FROM vw_censo_escolas s
+	JOIN municipios m ON m.id = s.municipio_id
 `
 
 // loadRegiaoPorMunicipio devolve o mapa município (normalizado) → Região de
 // Integração, usado para preencher a coluna territorial do relatório sem alterar
 // o payload da Saúde Operacional (que não expõe a região).
 func (app *application) loadRegiaoPorMunicipio(ctx context.Context) (map[string]string, error) {
-	rows, err := app.models.Schools.DB.QueryContext(ctx, reportRegiaoSQL)
+	rows, err := app.analyticsDB(ctx).QueryContext(ctx, reportRegiaoSQL)
 	if err != nil {
 		return nil, fmt.Errorf("consultar regiões de integração: %w", err)
 	}
@@ -113,6 +113,9 @@ func TestCensoStatusLabel(t *testing.T) {
 		{"rascunho", "draft", true, false, "Rascunho"},
 		{"completed nao sincronizado", "completed", true, false, "Pendente de Sincronização"},
 		{"completed sincronizado", "completed", true, true, "Concluído"},
+		{"em revisão", "em_revisao", true, true, "Em Revisão"},
+		{"devolvido", "devolvido", true, false, "Devolvido"},
+		{"aprovado", "aprovado", true, true, "Aprovado"},
 		{"status inesperado", "qualquer", true, false, "Verificar"},
 	}
 	for _, tt := range tests {
@@ -128,7 +131,8 @@ func TestCensoStatusLabel(t *testing.T) {
 // TestSituacaoOperacional garante que cada status gerencial mapeia para uma
 // frase operacional distinta.
 func TestSituacaoOperacional(t *testing.T) {
-	labels := []string{"Pendente", "Rascunho", "Pendente de Sincronização", "Concluído", "Verificar"}
+	labels := []string{"Pendente", "Rascunho", "Pendente de Sincronização", "Concluído",
+		"Em Revisão", "Devolvido", "Aprovado", "Verificar"}
 	seen := map[string]bool{}
 	for _, l := range labels {
 		s := situacaoOperacional(l)
@@ -253,25 +257,25 @@ func TestWriteReportXLSXNonEmpty(t *testing.T) {
 }
 
 // TestCensoPreenchimentoQueryShape valida o formato da consulta do piloto:
-// parte de schools s, usa LEFT JOIN (inclui escolas pendentes), filtro de
+// parte de vw_censo_escolas s, usa LEFT JOIN (inclui escolas pendentes), filtro de
 // ano opcional ($1 = 0 OR year = $1), filtros globais por AND e ordenação
 // por prioridade gerencial. Não pode exigir status='completed' no WHERE.
 func TestCensoPreenchimentoQueryShape(t *testing.T) {
 	q := censoPreenchimentoSelectSQL
 
 	mustContain := []string{
-		"FROM schools s",
+		"FROM vw_censo_escolas s",
 		"LEFT JOIN latest_census cr",
-		"LEFT JOIN reg_integracao ri",
+		"LEFT JOIN municipios ri ON ri.id = s.municipio_id",
 		"DISTINCT ON (school_id)",
 		"($1 = 0 OR year = $1)",
 		"($2 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($2)))",
 		"($3 = '' OR UPPER(TRIM(s.municipio)) = UPPER(TRIM($3)))",
 		"($4 = '' OR UPPER(TRIM(s.zona)) = UPPER(TRIM($4)))",
-		"FROM reg_integracao",
-		"UPPER(TRIM(regiao_de_integracao)) = UPPER(TRIM($5))",
+		"s.municipio_id IN (",
+		"censo_chave(regiao_integracao) = censo_chave($5)",
 		"WHEN cr.status IS NULL THEN 1",
-		"WHEN cr.status = 'draft' THEN 2",
+		"WHEN cr.status IN ('draft', 'devolvido') THEN 2",
 	}
 	for _, frag := range mustContain {
 		if !strings.Contains(q, frag) {
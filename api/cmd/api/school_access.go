@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"censo-api/internal/models"
+)
+
+// =====================================================================
+// Códigos de acesso por escola do formulário público
+// =====================================================================
+// Cada escola recebe um código (XXXX-XXXX-XXXX) ou o link mágico que o
+// embute (FORM_PUBLIC_URL?acesso=CODIGO), emitido pelo painel e repassado
+// ao diretor. O formulário envia o código no header X-School-Access, e as
+// escritas públicas só aceitam a escola vinculada a ele:
+//   - POST /v1/census e POST /v1/upload: school_id do corpo;
+//   - POST /v1/schools: a escola já cadastrada com o codigo_inep enviado.
+//     INEP ainda não cadastrado, sem código, cria a escola e devolve em
+//     access_code o código emitido para ela, que o formulário guarda.
+//
+// O código é obrigatório: ausente = 401, inválido/revogado = 401, de outra
+// escola = 403. SCHOOL_ACCESS_REQUIRED=false desliga a exigência durante a
+// distribuição dos códigos — um código ausente volta a ser aceito, mas um
+// código presente continua sendo verificado.
+//
+// Gestão (seduc_admin e dre_gestor, recorte forçado por enforceDREScope):
+//   - GET  /v1/admin/school-access?dre=          situação por escola;
+//   - POST /v1/admin/school-access/issue?dre=    emite para escolas sem código;
+//   - POST /v1/admin/school-access/rotate?dre=   revoga e reemite;
+//   - POST /v1/admin/school-access/revoke?dre=   revoga.
+// As três operações em lote aceitam {"school_ids": [...]} no corpo para
+// restringir o lote; sem dre nem school_ids são recusadas, para evitar uma
+// operação acidental no estado inteiro. issue/rotate devolvem os códigos em
+// claro uma única vez (format=xlsx gera a planilha de distribuição).
+// =====================================================================
+
+// headerSchoolAccess é o header que carrega o código de acesso da escola.
+const headerSchoolAccess = "X-School-Access"
+
+// schoolAccessAlphabet exclui caracteres ambíguos (0/O, 1/I). 32 símbolos:
+// cada byte aleatório mapeia sem viés (256 % 32 == 0).
+const schoolAccessAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const (
+	schoolAccessCodeLen   = 12 // 60 bits
+	schoolAccessGroupSize = 4
+)
+
+var (
+	errSchoolAccessRequired = fmt.Errorf("código de acesso da escola necessário")
+	errSchoolAccessInvalid  = fmt.Errorf("código de acesso inválido ou revogado")
+	errSchoolAccessMismatch = fmt.Errorf("código de acesso não corresponde a esta escola")
+	// errSchoolAccessNotIssued: escola nova cadastrada sem o código, que a
+	// DRE emite pelo painel.
+	errSchoolAccessNotIssued = fmt.Errorf("escola cadastrada, mas o código de acesso não foi emitido; procure a DRE")
+)
+
+// schoolAccessRequired informa se o código é obrigatório: sempre, exceto
+// com SCHOOL_ACCESS_REQUIRED=false (ou 0) explícito.
+func schoolAccessRequired() bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("SCHOOL_ACCESS_REQUIRED")))
+	return v != "0" && v != "false"
+}
+
+// generateSchoolAccessCode sorteia um código no formato XXXX-XXXX-XXXX.
+func generateSchoolAccessCode() (string, error) {
+	b := make([]byte, schoolAccessCodeLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("gerar código de acesso: %w", err)
+	}
+	var sb strings.Builder
+	for i, v := range b {
+		if i > 0 && i%schoolAccessGroupSize == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(schoolAccessAlphabet[int(v)%len(schoolAccessAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+// normalizeSchoolAccessCode tolera a digitação do diretor: caixa, hífens e
+// espaços não importam.
+func normalizeSchoolAccessCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t':
+			return -1
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, strings.TrimSpace(code))
+}
+
+// hashSchoolAccessCode é o único formato em que o código é persistido.
+func hashSchoolAccessCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeSchoolAccessCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// schoolAccessHint são os 4 últimos caracteres, exibidos no painel para
+// conferência com o diretor.
+func schoolAccessHint(code string) string {
+	n := normalizeSchoolAccessCode(code)
+	if len(n) <= 4 {
+		return n
+	}
+	return n[len(n)-4:]
+}
+
+// schoolAccessLink monta o link mágico quando FORM_PUBLIC_URL está
+// definido; sem ele, devolve "".
+func schoolAccessLink(code string) string {
+	base := strings.TrimRight(strings.TrimSpace(os.Getenv("FORM_PUBLIC_URL")), "/")
+	if base == "" {
+		return ""
+	}
+	return base + "/?acesso=" + url.QueryEscape(code)
+}
+
+// schoolAccessFromRequest resolve a escola vinculada ao código enviado.
+// Devolve 0 sem erro quando o código está ausente e não é obrigatório.
+func (app *application) schoolAccessFromRequest(r *http.Request) (int, int, error) {
+	code := strings.TrimSpace(r.Header.Get(headerSchoolAccess))
+	if code == "" {
+		if schoolAccessRequired() {
+			return 0, http.StatusUnauthorized, errSchoolAccessRequired
+		}
+		return 0, 0, nil
+	}
+	id, err := app.models.SchoolAccess.SchoolIDForCode(hashSchoolAccessCode(code))
+	if err != nil {
+		app.logger.Printf("schoolAccess: verificar código: %v", err)
+		return 0, http.StatusInternalServerError, fmt.Errorf("erro ao verificar código de acesso")
+	}
+	if id == 0 {
+		return 0, http.StatusUnauthorized, errSchoolAccessInvalid
+	}
+	return id, 0, nil
+}
+
+// authorizeSchoolWrite verifica se a requisição pode escrever dados da
+// escola schoolID (0 = escola ainda não cadastrada). Em caso negativo já
+// responde com o erro e devolve false.
+func (app *application) authorizeSchoolWrite(w http.ResponseWriter, r *http.Request, schoolID int) bool {
+	bound, status, err := app.schoolAccessFromRequest(r)
+	if err != nil {
+		app.errorJSON(w, err, status)
+		return false
+	}
+	if bound != 0 && bound != schoolID {
+		app.errorJSON(w, errSchoolAccessMismatch, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// createdSchool é a resposta de POST /v1/schools para escola nova: o
+// cadastro mais o código emitido, exibido só nesta resposta.
+type createdSchool struct {
+	models.School
+	AccessCode string `json:"access_code"`
+	AccessLink string `json:"access_link,omitempty"`
+}
+
+// createSchoolWithAccess cadastra a escola de INEP novo e emite o código
+// de acesso dela. O INEP cadastrado por outra requisição no meio do
+// caminho volta como models.ErrSchoolINEPInUse, sem escrita.
+func (app *application) createSchoolWithAccess(ctx context.Context, school models.School) (int, *issuedSchoolAccess, error) {
+	code, err := generateSchoolAccessCode()
+	if err != nil {
+		return 0, nil, err
+	}
+	id, err := app.models.Schools.Create(school)
+	if err != nil {
+		return 0, nil, err
+	}
+	issue := models.SchoolAccessIssue{SchoolID: id, Hash: hashSchoolAccessCode(code), Hint: schoolAccessHint(code)}
+	if err := app.models.SchoolAccess.IssueBatch(ctx, []models.SchoolAccessIssue{issue}, 0); err != nil {
+		app.logger.Printf("CreateSchool: emitir código da escola %d: %v", id, err)
+		return 0, nil, errSchoolAccessNotIssued
+	}
+	return id, &issuedSchoolAccess{
+		SchoolID: id, Nome: school.Nome, INEP: school.INEP, Municipio: school.Municipio, Dre: school.Dre,
+		Code: code, Link: schoolAccessLink(code),
+	}, nil
+}
+
+// schoolAccessBatchRequest é o corpo opcional das operações em lote.
+type schoolAccessBatchRequest struct {
+	SchoolIDs []int64 `json:"school_ids"`
+}
+
+// readSchoolAccessBatch lê o corpo (opcional) e o dre da query e recusa
+// lotes sem recorte.
+func (app *application) readSchoolAccessBatch(w http.ResponseWriter, r *http.Request) (string, []int64, error) {
+	var req schoolAccessBatchRequest
+	if err := app.readJSON(w, r, &req); err != nil && !errors.Is(err, io.EOF) {
+		return "", nil, fmt.Errorf("dados inválidos")
+	}
+	dre := strings.TrimSpace(r.URL.Query().Get("dre"))
+	if dre == "" && len(req.SchoolIDs) == 0 {
+		return "", nil, fmt.Errorf("informe dre ou school_ids")
+	}
+	return dre, req.SchoolIDs, nil
+}
+
+// issuedSchoolAccess é uma linha da resposta de issue/rotate — a única vez
+// em que o código aparece em claro.
+type issuedSchoolAccess struct {
+	SchoolID  int    `json:"school_id"`
+	Nome      string `json:"nome_escola"`
+	INEP      string `json:"codigo_inep"`
+	Municipio string `json:"municipio"`
+	Dre       string `json:"dre"`
+	Code      string `json:"code"`
+	Link      string `json:"link,omitempty"`
+}
+
+var schoolAccessReportColumns = []string{
+	"DRE",
+	"Município",
+	"Código INEP",
+	"Escola",
+	"Código de Acesso",
+	"Link de Acesso",
+}
+
+// AdminListSchoolAccess lista a situação dos códigos por escola.
+func (app *application) AdminListSchoolAccess(w http.ResponseWriter, r *http.Request) {
+	rows, err := app.models.SchoolAccess.ListSchools(r.Context(), strings.TrimSpace(r.URL.Query().Get("dre")), nil)
+	if err != nil {
+		app.logger.Printf("AdminListSchoolAccess: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao listar códigos de acesso"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: rows})
+}
+
+// AdminIssueSchoolAccess emite códigos para as escolas do recorte que ainda
+// não têm código ativo.
+func (app *application) AdminIssueSchoolAccess(w http.ResponseWriter, r *http.Request) {
+	app.issueSchoolAccess(w, r, false)
+}
+
+// AdminRotateSchoolAccess revoga o código atual e emite um novo para todas
+// as escolas do recorte.
+func (app *application) AdminRotateSchoolAccess(w http.ResponseWriter, r *http.Request) {
+	app.issueSchoolAccess(w, r, true)
+}
+
+func (app *application) issueSchoolAccess(w http.ResponseWriter, r *http.Request, rotate bool) {
+	format := strings.TrimSpace(r.URL.Query().Get("format"))
+	if format != "" && normalizeReportFormat(format) != reportFormatXLSX {
+		app.errorJSON(w, fmt.Errorf("formato %q não suportado; use format=xlsx", format), http.StatusBadRequest)
+		return
+	}
+	dre, ids, err := app.readSchoolAccessBatch(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	schools, err := app.models.SchoolAccess.ListSchools(r.Context(), dre, ids)
+	if err != nil {
+		app.logger.Printf("issueSchoolAccess: listar escolas: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao emitir códigos de acesso"), http.StatusInternalServerError)
+		return
+	}
+
+	issued := []issuedSchoolAccess{}
+	batch := []models.SchoolAccessIssue{}
+	for _, s := range schools {
+		if s.HasCode && !rotate {
+			continue
+		}
+		code, err := generateSchoolAccessCode()
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("erro ao emitir códigos de acesso"), http.StatusInternalServerError)
+			return
+		}
+		batch = append(batch, models.SchoolAccessIssue{SchoolID: s.SchoolID, Hash: hashSchoolAccessCode(code), Hint: schoolAccessHint(code)})
+		issued = append(issued, issuedSchoolAccess{
+			SchoolID: s.SchoolID, Nome: s.Nome, INEP: s.INEP, Municipio: s.Municipio, Dre: s.Dre,
+			Code: code, Link: schoolAccessLink(code),
+		})
+	}
+
+	id, _ := adminFromContext(r.Context())
+	if err := app.models.SchoolAccess.IssueBatch(r.Context(), batch, id.UserID); err != nil {
+		app.logger.Printf("issueSchoolAccess: gravar lote: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao emitir códigos de acesso"), http.StatusInternalServerError)
+		return
+	}
+
+	if format != "" {
+		data := make([][]any, 0, len(issued))
+		for _, s := range issued {
+			data = append(data, []any{s.Dre, s.Municipio, s.INEP, s.Nome, s.Code, s.Link})
+		}
+		filtersLine := "Filtros aplicados — DRE: todas"
+		fileParts := []string{"codigos_acesso_escolas"}
+		if dre != "" {
+			filtersLine = "Filtros aplicados — DRE: " + dre
+			fileParts = append(fileParts, "dre", sanitizeFileNamePart(dre))
+		}
+		app.sendReportXLSX(w, "issueSchoolAccess", reportData{
+			Title:       "Códigos de Acesso do Formulário do Censo",
+			SheetName:   "Códigos de Acesso",
+			FiltersLine: filtersLine,
+			Headers:     schoolAccessReportColumns,
+			Rows:        data,
+		}, strings.Join(fileParts, "_")+".xlsx")
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, jsonResponse{
+		Error:   false,
+		Message: fmt.Sprintf("%d código(s) emitido(s)", len(issued)),
+		Data:    issued,
+	})
+}
+
+// AdminRevokeSchoolAccess revoga os códigos ativos das escolas do recorte.
+func (app *application) AdminRevokeSchoolAccess(w http.ResponseWriter, r *http.Request) {
+	dre, ids, err := app.readSchoolAccessBatch(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	schools, err := app.models.SchoolAccess.ListSchools(r.Context(), dre, ids)
+	if err != nil {
+		app.logger.Printf("AdminRevokeSchoolAccess: listar escolas: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao revogar códigos de acesso"), http.StatusInternalServerError)
+		return
+	}
+	scoped := make([]int64, 0, len(schools))
+	for _, s := range schools {
+		scoped = append(scoped, int64(s.SchoolID))
+	}
+	n, err := app.models.SchoolAccess.RevokeSchools(r.Context(), scoped)
+	if err != nil {
+		app.logger.Printf("AdminRevokeSchoolAccess: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao revogar códigos de acesso"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{
+		Error:   false,
+		Message: fmt.Sprintf("%d código(s) revogado(s)", n),
+		Data:    map[string]int64{"revoked": n},
+	})
+}
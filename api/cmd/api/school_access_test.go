@@ -0,0 +1,105 @@
+package main
+
+// Testes dos códigos de acesso por escola. Sem banco: cobrem formato e
+// normalização do código, o link mágico, o caminho sem código (opcional x
+// obrigatório) e a leitura do lote das rotas administrativas.
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSchoolAccessCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		code, err := generateSchoolAccessCode()
+		if err != nil {
+			t.Fatalf("generateSchoolAccessCode: %v", err)
+		}
+		if len(code) != 14 || code[4] != '-' || code[9] != '-' {
+			t.Fatalf("código %q fora do formato XXXX-XXXX-XXXX", code)
+		}
+		for _, r := range strings.ReplaceAll(code, "-", "") {
+			if !strings.ContainsRune(schoolAccessAlphabet, r) {
+				t.Fatalf("código %q contém %q fora do alfabeto", code, r)
+			}
+		}
+		if seen[code] {
+			t.Fatalf("código repetido: %s", code)
+		}
+		seen[code] = true
+	}
+	if len(schoolAccessAlphabet) != 32 {
+		t.Fatalf("alfabeto com %d símbolos; want 32 (sorteio sem viés)", len(schoolAccessAlphabet))
+	}
+}
+
+func TestSchoolAccessCodeNormalization(t *testing.T) {
+	want := hashSchoolAccessCode("ABCD-EFGH-JKLM")
+	for _, typed := range []string{"abcd-efgh-jklm", " ABCDEFGHJKLM ", "abcd efgh jklm"} {
+		if got := hashSchoolAccessCode(typed); got != want {
+			t.Errorf("hash(%q) difere do código canônico", typed)
+		}
+	}
+	if hashSchoolAccessCode("ABCD-EFGH-JKLN") == want {
+		t.Error("códigos diferentes com o mesmo hash")
+	}
+	if got := schoolAccessHint("abcd-efgh-jklm"); got != "JKLM" {
+		t.Errorf("hint = %q; want JKLM", got)
+	}
+}
+
+func TestSchoolAccessLink(t *testing.T) {
+	t.Setenv("FORM_PUBLIC_URL", "")
+	if got := schoolAccessLink("ABCD-EFGH-JKLM"); got != "" {
+		t.Errorf("link sem FORM_PUBLIC_URL = %q; want vazio", got)
+	}
+	t.Setenv("FORM_PUBLIC_URL", "https://censo.seduc.pa.gov.br/")
+	if got := schoolAccessLink("ABCD-EFGH-JKLM"); got != "https://censo.seduc.pa.gov.br/?acesso=ABCD-EFGH-JKLM" {
+		t.Errorf("link = %q", got)
+	}
+}
+
+func TestAuthorizeSchoolWriteWithoutCode(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/census", nil)
+	for _, v := range []string{"", "true"} {
+		t.Setenv("SCHOOL_ACCESS_REQUIRED", v)
+		rec := httptest.NewRecorder()
+		if app.authorizeSchoolWrite(rec, req, 10) || rec.Code != http.StatusUnauthorized {
+			t.Fatalf("SCHOOL_ACCESS_REQUIRED=%q, código ausente: status %d; want 401", v, rec.Code)
+		}
+	}
+
+	t.Setenv("SCHOOL_ACCESS_REQUIRED", "false")
+	rec := httptest.NewRecorder()
+	if !app.authorizeSchoolWrite(rec, req, 10) {
+		t.Fatalf("exigência desligada, código ausente recusado (status %d)", rec.Code)
+	}
+}
+
+func TestReadSchoolAccessBatch(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	read := func(target, body string) (string, []int64, error) {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		return app.readSchoolAccessBatch(httptest.NewRecorder(), req)
+	}
+
+	if _, _, err := read("/v1/admin/school-access/rotate", ""); err == nil {
+		t.Error("lote sem dre nem school_ids aceito")
+	}
+	if dre, ids, err := read("/v1/admin/school-access/rotate?dre=MARABA", ""); err != nil || dre != "MARABA" || len(ids) != 0 {
+		t.Errorf("dre sem corpo: dre=%q ids=%v err=%v", dre, ids, err)
+	}
+	if _, ids, err := read("/v1/admin/school-access/revoke", `{"school_ids":[3,5]}`); err != nil || len(ids) != 2 {
+		t.Errorf("school_ids: ids=%v err=%v", ids, err)
+	}
+	if _, _, err := read("/v1/admin/school-access/revoke?dre=MARABA", `{"school_ids":"x"}`); err == nil {
+		t.Error("corpo malformado aceito")
+	}
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"censo-api/internal/models"
+	"censo-api/internal/services"
+)
+
+// =====================================================================
+// Fotos das escolas
+// =====================================================================
+// POST /v1/upload recebe até maxUploadPhotos fotos no campo "photo", cada
+// uma com a sua categoria no campo "category" (um valor para todas ou um
+// por foto, na mesma ordem). Antes de gravar, a foto perde EXIF/GPS e
+// ganha miniatura (services.PreparePhoto); foto e miniatura vão para o
+// armazenamento de PHOTO_STORE (photo_store.go) e os metadados para
+// school_photos.
+//
+// GET /v1/admin/schools/{id}/photos é a galeria do painel: as fotos do
+// ano agrupadas por categoria, cada grupo com as respostas do censo que a
+// foto ajuda a conferir (photoCensusKeys). O arquivo é servido pela API em
+// .../photos/{photo_id}/file (?size=thumb para a miniatura), porque nem o
+// disco local nem um bucket privado têm URL pública.
+// =====================================================================
+
+const (
+	maxUploadPhotos = 10
+	maxPhotoBytes   = 10 << 20
+	// maxUploadBodyBytes limita o corpo inteiro do upload (defesa contra
+	// DoS por disco), bem abaixo de maxUploadPhotos*maxPhotoBytes: várias
+	// fotos grandes vão em mais de um envio.
+	maxUploadBodyBytes = 32 << 20
+)
+
+var (
+	allowedPhotoExts  = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".gif": true}
+	allowedPhotoTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/webp": true, "image/gif": true}
+)
+
+// photoCensusKeys são as respostas do censo mostradas ao lado de cada
+// categoria na galeria.
+var photoCensusKeys = map[string][]string{
+	models.PhotoFachada:   {"situacao_estrutura"},
+	models.PhotoCozinha:   {"condicoes_cozinha"},
+	models.PhotoBanheiros: {"banheiros_vasos_funcionais"},
+	models.PhotoSalas:     {"qtd_salas_aula", "salas_climatizadas"},
+}
+
+// photoCategory normaliza a categoria; vazia vira "outras".
+func photoCategory(raw string) (string, error) {
+	c := strings.ToLower(strings.TrimSpace(raw))
+	if c == "" {
+		return models.PhotoOutras, nil
+	}
+	for _, known := range models.PhotoCategories {
+		if c == known {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("categoria inválida: %q (use %s)", raw, strings.Join(models.PhotoCategories, ", "))
+}
+
+// uploadCategories devolve a categoria de cada uma das n fotos: nenhum
+// valor ou um valor vale para todas; senão deve haver um por foto.
+func uploadCategories(values []string, n int) ([]string, error) {
+	if len(values) > 1 && len(values) != n {
+		return nil, fmt.Errorf("informe uma categoria para todas as fotos ou uma por foto")
+	}
+	out := make([]string, n)
+	for i := range out {
+		raw := ""
+		if len(values) == 1 {
+			raw = values[0]
+		} else if len(values) == n {
+			raw = values[i]
+		}
+		c, err := photoCategory(raw)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+// uploadYear é o ano do censo da foto: o campo year do formulário ou o ano
+// corrente.
+func uploadYear(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Now().Year(), nil
+	}
+	year, err := strconv.Atoi(raw)
+	if err != nil || year < 2000 || year > 2100 {
+		return 0, fmt.Errorf("ano inválido")
+	}
+	return year, nil
+}
+
+// sanitizePhotoName mantém no nome do arquivo só caracteres seguros.
+func sanitizePhotoName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, filepath.Base(name))
+}
+
+// photoUpload é uma foto do formulário já validada e sem metadados.
+type photoUpload struct {
+	name     string
+	category string
+	photo    *services.PreparedPhoto
+}
+
+// readPhotoUpload valida extensão, tamanho e conteúdo (magic bytes, não só
+// a extensão do nome) e prepara a foto.
+func readPhotoUpload(fh *multipart.FileHeader) (*services.PreparedPhoto, error) {
+	ext := strings.ToLower(filepath.Ext(fh.Filename))
+	if !allowedPhotoExts[ext] {
+		return nil, fmt.Errorf("tipo de arquivo não permitido. Use: jpg, jpeg, png, webp ou gif")
+	}
+	if fh.Size > maxPhotoBytes {
+		return nil, fmt.Errorf("arquivo muito grande (máx. 10MB)")
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("arquivo inválido")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxPhotoBytes+1))
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("arquivo inválido")
+	}
+	if len(data) > maxPhotoBytes {
+		return nil, fmt.Errorf("arquivo muito grande (máx. 10MB)")
+	}
+	detected := http.DetectContentType(data)
+	if !allowedPhotoTypes[detected] {
+		return nil, fmt.Errorf("conteúdo do arquivo não é uma imagem válida")
+	}
+	prepared, err := services.PreparePhoto(data, detected)
+	if errors.Is(err, services.ErrPhotoTooLarge) {
+		return nil, fmt.Errorf("resolução da foto acima do limite (%d MP)", services.PhotoMaxPixels/1_000_000)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("imagem inválida: %v", err)
+	}
+	return prepared, nil
+}
+
+func (app *application) uploadPhoto(w http.ResponseWriter, r *http.Request) {
+	if !app.allowRate(r, uploadLimit, ipKey(r)) {
+		w.Header().Set("Retry-After", "600")
+		app.errorJSON(w, fmt.Errorf("muitos uploads. Aguarde alguns minutos"), http.StatusTooManyRequests)
+		return
+	}
+
+	// O código de acesso é conferido antes de ler o corpo: sem ele, o
+	// multipart não chega ao disco. A escola do código é comparada com
+	// school_id depois.
+	bound, accessStatus, err := app.schoolAccessFromRequest(r)
+	if err != nil {
+		app.errorJSON(w, err, accessStatus)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBodyBytes)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		app.errorJSON(w, fmt.Errorf("arquivos muito grandes ou inválidos (máx. 10MB por foto e 32MB por envio)"), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["photo"]
+	if len(files) == 0 {
+		app.errorJSON(w, fmt.Errorf("arquivo inválido"), http.StatusBadRequest)
+		return
+	}
+	if len(files) > maxUploadPhotos {
+		app.errorJSON(w, fmt.Errorf("máximo de %d fotos por envio", maxUploadPhotos), http.StatusBadRequest)
+		return
+	}
+
+	schoolIDStr := r.FormValue("school_id")
+	if schoolIDStr == "" {
+		app.errorJSON(w, fmt.Errorf("school_id obrigatório"), http.StatusBadRequest)
+		return
+	}
+	schoolID, err := strconv.Atoi(schoolIDStr)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("school_id inválido"), http.StatusBadRequest)
+		return
+	}
+	if bound != 0 && bound != schoolID {
+		app.errorJSON(w, errSchoolAccessMismatch, http.StatusForbidden)
+		return
+	}
+	if !app.allowRate(r, schoolUploadLimit, schoolKey(schoolID)) {
+		w.Header().Set("Retry-After", "600")
+		app.errorJSON(w, fmt.Errorf("muitos uploads para esta escola. Aguarde alguns minutos"), http.StatusTooManyRequests)
+		return
+	}
+
+	categories, err := uploadCategories(r.MultipartForm.Value["category"], len(files))
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	year, err := uploadYear(r.FormValue("year"))
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if app.photos == nil {
+		app.errorJSON(w, fmt.Errorf("armazenamento de fotos não configurado"), http.StatusServiceUnavailable)
+		return
+	}
+
+	// Todas as fotos são validadas antes de a primeira ser gravada.
+	uploads := make([]photoUpload, len(files))
+	for i, fh := range files {
+		prepared, err := readPhotoUpload(fh)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("%s: %v", sanitizePhotoName(fh.Filename), err), http.StatusBadRequest)
+			return
+		}
+		uploads[i] = photoUpload{name: sanitizePhotoName(fh.Filename), category: categories[i], photo: prepared}
+	}
+
+	school, err := app.models.Schools.Get(schoolID)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("escola não encontrada"), http.StatusNotFound)
+		return
+	}
+
+	saved := make([]models.SchoolPhoto, 0, len(uploads))
+	created := 0
+	for _, up := range uploads {
+		photo, isNew, err := app.storePhoto(r.Context(), *school, year, up)
+		if err != nil {
+			app.logger.Printf("uploadPhoto: %s: %v", up.name, err)
+			app.errorJSON(w, fmt.Errorf("erro ao salvar %s (%d de %d fotos já salvas)", up.name, len(saved), len(uploads)), http.StatusBadGateway)
+			return
+		}
+		if isNew {
+			created++
+		}
+		saved = append(saved, *photo)
+	}
+
+	status := http.StatusCreated
+	if created == 0 {
+		status = http.StatusOK
+	}
+	app.writeJSON(w, status, jsonResponse{
+		Error:   false,
+		Message: fmt.Sprintf("%d foto(s) enviada(s), %d já existia(m)", created, len(saved)-created),
+		Data:    saved,
+	})
+}
+
+// storePhoto grava a foto e a miniatura e registra em school_photos. A
+// mesma foto já enviada para a escola e ano devolve o registro existente
+// (isNew = false) sem gravar de novo.
+func (app *application) storePhoto(ctx context.Context, school models.School, year int, up photoUpload) (*models.SchoolPhoto, bool, error) {
+	sum := sha256.Sum256(up.photo.Data)
+	sha := hex.EncodeToString(sum[:])
+	existing, err := app.models.SchoolPhotos.GetBySHA(ctx, school.ID, year, sha)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	obj := services.PhotoObject{
+		School: school, Year: year, Category: up.category, Name: up.name,
+		ContentType: up.photo.ContentType, Size: int64(len(up.photo.Data)), SHA256: sha,
+	}
+	stored, err := app.photos.Put(ctx, obj, bytes.NewReader(up.photo.Data))
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %v", app.photos.Kind(), err)
+	}
+	photo := models.SchoolPhoto{
+		SchoolID: school.ID, Year: year, Category: up.category, Storage: app.photos.Kind(), Path: stored.Path,
+		OriginalName: up.name, ContentType: up.photo.ContentType, SizeBytes: obj.Size, SHA256: sha,
+	}
+	if stored.URL != "" {
+		photo.URL = &stored.URL
+	}
+	if up.photo.Width > 0 {
+		photo.Width, photo.Height = &up.photo.Width, &up.photo.Height
+	}
+
+	if up.photo.Thumb != nil {
+		tsum := sha256.Sum256(up.photo.Thumb)
+		thumb := services.PhotoObject{
+			School: school, Year: year, Category: up.category,
+			Name:        "miniatura_" + strings.TrimSuffix(up.name, filepath.Ext(up.name)) + ".jpg",
+			ContentType: "image/jpeg", Size: int64(len(up.photo.Thumb)), SHA256: hex.EncodeToString(tsum[:]),
+		}
+		ts, err := app.photos.Put(ctx, thumb, bytes.NewReader(up.photo.Thumb))
+		if err != nil {
+			return nil, false, fmt.Errorf("%s (miniatura): %v", app.photos.Kind(), err)
+		}
+		photo.ThumbPath = &ts.Path
+		if ts.URL != "" {
+			photo.ThumbURL = &ts.URL
+		}
+	}
+
+	isNew, err := app.models.SchoolPhotos.Insert(ctx, &photo)
+	if err != nil {
+		return nil, false, err
+	}
+	return &photo, isNew, nil
+}
+
+// ─── Galeria do painel ───────────────────────────────────────────────────────
+
+// schoolPhotoView é a foto na galeria, com os endereços de arquivo da API.
+type schoolPhotoView struct {
+	models.SchoolPhoto
+	FileURL      string `json:"file_url"`
+	ThumbFileURL string `json:"thumb_file_url,omitempty"`
+}
+
+// photoCategoryGroup são as fotos de uma categoria com as respostas do
+// censo correspondentes.
+type photoCategoryGroup struct {
+	Category string            `json:"category"`
+	Censo    map[string]any    `json:"censo,omitempty"`
+	Photos   []schoolPhotoView `json:"photos"`
+}
+
+type schoolPhotoGallery struct {
+	SchoolID   int                  `json:"school_id"`
+	Nome       string               `json:"nome_escola"`
+	INEP       string               `json:"codigo_inep"`
+	Year       int                  `json:"year"`
+	Total      int                  `json:"total"`
+	Categories []photoCategoryGroup `json:"categories"`
+}
+
+// buildPhotoGallery agrupa as fotos por categoria, na ordem de
+// models.PhotoCategories; sem filtro, todas as categorias aparecem, mesmo
+// vazias. census são as respostas do censo do ano (nil sem censo).
+func buildPhotoGallery(school models.School, year int, category string, photos []models.SchoolPhoto, census map[string]any) schoolPhotoGallery {
+	g := schoolPhotoGallery{SchoolID: school.ID, Nome: school.Nome, INEP: school.INEP, Year: year, Total: len(photos)}
+	for _, c := range models.PhotoCategories {
+		if category != "" && c != category {
+			continue
+		}
+		group := photoCategoryGroup{Category: c, Photos: []schoolPhotoView{}}
+		for _, key := range photoCensusKeys[c] {
+			if v, ok := census[key]; ok {
+				if group.Censo == nil {
+					group.Censo = map[string]any{}
+				}
+				group.Censo[key] = v
+			}
+		}
+		for _, p := range photos {
+			if p.Category != c {
+				continue
+			}
+			v := schoolPhotoView{SchoolPhoto: p, FileURL: fmt.Sprintf("/v1/admin/schools/%d/photos/%d/file", school.ID, p.ID)}
+			if p.ThumbPath != nil {
+				v.ThumbFileURL = v.FileURL + "?size=thumb"
+			}
+			group.Photos = append(group.Photos, v)
+		}
+		g.Categories = append(g.Categories, group)
+	}
+	return g
+}
+
+// AdminListSchoolPhotos devolve a galeria de fotos da escola no ano
+// (?year=, padrão o ano corrente; ?category= filtra).
+func (app *application) AdminListSchoolPhotos(w http.ResponseWriter, r *http.Request) {
+	school, ok := app.adminSchool(w, r)
+	if !ok {
+		return
+	}
+	year, err := uploadYear(r.URL.Query().Get("year"))
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	category := ""
+	if raw := r.URL.Query().Get("category"); raw != "" {
+		if category, err = photoCategory(raw); err != nil {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	photos, err := app.models.SchoolPhotos.ListBySchool(r.Context(), school.ID, year, category)
+	if err != nil {
+		app.logger.Printf("AdminListSchoolPhotos: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao listar fotos"), http.StatusInternalServerError)
+		return
+	}
+	var census map[string]any
+	censo, err := app.models.Census.GetBySchoolID(school.ID, year)
+	if err != nil {
+		app.logger.Printf("AdminListSchoolPhotos: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar censo"), http.StatusInternalServerError)
+		return
+	}
+	if censo != nil {
+		_ = json.Unmarshal(censo.Data, &census)
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: buildPhotoGallery(*school, year, category, photos, census)})
+}
+
+// AdminGetSchoolPhotoFile devolve o arquivo da foto (?size=thumb para a
+// miniatura). Foto gravada em outro armazenamento que não o atual é
+// redirecionada para o link dela, quando houver.
+func (app *application) AdminGetSchoolPhotoFile(w http.ResponseWriter, r *http.Request) {
+	school, ok := app.adminSchool(w, r)
+	if !ok {
+		return
+	}
+	photoID, err := strconv.ParseInt(chi.URLParam(r, "photo_id"), 10, 64)
+	if err != nil || photoID <= 0 {
+		app.errorJSON(w, fmt.Errorf("photo_id inválido"), http.StatusBadRequest)
+		return
+	}
+	photo, err := app.models.SchoolPhotos.Get(r.Context(), photoID)
+	if err != nil {
+		app.logger.Printf("AdminGetSchoolPhotoFile: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar foto"), http.StatusInternalServerError)
+		return
+	}
+	if photo == nil || photo.SchoolID != school.ID {
+		app.errorJSON(w, fmt.Errorf("foto não encontrada"), http.StatusNotFound)
+		return
+	}
+
+	path, contentType, link := photo.Path, photo.ContentType, photo.URL
+	if r.URL.Query().Get("size") == "thumb" {
+		if photo.ThumbPath == nil {
+			app.errorJSON(w, fmt.Errorf("foto sem miniatura"), http.StatusNotFound)
+			return
+		}
+		path, contentType, link = *photo.ThumbPath, "image/jpeg", photo.ThumbURL
+	}
+	if app.photos == nil || app.photos.Kind() != photo.Storage {
+		if link != nil {
+			http.Redirect(w, r, *link, http.StatusFound)
+			return
+		}
+		app.errorJSON(w, fmt.Errorf("foto gravada no armazenamento %s, diferente do atual", photo.Storage), http.StatusConflict)
+		return
+	}
+
+	body, err := app.photos.Open(r.Context(), path)
+	if err != nil {
+		app.logger.Printf("AdminGetSchoolPhotoFile: %s: %v", photo.Storage, err)
+		app.errorJSON(w, fmt.Errorf("erro ao ler foto"), http.StatusBadGateway)
+		return
+	}
+	defer body.Close()
+	// O caminho é derivado do conteúdo: o arquivo de um id nunca muda.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, body); err != nil {
+		app.logger.Printf("AdminGetSchoolPhotoFile: %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+// Testes das fotos categorizadas. Sem banco: cobrem a remoção de EXIF/GPS
+// (JPEG girada pela orientação, chunks de PNG e WebP), o primeiro quadro
+// do GIF, a miniatura, o limite de resolução, as categorias do upload, o
+// ano, o código de acesso conferido antes do corpo e a montagem da galeria
+// com as respostas do censo.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"censo-api/internal/models"
+	"censo-api/internal/services"
+)
+
+// halfImage é uma imagem w×h com a metade esquerda vermelha e a direita azul.
+func halfImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{B: 255, A: 255}
+			if x < w/2 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// jpegWithExif insere depois do SOI um APP1 com a orientação e um texto
+// de GPS.
+func jpegWithExif(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPSLatitude -1.4558"...)
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(seg)+2))
+	app1 = append(app1, seg...)
+	raw := buf.Bytes()
+	return append(append(append([]byte{}, raw[:2]...), app1...), raw[2:]...)
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	c := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	c = append(c, typ...)
+	c = append(c, data...)
+	return binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE(c[4:]))
+}
+
+func TestPreparePhotoJPEG(t *testing.T) {
+	raw := jpegWithExif(t, halfImage(40, 20), 6)
+	if !bytes.Contains(raw, []byte("GPSLatitude")) {
+		t.Fatal("fixture sem EXIF")
+	}
+	p, err := services.PreparePhoto(raw, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(p.Data, []byte("Exif")) || bytes.Contains(p.Data, []byte("GPSLatitude")) {
+		t.Error("EXIF permaneceu na foto")
+	}
+	// Orientação 6: girada 90° no sentido horário, a metade esquerda
+	// (vermelha) vira a metade de cima.
+	if p.Width != 20 || p.Height != 40 {
+		t.Fatalf("dimensões = %dx%d; want 20x40", p.Width, p.Height)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(p.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, b, _ := img.At(10, 5).RGBA(); r < b {
+		t.Errorf("topo não é vermelho: r=%d b=%d", r, b)
+	}
+	if r, _, b, _ := img.At(10, 35).RGBA(); b < r {
+		t.Errorf("base não é azul: r=%d b=%d", r, b)
+	}
+	if len(p.Thumb) == 0 {
+		t.Error("sem miniatura")
+	}
+}
+
+func TestPreparePhotoThumb(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, halfImage(800, 400)); err != nil {
+		t.Fatal(err)
+	}
+	p, err := services.PreparePhoto(buf.Bytes(), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(p.Thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != services.PhotoThumbSize || cfg.Height != services.PhotoThumbSize/2 {
+		t.Errorf("miniatura = %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestPreparePhotoPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, halfImage(8, 8)); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	iend := bytes.Index(raw, []byte("IEND")) - 4
+	withMeta := append(append([]byte{}, raw[:iend]...), pngChunk("tEXt", []byte("GPS\x00-1.4558,-48.4902"))...)
+	withMeta = append(withMeta, pngChunk("eXIf", []byte("MM\x00*"))...)
+	withMeta = append(withMeta, raw[iend:]...)
+
+	p, err := services.PreparePhoto(withMeta, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p.Data, raw) {
+		t.Errorf("PNG limpo difere do original: %d bytes, want %d", len(p.Data), len(raw))
+	}
+	if p.Width != 8 || p.Height != 8 || len(p.Thumb) == 0 {
+		t.Errorf("prepared = %dx%d thumb=%d", p.Width, p.Height, len(p.Thumb))
+	}
+
+	// IHDR com 10000×5000 passa do limite sem decodificar a imagem.
+	huge := append([]byte{}, raw...)
+	binary.BigEndian.PutUint32(huge[16:], 10000)
+	binary.BigEndian.PutUint32(huge[20:], 5000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	if _, err := services.PreparePhoto(huge, "image/png"); !errors.Is(err, services.ErrPhotoTooLarge) {
+		t.Errorf("err = %v; want ErrPhotoTooLarge", err)
+	}
+}
+
+func TestPreparePhotoGIF(t *testing.T) {
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 16, 8), color.Palette{color.Black, color.White})
+		frame.SetColorIndex(i, 0, 1)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := services.PreparePhoto(buf.Bytes(), "image/gif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Só o primeiro quadro é decodificado e gravado.
+	got, err := gif.DecodeAll(bytes.NewReader(p.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Image) != 1 || p.Width != 16 || p.Height != 8 || len(p.Thumb) == 0 {
+		t.Errorf("quadros = %d, %dx%d, thumb=%d", len(got.Image), p.Width, p.Height, len(p.Thumb))
+	}
+	if got.Image[0].ColorIndexAt(0, 0) != 1 || got.Image[0].ColorIndexAt(1, 0) != 0 {
+		t.Error("quadro gravado não é o primeiro")
+	}
+}
+
+func TestPreparePhotoWebP(t *testing.T) {
+	riffChunk := func(fourcc string, data []byte) []byte {
+		c := append([]byte(fourcc), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x0C // EXIF + XMP
+	body := []byte("WEBP")
+	body = append(body, riffChunk("VP8X", vp8x)...)
+	body = append(body, riffChunk("VP8 ", []byte("quadro"))...)
+	body = append(body, riffChunk("EXIF", []byte("GPS-1"))...)
+	body = append(body, riffChunk("XMP ", []byte("<x:gps/>"))...)
+	raw := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	raw = append(raw, body...)
+
+	p, err := services.PreparePhoto(raw, "image/webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(p.Data, []byte("EXIF")) || bytes.Contains(p.Data, []byte("XMP ")) || bytes.Contains(p.Data, []byte("GPS")) {
+		t.Errorf("metadados permaneceram: %q", p.Data)
+	}
+	if !bytes.Contains(p.Data, []byte("quadro")) || p.Data[20] != 0 {
+		t.Errorf("imagem ou flags de VP8X: %q", p.Data)
+	}
+	if got := binary.LittleEndian.Uint32(p.Data[4:]); int(got) != len(p.Data)-8 {
+		t.Errorf("tamanho RIFF = %d; want %d", got, len(p.Data)-8)
+	}
+	if p.Thumb != nil {
+		t.Error("WebP não deveria ter miniatura")
+	}
+}
+
+func TestUploadCategories(t *testing.T) {
+	got, err := uploadCategories(nil, 2)
+	if err != nil || got[0] != models.PhotoOutras || got[1] != models.PhotoOutras {
+		t.Errorf("sem categoria: %v %v", got, err)
+	}
+	got, err = uploadCategories([]string{" Fachada "}, 2)
+	if err != nil || got[0] != "fachada" || got[1] != "fachada" {
+		t.Errorf("uma para todas: %v %v", got, err)
+	}
+	got, err = uploadCategories([]string{"cozinha", ""}, 2)
+	if err != nil || got[0] != "cozinha" || got[1] != models.PhotoOutras {
+		t.Errorf("uma por foto: %v %v", got, err)
+	}
+	if _, err := uploadCategories([]string{"cozinha", "salas"}, 3); err == nil {
+		t.Error("quantidade diferente aceita")
+	}
+	if _, err := uploadCategories([]string{"quadra"}, 1); err == nil {
+		t.Error("categoria desconhecida aceita")
+	}
+	if got := sanitizePhotoName("../../fotos/Fachada Nova (1).JPG"); got != "Fachada_Nova__1_.JPG" {
+		t.Errorf("nome = %q", got)
+	}
+}
+
+func TestUploadYear(t *testing.T) {
+	if y, err := uploadYear(" 2025 "); err != nil || y != 2025 {
+		t.Errorf("2025: %d %v", y, err)
+	}
+	if y, err := uploadYear(""); err != nil || y < 2025 {
+		t.Errorf("vazio: %d %v", y, err)
+	}
+	for _, raw := range []string{"abc", "25", "3000"} {
+		if _, err := uploadYear(raw); err == nil {
+			t.Errorf("%q aceito", raw)
+		}
+	}
+}
+
+func TestBuildPhotoGallery(t *testing.T) {
+	thumb := "9/2026/t.jpg"
+	photos := []models.SchoolPhoto{
+		{ID: 1, SchoolID: 9, Category: "fachada", ThumbPath: &thumb},
+		{ID: 2, SchoolID: 9, Category: "cozinha"},
+		{ID: 3, SchoolID: 9, Category: "fachada"},
+	}
+	census := map[string]any{"situacao_estrutura": "Necessita de reforma geral", "condicoes_cozinha": "Precária", "total_alunos": 10.0}
+	g := buildPhotoGallery(models.School{ID: 9, Nome: "EEEM Teste"}, 2026, "", photos, census)
+
+	if g.Total != 3 || len(g.Categories) != len(models.PhotoCategories) {
+		t.Fatalf("total=%d categorias=%d", g.Total, len(g.Categories))
+	}
+	fachada := g.Categories[0]
+	if fachada.Category != "fachada" || len(fachada.Photos) != 2 || fachada.Censo["situacao_estrutura"] != "Necessita de reforma geral" {
+		t.Errorf("fachada = %+v", fachada)
+	}
+	if fachada.Photos[0].FileURL != "/v1/admin/schools/9/photos/1/file" || fachada.Photos[0].ThumbFileURL != "/v1/admin/schools/9/photos/1/file?size=thumb" {
+		t.Errorf("urls = %q %q", fachada.Photos[0].FileURL, fachada.Photos[0].ThumbFileURL)
+	}
+	if fachada.Photos[1].ThumbFileURL != "" {
+		t.Error("miniatura inexistente com URL")
+	}
+	if c := g.Categories[1]; c.Category != "cozinha" || c.Censo["condicoes_cozinha"] != "Precária" || len(c.Photos) != 1 {
+		t.Errorf("cozinha = %+v", c)
+	}
+	if c := g.Categories[3]; c.Photos == nil || c.Censo != nil {
+		t.Errorf("salas vazia = %+v", c)
+	}
+
+	g = buildPhotoGallery(models.School{ID: 9}, 2026, "cozinha", photos[1:2], nil)
+	if len(g.Categories) != 1 || g.Categories[0].Censo != nil {
+		t.Errorf("filtro sem censo = %+v", g.Categories)
+	}
+}
+
+// failReader falha o teste se o corpo da requisição for lido.
+type failReader struct{ t *testing.T }
+
+func (f failReader) Read([]byte) (int, error) {
+	f.t.Error("corpo do upload lido antes de conferir o código de acesso")
+	return 0, io.EOF
+}
+
+func TestUploadPhotoChecksAccessBeforeBody(t *testing.T) {
+	t.Setenv("SCHOOL_ACCESS_REQUIRED", "")
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	req := httptest.NewRequest(http.MethodPost, "/v1/upload", failReader{t})
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	rec := httptest.NewRecorder()
+	app.uploadPhoto(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("upload sem código: status %d; want 401", rec.Code)
+	}
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"censo-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// =====================================================================
+// Manutenção do cadastro de escolas pelo painel
+// =====================================================================
+// O formulário público só cria ou atualiza escolas pelo codigo_inep
+// (POST /v1/schools). O painel corrige e mantém o cadastro:
+//
+//   - GET    /v1/admin/schools/{id}         cadastro completo;
+//   - PUT    /v1/admin/schools/{id}         substitui os campos editáveis;
+//   - PATCH  /v1/admin/schools/{id}         JSON Merge Patch dos mesmos campos;
+//   - DELETE /v1/admin/schools/{id}?ended_on=AAAA-MM-DD
+//                                           desativa (padrão: hoje);
+//   - POST   /v1/admin/schools/{id}/merge   {"into": id} funde na escola
+//                                           sobrevivente (só seduc_admin).
+//
+// Validação igual à do formulário (INEP de 8 dígitos, CEP, CNPJ, telefone,
+// zona), aplicada só aos campos alterados: valores legados que não mudam
+// não travam a correção de outro campo. Escola inativa (active=false) tem
+// sempre ended_on, sai da listagem pública, da emissão de códigos e do
+// universo de preenchimento, e perde o código de acesso ativo. Reativar é
+// um PATCH {"active": true}. Contas de DRE só mexem em escolas da própria
+// DRE e não as movem para outra.
+// =====================================================================
+
+var (
+	schoolINEPPattern     = regexp.MustCompile(`^\d{8}$`)
+	schoolCEPPattern      = regexp.MustCompile(`^\d{5}-\d{3}$`)
+	schoolCNPJPattern     = regexp.MustCompile(`^\d{2}\.\d{3}\.\d{3}/\d{4}-\d{2}$`)
+	schoolTelefonePattern = regexp.MustCompile(`^\(\d{2}\) \d{4,5}-\d{4}$`)
+)
+
+// schoolZonas são as zonas aceitas pelo formulário.
+var schoolZonas = []string{"Urbana", "Rural", "Ribeirinha"}
+
+// schoolRequest é a representação editável do cadastro, com os nomes de
+// campo de models.School.
+type schoolRequest struct {
+	Nome                 string          `json:"nome_escola"`
+	INEP                 string          `json:"codigo_inep"`
+	Municipio            string          `json:"municipio"`
+	Dre                  string          `json:"dre"`
+	Zona                 string          `json:"zona"`
+	Endereco             string          `json:"endereco"`
+	CNPJ                 string          `json:"cnpj"`
+	Telefone             string          `json:"telefone_institucional"`
+	Email                string          `json:"email"`
+	CEP                  string          `json:"cep"`
+	NomeDiretor          string          `json:"nome_diretor"`
+	MatriculaDiretor     string          `json:"matricula_diretor"`
+	ContatoDiretor       string          `json:"contato_diretor"`
+	Turnos               json.RawMessage `json:"turnos"`
+	EtapasOfertadas      json.RawMessage `json:"etapas_ofertadas"`
+	ModalidadesOfertadas json.RawMessage `json:"modalidades_ofertadas"`
+	Active               *bool           `json:"active"`
+	EndedOn              string          `json:"ended_on"`
+}
+
+// schoolRequestFrom devolve o cadastro gravado na forma editável.
+func schoolRequestFrom(s *models.School) schoolRequest {
+	active := s.Active
+	req := schoolRequest{
+		Nome: s.Nome, INEP: s.INEP, Municipio: s.Municipio, Dre: s.Dre, Zona: s.Zona, Endereco: s.Endereco,
+		CNPJ: s.CNPJ, Telefone: s.Telefone, Email: s.Email, CEP: s.CEP,
+		NomeDiretor: s.NomeDiretor, MatriculaDiretor: s.MatriculaDiretor, ContatoDiretor: s.ContatoDiretor,
+		Turnos: s.Turnos, EtapasOfertadas: s.EtapasOfertadas, ModalidadesOfertadas: s.ModalidadesOfertadas,
+		Active: &active,
+	}
+	if s.EndedOn != nil {
+		req.EndedOn = *s.EndedOn
+	}
+	return req
+}
+
+// normalize apara os textos e aplica o padrão active=true.
+func (req *schoolRequest) normalize() {
+	for _, f := range []*string{&req.Nome, &req.INEP, &req.Municipio, &req.Dre, &req.Zona, &req.Endereco,
+		&req.CNPJ, &req.Telefone, &req.Email, &req.CEP,
+		&req.NomeDiretor, &req.MatriculaDiretor, &req.ContatoDiretor, &req.EndedOn} {
+		*f = strings.TrimSpace(*f)
+	}
+	if req.Active == nil {
+		active := true
+		req.Active = &active
+	}
+	for _, z := range schoolZonas {
+		if strings.EqualFold(req.Zona, z) {
+			req.Zona = z
+		}
+	}
+	for _, l := range []*json.RawMessage{&req.Turnos, &req.EtapasOfertadas, &req.ModalidadesOfertadas} {
+		if string(*l) == "null" {
+			*l = nil
+		}
+	}
+}
+
+// issueInvalidFormat marca campo do cadastro fora do formato do formulário.
+const issueInvalidFormat = "formato_invalido"
+
+// validate confere os campos que diferem de prev (o cadastro gravado) e
+// devolve os problemas por campo, no formato da validação do censo.
+func (req *schoolRequest) validate(prev schoolRequest) []censusFieldIssue {
+	issues := []censusFieldIssue{}
+	report := func(field, code, msg string) {
+		issues = append(issues, censusFieldIssue{Field: field, Code: code, Message: msg})
+	}
+
+	if req.Nome != prev.Nome {
+		if n := utf8.RuneCountInString(req.Nome); n < 3 || n > 255 {
+			report("nome_escola", issueTooShort, "o nome deve ter entre 3 e 255 caracteres")
+		}
+	}
+	if req.INEP != prev.INEP && !schoolINEPPattern.MatchString(req.INEP) {
+		report("codigo_inep", issueInvalidFormat, "o INEP deve conter exatamente 8 números")
+	}
+	for _, f := range []struct{ field, v, prev, label string }{
+		{"dre", req.Dre, prev.Dre, "a DRE"},
+		{"municipio", req.Municipio, prev.Municipio, "o município"},
+	} {
+		if f.v != f.prev && (f.v == "" || utf8.RuneCountInString(f.v) > 100) {
+			report(f.field, issueRequired, "informe "+f.label+" (até 100 caracteres)")
+		}
+	}
+	if req.Zona != prev.Zona {
+		valid := false
+		for _, z := range schoolZonas {
+			valid = valid || req.Zona == z
+		}
+		if !valid {
+			report("zona", issueInvalidOpt, "use "+strings.Join(schoolZonas, ", "))
+		}
+	}
+	if req.Endereco != prev.Endereco && utf8.RuneCountInString(req.Endereco) < 5 {
+		report("endereco", issueTooShort, "informe o endereço completo")
+	}
+	if req.CEP != prev.CEP && !schoolCEPPattern.MatchString(req.CEP) {
+		report("cep", issueInvalidFormat, "o CEP deve estar no formato 00000-000")
+	}
+	if req.CNPJ != prev.CNPJ && req.CNPJ != "" && !schoolCNPJPattern.MatchString(req.CNPJ) {
+		report("cnpj", issueInvalidFormat, "use o formato 00.000.000/0000-00")
+	}
+	if req.Telefone != prev.Telefone && req.Telefone != "" && !schoolTelefonePattern.MatchString(req.Telefone) {
+		report("telefone_institucional", issueInvalidFormat, "formato esperado: (91) 90000-0000")
+	}
+	if req.Email != prev.Email && req.Email != "" {
+		if a, err := mail.ParseAddress(req.Email); err != nil || a.Address != req.Email || len(req.Email) > 150 {
+			report("email", issueInvalidFormat, "e-mail inválido")
+		}
+	}
+	for _, f := range []struct {
+		field, v, prev string
+		limit          int
+	}{
+		{"nome_diretor", req.NomeDiretor, prev.NomeDiretor, 150},
+		{"matricula_diretor", req.MatriculaDiretor, prev.MatriculaDiretor, 50},
+		{"contato_diretor", req.ContatoDiretor, prev.ContatoDiretor, 50},
+	} {
+		if f.v != f.prev && utf8.RuneCountInString(f.v) > f.limit {
+			report(f.field, issueInvalidFormat, fmt.Sprintf("até %d caracteres", f.limit))
+		}
+	}
+	for _, f := range []struct {
+		field string
+		list  json.RawMessage
+	}{
+		{"turnos", req.Turnos}, {"etapas_ofertadas", req.EtapasOfertadas}, {"modalidades_ofertadas", req.ModalidadesOfertadas},
+	} {
+		var items []string
+		if len(f.list) > 0 && json.Unmarshal(f.list, &items) != nil {
+			report(f.field, issueInvalidType, "deve ser uma lista de textos")
+		}
+	}
+
+	if *req.Active {
+		if req.EndedOn != "" {
+			report("ended_on", issueInconsistent, "a data de encerramento só se aplica a escola inativa")
+		}
+	} else if _, err := parseCampaignDate(req.EndedOn); err != nil {
+		report("ended_on", issueRequired, "informe a data de encerramento (AAAA-MM-DD) para desativar")
+	}
+	return issues
+}
+
+// school devolve o cadastro a gravar.
+func (req *schoolRequest) school(id int) models.School {
+	s := models.School{
+		ID: id, Nome: req.Nome, INEP: req.INEP, Municipio: req.Municipio, Dre: req.Dre, Zona: req.Zona,
+		Endereco: req.Endereco, CNPJ: req.CNPJ, Telefone: req.Telefone, Email: req.Email, CEP: req.CEP,
+		NomeDiretor: req.NomeDiretor, MatriculaDiretor: req.MatriculaDiretor, ContatoDiretor: req.ContatoDiretor,
+		Turnos: req.Turnos, EtapasOfertadas: req.EtapasOfertadas, ModalidadesOfertadas: req.ModalidadesOfertadas,
+		Active: *req.Active,
+	}
+	if !s.Active {
+		ended := req.EndedOn
+		s.EndedOn = &ended
+	}
+	return s
+}
+
+// adminSchool carrega a escola de {id} conferindo o recorte de DRE da
+// conta. Em caso de erro já respondeu.
+func (app *application) adminSchool(w http.ResponseWriter, r *http.Request) (*models.School, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		app.errorJSON(w, fmt.Errorf("id inválido"), http.StatusBadRequest)
+		return nil, false
+	}
+	school, err := app.models.Schools.Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		app.errorJSON(w, fmt.Errorf("escola não encontrada"), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		app.logger.Printf("adminSchool: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar escola"), http.StatusInternalServerError)
+		return nil, false
+	}
+	if scope := adminDREScope(r.Context()); scope != "" && !sameDRE(school.Dre, scope) {
+		app.errorJSON(w, errDREForaDoEscopo, http.StatusForbidden)
+		return nil, false
+	}
+	return school, true
+}
+
+// AdminGetSchool devolve o cadastro completo da escola.
+func (app *application) AdminGetSchool(w http.ResponseWriter, r *http.Request) {
+	school, ok := app.adminSchool(w, r)
+	if !ok {
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: school})
+}
+
+// AdminPutSchool substitui os campos editáveis do cadastro.
+func (app *application) AdminPutSchool(w http.ResponseWriter, r *http.Request) {
+	school, ok := app.adminSchool(w, r)
+	if !ok {
+		return
+	}
+	var req schoolRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	app.saveSchool(w, r, school, req)
+}
+
+// AdminPatchSchool aplica um JSON Merge Patch ao cadastro.
+func (app *application) AdminPatchSchool(w http.ResponseWriter, r *http.Request) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, _ := mime.ParseMediaType(ct)
+		if mt != "application/merge-patch+json" && mt != "application/json" {
+			app.errorJSON(w, fmt.Errorf("use Content-Type application/merge-patch+json"), http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+	school, ok := app.adminSchool(w, r)
+	if !ok {
+		return
+	}
+	var patch map[string]any
+	if err := app.readJSON(w, r, &patch); err != nil || patch == nil {
+		app.errorJSON(w, fmt.Errorf("o patch deve ser um objeto JSON"), http.StatusBadRequest)
+		return
+	}
+	req, err := patchSchoolRequest(schoolRequestFrom(school), patch)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	app.saveSchool(w, r, school, req)
+}
+
+// patchSchoolRequest aplica patch sobre o cadastro atual. Reativar
+// ({"active": true}) descarta a data de encerramento.
+func patchSchoolRequest(current schoolRequest, patch map[string]any) (schoolRequest, error) {
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return schoolRequest{}, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return schoolRequest{}, err
+	}
+	if active, ok := patch["active"].(bool); ok && active {
+		if _, set := patch["ended_on"]; !set {
+			doc["ended_on"] = ""
+		}
+	}
+	merged, err := json.Marshal(applyMergePatch(doc, patch))
+	if err != nil {
+		return schoolRequest{}, err
+	}
+	var req schoolRequest
+	if err := json.Unmarshal(merged, &req); err != nil {
+		return schoolRequest{}, fmt.Errorf("patch com tipo inválido: %v", err)
+	}
+	return req, nil
+}
+
+// AdminDeleteSchool desativa a escola (exclusão lógica): o histórico fica,
+// a escola sai da listagem pública e perde o código de acesso.
+func (app *application) AdminDeleteSchool(w http.ResponseWriter, r *http.Request) {
+	school, ok := app.adminSchool(w, r)
+	if !ok {
+		return
+	}
+	if !school.Active {
+		app.errorJSON(w, fmt.Errorf("escola já está inativa"), http.StatusConflict)
+		return
+	}
+	req := schoolRequestFrom(school)
+	inactive := false
+	req.Active = &inactive
+	req.EndedOn = strings.TrimSpace(r.URL.Query().Get("ended_on"))
+	if req.EndedOn == "" {
+		req.EndedOn = campaignDay(time.Now()).Format(models.CampaignDateLayout)
+	}
+	app.saveSchool(w, r, school, req)
+}
+
+// saveSchool valida e grava req sobre school e responde com o cadastro
+// atualizado.
+func (app *application) saveSchool(w http.ResponseWriter, r *http.Request, school *models.School, req schoolRequest) {
+	if school.MergedInto != nil {
+		app.errorJSON(w, fmt.Errorf("escola fundida na escola %d; edite a sobrevivente", *school.MergedInto), http.StatusConflict)
+		return
+	}
+	req.normalize()
+	prev := schoolRequestFrom(school)
+	if issues := req.validate(prev); len(issues) > 0 {
+		app.writeJSON(w, http.StatusUnprocessableEntity, jsonResponse{
+			Error:   true,
+			Message: fmt.Sprintf("cadastro inválido: %d campo(s) com problema", len(issues)),
+			Errors:  issues,
+		})
+		return
+	}
+	if scope := adminDREScope(r.Context()); scope != "" && !sameDRE(req.Dre, scope) {
+		app.errorJSON(w, errDREForaDoEscopo, http.StatusForbidden)
+		return
+	}
+
+	err := app.models.Schools.Update(r.Context(), req.school(school.ID))
+	switch {
+	case errors.Is(err, models.ErrSchoolINEPInUse):
+		app.errorJSON(w, fmt.Errorf("codigo_inep %s já pertence a outra escola; use a fusão", req.INEP), http.StatusConflict)
+		return
+	case errors.Is(err, models.ErrSchoolMerged):
+		app.errorJSON(w, fmt.Errorf("escola fundida em outra; edite a sobrevivente"), http.StatusConflict)
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		app.errorJSON(w, fmt.Errorf("escola não encontrada"), http.StatusNotFound)
+		return
+	case err != nil:
+		app.logger.Printf("saveSchool: escola %d: %v", school.ID, err)
+		app.errorJSON(w, fmt.Errorf("erro ao gravar escola"), http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := app.models.Schools.Get(school.ID)
+	if err != nil {
+		app.logger.Printf("saveSchool: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar escola"), http.StatusInternalServerError)
+		return
+	}
+	msg := "Escola atualizada"
+	if !updated.Active && school.Active {
+		msg = "Escola desativada"
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: msg, Data: updated})
+}
+
+// AdminMergeSchool funde a escola {id} na escola informada em "into".
+func (app *application) AdminMergeSchool(w http.ResponseWriter, r *http.Request) {
+	source, ok := app.adminSchool(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Into int `json:"into"`
+	}
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	if req.Into <= 0 || req.Into == source.ID {
+		app.errorJSON(w, fmt.Errorf("informe em into a escola sobrevivente, diferente da escola fundida"), http.StatusBadRequest)
+		return
+	}
+
+	res, err := app.models.Schools.Merge(r.Context(), source.ID, req.Into)
+	var conflict *models.SchoolMergeConflictError
+	switch {
+	case errors.As(err, &conflict):
+		years := make([]string, len(conflict.Years))
+		for i, y := range conflict.Years {
+			years[i] = strconv.Itoa(y)
+		}
+		app.errorJSON(w, fmt.Errorf("as duas escolas têm censo em %s; resolva antes de fundir", strings.Join(years, ", ")),
+			http.StatusConflict)
+		return
+	case errors.Is(err, models.ErrSchoolMerged):
+		app.errorJSON(w, fmt.Errorf("uma das escolas já foi fundida em outra"), http.StatusConflict)
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		app.errorJSON(w, fmt.Errorf("escola sobrevivente não encontrada"), http.StatusNotFound)
+		return
+	case err != nil:
+		app.logger.Printf("AdminMergeSchool: %d -> %d: %v", source.ID, req.Into, err)
+		app.errorJSON(w, fmt.Errorf("erro ao fundir escolas"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false,
+		Message: fmt.Sprintf("Escola %d fundida na escola %d", res.SourceID, res.TargetID),
+		Data:    res})
+}
@@ -0,0 +1,154 @@
+package main
+
+// Testes da manutenção do cadastro de escolas. Sem banco: cobrem a
+// validação só dos campos alterados, a desativação com data de
+// encerramento, o merge patch (reativação incluída) e a recusa de ids
+// inválidos antes de consultar o banco.
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"censo-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func testSchool() *models.School {
+	return &models.School{
+		ID: 7, Nome: "EEEM Dom Pedro II", INEP: "15012345", Municipio: "Belém", Dre: "DRE Belém",
+		Zona: "Urbana", Endereco: "Av. Nazaré, 100", CEP: "66035-000",
+		// Legado importado fora do padrão do formulário.
+		Telefone: "91 3222-0000",
+		Turnos:   json.RawMessage(`["Manhã","Tarde"]`),
+		Active:   true,
+	}
+}
+
+func issueFields(issues []censusFieldIssue) map[string]string {
+	out := map[string]string{}
+	for _, i := range issues {
+		out[i.Field] = i.Code
+	}
+	return out
+}
+
+func TestSchoolRequestValidate(t *testing.T) {
+	prev := schoolRequestFrom(testSchool())
+
+	req := prev
+	req.Zona = " rural "
+	req.normalize()
+	if issues := req.validate(prev); len(issues) > 0 {
+		t.Errorf("telefone legado inalterado travou a edição: %+v", issues)
+	}
+	if req.Zona != "Rural" {
+		t.Errorf("zona = %q", req.Zona)
+	}
+
+	req = prev
+	req.INEP = "1501234"
+	req.CEP = "66035000"
+	req.Zona = "Centro"
+	req.Telefone = "(91) 3222-0000"
+	req.Email = "diretor@"
+	req.Turnos = json.RawMessage(`"Manhã"`)
+	req.normalize()
+	got := issueFields(req.validate(prev))
+	for field, code := range map[string]string{
+		"codigo_inep": issueInvalidFormat, "cep": issueInvalidFormat, "zona": issueInvalidOpt,
+		"email": issueInvalidFormat, "turnos": issueInvalidType,
+	} {
+		if got[field] != code {
+			t.Errorf("%s = %q; want %q (%v)", field, got[field], code, got)
+		}
+	}
+	if _, ok := got["telefone_institucional"]; ok {
+		t.Error("telefone no formato do formulário recusado")
+	}
+}
+
+func TestSchoolRequestActive(t *testing.T) {
+	prev := schoolRequestFrom(testSchool())
+
+	var req schoolRequest
+	if err := json.Unmarshal([]byte(`{"nome_escola":"EEEM Dom Pedro II"}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	req.normalize()
+	if !*req.Active {
+		t.Error("PUT sem active deveria manter a escola ativa")
+	}
+
+	inactive := false
+	req = prev
+	req.Active = &inactive
+	req.normalize()
+	if got := issueFields(req.validate(prev)); got["ended_on"] != issueRequired {
+		t.Errorf("desativação sem data: %v", got)
+	}
+	req.EndedOn = "2026-02-01"
+	if issues := req.validate(prev); len(issues) > 0 {
+		t.Errorf("desativação com data: %+v", issues)
+	}
+	s := req.school(7)
+	if s.Active || s.EndedOn == nil || *s.EndedOn != "2026-02-01" {
+		t.Errorf("escola = %+v", s)
+	}
+
+	req = prev
+	req.EndedOn = "2026-02-01"
+	req.normalize()
+	if got := issueFields(req.validate(prev)); got["ended_on"] != issueInconsistent {
+		t.Errorf("encerramento em escola ativa: %v", got)
+	}
+}
+
+func TestPatchSchoolRequest(t *testing.T) {
+	school := testSchool()
+	school.Active = false
+	ended := "2025-12-31"
+	school.EndedOn = &ended
+
+	req, err := patchSchoolRequest(schoolRequestFrom(school), map[string]any{"active": true, "cnpj": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.normalize()
+	if !*req.Active || req.EndedOn != "" || req.Nome != school.Nome {
+		t.Errorf("reativação = %+v", req)
+	}
+
+	req, err = patchSchoolRequest(schoolRequestFrom(testSchool()), map[string]any{"dre": "DRE Castanhal", "turnos": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.normalize()
+	if req.Dre != "DRE Castanhal" || req.Turnos != nil || req.INEP != "15012345" {
+		t.Errorf("patch = %+v", req)
+	}
+
+	if _, err := patchSchoolRequest(schoolRequestFrom(testSchool()), map[string]any{"active": "sim"}); err == nil {
+		t.Error("active com tipo inválido aceito")
+	}
+}
+
+func TestAdminSchoolInvalidID(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	for _, h := range []http.HandlerFunc{app.AdminGetSchool, app.AdminPutSchool, app.AdminDeleteSchool, app.AdminMergeSchool} {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "abc")
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/schools/abc", nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d", rec.Code)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"censo-api/internal/models"
+	"censo-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// =====================================================================
+// Fila de sincronização (sync_outbox)
+// =====================================================================
+// O envio do censo entra em sync_outbox na mesma transação que o grava
+// (CensusModel.Upsert), um item por destino habilitado (sync_sinks, ver
+// sync_sinks.go); a revisão da DRE também enfileira, para o destino
+// acompanhar o status. Nada depende de uma goroutine sobreviver. O worker
+// reserva os itens vencidos e chama o destino: Append na primeira vez,
+// Update depois e Delete quando o censo voltou para a escola. Uma falha
+// reagenda o item com backoff exponencial (1 min, 2 min, 4 min... até
+// 6 h); esgotadas SYNC_OUTBOX_MAX_ATTEMPTS tentativas (padrão 10) o item
+// vai para 'dead' e só volta com retry manual.
+//
+//   - GET  /v1/admin/sync/outbox?status=      itens travados (padrão) ou
+//     de um estado, com a contagem por estado
+//   - POST /v1/admin/sync/outbox/{id}/retry   volta para a fila, tentativas
+//     zeradas
+//   - POST /v1/admin/sync/outbox/{id}/discard tira da fila
+//
+// POST /v1/admin/sync-sheets continua disponível: enfileira o que faltar
+// em cada destino, antecipa os pendentes e processa a fila na hora.
+//
+// O envio atualiza a linha da escola e ano em Base_dados e nas abas de
+// déficit em vez de acrescentar outra (chave INEP/ano, sheets_rows.go). As
+// duplicatas deixadas pelo append antigo saem uma vez com
+//
+//   - POST /v1/admin/sync-sheets/dedup?dry_run=true   mostra o que sairia
+//   - POST /v1/admin/sync-sheets/dedup                remove
+// =====================================================================
+
+const (
+	outboxPollInterval       = 30 * time.Second
+	outboxBatchSize          = 20
+	outboxStaleAfter         = 10 * time.Minute
+	outboxBaseBackoff        = time.Minute
+	outboxMaxBackoff         = 6 * time.Hour
+	defaultOutboxMaxAttempts = 10
+	outboxListDefaultLimit   = 100
+	outboxListMaxLimit       = 500
+	maxOutboxErrorLen        = 1000
+)
+
+// outboxErrorText é o erro gravado em last_error: UTF-8 válido (o Postgres
+// recusa o texto de outro modo) e até maxOutboxErrorLen runas.
+func outboxErrorText(err error) string {
+	return truncateRunes(strings.ToValidUTF8(err.Error(), "?"), maxOutboxErrorLen)
+}
+
+// outboxStuck é o filtro padrão da listagem: 'dead' e pendentes que já
+// falharam.
+const outboxStuck = "stuck"
+
+var outboxListStatuses = map[string]bool{
+	outboxStuck:             true,
+	models.OutboxPending:    true,
+	models.OutboxProcessing: true,
+	models.OutboxDone:       true,
+	models.OutboxDead:       true,
+	models.OutboxDiscarded:  true,
+}
+
+// outboxMaxAttempts lê SYNC_OUTBOX_MAX_ATTEMPTS; valor ausente ou inválido
+// usa o padrão.
+func outboxMaxAttempts() int {
+	v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("SYNC_OUTBOX_MAX_ATTEMPTS")))
+	if err != nil || v < 1 {
+		return defaultOutboxMaxAttempts
+	}
+	return v
+}
+
+// outboxBackoff é a espera após a tentativa de número attempts (a partir
+// de 1): o dobro da anterior, limitada a outboxMaxBackoff.
+func outboxBackoff(attempts int) time.Duration {
+	d := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return d
+}
+
+// outboxRetryAt decide o destino de um item que falhou na tentativa
+// attempts: nova tentativa em now+backoff ou 'dead'.
+func outboxRetryAt(now time.Time, attempts, maxAttempts int) (time.Time, bool) {
+	if attempts >= maxAttempts {
+		return now, true
+	}
+	return now.Add(outboxBackoff(attempts)), false
+}
+
+// wakeSyncOutbox acorda o worker sem esperar o próximo ciclo. Não bloqueia:
+// com um aviso já pendente, este é descartado.
+func (app *application) wakeSyncOutbox() {
+	select {
+	case app.syncWake <- struct{}{}:
+	default:
+	}
+}
+
+// syncOutboxJob processa a fila a cada outboxPollInterval ou quando
+// acordado por um envio.
+func (app *application) syncOutboxJob() {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-app.syncWake:
+		}
+		done, failed, err := app.processSyncOutbox(context.Background())
+		if err != nil {
+			app.logger.Printf("syncOutbox: %v", err)
+		}
+		if done > 0 || failed > 0 {
+			app.logger.Printf("syncOutbox: %d enviado(s), %d falha(s)", done, failed)
+		}
+	}
+}
+
+// processSyncOutbox esvazia os itens vencidos da fila. Sem destino
+// configurado, os itens aguardam.
+func (app *application) processSyncOutbox(ctx context.Context) (done, failed int, err error) {
+	if len(app.sinks) == 0 {
+		return 0, 0, nil
+	}
+	maxAttempts := outboxMaxAttempts()
+	for {
+		items, err := app.models.SyncOutbox.Claim(ctx, outboxBatchSize, outboxStaleAfter)
+		if err != nil {
+			return done, failed, err
+		}
+		for _, it := range items {
+			op, err := app.deliverOutboxItem(ctx, it)
+			if err != nil {
+				failed++
+				next, dead := outboxRetryAt(time.Now(), it.Attempts, maxAttempts)
+				app.logger.Printf("syncOutbox: item %d (censo %d, %s) tentativa %d: %v",
+					it.ID, it.CensusID, it.Target, it.Attempts, err)
+				if e := app.models.SyncOutbox.Fail(ctx, it.ID, outboxErrorText(err), next, dead); e != nil {
+					app.logger.Printf("syncOutbox: registrando falha do item %d: %v", it.ID, e)
+				}
+				continue
+			}
+			if err := app.models.SyncOutbox.Complete(ctx, it, op); err != nil {
+				failed++
+				app.logger.Printf("syncOutbox: concluindo item %d: %v", it.ID, err)
+				continue
+			}
+			done++
+		}
+		if len(items) < outboxBatchSize {
+			return done, failed, nil
+		}
+	}
+}
+
+// outboxOp escolhe a operação do envio: o censo devolvido à escola sai
+// do destino; o enviado entra (append) ou é atualizado (update) conforme o
+// último envio concluído o tenha deixado lá.
+func outboxOp(status string, delivered bool) string {
+	switch {
+	case !models.CensusSent(status):
+		return models.OutboxOpDelete
+	case delivered:
+		return models.OutboxOpUpdate
+	default:
+		return models.OutboxOpAppend
+	}
+}
+
+// deliverOutboxItem faz o envio do item com o censo e a escola lidos agora
+// e devolve a operação feita.
+func (app *application) deliverOutboxItem(ctx context.Context, it models.OutboxItem) (string, error) {
+	sink, ok := app.sinks[it.Kind]
+	if !ok || sink.Target() != it.Target {
+		return "", fmt.Errorf("destino não configurado: %s %s", it.Kind, it.Target)
+	}
+	censo, err := app.models.Census.GetByID(ctx, it.CensusID)
+	if err != nil {
+		return "", err
+	}
+	if censo == nil {
+		return "", fmt.Errorf("censo %d não encontrado", it.CensusID)
+	}
+	school, err := app.models.Schools.Get(censo.SchoolID)
+	if err != nil {
+		return "", fmt.Errorf("escola %d: %w", censo.SchoolID, err)
+	}
+	merged, err := app.models.Schools.MergedInto(ctx, school.ID)
+	if err != nil {
+		return "", fmt.Errorf("escolas fundidas em %d: %w", school.ID, err)
+	}
+	rec := services.NewCensusRecord(*censo, *school, merged)
+	op := outboxOp(censo.Status, it.Delivered)
+	switch op {
+	case models.OutboxOpAppend:
+		err = sink.Append(ctx, rec)
+	case models.OutboxOpUpdate:
+		err = sink.Update(ctx, rec)
+	default:
+		err = sink.Delete(ctx, rec)
+	}
+	return op, err
+}
+
+// outboxID lê o {id} da rota.
+func outboxID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("id inválido")
+	}
+	return id, nil
+}
+
+// AdminListSyncOutbox lista os itens da fila: por padrão os travados
+// ('dead' e pendentes com falha), ou os de ?status=.
+func (app *application) AdminListSyncOutbox(w http.ResponseWriter, r *http.Request) {
+	status := strings.TrimSpace(r.URL.Query().Get("status"))
+	if status == "" {
+		status = outboxStuck
+	}
+	if !outboxListStatuses[status] {
+		app.errorJSON(w, fmt.Errorf("status inválido: %s", status), http.StatusBadRequest)
+		return
+	}
+	limit := outboxListDefaultLimit
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > outboxListMaxLimit {
+			app.errorJSON(w, fmt.Errorf("limit deve estar entre 1 e %d", outboxListMaxLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	filter := status
+	if filter == outboxStuck {
+		filter = ""
+	}
+	items, err := app.models.SyncOutbox.List(r.Context(), filter, limit)
+	if err != nil {
+		app.logger.Printf("AdminListSyncOutbox: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar a fila de sincronização"), http.StatusInternalServerError)
+		return
+	}
+	counts, err := app.models.SyncOutbox.Counts(r.Context())
+	if err != nil {
+		app.logger.Printf("AdminListSyncOutbox: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar a fila de sincronização"), http.StatusInternalServerError)
+		return
+	}
+	sinks, err := app.models.SyncOutbox.Sinks(r.Context())
+	if err != nil {
+		app.logger.Printf("AdminListSyncOutbox: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao consultar a fila de sincronização"), http.StatusInternalServerError)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Data: map[string]any{
+		"status":       status,
+		"max_attempts": outboxMaxAttempts(),
+		"counts":       counts,
+		"sinks":        sinks,
+		"items":        items,
+	}})
+}
+
+// AdminRetrySyncOutbox devolve um item 'dead', 'discarded' ou pendente para
+// a fila com as tentativas zeradas e acorda o worker.
+func (app *application) AdminRetrySyncOutbox(w http.ResponseWriter, r *http.Request) {
+	id, err := outboxID(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.outboxAction(w, "AdminRetrySyncOutbox", app.models.SyncOutbox.Retry(r.Context(), id)) {
+		return
+	}
+	app.wakeSyncOutbox()
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "Item devolvido para a fila"})
+}
+
+// AdminDiscardSyncOutbox tira da fila um item pendente ou 'dead'.
+func (app *application) AdminDiscardSyncOutbox(w http.ResponseWriter, r *http.Request) {
+	id, err := outboxID(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.outboxAction(w, "AdminDiscardSyncOutbox", app.models.SyncOutbox.Discard(r.Context(), id)) {
+		return
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: "Item descartado"})
+}
+
+// outboxAction traduz o erro de retry/discard na resposta; devolve true
+// quando não houve erro.
+func (app *application) outboxAction(w http.ResponseWriter, handler string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, sql.ErrNoRows):
+		app.errorJSON(w, fmt.Errorf("item não encontrado"), http.StatusNotFound)
+	case errors.Is(err, models.ErrOutboxState):
+		app.errorJSON(w, fmt.Errorf("o item já foi enviado ou está em envio"), http.StatusConflict)
+	case errors.Is(err, models.ErrOutboxDuplicate):
+		app.errorJSON(w, fmt.Errorf("já existe item pendente para o mesmo censo e destino"), http.StatusConflict)
+	default:
+		app.logger.Printf("%s: %v", handler, err)
+		app.errorJSON(w, fmt.Errorf("erro ao atualizar a fila de sincronização"), http.StatusInternalServerError)
+	}
+	return false
+}
+
+// AdminDedupSheets remove as linhas duplicadas da planilha (ver
+// SheetsService.DedupSheets); com ?dry_run=true só lista o que sairia.
+func (app *application) AdminDedupSheets(w http.ResponseWriter, r *http.Request) {
+	if app.sheets == nil {
+		app.errorJSON(w, fmt.Errorf("planilha não configurada"), http.StatusServiceUnavailable)
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	out, err := app.sheets.DedupSheets(dryRun)
+	if err != nil {
+		app.logger.Printf("AdminDedupSheets: %v", err)
+		app.errorJSON(w, fmt.Errorf("erro ao remover duplicatas da planilha"), http.StatusInternalServerError)
+		return
+	}
+	removed := 0
+	for _, d := range out {
+		removed += len(d.Removed)
+	}
+	msg := fmt.Sprintf("%d linha(s) duplicada(s) removida(s)", removed)
+	if dryRun {
+		msg = fmt.Sprintf("%d linha(s) duplicada(s) seriam removidas", removed)
+	}
+	app.writeJSON(w, http.StatusOK, jsonResponse{Error: false, Message: msg, Data: map[string]any{
+		"dry_run": dryRun,
+		"sheets":  out,
+	}})
+}
@@ -0,0 +1,229 @@
+package main
+
+// Testes da fila de sincronização com a planilha. Sem banco: cobrem o
+// backoff exponencial com teto, a passagem para 'dead' ao esgotar as
+// tentativas, a leitura de SYNC_OUTBOX_MAX_ATTEMPTS, o aviso ao worker que
+// não bloqueia, a operação de cada envio (append, update, delete), a
+// validação de status, limit e id nas rotas admin, a chave das linhas da
+// planilha, a escolha das duplicatas a remover e o texto do erro gravado.
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"censo-api/internal/models"
+	"censo-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, outboxMaxBackoff},
+		{50, outboxMaxBackoff},
+	} {
+		if got := outboxBackoff(tc.attempts); got != tc.want {
+			t.Errorf("outboxBackoff(%d) = %v; want %v", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+func TestOutboxRetryAt(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	next, dead := outboxRetryAt(now, 3, 10)
+	if dead || !next.Equal(now.Add(4*time.Minute)) {
+		t.Errorf("tentativa 3 = %v, dead=%v", next, dead)
+	}
+	if _, dead := outboxRetryAt(now, 10, 10); !dead {
+		t.Error("última tentativa não foi para dead")
+	}
+}
+
+func TestOutboxErrorText(t *testing.T) {
+	// "ç" ocupa 2 bytes: cortar por bytes no limite partiria o caractere.
+	long := strings.Repeat("a", maxOutboxErrorLen-1) + "çç"
+	got := outboxErrorText(errors.New(long))
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != maxOutboxErrorLen {
+		t.Errorf("texto inválido ou fora do limite: %d runas", utf8.RuneCountInString(got))
+	}
+	if got := outboxErrorText(errors.New("sheets: \xff\xfe resposta")); !utf8.ValidString(got) {
+		t.Errorf("bytes inválidos mantidos: %q", got)
+	}
+}
+
+func TestOutboxMaxAttempts(t *testing.T) {
+	for _, tc := range []struct {
+		env  string
+		want int
+	}{
+		{"", defaultOutboxMaxAttempts},
+		{"5", 5},
+		{" 3 ", 3},
+		{"0", defaultOutboxMaxAttempts},
+		{"abc", defaultOutboxMaxAttempts},
+	} {
+		t.Setenv("SYNC_OUTBOX_MAX_ATTEMPTS", tc.env)
+		if got := outboxMaxAttempts(); got != tc.want {
+			t.Errorf("SYNC_OUTBOX_MAX_ATTEMPTS=%q: %d; want %d", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestWakeSyncOutboxDoesNotBlock(t *testing.T) {
+	app := &application{syncWake: make(chan struct{}, 1)}
+	app.wakeSyncOutbox()
+	app.wakeSyncOutbox()
+	if len(app.syncWake) != 1 {
+		t.Errorf("avisos pendentes = %d", len(app.syncWake))
+	}
+	// Sem canal (testes, ferramentas) o aviso é descartado.
+	(&application{}).wakeSyncOutbox()
+}
+
+func TestAdminListSyncOutboxValidation(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	for _, q := range []string{"?status=travado", "?limit=0", "?limit=501", "?limit=x"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/sync/outbox"+q, nil)
+		rec := httptest.NewRecorder()
+		app.AdminListSyncOutbox(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", q, rec.Code)
+		}
+	}
+}
+
+func TestAdminSyncOutboxInvalidID(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	for _, h := range []http.HandlerFunc{app.AdminRetrySyncOutbox, app.AdminDiscardSyncOutbox} {
+		for _, id := range []string{"abc", "0", "-1"} {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id)
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/sync/outbox/"+id+"/retry", nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+			h(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("id %q: status = %d", id, rec.Code)
+			}
+		}
+	}
+}
+
+func TestSheetKey(t *testing.T) {
+	if got := services.SheetKey(models.School{ID: 7, INEP: " 15012345 "}, 2026); got != "escola:7/2026" {
+		t.Errorf("SheetKey = %q", got)
+	}
+	if got := services.SheetKey(models.School{ID: 7}, 2026); got != "escola:7/2026" {
+		t.Errorf("SheetKey sem INEP = %q", got)
+	}
+}
+
+// As chaves antigas cobrem o formato INEP/ano e as escolas fundidas; a
+// chave atual não se repete entre elas.
+func TestNewCensusRecordOldKeys(t *testing.T) {
+	rec := services.NewCensusRecord(models.CensusResponse{Year: 2026},
+		models.School{ID: 7, INEP: " 15012345 "},
+		[]models.MergedSchool{{ID: 3, INEP: "15000003"}, {ID: 4}, {ID: 7}})
+	want := []string{"15012345/2026", "escola:3/2026", "15000003/2026", "escola:4/2026"}
+	if rec.Key != "escola:7/2026" || !slices.Equal(rec.OldKeys, want) {
+		t.Errorf("Key = %q, OldKeys = %v; want %v", rec.Key, rec.OldKeys, want)
+	}
+	if !rec.HasKey("escola:3/2026") || rec.HasKey("") || rec.HasKey("15012345/2025") {
+		t.Errorf("HasKey: %+v", rec)
+	}
+	if rec := services.NewCensusRecord(models.CensusResponse{Year: 2026}, models.School{ID: 7}, nil); len(rec.OldKeys) != 0 {
+		t.Errorf("escola sem INEP: OldKeys = %v", rec.OldKeys)
+	}
+}
+
+func TestPlanSheetDedup(t *testing.T) {
+	rows := []services.SheetRow{
+		{Row: 2, INEP: "15000001"},                       // sem chave (2025), INEP tem linha de 2026
+		{Row: 3, INEP: "15000002"},                       // sem chave, repetida abaixo
+		{Row: 4, INEP: "15000002"},                       // fica
+		{Row: 5, Key: "15000001/2026", INEP: "15000001"}, // repetida abaixo
+		{Row: 6, Key: "15000001/2025", INEP: "15000001"}, // outro ano: fica
+		{Row: 7, Key: "15000001/2026", INEP: "15000001"}, // fica
+		{Row: 8, INEP: "15000003"},                       // única: fica
+	}
+	assertRemoved := func(got []services.SheetRow, want ...int) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("removidas = %+v; want linhas %v", got, want)
+		}
+		for i, r := range got {
+			if r.Row != want[i] {
+				t.Errorf("removida[%d] = linha %d; want %d", i, r.Row, want[i])
+			}
+		}
+	}
+	// Com ano de corte 2025, a linha 2 perde para a 15000001/2025.
+	assertRemoved(services.PlanSheetDedup(rows, 2025), 2, 3, 5)
+	// Sem ano de corte, linhas sem chave ficam.
+	assertRemoved(services.PlanSheetDedup(rows, 0), 5)
+	assertRemoved(services.PlanSheetDedup(rows[5:], 2025))
+}
+
+// Linhas sem chave de dois anos: a linha com chave de outro ano não as
+// remove, e sem ano de corte elas não são juntadas.
+func TestPlanSheetDedupLegacyTwoYears(t *testing.T) {
+	rows := []services.SheetRow{
+		{Row: 2, INEP: "15000001"}, // censo 2024
+		{Row: 3, INEP: "15000001"}, // censo 2025
+		{Row: 4, Key: "15000001/2026", INEP: "15000001"},
+	}
+	if got := services.PlanSheetDedup(rows, 0); len(got) != 0 {
+		t.Errorf("sem ano de corte removeu %+v", got)
+	}
+	if got := services.PlanSheetDedup(rows[1:], 2025); len(got) != 0 {
+		t.Errorf("linha de 2026 removeu a de 2025: %+v", got)
+	}
+	rows = append(rows, services.SheetRow{Row: 5, Key: "15000001/2025", INEP: "15000001"})
+	if got := services.PlanSheetDedup(rows[1:], 2025); len(got) != 1 || got[0].Row != 3 {
+		t.Errorf("linha de 2025 com chave: removidas %+v; want linha 3", got)
+	}
+}
+
+func TestAdminDedupSheetsWithoutSheets(t *testing.T) {
+	app := &application{logger: log.New(io.Discard, "", 0)}
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/sync-sheets/dedup?dry_run=true", nil)
+	rec := httptest.NewRecorder()
+	app.AdminDedupSheets(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d", rec.Code)
+	}
+}
+
+func TestOutboxOp(t *testing.T) {
+	for _, tc := range []struct {
+		status    string
+		delivered bool
+		want      string
+	}{
+		{models.CensusStatusSubmitted, false, models.OutboxOpAppend},
+		{models.CensusStatusSubmitted, true, models.OutboxOpUpdate},
+		{models.CensusStatusApproved, true, models.OutboxOpUpdate},
+		{models.CensusStatusReturned, true, models.OutboxOpDelete},
+		{models.CensusStatusDraft, false, models.OutboxOpDelete},
+	} {
+		if got := outboxOp(tc.status, tc.delivered); got != tc.want {
+			t.Errorf("outboxOp(%q, %v) = %q; want %q", tc.status, tc.delivered, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"censo-api/internal/models"
+	"censo-api/internal/services"
+)
+
+// =====================================================================
+// Destinos da sincronização do censo
+// =====================================================================
+// SYNC_SINKS lista os destinos (separados por vírgula) que recebem cada
+// censo enviado; o padrão é só a planilha.
+//
+//   - sheets   planilha do Google Sheets (SPREADSHEET_ID)
+//   - file     arquivo local: SYNC_FILE_DIR (padrão data/sync) e
+//     SYNC_FILE_FORMAT jsonl (eventos, padrão) ou csv (uma linha por
+//     escola e ano)
+//   - webhook  POST JSON para SYNC_WEBHOOK_URL, assinado com
+//     SYNC_WEBHOOK_SECRET quando definido
+//
+// No startup os destinos montados são gravados em sync_sinks e os demais
+// desabilitados: as réplicas da API devem ter a mesma configuração. Cada
+// destino tem seus próprios itens em sync_outbox, então uma falha no
+// webhook não atrasa a planilha.
+// =====================================================================
+
+const (
+	defaultSyncSinks      = models.OutboxKindSheets
+	defaultSyncFileDir    = "data/sync"
+	defaultSyncFileFormat = services.FileSinkJSONL
+)
+
+// newSyncSinks monta os destinos de SYNC_SINKS. Destino desconhecido ou
+// mal configurado fica de fora e volta como aviso.
+func newSyncSinks(sheets *services.SheetsService) ([]services.CensusSink, []string) {
+	raw := strings.TrimSpace(os.Getenv("SYNC_SINKS"))
+	if raw == "" {
+		raw = defaultSyncSinks
+	}
+	var sinks []services.CensusSink
+	var warnings []string
+	seen := map[string]bool{}
+	for _, kind := range strings.Split(raw, ",") {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" || seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		switch kind {
+		case models.OutboxKindSheets:
+			if sheets == nil {
+				warnings = append(warnings, "sync sheets: planilha não configurada")
+				continue
+			}
+			sinks = append(sinks, sheets)
+		case models.OutboxKindFile:
+			dir := strings.TrimSpace(os.Getenv("SYNC_FILE_DIR"))
+			if dir == "" {
+				dir = defaultSyncFileDir
+			}
+			format := strings.ToLower(strings.TrimSpace(os.Getenv("SYNC_FILE_FORMAT")))
+			if format == "" {
+				format = defaultSyncFileFormat
+			}
+			fs, err := services.NewFileSink(dir, format)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("sync file: %v", err))
+				continue
+			}
+			sinks = append(sinks, fs)
+		case models.OutboxKindWebhook:
+			hook, err := services.NewWebhookSink(strings.TrimSpace(os.Getenv("SYNC_WEBHOOK_URL")), os.Getenv("SYNC_WEBHOOK_SECRET"))
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("sync webhook: %v", err))
+				continue
+			}
+			sinks = append(sinks, hook)
+		default:
+			warnings = append(warnings, fmt.Sprintf("SYNC_SINKS: destino desconhecido %q", kind))
+		}
+	}
+	return sinks, warnings
+}
+
+// setupSyncSinks monta os destinos, registra-os em sync_sinks e os deixa
+// disponíveis para o worker.
+func (app *application) setupSyncSinks() error {
+	sinks, warnings := newSyncSinks(app.sheets)
+	for _, w := range warnings {
+		app.logger.Printf("AVISO: %s", w)
+	}
+	app.sinks = make(map[string]services.CensusSink, len(sinks))
+	refs := make([]models.SyncSink, len(sinks))
+	for i, s := range sinks {
+		app.sinks[s.Kind()] = s
+		refs[i] = models.SyncSink{Kind: s.Kind(), Target: s.Target()}
+		app.logger.Printf("Destino de sincronização: %s → %s", s.Kind(), s.Target())
+	}
+	return app.models.SyncOutbox.RegisterSinks(context.Background(), refs)
+}
@@ -0,0 +1,201 @@
+package main
+
+// Testes dos destinos da sincronização. Sem banco e sem Google: cobrem a
+// leitura de SYNC_SINKS, o arquivo JSONL (um evento por operação), o CSV
+// (uma linha por escola e ano, atualizada e removida no lugar) e o webhook
+// (assinatura, evento e falha em resposta fora de 2xx).
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"censo-api/internal/models"
+	"censo-api/internal/services"
+)
+
+func sinkRecord(schoolID int, inep string, year int, data string) services.CensusRecord {
+	return services.NewCensusRecord(
+		models.CensusResponse{ID: 1, Year: year, Status: models.CensusStatusSubmitted, Data: json.RawMessage(data)},
+		models.School{ID: schoolID, INEP: inep, Nome: "EEEM Teste", Dre: "DRE Belém 1", Municipio: "Belém"},
+		nil,
+	)
+}
+
+func TestNewSyncSinks(t *testing.T) {
+	t.Setenv("SYNC_SINKS", "")
+	sinks, warnings := newSyncSinks(nil)
+	if len(sinks) != 0 || len(warnings) != 1 {
+		t.Errorf("padrão sem planilha: sinks=%d avisos=%v", len(sinks), warnings)
+	}
+
+	t.Setenv("SYNC_SINKS", " file , webhook, ftp, file")
+	t.Setenv("SYNC_FILE_DIR", t.TempDir())
+	t.Setenv("SYNC_FILE_FORMAT", "CSV")
+	t.Setenv("SYNC_WEBHOOK_URL", "https://user:pw@exemplo.gov.br/censo?token=x")
+	sinks, warnings = newSyncSinks(nil)
+	if len(sinks) != 2 || len(warnings) != 1 {
+		t.Fatalf("sinks=%d avisos=%v", len(sinks), warnings)
+	}
+	if sinks[0].Kind() != models.OutboxKindFile || filepath.Ext(sinks[0].Target()) != ".csv" {
+		t.Errorf("file: %s %s", sinks[0].Kind(), sinks[0].Target())
+	}
+	if got := sinks[1].Target(); got != "https://exemplo.gov.br/censo" {
+		t.Errorf("webhook target = %q", got)
+	}
+
+	t.Setenv("SYNC_SINKS", "webhook")
+	t.Setenv("SYNC_WEBHOOK_URL", "ftp://exemplo")
+	if sinks, warnings = newSyncSinks(nil); len(sinks) != 0 || len(warnings) != 1 {
+		t.Errorf("URL inválida: sinks=%d avisos=%v", len(sinks), warnings)
+	}
+}
+
+func TestFileSinkJSONL(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := services.NewFileSink(dir, services.FileSinkJSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	rec := sinkRecord(9, "15000001", 2026, `{"total_alunos": 10}`)
+	if err := sink.Append(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Update(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Delete(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "censos.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ops []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ev map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev["chave_censo"] != "escola:9/2026" {
+			t.Errorf("chave = %v", ev["chave_censo"])
+		}
+		_, hasData := ev["dados"]
+		if op := ev["op"].(string); hasData == (op == "delete") {
+			t.Errorf("%s: dados presentes = %v", op, hasData)
+		}
+		ops = append(ops, ev["op"].(string))
+	}
+	if len(ops) != 3 || ops[0] != "append" || ops[1] != "update" || ops[2] != "delete" {
+		t.Errorf("ops = %v", ops)
+	}
+}
+
+func TestFileSinkCSV(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := services.NewFileSink(dir, services.FileSinkCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	a := sinkRecord(9, "15000001", 2026, `{"v": 1}`)
+	b := sinkRecord(10, "15000002", 2026, `{"v": 1}`)
+	// Linha gravada com a chave no formato INEP/ano: o envio a substitui.
+	old := a
+	old.Key, old.OldKeys = "15000001/2026", nil
+	for _, step := range []func() error{
+		func() error { return sink.Append(ctx, old) },
+		func() error { return sink.Append(ctx, a) },
+		func() error { return sink.Append(ctx, b) },
+		func() error { return sink.Update(ctx, sinkRecord(9, "15000001", 2026, `{"v": 2}`)) },
+		func() error { return sink.Update(ctx, a) }, // repetição de tentativa
+		func() error { return sink.Delete(ctx, b) },
+		func() error { return sink.Delete(ctx, b) },
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(dir, "censos.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0][0] != "chave_censo" || rows[1][0] != "escola:9/2026" {
+		t.Fatalf("linhas = %v", rows)
+	}
+	if got := rows[1][len(rows[1])-1]; got != `{"v": 1}` {
+		t.Errorf("dados = %q", got)
+	}
+	if _, err := services.NewFileSink(dir, "xml"); err == nil {
+		t.Error("formato inválido aceito")
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var gotEvent, gotSig string
+	var gotBody []byte
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEvent = r.Header.Get("X-Censo-Event")
+		gotSig = r.Header.Get("X-Censo-Signature")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	hook, err := services.NewWebhookSink(srv.URL, "segredo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := sinkRecord(9, "15000001", 2026, `{"total_alunos": 10}`)
+	if err := hook.Update(context.Background(), rec); err != nil {
+		t.Fatal(err)
+	}
+	if gotEvent != "census.update" {
+		t.Errorf("evento = %q", gotEvent)
+	}
+	if want := services.WebhookSignature([]byte("segredo"), gotBody); gotSig != want {
+		t.Errorf("assinatura = %q; want %q", gotSig, want)
+	}
+	var p map[string]any
+	if err := json.Unmarshal(gotBody, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p["chave_censo"] != "escola:9/2026" || p["dados"] == nil {
+		t.Errorf("corpo = %s", gotBody)
+	}
+
+	status = http.StatusBadGateway
+	if err := hook.Delete(context.Background(), rec); err == nil {
+		t.Error("502 não virou falha")
+	}
+
+	// Falha de conexão não expõe a URL (nem o token da query) no erro.
+	srv.Close()
+	hook, err = services.NewWebhookSink(srv.URL+"/hook?token=segredo-da-url", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = hook.Update(context.Background(), rec)
+	if err == nil || strings.Contains(err.Error(), "segredo-da-url") || strings.Contains(err.Error(), srv.URL) {
+		t.Errorf("erro = %v", err)
+	}
+}
@@ -1,4 +1,5 @@
-// genpasswd gera o hash bcrypt da senha do admin para uso na variável ADMIN_PASSWORD_HASH.
+// genpasswd gera o hash bcrypt da senha do admin para uso na variável
+// ADMIN_PASSWORD_HASH (conta inicial semeada em admin_users no primeiro boot).
 // Uso: go run ./cmd/genpasswd <senha>
 package main
 
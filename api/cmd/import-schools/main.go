@@ -0,0 +1,657 @@
+// import-schools carrega o cadastro oficial de escolas estaduais (lista da
+// SEDUC ou exportação do Catálogo de Escolas INEP/Educacenso, em CSV ou
+// XLSX) na tabela schools, para que o painel conheça todas as escolas — e
+// não só as que já abriram o formulário.
+//
+// Uso (a partir da pasta api/):
+//
+//	go run ./cmd/import-schools --file ../_local/escolas_estaduais.xlsx --dry-run
+//	go run ./cmd/import-schools --file ../_local/escolas_estaduais.xlsx
+//
+// Regras:
+//   - codigo_inep é a chave: escola nova é inserida, existente é atualizada
+//     só nos campos que o arquivo traz preenchidos (nome, município, DRE,
+//     zona, endereço, telefone, CEP, e-mail). Dados do diretor, turnos e
+//     etapas, preenchidos pelo formulário, nunca são tocados.
+//   - Com a coluna de dependência administrativa, só entram as estaduais;
+//     com a de situação de funcionamento, só as em atividade. As demais
+//     linhas são listadas como ignoradas.
+//   - Escolas fundidas em outra (merged_into) não são atualizadas; o INEP
+//     de uma fundida (codigo_inep_fundido) que não passou à sobrevivente
+//     também não gera escola nova.
+//   - Escolas do cadastro ausentes no arquivo são apenas relatadas: a
+//     desativação é decisão do painel (DELETE /v1/admin/schools/{id}).
+//   - O dry-run mostra o mesmo relatório sem gravar. A carga real grava o
+//     lote em school_import_batches numa única transação.
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/joho/godotenv"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// columnAliases mapeia cada campo aos nomes de coluna aceitos, já
+// normalizados por headerKey (minúsculas, sem acento, "_" no lugar de
+// espaços). Cobre o layout do projeto e o do Catálogo de Escolas do INEP.
+var columnAliases = map[string][]string{
+	"codigo_inep": {"codigo_inep", "inep", "codigo_da_escola", "co_entidade", "codigo_inep_da_escola"},
+	"nome_escola": {"nome_escola", "escola", "nome_da_escola", "no_entidade"},
+	"municipio":   {"municipio", "no_municipio", "nome_do_municipio"},
+	"dre":         {"dre", "dre_setor", "diretoria_regional", "ure", "regional"},
+	"zona":        {"zona", "localizacao", "tp_localizacao"},
+	"endereco":    {"endereco", "ds_endereco"},
+	"telefone":    {"telefone", "telefone_institucional", "nu_telefone"},
+	"cep":         {"cep", "co_cep"},
+	"email":       {"email", "e_mail", "ds_email"},
+	"dependencia": {"dependencia", "dependencia_administrativa", "tp_dependencia"},
+	"situacao":    {"situacao", "situacao_de_funcionamento", "restricao_de_atendimento", "tp_situacao_funcionamento"},
+}
+
+// requiredColumns precisam existir no cabeçalho.
+var requiredColumns = []string{"codigo_inep", "nome_escola", "municipio"}
+
+var inepPattern = regexp.MustCompile(`^\d{8}$`)
+
+type schoolRow struct {
+	line      int
+	inep      string
+	nome      string
+	municipio string
+	dre       string
+	zona      string
+	endereco  string
+	telefone  string
+	cep       string
+	email     string
+}
+
+// skippedRow é uma linha do arquivo que não entra na carga.
+type skippedRow struct {
+	Line   int    `json:"linha"`
+	INEP   string `json:"codigo_inep"`
+	Nome   string `json:"nome_escola"`
+	Reason string `json:"motivo"`
+}
+
+// dbSchool é o que a comparação precisa do cadastro atual.
+type dbSchool struct {
+	id        int
+	inep      string
+	nome      string
+	municipio string
+	dre       string
+	zona      string
+	endereco  string
+	telefone  string
+	cep       string
+	email     string
+	active    bool
+	merged    bool
+}
+
+// change descreve uma escola cuja DRE ou município mudou.
+type change struct {
+	SchoolID int    `json:"school_id"`
+	INEP     string `json:"codigo_inep"`
+	Nome     string `json:"nome_escola"`
+	Antes    string `json:"antes"`
+	Depois   string `json:"depois"`
+}
+
+// missingSchool é uma escola ativa do cadastro ausente no arquivo.
+type missingSchool struct {
+	SchoolID  int    `json:"school_id"`
+	INEP      string `json:"codigo_inep"`
+	Nome      string `json:"nome_escola"`
+	Municipio string `json:"municipio"`
+	Dre       string `json:"dre"`
+}
+
+// plan é o resultado da comparação do arquivo com o cadastro; também é o
+// relatório gravado no lote.
+type plan struct {
+	inserts   []schoolRow
+	updates   []schoolRow
+	unchanged int
+
+	Skipped          []skippedRow    `json:"ignoradas"`
+	Merged           []skippedRow    `json:"fundidas"`
+	Inactive         []change        `json:"inativas_no_cadastro"`
+	Inserted         []string        `json:"novas"`
+	DreChanged       []change        `json:"dre_alterada"`
+	MunicipioChanged []change        `json:"municipio_alterado"`
+	Missing          []missingSchool `json:"ausentes_no_arquivo"`
+}
+
+func main() {
+	var (
+		filePath = flag.String("file", "", "caminho do CSV ou XLSX com o cadastro (obrigatório)")
+		sheet    = flag.String("sheet", "", "aba do XLSX (padrão: a primeira)")
+		dryRun   = flag.Bool("dry-run", false, "mostra o relatório, mas não grava no banco")
+		dsnFlag  = flag.String("dsn", "", "DSN PostgreSQL (opcional; default = variáveis de ambiente)")
+	)
+	flag.Parse()
+
+	if err := run(*filePath, *sheet, *dryRun, *dsnFlag); err != nil {
+		fmt.Fprintln(os.Stderr, "ERRO:", err)
+		os.Exit(1)
+	}
+}
+
+func run(filePath, sheet string, dryRun bool, dsnFlag string) error {
+	if filePath == "" {
+		return errors.New("--file é obrigatório")
+	}
+
+	raw, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("lendo arquivo: %w", err)
+	}
+	hash := sha256.Sum256(raw)
+	sourceHash := hex.EncodeToString(hash[:])
+
+	records, err := readRecords(raw, filepath.Ext(filePath), sheet)
+	if err != nil {
+		return err
+	}
+	rows, skipped, err := parseRows(records)
+	if err != nil {
+		return err
+	}
+
+	dsn := resolveDSN(dsnFlag)
+	if dsn == "" {
+		if !dryRun {
+			return errors.New("DSN não encontrado: informe --dsn ou DATABASE_URL/DB_DSN/DB_HOST no ambiente")
+		}
+		fmt.Fprintln(os.Stderr, "AVISO: dry-run sem DSN — arquivo validado, sem comparação com o cadastro.")
+		fmt.Println("Arquivo:", filePath)
+		fmt.Println("Escolas válidas:", len(rows))
+		fmt.Println("Linhas ignoradas:", len(skipped))
+		printSkipped(skipped)
+		return nil
+	}
+	db, err := openDB(dsn)
+	if err != nil {
+		return fmt.Errorf("conectando ao banco: %w", err)
+	}
+	defer db.Close()
+
+	existing, err := loadSchools(db)
+	if err != nil {
+		return err
+	}
+	p := diff(rows, existing)
+	p.Skipped = skipped
+
+	var batchID int64
+	if !dryRun {
+		batchID, err = importPlan(db, p, len(rows)+len(skipped), filepath.Base(filePath), sourceHash)
+		if err != nil {
+			return err
+		}
+	}
+	printSummary(filePath, p, dryRun, batchID)
+	return nil
+}
+
+// readRecords lê as linhas do arquivo: XLSX pela aba informada (ou a
+// primeira), CSV com separador vírgula ou ponto e vírgula.
+func readRecords(raw []byte, ext, sheet string) ([][]string, error) {
+	switch strings.ToLower(ext) {
+	case ".xlsx":
+		f, err := excelize.OpenReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("abrindo XLSX: %w", err)
+		}
+		defer f.Close()
+		if sheet == "" {
+			sheet = f.GetSheetName(0)
+		}
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return nil, fmt.Errorf("lendo aba %q: %w", sheet, err)
+		}
+		return rows, nil
+	case ".csv", ".txt":
+		text := strings.TrimPrefix(string(raw), "\uFEFF")
+		r := csv.NewReader(strings.NewReader(text))
+		r.FieldsPerRecord = -1
+		first, _, _ := strings.Cut(text, "\n")
+		if strings.Count(first, ";") > strings.Count(first, ",") {
+			r.Comma = ';'
+		}
+		var out [][]string
+		for {
+			rec, err := r.Read()
+			if err == io.EOF {
+				return out, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("lendo CSV: %w", err)
+			}
+			out = append(out, rec)
+		}
+	default:
+		return nil, fmt.Errorf("formato %q não suportado; use .csv ou .xlsx", ext)
+	}
+}
+
+// parseRows valida o cabeçalho e converte as linhas. Linhas fora do recorte
+// (não estaduais, paralisadas/extintas) e inválidas voltam em skipped; INEP
+// repetido no arquivo aborta a carga.
+func parseRows(records [][]string) ([]schoolRow, []skippedRow, error) {
+	if len(records) == 0 {
+		return nil, nil, errors.New("arquivo vazio")
+	}
+	idx := map[string]int{}
+	for i, name := range records[0] {
+		key := headerKey(name)
+		for field, aliases := range columnAliases {
+			for _, a := range aliases {
+				if _, seen := idx[field]; !seen && key == a {
+					idx[field] = i
+				}
+			}
+		}
+	}
+	var missing []string
+	for _, col := range requiredColumns {
+		if _, ok := idx[col]; !ok {
+			missing = append(missing, col)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("colunas obrigatórias ausentes no cabeçalho: %s", strings.Join(missing, ", "))
+	}
+
+	get := func(rec []string, field string) string {
+		i, ok := idx[field]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.Join(strings.Fields(rec[i]), " ")
+	}
+
+	var rows []schoolRow
+	var skipped []skippedRow
+	seen := map[string]int{}
+	for n, rec := range records[1:] {
+		line := n + 2
+		r := schoolRow{
+			line:      line,
+			inep:      get(rec, "codigo_inep"),
+			nome:      get(rec, "nome_escola"),
+			municipio: get(rec, "municipio"),
+			dre:       get(rec, "dre"),
+			zona:      normalizeZona(get(rec, "zona")),
+			endereco:  get(rec, "endereco"),
+			telefone:  get(rec, "telefone"),
+			cep:       get(rec, "cep"),
+			email:     get(rec, "email"),
+		}
+		if r.inep == "" && r.nome == "" {
+			continue
+		}
+		skip := func(reason string) {
+			skipped = append(skipped, skippedRow{Line: line, INEP: r.inep, Nome: r.nome, Reason: reason})
+		}
+		if dep := foldKey(get(rec, "dependencia")); dep != "" && dep != "estadual" && dep != "2" {
+			skip("dependência administrativa " + get(rec, "dependencia"))
+			continue
+		}
+		if sit := foldKey(get(rec, "situacao")); sit != "" && sit != "em atividade" && sit != "1" {
+			skip("situação " + get(rec, "situacao"))
+			continue
+		}
+		if !inepPattern.MatchString(r.inep) {
+			skip("codigo_inep inválido")
+			continue
+		}
+		if r.nome == "" || r.municipio == "" {
+			skip("nome ou município vazio")
+			continue
+		}
+		if prev, dup := seen[r.inep]; dup {
+			return nil, nil, fmt.Errorf("linha %d: codigo_inep %s repetido (linha %d)", line, r.inep, prev)
+		}
+		seen[r.inep] = line
+		rows = append(rows, r)
+	}
+	return rows, skipped, nil
+}
+
+// loadSchools lê o cadastro atual indexado por codigo_inep.
+func loadSchools(db *sql.DB) (map[string]dbSchool, error) {
+	rows, err := db.Query(`
+		SELECT id, COALESCE(codigo_inep, ''), COALESCE(nome_escola, ''), COALESCE(municipio, ''),
+		       COALESCE(dre, ''), COALESCE(zona, ''), COALESCE(endereco, ''), COALESCE(telefone, ''),
+		       COALESCE(cep, ''), COALESCE(email, ''), active, merged_into IS NOT NULL,
+		       COALESCE(codigo_inep_fundido, '')
+		FROM schools`)
+	if err != nil {
+		return nil, fmt.Errorf("lendo schools: %w", err)
+	}
+	defer rows.Close()
+	out := map[string]dbSchool{}
+	var fundidas []dbSchool
+	for rows.Next() {
+		var s dbSchool
+		var fundido string
+		if err := rows.Scan(&s.id, &s.inep, &s.nome, &s.municipio, &s.dre, &s.zona, &s.endereco,
+			&s.telefone, &s.cep, &s.email, &s.active, &s.merged, &fundido); err != nil {
+			return nil, fmt.Errorf("lendo schools: %w", err)
+		}
+		if s.inep != "" {
+			out[s.inep] = s
+		}
+		if s.merged && fundido != "" {
+			s.inep = fundido
+			fundidas = append(fundidas, s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// O INEP que passou à sobrevivente é dela.
+	for _, s := range fundidas {
+		if _, ok := out[s.inep]; !ok {
+			out[s.inep] = s
+		}
+	}
+	return out, nil
+}
+
+// diff compara o arquivo com o cadastro. Campo vazio no arquivo mantém o
+// valor atual; DRE e município comparam sem caixa, acento e prefixo "DRE".
+func diff(rows []schoolRow, existing map[string]dbSchool) *plan {
+	p := &plan{}
+	inFile := map[string]bool{}
+	for _, r := range rows {
+		inFile[r.inep] = true
+		s, ok := existing[r.inep]
+		if !ok {
+			p.inserts = append(p.inserts, r)
+			p.Inserted = append(p.Inserted, r.inep)
+			continue
+		}
+		if s.merged {
+			p.Merged = append(p.Merged, skippedRow{Line: r.line, INEP: r.inep, Nome: r.nome,
+				Reason: "escola fundida em outra no cadastro"})
+			continue
+		}
+		if !s.active {
+			p.Inactive = append(p.Inactive, change{SchoolID: s.id, INEP: s.inep, Nome: s.nome})
+		}
+		if r.dre != "" && dreKey(r.dre) != dreKey(s.dre) {
+			p.DreChanged = append(p.DreChanged, change{SchoolID: s.id, INEP: s.inep, Nome: s.nome, Antes: s.dre, Depois: r.dre})
+		}
+		if foldKey(r.municipio) != foldKey(s.municipio) {
+			p.MunicipioChanged = append(p.MunicipioChanged, change{SchoolID: s.id, INEP: s.inep, Nome: s.nome,
+				Antes: s.municipio, Depois: r.municipio})
+		}
+		if differs(r, s) {
+			p.updates = append(p.updates, r)
+		} else {
+			p.unchanged++
+		}
+	}
+	for _, s := range existing {
+		if s.active && !inFile[s.inep] {
+			p.Missing = append(p.Missing, missingSchool{SchoolID: s.id, INEP: s.inep, Nome: s.nome,
+				Municipio: s.municipio, Dre: s.dre})
+		}
+	}
+	sort.Slice(p.Missing, func(i, j int) bool {
+		a, b := p.Missing[i], p.Missing[j]
+		if a.Dre != b.Dre {
+			return a.Dre < b.Dre
+		}
+		return a.INEP < b.INEP
+	})
+	return p
+}
+
+// differs informa se algum campo preenchido no arquivo difere do cadastro.
+func differs(r schoolRow, s dbSchool) bool {
+	for _, f := range [][2]string{
+		{r.nome, s.nome}, {r.municipio, s.municipio}, {r.dre, s.dre}, {r.zona, s.zona},
+		{r.endereco, s.endereco}, {r.telefone, s.telefone}, {r.cep, s.cep}, {r.email, s.email},
+	} {
+		if f[0] != "" && f[0] != f[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// importPlan grava o lote e aplica inserções e atualizações numa única
+// transação.
+func importPlan(db *sql.DB, p *plan, rowsTotal int, sourceFile, sourceHash string) (int64, error) {
+	report, err := json.Marshal(p)
+	if err != nil {
+		return 0, fmt.Errorf("montando relatório: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("iniciando transação: %w", err)
+	}
+	defer tx.Rollback() //nolint — no-op após Commit
+
+	var batchID int64
+	err = tx.QueryRow(`
+		INSERT INTO school_import_batches
+		  (source_file, source_hash, rows_total, inserted, updated, unchanged, skipped,
+		   missing, dre_changed, municipio_changed, report, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`,
+		sourceFile, sourceHash, rowsTotal, len(p.inserts), len(p.updates), p.unchanged,
+		len(p.Skipped)+len(p.Merged), len(p.Missing), len(p.DreChanged), len(p.MunicipioChanged),
+		string(report), "Carga do cadastro de escolas via cmd/import-schools",
+	).Scan(&batchID)
+	if err != nil {
+		return 0, fmt.Errorf("inserindo lote: %w", err)
+	}
+
+	// Campo vazio no arquivo ($n = '') mantém o valor atual. A condição
+	// merged_into IS NULL protege escolas fundidas de uma corrida com o
+	// painel entre a leitura e a gravação.
+	stmt, err := tx.Prepare(`
+		INSERT INTO schools (codigo_inep, nome_escola, municipio, dre, zona, endereco, telefone, cep, email,
+		                     import_batch_id, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''),
+		        NULLIF($9, ''), $10, NOW())
+		ON CONFLICT (codigo_inep) DO UPDATE SET
+			nome_escola     = EXCLUDED.nome_escola,
+			municipio       = EXCLUDED.municipio,
+			dre             = COALESCE(EXCLUDED.dre, schools.dre),
+			zona            = COALESCE(EXCLUDED.zona, schools.zona),
+			endereco        = COALESCE(EXCLUDED.endereco, schools.endereco),
+			telefone        = COALESCE(EXCLUDED.telefone, schools.telefone),
+			cep             = COALESCE(EXCLUDED.cep, schools.cep),
+			email           = COALESCE(EXCLUDED.email, schools.email),
+			import_batch_id = EXCLUDED.import_batch_id,
+			updated_at      = NOW()
+		WHERE schools.merged_into IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("preparando upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, group := range [][]schoolRow{p.inserts, p.updates} {
+		for _, r := range group {
+			if _, err := stmt.Exec(r.inep, r.nome, r.municipio, r.dre, r.zona, r.endereco,
+				r.telefone, r.cep, r.email, batchID); err != nil {
+				return 0, fmt.Errorf("upsert linha %d (INEP %s): %w", r.line, r.inep, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return batchID, nil
+}
+
+// ---------------------------------------------------------------------------
+// Resumo
+// ---------------------------------------------------------------------------
+
+func printSummary(file string, p *plan, dryRun bool, batchID int64) {
+	fmt.Println("Arquivo:", file)
+	fmt.Println("Novas:", len(p.inserts))
+	fmt.Println("Atualizadas:", len(p.updates))
+	fmt.Println("Sem alteração:", p.unchanged)
+	fmt.Println("Linhas ignoradas:", len(p.Skipped)+len(p.Merged))
+	printSkipped(append(append([]skippedRow{}, p.Skipped...), p.Merged...))
+
+	fmt.Println("DRE alterada:", len(p.DreChanged))
+	for _, c := range p.DreChanged {
+		fmt.Printf("  %s %s: %q -> %q\n", c.INEP, c.Nome, c.Antes, c.Depois)
+	}
+	fmt.Println("Município alterado:", len(p.MunicipioChanged))
+	for _, c := range p.MunicipioChanged {
+		fmt.Printf("  %s %s: %q -> %q\n", c.INEP, c.Nome, c.Antes, c.Depois)
+	}
+	fmt.Println("Inativas no cadastro presentes no arquivo:", len(p.Inactive))
+	for _, c := range p.Inactive {
+		fmt.Printf("  %s %s (id %d)\n", c.INEP, c.Nome, c.SchoolID)
+	}
+	fmt.Println("Ausentes no arquivo (ativas no cadastro):", len(p.Missing))
+	for _, m := range p.Missing {
+		fmt.Printf("  %s %s — %s / %s (id %d)\n", m.INEP, m.Nome, m.Dre, m.Municipio, m.SchoolID)
+	}
+
+	fmt.Println("Dry-run:", yesNo(dryRun))
+	if batchID > 0 {
+		fmt.Println("Lote:", batchID)
+	} else {
+		fmt.Println("Lote: -")
+	}
+}
+
+func printSkipped(rows []skippedRow) {
+	for _, s := range rows {
+		fmt.Printf("  linha %d %s %s: %s\n", s.Line, s.INEP, s.Nome, s.Reason)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+// foldKey reduz um texto a minúsculas sem acento e com espaços colapsados.
+func foldKey(s string) string {
+	t := transform.Chain(norm.NFD, transform.RemoveFunc(func(r rune) bool {
+		return unicode.Is(unicode.Mn, r)
+	}), norm.NFC)
+	out, _, _ := transform.String(t, strings.ToLower(s))
+	return strings.Join(strings.Fields(out), " ")
+}
+
+// headerKey normaliza o nome de uma coluna: foldKey com "_" no lugar de
+// espaços, hífens e pontos.
+func headerKey(s string) string {
+	s = strings.TrimPrefix(s, "\uFEFF")
+	return strings.NewReplacer(" ", "_", "-", "_", ".", "_").Replace(foldKey(s))
+}
+
+// dreKey compara DREs com a tolerância do painel: "DRE Belém" = "BELEM".
+func dreKey(s string) string {
+	return strings.TrimPrefix(foldKey(s), "dre ")
+}
+
+// normalizeZona traz a localização para as zonas do formulário; o código
+// do INEP (1 urbana, 2 rural) também é aceito. Valor desconhecido vira
+// vazio e mantém o cadastro.
+func normalizeZona(s string) string {
+	switch foldKey(s) {
+	case "urbana", "1":
+		return "Urbana"
+	case "rural", "2":
+		return "Rural"
+	case "ribeirinha":
+		return "Ribeirinha"
+	}
+	return ""
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "sim"
+	}
+	return "não"
+}
+
+// resolveDSN replica a resolução de DSN do servidor (cmd/api/main.go):
+// --dsn > DATABASE_URL > DB_DSN > componentes DB_HOST/PORT/USER/...
+func resolveDSN(dsnFlag string) string {
+	loadEnv()
+	if dsnFlag != "" {
+		return dsnFlag
+	}
+	if v := os.Getenv("DATABASE_URL"); v != "" {
+		return v
+	}
+	if v := os.Getenv("DB_DSN"); v != "" {
+		return v
+	}
+	if host := os.Getenv("DB_HOST"); host != "" {
+		sslmode := os.Getenv("DB_SSLMODE")
+		if sslmode == "" {
+			sslmode = "disable"
+		}
+		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s connect_timeout=5",
+			os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"),
+			os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"), sslmode)
+	}
+	return ""
+}
+
+// loadEnv procura um .env nos mesmos lugares que o servidor, de forma best-effort.
+func loadEnv() {
+	cwd, _ := os.Getwd()
+	for _, p := range []string{
+		".env",
+		filepath.Join(cwd, ".env"),
+		filepath.Join(cwd, "..", ".env"),
+		filepath.Join(cwd, "..", "infra", ".env"),
+	} {
+		if err := godotenv.Load(p); err == nil {
+			return
+		}
+	}
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
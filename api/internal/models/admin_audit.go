@@ -0,0 +1,112 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AdminAuditEntry é uma linha da trilha de auditoria do painel
+// (admin_audit_log). Query guarda os filtros da query string como objeto
+// JSON {"parametro": "valor"}.
+type AdminAuditEntry struct {
+	ID         int64           `json:"id"`
+	UserID     *int            `json:"user_id,omitempty"`
+	Username   string          `json:"username"`
+	Role       string          `json:"role"`
+	IP         string          `json:"ip"`
+	Method     string          `json:"method"`
+	Route      string          `json:"route"`
+	Path       string          `json:"path"`
+	Query      json.RawMessage `json:"query"`
+	StatusCode int             `json:"status_code"`
+	DurationMs int             `json:"duration_ms"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AdminAuditFilter são os filtros de consulta da trilha. Zero/"" desativa
+// o filtro. From e To são datas YYYY-MM-DD (inclusivas), já validadas pelo
+// handler; Route casa por prefixo do padrão chi.
+type AdminAuditFilter struct {
+	UserID   int
+	Username string
+	Route    string
+	Method   string
+	Status   int
+	IP       string
+	From     string
+	To       string
+}
+
+type AdminAuditModel struct {
+	DB *sql.DB
+}
+
+// adminAuditWhereSQL é compartilhada entre contagem, página e exportação.
+// Argumentos: $1=user_id, $2=username, $3=route, $4=method, $5=status,
+// $6=ip, $7=from, $8=to.
+const adminAuditWhereSQL = `
+	WHERE ($1 = 0 OR user_id = $1)
+	  AND ($2 = '' OR LOWER(username) = LOWER($2))
+	  AND ($3 = '' OR position($3 in route) = 1)
+	  AND ($4 = '' OR method = UPPER($4))
+	  AND ($5 = 0 OR status_code = $5)
+	  AND ($6 = '' OR ip = $6)
+	  AND ($7 = '' OR created_at >= $7::date)
+	  AND ($8 = '' OR created_at < $8::date + 1)`
+
+const adminAuditColumns = `id, user_id, username, role, ip, method, route, path, query, status_code, duration_ms, created_at`
+
+func (f AdminAuditFilter) args() []any {
+	return []any{f.UserID, f.Username, f.Route, f.Method, f.Status, f.IP, f.From, f.To}
+}
+
+// Insert grava uma entrada. created_at vem do banco.
+func (m *AdminAuditModel) Insert(e *AdminAuditEntry) error {
+	query := e.Query
+	if len(query) == 0 {
+		query = json.RawMessage(`{}`)
+	}
+	stmt := `
+		INSERT INTO admin_audit_log (user_id, username, role, ip, method, route, path, query, status_code, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		RETURNING id, created_at`
+	return m.DB.QueryRowContext(context.Background(), stmt,
+		e.UserID, e.Username, e.Role, e.IP, e.Method, e.Route, e.Path, string(query), e.StatusCode, e.DurationMs,
+	).Scan(&e.ID, &e.CreatedAt)
+}
+
+// Count devolve o total de entradas que atendem ao filtro.
+func (m *AdminAuditModel) Count(ctx context.Context, f AdminAuditFilter) (int, error) {
+	var n int
+	err := m.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM admin_audit_log`+adminAuditWhereSQL, f.args()...).Scan(&n)
+	return n, err
+}
+
+// List devolve até limit entradas a partir de offset, mais recentes
+// primeiro.
+func (m *AdminAuditModel) List(ctx context.Context, f AdminAuditFilter, limit, offset int) ([]*AdminAuditEntry, error) {
+	rows, err := m.DB.QueryContext(ctx,
+		`SELECT `+adminAuditColumns+` FROM admin_audit_log`+adminAuditWhereSQL+`
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $9 OFFSET $10`,
+		append(f.args(), limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*AdminAuditEntry{}
+	for rows.Next() {
+		var e AdminAuditEntry
+		var query []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Username, &e.Role, &e.IP, &e.Method, &e.Route,
+			&e.Path, &query, &e.StatusCode, &e.DurationMs, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Query = json.RawMessage(query)
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
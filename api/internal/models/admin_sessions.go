@@ -0,0 +1,209 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AdminSession é um login do painel (admin_sessions). Os hashes do refresh
+// token nunca são serializados: a listagem de sessões devolve a struct
+// diretamente.
+type AdminSession struct {
+	ID                int64      `json:"id"`
+	UserID            int        `json:"user_id"`
+	RefreshTokenHash  string     `json:"-"`
+	RotatedAt         *time.Time `json:"rotated_at,omitempty"`
+	IP                string     `json:"ip"`
+	UserAgent         string     `json:"user_agent"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	AbsoluteExpiresAt time.Time  `json:"absolute_expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+}
+
+type AdminSessionModel struct {
+	DB *sql.DB
+}
+
+const adminSessionColumns = `id, user_id, refresh_token_hash, rotated_at, ip, user_agent,
+	created_at, last_used_at, expires_at, absolute_expires_at, revoked_at`
+
+// adminSessionActive é o predicado de sessão utilizável: não revogada e
+// dentro das duas expirações.
+const adminSessionActive = `revoked_at IS NULL AND expires_at > NOW() AND absolute_expires_at > NOW()`
+
+func scanAdminSession(row interface{ Scan(...any) error }) (*AdminSession, error) {
+	var s AdminSession
+	err := row.Scan(&s.ID, &s.UserID, &s.RefreshTokenHash, &s.RotatedAt, &s.IP, &s.UserAgent,
+		&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.AbsoluteExpiresAt, &s.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Create abre uma sessão para o usuário. As expirações são calculadas no
+// banco (NOW() + ttl), no mesmo relógio usado pelas checagens.
+func (m *AdminSessionModel) Create(userID int, tokenHash, ip, userAgent string, ttl, maxAge time.Duration) (*AdminSession, error) {
+	stmt := `
+		INSERT INTO admin_sessions (user_id, refresh_token_hash, ip, user_agent, created_at, last_used_at, expires_at, absolute_expires_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW(),
+			NOW() + make_interval(secs => $5), NOW() + make_interval(secs => $6))
+		RETURNING ` + adminSessionColumns
+	return scanAdminSession(m.DB.QueryRowContext(context.Background(), stmt,
+		userID, tokenHash, ip, userAgent, ttl.Seconds(), maxAge.Seconds()))
+}
+
+// GetActiveByRefreshHash devolve a sessão ativa cujo refresh token atual tem
+// o hash informado, ou (nil, nil).
+func (m *AdminSessionModel) GetActiveByRefreshHash(hash string) (*AdminSession, error) {
+	row := m.DB.QueryRowContext(context.Background(),
+		`SELECT `+adminSessionColumns+` FROM admin_sessions
+		 WHERE refresh_token_hash = $1 AND `+adminSessionActive, hash)
+	s, err := scanAdminSession(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GetByPreviousHash devolve a sessão (ativa ou não) cujo refresh token
+// ANTERIOR tem o hash informado, ou (nil, nil). Usado para detectar reuso de
+// um refresh token já rotacionado.
+func (m *AdminSessionModel) GetByPreviousHash(hash string) (*AdminSession, error) {
+	row := m.DB.QueryRowContext(context.Background(),
+		`SELECT `+adminSessionColumns+` FROM admin_sessions WHERE previous_token_hash = $1`, hash)
+	s, err := scanAdminSession(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// Rotate troca o refresh token da sessão de oldHash para newHash e desliza
+// expires_at por ttl (limitado a absolute_expires_at). A troca é condicional
+// ao hash atual: duas renovações concorrentes com o mesmo token não podem
+// ambas vencer. Devolve false quando a sessão já não tem oldHash ou não está
+// mais ativa.
+func (m *AdminSessionModel) Rotate(s *AdminSession, oldHash, newHash string, ttl time.Duration) (bool, error) {
+	stmt := `
+		UPDATE admin_sessions
+		SET previous_token_hash = refresh_token_hash,
+		    refresh_token_hash  = $3,
+		    rotated_at          = NOW(),
+		    last_used_at        = NOW(),
+		    expires_at          = LEAST(NOW() + make_interval(secs => $4), absolute_expires_at)
+		WHERE id = $1 AND refresh_token_hash = $2 AND ` + adminSessionActive + `
+		RETURNING rotated_at, last_used_at, expires_at`
+	err := m.DB.QueryRowContext(context.Background(), stmt, s.ID, oldHash, newHash, ttl.Seconds()).
+		Scan(&s.RotatedAt, &s.LastUsedAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.RefreshTokenHash = newHash
+	return true, nil
+}
+
+// Revoke encerra a sessão. Revogar uma sessão já encerrada não é erro.
+func (m *AdminSessionModel) Revoke(id int64) error {
+	_, err := m.DB.ExecContext(context.Background(),
+		`UPDATE admin_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	return err
+}
+
+// RevokeForUser encerra a sessão id somente se ela pertencer a userID e
+// estiver ativa. Devolve false quando nada foi encerrado.
+func (m *AdminSessionModel) RevokeForUser(userID int, id int64) (bool, error) {
+	res, err := m.DB.ExecContext(context.Background(),
+		`UPDATE admin_sessions SET revoked_at = NOW()
+		 WHERE id = $1 AND user_id = $2 AND `+adminSessionActive, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeAllForUser encerra todas as sessões ativas do usuário e devolve
+// quantas foram encerradas.
+func (m *AdminSessionModel) RevokeAllForUser(userID int) (int64, error) {
+	res, err := m.DB.ExecContext(context.Background(),
+		`UPDATE admin_sessions SET revoked_at = NOW() WHERE user_id = $1 AND `+adminSessionActive, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetActiveForUser lista as sessões ativas do usuário, mais recentes
+// primeiro.
+func (m *AdminSessionModel) GetActiveForUser(userID int) ([]*AdminSession, error) {
+	rows, err := m.DB.QueryContext(context.Background(),
+		`SELECT `+adminSessionColumns+` FROM admin_sessions
+		 WHERE user_id = $1 AND `+adminSessionActive+`
+		 ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*AdminSession{}
+	for rows.Next() {
+		s, err := scanAdminSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeToken inclui o jti de um access token na lista de revogação até a
+// expiração do próprio token (ttl = tempo de vida restante).
+func (m *AdminSessionModel) RevokeToken(jti string, userID int, ttl time.Duration) error {
+	_, err := m.DB.ExecContext(context.Background(), `
+		INSERT INTO admin_revoked_tokens (jti, user_id, expires_at, revoked_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3), NOW())
+		ON CONFLICT (jti) DO NOTHING`, jti, userID, ttl.Seconds())
+	return err
+}
+
+// AccessRevoked informa se um access token deixou de valer: jti na lista de
+// revogação ou sessão de origem encerrada/expirada.
+func (m *AdminSessionModel) AccessRevoked(jti string, sessionID int64) (bool, error) {
+	var revoked bool
+	err := m.DB.QueryRowContext(context.Background(), `
+		SELECT EXISTS (SELECT 1 FROM admin_revoked_tokens WHERE jti = $1)
+		    OR NOT EXISTS (SELECT 1 FROM admin_sessions WHERE id = $2 AND `+adminSessionActive+`)`,
+		jti, sessionID).Scan(&revoked)
+	return revoked, err
+}
+
+// DeleteExpired remove jtis revogados já expirados e sessões encerradas ou
+// expiradas há mais de keep. Sessões recentes são mantidas para que o reuso
+// de um refresh token antigo ainda seja reconhecido.
+func (m *AdminSessionModel) DeleteExpired(keep time.Duration) (tokens, sessions int64, err error) {
+	ctx := context.Background()
+	res, err := m.DB.ExecContext(ctx, `DELETE FROM admin_revoked_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, 0, err
+	}
+	if tokens, err = res.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+
+	res, err = m.DB.ExecContext(ctx, `
+		DELETE FROM admin_sessions
+		WHERE COALESCE(revoked_at, LEAST(expires_at, absolute_expires_at)) < NOW() - make_interval(secs => $1)`,
+		keep.Seconds())
+	if err != nil {
+		return tokens, 0, err
+	}
+	sessions, err = res.RowsAffected()
+	return tokens, sessions, err
+}
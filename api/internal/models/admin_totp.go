@@ -0,0 +1,153 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AdminTOTP é o estado do segundo fator de uma conta. Os segredos chegam e
+// saem cifrados: o model não conhece a chave.
+type AdminTOTP struct {
+	Secret        string
+	PendingSecret string
+	EnabledAt     *time.Time
+	LastStep      int64
+	// RecoveryLeft é o número de códigos de recuperação ainda não usados.
+	RecoveryLeft int
+}
+
+type AdminTOTPModel struct {
+	DB *sql.DB
+}
+
+// Get devolve (nil, nil) quando a conta não existe.
+func (m *AdminTOTPModel) Get(userID int) (*AdminTOTP, error) {
+	var t AdminTOTP
+	err := m.DB.QueryRowContext(context.Background(), `
+		SELECT COALESCE(u.totp_secret, ''), COALESCE(u.totp_pending_secret, ''),
+		       u.totp_enabled_at, COALESCE(u.totp_last_step, 0),
+		       (SELECT COUNT(*) FROM admin_totp_recovery_codes c
+		        WHERE c.user_id = u.id AND c.used_at IS NULL)
+		FROM admin_users u WHERE u.id = $1`, userID).
+		Scan(&t.Secret, &t.PendingSecret, &t.EnabledAt, &t.LastStep, &t.RecoveryLeft)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SetPending guarda o segredo de uma ativação em andamento, substituindo
+// uma anterior não confirmada.
+func (m *AdminTOTPModel) SetPending(userID int, secret string) error {
+	_, err := m.DB.ExecContext(context.Background(),
+		`UPDATE admin_users SET totp_pending_secret = $2, updated_at = NOW() WHERE id = $1`,
+		userID, secret)
+	return err
+}
+
+// Enable confirma a ativação: o segredo pendente passa a valer, step é
+// registrado como já usado e os códigos de recuperação são trocados pelos
+// informados, tudo numa transação.
+func (m *AdminTOTPModel) Enable(ctx context.Context, userID int, secret string, step int64, recoveryHashes []string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE admin_users
+		SET totp_secret = $2, totp_pending_secret = NULL, totp_enabled_at = NOW(),
+		    totp_last_step = $3, updated_at = NOW()
+		WHERE id = $1`, userID, secret, step); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Disable desliga o segundo fator e apaga os códigos de recuperação.
+func (m *AdminTOTPModel) Disable(ctx context.Context, userID int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE admin_users
+		SET totp_secret = NULL, totp_pending_secret = NULL, totp_enabled_at = NULL,
+		    totp_last_step = NULL, updated_at = NOW()
+		WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM admin_totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AdvanceStep registra step como o último passo aceito, desde que seja
+// posterior ao anterior. false indica código já usado (replay) ou segundo
+// fator desligado nesse meio-tempo.
+func (m *AdminTOTPModel) AdvanceStep(userID int, step int64) (bool, error) {
+	res, err := m.DB.ExecContext(context.Background(), `
+		UPDATE admin_users SET totp_last_step = $2
+		WHERE id = $1 AND totp_enabled_at IS NOT NULL AND COALESCE(totp_last_step, 0) < $2`,
+		userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// UseRecoveryCode consome o código de recuperação com o hash informado.
+// false quando não existe ou já foi usado.
+func (m *AdminTOTPModel) UseRecoveryCode(userID int, hash string) (bool, error) {
+	res, err := m.DB.ExecContext(context.Background(), `
+		UPDATE admin_totp_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReplaceRecoveryCodes descarta os códigos de recuperação da conta e grava
+// os novos.
+func (m *AdminTOTPModel) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, hashes []string) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM admin_totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO admin_totp_recovery_codes (user_id, code_hash, created_at)
+			VALUES ($1, $2, NOW())`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
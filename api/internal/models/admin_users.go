@@ -0,0 +1,118 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AdminUser é uma conta individual do painel administrativo (admin_users).
+// PasswordHash nunca é serializado: as rotas de gestão de contas devolvem a
+// struct diretamente.
+type AdminUser struct {
+	ID           int        `json:"id"`
+	Username     string     `json:"username"`
+	Nome         string     `json:"nome"`
+	PasswordHash string     `json:"-"`
+	Role         string     `json:"role"`
+	Dre          string     `json:"dre,omitempty"`
+	Active       bool       `json:"active"`
+	TOTPEnabled  bool       `json:"totp_enabled"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type AdminUserModel struct {
+	DB *sql.DB
+}
+
+// adminUserColumns projeta dre com COALESCE: NULL (acesso estadual) vira "".
+const adminUserColumns = `id, username, nome, password_hash, role, COALESCE(dre, ''), active,
+	totp_enabled_at IS NOT NULL, last_login_at, created_at, updated_at`
+
+func scanAdminUser(row interface{ Scan(...any) error }) (*AdminUser, error) {
+	var u AdminUser
+	err := row.Scan(&u.ID, &u.Username, &u.Nome, &u.PasswordHash, &u.Role, &u.Dre, &u.Active,
+		&u.TOTPEnabled, &u.LastLoginAt, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetByUsername devolve (nil, nil) quando o usuário não existe, no mesmo
+// padrão de CensusModel.GetBySchoolID.
+func (m *AdminUserModel) GetByUsername(username string) (*AdminUser, error) {
+	row := m.DB.QueryRowContext(context.Background(),
+		`SELECT `+adminUserColumns+` FROM admin_users WHERE username = $1`, username)
+	u, err := scanAdminUser(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return u, err
+}
+
+// Get devolve (nil, nil) quando o id não existe.
+func (m *AdminUserModel) Get(id int) (*AdminUser, error) {
+	row := m.DB.QueryRowContext(context.Background(),
+		`SELECT `+adminUserColumns+` FROM admin_users WHERE id = $1`, id)
+	u, err := scanAdminUser(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return u, err
+}
+
+func (m *AdminUserModel) GetAll() ([]*AdminUser, error) {
+	rows, err := m.DB.QueryContext(context.Background(),
+		`SELECT `+adminUserColumns+` FROM admin_users ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*AdminUser{}
+	for rows.Next() {
+		u, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (m *AdminUserModel) Count() (int, error) {
+	var n int
+	err := m.DB.QueryRowContext(context.Background(), `SELECT COUNT(*) FROM admin_users`).Scan(&n)
+	return n, err
+}
+
+func (m *AdminUserModel) Insert(u *AdminUser) error {
+	stmt := `
+		INSERT INTO admin_users (username, nome, password_hash, role, dre, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF(TRIM($5), ''), $6, NOW(), NOW())
+		RETURNING id, created_at, updated_at`
+	return m.DB.QueryRowContext(context.Background(), stmt,
+		u.Username, u.Nome, u.PasswordHash, u.Role, u.Dre, u.Active,
+	).Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
+}
+
+// Update grava nome, papel, DRE, flag de ativo e hash de senha da conta.
+func (m *AdminUserModel) Update(u *AdminUser) error {
+	stmt := `
+		UPDATE admin_users
+		SET nome = $1, role = $2, dre = NULLIF(TRIM($3), ''), active = $4, password_hash = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING updated_at`
+	return m.DB.QueryRowContext(context.Background(), stmt,
+		u.Nome, u.Role, u.Dre, u.Active, u.PasswordHash, u.ID,
+	).Scan(&u.UpdatedAt)
+}
+
+func (m *AdminUserModel) TouchLastLogin(id int) error {
+	_, err := m.DB.ExecContext(context.Background(),
+		`UPDATE admin_users SET last_login_at = NOW() WHERE id = $1`, id)
+	return err
+}
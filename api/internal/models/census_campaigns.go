@@ -0,0 +1,184 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CampaignDateLayout é o formato das datas de campanha (DATE, inclusivas).
+const CampaignDateLayout = "2006-01-02"
+
+// CensusCampaign é a janela de preenchimento do censo de um ano.
+type CensusCampaign struct {
+	Year        int                       `json:"year"`
+	OpensOn     string                    `json:"opens_on"`
+	ClosesOn    string                    `json:"closes_on"`
+	FormVersion string                    `json:"form_version"`
+	Extensions  []CensusCampaignExtension `json:"extensions"`
+	UpdatedAt   time.Time                 `json:"updated_at"`
+}
+
+// CensusCampaignExtension prorroga o encerramento da campanha para uma DRE.
+type CensusCampaignExtension struct {
+	DRE      string `json:"dre"`
+	ClosesOn string `json:"closes_on"`
+	Reason   string `json:"reason"`
+}
+
+type CensusCampaignModel struct {
+	DB *sql.DB
+}
+
+// List devolve as campanhas, da mais recente à mais antiga, com as
+// prorrogações.
+func (m *CensusCampaignModel) List(ctx context.Context) ([]*CensusCampaign, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT year, opens_on, closes_on, form_version, updated_at
+		FROM census_campaigns ORDER BY year DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*CensusCampaign
+	byYear := map[int]*CensusCampaign{}
+	for rows.Next() {
+		c, err := scanCensusCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+		byYear[c.Year] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ext, err := m.DB.QueryContext(ctx, `
+		SELECT year, dre, closes_on, reason FROM census_campaign_extensions ORDER BY year, dre`)
+	if err != nil {
+		return nil, err
+	}
+	defer ext.Close()
+	for ext.Next() {
+		var year int
+		e, err := scanCampaignExtension(ext, &year)
+		if err != nil {
+			return nil, err
+		}
+		if c := byYear[year]; c != nil {
+			c.Extensions = append(c.Extensions, e)
+		}
+	}
+	return out, ext.Err()
+}
+
+// Get devolve a campanha do ano, ou nil se não houver.
+func (m *CensusCampaignModel) Get(ctx context.Context, year int) (*CensusCampaign, error) {
+	c, err := scanCensusCampaign(m.DB.QueryRowContext(ctx, `
+		SELECT year, opens_on, closes_on, form_version, updated_at
+		FROM census_campaigns WHERE year = $1`, year))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT year, dre, closes_on, reason FROM census_campaign_extensions
+		WHERE year = $1 ORDER BY dre`, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var y int
+		e, err := scanCampaignExtension(rows, &y)
+		if err != nil {
+			return nil, err
+		}
+		c.Extensions = append(c.Extensions, e)
+	}
+	return c, rows.Err()
+}
+
+// Any diz se há alguma campanha cadastrada. Sem campanhas, a janela de
+// preenchimento não é aplicada.
+func (m *CensusCampaignModel) Any(ctx context.Context) (bool, error) {
+	var ok bool
+	err := m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM census_campaigns)`).Scan(&ok)
+	return ok, err
+}
+
+// Save grava a campanha e substitui as prorrogações, numa transação.
+func (m *CensusCampaignModel) Save(ctx context.Context, c *CensusCampaign) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO census_campaigns (year, opens_on, closes_on, form_version, updated_at)
+		VALUES ($1, $2::date, $3::date, $4, NOW())
+		ON CONFLICT (year) DO UPDATE SET
+			opens_on = EXCLUDED.opens_on,
+			closes_on = EXCLUDED.closes_on,
+			form_version = EXCLUDED.form_version,
+			updated_at = NOW()
+		RETURNING updated_at`, c.Year, c.OpensOn, c.ClosesOn, c.FormVersion).Scan(&c.UpdatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM census_campaign_extensions WHERE year = $1`, c.Year); err != nil {
+		return err
+	}
+	for _, e := range c.Extensions {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO census_campaign_extensions (year, dre, closes_on, reason)
+			VALUES ($1, $2, $3::date, $4)`, c.Year, e.DRE, e.ClosesOn, e.Reason); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete remove a campanha do ano (e as prorrogações, em cascata). Devolve
+// sql.ErrNoRows se não havia campanha.
+func (m *CensusCampaignModel) Delete(ctx context.Context, year int) error {
+	res, err := m.DB.ExecContext(ctx, `DELETE FROM census_campaigns WHERE year = $1`, year)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCensusCampaign(row rowScanner) (*CensusCampaign, error) {
+	var c CensusCampaign
+	var opens, closes time.Time
+	if err := row.Scan(&c.Year, &opens, &closes, &c.FormVersion, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	c.OpensOn = opens.Format(CampaignDateLayout)
+	c.ClosesOn = closes.Format(CampaignDateLayout)
+	c.Extensions = []CensusCampaignExtension{}
+	return &c, nil
+}
+
+func scanCampaignExtension(row rowScanner, year *int) (CensusCampaignExtension, error) {
+	var e CensusCampaignExtension
+	var closes time.Time
+	if err := row.Scan(year, &e.DRE, &closes, &e.Reason); err != nil {
+		return e, err
+	}
+	e.ClosesOn = closes.Format(CampaignDateLayout)
+	return e, nil
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Estados de census_responses.status. completed é o estado "enviado"
+// (submitted) e mantém o valor histórico gravado pelo formulário.
+const (
+	CensusStatusDraft     = "draft"
+	CensusStatusSubmitted = "completed"
+	CensusStatusInReview  = "em_revisao"
+	CensusStatusReturned  = "devolvido"
+	CensusStatusApproved  = "aprovado"
+)
+
+// censusReviewTransitions são as transições feitas pela revisão da DRE. As
+// do formulário (draft ⇄ completed, devolvido → completed) passam pelo
+// Upsert.
+var censusReviewTransitions = map[string][]string{
+	CensusStatusSubmitted: {CensusStatusInReview},
+	CensusStatusInReview:  {CensusStatusReturned, CensusStatusApproved},
+}
+
+var (
+	// ErrCensusLocked indica escrita do formulário num censo em revisão ou
+	// aprovado.
+	ErrCensusLocked = errors.New("census: censo em revisão ou aprovado")
+	// ErrCensusTransition indica transição de revisão fora da máquina de
+	// estados.
+	ErrCensusTransition = errors.New("census: transição de status inválida")
+)
+
+// CensusLockedForForm diz se o formulário público pode alterar o censo.
+func CensusLockedForForm(status string) bool {
+	return status == CensusStatusInReview || status == CensusStatusApproved
+}
+
+// CensusSent espelha censo_enviado: enviado, em revisão ou aprovado.
+func CensusSent(status string) bool {
+	return status == CensusStatusSubmitted || status == CensusStatusInReview || status == CensusStatusApproved
+}
+
+// CensusReviewAllowed diz se a revisão pode levar o censo de from para to.
+func CensusReviewAllowed(from, to string) bool {
+	for _, s := range censusReviewTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CensusReview é uma transição de revisão registrada em census_reviews.
+type CensusReview struct {
+	ID         int64     `json:"id"`
+	CensusID   int       `json:"census_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Reviewer   string    `json:"reviewer,omitempty"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CensusReviewModel struct {
+	DB *sql.DB
+}
+
+func (m *CensusReviewModel) list(ctx context.Context, where string, args ...any) ([]*CensusReview, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT id, census_id, from_status, to_status, reviewer, comment, created_at
+		FROM census_reviews WHERE `+where+` ORDER BY created_at DESC, id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*CensusReview
+	for rows.Next() {
+		var rv CensusReview
+		if err := rows.Scan(&rv.ID, &rv.CensusID, &rv.FromStatus, &rv.ToStatus,
+			&rv.Reviewer, &rv.Comment, &rv.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &rv)
+	}
+	return out, rows.Err()
+}
+
+// List devolve as transições de revisão do censo, da mais recente à mais
+// antiga.
+func (m *CensusReviewModel) List(ctx context.Context, censusID int) ([]*CensusReview, error) {
+	return m.list(ctx, `census_id = $1`, censusID)
+}
+
+// Returns devolve as devoluções do censo, da mais recente à mais antiga.
+func (m *CensusReviewModel) Returns(ctx context.Context, censusID int) ([]*CensusReview, error) {
+	return m.list(ctx, `census_id = $1 AND to_status = $2`, censusID, CensusStatusReturned)
+}
+
+// Transition leva o censo ao status to, registrando a revisão (revisor em
+// actor.Name) e uma revisão em census_revisions com os dados inalterados —
+// a versão do censo avança como em qualquer escrita. Devolve a nova versão,
+// ErrCensusTransition quando o status atual não permite a transição ou
+// sql.ErrNoRows quando o censo não existe.
+func (m *CensusReviewModel) Transition(ctx context.Context, censusID int, to string, actor CensusActor, comment string) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Mesmo advisory lock do Upsert: a revisão não intercala com uma
+	// escrita do formulário.
+	var schoolID, year int
+	if err := tx.QueryRowContext(ctx, `SELECT school_id, year FROM census_responses WHERE id = $1`,
+		censusID).Scan(&schoolID, &year); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, schoolID, year); err != nil {
+		return 0, err
+	}
+
+	var status string
+	var data []byte
+	var version int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT status, COALESCE(data, '{}'::jsonb), version
+		FROM census_responses WHERE id = $1`, censusID).Scan(&status, &data, &version); err != nil {
+		return 0, err
+	}
+	if !CensusReviewAllowed(status, to) {
+		return 0, ErrCensusTransition
+	}
+
+	var next int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT GREATEST($2, COALESCE(MAX(revision), 0)) + 1
+		FROM census_revisions WHERE census_id = $1`, censusID, version).Scan(&next); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE census_responses SET status = $2, version = $3, updated_at = NOW()
+		WHERE id = $1`, censusID, to, next); err != nil {
+		return 0, err
+	}
+	prev := status
+	if err := insertCensusRevision(ctx, tx, censusID, next, &prev, data, to, data, actor); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO census_reviews (census_id, from_status, to_status, reviewer, comment, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())`, censusID, status, to, actor.Name, comment); err != nil {
+		return 0, err
+	}
+	// Os destinos de sincronização acompanham o status; o censo devolvido
+	// sai deles até o reenvio.
+	if err := enqueueCensusSync(ctx, tx, censusID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return next, nil
+}
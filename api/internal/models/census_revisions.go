@@ -0,0 +1,111 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Origens de uma escrita no censo (census_revisions.actor_kind).
+const (
+	CensusActorSchool = "escola"     // formulário com código de acesso verificado
+	CensusActorForm   = "formulario" // formulário sem código
+	CensusActorAdmin  = "admin"      // painel; Name = username
+)
+
+// CensusActor identifica quem fez uma escrita no censo.
+type CensusActor struct {
+	Kind string
+	Name string
+	IP   string
+}
+
+// CensusRevision é uma escrita aceita em census_responses.
+type CensusRevision struct {
+	ID             int64           `json:"id"`
+	CensusID       int             `json:"census_id"`
+	Revision       int             `json:"revision"`
+	PreviousStatus *string         `json:"previous_status"`
+	Status         string          `json:"status"`
+	PreviousData   json.RawMessage `json:"previous_data,omitempty"`
+	Data           json.RawMessage `json:"data,omitempty"`
+	ActorKind      string          `json:"actor_kind"`
+	Actor          string          `json:"actor,omitempty"`
+	ClientIP       string          `json:"client_ip,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type CensusRevisionModel struct {
+	DB *sql.DB
+}
+
+const censusRevisionColumns = `id, census_id, revision, previous_status, status,
+	previous_data, data, actor_kind, actor, client_ip, created_at`
+
+func scanCensusRevision(row interface{ Scan(...any) error }) (*CensusRevision, error) {
+	var rev CensusRevision
+	var prev, data []byte
+	if err := row.Scan(&rev.ID, &rev.CensusID, &rev.Revision, &rev.PreviousStatus, &rev.Status,
+		&prev, &data, &rev.ActorKind, &rev.Actor, &rev.ClientIP, &rev.CreatedAt); err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		rev.PreviousData = json.RawMessage(prev)
+	}
+	rev.Data = json.RawMessage(data)
+	return &rev, nil
+}
+
+// List devolve as revisões do censo em ordem crescente, com os JSONs.
+func (m *CensusRevisionModel) List(ctx context.Context, censusID int) ([]*CensusRevision, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT `+censusRevisionColumns+`
+		FROM census_revisions WHERE census_id = $1 ORDER BY revision`, censusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*CensusRevision
+	for rows.Next() {
+		rev, err := scanCensusRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rev)
+	}
+	return out, rows.Err()
+}
+
+// Get devolve (nil, nil) quando a revisão não existe.
+func (m *CensusRevisionModel) Get(ctx context.Context, censusID, revision int) (*CensusRevision, error) {
+	rev, err := scanCensusRevision(m.DB.QueryRowContext(ctx, `
+		SELECT `+censusRevisionColumns+`
+		FROM census_revisions WHERE census_id = $1 AND revision = $2`, censusID, revision))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rev, err
+}
+
+// insertCensusRevision grava a escrita no histórico, dentro da transação
+// do Upsert. prevData nil = censo novo.
+func insertCensusRevision(ctx context.Context, tx *sql.Tx, censusID, revision int, prevStatus *string, prevData []byte,
+	status string, data []byte, actor CensusActor) error {
+	var prev any
+	if prevData != nil {
+		prev = prevData
+	}
+	kind := actor.Kind
+	if kind == "" {
+		kind = CensusActorForm
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO census_revisions
+			(census_id, revision, previous_status, status, previous_data, data,
+			 actor_kind, actor, client_ip, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())`,
+		censusID, revision, prevStatus, status, prev, data, kind, actor.Name, actor.IP)
+	return err
+}
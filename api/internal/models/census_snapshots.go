@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrSnapshotEmpty indica congelamento de um ano sem censos.
+var ErrSnapshotEmpty = errors.New("snapshot: ano sem censos")
+
+// CensusSnapshot é um lote de census_snapshots: a fotografia dos censos de
+// um ano num instante, identificada pelo id e pelo hash do conteúdo.
+type CensusSnapshot struct {
+	ID          int64     `json:"id"`
+	Year        int       `json:"year"`
+	Label       string    `json:"label"`
+	RowCount    int       `json:"row_count"`
+	ContentHash string    `json:"content_hash"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CensusSnapshotModel struct {
+	DB *sql.DB
+}
+
+// snapshotBatchHashSQL encadeia os hashes das linhas (censo_snapshot_hash)
+// em ordem de census_id; a origem, concatenada ao fim, traz as colunas id,
+// school_id, year, status, data e escola (censo_snapshot_escola).
+const snapshotBatchHashSQL = `
+	SELECT COUNT(*), encode(sha256(convert_to(COALESCE(string_agg(
+		censo_snapshot_hash(school_id, year, status, data, escola), '' ORDER BY id), ''), 'UTF8')), 'hex')
+	FROM `
+
+// snapshotLiveSQL são os censos do ano ($1) com o cadastro atual da
+// escola, na forma lida por snapshotBatchHashSQL.
+const snapshotLiveSQL = `(
+		SELECT cr.id, cr.school_id, cr.year, cr.status, cr.data,
+		       censo_snapshot_escola(s.dre, s.municipio, s.zona, s.municipio_id, s.dre_id, s.active) AS escola
+		FROM census_responses cr
+		JOIN schools s ON s.id = cr.school_id
+		WHERE cr.year = $1) l`
+
+// Freeze copia todos os censos do ano para um novo lote, com o cadastro da
+// escola usado nos agrupamentos (dre, município, zona, ids e ativa). Roda em
+// REPEATABLE READ: o hash do lote e as linhas copiadas vêm do mesmo
+// instante, mesmo com escritas concorrentes.
+func (m *CensusSnapshotModel) Freeze(ctx context.Context, year int, label, actor string) (*CensusSnapshot, error) {
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s := CensusSnapshot{Year: year, Label: label, CreatedBy: actor}
+	if err := tx.QueryRowContext(ctx, snapshotBatchHashSQL+snapshotLiveSQL, year).
+		Scan(&s.RowCount, &s.ContentHash); err != nil {
+		return nil, err
+	}
+	if s.RowCount == 0 {
+		return nil, ErrSnapshotEmpty
+	}
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO census_snapshot_batches (year, label, row_count, content_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`, year, label, s.RowCount, s.ContentHash, actor).Scan(&s.ID, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO census_snapshots (batch_id, census_id, school_id, year, status, data, version,
+		                              created_at, updated_at, sheet_synced_at, content_hash,
+		                              dre, municipio, zona, municipio_id, dre_id, active)
+		SELECT $1, cr.id, cr.school_id, cr.year, cr.status, COALESCE(cr.data, '{}'::jsonb), cr.version,
+		       cr.created_at, cr.updated_at, cr.sheet_synced_at,
+		       censo_snapshot_hash(cr.school_id, cr.year, cr.status, cr.data,
+		           censo_snapshot_escola(s.dre, s.municipio, s.zona, s.municipio_id, s.dre_id, s.active)),
+		       s.dre, s.municipio, s.zona, s.municipio_id, s.dre_id, s.active
+		FROM census_responses cr
+		JOIN schools s ON s.id = cr.school_id
+		WHERE cr.year = $2`, s.ID, year); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// List devolve os lotes, do mais recente ao mais antigo; year 0 lista
+// todos os anos.
+func (m *CensusSnapshotModel) List(ctx context.Context, year int) ([]*CensusSnapshot, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT id, year, label, row_count, content_hash, created_by, created_at
+		FROM census_snapshot_batches
+		WHERE $1 = 0 OR year = $1
+		ORDER BY created_at DESC, id DESC`, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*CensusSnapshot
+	for rows.Next() {
+		var s CensusSnapshot
+		if err := rows.Scan(&s.ID, &s.Year, &s.Label, &s.RowCount, &s.ContentHash, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &s)
+	}
+	return out, rows.Err()
+}
+
+// Get devolve o lote, ou nil se não existe.
+func (m *CensusSnapshotModel) Get(ctx context.Context, id int64) (*CensusSnapshot, error) {
+	var s CensusSnapshot
+	err := m.DB.QueryRowContext(ctx, `
+		SELECT id, year, label, row_count, content_hash, created_by, created_at
+		FROM census_snapshot_batches WHERE id = $1`, id).
+		Scan(&s.ID, &s.Year, &s.Label, &s.RowCount, &s.ContentHash, &s.CreatedBy, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Verify recalcula o hash e a contagem das linhas gravadas no lote, para
+// comparar com os registrados no congelamento. Linhas de lotes anteriores
+// à 0039, sem o cadastro da escola, entram com o hash de quatro campos.
+func (m *CensusSnapshotModel) Verify(ctx context.Context, id int64) (int, string, error) {
+	var n int
+	var hash string
+	err := m.DB.QueryRowContext(ctx, snapshotBatchHashSQL+`(
+		SELECT census_id AS id, school_id, year, status, data,
+		       censo_snapshot_escola(dre, municipio, zona, municipio_id, dre_id, active) AS escola
+		FROM census_snapshots WHERE batch_id = $1) l`, id).
+		Scan(&n, &hash)
+	return n, hash, err
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Location é uma escola da hierarquia DRE → município → escola usada pelos
+// seletores do formulário.
+type Location struct {
+	Dre       string  `json:"dre"`
+	Municipio string  `json:"municipio"`
+	Escola    string  `json:"escola"`
+	INEP      *string `json:"codigo_inep,omitempty"`
+}
+
+// LocationUpload registra uma substituição da hierarquia.
+type LocationUpload struct {
+	ID          int64     `json:"id"`
+	SourceFile  string    `json:"source_file"`
+	SourceHash  string    `json:"source_hash"`
+	RowsTotal   int       `json:"rows_total"`
+	Added       int       `json:"added"`
+	Removed     int       `json:"removed"`
+	INEPChanged int       `json:"inep_changed"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type LocationModel struct {
+	DB *sql.DB
+}
+
+// All devolve a hierarquia ordenada por DRE, município e escola.
+func (m *LocationModel) All(ctx context.Context) ([]Location, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT dre, municipio, escola, codigo_inep
+		FROM locations
+		ORDER BY dre, municipio, escola`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Location
+	for rows.Next() {
+		var l Location
+		var inep sql.NullString
+		if err := rows.Scan(&l.Dre, &l.Municipio, &l.Escola, &inep); err != nil {
+			return nil, err
+		}
+		if inep.Valid {
+			l.INEP = &inep.String
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// Count devolve o número de escolas na hierarquia.
+func (m *LocationModel) Count(ctx context.Context) (int, error) {
+	var n int
+	err := m.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM locations`).Scan(&n)
+	return n, err
+}
+
+// Replace troca a hierarquia inteira por rows e grava u (com o diff em
+// JSON) numa única transação; preenche u.ID e u.CreatedAt.
+func (m *LocationModel) Replace(ctx context.Context, rows []Location, u *LocationUpload, diff []byte) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Leituras concorrentes continuam vendo a hierarquia antiga até o
+	// commit; duas substituições simultâneas esperam uma pela outra.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE locations IN EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM locations`); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO locations (dre, municipio, escola, codigo_inep) VALUES ($1, $2, $3, $4)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, l := range rows {
+		if _, err := stmt.ExecContext(ctx, l.Dre, l.Municipio, l.Escola, l.INEP); err != nil {
+			return err
+		}
+	}
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO location_uploads (source_file, source_hash, rows_total, added, removed, inep_changed, diff, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		u.SourceFile, u.SourceHash, u.RowsTotal, u.Added, u.Removed, u.INEPChanged, string(diff), u.CreatedBy,
+	).Scan(&u.ID, &u.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"time"
 )
 
@@ -27,6 +28,17 @@ type School struct {
 	EtapasOfertadas      json.RawMessage `json:"etapas_ofertadas"`
 	ModalidadesOfertadas json.RawMessage `json:"modalidades_ofertadas"`
 
+	// Active/EndedOn: desativação lógica pelo painel; MergedInto: escola
+	// fundida em outra (ver SchoolModel.Merge).
+	Active           bool            `json:"active"`
+	EndedOn          *string         `json:"ended_on,omitempty"`
+	MergedInto       *int            `json:"merged_into,omitempty"`
+
+	// MunicipioID/DreID: ids canônicos de municipios e dres, resolvidos do
+	// texto pelo trigger schools_resolve_referencias; nil quando não casou.
+	MunicipioID      *int            `json:"municipio_id,omitempty"`
+	DreID            *int            `json:"dre_id,omitempty"`
+
 	CreatedAt        time.Time       `json:"created_at"`
 }
 
@@ -36,7 +48,12 @@ type CensusResponse struct {
 	Year           int             `json:"year"`
 	Status         string          `json:"status"`
 	Data           json.RawMessage `json:"data"`
+	Version        int             `json:"version"`
 	SheetSyncedAt  *time.Time      `json:"sheet_synced_at,omitempty"`
+	// SeededFromYear/SeededFields: pré-preenchimento a partir do ano
+	// anterior (chaves copiadas ainda não confirmadas pelo diretor).
+	SeededFromYear *int            `json:"seeded_from_year,omitempty"`
+	SeededFields   []string        `json:"seeded_fields,omitempty"`
 	CreatedAt      time.Time       `json:"created_at"`
 	UpdatedAt      time.Time       `json:"updated_at"`
 }
@@ -50,34 +67,66 @@ type CensusModel struct {
 }
 
 type Models struct {
-	Schools SchoolModel
-	Census  CensusModel
+	Schools         SchoolModel
+	Census          CensusModel
+	AdminUsers      AdminUserModel
+	AdminSessions   AdminSessionModel
+	AdminAudit      AdminAuditModel
+	SchoolAccess    SchoolAccessModel
+	RateLimits      RateLimitModel
+	AdminTOTP       AdminTOTPModel
+	CensusRevisions CensusRevisionModel
+	CensusReviews   CensusReviewModel
+	CensusCampaigns CensusCampaignModel
+	CensusSnapshots CensusSnapshotModel
+	Locations       LocationModel
+	References      ReferenceModel
+	SyncOutbox      SyncOutboxModel
+	SchoolPhotos    SchoolPhotoModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Schools: SchoolModel{DB: db},
-		Census:  CensusModel{DB: db},
+		Schools:         SchoolModel{DB: db},
+		Census:          CensusModel{DB: db},
+		AdminUsers:      AdminUserModel{DB: db},
+		AdminSessions:   AdminSessionModel{DB: db},
+		AdminAudit:      AdminAuditModel{DB: db},
+		SchoolAccess:    SchoolAccessModel{DB: db},
+		RateLimits:      RateLimitModel{DB: db},
+		AdminTOTP:       AdminTOTPModel{DB: db},
+		CensusRevisions: CensusRevisionModel{DB: db},
+		CensusReviews:   CensusReviewModel{DB: db},
+		CensusCampaigns: CensusCampaignModel{DB: db},
+		CensusSnapshots: CensusSnapshotModel{DB: db},
+		Locations:       LocationModel{DB: db},
+		References:      ReferenceModel{DB: db},
+		SyncOutbox:      SyncOutboxModel{DB: db},
+		SchoolPhotos:    SchoolPhotoModel{DB: db},
 	}
 }
 
+// Insert é o upsert do formulário público por codigo_inep. O INEP de uma
+// escola fundida atualiza a sobrevivente (ver liveIDByINEP); a linha
+// fundida nunca é alterada.
 func (m *SchoolModel) Insert(school School) (int, error) {
-	var existingID int
-	queryCheck := `SELECT id FROM schools WHERE codigo_inep = $1`
-	err := m.DB.QueryRowContext(context.Background(), queryCheck, school.INEP).Scan(&existingID)
+	existingID, err := m.liveIDByINEP(context.Background(), school.INEP)
+	if err != nil {
+		return 0, err
+	}
 
 	turnos := string(school.Turnos)
 	etapas := string(school.EtapasOfertadas)
 	modalidades := string(school.ModalidadesOfertadas)
 
-	if err == nil {
+	if existingID != 0 {
 		queryUpdate := `
 			UPDATE schools 
 			SET nome_escola = $1, municipio = $2, dre = $3, zona = $4, endereco = $5, 
 			    cnpj = $6, telefone = $7, email = $8, cep = $9, 
 			    nome_diretor = $10, matricula_diretor = $11, contato_diretor = $12,
 			    turnos = $13, etapas_ofertadas = $14, modalidades_ofertadas = $15
-			WHERE id = $16`
+			WHERE id = $16 AND merged_into IS NULL`
 		
 		_, errUpdate := m.DB.ExecContext(context.Background(), queryUpdate, 
 			school.Nome, school.Municipio, school.Dre, school.Zona, school.Endereco, 
@@ -93,49 +142,22 @@ func (m *SchoolModel) Insert(school School) (int, error) {
 		return existingID, nil
 	}
 
-	stmt := `
-		INSERT INTO schools (
-			nome_escola, codigo_inep, municipio, dre, zona, endereco, 
-			cnpj, telefone, email, cep, nome_diretor, matricula_diretor, contato_diretor, 
-			turnos, etapas_ofertadas, modalidades_ofertadas,
-			created_at
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW())
-		RETURNING id`
-
-	var id int
-	err = m.DB.QueryRowContext(context.Background(), stmt, 
-		school.Nome, 
-		school.INEP, 
-		school.Municipio,
-		school.Dre, 
-		school.Zona,
-		school.Endereco,
-		school.CNPJ,
-		school.Telefone,
-		school.Email,
-		school.CEP,
-		school.NomeDiretor,
-		school.MatriculaDiretor,
-		school.ContatoDiretor,
-		turnos,
-		etapas,
-		modalidades,
-	).Scan(&id)
-
-	if err != nil {
-		return 0, err
-	}
+	return m.Create(school)
+}
 
-	return id, nil
+// IDByINEP devolve o id da escola viva com o código INEP informado (a
+// sobrevivente, se o INEP é de uma escola fundida), ou 0 quando não existe.
+func (m *SchoolModel) IDByINEP(inep string) (int, error) {
+	return m.liveIDByINEP(context.Background(), inep)
 }
 
 func (m *SchoolModel) Get(id int) (*School, error) {
 	query := `
-		SELECT id, nome_escola, codigo_inep, municipio, dre, zona, endereco, 
+		SELECT id, nome_escola, COALESCE(codigo_inep, ''), municipio, dre, zona, endereco, 
 		       COALESCE(cnpj, ''), COALESCE(telefone, ''), COALESCE(email, ''), COALESCE(cep, ''),
 		       COALESCE(nome_diretor, ''), COALESCE(matricula_diretor, ''), COALESCE(contato_diretor, ''),
 		       COALESCE(turnos, ''), COALESCE(etapas_ofertadas, ''), COALESCE(modalidades_ofertadas, ''),
+		       active, to_char(ended_on, 'YYYY-MM-DD'), merged_into, municipio_id, dre_id,
 		       created_at
 		FROM schools
 		WHERE id = $1`
@@ -148,6 +170,7 @@ func (m *SchoolModel) Get(id int) (*School, error) {
 		&s.CNPJ, &s.Telefone, &s.Email, &s.CEP,
 		&s.NomeDiretor, &s.MatriculaDiretor, &s.ContatoDiretor,
 		&turnos, &etapas, &modalidades,
+		&s.Active, &s.EndedOn, &s.MergedInto, &s.MunicipioID, &s.DreID,
 		&s.CreatedAt,
 	)
 
@@ -164,12 +187,14 @@ func (m *SchoolModel) Get(id int) (*School, error) {
 
 func (m *SchoolModel) GetAll() ([]*School, error) {
 	query := `
-		SELECT id, nome_escola, codigo_inep, municipio, dre, zona, endereco, 
+		SELECT id, nome_escola, COALESCE(codigo_inep, ''), municipio, dre, zona, endereco, 
 		       COALESCE(cnpj, ''), COALESCE(telefone, ''), COALESCE(email, ''), COALESCE(cep, ''),
 		       COALESCE(nome_diretor, ''), COALESCE(matricula_diretor, ''), COALESCE(contato_diretor, ''),
 		       COALESCE(turnos, ''), COALESCE(etapas_ofertadas, ''), COALESCE(modalidades_ofertadas, ''),
+		       active, to_char(ended_on, 'YYYY-MM-DD'), merged_into, municipio_id, dre_id,
 		       created_at
 		FROM schools
+		WHERE active
 		ORDER BY nome_escola`
 
 	rows, err := m.DB.QueryContext(context.Background(), query)
@@ -187,6 +212,7 @@ func (m *SchoolModel) GetAll() ([]*School, error) {
 			&s.CNPJ, &s.Telefone, &s.Email, &s.CEP,
 			&s.NomeDiretor, &s.MatriculaDiretor, &s.ContatoDiretor,
 			&turnos, &etapas, &modalidades,
+			&s.Active, &s.EndedOn, &s.MergedInto, &s.MunicipioID, &s.DreID,
 			&s.CreatedAt,
 		)
 		if err != nil {
@@ -203,71 +229,132 @@ func (m *SchoolModel) GetAll() ([]*School, error) {
 	return schools, nil
 }
 
-func (m *CensusModel) Upsert(response *CensusResponse) error {
+// ErrCensusVersionConflict indica que o censo mudou desde a versão que a
+// escrita esperava (If-Match).
+var ErrCensusVersionConflict = errors.New("census: versão desatualizada")
+
+// Upsert grava o censo e devolve em response o id e a nova versão. Com
+// ifVersion não nil, a escrita só acontece se a versão gravada ainda for
+// *ifVersion (0 = censo inexistente); do contrário devolve
+// ErrCensusVersionConflict sem alterar nada.
+func (m *CensusModel) Upsert(response *CensusResponse, actor CensusActor, ifVersion *int) error {
 	// O merge de dados já foi feito na camada de handler (Go), então aqui
 	// sobrescrevemos diretamente sem double-merge no SQL.
 	// sheet_synced_at é preservado — não resetamos ao re-salvar.
+	// Cada escrita entra em census_revisions na mesma transação; o advisory
+	// lock por (escola, ano) serializa escritas concorrentes para que o JSON
+	// anterior registrado e a checagem de versão sejam de fato do estado
+	// anterior.
+	ctx := context.Background()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`,
+		response.SchoolID, response.Year); err != nil {
+		return err
+	}
+
+	var prevID, prevVersion int
+	var prevStatus *string
+	var prevData []byte
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, status, COALESCE(data, '{}'::jsonb), version
+		FROM census_responses WHERE school_id = $1 AND year = $2`,
+		response.SchoolID, response.Year).Scan(&prevID, &prevStatus, &prevData, &prevVersion)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if ifVersion != nil && *ifVersion != prevVersion {
+		return ErrCensusVersionConflict
+	}
+	// Revisão da DRE: em revisão ou aprovado, o censo fica travado; um
+	// rascunho sobre um censo devolvido continua devolvido até o reenvio.
+	if prevStatus != nil && CensusLockedForForm(*prevStatus) {
+		return ErrCensusLocked
+	}
+	if prevStatus != nil && *prevStatus == CensusStatusReturned && response.Status == CensusStatusDraft {
+		response.Status = CensusStatusReturned
+	}
+
+	// A versão nova é o número da revisão gravada abaixo.
+	next := 1
+	if prevID != 0 {
+		if err := tx.QueryRowContext(ctx, `
+			SELECT GREATEST($2, COALESCE(MAX(revision), 0)) + 1
+			FROM census_revisions WHERE census_id = $1`, prevID, prevVersion).Scan(&next); err != nil {
+			return err
+		}
+	}
+
+	// seeded_fields é sempre regravado (o handler já descontou as chaves
+	// confirmadas); seeded_from_year só é definido pelo pré-preenchimento.
+	seeded := response.SeededFields
+	if seeded == nil {
+		seeded = []string{}
+	}
+	seededJSON, err := json.Marshal(seeded)
+	if err != nil {
+		return err
+	}
+
 	stmt := `
-		INSERT INTO census_responses (school_id, year, status, data, updated_at)
-		VALUES ($1, $2, $3, $4, NOW())
+		INSERT INTO census_responses (school_id, year, status, data, version, updated_at, seeded_fields, seeded_from_year)
+		VALUES ($1, $2, $3, $4, $5, NOW(), $6, $7)
 		ON CONFLICT (school_id, year)
 		DO UPDATE SET
 			status = EXCLUDED.status,
 			data = EXCLUDED.data,
+			version = EXCLUDED.version,
 			updated_at = NOW(),
+			seeded_fields = EXCLUDED.seeded_fields,
+			seeded_from_year = COALESCE(EXCLUDED.seeded_from_year, census_responses.seeded_from_year),
 			sheet_synced_at = CASE WHEN EXCLUDED.status = 'completed' THEN NULL ELSE census_responses.sheet_synced_at END
 		RETURNING id`
 
-	return m.DB.QueryRowContext(context.Background(), stmt,
+	if err := tx.QueryRowContext(ctx, stmt,
 		response.SchoolID,
 		response.Year,
 		response.Status,
 		response.Data,
-	).Scan(&response.ID)
-}
-
-func (m *CensusModel) MarkSheetSynced(id int) error {
-	_, err := m.DB.ExecContext(context.Background(),
-		`UPDATE census_responses SET sheet_synced_at = NOW() WHERE id = $1`, id)
-	return err
-}
-
-// GetPendingSheetSync retorna todos os censos completed que ainda não foram
-// enviados para a planilha. Usado pelo job de retry e pela rota de resync.
-func (m *CensusModel) GetPendingSheetSync() ([]*CensusResponse, error) {
-	stmt := `SELECT id, school_id, year, status, data, created_at, updated_at
-	         FROM census_responses
-	         WHERE status = 'completed' AND sheet_synced_at IS NULL
-	         ORDER BY updated_at`
-
-	rows, err := m.DB.QueryContext(context.Background(), stmt)
-	if err != nil {
-		return nil, err
+		next,
+		seededJSON,
+		response.SeededFromYear,
+	).Scan(&response.ID); err != nil {
+		return err
 	}
-	defer rows.Close()
 
-	var results []*CensusResponse
-	for rows.Next() {
-		var c CensusResponse
-		var data []byte
-		if err := rows.Scan(&c.ID, &c.SchoolID, &c.Year, &c.Status, &data, &c.CreatedAt, &c.UpdatedAt); err != nil {
-			return nil, err
+	if err := insertCensusRevision(ctx, tx, response.ID, next, prevStatus, prevData,
+		response.Status, response.Data, actor); err != nil {
+		return err
+	}
+	// Envio concluído entra na fila de cada destino junto com a gravação; o
+	// worker de sync_outbox faz o envio e as novas tentativas.
+	if response.Status == CensusStatusSubmitted {
+		if err := enqueueCensusSync(ctx, tx, response.ID); err != nil {
+			return err
 		}
-		c.Data = json.RawMessage(data)
-		results = append(results, &c)
 	}
-	return results, nil
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	response.Version = next
+	return nil
 }
 
 func (m *CensusModel) GetBySchoolID(schoolID int, year int) (*CensusResponse, error) {
-	stmt := `SELECT id, school_id, year, status, data, sheet_synced_at, created_at, updated_at
+	stmt := `SELECT id, school_id, year, status, data, version, sheet_synced_at, created_at, updated_at,
+	                seeded_from_year, seeded_fields
 	         FROM census_responses WHERE school_id = $1 AND year = $2`
 
 	var c CensusResponse
-	var data []byte
+	var data, seeded []byte
 
 	err := m.DB.QueryRowContext(context.Background(), stmt, schoolID, year).Scan(
-		&c.ID, &c.SchoolID, &c.Year, &c.Status, &data, &c.SheetSyncedAt, &c.CreatedAt, &c.UpdatedAt,
+		&c.ID, &c.SchoolID, &c.Year, &c.Status, &data, &c.Version, &c.SheetSyncedAt, &c.CreatedAt, &c.UpdatedAt,
+		&c.SeededFromYear, &seeded,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -277,5 +364,10 @@ func (m *CensusModel) GetBySchoolID(schoolID int, year int) (*CensusResponse, er
 	}
 
 	c.Data = json.RawMessage(data)
+	if len(seeded) > 0 {
+		if err := json.Unmarshal(seeded, &c.SeededFields); err != nil {
+			return nil, err
+		}
+	}
 	return &c, nil
 }
\ No newline at end of file
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RateLimitModel guarda no Postgres as tentativas dos limites de taxa, de
+// modo que o limite valha para todas as réplicas da API.
+type RateLimitModel struct {
+	DB *sql.DB
+}
+
+// Allow registra uma tentativa de key no balde se ela couber em max dentro
+// da janela deslizante e informa se coube. Tentativas recusadas não são
+// gravadas. Um advisory lock por (bucket, key) serializa réplicas
+// concorrentes, para que a contagem e a inserção sejam atômicas.
+func (m *RateLimitModel) Allow(ctx context.Context, bucket, key string, max int, window time.Duration) (bool, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, bucket+"|"+key); err != nil {
+		return false, err
+	}
+
+	secs := window.Seconds()
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM rate_limit_hits
+		WHERE bucket = $1 AND key = $2 AND hit_at > NOW() - make_interval(secs => $3)`,
+		bucket, key, secs).Scan(&n); err != nil {
+		return false, err
+	}
+	if n >= max {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_hits (bucket, key, hit_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW() + make_interval(secs => $3))`,
+		bucket, key, secs); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Cleanup apaga as tentativas que já saíram da janela e devolve quantas
+// foram removidas.
+func (m *RateLimitModel) Cleanup(ctx context.Context) (int64, error) {
+	res, err := m.DB.ExecContext(ctx, `DELETE FROM rate_limit_hits WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
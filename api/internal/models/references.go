@@ -0,0 +1,133 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Municipio é um município da tabela de referência (código IBGE).
+type Municipio struct {
+	ID               int     `json:"id"`
+	CodigoIBGE       string  `json:"codigo_ibge"`
+	Nome             string  `json:"nome"`
+	Chave            string  `json:"chave"`
+	RegiaoIntegracao *string `json:"regiao_integracao,omitempty"`
+}
+
+// Dre é uma Diretoria Regional de Educação da tabela de referência.
+type Dre struct {
+	ID              int    `json:"id"`
+	Nome            string `json:"nome"`
+	Chave           string `json:"chave"`
+	SedeMunicipioID *int   `json:"sede_municipio_id,omitempty"`
+}
+
+// UnmatchedValue é um texto livre de município ou DRE sem correspondência
+// nas tabelas de referência, com quantas escolas e linhas de locations o
+// usam.
+type UnmatchedValue struct {
+	Value     string `json:"value"`
+	Schools   int    `json:"schools"`
+	Locations int    `json:"locations"`
+}
+
+type ReferenceModel struct {
+	DB *sql.DB
+}
+
+// Municipios lista os municípios em ordem alfabética.
+func (m *ReferenceModel) Municipios(ctx context.Context) ([]Municipio, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT id, codigo_ibge, nome, chave, regiao_integracao
+		FROM municipios
+		ORDER BY chave`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Municipio
+	for rows.Next() {
+		var mu Municipio
+		if err := rows.Scan(&mu.ID, &mu.CodigoIBGE, &mu.Nome, &mu.Chave, &mu.RegiaoIntegracao); err != nil {
+			return nil, err
+		}
+		out = append(out, mu)
+	}
+	return out, rows.Err()
+}
+
+// Dres lista as DREs em ordem alfabética.
+func (m *ReferenceModel) Dres(ctx context.Context) ([]Dre, error) {
+	rows, err := m.DB.QueryContext(ctx, `SELECT id, nome, chave, sede_municipio_id FROM dres ORDER BY chave`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Dre
+	for rows.Next() {
+		var d Dre
+		if err := rows.Scan(&d.ID, &d.Nome, &d.Chave, &d.SedeMunicipioID); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// unmatchedSQL agrupa os valores sem correspondência de schools (id NULL,
+// resolvido pelo trigger) e de locations (casados na hora); %[1]s é a
+// coluna de texto, %[2]s a de id em schools e %[3]s a função de casamento.
+const unmatchedSQL = `
+	SELECT value, SUM(schools)::int, SUM(locations)::int
+	FROM (
+		SELECT btrim(%[1]s) AS value, 1 AS schools, 0 AS locations
+		FROM schools
+		WHERE %[2]s IS NULL AND btrim(COALESCE(%[1]s, '')) <> ''
+		UNION ALL
+		SELECT btrim(%[1]s), 0, 1
+		FROM locations
+		WHERE %[3]s(%[1]s) IS NULL
+	) u
+	GROUP BY value
+	ORDER BY SUM(schools) DESC, value`
+
+// Unmatched devolve os municípios e as DREs em texto livre que não casam
+// com as tabelas de referência.
+func (m *ReferenceModel) Unmatched(ctx context.Context) (municipios, dres []UnmatchedValue, err error) {
+	if municipios, err = m.unmatched(ctx, "municipio", "municipio_id", "censo_municipio_id"); err != nil {
+		return nil, nil, err
+	}
+	if dres, err = m.unmatched(ctx, "dre", "dre_id", "censo_dre_id"); err != nil {
+		return nil, nil, err
+	}
+	return municipios, dres, nil
+}
+
+func (m *ReferenceModel) unmatched(ctx context.Context, column, idColumn, resolve string) ([]UnmatchedValue, error) {
+	rows, err := m.DB.QueryContext(ctx, fmt.Sprintf(unmatchedSQL, column, idColumn, resolve))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UnmatchedValue{}
+	for rows.Next() {
+		var u UnmatchedValue
+		if err := rows.Scan(&u.Value, &u.Schools, &u.Locations); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// SchoolReferences devolve os ids canônicos resolvidos para a escola (nil
+// quando o texto não casou).
+func (m *ReferenceModel) SchoolReferences(ctx context.Context, schoolID int) (municipioID, dreID *int, err error) {
+	err = m.DB.QueryRowContext(ctx, `SELECT municipio_id, dre_id FROM schools WHERE id = $1`, schoolID).
+		Scan(&municipioID, &dreID)
+	return municipioID, dreID, err
+}
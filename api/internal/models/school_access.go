@@ -0,0 +1,123 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SchoolAccessStatus é a situação do código de acesso de uma escola, para a
+// listagem do painel. O código em claro nunca é lido do banco.
+type SchoolAccessStatus struct {
+	SchoolID   int        `json:"school_id"`
+	Nome       string     `json:"nome_escola"`
+	INEP       string     `json:"codigo_inep"`
+	Municipio  string     `json:"municipio"`
+	Dre        string     `json:"dre"`
+	HasCode    bool       `json:"has_code"`
+	CodeHint   string     `json:"code_hint,omitempty"`
+	IssuedAt   *time.Time `json:"issued_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// SchoolAccessIssue é um código a gravar: hash e dica, nunca o valor em
+// claro.
+type SchoolAccessIssue struct {
+	SchoolID int
+	Hash     string
+	Hint     string
+}
+
+type SchoolAccessModel struct {
+	DB *sql.DB
+}
+
+// ListSchools devolve as escolas do recorte com a situação do código ativo.
+// dre "" não filtra por DRE; schoolIDs vazio não filtra por id. Os dois
+// filtros combinam por AND, de modo que ids fora da DRE informada ficam de
+// fora. Escolas desativadas não entram.
+func (m *SchoolAccessModel) ListSchools(ctx context.Context, dre string, schoolIDs []int64) ([]*SchoolAccessStatus, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT s.id, COALESCE(s.nome_escola, ''), COALESCE(s.codigo_inep, ''),
+		       COALESCE(s.municipio, ''), COALESCE(s.dre, ''),
+		       c.id IS NOT NULL, COALESCE(c.code_hint, ''), c.created_at, c.last_used_at
+		FROM schools s
+		LEFT JOIN school_access_codes c ON c.school_id = s.id AND c.revoked_at IS NULL
+		WHERE s.active
+		  AND ($1 = '' OR UPPER(TRIM(s.dre)) = UPPER(TRIM($1)))
+		  AND (COALESCE(cardinality($2::bigint[]), 0) = 0 OR s.id = ANY($2::bigint[]))
+		ORDER BY UPPER(TRIM(s.dre)), UPPER(TRIM(s.municipio)), UPPER(TRIM(s.nome_escola)), s.id`,
+		dre, schoolIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*SchoolAccessStatus{}
+	for rows.Next() {
+		var s SchoolAccessStatus
+		if err := rows.Scan(&s.SchoolID, &s.Nome, &s.INEP, &s.Municipio, &s.Dre,
+			&s.HasCode, &s.CodeHint, &s.IssuedAt, &s.LastUsedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &s)
+	}
+	return out, rows.Err()
+}
+
+// IssueBatch grava os códigos numa única transação, revogando antes o
+// código ativo de cada escola (rotação). issuedBy 0 grava NULL.
+func (m *SchoolAccessModel) IssueBatch(ctx context.Context, codes []SchoolAccessIssue, issuedBy int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var by any
+	if issuedBy > 0 {
+		by = issuedBy
+	}
+	for _, c := range codes {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE school_access_codes SET revoked_at = NOW() WHERE school_id = $1 AND revoked_at IS NULL`,
+			c.SchoolID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO school_access_codes (school_id, code_hash, code_hint, issued_by, created_at)
+			VALUES ($1, $2, $3, $4, NOW())`, c.SchoolID, c.Hash, c.Hint, by); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RevokeSchools revoga o código ativo das escolas informadas e devolve
+// quantos foram revogados.
+func (m *SchoolAccessModel) RevokeSchools(ctx context.Context, schoolIDs []int64) (int64, error) {
+	if len(schoolIDs) == 0 {
+		return 0, nil
+	}
+	res, err := m.DB.ExecContext(ctx, `
+		UPDATE school_access_codes SET revoked_at = NOW()
+		WHERE school_id = ANY($1::bigint[]) AND revoked_at IS NULL`, schoolIDs)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SchoolIDForCode devolve a escola vinculada ao código ativo com o hash
+// informado (0 quando não existe ou foi revogado) e registra o uso.
+func (m *SchoolAccessModel) SchoolIDForCode(hash string) (int, error) {
+	var id int
+	err := m.DB.QueryRowContext(context.Background(), `
+		UPDATE school_access_codes SET last_used_at = NOW()
+		WHERE code_hash = $1 AND revoked_at IS NULL
+		RETURNING school_id`, hash).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
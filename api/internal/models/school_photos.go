@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Categorias das fotos da escola.
+const (
+	PhotoFachada   = "fachada"
+	PhotoCozinha   = "cozinha"
+	PhotoBanheiros = "banheiros"
+	PhotoSalas     = "salas"
+	PhotoOutras    = "outras"
+)
+
+// PhotoCategories lista as categorias na ordem da galeria.
+var PhotoCategories = []string{PhotoFachada, PhotoCozinha, PhotoBanheiros, PhotoSalas, PhotoOutras}
+
+// SchoolPhoto é uma foto da escola gravada no armazenamento de fotos
+// (ver services.PhotoStore).
+type SchoolPhoto struct {
+	ID           int64     `json:"id"`
+	SchoolID     int       `json:"school_id"`
+	Year         int       `json:"year"`
+	Category     string    `json:"category"`
+	Storage      string    `json:"storage"`
+	Path         string    `json:"path"`
+	URL          *string   `json:"url,omitempty"`
+	ThumbPath    *string   `json:"thumb_path,omitempty"`
+	ThumbURL     *string   `json:"thumb_url,omitempty"`
+	OriginalName string    `json:"original_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	Width        *int      `json:"width,omitempty"`
+	Height       *int      `json:"height,omitempty"`
+	SHA256       string    `json:"sha256"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type SchoolPhotoModel struct {
+	DB *sql.DB
+}
+
+const schoolPhotoColumns = `id, school_id, year, category, storage, path, url, thumb_path, thumb_url,
+	original_name, content_type, size_bytes, width, height, sha256, created_at`
+
+func scanSchoolPhoto(row interface{ Scan(...any) error }) (*SchoolPhoto, error) {
+	var p SchoolPhoto
+	err := row.Scan(&p.ID, &p.SchoolID, &p.Year, &p.Category, &p.Storage, &p.Path, &p.URL, &p.ThumbPath, &p.ThumbURL,
+		&p.OriginalName, &p.ContentType, &p.SizeBytes, &p.Width, &p.Height, &p.SHA256, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetBySHA devolve a foto da escola no ano com o mesmo conteúdo (nil, nil
+// quando não existe).
+func (m *SchoolPhotoModel) GetBySHA(ctx context.Context, schoolID, year int, sha string) (*SchoolPhoto, error) {
+	p, err := scanSchoolPhoto(m.DB.QueryRowContext(ctx, `
+		SELECT `+schoolPhotoColumns+`
+		FROM school_photos
+		WHERE school_id = $1 AND year = $2 AND sha256 = $3`,
+		schoolID, year, sha))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// Insert registra a foto e preenche ID e CreatedAt. Se outra requisição já
+// registrou o mesmo conteúdo para a escola e ano, devolve created = false e
+// preenche p com o registro existente.
+func (m *SchoolPhotoModel) Insert(ctx context.Context, p *SchoolPhoto) (created bool, err error) {
+	err = m.DB.QueryRowContext(ctx, `
+		INSERT INTO school_photos
+			(school_id, year, category, storage, path, url, thumb_path, thumb_url,
+			 original_name, content_type, size_bytes, width, height, sha256)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (school_id, year, sha256) DO NOTHING
+		RETURNING id, created_at`,
+		p.SchoolID, p.Year, p.Category, p.Storage, p.Path, p.URL, p.ThumbPath, p.ThumbURL,
+		p.OriginalName, p.ContentType, p.SizeBytes, p.Width, p.Height, p.SHA256,
+	).Scan(&p.ID, &p.CreatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	existing, err := m.GetBySHA(ctx, p.SchoolID, p.Year, p.SHA256)
+	if err != nil {
+		return false, err
+	}
+	if existing == nil {
+		return false, sql.ErrNoRows
+	}
+	*p = *existing
+	return false, nil
+}
+
+// Get devolve a foto pelo id (nil, nil quando não existe).
+func (m *SchoolPhotoModel) Get(ctx context.Context, id int64) (*SchoolPhoto, error) {
+	p, err := scanSchoolPhoto(m.DB.QueryRowContext(ctx, `
+		SELECT `+schoolPhotoColumns+` FROM school_photos WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ListBySchool lista as fotos da escola no ano, na ordem das categorias e
+// de envio; category vazia traz todas.
+func (m *SchoolPhotoModel) ListBySchool(ctx context.Context, schoolID, year int, category string) ([]SchoolPhoto, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT `+schoolPhotoColumns+`
+		FROM school_photos
+		WHERE school_id = $1 AND year = $2 AND ($3::text = '' OR category = $3::text)
+		ORDER BY array_position(ARRAY['fachada', 'cozinha', 'banheiros', 'salas', 'outras'], category), created_at, id`,
+		schoolID, year, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SchoolPhoto{}
+	for rows.Next() {
+		p, err := scanSchoolPhoto(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
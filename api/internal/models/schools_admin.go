@@ -0,0 +1,341 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrSchoolINEPInUse indica codigo_inep já usado por outra escola.
+	ErrSchoolINEPInUse = errors.New("escola: codigo_inep em uso")
+	// ErrSchoolMerged indica escrita numa escola já fundida em outra.
+	ErrSchoolMerged = errors.New("escola: fundida em outra")
+)
+
+// SchoolMergeConflictError lista os anos em que as duas escolas têm censo:
+// a fusão não escolhe entre eles, um dos dois precisa ser resolvido antes.
+type SchoolMergeConflictError struct {
+	Years []int
+}
+
+func (e *SchoolMergeConflictError) Error() string {
+	years := make([]string, len(e.Years))
+	for i, y := range e.Years {
+		years[i] = fmt.Sprint(y)
+	}
+	return "escola: censos nos dois cadastros em " + strings.Join(years, ", ")
+}
+
+// SchoolMergeResult resume o que a fusão moveu para a escola sobrevivente.
+type SchoolMergeResult struct {
+	SourceID      int   `json:"source_id"`
+	TargetID      int   `json:"target_id"`
+	Census        int64 `json:"census_responses"`
+	Prodep        int64 `json:"prodep_repasses"`
+	Ideb          int64 `json:"ideb_resultados"`
+	AccessRevoked int64 `json:"access_codes_revoked"`
+	// INEPMoved indica que o codigo_inep da origem passou ao destino, que
+	// não tinha INEP.
+	INEPMoved bool `json:"codigo_inep_transferido"`
+}
+
+// Update grava o cadastro editado pelo painel (todos os campos editáveis,
+// inclusive active/ended_on). Desativar revoga o código de acesso ativo da
+// escola. sql.ErrNoRows quando a escola não existe.
+func (m *SchoolModel) Update(ctx context.Context, s School) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var merged sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT merged_into FROM schools WHERE id = $1 FOR UPDATE`, s.ID).
+		Scan(&merged); err != nil {
+		return err
+	}
+	if merged.Valid {
+		return ErrSchoolMerged
+	}
+	var other int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM schools WHERE codigo_inep = NULLIF($1, '') AND id <> $2`, s.INEP, s.ID).Scan(&other)
+	if err == nil {
+		return ErrSchoolINEPInUse
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	var endedOn any
+	if !s.Active && s.EndedOn != nil {
+		endedOn = *s.EndedOn
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE schools
+		SET nome_escola = $1, codigo_inep = NULLIF($2, ''), municipio = $3, dre = $4, zona = $5, endereco = $6,
+		    cnpj = $7, telefone = $8, email = $9, cep = $10,
+		    nome_diretor = $11, matricula_diretor = $12, contato_diretor = $13,
+		    turnos = $14, etapas_ofertadas = $15, modalidades_ofertadas = $16,
+		    active = $17, ended_on = $18::date, updated_at = NOW()
+		WHERE id = $19`,
+		s.Nome, s.INEP, s.Municipio, s.Dre, s.Zona, s.Endereco,
+		s.CNPJ, s.Telefone, s.Email, s.CEP,
+		s.NomeDiretor, s.MatriculaDiretor, s.ContatoDiretor,
+		nullableJSONText(s.Turnos), nullableJSONText(s.EtapasOfertadas), nullableJSONText(s.ModalidadesOfertadas),
+		s.Active, endedOn, s.ID); err != nil {
+		return err
+	}
+	if !s.Active {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE school_access_codes SET revoked_at = NOW()
+			WHERE school_id = $1 AND revoked_at IS NULL`, s.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Merge funde a escola sourceID em targetID: censos, repasses PRODEP
+// (school_id e school_id_sede) e resultados IDEB passam para a
+// sobrevivente, o código de acesso da origem é revogado e a origem fica
+// inativa com merged_into apontando para o destino. O codigo_inep da origem
+// é liberado (fica em codigo_inep_fundido) e passa ao destino quando este
+// não tem INEP. Os censos movidos são enfileirados em sync_outbox. Anos
+// com censo nas duas resultam em *SchoolMergeConflictError, sem alterar
+// nada.
+func (m *SchoolModel) Merge(ctx context.Context, sourceID, targetID int) (*SchoolMergeResult, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Trava as duas linhas em ordem de id, para duas fusões cruzadas não
+	// se bloquearem mutuamente.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, merged_into, COALESCE(codigo_inep, '') FROM schools WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	found := 0
+	var sourceINEP string
+	for rows.Next() {
+		var id int
+		var merged sql.NullInt64
+		var inep string
+		if err := rows.Scan(&id, &merged, &inep); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if merged.Valid {
+			rows.Close()
+			return nil, ErrSchoolMerged
+		}
+		if id == sourceID {
+			sourceINEP = inep
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if found < 2 {
+		return nil, sql.ErrNoRows
+	}
+
+	yrows, err := tx.QueryContext(ctx, `
+		SELECT a.year FROM census_responses a
+		JOIN census_responses b ON b.year = a.year AND b.school_id = $2
+		WHERE a.school_id = $1
+		ORDER BY a.year`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	var conflict SchoolMergeConflictError
+	for yrows.Next() {
+		var y int
+		if err := yrows.Scan(&y); err != nil {
+			yrows.Close()
+			return nil, err
+		}
+		conflict.Years = append(conflict.Years, y)
+	}
+	yrows.Close()
+	if err := yrows.Err(); err != nil {
+		return nil, err
+	}
+	if len(conflict.Years) > 0 {
+		return nil, &conflict
+	}
+
+	var movedCensus []int
+	crows, err := tx.QueryContext(ctx, `SELECT id FROM census_responses WHERE school_id = $1 ORDER BY id`, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	for crows.Next() {
+		var id int
+		if err := crows.Scan(&id); err != nil {
+			crows.Close()
+			return nil, err
+		}
+		movedCensus = append(movedCensus, id)
+	}
+	crows.Close()
+	if err := crows.Err(); err != nil {
+		return nil, err
+	}
+
+	res := &SchoolMergeResult{SourceID: sourceID, TargetID: targetID}
+	moved := []any{sourceID, targetID}
+	var inepMoved int64
+	for _, step := range []struct {
+		query string
+		args  []any
+		n     *int64
+	}{
+		{`UPDATE census_responses SET school_id = $2 WHERE school_id = $1`, moved, &res.Census},
+		{`UPDATE prodep_repasses SET school_id = $2 WHERE school_id = $1`, moved, &res.Prodep},
+		{`UPDATE prodep_repasses SET school_id_sede = $2 WHERE school_id_sede = $1`, moved, nil},
+		{`UPDATE ideb_resultados SET school_id = $2 WHERE school_id = $1`, moved, &res.Ideb},
+		{`UPDATE school_access_codes SET revoked_at = NOW() WHERE school_id = $1 AND revoked_at IS NULL`,
+			[]any{sourceID}, &res.AccessRevoked},
+		{`UPDATE schools SET active = FALSE, ended_on = COALESCE(ended_on, CURRENT_DATE),
+		         merged_into = $2, codigo_inep_fundido = NULLIF(codigo_inep, ''), codigo_inep = NULL,
+		         updated_at = NOW()
+		  WHERE id = $1`, moved, nil},
+		// Só depois de a origem liberar o código (UNIQUE).
+		{`UPDATE schools SET codigo_inep = $2, updated_at = NOW()
+		  WHERE id = $1 AND NULLIF(codigo_inep, '') IS NULL AND $2 <> ''`,
+			[]any{targetID, sourceINEP}, &inepMoved},
+	} {
+		r, err := tx.ExecContext(ctx, step.query, step.args...)
+		if err != nil {
+			return nil, err
+		}
+		if step.n != nil {
+			if *step.n, err = r.RowsAffected(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Os censos movidos voltam à fila: nos destinos, a linha passa da chave
+	// da origem para a da sobrevivente.
+	for _, id := range movedCensus {
+		if err := enqueueCensusSync(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	res.INEPMoved = inepMoved > 0
+	return res, nil
+}
+
+// liveIDByINEP resolve o INEP para a escola viva: a dona do codigo_inep ou,
+// para o INEP de uma escola fundida (codigo_inep_fundido), a sobrevivente ao
+// fim da cadeia merged_into. 0 quando nenhuma escola teve o INEP;
+// ErrSchoolMerged quando a cadeia não termina numa escola viva.
+func (m *SchoolModel) liveIDByINEP(ctx context.Context, inep string) (int, error) {
+	var id sql.NullInt64
+	var known bool
+	err := m.DB.QueryRowContext(ctx, `
+		WITH RECURSIVE cadeia AS (
+			SELECT id, merged_into, 0 AS passo
+			FROM schools
+			WHERE codigo_inep = $1 OR codigo_inep_fundido = $1
+			UNION ALL
+			SELECT s.id, s.merged_into, c.passo + 1
+			FROM schools s
+			JOIN cadeia c ON s.id = c.merged_into
+			WHERE c.passo < 32
+		)
+		SELECT (SELECT id FROM cadeia WHERE merged_into IS NULL ORDER BY passo, id LIMIT 1),
+		       EXISTS (SELECT 1 FROM cadeia)`, inep).Scan(&id, &known)
+	if err != nil {
+		return 0, err
+	}
+	if id.Valid {
+		return int(id.Int64), nil
+	}
+	if known {
+		return 0, ErrSchoolMerged
+	}
+	return 0, nil
+}
+
+// MergedSchool é uma escola fundida em outra, com o INEP que tinha.
+type MergedSchool struct {
+	ID   int
+	INEP string
+}
+
+// MergedInto devolve as escolas fundidas em id, direta ou indiretamente
+// (fundidas numa escola depois fundida em id).
+func (m *SchoolModel) MergedInto(ctx context.Context, id int) ([]MergedSchool, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		WITH RECURSIVE fundidas AS (
+			SELECT id, COALESCE(NULLIF(codigo_inep_fundido, ''), codigo_inep, '') AS inep, 0 AS passo
+			FROM schools
+			WHERE merged_into = $1
+			UNION ALL
+			SELECT s.id, COALESCE(NULLIF(s.codigo_inep_fundido, ''), s.codigo_inep, ''), f.passo + 1
+			FROM schools s
+			JOIN fundidas f ON s.merged_into = f.id
+			WHERE f.passo < 32
+		)
+		SELECT DISTINCT id, inep FROM fundidas ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MergedSchool
+	for rows.Next() {
+		var ms MergedSchool
+		if err := rows.Scan(&ms.ID, &ms.INEP); err != nil {
+			return nil, err
+		}
+		out = append(out, ms)
+	}
+	return out, rows.Err()
+}
+
+// Create cadastra uma escola nova. Se o codigo_inep já está em uso (por
+// exemplo, cadastrado por outra requisição depois da consulta), devolve
+// ErrSchoolINEPInUse sem alterar a escola existente.
+func (m *SchoolModel) Create(school School) (int, error) {
+	var id int
+	err := m.DB.QueryRowContext(context.Background(), `
+		INSERT INTO schools (
+			nome_escola, codigo_inep, municipio, dre, zona, endereco,
+			cnpj, telefone, email, cep, nome_diretor, matricula_diretor, contato_diretor,
+			turnos, etapas_ofertadas, modalidades_ofertadas,
+			created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW())
+		ON CONFLICT (codigo_inep) DO NOTHING
+		RETURNING id`,
+		school.Nome, school.INEP, school.Municipio, school.Dre, school.Zona, school.Endereco,
+		school.CNPJ, school.Telefone, school.Email, school.CEP,
+		school.NomeDiretor, school.MatriculaDiretor, school.ContatoDiretor,
+		string(school.Turnos), string(school.EtapasOfertadas), string(school.ModalidadesOfertadas),
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrSchoolINEPInUse
+	}
+	return id, err
+}
+
+// nullableJSONText grava listas JSON (turnos, etapas, modalidades) como o
+// texto que Insert grava; vazio vira NULL.
+func nullableJSONText(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
@@ -0,0 +1,421 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Estados de um item de sync_outbox.
+const (
+	OutboxPending    = "pending"
+	OutboxProcessing = "processing"
+	OutboxDone       = "done"
+	OutboxDead       = "dead"
+	OutboxDiscarded  = "discarded"
+)
+
+// Destinos de sync_outbox (kind). Na planilha do Google Sheets o target é
+// a aba; no arquivo, o caminho; no webhook, a URL.
+const (
+	OutboxKindSheets      = "sheets"
+	OutboxKindFile        = "file"
+	OutboxKindWebhook     = "webhook"
+	OutboxTargetBaseDados = "Base_dados"
+)
+
+// Operações registradas em sync_outbox.op pelo envio concluído.
+const (
+	OutboxOpAppend = "append"
+	OutboxOpUpdate = "update"
+	OutboxOpDelete = "delete"
+)
+
+var (
+	// ErrOutboxState indica ação incompatível com o estado do item (retry
+	// de item em envio, descarte de item já enviado).
+	ErrOutboxState = errors.New("outbox: estado não permite a ação")
+	// ErrOutboxDuplicate indica retry de um item cujo censo e destino já
+	// têm outro item pendente.
+	ErrOutboxDuplicate = errors.New("outbox: já existe item pendente para o censo e destino")
+)
+
+// OutboxItem é um envio de sync_outbox com a identificação da escola.
+type OutboxItem struct {
+	ID            int64      `json:"id"`
+	CensusID      int        `json:"census_id"`
+	Kind          string     `json:"kind"`
+	Target        string     `json:"target"`
+	Status        string     `json:"status"`
+	Op            *string    `json:"op,omitempty"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DoneAt        *time.Time `json:"done_at,omitempty"`
+	SchoolID      int        `json:"school_id"`
+	Escola        string     `json:"nome_escola"`
+	INEP          string     `json:"codigo_inep"`
+	Dre           string     `json:"dre"`
+	Year          int        `json:"year"`
+	// Delivered indica que o último envio concluído do censo para o mesmo
+	// destino deixou a linha lá (append ou update); preenchido por Claim.
+	Delivered bool `json:"-"`
+}
+
+// SyncSink é um destino registrado em sync_sinks.
+type SyncSink struct {
+	Kind      string    `json:"kind"`
+	Target    string    `json:"target"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SyncOutboxModel struct {
+	DB *sql.DB
+}
+
+// outboxExecer é o pool ou a transação em que o item é enfileirado.
+type outboxExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// enqueueCensusSync enfileira um envio do censo para cada destino
+// habilitado em sync_sinks; com item pendente para o mesmo censo e
+// destino, não faz nada (o envio lê o censo no momento do processamento).
+func enqueueCensusSync(ctx context.Context, db outboxExecer, censusID int) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO sync_outbox (census_id, kind, target)
+		SELECT $1, kind, target FROM sync_sinks WHERE enabled
+		ON CONFLICT (census_id, kind, target) WHERE status = 'pending' DO NOTHING`, censusID)
+	return err
+}
+
+const outboxSelect = `
+	SELECT o.id, o.census_id, o.kind, o.target, o.status, o.op, o.attempts, o.next_attempt_at, o.last_error,
+	       o.created_at, o.updated_at, o.done_at,
+	       cr.school_id, COALESCE(s.nome_escola, ''), COALESCE(s.codigo_inep, ''), COALESCE(s.dre, ''), cr.year,
+	       FALSE
+	FROM sync_outbox o
+	JOIN census_responses cr ON cr.id = o.census_id
+	JOIN schools s ON s.id = cr.school_id`
+
+func scanOutboxItems(rows *sql.Rows) ([]OutboxItem, error) {
+	defer rows.Close()
+	out := []OutboxItem{}
+	for rows.Next() {
+		var it OutboxItem
+		if err := rows.Scan(&it.ID, &it.CensusID, &it.Kind, &it.Target, &it.Status, &it.Op, &it.Attempts,
+			&it.NextAttemptAt, &it.LastError, &it.CreatedAt, &it.UpdatedAt, &it.DoneAt,
+			&it.SchoolID, &it.Escola, &it.INEP, &it.Dre, &it.Year, &it.Delivered); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// Claim reserva até limit itens vencidos de destinos habilitados,
+// marcando-os como 'processing' e contando a tentativa. Itens em 'processing' há mais de staleAfter (API
+// reiniciada no meio do envio) voltam para a fila antes. FOR UPDATE SKIP
+// LOCKED permite mais de uma réplica processando a fila. Como em Fail, o
+// item vencido com outro pendente para o mesmo destino é descartado.
+func (m *SyncOutboxModel) Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]OutboxItem, error) {
+	if _, err := m.DB.ExecContext(ctx, `
+		UPDATE sync_outbox o
+		SET status = CASE WHEN EXISTS (
+		            SELECT 1 FROM sync_outbox p
+		            WHERE p.status = 'pending' AND p.census_id = o.census_id
+		              AND p.kind = o.kind AND p.target = o.target)
+		        THEN 'discarded' ELSE 'pending' END,
+		    locked_at = NULL, updated_at = NOW()
+		WHERE o.status = 'processing' AND o.locked_at < NOW() - make_interval(secs => $1::float8)`,
+		staleAfter.Seconds()); err != nil {
+		return nil, err
+	}
+	rows, err := m.DB.QueryContext(ctx, `
+		WITH claimed AS (
+			UPDATE sync_outbox SET status = 'processing', attempts = attempts + 1,
+			       locked_at = NOW(), updated_at = NOW()
+			WHERE id IN (
+				SELECT id FROM sync_outbox
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				  AND (kind, target) IN (SELECT kind, target FROM sync_sinks WHERE enabled)
+				ORDER BY next_attempt_at, id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED)
+			RETURNING *)
+		SELECT o.id, o.census_id, o.kind, o.target, o.status, o.op, o.attempts, o.next_attempt_at, o.last_error,
+		       o.created_at, o.updated_at, o.done_at,
+		       cr.school_id, COALESCE(s.nome_escola, ''), COALESCE(s.codigo_inep, ''), COALESCE(s.dre, ''), cr.year,
+		       COALESCE((
+		           SELECT COALESCE(d.op, 'append') <> 'delete' FROM sync_outbox d
+		           WHERE d.census_id = o.census_id AND d.kind = o.kind AND d.target = o.target
+		             AND d.status = 'done'
+		           ORDER BY d.done_at DESC, d.id DESC
+		           LIMIT 1), FALSE)
+		FROM claimed o
+		JOIN census_responses cr ON cr.id = o.census_id
+		JOIN schools s ON s.id = cr.school_id
+		ORDER BY o.next_attempt_at, o.id`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxItems(rows)
+}
+
+// Complete marca o item como enviado com a operação feita. O envio para a
+// planilha também marca census_responses.sheet_synced_at (limpo quando a
+// linha saiu).
+func (m *SyncOutboxModel) Complete(ctx context.Context, item OutboxItem, op string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sync_outbox SET status = 'done', op = $2, last_error = NULL, locked_at = NULL,
+		       done_at = NOW(), updated_at = NOW()
+		WHERE id = $1`, item.ID, op); err != nil {
+		return err
+	}
+	if item.Kind == OutboxKindSheets {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE census_responses
+			SET sheet_synced_at = CASE WHEN $2 THEN NULL ELSE NOW() END
+			WHERE id = $1`, item.CensusID, op == OutboxOpDelete); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Fail registra a falha: volta para 'pending' em next, ou 'dead' quando as
+// tentativas se esgotaram. Se outro item pendente do mesmo censo e destino
+// surgiu durante o envio, este é descartado em favor dele.
+func (m *SyncOutboxModel) Fail(ctx context.Context, id int64, lastError string, next time.Time, dead bool) error {
+	status := OutboxPending
+	if dead {
+		status = OutboxDead
+	}
+	_, err := m.DB.ExecContext(ctx, `
+		UPDATE sync_outbox o
+		SET status = CASE
+		        WHEN $2::text = 'pending' AND EXISTS (
+		            SELECT 1 FROM sync_outbox p
+		            WHERE p.status = 'pending' AND p.census_id = o.census_id
+		              AND p.kind = o.kind AND p.target = o.target)
+		        THEN 'discarded' ELSE $2::text END,
+		    last_error = $3, next_attempt_at = $4, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1`, id, status, lastError, next)
+	return err
+}
+
+// List devolve os itens do estado pedido, dos mais recentes aos mais
+// antigos. status "" lista os travados: 'dead' e 'pending' que já falharam.
+func (m *SyncOutboxModel) List(ctx context.Context, status string, limit int) ([]OutboxItem, error) {
+	where := `WHERE o.status = $1`
+	if status == "" {
+		where = `WHERE ($1::text = '' AND (o.status = 'dead' OR (o.status = 'pending' AND o.attempts > 0)))`
+	}
+	rows, err := m.DB.QueryContext(ctx, outboxSelect+" "+where+`
+		ORDER BY o.updated_at DESC, o.id DESC
+		LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxItems(rows)
+}
+
+// Counts devolve o número de itens por estado.
+func (m *SyncOutboxModel) Counts(ctx context.Context) (map[string]int, error) {
+	rows, err := m.DB.QueryContext(ctx, `SELECT status, COUNT(*) FROM sync_outbox GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{OutboxPending: 0, OutboxProcessing: 0, OutboxDone: 0, OutboxDead: 0, OutboxDiscarded: 0}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		out[status] = n
+	}
+	return out, rows.Err()
+}
+
+// Retry devolve um item 'dead', 'discarded' ou 'pending' para a fila com as
+// tentativas zeradas e envio imediato. sql.ErrNoRows quando não existe.
+func (m *SyncOutboxModel) Retry(ctx context.Context, id int64) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var duplicate bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT o.status, EXISTS (
+			SELECT 1 FROM sync_outbox p
+			WHERE p.status = 'pending' AND p.id <> o.id AND p.census_id = o.census_id
+			  AND p.kind = o.kind AND p.target = o.target)
+		FROM sync_outbox o WHERE o.id = $1 FOR UPDATE`, id).Scan(&status, &duplicate); err != nil {
+		return err
+	}
+	switch {
+	case status == OutboxProcessing || status == OutboxDone:
+		return ErrOutboxState
+	case duplicate:
+		return ErrOutboxDuplicate
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sync_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Discard tira da fila um item 'pending' ou 'dead'. sql.ErrNoRows quando
+// não existe.
+func (m *SyncOutboxModel) Discard(ctx context.Context, id int64) error {
+	var status string
+	err := m.DB.QueryRowContext(ctx, `
+		UPDATE sync_outbox o SET status = 'discarded', updated_at = NOW()
+		FROM (SELECT id, status FROM sync_outbox WHERE id = $1 FOR UPDATE) prev
+		WHERE o.id = prev.id AND prev.status IN ('pending', 'dead')
+		RETURNING prev.status`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM sync_outbox WHERE id = $1)`, id).
+			Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrOutboxState
+		}
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// RequeueUnsynced enfileira, para cada destino habilitado, os censos
+// enviados que ainda não chegaram a ele e não têm item pendente ou em
+// envio, e antecipa os pendentes para agora. Na planilha, "não chegou" é
+// sheet_synced_at vazio; nos demais destinos, nenhum envio concluído
+// depois da última alteração do censo. Devolve quantos itens ficaram
+// pendentes.
+func (m *SyncOutboxModel) RequeueUnsynced(ctx context.Context) (int, error) {
+	if _, err := m.DB.ExecContext(ctx, `
+		INSERT INTO sync_outbox (census_id, kind, target)
+		SELECT cr.id, k.kind, k.target
+		FROM census_responses cr
+		CROSS JOIN sync_sinks k
+		WHERE k.enabled AND censo_enviado(cr.status)
+		  AND CASE WHEN k.kind = $1 THEN cr.sheet_synced_at IS NULL
+		           ELSE NOT EXISTS (
+		               SELECT 1 FROM sync_outbox d
+		               WHERE d.census_id = cr.id AND d.kind = k.kind AND d.target = k.target
+		                 AND d.status = 'done' AND d.done_at >= cr.updated_at)
+		      END
+		  AND NOT EXISTS (
+			SELECT 1 FROM sync_outbox o
+			WHERE o.census_id = cr.id AND o.kind = k.kind AND o.target = k.target
+			  AND o.status IN ('pending', 'processing'))
+		ON CONFLICT (census_id, kind, target) WHERE status = 'pending' DO NOTHING`,
+		OutboxKindSheets); err != nil {
+		return 0, err
+	}
+	res, err := m.DB.ExecContext(ctx, `
+		UPDATE sync_outbox SET next_attempt_at = NOW(), updated_at = NOW()
+		WHERE status = 'pending'`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// RegisterSinks grava os destinos configurados nesta instância como
+// habilitados e desabilita os demais, inclusive o target anterior de um
+// kind configurado; itens de destino desabilitado ficam na fila sem serem
+// reservados.
+func (m *SyncOutboxModel) RegisterSinks(ctx context.Context, sinks []SyncSink) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, k := range sinks {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO sync_sinks (kind, target, enabled, updated_at)
+			VALUES ($1, $2, TRUE, NOW())
+			ON CONFLICT (kind, target) DO UPDATE
+			SET enabled = TRUE, updated_at = NOW()
+			WHERE NOT sync_sinks.enabled`,
+			k.Kind, k.Target); err != nil {
+			return err
+		}
+	}
+	if sinks == nil {
+		sinks = []SyncSink{}
+	}
+	sinksJSON, err := json.Marshal(sinks)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sync_sinks SET enabled = FALSE, updated_at = NOW()
+		WHERE enabled AND NOT EXISTS (
+			SELECT 1 FROM jsonb_to_recordset($1::jsonb) AS r(kind TEXT, target TEXT)
+			WHERE r.kind = sync_sinks.kind AND r.target = sync_sinks.target)`,
+		string(sinksJSON)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Sinks lista os destinos registrados.
+func (m *SyncOutboxModel) Sinks(ctx context.Context) ([]SyncSink, error) {
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT kind, target, enabled, updated_at FROM sync_sinks ORDER BY kind, target`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SyncSink{}
+	for rows.Next() {
+		var k SyncSink
+		if err := rows.Scan(&k.Kind, &k.Target, &k.Enabled, &k.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// GetByID devolve o censo pelo id (nil, nil quando não existe).
+func (m *CensusModel) GetByID(ctx context.Context, id int) (*CensusResponse, error) {
+	var c CensusResponse
+	var data []byte
+	err := m.DB.QueryRowContext(ctx, `
+		SELECT id, school_id, year, status, COALESCE(data, '{}'::jsonb), version, sheet_synced_at, created_at, updated_at
+		FROM census_responses WHERE id = $1`, id).Scan(
+		&c.ID, &c.SchoolID, &c.Year, &c.Status, &data, &c.Version, &c.SheetSyncedAt, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Data = json.RawMessage(data)
+	return &c, nil
+}
@@ -67,7 +67,9 @@ func NewDriveService() (*DriveService, error) {
 	return &DriveService{srv: srv}, nil
 }
 
-func (s *DriveService) UploadSchoolPhoto(folderName string, fileName string, contentType string, fileContent io.Reader) (string, error) {
+// uploadToSchoolFolder grava o arquivo na pasta da escola (criada quando
+// não existe) e devolve o id e o link de visualização.
+func (s *DriveService) uploadToSchoolFolder(folderName string, fileName string, contentType string, fileContent io.Reader) (string, string, error) {
 	// Nome canônico documentado no .env.example é DRIVE_ROOT_FOLDER_ID.
 	// Mantemos fallback para DRIVER_ROOT_FOLDER_ID (nome legado usado no código
 	// antigo) para não quebrar ambientes já configurados com a chave antiga.
@@ -76,7 +78,7 @@ func (s *DriveService) UploadSchoolPhoto(folderName string, fileName string, con
 		rootFolderID = os.Getenv("DRIVER_ROOT_FOLDER_ID")
 	}
 	if rootFolderID == "" {
-		return "", fmt.Errorf("DRIVE_ROOT_FOLDER_ID não configurado")
+		return "", "", fmt.Errorf("DRIVE_ROOT_FOLDER_ID não configurado")
 	}
 
 	// Tenta rebobinar o arquivo se ele for um ReadSeeker (segurança extra)
@@ -98,7 +100,7 @@ func (s *DriveService) UploadSchoolPhoto(folderName string, fileName string, con
 		Do()
 
 	if err != nil {
-		return "", fmt.Errorf("erro buscar pasta: %v", err)
+		return "", "", fmt.Errorf("erro buscar pasta: %v", err)
 	}
 
 	var schoolFolderID string
@@ -118,7 +120,7 @@ func (s *DriveService) UploadSchoolPhoto(folderName string, fileName string, con
 			Do()
 
 		if err != nil {
-			return "", fmt.Errorf("erro criar pasta: %v", err)
+			return "", "", fmt.Errorf("erro criar pasta: %v", err)
 		}
 		schoolFolderID = folder.Id
 		log.Printf("[Drive] Nova pasta criada: %s (%s)", folderName, schoolFolderID)
@@ -139,7 +141,7 @@ func (s *DriveService) UploadSchoolPhoto(folderName string, fileName string, con
 
 	if err != nil {
 		// Loga o erro exato para debug
-		return "", fmt.Errorf("erro upload arquivo: %v", err)
+		return "", "", fmt.Errorf("erro upload arquivo: %v", err)
 	}
 
 	log.Printf("[Drive] Arquivo enviado com sucesso! ID: %s", uploadedFile.Id)
@@ -149,5 +151,5 @@ func (s *DriveService) UploadSchoolPhoto(folderName string, fileName string, con
 		link = fmt.Sprintf("https://drive.google.com/file/d/%s/view", uploadedFile.Id)
 	}
 
-	return link, nil
+	return uploadedFile.Id, link, nil
 }
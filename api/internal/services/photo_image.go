@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// ─── Preparo das fotos: metadados e miniatura ────────────────────────────────
+//
+// PreparePhoto limpa a foto antes de ela sair do servidor. Fotos de celular
+// trazem EXIF com coordenadas GPS, modelo do aparelho e data; nada disso
+// deve ser gravado.
+//
+//   - JPEG: decodificada, girada conforme a orientação do EXIF e
+//     recodificada (qualidade 90), o que descarta EXIF, XMP e demais
+//     segmentos APPn
+//   - PNG:  os chunks são copiados sem recodificar, exceto eXIf, textos
+//     (tEXt, zTXt, iTXt), tIME e chunks desconhecidos
+//   - GIF:  só o primeiro quadro, recodificado sem as extensões de
+//     comentário e aplicação (onde fica o XMP); decodificar todos os
+//     quadros deixaria um GIF pequeno alocar quadros × área em memória
+//   - WebP: sem decodificador na biblioteca padrão, os chunks EXIF e XMP
+//     saem do RIFF e a foto fica sem miniatura
+//
+// A miniatura é um JPEG de até PhotoThumbSize pixels no maior lado.
+
+const (
+	// PhotoThumbSize é o maior lado da miniatura, em pixels.
+	PhotoThumbSize = 320
+	// PhotoMaxPixels limita a área da foto decodificada (uma imagem de
+	// 40 MP ocupa 160 MB em RGBA); acima disso o upload é recusado.
+	PhotoMaxPixels = 40_000_000
+
+	photoJPEGQuality = 90
+	thumbJPEGQuality = 80
+)
+
+// ErrPhotoTooLarge indica foto com área acima de PhotoMaxPixels.
+var ErrPhotoTooLarge = errors.New("foto com resolução acima do limite")
+
+// PreparedPhoto é a foto limpa com a miniatura (nil quando o formato não
+// permite gerá-la) e as dimensões; Width e Height ficam zerados para WebP.
+type PreparedPhoto struct {
+	Data        []byte
+	ContentType string
+	Thumb       []byte
+	Width       int
+	Height      int
+}
+
+// PreparePhoto remove os metadados da foto e gera a miniatura. contentType
+// é o tipo detectado pelo conteúdo (http.DetectContentType).
+func PreparePhoto(data []byte, contentType string) (*PreparedPhoto, error) {
+	switch contentType {
+	case "image/jpeg":
+		return prepareJPEG(data)
+	case "image/png":
+		return preparePNG(data)
+	case "image/gif":
+		return prepareGIF(data)
+	case "image/webp":
+		clean, err := stripWebPMetadata(data)
+		if err != nil {
+			return nil, err
+		}
+		return &PreparedPhoto{Data: clean, ContentType: contentType}, nil
+	}
+	return nil, fmt.Errorf("tipo de imagem não suportado: %s", contentType)
+}
+
+func checkPhotoSize(cfg image.Config) error {
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return fmt.Errorf("dimensões da imagem inválidas")
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > PhotoMaxPixels {
+		return ErrPhotoTooLarge
+	}
+	return nil
+}
+
+func prepareJPEG(data []byte) (*PreparedPhoto, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("JPEG inválido: %v", err)
+	}
+	if err := checkPhotoSize(cfg); err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("JPEG inválido: %v", err)
+	}
+	rgba := orient(toRGBA(img), jpegOrientation(data))
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, rgba, &jpeg.Options{Quality: photoJPEGQuality}); err != nil {
+		return nil, err
+	}
+	thumb, err := thumbnail(rgba)
+	if err != nil {
+		return nil, err
+	}
+	b := rgba.Bounds()
+	return &PreparedPhoto{Data: out.Bytes(), ContentType: "image/jpeg", Thumb: thumb, Width: b.Dx(), Height: b.Dy()}, nil
+}
+
+func preparePNG(data []byte) (*PreparedPhoto, error) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("PNG inválido: %v", err)
+	}
+	if err := checkPhotoSize(cfg); err != nil {
+		return nil, err
+	}
+	clean, err := stripPNGMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(bytes.NewReader(clean))
+	if err != nil {
+		return nil, fmt.Errorf("PNG inválido: %v", err)
+	}
+	thumb, err := thumbnail(toRGBA(img))
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedPhoto{Data: clean, ContentType: "image/png", Thumb: thumb, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+func prepareGIF(data []byte) (*PreparedPhoto, error) {
+	cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("GIF inválido: %v", err)
+	}
+	if err := checkPhotoSize(cfg); err != nil {
+		return nil, err
+	}
+	img, err := gif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("GIF inválido: %v", err)
+	}
+	var out bytes.Buffer
+	if err := gif.Encode(&out, img, nil); err != nil {
+		return nil, err
+	}
+	thumb, err := thumbnail(toRGBA(img))
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedPhoto{Data: out.Bytes(), ContentType: "image/gif", Thumb: thumb, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// ─── EXIF: orientação ────────────────────────────────────────────────────────
+
+// jpegOrientation lê a tag Orientation (0x0112) do EXIF da JPEG; 1 quando
+// ausente ou ilegível.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 || marker == 0xFF {
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return exifOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation procura a orientação no IFD0 do bloco TIFF.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	if bo.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+	ifd := int(bo.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	n := int(bo.Uint16(tiff[ifd:]))
+	for k := 0; k < n; k++ {
+		e := ifd + 2 + 12*k
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if bo.Uint16(tiff[e:]) == 0x0112 && bo.Uint16(tiff[e+2:]) == 3 {
+			if o := int(bo.Uint16(tiff[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient aplica a orientação do EXIF (2 a 8: espelhamentos e rotações).
+func orient(src *image.RGBA, o int) *image.RGBA {
+	if o <= 1 || o > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			si := src.PixOffset(b.Min.X+x, b.Min.Y+y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// ─── Miniatura ───────────────────────────────────────────────────────────────
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// thumbnail reduz a imagem por média de área até PhotoThumbSize no maior
+// lado e devolve o JPEG. Imagens menores só são recodificadas.
+func thumbnail(src *image.RGBA) ([]byte, error) {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > PhotoThumbSize || h > PhotoThumbSize {
+		if w >= h {
+			tw, th = PhotoThumbSize, max(1, h*PhotoThumbSize/w)
+		} else {
+			tw, th = max(1, w*PhotoThumbSize/h), PhotoThumbSize
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := ty*h/th, max((ty+1)*h/th, ty*h/th+1)
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := tx*w/tw, max((tx+1)*w/tw, tx*w/tw+1)
+			var r, g, bl, a, n uint64
+			for y := y0; y < y1; y++ {
+				i := src.PixOffset(b.Min.X+x0, b.Min.Y+y)
+				for x := x0; x < x1; x++ {
+					r += uint64(src.Pix[i])
+					g += uint64(src.Pix[i+1])
+					bl += uint64(src.Pix[i+2])
+					a += uint64(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			di := dst.PixOffset(tx, ty)
+			dst.Pix[di] = uint8(r / n)
+			dst.Pix[di+1] = uint8(g / n)
+			dst.Pix[di+2] = uint8(bl / n)
+			dst.Pix[di+3] = uint8(a / n)
+		}
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: thumbJPEGQuality}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// ─── PNG e WebP: remoção de chunks ───────────────────────────────────────────
+
+// pngKeepChunks são os chunks de imagem preservados; os demais (eXIf,
+// tEXt, zTXt, iTXt, tIME e privados) saem.
+var pngKeepChunks = map[string]bool{
+	"IHDR": true, "PLTE": true, "IDAT": true, "IEND": true,
+	"tRNS": true, "gAMA": true, "cHRM": true, "sRGB": true, "iCCP": true,
+	"sBIT": true, "bKGD": true, "pHYs": true, "hIST": true, "sPLT": true,
+	"acTL": true, "fcTL": true, "fdAT": true,
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("PNG inválido")
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+	for i := len(pngSignature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, fmt.Errorf("PNG truncado")
+		}
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) {
+			return nil, fmt.Errorf("PNG truncado")
+		}
+		typ := string(data[i+4 : i+8])
+		if pngKeepChunks[typ] {
+			out.Write(data[i:end])
+		}
+		i = end
+		if typ == "IEND" {
+			break
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// Bits de VP8X que anunciam os chunks EXIF e XMP.
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("WebP inválido")
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, fmt.Errorf("WebP truncado")
+		}
+		fourcc := string(data[i : i+4])
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + n + n%2
+		if n < 0 || i+8+n > len(data) {
+			return nil, fmt.Errorf("WebP truncado")
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+		switch fourcc {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if n > 0 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	clean := out.Bytes()
+	binary.LittleEndian.PutUint32(clean[4:], uint32(len(clean)-8))
+	return clean, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ─── Disco local ─────────────────────────────────────────────────────────────
+//
+// LocalPhotoStore grava as fotos em dir/PhotoKey. O diretório deve ficar
+// num volume persistente: o contêiner da API é descartável. Path guarda o
+// caminho relativo a dir, para que o volume possa mudar de lugar.
+
+type LocalPhotoStore struct {
+	dir string
+}
+
+// NewLocalPhotoStore prepara o diretório e devolve o armazenamento.
+func NewLocalPhotoStore(dir string) (*LocalPhotoStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
+	}
+	return &LocalPhotoStore{dir: dir}, nil
+}
+
+// Kind implementa PhotoStore.
+func (l *LocalPhotoStore) Kind() string { return "local" }
+
+// Put implementa PhotoStore. A escrita passa por um arquivo temporário no
+// mesmo diretório e rename: uma falha no meio não deixa foto truncada.
+func (l *LocalPhotoStore) Put(_ context.Context, obj PhotoObject, r io.Reader) (StoredPhoto, error) {
+	key := PhotoKey(obj)
+	dst := filepath.Join(l.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return StoredPhoto{}, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".foto-*")
+	if err != nil {
+		return StoredPhoto{}, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return StoredPhoto{}, fmt.Errorf("erro ao gravar foto: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return StoredPhoto{}, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return StoredPhoto{}, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return StoredPhoto{}, err
+	}
+	return StoredPhoto{Path: key}, nil
+}
+
+// Open implementa PhotoStore: path é relativo ao diretório base.
+func (l *LocalPhotoStore) Open(_ context.Context, path string) (io.ReadCloser, error) {
+	if !filepath.IsLocal(filepath.FromSlash(path)) {
+		return nil, fmt.Errorf("caminho de foto inválido: %q", path)
+	}
+	return os.Open(filepath.Join(l.dir, filepath.FromSlash(path)))
+}
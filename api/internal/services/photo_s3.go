@@ -0,0 +1,191 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ─── Armazenamento compatível com S3 ─────────────────────────────────────────
+//
+// S3PhotoStore grava as fotos com PUT no endpoint configurado (AWS S3,
+// MinIO, R2...), em estilo de caminho: {endpoint}/{bucket}/{prefixo}PhotoKey.
+// A requisição é assinada com AWS Signature V4; o sha256 do conteúdo já
+// calculado no upload vai em x-amz-content-sha256, então o corpo não
+// precisa ser lido duas vezes. Path guarda a chave do objeto e URL o
+// endereço dele no endpoint (acessível só se o bucket for público).
+
+const s3Timeout = 60 * time.Second
+
+// S3Config configura o S3PhotoStore.
+type S3Config struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// Prefix vai à frente de cada chave (ex.: "censo/").
+	Prefix string
+}
+
+type S3PhotoStore struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	// now permite fixar o relógio da assinatura nos testes.
+	now func() time.Time
+}
+
+// NewS3PhotoStore valida a configuração e devolve o armazenamento.
+func NewS3PhotoStore(cfg S3Config) (*S3PhotoStore, error) {
+	u, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("endpoint S3 inválido: %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("bucket e credenciais S3 são obrigatórios")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3PhotoStore{cfg: cfg, endpoint: u, client: &http.Client{Timeout: s3Timeout}, now: time.Now}, nil
+}
+
+// Kind implementa PhotoStore.
+func (s *S3PhotoStore) Kind() string { return "s3" }
+
+// Put implementa PhotoStore.
+func (s *S3PhotoStore) Put(ctx context.Context, obj PhotoObject, r io.Reader) (StoredPhoto, error) {
+	key := s.cfg.Prefix + PhotoKey(obj)
+	u := s.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), r)
+	if err != nil {
+		return StoredPhoto{}, err
+	}
+	req.ContentLength = obj.Size
+	req.Header.Set("Content-Type", obj.ContentType)
+	s.sign(req, obj.SHA256)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return StoredPhoto{}, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return StoredPhoto{Path: key, URL: u.String()}, nil
+}
+
+// Open implementa PhotoStore: GET assinado da chave do objeto.
+func (s *S3PhotoStore) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	u := s.objectURL(path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, emptySHA256)
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// emptySHA256 é o hash do corpo vazio, usado na assinatura do GET.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// objectURL é o endereço do objeto em estilo de caminho.
+func (s *S3PhotoStore) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	u.Path = u.Path + "/" + s.cfg.Bucket + "/" + key
+	u.RawPath = s3URIEncode(u.Path)
+	return &u
+}
+
+// do envia a requisição; resposta fora de 2xx vira erro com o corpo.
+func (s *S3PhotoStore) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("s3 respondeu %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return resp, nil
+}
+
+// sign assina a requisição (AWS Signature V4) com os cabeçalhos host,
+// x-amz-content-sha256, x-amz-date e content-type quando presente.
+func (s *S3PhotoStore) sign(req *http.Request, payloadSHA string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadSHA)
+
+	var headers, signed []string
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		headers = append(headers, "content-type:"+ct+"\n")
+		signed = append(signed, "content-type")
+	}
+	headers = append(headers,
+		"host:"+req.URL.Host+"\n",
+		"x-amz-content-sha256:"+payloadSHA+"\n",
+		"x-amz-date:"+amzDate+"\n")
+	signed = append(signed, "host", "x-amz-content-sha256", "x-amz-date")
+	signedHeaders := strings.Join(signed, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		strings.Join(headers, ""),
+		signedHeaders,
+		payloadSHA,
+	}, "\n")
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	sig := hmacSHA256(S3SigningKey(s.cfg.SecretKey, date, s.cfg.Region, "s3"), toSign)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, hex.EncodeToString(sig)))
+}
+
+// S3SigningKey deriva a chave de assinatura V4 do dia, região e serviço.
+func S3SigningKey(secret, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3URIEncode codifica o caminho como a assinatura V4 espera: só letras,
+// dígitos, "-_.~" e "/" passam sem escape.
+func s3URIEncode(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
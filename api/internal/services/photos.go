@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"censo-api/internal/models"
+)
+
+// ─── Armazenamento das fotos das escolas ─────────────────────────────────────
+//
+// Um PhotoStore grava cada foto no momento do upload; os metadados (escola,
+// ano, caminho, tipo, tamanho e sha256) ficam em school_photos. Put deve
+// ser seguro para repetir: a mesma foto gravada de novo substitui o arquivo
+// ou cria uma cópia, nunca falha por já existir. Open devolve o conteúdo
+// gravado a partir do Path, para a galeria do painel.
+
+// PhotoObject descreve a foto a gravar.
+type PhotoObject struct {
+	School models.School
+	Year   int
+	// Category é a categoria da foto (fachada, cozinha...).
+	Category string
+	// Name é o nome original do arquivo, já saneado.
+	Name        string
+	ContentType string
+	Size        int64
+	// SHA256 é o hash do conteúdo em hexadecimal.
+	SHA256 string
+}
+
+// StoredPhoto é a localização da foto gravada: Path no armazenamento (id do
+// arquivo, caminho relativo ou chave do objeto) e URL de visualização,
+// vazia quando o armazenamento não oferece uma.
+type StoredPhoto struct {
+	Path string
+	URL  string
+}
+
+// PhotoStore é um armazenamento de fotos.
+type PhotoStore interface {
+	// Kind identifica o armazenamento em school_photos.storage.
+	Kind() string
+	Put(ctx context.Context, obj PhotoObject, r io.Reader) (StoredPhoto, error)
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
+}
+
+var photoExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// PhotoKey é o caminho da foto nos armazenamentos de arquivo e objeto:
+// {school_id}/{ano}/{sha256}{ext}. O conteúdo define o nome, então a mesma
+// foto enviada duas vezes cai no mesmo arquivo.
+func PhotoKey(obj PhotoObject) string {
+	ext, ok := photoExts[obj.ContentType]
+	if !ok {
+		ext = strings.ToLower(filepath.Ext(obj.Name))
+	}
+	return fmt.Sprintf("%d/%d/%s%s", obj.School.ID, obj.Year, obj.SHA256, ext)
+}
+
+// ─── Google Drive ────────────────────────────────────────────────────────────
+
+// Kind implementa PhotoStore.
+func (s *DriveService) Kind() string { return "drive" }
+
+// Put implementa PhotoStore: grava na pasta da escola (DriveFolderName) com
+// ano e categoria à frente do nome original.
+func (s *DriveService) Put(_ context.Context, obj PhotoObject, r io.Reader) (StoredPhoto, error) {
+	name := fmt.Sprintf("%d_%s", obj.Year, obj.Name)
+	if obj.Category != "" {
+		name = fmt.Sprintf("%d_%s_%s", obj.Year, obj.Category, obj.Name)
+	}
+	id, link, err := s.uploadToSchoolFolder(DriveFolderName(obj.School), name, obj.ContentType, r)
+	if err != nil {
+		return StoredPhoto{}, err
+	}
+	return StoredPhoto{Path: id, URL: link}, nil
+}
+
+// Open implementa PhotoStore: path é o id do arquivo no Drive.
+func (s *DriveService) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := s.srv.Files.Get(path).SupportsAllDrives(true).Context(ctx).Download()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao baixar arquivo: %v", err)
+	}
+	return resp.Body, nil
+}
+
+// DriveFolderName é a pasta da escola no Drive: "INEP - Nome" (ou
+// "Escola {id} - Nome" sem INEP), só com letras, dígitos e espaços. A
+// pasta não leva mais o nome do diretor, que muda com a gestão.
+func DriveFolderName(school models.School) string {
+	sanitize := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == ' ' {
+				return r
+			}
+			return '-'
+		}, s)
+	}
+	id := school.INEP
+	if id == "" {
+		id = fmt.Sprintf("Escola %d", school.ID)
+	}
+	return fmt.Sprintf("%s - %s", sanitize(id), sanitize(school.Nome))
+}
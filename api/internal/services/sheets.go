@@ -11,17 +11,21 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/xuri/excelize/v2"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 const SheetRange = "Base_dados!A:A"
 
+// BaseDadosSheet é a aba com uma linha por censo enviado.
+const BaseDadosSheet = "Base_dados"
+
 type SheetsService struct {
 	srv                 *sheets.Service
 	censusSpreadsheetID string
-	localFilePath       string
+	// legacyYear é o ano das linhas gravadas antes da chave_censo
+	// (SHEETS_LEGACY_YEAR); zero quando não configurado.
+	legacyYear int
 }
 
 func NewSheetsService() (*SheetsService, error) {
@@ -55,94 +59,124 @@ func NewSheetsService() (*SheetsService, error) {
 		return nil, fmt.Errorf("ERRO: Variável SPREADSHEET_ID não configurada")
 	}
 
+	legacyYear := 0
+	if raw := strings.TrimSpace(os.Getenv("SHEETS_LEGACY_YEAR")); raw != "" {
+		legacyYear, err = strconv.Atoi(raw)
+		if err != nil || legacyYear < 2000 {
+			return nil, fmt.Errorf("SHEETS_LEGACY_YEAR inválido: %q", raw)
+		}
+	}
+
 	return &SheetsService{
 		srv:                 srv,
 		censusSpreadsheetID: censusID,
-		localFilePath:       "data/locations.xlsx",
+		legacyYear:          legacyYear,
 	}, nil
 }
 
-func (s *SheetsService) GetLocations() (map[string]map[string][]string, error) {
-	f, err := excelize.OpenFile(s.localFilePath)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir planilha local: %v", err)
-	}
-	defer f.Close()
-
-	sheetName := f.GetSheetName(0)
-	rows, err := f.GetRows(sheetName)
-	if err != nil {
-		return nil, err
+// censoValues decodifica o JSON do censo e devolve o leitor de campos das
+// abas: listas viram texto separado por vírgula, chave ausente vira "".
+func censoValues(censo models.CensusResponse) (func(key string) interface{}, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(censo.Data, &data); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar JSON: %v", err)
 	}
-
-	mapping := make(map[string]map[string][]string)
-
-	for i, row := range rows {
-		if i == 0 {
-			continue
+	return func(key string) interface{} {
+		if v, ok := data[key]; ok {
+			if arr, ok := v.([]interface{}); ok {
+				strs := make([]string, len(arr))
+				for i, item := range arr {
+					strs[i] = fmt.Sprint(item)
+				}
+				return strings.Join(strs, ", ")
+			}
+			return v
 		}
+		return ""
+	}, nil
+}
 
-		if len(row) <= 2 {
-			continue
-		}
+// deficitSheet é uma aba de déficit de pessoal: recebe uma linha quando o
+// campo do quantitativo necessário está preenchido e diferente de zero.
+type deficitSheet struct {
+	key      string
+	question string
+}
 
-		dre := strings.TrimSpace(row[0])
-		municipio := strings.TrimSpace(row[1])
-		escola := strings.TrimSpace(row[2])
+var deficitSheets = map[string]deficitSheet{
+	"Deficit_Portaria": {
+		key:      "quantitativo_necessario_portaria",
+		question: "Para atender plenamente à demanda atual da escola, quantos agentes de portaria faltam para completar a equipe?",
+	},
+	"Deficit_Servicos_Gerais": {
+		key:      "quantitativo_necessario_sg",
+		question: "Para atender plenamente à demanda atual da escola, quantas serviços gerais faltam para completar a equipe?",
+	},
+	"Deficit_Merenda": {
+		key:      "quantitativo_necessario_merenda",
+		question: "Para atender plenamente à demanda atual da merenda escolar, quantas merendeiras faltam para completar a equipe da cozinha?",
+	},
+}
 
-		if dre == "" || municipio == "" || escola == "" {
-			continue
-		}
+// deficitOrder é a ordem de envio das abas de déficit.
+var deficitOrder = []string{"Deficit_Portaria", "Deficit_Servicos_Gerais", "Deficit_Merenda"}
 
-		if mapping[dre] == nil {
-			mapping[dre] = make(map[string][]string)
-		}
+// deficitValue devolve o quantitativo do déficit como texto. Valores JSON
+// chegam como float64; fmt.Sprint evita comparação errada de tipos
+// (interface{float64} != int(0)).
+func deficitValue(val func(string) interface{}, key string) (string, bool) {
+	str := fmt.Sprint(val(key))
+	return str, str != "" && str != "0"
+}
 
-		found := false
-		for _, ex := range mapping[dre][municipio] {
-			if ex == escola {
-				found = true
-				break
-			}
-		}
-		if !found {
-			mapping[dre][municipio] = append(mapping[dre][municipio], escola)
-		}
+// upsertDeficit grava a linha do censo na aba de déficit sheetTitle,
+// atualizando a linha existente da escola e ano (ver sheets_rows.go) e
+// criando a aba com cabeçalho quando ainda não existe. Sem déficit, remove
+// a linha existente.
+func (s *SheetsService) upsertDeficit(sheetTitle string, rec CensusRecord) error {
+	censo, school := rec.Census, rec.School
+	def, ok := deficitSheets[sheetTitle]
+	if !ok {
+		return fmt.Errorf("aba de déficit desconhecida: %s", sheetTitle)
+	}
+	val, err := censoValues(censo)
+	if err != nil {
+		return err
 	}
-
-	for dre, cities := range mapping {
-		for city, schools := range cities {
-			sort.Strings(schools)
-			mapping[dre][city] = schools
-		}
+	str, ok := deficitValue(val, def.key)
+	if !ok {
+		return s.deleteSheetRow(sheetTitle, deficitINEPCol, deficitKeyCol, rec)
 	}
-
-	return mapping, nil
+	if err := s.ensureDeficitSheet(sheetTitle, def.question); err != nil {
+		return err
+	}
+	row := []interface{}{school.INEP, school.Nome, school.Dre, school.Municipio, str, rec.Key}
+	return s.upsertSheetRow(sheetTitle, deficitINEPCol, deficitKeyCol, rec, row)
 }
 
-func (s *SheetsService) AppendCenso(censo models.CensusResponse, school models.School) error {
+// upsertCensoRow grava a linha do censo em Base_dados: atualiza a linha da
+// escola e ano quando já existe e só acrescenta uma nova quando não há
+// (ver sheets_rows.go).
+func (s *SheetsService) upsertCensoRow(rec CensusRecord) error {
 	if s.censusSpreadsheetID == "" {
 		return fmt.Errorf("ID da planilha do Censo não configurado")
 	}
-
-	var data map[string]interface{}
-	err := json.Unmarshal(censo.Data, &data)
+	row, err := censoRow(rec.Census, rec.School)
 	if err != nil {
-		return fmt.Errorf("erro ao decodificar JSON: %v", err)
+		return err
 	}
+	row = append(row, rec.Key)
+	if err := s.upsertSheetRow(BaseDadosSheet, colINEP, len(row)-1, rec, row); err != nil {
+		return fmt.Errorf("erro ao escrever na planilha do censo: %v", err)
+	}
+	return nil
+}
 
-	val := func(key string) interface{} {
-		if v, ok := data[key]; ok {
-			if arr, ok := v.([]interface{}); ok {
-				strs := make([]string, len(arr))
-				for i, item := range arr {
-					strs[i] = fmt.Sprint(item)
-				}
-				return strings.Join(strs, ", ")
-			}
-			return v
-		}
-		return ""
+// censoRow monta as colunas de Base_dados para o censo, sem a chave.
+func censoRow(censo models.CensusResponse, school models.School) ([]interface{}, error) {
+	val, err := censoValues(censo)
+	if err != nil {
+		return nil, err
 	}
 
 	formatJsonField := func(raw json.RawMessage) string {
@@ -256,44 +290,12 @@ func (s *SheetsService) AppendCenso(censo models.CensusResponse, school models.S
 		val("nome_responsavel"), val("cargo_funcao"), val("matricula_funcional"), val("declaracao_verdadeira"),
 	}
 
-	vr := &sheets.ValueRange{Values: [][]interface{}{row}}
-
-	_, err = s.srv.Spreadsheets.Values.Append(s.censusSpreadsheetID, SheetRange, vr).ValueInputOption("RAW").Do()
-	if err != nil {
-		return fmt.Errorf("erro ao escrever na planilha do censo: %v", err)
-	}
-
-	// Valores JSON chegam como float64; usar fmt.Sprint evita comparação errada de tipos (interface{float64} != int(0))
-	if str := fmt.Sprint(val("quantitativo_necessario_portaria")); str != "" && str != "0" {
-		_ = s.ensureAndAppendDeficit(
-			"Deficit_Portaria",
-			"Para atender plenamente à demanda atual da escola, quantos agentes de portaria faltam para completar a equipe?",
-			str, school,
-		)
-	}
-
-	if str := fmt.Sprint(val("quantitativo_necessario_sg")); str != "" && str != "0" {
-		_ = s.ensureAndAppendDeficit(
-			"Deficit_Servicos_Gerais",
-			"Para atender plenamente à demanda atual da escola, quantas serviços gerais faltam para completar a equipe?",
-			str, school,
-		)
-	}
-
-	if str := fmt.Sprint(val("quantitativo_necessario_merenda")); str != "" && str != "0" {
-		_ = s.ensureAndAppendDeficit(
-			"Deficit_Merenda",
-			"Para atender plenamente à demanda atual da merenda escolar, quantas merendeiras faltam para completar a equipe da cozinha?",
-			str, school,
-		)
-	}
-
-	return nil
+	return row, nil
 }
 
 // ─── Dashboard metrics ────────────────────────────────────────────────────────
 
-// Colunas em Base_dados (0-based, mesma ordem do AppendCenso):
+// Colunas em Base_dados (0-based, mesma ordem do censoRow):
 // 0:NomeDiretor 1:Matricula 2:Contato 3:DRE 4:Nome 5:INEP 6:CNPJ
 // 7:Endereco 8:Telefone 9:Municipio 10:CEP 11:Zona 12:Turnos
 // 13:tipo_predio 14:possui_anexos 15:qtd_anexos 16:tipo_predio_anexo
@@ -628,54 +630,36 @@ func (s *SheetsService) GetIndicadoresMetrics() (*IndicadoresMetrics, error) {
 	}, nil
 }
 
-func (s *SheetsService) ensureAndAppendDeficit(sheetTitle string, questionText string, value interface{}, school models.School) error {
-	spreadsheet, err := s.srv.Spreadsheets.Get(s.censusSpreadsheetID).Do()
+// ensureDeficitSheet cria a aba de déficit com cabeçalho quando ainda não
+// existe.
+func (s *SheetsService) ensureDeficitSheet(sheetTitle string, questionText string) error {
+	ids, err := s.sheetIDs()
 	if err != nil {
 		return err
 	}
-
-	exists := false
-	for _, sheet := range spreadsheet.Sheets {
-		if sheet.Properties.Title == sheetTitle {
-			exists = true
-			break
-		}
+	if _, ok := ids[sheetTitle]; ok {
+		return nil
 	}
 
-	if !exists {
-		addSheetReq := &sheets.Request{
-			AddSheet: &sheets.AddSheetRequest{
-				Properties: &sheets.SheetProperties{
-					Title: sheetTitle,
-				},
+	addSheetReq := &sheets.Request{
+		AddSheet: &sheets.AddSheetRequest{
+			Properties: &sheets.SheetProperties{
+				Title: sheetTitle,
 			},
-		}
-		
-		_, err := s.srv.Spreadsheets.BatchUpdate(s.censusSpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
-			Requests: []*sheets.Request{addSheetReq},
-		}).Do()
-		
-		if err != nil {
-			return fmt.Errorf("erro ao criar aba %s: %v", sheetTitle, err)
-		}
-
-		header := []interface{}{"INEP", "Escola", "DRE", "Município", questionText}
-		headerVr := &sheets.ValueRange{Values: [][]interface{}{header}}
-		_, err = s.srv.Spreadsheets.Values.Append(s.censusSpreadsheetID, fmt.Sprintf("%s!A1", sheetTitle), headerVr).ValueInputOption("RAW").Do()
-		if err != nil {
-			return fmt.Errorf("erro ao escrever cabeçalho na aba %s: %v", sheetTitle, err)
-		}
+		},
+	}
+	_, err = s.srv.Spreadsheets.BatchUpdate(s.censusSpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{addSheetReq},
+	}).Do()
+	if err != nil {
+		return fmt.Errorf("erro ao criar aba %s: %v", sheetTitle, err)
 	}
 
-	row := []interface{}{
-		school.INEP,
-		school.Nome,
-		school.Dre,
-		school.Municipio,
-		value,
+	header := []interface{}{"INEP", "Escola", "DRE", "Município", questionText, sheetKeyHeader}
+	headerVr := &sheets.ValueRange{Values: [][]interface{}{header}}
+	_, err = s.srv.Spreadsheets.Values.Append(s.censusSpreadsheetID, fmt.Sprintf("%s!A1", sheetTitle), headerVr).ValueInputOption("RAW").Do()
+	if err != nil {
+		return fmt.Errorf("erro ao escrever cabeçalho na aba %s: %v", sheetTitle, err)
 	}
-	vr := &sheets.ValueRange{Values: [][]interface{}{row}}
-	_, err = s.srv.Spreadsheets.Values.Append(s.censusSpreadsheetID, fmt.Sprintf("%s!A:A", sheetTitle), vr).ValueInputOption("RAW").Do()
-	
-	return err
-}
\ No newline at end of file
+	return nil
+}
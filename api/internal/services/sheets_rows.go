@@ -0,0 +1,322 @@
+package services
+
+import (
+	"censo-api/internal/models"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// ─── Linhas por escola e ano ──────────────────────────────────────────────────
+//
+// Base_dados e as abas Deficit_* guardam uma linha por escola e ano. A
+// última coluna de cada linha é a chave (SheetKey, cabeçalho chave_censo):
+// o envio procura a linha pela chave e a atualiza no lugar, acrescentando
+// só quando ela não existe. A chave usa o id da escola, que não muda com a
+// troca de INEP; linhas com chave antiga (INEP/ano, ou a de uma escola
+// fundida nesta — CensusRecord.OldKeys) também são encontradas e recebem a
+// chave atual. Linhas gravadas antes da chave (coluna vazia) não dizem o
+// ano: só valem como censo INEP/SHEETS_LEGACY_YEAR, quando configurado
+// (legacyYear). Sem o ano de corte, ficam como estão. Das linhas
+// encontradas fica uma; as demais são removidas no mesmo envio.
+//
+// DedupSheets remove as duplicatas deixadas pelo append cego: em cada
+// grupo fica a linha mais abaixo (a última gravada). Linhas sem chave só
+// entram no grupo de INEP/legacyYear; as de outro ano nunca saem.
+
+// sheetKeyHeader é o cabeçalho da coluna de chave.
+const sheetKeyHeader = "chave_censo"
+
+// Colunas das abas de déficit (0-based).
+const (
+	deficitINEPCol = 0
+	deficitKeyCol  = 5
+)
+
+// SheetKey identifica a linha do censo nas abas: "escola:<id>/<ano>".
+func SheetKey(school models.School, year int) string {
+	return fmt.Sprintf("escola:%d/%d", school.ID, year)
+}
+
+// inepSheetKey é a chave no formato anterior a SheetKey, "<INEP>/<ano>";
+// "" sem INEP (a escola sem INEP já usava escola:<id>/<ano>).
+func inepSheetKey(inep string, year int) string {
+	inep = strings.TrimSpace(inep)
+	if inep == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", inep, year)
+}
+
+// columnLetter converte o índice 0-based da coluna na letra A1 (0 → A,
+// 26 → AA).
+func columnLetter(idx int) string {
+	var b []byte
+	for idx++; idx > 0; idx = (idx - 1) / 26 {
+		b = append([]byte{byte('A' + (idx-1)%26)}, b...)
+	}
+	return string(b)
+}
+
+// SheetRow é uma linha de dados de uma aba: número (1-based), chave e INEP.
+type SheetRow struct {
+	Row  int    `json:"row"`
+	Key  string `json:"key,omitempty"`
+	INEP string `json:"codigo_inep"`
+}
+
+// isSheetHeader indica a linha 1 com texto (cabeçalho) na coluna de INEP.
+func isSheetHeader(inep string) bool {
+	_, err := strconv.Atoi(inep)
+	return inep != "" && err != nil
+}
+
+// readSheetRows lê as colunas de INEP e chave da aba. hasHeader indica a
+// linha 1 de cabeçalho; headerKey é o cabeçalho atual da coluna de chave.
+func (s *SheetsService) readSheetRows(title string, inepCol, keyCol int) (rows []SheetRow, hasHeader bool, headerKey string, err error) {
+	inepRange := fmt.Sprintf("%s!%[2]s:%[2]s", title, columnLetter(inepCol))
+	keyRange := fmt.Sprintf("%s!%[2]s:%[2]s", title, columnLetter(keyCol))
+	resp, err := s.srv.Spreadsheets.Values.BatchGet(s.censusSpreadsheetID).Ranges(inepRange, keyRange).Do()
+	if err != nil {
+		return nil, false, "", fmt.Errorf("erro ao ler %s: %v", title, err)
+	}
+	if len(resp.ValueRanges) != 2 {
+		return nil, false, "", fmt.Errorf("erro ao ler %s: resposta incompleta", title)
+	}
+	ineps, keys := resp.ValueRanges[0].Values, resp.ValueRanges[1].Values
+	n := max(len(ineps), len(keys))
+	for i := 0; i < n; i++ {
+		var inep, key string
+		if i < len(ineps) {
+			inep = cell(ineps[i], 0)
+		}
+		if i < len(keys) {
+			key = cell(keys[i], 0)
+		}
+		if i == 0 && isSheetHeader(inep) {
+			hasHeader, headerKey = true, key
+			continue
+		}
+		if inep == "" && key == "" {
+			continue
+		}
+		rows = append(rows, SheetRow{Row: i + 1, Key: key, INEP: inep})
+	}
+	return rows, hasHeader, headerKey, nil
+}
+
+// legacySheetKey é a chave atribuída à linha sem chave: INEP/legacyYear,
+// ou "" quando o ano de corte não está configurado.
+func legacySheetKey(r SheetRow, legacyYear int) string {
+	if r.Key != "" || legacyYear <= 0 {
+		return ""
+	}
+	return inepSheetKey(r.INEP, legacyYear)
+}
+
+// findSheetRows devolve as linhas do censo rec: as de chave antiga ou sem
+// chave atribuídas a uma chave antiga (ver legacySheetKey) e, por último,
+// as de chave igual a rec.Key. A última da lista é a que fica.
+func findSheetRows(rows []SheetRow, rec CensusRecord, legacyYear int) []int {
+	var keyed, old []int
+	for _, r := range rows {
+		switch {
+		case r.Key == rec.Key:
+			keyed = append(keyed, r.Row)
+		case rec.HasKey(r.Key), rec.HasKey(legacySheetKey(r, legacyYear)):
+			old = append(old, r.Row)
+		}
+	}
+	return append(old, keyed...)
+}
+
+// upsertSheetRow atualiza a linha do censo em title ou acrescenta uma nova,
+// removendo as demais linhas encontradas para o censo. row já traz a chave
+// na coluna keyCol.
+func (s *SheetsService) upsertSheetRow(title string, inepCol, keyCol int, rec CensusRecord, row []interface{}) error {
+	rows, hasHeader, headerKey, err := s.readSheetRows(title, inepCol, keyCol)
+	if err != nil {
+		return err
+	}
+	if hasHeader && headerKey == "" {
+		hdr := &sheets.ValueRange{Values: [][]interface{}{{sheetKeyHeader}}}
+		if _, err := s.srv.Spreadsheets.Values.Update(s.censusSpreadsheetID,
+			fmt.Sprintf("%s!%s1", title, columnLetter(keyCol)), hdr).ValueInputOption("RAW").Do(); err != nil {
+			return fmt.Errorf("erro ao escrever cabeçalho da chave em %s: %v", title, err)
+		}
+	}
+
+	vr := &sheets.ValueRange{Values: [][]interface{}{row}}
+	if found := findSheetRows(rows, rec, s.legacyYear); len(found) > 0 {
+		if _, err := s.srv.Spreadsheets.Values.Update(s.censusSpreadsheetID,
+			fmt.Sprintf("%s!A%d", title, found[len(found)-1]), vr).ValueInputOption("RAW").Do(); err != nil {
+			return err
+		}
+		extra := found[:len(found)-1]
+		if len(extra) == 0 {
+			return nil
+		}
+		ids, err := s.sheetIDs()
+		if err != nil {
+			return err
+		}
+		if err := s.deleteRows(ids[title], extra); err != nil {
+			return fmt.Errorf("erro ao remover linhas repetidas de %s: %v", title, err)
+		}
+		return nil
+	}
+	_, err = s.srv.Spreadsheets.Values.Append(s.censusSpreadsheetID, fmt.Sprintf("%s!A:A", title), vr).ValueInputOption("RAW").Do()
+	return err
+}
+
+// deleteSheetRow remove de title as linhas do censo, quando a aba existe.
+func (s *SheetsService) deleteSheetRow(title string, inepCol, keyCol int, rec CensusRecord) error {
+	ids, err := s.sheetIDs()
+	if err != nil {
+		return err
+	}
+	sheetID, ok := ids[title]
+	if !ok {
+		return nil
+	}
+	rows, _, _, err := s.readSheetRows(title, inepCol, keyCol)
+	if err != nil {
+		return err
+	}
+	return s.deleteRows(sheetID, findSheetRows(rows, rec, s.legacyYear))
+}
+
+// sheetIDs devolve o id de cada aba da planilha pelo título.
+func (s *SheetsService) sheetIDs() (map[string]int64, error) {
+	spreadsheet, err := s.srv.Spreadsheets.Get(s.censusSpreadsheetID).Fields("sheets.properties").Do()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]int64, len(spreadsheet.Sheets))
+	for _, sheet := range spreadsheet.Sheets {
+		out[sheet.Properties.Title] = sheet.Properties.SheetId
+	}
+	return out, nil
+}
+
+// deleteRows apaga as linhas (1-based) numa única chamada, de baixo para
+// cima para que os números das restantes não mudem no meio.
+func (s *SheetsService) deleteRows(sheetID int64, rows []int) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	sorted := append([]int(nil), rows...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	reqs := make([]*sheets.Request, len(sorted))
+	for i, r := range sorted {
+		reqs[i] = &sheets.Request{DeleteDimension: &sheets.DeleteDimensionRequest{
+			Range: &sheets.DimensionRange{
+				SheetId:    sheetID,
+				Dimension:  "ROWS",
+				StartIndex: int64(r - 1),
+				EndIndex:   int64(r),
+			},
+		}}
+	}
+	_, err := s.srv.Spreadsheets.BatchUpdate(s.censusSpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: reqs,
+	}).Do()
+	return err
+}
+
+// PlanSheetDedup escolhe as linhas duplicadas a remover: por chave, fica a
+// mais abaixo; as sem chave contam como INEP/legacyYear e perdem para uma
+// linha com essa chave. Com legacyYear zero, nenhuma linha sem chave sai.
+// Devolve as linhas a remover em ordem.
+func PlanSheetDedup(rows []SheetRow, legacyYear int) []SheetRow {
+	lastKeyed := map[string]int{}
+	lastLegacy := map[string]int{}
+	for _, r := range rows {
+		if r.Key != "" {
+			lastKeyed[r.Key] = r.Row
+		} else if k := legacySheetKey(r, legacyYear); k != "" {
+			lastLegacy[k] = r.Row
+		}
+	}
+	var out []SheetRow
+	for _, r := range rows {
+		if r.Key != "" {
+			if lastKeyed[r.Key] != r.Row {
+				out = append(out, r)
+			}
+			continue
+		}
+		k := legacySheetKey(r, legacyYear)
+		if k == "" {
+			continue
+		}
+		if _, keyed := lastKeyed[k]; keyed || lastLegacy[k] != r.Row {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// SheetDedup é o resultado da limpeza de uma aba.
+type SheetDedup struct {
+	Sheet   string     `json:"sheet"`
+	Rows    int        `json:"rows"`
+	Removed []SheetRow `json:"removed"`
+}
+
+// baseDadosKeyCol é a coluna de chave de Base_dados: logo após as colunas
+// montadas por censoRow.
+func baseDadosKeyCol() int {
+	row, _ := censoRow(models.CensusResponse{Data: json.RawMessage(`{}`)}, models.School{})
+	return len(row)
+}
+
+// DedupSheets remove as linhas duplicadas de Base_dados e das abas de
+// déficit existentes (ver PlanSheetDedup). Com dryRun, só devolve o que
+// seria removido. A fila de sincronização deve estar sem envios em curso:
+// um envio entre a leitura e a remoção pode ter a linha deslocada.
+func (s *SheetsService) DedupSheets(dryRun bool) ([]SheetDedup, error) {
+	ids, err := s.sheetIDs()
+	if err != nil {
+		return nil, err
+	}
+	type target struct {
+		title           string
+		inepCol, keyCol int
+	}
+	targets := []target{{BaseDadosSheet, colINEP, baseDadosKeyCol()}}
+	for _, title := range deficitOrder {
+		targets = append(targets, target{title, deficitINEPCol, deficitKeyCol})
+	}
+
+	out := []SheetDedup{}
+	for _, t := range targets {
+		sheetID, ok := ids[t.title]
+		if !ok {
+			continue
+		}
+		rows, _, _, err := s.readSheetRows(t.title, t.inepCol, t.keyCol)
+		if err != nil {
+			return nil, err
+		}
+		removed := PlanSheetDedup(rows, s.legacyYear)
+		if removed == nil {
+			removed = []SheetRow{}
+		}
+		if !dryRun {
+			nums := make([]int, len(removed))
+			for i, r := range removed {
+				nums[i] = r.Row
+			}
+			if err := s.deleteRows(sheetID, nums); err != nil {
+				return nil, fmt.Errorf("erro ao remover duplicatas de %s: %v", t.title, err)
+			}
+		}
+		out = append(out, SheetDedup{Sheet: t.title, Rows: len(rows), Removed: removed})
+	}
+	return out, nil
+}
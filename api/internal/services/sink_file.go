@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"censo-api/internal/models"
+)
+
+// ─── Arquivo local (CSV ou JSONL) ────────────────────────────────────────────
+//
+// FileSink grava os censos em dir:
+//
+//   - jsonl: censos.jsonl, um evento por linha (append, update, delete) com
+//     os dados completos — histórico que pode ser reprocessado
+//   - csv:   censos.csv, uma linha por escola e ano, reescrita a cada envio
+//     (arquivo temporário + rename, sem meia escrita)
+//
+// A escrita é serializada no processo; mais de uma réplica gravando no
+// mesmo diretório não é suportado.
+
+// Formatos do FileSink.
+const (
+	FileSinkJSONL = "jsonl"
+	FileSinkCSV   = "csv"
+)
+
+var fileSinkHeader = []string{
+	"chave_censo", "census_id", "codigo_inep", "nome_escola", "dre", "municipio",
+	"ano", "status", "atualizado_em", "dados",
+}
+
+type FileSink struct {
+	path   string
+	format string
+	mu     sync.Mutex
+}
+
+// NewFileSink prepara o diretório e devolve o destino no formato pedido.
+func NewFileSink(dir, format string) (*FileSink, error) {
+	if format != FileSinkJSONL && format != FileSinkCSV {
+		return nil, fmt.Errorf("formato de arquivo inválido: %q (use jsonl ou csv)", format)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
+	}
+	return &FileSink{path: filepath.Join(dir, "censos."+format), format: format}, nil
+}
+
+// Kind implementa CensusSink.
+func (f *FileSink) Kind() string { return models.OutboxKindFile }
+
+// Target implementa CensusSink: o caminho do arquivo.
+func (f *FileSink) Target() string { return f.path }
+
+// Append implementa CensusSink.
+func (f *FileSink) Append(_ context.Context, rec CensusRecord) error {
+	return f.write("append", rec)
+}
+
+// Update implementa CensusSink.
+func (f *FileSink) Update(_ context.Context, rec CensusRecord) error {
+	return f.write("update", rec)
+}
+
+// Delete implementa CensusSink.
+func (f *FileSink) Delete(_ context.Context, rec CensusRecord) error {
+	return f.write("delete", rec)
+}
+
+// fileSinkEvent é a linha do JSONL.
+type fileSinkEvent struct {
+	Op         string          `json:"op"`
+	Key        string          `json:"chave_censo"`
+	CensusID   int             `json:"census_id"`
+	INEP       string          `json:"codigo_inep"`
+	Escola     string          `json:"nome_escola"`
+	Dre        string          `json:"dre"`
+	Municipio  string          `json:"municipio"`
+	Year       int             `json:"ano"`
+	Status     string          `json:"status"`
+	UpdatedAt  time.Time       `json:"atualizado_em"`
+	Data       json.RawMessage `json:"dados,omitempty"`
+	RecordedAt time.Time       `json:"registrado_em"`
+}
+
+func (f *FileSink) write(op string, rec CensusRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.format == FileSinkJSONL {
+		return f.appendJSONL(op, rec)
+	}
+	return f.rewriteCSV(op, rec)
+}
+
+func (f *FileSink) appendJSONL(op string, rec CensusRecord) error {
+	ev := fileSinkEvent{
+		Op: op, Key: rec.Key, CensusID: rec.Census.ID,
+		INEP: rec.School.INEP, Escola: rec.School.Nome, Dre: rec.School.Dre, Municipio: rec.School.Municipio,
+		Year: rec.Census.Year, Status: rec.Census.Status, UpdatedAt: rec.Census.UpdatedAt,
+		RecordedAt: time.Now().UTC(),
+	}
+	if op != "delete" && len(rec.Census.Data) > 0 {
+		ev.Data = rec.Census.Data
+	}
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *FileSink) rewriteCSV(op string, rec CensusRecord) error {
+	rows, err := f.readCSV()
+	if err != nil {
+		return err
+	}
+
+	var row []string
+	if op != "delete" {
+		data := string(rec.Census.Data)
+		row = []string{
+			rec.Key, fmt.Sprint(rec.Census.ID), rec.School.INEP, rec.School.Nome, rec.School.Dre,
+			rec.School.Municipio, fmt.Sprint(rec.Census.Year), rec.Census.Status,
+			rec.Census.UpdatedAt.UTC().Format(time.RFC3339), data,
+		}
+	}
+	out := [][]string{fileSinkHeader}
+	replaced := false
+	for _, r := range rows {
+		if len(r) == 0 || !rec.HasKey(r[0]) {
+			out = append(out, r)
+			continue
+		}
+		if row != nil && !replaced {
+			out = append(out, row)
+			replaced = true
+		}
+	}
+	if row != nil && !replaced {
+		out = append(out, row)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".censos-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := csv.NewWriter(tmp)
+	if err := w.WriteAll(out); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// readCSV lê as linhas atuais do CSV, sem o cabeçalho.
+func (f *FileSink) readCSV() ([][]string, error) {
+	file, err := os.Open(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", f.path, err)
+	}
+	if len(rows) > 0 {
+		rows = rows[1:]
+	}
+	return rows, nil
+}
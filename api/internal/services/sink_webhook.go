@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"censo-api/internal/models"
+)
+
+// ─── Webhook HTTP ────────────────────────────────────────────────────────────
+//
+// WebhookSink envia cada operação como POST JSON para a URL configurada:
+//
+//	{"event": "census.append" | "census.update" | "census.delete",
+//	 "chave_censo": "15012345/2026", "census_id": 1, "ano": 2026,
+//	 "status": "completed", "escola": {...}, "dados": {...}, "enviado_em": "..."}
+//
+// Com segredo configurado, X-Censo-Signature leva "sha256=" + HMAC-SHA256
+// do corpo. Qualquer resposta fora de 2xx é falha e volta para a fila. O
+// receptor deve tratar o evento como idempotente pela chave: o mesmo
+// evento pode chegar de novo depois de um timeout.
+
+const webhookTimeout = 15 * time.Second
+
+type WebhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookSink valida a URL (http ou https) e devolve o destino.
+func NewWebhookSink(rawURL, secret string) (*WebhookSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("URL de webhook inválida: %q", rawURL)
+	}
+	return &WebhookSink{url: rawURL, secret: []byte(secret), client: &http.Client{Timeout: webhookTimeout}}, nil
+}
+
+// Kind implementa CensusSink.
+func (h *WebhookSink) Kind() string { return models.OutboxKindWebhook }
+
+// Target implementa CensusSink: a URL sem query string nem credenciais,
+// que podem carregar tokens.
+func (h *WebhookSink) Target() string {
+	u, err := url.Parse(h.url)
+	if err != nil {
+		return ""
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
+
+// Append implementa CensusSink.
+func (h *WebhookSink) Append(ctx context.Context, rec CensusRecord) error {
+	return h.post(ctx, "census.append", rec)
+}
+
+// Update implementa CensusSink.
+func (h *WebhookSink) Update(ctx context.Context, rec CensusRecord) error {
+	return h.post(ctx, "census.update", rec)
+}
+
+// Delete implementa CensusSink.
+func (h *WebhookSink) Delete(ctx context.Context, rec CensusRecord) error {
+	return h.post(ctx, "census.delete", rec)
+}
+
+type webhookSchool struct {
+	ID        int    `json:"id"`
+	INEP      string `json:"codigo_inep"`
+	Nome      string `json:"nome_escola"`
+	Dre       string `json:"dre"`
+	Municipio string `json:"municipio"`
+}
+
+type webhookPayload struct {
+	Event    string          `json:"event"`
+	Key      string          `json:"chave_censo"`
+	CensusID int             `json:"census_id"`
+	Year     int             `json:"ano"`
+	Status   string          `json:"status"`
+	School   webhookSchool   `json:"escola"`
+	Data     json.RawMessage `json:"dados,omitempty"`
+	SentAt   time.Time       `json:"enviado_em"`
+}
+
+// WebhookSignature é o valor de X-Censo-Signature para o corpo.
+func WebhookSignature(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *WebhookSink) post(ctx context.Context, event string, rec CensusRecord) error {
+	p := webhookPayload{
+		Event: event, Key: rec.Key, CensusID: rec.Census.ID, Year: rec.Census.Year, Status: rec.Census.Status,
+		School: webhookSchool{
+			ID: rec.School.ID, INEP: rec.School.INEP, Nome: rec.School.Nome,
+			Dre: rec.School.Dre, Municipio: rec.School.Municipio,
+		},
+		SentAt: time.Now().UTC(),
+	}
+	if event != "census.delete" && len(rec.Census.Data) > 0 {
+		p.Data = rec.Census.Data
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Censo-Event", event)
+	if len(h.secret) > 0 {
+		req.Header.Set("X-Censo-Signature", WebhookSignature(h.secret, body))
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		// *url.Error traz a URL, e o token na query iria parar no last_error
+		// da fila e no painel: fica só a causa.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook respondeu %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"censo-api/internal/models"
+)
+
+// ─── Destinos de sincronização do censo ──────────────────────────────────────
+//
+// Um CensusSink recebe a linha de cada censo enviado: Append na primeira
+// vez, Update nos envios seguintes e Delete quando o censo volta para a
+// escola. A fila sync_outbox chama o destino com o censo e a escola lidos
+// no momento do envio e cuida das tentativas; o destino só precisa falhar
+// com erro quando a gravação não aconteceu. Todas as operações devem ser
+// idempotentes: uma tentativa repetida depois de uma falha no meio do
+// caminho não pode duplicar a linha.
+
+// CensusRecord é a linha do censo entregue aos destinos.
+type CensusRecord struct {
+	// Key identifica a linha no destino (SheetKey: escola:<id>/<ano>).
+	Key string
+	// OldKeys são outras chaves em que a linha pode ter ficado: a do
+	// formato INEP/ano e as das escolas fundidas nesta. O destino troca a
+	// linha encontrada por uma com Key.
+	OldKeys []string
+	Census  models.CensusResponse
+	School  models.School
+}
+
+// NewCensusRecord monta o registro do censo com a chave da escola e ano e
+// as chaves antigas da escola e das fundidas nela (merged).
+func NewCensusRecord(censo models.CensusResponse, school models.School, merged []models.MergedSchool) CensusRecord {
+	rec := CensusRecord{Key: SheetKey(school, censo.Year), Census: censo, School: school}
+	rec.addOldKey(inepSheetKey(school.INEP, censo.Year))
+	for _, m := range merged {
+		rec.addOldKey(SheetKey(models.School{ID: m.ID}, censo.Year))
+		rec.addOldKey(inepSheetKey(m.INEP, censo.Year))
+	}
+	return rec
+}
+
+func (rec *CensusRecord) addOldKey(key string) {
+	if key != "" && key != rec.Key && !slices.Contains(rec.OldKeys, key) {
+		rec.OldKeys = append(rec.OldKeys, key)
+	}
+}
+
+// HasKey indica se key é a chave do registro ou uma das antigas.
+func (rec CensusRecord) HasKey(key string) bool {
+	return key == rec.Key || slices.Contains(rec.OldKeys, key)
+}
+
+// CensusSink é um destino da sincronização do censo.
+type CensusSink interface {
+	// Kind identifica o destino em sync_outbox e sync_sinks.
+	Kind() string
+	// Target descreve onde o destino grava (aba, arquivo, URL).
+	Target() string
+	Append(ctx context.Context, rec CensusRecord) error
+	Update(ctx context.Context, rec CensusRecord) error
+	Delete(ctx context.Context, rec CensusRecord) error
+}
+
+// ─── Google Sheets ───────────────────────────────────────────────────────────
+
+// Kind implementa CensusSink: a planilha do censo.
+func (s *SheetsService) Kind() string { return models.OutboxKindSheets }
+
+// Target implementa CensusSink.
+func (s *SheetsService) Target() string { return BaseDadosSheet }
+
+// Append implementa CensusSink. Na planilha, acrescentar e atualizar são o
+// mesmo upsert por chave: uma linha deixada por tentativa anterior é
+// reaproveitada.
+func (s *SheetsService) Append(ctx context.Context, rec CensusRecord) error {
+	return s.Update(ctx, rec)
+}
+
+// Update implementa CensusSink: Base_dados e as abas de déficit.
+func (s *SheetsService) Update(_ context.Context, rec CensusRecord) error {
+	if err := s.upsertCensoRow(rec); err != nil {
+		return err
+	}
+	for _, title := range deficitOrder {
+		if err := s.upsertDeficit(title, rec); err != nil {
+			return fmt.Errorf("erro ao escrever em %s: %v", title, err)
+		}
+	}
+	return nil
+}
+
+// Delete implementa CensusSink: remove a linha do censo de Base_dados e das
+// abas de déficit.
+func (s *SheetsService) Delete(_ context.Context, rec CensusRecord) error {
+	if err := s.deleteSheetRow(BaseDadosSheet, colINEP, baseDadosKeyCol(), rec); err != nil {
+		return fmt.Errorf("erro ao remover de %s: %v", BaseDadosSheet, err)
+	}
+	for _, title := range deficitOrder {
+		if err := s.deleteSheetRow(title, deficitINEPCol, deficitKeyCol, rec); err != nil {
+			return fmt.Errorf("erro ao remover de %s: %v", title, err)
+		}
+	}
+	return nil
+}
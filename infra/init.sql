@@ -691,3 +691,1589 @@ CREATE INDEX IF NOT EXISTS idx_ideb_resultados_status_ideb      ON ideb_resultad
 CREATE INDEX IF NOT EXISTS idx_ideb_resultados_status_vinculo   ON ideb_resultados (status_vinculo);
 CREATE INDEX IF NOT EXISTS idx_ideb_resultados_ano_etapa        ON ideb_resultados (ano, etapa);
 CREATE INDEX IF NOT EXISTS idx_ideb_resultados_ano_etapa_status ON ideb_resultados (ano, etapa, status_ideb);
+
+-- =====================================================================
+-- admin_users — contas individuais do painel (espelho de
+-- infra/migrations/0018_admin_users.sql)
+-- =====================================================================
+-- Uma linha por usuário do painel, com hash bcrypt, flag de ativo e papel
+-- (seduc_admin, dre_gestor, analista). A conta de ADMIN_USERNAME é semeada
+-- no boot da API quando a tabela está vazia.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS admin_users (
+    id             SERIAL PRIMARY KEY,
+    username       VARCHAR(64)  NOT NULL,
+    nome           VARCHAR(150) NOT NULL DEFAULT '',
+    password_hash  TEXT         NOT NULL,
+    role           VARCHAR(30)  NOT NULL,
+    active         BOOLEAN      NOT NULL DEFAULT TRUE,
+    last_login_at  TIMESTAMP    NULL,
+    created_at     TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$ BEGIN
+    ALTER TABLE admin_users
+        ADD CONSTRAINT admin_users_username_uniq
+        UNIQUE (username);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+    ALTER TABLE admin_users
+        ADD CONSTRAINT admin_users_role_chk
+        CHECK (role IN ('seduc_admin', 'dre_gestor', 'analista'));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_admin_users_role ON admin_users (role);
+
+-- =====================================================================
+-- admin_users.dre — escopo regional das contas (espelho de
+-- infra/migrations/0019_admin_users_dre.sql)
+-- =====================================================================
+-- NULL = acesso estadual. dre_gestor sempre tem DRE; seduc_admin nunca.
+-- =====================================================================
+
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS dre VARCHAR(100) NULL;
+
+DO $$ BEGIN
+    ALTER TABLE admin_users
+        ADD CONSTRAINT admin_users_dre_role_chk
+        CHECK (
+            (role = 'dre_gestor' AND dre IS NOT NULL AND TRIM(dre) <> '')
+            OR (role = 'seduc_admin' AND dre IS NULL)
+            OR role = 'analista'
+        );
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+-- =====================================================================
+-- admin_sessions / admin_revoked_tokens — sessões do painel e revogação
+-- de tokens (espelho de infra/migrations/0020_admin_sessions.sql)
+-- =====================================================================
+-- Refresh tokens rotativos guardados só como hash SHA-256; lista de
+-- revogação por jti dos access tokens até a expiração de cada um.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id                   BIGSERIAL PRIMARY KEY,
+    user_id              INTEGER      NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    refresh_token_hash   CHAR(64)     NOT NULL,
+    previous_token_hash  CHAR(64)     NULL,
+    rotated_at           TIMESTAMP    NULL,
+    ip                   VARCHAR(64)  NOT NULL DEFAULT '',
+    user_agent           VARCHAR(255) NOT NULL DEFAULT '',
+    created_at           TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at           TIMESTAMP    NOT NULL,
+    absolute_expires_at  TIMESTAMP    NOT NULL,
+    revoked_at           TIMESTAMP    NULL
+);
+
+DO $$ BEGIN
+    ALTER TABLE admin_sessions
+        ADD CONSTRAINT admin_sessions_refresh_hash_uniq
+        UNIQUE (refresh_token_hash);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_user
+    ON admin_sessions (user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_previous_hash
+    ON admin_sessions (previous_token_hash) WHERE previous_token_hash IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS admin_revoked_tokens (
+    jti         VARCHAR(64) PRIMARY KEY,
+    user_id     INTEGER     NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    expires_at  TIMESTAMP   NOT NULL,
+    revoked_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_revoked_tokens_expires
+    ON admin_revoked_tokens (expires_at);
+
+-- =====================================================================
+-- admin_audit_log — trilha de auditoria do painel (espelho de
+-- infra/migrations/0021_admin_audit_log.sql)
+-- =====================================================================
+-- Uma linha por requisição autenticada em /v1/admin/*: usuário, IP, rota,
+-- filtros da query string, status HTTP e data/hora.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      INTEGER      NULL REFERENCES admin_users (id) ON DELETE SET NULL,
+    username     VARCHAR(64)  NOT NULL DEFAULT '',
+    role         VARCHAR(30)  NOT NULL DEFAULT '',
+    ip           VARCHAR(64)  NOT NULL DEFAULT '',
+    method       VARCHAR(10)  NOT NULL,
+    route        VARCHAR(255) NOT NULL,
+    path         VARCHAR(500) NOT NULL,
+    query        JSONB        NOT NULL DEFAULT '{}'::jsonb,
+    status_code  INTEGER      NOT NULL,
+    duration_ms  INTEGER      NOT NULL DEFAULT 0,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created_at ON admin_audit_log (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_user ON admin_audit_log (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_route ON admin_audit_log (route);
+
+-- =====================================================================
+-- school_access_codes — códigos de acesso por escola do formulário
+-- público (espelho de infra/migrations/0022_school_access_codes.sql)
+-- =====================================================================
+-- Só o hash SHA-256 do código é guardado; no máximo um código ativo por
+-- escola.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS school_access_codes (
+    id            BIGSERIAL PRIMARY KEY,
+    school_id     INTEGER     NOT NULL REFERENCES schools (id) ON DELETE CASCADE,
+    code_hash     CHAR(64)    NOT NULL,
+    code_hint     VARCHAR(8)  NOT NULL DEFAULT '',
+    issued_by     INTEGER     NULL REFERENCES admin_users (id) ON DELETE SET NULL,
+    created_at    TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at  TIMESTAMP   NULL,
+    revoked_at    TIMESTAMP   NULL
+);
+
+DO $$ BEGIN
+    ALTER TABLE school_access_codes
+        ADD CONSTRAINT school_access_codes_hash_uniq
+        UNIQUE (code_hash);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_school_access_codes_active
+    ON school_access_codes (school_id) WHERE revoked_at IS NULL;
+
+-- =====================================================================
+-- rate_limit_hits — tentativas contadas pelos limites de taxa
+-- (espelho de infra/migrations/0023_rate_limit_hits.sql)
+-- =====================================================================
+-- Compartilhado entre réplicas; linhas expiradas são apagadas pela API.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS rate_limit_hits (
+    id          BIGSERIAL PRIMARY KEY,
+    bucket      VARCHAR(40)  NOT NULL,
+    key         VARCHAR(120) NOT NULL,
+    hit_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at  TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_hits_key
+    ON rate_limit_hits (bucket, key, hit_at);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_hits_expires
+    ON rate_limit_hits (expires_at);
+
+-- =====================================================================
+-- admin_totp — segundo fator TOTP das contas do painel
+-- (espelho de infra/migrations/0024_admin_totp.sql)
+-- =====================================================================
+-- Segredo cifrado em admin_users; códigos de recuperação só como hash.
+-- =====================================================================
+
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_secret         TEXT      NULL;
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_pending_secret TEXT      NULL;
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_enabled_at     TIMESTAMP NULL;
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_last_step      BIGINT    NULL;
+
+CREATE TABLE IF NOT EXISTS admin_totp_recovery_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     INTEGER   NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    code_hash   CHAR(64)  NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at     TIMESTAMP NULL
+);
+
+DO $$ BEGIN
+    ALTER TABLE admin_totp_recovery_codes
+        ADD CONSTRAINT admin_totp_recovery_codes_uniq
+        UNIQUE (user_id, code_hash);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+-- =====================================================================
+-- census_revisions — histórico de escritas no censo
+-- (espelho de infra/migrations/0025_census_revisions.sql)
+-- =====================================================================
+-- JSON anterior e novo, transição de status, autor e IP de cada escrita.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS census_revisions (
+    id               BIGSERIAL PRIMARY KEY,
+    census_id        INTEGER     NOT NULL REFERENCES census_responses (id) ON DELETE CASCADE,
+    revision         INTEGER     NOT NULL,
+    previous_status  VARCHAR(50) NULL,
+    status           VARCHAR(50) NOT NULL,
+    previous_data    JSONB       NULL,
+    data             JSONB       NOT NULL,
+    actor_kind       VARCHAR(20) NOT NULL DEFAULT 'formulario',
+    actor            VARCHAR(64) NOT NULL DEFAULT '',
+    client_ip        VARCHAR(64) NOT NULL DEFAULT '',
+    created_at       TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$ BEGIN
+    ALTER TABLE census_revisions
+        ADD CONSTRAINT census_revisions_census_revision_uniq
+        UNIQUE (census_id, revision);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_census_revisions_created_at ON census_revisions (created_at DESC);
+
+-- =====================================================================
+-- census_version — versão do censo para concorrência otimista
+-- (espelho de infra/migrations/0026_census_version.sql)
+-- =====================================================================
+-- version = número da última revisão; exposta como ETag em /v1/census.
+-- =====================================================================
+
+ALTER TABLE census_responses ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0;
+
+UPDATE census_responses cr
+SET version = r.last_revision
+FROM (SELECT census_id, MAX(revision) AS last_revision
+      FROM census_revisions GROUP BY census_id) r
+WHERE r.census_id = cr.id AND cr.version < r.last_revision;
+
+-- =====================================================================
+-- census_review — fluxo de revisão do censo pela DRE
+-- (espelho de infra/migrations/0027_census_review.sql)
+-- =====================================================================
+-- completed (enviado) → em_revisao → devolvido | aprovado; census_reviews
+-- guarda revisor e comentário de cada transição.
+-- =====================================================================
+
+CREATE OR REPLACE FUNCTION censo_enviado(status TEXT) RETURNS BOOLEAN
+    LANGUAGE sql IMMUTABLE
+    AS $fn$ SELECT status IN ('completed', 'em_revisao', 'aprovado') $fn$;
+
+DO $$ BEGIN
+    ALTER TABLE census_responses
+        ADD CONSTRAINT census_responses_status_check
+        CHECK (status IN ('draft', 'completed', 'em_revisao', 'devolvido', 'aprovado')) NOT VALID;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE TABLE IF NOT EXISTS census_reviews (
+    id          BIGSERIAL   PRIMARY KEY,
+    census_id   INTEGER     NOT NULL REFERENCES census_responses(id) ON DELETE CASCADE,
+    from_status VARCHAR(50) NOT NULL,
+    to_status   VARCHAR(50) NOT NULL,
+    reviewer    VARCHAR(64) NOT NULL,
+    comment     TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_census_reviews_census ON census_reviews (census_id, created_at DESC);
+
+-- vw_censo_governanca_institucional passa a usar o recorte censo_enviado.
+
+CREATE OR REPLACE VIEW vw_censo_governanca_institucional AS
+WITH base AS (
+    SELECT
+        cr.id                                       AS census_id,
+        s.id                                        AS school_id,
+        s.codigo_inep,
+        s.nome_escola                               AS escola,
+        s.dre,
+        s.municipio,
+        s.zona,
+        NULLIF(cr.data->>'regularizada_cee', '')    AS regularizada_cee,
+        NULLIF(cr.data->>'conselho_escolar', '')    AS conselho_escolar,
+        NULLIF(cr.data->>'conselho_ativo', '')      AS conselho_ativo
+    FROM census_responses cr
+    JOIN schools s ON s.id = cr.school_id
+    WHERE censo_enviado(cr.status)
+)
+SELECT
+    census_id,
+    school_id,
+    codigo_inep,
+    escola,
+    dre,
+    municipio,
+    zona,
+    regularizada_cee,
+    conselho_escolar,
+    conselho_ativo,
+    -- Booleanos: NULL quando o campo é "Não informado" (não vira FALSE).
+    (regularizada_cee = 'Sim')        AS is_regularizada_cee,
+    (conselho_escolar = 'Sim')        AS has_conselho_escolar,
+    (conselho_ativo = 'Sim')          AS is_conselho_ativo,
+    (conselho_ativo = 'Parcialmente') AS is_conselho_parcialmente_ativo,
+    -- Governança completa: os três quesitos = 'Sim'. NULL em qualquer campo
+    -- propaga NULL (não conta como completa nem como crítica).
+    (regularizada_cee = 'Sim'
+     AND conselho_escolar = 'Sim'
+     AND conselho_ativo = 'Sim')      AS is_governanca_completa,
+    -- Governança crítica: pelo menos um quesito = 'Não'. Como o OR com TRUE
+    -- curto-circuita, basta um 'Não' para resultar TRUE; valores NULL não
+    -- forçam crítica (Não informado não vira Não).
+    (regularizada_cee = 'Não'
+     OR conselho_escolar = 'Não'
+     OR conselho_ativo = 'Não')       AS is_governanca_critica
+FROM base;
+
+-- =====================================================================
+-- census_seed — pré-preenchimento a partir do censo do ano anterior
+-- (espelho de infra/migrations/0028_census_seed.sql)
+-- =====================================================================
+-- seeded_fields = chaves copiadas de seeded_from_year ainda não confirmadas.
+-- =====================================================================
+
+ALTER TABLE census_responses ADD COLUMN IF NOT EXISTS seeded_from_year INTEGER NULL;
+ALTER TABLE census_responses ADD COLUMN IF NOT EXISTS seeded_fields JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- =====================================================================
+-- census_campaigns — janelas de preenchimento do censo por ano
+-- (espelho de infra/migrations/0029_census_campaigns.sql)
+-- =====================================================================
+-- Prorrogações por DRE em census_campaign_extensions.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS census_campaigns (
+    year         INTEGER     PRIMARY KEY,
+    opens_on     DATE        NOT NULL,
+    closes_on    DATE        NOT NULL,
+    form_version VARCHAR(32) NOT NULL DEFAULT '',
+    created_at   TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT census_campaigns_window_check CHECK (closes_on >= opens_on)
+);
+
+CREATE TABLE IF NOT EXISTS census_campaign_extensions (
+    year       INTEGER      NOT NULL REFERENCES census_campaigns(year) ON DELETE CASCADE,
+    dre        VARCHAR(255) NOT NULL,
+    closes_on  DATE         NOT NULL,
+    reason     TEXT         NOT NULL DEFAULT '',
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (year, dre)
+);
+
+-- =====================================================================
+-- census_snapshots — fotografia oficial do censo por ano
+-- (espelho de infra/migrations/0030_census_snapshots.sql)
+-- =====================================================================
+-- Lotes imutáveis com hash; vw_censo_fonte alterna entre censo vivo e lote.
+-- =====================================================================
+
+CREATE OR REPLACE FUNCTION censo_snapshot_hash(school_id INTEGER, year INTEGER, status TEXT, data JSONB) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE
+    AS $fn$ SELECT encode(sha256(convert_to(concat_ws('|', school_id, year, status, COALESCE(data, '{}'::jsonb)::text), 'UTF8')), 'hex') $fn$;
+
+CREATE TABLE IF NOT EXISTS census_snapshot_batches (
+    id           BIGSERIAL    PRIMARY KEY,
+    year         INTEGER      NOT NULL,
+    label        VARCHAR(120) NOT NULL DEFAULT '',
+    row_count    INTEGER      NOT NULL,
+    content_hash CHAR(64)     NOT NULL,
+    created_by   VARCHAR(64)  NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_census_snapshot_batches_year ON census_snapshot_batches (year, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS census_snapshots (
+    batch_id        BIGINT      NOT NULL REFERENCES census_snapshot_batches (id),
+    census_id       INTEGER     NOT NULL,
+    school_id       INTEGER     NOT NULL,
+    year            INTEGER     NOT NULL,
+    status          VARCHAR(50) NOT NULL,
+    data            JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    version         INTEGER     NOT NULL DEFAULT 0,
+    created_at      TIMESTAMP   NULL,
+    updated_at      TIMESTAMP   NULL,
+    sheet_synced_at TIMESTAMP   NULL,
+    content_hash    CHAR(64)    NOT NULL,
+    PRIMARY KEY (batch_id, census_id)
+);
+
+CREATE OR REPLACE FUNCTION census_snapshots_imutavel() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $fn$ BEGIN RAISE EXCEPTION 'fotografias do censo são imutáveis (%)', TG_TABLE_NAME; END $fn$;
+
+DROP TRIGGER IF EXISTS trg_census_snapshots_imutavel ON census_snapshots;
+CREATE TRIGGER trg_census_snapshots_imutavel BEFORE UPDATE OR DELETE ON census_snapshots
+    FOR EACH ROW EXECUTE FUNCTION census_snapshots_imutavel();
+
+DROP TRIGGER IF EXISTS trg_census_snapshot_batches_imutavel ON census_snapshot_batches;
+CREATE TRIGGER trg_census_snapshot_batches_imutavel BEFORE UPDATE OR DELETE ON census_snapshot_batches
+    FOR EACH ROW EXECUTE FUNCTION census_snapshots_imutavel();
+
+-- O select externo fixa os tipos das colunas (status VARCHAR(50)) para que
+-- as views dependentes possam ser recriadas sem mudança de tipo.
+CREATE OR REPLACE VIEW vw_censo_fonte AS
+SELECT
+    f.id,
+    f.school_id,
+    f.year,
+    f.status::VARCHAR(50) AS status,
+    f.data,
+    f.version,
+    f.created_at,
+    f.updated_at,
+    f.sheet_synced_at
+FROM (
+    SELECT id, school_id, year, status, data, version, created_at, updated_at, sheet_synced_at
+    FROM census_responses
+    WHERE NULLIF(current_setting('censo.snapshot', true), '') IS NULL
+    UNION ALL
+    SELECT census_id, school_id, year, status, data, version, created_at, updated_at, sheet_synced_at
+    FROM census_snapshots
+    WHERE batch_id = NULLIF(current_setting('censo.snapshot', true), '')::BIGINT
+) f;
+
+CREATE OR REPLACE FUNCTION censo_views_ler_fonte() RETURNS VOID
+    LANGUAGE plpgsql
+    AS $fn$
+DECLARE
+    v TEXT;
+    def TEXT;
+BEGIN
+    FOREACH v IN ARRAY ARRAY[
+        'vw_censo_base', 'vw_censo_direcao_escolar', 'vw_censo_coordenacao_area',
+        'vw_censo_quadro_pessoal', 'vw_censo_equipamentos_tecnologia', 'vw_censo_ambientes',
+        'vw_censo_infraestrutura_seguranca', 'vw_censo_equipamentos_merenda',
+        'vw_censo_rh_merendeiras', 'vw_censo_rh_servicos_gerais',
+        'vw_censo_servicos_terceirizados', 'vw_censo_governanca_institucional'
+    ] LOOP
+        IF to_regclass(v) IS NULL THEN
+            CONTINUE;
+        END IF;
+        def := regexp_replace(pg_get_viewdef(v::regclass, false), ';\s*$', '');
+        IF def ~ '\mcensus_responses\M' THEN
+            EXECUTE format('CREATE OR REPLACE VIEW %I AS %s', v,
+                regexp_replace(def, '\mcensus_responses\M', 'vw_censo_fonte', 'g'));
+        END IF;
+    END LOOP;
+END $fn$;
+
+SELECT censo_views_ler_fonte();
+
+-- =====================================================================
+-- schools — desativação lógica e fusão pelo painel
+-- (espelho de infra/migrations/0031_schools_admin.sql)
+-- =====================================================================
+-- active/ended_on e merged_into; a fusão move censos, PRODEP e IDEB.
+-- =====================================================================
+
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS active      BOOLEAN   NOT NULL DEFAULT TRUE;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS ended_on    DATE      NULL;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS merged_into INTEGER   NULL;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS updated_at  TIMESTAMP NULL;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_merged_into_fk
+        FOREIGN KEY (merged_into) REFERENCES schools(id) ON DELETE SET NULL;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_active_ended_chk
+        CHECK ((active AND ended_on IS NULL) OR (NOT active AND ended_on IS NOT NULL));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_merged_inactive_chk
+        CHECK (merged_into IS NULL OR (NOT active AND merged_into <> id));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_schools_merged_into ON schools (merged_into) WHERE merged_into IS NOT NULL;
+
+-- =====================================================================
+-- school_import_batches — carga do cadastro oficial de escolas
+-- (espelho de infra/migrations/0032_school_import_batches.sql)
+-- =====================================================================
+-- Lotes de cmd/import-schools com contagens e relatório de diferenças.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS school_import_batches (
+    id                BIGSERIAL PRIMARY KEY,
+    source_file       TEXT      NOT NULL,
+    source_hash       CHAR(64)  NOT NULL,
+    rows_total        INTEGER   NOT NULL,
+    inserted          INTEGER   NOT NULL DEFAULT 0,
+    updated           INTEGER   NOT NULL DEFAULT 0,
+    unchanged         INTEGER   NOT NULL DEFAULT 0,
+    skipped           INTEGER   NOT NULL DEFAULT 0,
+    missing           INTEGER   NOT NULL DEFAULT 0,
+    dre_changed       INTEGER   NOT NULL DEFAULT 0,
+    municipio_changed INTEGER   NOT NULL DEFAULT 0,
+    report            JSONB     NOT NULL DEFAULT '{}'::jsonb,
+    created_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    notes             TEXT
+);
+
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS import_batch_id BIGINT NULL;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_import_batch_fk
+        FOREIGN KEY (import_batch_id) REFERENCES school_import_batches(id) ON DELETE SET NULL;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+-- =====================================================================
+-- locations — hierarquia DRE → município → escola do formulário
+-- (espelho de infra/migrations/0033_locations.sql)
+-- =====================================================================
+-- Semeada de data/locations.xlsx; substituída por POST /v1/admin/locations.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS locations (
+    id          SERIAL       PRIMARY KEY,
+    dre         TEXT         NOT NULL,
+    municipio   TEXT         NOT NULL,
+    escola      TEXT         NOT NULL,
+    codigo_inep VARCHAR(20)  NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT locations_escola_uniq UNIQUE (dre, municipio, escola)
+);
+
+CREATE INDEX IF NOT EXISTS idx_locations_codigo_inep ON locations (codigo_inep);
+
+CREATE TABLE IF NOT EXISTS location_uploads (
+    id           BIGSERIAL PRIMARY KEY,
+    source_file  TEXT      NOT NULL,
+    source_hash  CHAR(64)  NOT NULL,
+    rows_total   INTEGER   NOT NULL,
+    added        INTEGER   NOT NULL DEFAULT 0,
+    removed      INTEGER   NOT NULL DEFAULT 0,
+    inep_changed INTEGER   NOT NULL DEFAULT 0,
+    diff         JSONB     NOT NULL DEFAULT '{}'::jsonb,
+    created_by   TEXT      NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- =====================================================================
+-- municipios / dres — tabelas de referência com códigos IBGE
+-- (espelho de infra/migrations/0034_municipios_dres.sql)
+-- =====================================================================
+-- Chave normalizada (unaccent, hífens) e schools.municipio_id/dre_id por trigger.
+-- =====================================================================
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- Chave de comparação de nomes. IMMUTABLE com o dicionário explícito, para
+-- poder ser usada em índices e constraints.
+CREATE OR REPLACE FUNCTION censo_chave(valor TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT NULLIF(btrim(regexp_replace(
+        regexp_replace(lower(public.unaccent('public.unaccent'::regdictionary, COALESCE(valor, ''))),
+                       '[''’´`]', '', 'g'),
+        '[^a-z0-9]+', ' ', 'g')), '')
+$$;
+
+-- Chave de DRE: como censo_chave, sem o prefixo "DRE"/"URE" ("DRE Belém 1"
+-- = "BELEM 1").
+CREATE OR REPLACE FUNCTION censo_chave_dre(valor TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT NULLIF(regexp_replace(censo_chave(valor), '^(dre|ure) ', ''), '')
+$$;
+
+CREATE TABLE IF NOT EXISTS municipios (
+    id                SERIAL  PRIMARY KEY,
+    codigo_ibge       CHAR(7) NOT NULL UNIQUE,
+    nome              TEXT    NOT NULL,
+    chave             TEXT    NOT NULL UNIQUE,
+    regiao_integracao TEXT    NULL
+);
+
+CREATE TABLE IF NOT EXISTS municipio_aliases (
+    chave        TEXT    PRIMARY KEY,
+    municipio_id INTEGER NOT NULL REFERENCES municipios(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS dres (
+    id                SERIAL  PRIMARY KEY,
+    nome              TEXT    NOT NULL UNIQUE,
+    chave             TEXT    NOT NULL UNIQUE,
+    sede_municipio_id INTEGER NULL REFERENCES municipios(id) ON DELETE SET NULL
+);
+
+-- Os 144 municípios do Pará (IBGE, tabela DTB).
+INSERT INTO municipios (codigo_ibge, nome, chave)
+SELECT v.codigo_ibge, v.nome, censo_chave(v.nome)
+FROM (VALUES
+    ('1500107', 'Abaetetuba'),
+    ('1500131', 'Abel Figueiredo'),
+    ('1500206', 'Acará'),
+    ('1500305', 'Afuá'),
+    ('1500347', 'Água Azul do Norte'),
+    ('1500404', 'Alenquer'),
+    ('1500503', 'Almeirim'),
+    ('1500602', 'Altamira'),
+    ('1500701', 'Anajás'),
+    ('1500800', 'Ananindeua'),
+    ('1500859', 'Anapu'),
+    ('1500909', 'Augusto Corrêa'),
+    ('1500958', 'Aurora do Pará'),
+    ('1501006', 'Aveiro'),
+    ('1501105', 'Bagre'),
+    ('1501204', 'Baião'),
+    ('1501253', 'Bannach'),
+    ('1501303', 'Barcarena'),
+    ('1501402', 'Belém'),
+    ('1501451', 'Belterra'),
+    ('1501501', 'Benevides'),
+    ('1501576', 'Bom Jesus do Tocantins'),
+    ('1501600', 'Bonito'),
+    ('1501709', 'Bragança'),
+    ('1501725', 'Brasil Novo'),
+    ('1501758', 'Brejo Grande do Araguaia'),
+    ('1501782', 'Breu Branco'),
+    ('1501808', 'Breves'),
+    ('1501907', 'Bujaru'),
+    ('1501956', 'Cachoeira do Piriá'),
+    ('1502004', 'Cachoeira do Arari'),
+    ('1502103', 'Cametá'),
+    ('1502152', 'Canaã dos Carajás'),
+    ('1502202', 'Capanema'),
+    ('1502301', 'Capitão Poço'),
+    ('1502400', 'Castanhal'),
+    ('1502509', 'Chaves'),
+    ('1502608', 'Colares'),
+    ('1502707', 'Conceição do Araguaia'),
+    ('1502756', 'Concórdia do Pará'),
+    ('1502764', 'Cumaru do Norte'),
+    ('1502772', 'Curionópolis'),
+    ('1502806', 'Curralinho'),
+    ('1502855', 'Curuá'),
+    ('1502905', 'Curuçá'),
+    ('1502939', 'Dom Eliseu'),
+    ('1502954', 'Eldorado do Carajás'),
+    ('1503002', 'Faro'),
+    ('1503044', 'Floresta do Araguaia'),
+    ('1503077', 'Garrafão do Norte'),
+    ('1503093', 'Goianésia do Pará'),
+    ('1503101', 'Gurupá'),
+    ('1503200', 'Igarapé-Açu'),
+    ('1503309', 'Igarapé-Miri'),
+    ('1503408', 'Inhangapi'),
+    ('1503457', 'Ipixuna do Pará'),
+    ('1503507', 'Irituia'),
+    ('1503606', 'Itaituba'),
+    ('1503705', 'Itupiranga'),
+    ('1503754', 'Jacareacanga'),
+    ('1503804', 'Jacundá'),
+    ('1503903', 'Juruti'),
+    ('1504000', 'Limoeiro do Ajuru'),
+    ('1504059', 'Mãe do Rio'),
+    ('1504109', 'Magalhães Barata'),
+    ('1504208', 'Marabá'),
+    ('1504307', 'Maracanã'),
+    ('1504406', 'Marapanim'),
+    ('1504422', 'Marituba'),
+    ('1504455', 'Medicilândia'),
+    ('1504505', 'Melgaço'),
+    ('1504604', 'Mocajuba'),
+    ('1504703', 'Moju'),
+    ('1504752', 'Mojuí dos Campos'),
+    ('1504802', 'Monte Alegre'),
+    ('1504901', 'Muaná'),
+    ('1504950', 'Nova Esperança do Piriá'),
+    ('1504976', 'Nova Ipixuna'),
+    ('1505007', 'Nova Timboteua'),
+    ('1505031', 'Novo Progresso'),
+    ('1505064', 'Novo Repartimento'),
+    ('1505106', 'Óbidos'),
+    ('1505205', 'Oeiras do Pará'),
+    ('1505304', 'Oriximiná'),
+    ('1505403', 'Ourém'),
+    ('1505437', 'Ourilândia do Norte'),
+    ('1505486', 'Pacajá'),
+    ('1505494', 'Palestina do Pará'),
+    ('1505502', 'Paragominas'),
+    ('1505536', 'Parauapebas'),
+    ('1505551', 'Pau D''Arco'),
+    ('1505601', 'Peixe-Boi'),
+    ('1505635', 'Piçarra'),
+    ('1505650', 'Placas'),
+    ('1505700', 'Ponta de Pedras'),
+    ('1505809', 'Portel'),
+    ('1505908', 'Porto de Moz'),
+    ('1506005', 'Prainha'),
+    ('1506104', 'Primavera'),
+    ('1506112', 'Quatipuru'),
+    ('1506138', 'Redenção'),
+    ('1506161', 'Rio Maria'),
+    ('1506187', 'Rondon do Pará'),
+    ('1506195', 'Rurópolis'),
+    ('1506203', 'Salinópolis'),
+    ('1506302', 'Salvaterra'),
+    ('1506351', 'Santa Bárbara do Pará'),
+    ('1506401', 'Santa Cruz do Arari'),
+    ('1506500', 'Santa Izabel do Pará'),
+    ('1506559', 'Santa Luzia do Pará'),
+    ('1506583', 'Santa Maria das Barreiras'),
+    ('1506609', 'Santa Maria do Pará'),
+    ('1506708', 'Santana do Araguaia'),
+    ('1506807', 'Santarém'),
+    ('1506906', 'Santarém Novo'),
+    ('1507003', 'Santo Antônio do Tauá'),
+    ('1507102', 'São Caetano de Odivelas'),
+    ('1507151', 'São Domingos do Araguaia'),
+    ('1507201', 'São Domingos do Capim'),
+    ('1507300', 'São Félix do Xingu'),
+    ('1507409', 'São Francisco do Pará'),
+    ('1507458', 'São Geraldo do Araguaia'),
+    ('1507466', 'São João da Ponta'),
+    ('1507474', 'São João de Pirabas'),
+    ('1507508', 'São João do Araguaia'),
+    ('1507607', 'São Miguel do Guamá'),
+    ('1507706', 'São Sebastião da Boa Vista'),
+    ('1507755', 'Sapucaia'),
+    ('1507805', 'Senador José Porfírio'),
+    ('1507904', 'Soure'),
+    ('1507953', 'Tailândia'),
+    ('1507961', 'Terra Alta'),
+    ('1507979', 'Terra Santa'),
+    ('1508001', 'Tomé-Açu'),
+    ('1508035', 'Tracuateua'),
+    ('1508050', 'Trairão'),
+    ('1508084', 'Tucumã'),
+    ('1508100', 'Tucuruí'),
+    ('1508126', 'Ulianópolis'),
+    ('1508159', 'Uruará'),
+    ('1508209', 'Vigia'),
+    ('1508308', 'Viseu'),
+    ('1508357', 'Vitória do Xingu'),
+    ('1508407', 'Xinguara')
+) AS v (codigo_ibge, nome)
+ON CONFLICT (codigo_ibge) DO NOTHING;
+
+-- Grafias que aparecem nas planilhas e em reg_integracao.
+INSERT INTO municipio_aliases (chave, municipio_id)
+SELECT censo_chave(v.alias), m.id
+FROM (VALUES
+    ('Eldorado dos Carajás',      '1502954'),
+    ('Santa Isabel do Pará',      '1506500'),
+    ('Santa Bárbara',             '1506351'),
+    ('Icoaraci',                  '1501402'),
+    ('Distrito de Icoaraci',      '1501402'),
+    ('Outeiro',                   '1501402'),
+    ('Distrito de Outeiro',       '1501402'),
+    ('Mosqueiro',                 '1501402'),
+    ('Distrito de Mosqueiro',     '1501402'),
+    ('Monte Dourado',             '1500503'),
+    ('Distrito de Monte Dourado', '1500503')
+) AS v (alias, codigo_ibge)
+JOIN municipios m ON m.codigo_ibge = v.codigo_ibge
+ON CONFLICT (chave) DO NOTHING;
+
+-- Município canônico de um texto livre: chave do nome ou alias.
+CREATE OR REPLACE FUNCTION censo_municipio_id(valor TEXT) RETURNS INTEGER
+LANGUAGE sql STABLE AS $$
+    SELECT COALESCE(
+        (SELECT id FROM municipios WHERE chave = censo_chave(valor)),
+        (SELECT municipio_id FROM municipio_aliases WHERE chave = censo_chave(valor)))
+$$;
+
+CREATE OR REPLACE FUNCTION censo_dre_id(valor TEXT) RETURNS INTEGER
+LANGUAGE sql STABLE AS $$
+    SELECT id FROM dres WHERE chave = censo_chave_dre(valor)
+$$;
+
+UPDATE municipios m
+SET regiao_integracao = r.regiao_de_integracao
+FROM reg_integracao r
+WHERE censo_municipio_id(r.municipio) = m.id
+  AND m.regiao_integracao IS NULL;
+
+-- DREs da SEDUC (setores de data/locations.xlsx) com o município sede.
+INSERT INTO dres (nome, chave, sede_municipio_id)
+SELECT v.nome, censo_chave_dre(v.nome), m.id
+FROM (VALUES
+    ('ABAETETUBA',            '1500107'),
+    ('AFUA',                  '1500305'),
+    ('ALTAMIRA',              '1500602'),
+    ('ANANINDEUA 1',          '1500800'),
+    ('ANANINDEUA 2',          '1500800'),
+    ('ANANINDEUA 3',          '1500800'),
+    ('ANANINDEUA 4',          '1500800'),
+    ('ANANINDEUA 5',          '1500800'),
+    ('BELEM 1',               '1501402'),
+    ('BELEM 2',               '1501402'),
+    ('BELEM 3',               '1501402'),
+    ('BELEM 4',               '1501402'),
+    ('BELEM 5',               '1501402'),
+    ('BELEM 6',               '1501402'),
+    ('BELEM 7',               '1501402'),
+    ('BELEM 8',               '1501402'),
+    ('BELEM 9',               '1501402'),
+    ('BELEM 10',              '1501402'),
+    ('BENEVIDES',             '1501501'),
+    ('BRAGANCA',              '1501709'),
+    ('BREVES',                '1501808'),
+    ('CACHOEIRA DO ARARI',    '1502004'),
+    ('CAMETA',                '1502103'),
+    ('CAPANEMA',              '1502202'),
+    ('CAPITAO POCO',          '1502301'),
+    ('CASTANHAL',             '1502400'),
+    ('CONCEICAO DO ARAGUAIA', '1502707'),
+    ('CURRALINHO',            '1502806'),
+    ('ITAITUBA',              '1503606'),
+    ('MAE DO RIO',            '1504059'),
+    ('MARABA',                '1504208'),
+    ('MARACANA',              '1504307'),
+    ('MONTE ALEGRE',          '1504802'),
+    ('OBIDOS',                '1505106'),
+    ('PARAUAPEBAS',           '1505536'),
+    ('SANTA BARBARA',         '1506351'),
+    ('SANTA IZABEL DO PARA',  '1506500'),
+    ('SANTAREM',              '1506807'),
+    ('TUCURUI',               '1508100'),
+    ('XINGUARA',              '1508407')
+) AS v (nome, codigo_ibge)
+LEFT JOIN municipios m ON m.codigo_ibge = v.codigo_ibge
+ON CONFLICT (nome) DO NOTHING;
+
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS municipio_id INTEGER NULL;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS dre_id       INTEGER NULL;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_municipio_fk
+        FOREIGN KEY (municipio_id) REFERENCES municipios(id) ON DELETE SET NULL;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_dre_fk
+        FOREIGN KEY (dre_id) REFERENCES dres(id) ON DELETE SET NULL;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_schools_municipio_id ON schools (municipio_id);
+CREATE INDEX IF NOT EXISTS idx_schools_dre_id       ON schools (dre_id);
+
+-- Resolve municipio_id e dre_id a partir do texto, em qualquer caminho de
+-- escrita (formulário, painel, cmd/import-schools).
+CREATE OR REPLACE FUNCTION schools_resolve_referencias() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.municipio_id := censo_municipio_id(NEW.municipio);
+    NEW.dre_id := censo_dre_id(NEW.dre);
+    RETURN NEW;
+END $$;
+
+DROP TRIGGER IF EXISTS schools_resolve_referencias ON schools;
+CREATE TRIGGER schools_resolve_referencias
+    BEFORE INSERT OR UPDATE OF municipio, dre ON schools
+    FOR EACH ROW EXECUTE FUNCTION schools_resolve_referencias();
+
+UPDATE schools
+SET municipio = municipio
+WHERE (municipio_id IS NULL AND censo_municipio_id(municipio) IS NOT NULL)
+   OR (dre_id IS NULL AND censo_dre_id(dre) IS NOT NULL);
+
+-- =====================================================================
+-- sync_outbox — fila durável da sincronização com o Google Sheets
+-- (espelho de infra/migrations/0035_sync_outbox.sql)
+-- =====================================================================
+-- Tentativas, backoff exponencial e dead-letter por item enviado.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS sync_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    census_id       INTEGER   NOT NULL REFERENCES census_responses(id) ON DELETE CASCADE,
+    kind            TEXT      NOT NULL,
+    target          TEXT      NOT NULL,
+    status          TEXT      NOT NULL DEFAULT 'pending',
+    attempts        INTEGER   NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error      TEXT      NULL,
+    locked_at       TIMESTAMP NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    done_at         TIMESTAMP NULL,
+    CONSTRAINT sync_outbox_status_chk
+        CHECK (status IN ('pending', 'processing', 'done', 'dead', 'discarded'))
+);
+
+-- No máximo um item pendente por censo e destino: concluir o censo de novo
+-- antes do envio não duplica a linha na planilha.
+CREATE UNIQUE INDEX IF NOT EXISTS sync_outbox_pending_uniq
+    ON sync_outbox (census_id, kind, target) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_sync_outbox_due
+    ON sync_outbox (next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_sync_outbox_status
+    ON sync_outbox (status, updated_at DESC);
+
+-- Censos concluídos ainda não enviados entram na fila. Censos que já têm
+-- item (inclusive 'dead' ou descartado) ficam como estão: a reaplicação
+-- no startup não ressuscita o que o painel tirou da fila.
+INSERT INTO sync_outbox (census_id, kind, target)
+SELECT cr.id, 'sheets', 'Base_dados'
+FROM census_responses cr
+WHERE censo_enviado(cr.status) AND cr.sheet_synced_at IS NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM sync_outbox o
+      WHERE o.census_id = cr.id AND o.kind = 'sheets' AND o.target = 'Base_dados')
+ON CONFLICT (census_id, kind, target) WHERE status = 'pending' DO NOTHING;
+
+-- =====================================================================
+-- sync_sinks — destinos da sincronização do censo
+-- (espelho de infra/migrations/0036_sync_sinks.sql)
+-- =====================================================================
+-- Sheets, arquivo CSV/JSONL e webhook; operação feita em sync_outbox.op.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS sync_sinks (
+    kind       TEXT      PRIMARY KEY,
+    target     TEXT      NOT NULL,
+    enabled    BOOLEAN   NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO sync_sinks (kind, target)
+VALUES ('sheets', 'Base_dados')
+ON CONFLICT (kind) DO NOTHING;
+
+ALTER TABLE sync_outbox ADD COLUMN IF NOT EXISTS op TEXT NULL;
+
+DO $$ BEGIN
+    ALTER TABLE sync_outbox
+        ADD CONSTRAINT sync_outbox_op_chk CHECK (op IN ('append', 'update', 'delete'));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+-- Último envio concluído por censo e destino.
+CREATE INDEX IF NOT EXISTS idx_sync_outbox_done
+    ON sync_outbox (census_id, kind, done_at DESC) WHERE status = 'done';
+
+-- =====================================================================
+-- school_photos — metadados das fotos das escolas
+-- (espelho de infra/migrations/0037_school_photos.sql)
+-- =====================================================================
+-- Escola, ano, armazenamento, caminho, tipo, tamanho e sha256 de cada foto.
+-- =====================================================================
+
+CREATE TABLE IF NOT EXISTS school_photos (
+    id            BIGSERIAL PRIMARY KEY,
+    school_id     INTEGER   NOT NULL REFERENCES schools(id) ON DELETE CASCADE,
+    year          INTEGER   NOT NULL,
+    storage       TEXT      NOT NULL,
+    path          TEXT      NOT NULL,
+    url           TEXT      NULL,
+    original_name TEXT      NOT NULL,
+    content_type  TEXT      NOT NULL,
+    size_bytes    BIGINT    NOT NULL CHECK (size_bytes > 0),
+    sha256        CHAR(64)  NOT NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_school_photos_sha
+    ON school_photos (school_id, year, sha256);
+
+-- =====================================================================
+-- school_photos — categorias e miniaturas
+-- (espelho de infra/migrations/0038_school_photo_categories.sql)
+-- =====================================================================
+-- Categoria (fachada, cozinha, banheiros, salas, outras), miniatura e dimensões.
+-- =====================================================================
+
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS category   TEXT    NOT NULL DEFAULT 'outras';
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS thumb_path TEXT    NULL;
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS thumb_url  TEXT    NULL;
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS width      INTEGER NULL;
+ALTER TABLE school_photos ADD COLUMN IF NOT EXISTS height     INTEGER NULL;
+
+DO $$ BEGIN
+    ALTER TABLE school_photos
+        ADD CONSTRAINT school_photos_category_chk
+        CHECK (category IN ('fachada', 'cozinha', 'banheiros', 'salas', 'outras'));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+-- Galeria do painel: fotos da escola por ano e categoria.
+CREATE INDEX IF NOT EXISTS idx_school_photos_school
+    ON school_photos (school_id, year, category, created_at);
+
+-- =====================================================================
+-- Views do censo lendo vw_censo_fonte e vw_censo_escolas
+-- (espelho de infra/migrations/0039_censo_views_fonte.sql)
+-- =====================================================================
+-- Cadastro da escola congelado no lote; views recriadas sobre a fonte.
+-- =====================================================================
+
+DROP FUNCTION IF EXISTS censo_views_ler_fonte();
+
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS dre          VARCHAR(100) NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS municipio    VARCHAR(100) NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS zona         VARCHAR(50)  NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS municipio_id INTEGER      NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS dre_id       INTEGER      NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS active       BOOLEAN      NULL;
+
+CREATE INDEX IF NOT EXISTS idx_census_snapshots_school ON census_snapshots (batch_id, school_id);
+
+-- Cadastro da escola no hash da linha; NULL (lote antigo, active nulo)
+-- mantém o hash de quatro campos, já que concat_ws ignora NULL.
+CREATE OR REPLACE FUNCTION censo_snapshot_escola(dre TEXT, municipio TEXT, zona TEXT,
+                                                 municipio_id INTEGER, dre_id INTEGER, active BOOLEAN) RETURNS JSONB
+    LANGUAGE sql IMMUTABLE
+    AS $fn$ SELECT CASE WHEN active IS NULL THEN NULL ELSE jsonb_build_object(
+        'dre', dre, 'municipio', municipio, 'zona', zona,
+        'municipio_id', municipio_id, 'dre_id', dre_id, 'active', active) END $fn$;
+
+CREATE OR REPLACE FUNCTION censo_snapshot_hash(school_id INTEGER, year INTEGER, status TEXT, data JSONB, escola JSONB) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE
+    AS $fn$ SELECT encode(sha256(convert_to(concat_ws('|', school_id, year, status, COALESCE(data, '{}'::jsonb)::text, escola::text), 'UTF8')), 'hex') $fn$;
+
+-- Os casts fixam os tipos das colunas de schools, como em vw_censo_fonte.
+CREATE OR REPLACE VIEW vw_censo_escolas AS
+SELECT
+    s.id,
+    s.nome_escola,
+    s.codigo_inep,
+    (CASE WHEN sn.school_id IS NULL THEN s.municipio ELSE sn.municipio END)::VARCHAR(100) AS municipio,
+    (CASE WHEN sn.school_id IS NULL THEN s.dre ELSE sn.dre END)::VARCHAR(100)             AS dre,
+    (CASE WHEN sn.school_id IS NULL THEN s.zona ELSE sn.zona END)::VARCHAR(50)            AS zona,
+    s.endereco,
+    s.cnpj,
+    s.telefone,
+    s.email,
+    s.cep,
+    s.nome_diretor,
+    s.matricula_diretor,
+    s.contato_diretor,
+    s.turnos,
+    s.etapas_ofertadas,
+    s.modalidades_ofertadas,
+    s.created_at,
+    CASE WHEN sn.school_id IS NULL THEN s.active ELSE sn.active END                       AS active,
+    s.ended_on,
+    s.merged_into,
+    s.updated_at,
+    s.import_batch_id,
+    CASE WHEN sn.school_id IS NULL THEN s.municipio_id ELSE sn.municipio_id END           AS municipio_id,
+    CASE WHEN sn.school_id IS NULL THEN s.dre_id ELSE sn.dre_id END                       AS dre_id
+FROM schools s
+LEFT JOIN census_snapshots sn
+       ON sn.school_id = s.id
+      AND sn.active IS NOT NULL
+      AND sn.batch_id = NULLIF(current_setting('censo.snapshot', true), '')::BIGINT;
+
+-- vw_censo_base (0001)
+CREATE OR REPLACE VIEW vw_censo_base AS
+SELECT
+    -- Identificação (schools) ------------------------------------------
+    s.id                                                AS school_id,
+    s.codigo_inep,
+    s.nome_escola,
+    s.dre,
+    s.municipio,
+    s.zona,
+
+    -- Operacional (census_responses) -----------------------------------
+    cr.id                                               AS census_id,
+    cr.year,
+    cr.status,
+    cr.created_at,
+    cr.updated_at,
+    cr.sheet_synced_at,
+
+    -- Quantitativos numéricos extraídos com cast seguro ----------------
+    CASE WHEN cr.data->>'total_alunos'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'total_alunos')::numeric       END AS total_alunos,
+    CASE WHEN cr.data->>'alunos_pcd'         ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'alunos_pcd')::numeric         END AS alunos_pcd,
+    CASE WHEN cr.data->>'alunos_rural'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'alunos_rural')::numeric       END AS alunos_rural,
+    CASE WHEN cr.data->>'alunos_urbana'      ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'alunos_urbana')::numeric      END AS alunos_urbana,
+    CASE WHEN cr.data->>'qtd_salas_aula'     ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_salas_aula')::numeric     END AS qtd_salas_aula,
+    CASE WHEN cr.data->>'salas_climatizadas' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'salas_climatizadas')::numeric END AS salas_climatizadas,
+    CASE WHEN cr.data->>'turmas_manha'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'turmas_manha')::numeric       END AS turmas_manha,
+    CASE WHEN cr.data->>'turmas_tarde'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'turmas_tarde')::numeric       END AS turmas_tarde,
+    CASE WHEN cr.data->>'turmas_noite'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'turmas_noite')::numeric       END AS turmas_noite,
+    CASE WHEN cr.data->>'turmas_integral'    ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'turmas_integral')::numeric    END AS turmas_integral,
+
+    -- Categóricos básicos (string vazia ⇒ NULL) ------------------------
+    NULLIF(cr.data->>'tipo_predio',           '')       AS tipo_predio,
+    NULLIF(cr.data->>'possui_anexos',         '')       AS possui_anexos,
+    NULLIF(cr.data->>'situacao_estrutura',    '')       AS situacao_estrutura,
+    NULLIF(cr.data->>'muro_cerca',            '')       AS muro_cerca,
+    NULLIF(cr.data->>'perimetro_fechado',     '')       AS perimetro_fechado,
+    NULLIF(cr.data->>'rede_eletrica_atende',  '')       AS rede_eletrica_atende,
+    NULLIF(cr.data->>'cameras_funcionamento', '')       AS cameras_funcionamento
+FROM vw_censo_escolas s
+LEFT JOIN vw_censo_fonte cr ON cr.school_id = s.id;
+
+-- vw_censo_direcao_escolar (0003)
+CREATE OR REPLACE VIEW vw_censo_direcao_escolar AS
+WITH censo_data AS (
+    SELECT
+        b.school_id,
+        b.codigo_inep,
+        b.nome_escola,
+        b.dre,
+        b.municipio,
+        b.zona,
+        b.year,
+        b.status,
+        b.census_id,
+        cr.data
+    FROM vw_censo_base b
+    JOIN vw_censo_fonte cr ON cr.id = b.census_id
+)
+SELECT
+    cd.school_id,
+    cd.codigo_inep,
+    cd.nome_escola,
+    cd.dre,
+    cd.municipio,
+    cd.zona,
+    cd.year,
+    cd.status,
+    cd.census_id,
+    v.cargo,
+    v.possui,
+    v.ordem
+FROM censo_data cd
+CROSS JOIN LATERAL (
+    VALUES
+        ('Direção Escolar',             lower(cd.data->>'possui_direcao') IN ('sim', 'true', 't', '1'), 1),
+        ('Vice-Diretor Pedagógico',     lower(cd.data->>'possui_vice_pedagogico') IN ('sim', 'true', 't', '1'), 2),
+        ('Vice-Diretor Administrativo', lower(cd.data->>'possui_vice_administrativo') IN ('sim', 'true', 't', '1'), 3),
+        ('Secretário Escolar',          lower(cd.data->>'possui_secretario') IN ('sim', 'true', 't', '1'), 4),
+        ('Coordenação Pedagógica',      lower(cd.data->>'possui_coord_pedagogico') IN ('sim', 'true', 't', '1'), 5)
+) AS v(cargo, possui, ordem);
+
+-- vw_censo_coordenacao_area (0004)
+CREATE OR REPLACE VIEW vw_censo_coordenacao_area AS
+WITH censo_data AS (
+    SELECT
+        b.school_id,
+        b.codigo_inep,
+        b.nome_escola,
+        b.dre,
+        b.municipio,
+        b.zona,
+        b.year,
+        b.status,
+        b.census_id,
+        cr.data
+    FROM vw_censo_base b
+    JOIN vw_censo_fonte cr ON cr.id = b.census_id
+)
+SELECT
+    cd.school_id,
+    cd.codigo_inep,
+    cd.nome_escola,
+    cd.dre,
+    cd.municipio,
+    cd.zona,
+    cd.year,
+    cd.status,
+    cd.census_id,
+    v.area,
+    v.possui,
+    v.ordem
+FROM censo_data cd
+CROSS JOIN LATERAL (
+    VALUES
+        ('Linguagens',         lower(cd.data->>'possui_coord_area_linguagem') IN ('sim', 'true', 't', '1'), 1),
+        ('Matemática',         lower(cd.data->>'possui_coord_area_matematica') IN ('sim', 'true', 't', '1'), 2),
+        ('Ciências Humanas',   lower(cd.data->>'possui_coord_area_humanas') IN ('sim', 'true', 't', '1'), 3),
+        ('Ciências da Natureza', lower(cd.data->>'possui_coord_area_natureza') IN ('sim', 'true', 't', '1'), 4)
+) AS v(area, possui, ordem);
+
+-- vw_censo_quadro_pessoal (0005)
+CREATE OR REPLACE VIEW vw_censo_quadro_pessoal AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.year,
+    b.status,
+    b.census_id,
+    CASE WHEN cr.data->>'qtd_professores_efetivos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_professores_efetivos')::numeric
+         ELSE 0 END AS qtd_professores_efetivos,
+    CASE WHEN cr.data->>'qtd_professores_temporarios' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_professores_temporarios')::numeric
+         ELSE 0 END AS qtd_professores_temporarios,
+    CASE WHEN cr.data->>'qtd_servidores_administrativos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_servidores_administrativos')::numeric
+         ELSE 0 END AS qtd_servidores_administrativos,
+    CASE WHEN cr.data->>'qtd_professor_readaptado' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_professor_readaptado')::numeric
+         ELSE 0 END AS qtd_professor_readaptado,
+    (CASE WHEN cr.data->>'qtd_professores_efetivos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+           THEN (cr.data->>'qtd_professores_efetivos')::numeric
+           ELSE 0 END +
+     CASE WHEN cr.data->>'qtd_professores_temporarios' ~ '^-?[0-9]+(\.[0-9]+)?$'
+           THEN (cr.data->>'qtd_professores_temporarios')::numeric
+           ELSE 0 END)::numeric AS total_professores
+FROM vw_censo_base b
+JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_equipamentos_tecnologia (0006)
+CREATE OR REPLACE VIEW vw_censo_equipamentos_tecnologia AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.year,
+    b.status,
+    b.census_id,
+
+    -- Conectividade
+    lower(cr.data->>'internet_disponivel') IN ('sim', 'true', 't', '1')
+        AS internet_disponivel,
+    NULLIF(cr.data->>'provedor_internet', '')
+        AS provedor_internet,
+    NULLIF(cr.data->>'qualidade_internet', '')
+        AS qualidade_internet,
+
+    -- Parque de computadores
+    CASE WHEN cr.data->>'qtd_desktop_adm' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_desktop_adm')::numeric END AS qtd_desktop_adm,
+    CASE WHEN cr.data->>'qtd_desktop_alunos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_desktop_alunos')::numeric END AS qtd_desktop_alunos,
+    CASE WHEN cr.data->>'qtd_notebooks' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_notebooks')::numeric END AS qtd_notebooks,
+    CASE WHEN cr.data->>'qtd_chromebooks' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_chromebooks')::numeric END AS qtd_chromebooks,
+    CASE WHEN cr.data->>'qtd_computadores_inoperantes' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_computadores_inoperantes')::numeric END AS qtd_computadores_inoperantes,
+    NULLIF(cr.data->>'computadores_atendem', '')
+        AS computadores_atendem,
+
+    -- Recursos pedagógicos
+    lower(cr.data->>'possui_projetor') IN ('sim', 'true', 't', '1')
+        AS possui_projetor,
+    CASE WHEN cr.data->>'qtd_projetores' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_projetores')::numeric END AS qtd_projetores,
+    lower(cr.data->>'possui_lousa_digital') IN ('sim', 'true', 't', '1')
+        AS possui_lousa_digital
+
+FROM vw_censo_base b
+JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_ambientes (0007)
+CREATE OR REPLACE VIEW vw_censo_ambientes AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+    amb.value AS ambiente
+FROM vw_censo_base b
+INNER JOIN vw_censo_fonte cr
+        ON cr.id = b.census_id
+       AND cr.data ? 'ambientes'
+       AND jsonb_typeof(cr.data->'ambientes') = 'array'
+CROSS JOIN LATERAL jsonb_array_elements_text(cr.data->'ambientes') AS amb(value)
+WHERE amb.value IS NOT NULL
+  AND amb.value <> '';
+
+-- vw_censo_infraestrutura_seguranca (0008)
+CREATE OR REPLACE VIEW vw_censo_infraestrutura_seguranca AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    b.tipo_predio,
+    b.situacao_estrutura,
+    b.possui_anexos,
+    CASE WHEN cr.data->>'qtd_anexos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_anexos')::numeric END              AS qtd_anexos,
+    NULLIF(cr.data->>'tipo_predio_anexo',          '')           AS tipo_predio_anexo,
+
+    b.muro_cerca,
+    b.perimetro_fechado,
+
+    NULLIF(cr.data->>'quadra_coberta',             '')           AS quadra_coberta,
+    CASE WHEN cr.data->>'qtd_quadras' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_quadras')::numeric END             AS qtd_quadras,
+    NULLIF(cr.data->>'banda_fanfarra',             '')           AS banda_fanfarra,
+
+    CASE WHEN cr.data->>'banheiros_alunos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'banheiros_alunos')::numeric END        AS banheiros_alunos,
+    CASE WHEN cr.data->>'banheiros_prof' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'banheiros_prof')::numeric END          AS banheiros_prof,
+    CASE WHEN cr.data->>'banheiros_chuveiro' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'banheiros_chuveiro')::numeric END      AS banheiros_chuveiro,
+    NULLIF(cr.data->>'banheiros_vasos_funcionais', '')           AS banheiros_vasos_funcionais,
+
+    NULLIF(cr.data->>'energia',                    '')           AS energia,
+    b.rede_eletrica_atende,
+    NULLIF(cr.data->>'estrutura_climatizacao',     '')           AS estrutura_climatizacao,
+    NULLIF(cr.data->>'suporta_novos_equipamentos', '')           AS suporta_novos_equipamentos,
+
+    b.cameras_funcionamento,
+    NULLIF(cr.data->>'cameras_cobrem',             '')           AS cameras_cobrem,
+
+    NULLIF(cr.data->>'possui_guarita',             '')           AS possui_guarita,
+    NULLIF(cr.data->>'controle_portao',            '')           AS controle_portao,
+    NULLIF(cr.data->>'iluminacao_externa',         '')           AS iluminacao_externa,
+    NULLIF(cr.data->>'possui_botao_panico',        '')           AS possui_botao_panico,
+
+    NULLIF(cr.data->>'plano_evacuacao',            '')           AS plano_evacuacao,
+    NULLIF(cr.data->>'politica_bullying',          '')           AS politica_bullying
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_equipamentos_merenda (0009)
+CREATE OR REPLACE VIEW vw_censo_equipamentos_merenda AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    NULLIF(cr.data->>'condicoes_cozinha',   '')  AS condicoes_cozinha,
+    NULLIF(cr.data->>'tamanho_cozinha',     '')  AS tamanho_cozinha,
+    NULLIF(cr.data->>'possui_refeitorio',   '')  AS possui_refeitorio,
+    NULLIF(cr.data->>'refeitorio_adequado', '')  AS refeitorio_adequado,
+    NULLIF(cr.data->>'possui_balanca',      '')  AS possui_balanca,
+    NULLIF(cr.data->>'bancadas_inox',       '')  AS bancadas_inox,
+    NULLIF(cr.data->>'sistema_exaustao',    '')  AS sistema_exaustao,
+    NULLIF(cr.data->>'despensa_exclusiva',  '')  AS despensa_exclusiva,
+    NULLIF(cr.data->>'deposito_conserva',   '')  AS deposito_conserva,
+    NULLIF(cr.data->>'estoque_epi_extintor','')  AS estoque_epi_extintor,
+    NULLIF(cr.data->>'manutencao_extintores','') AS manutencao_extintores,
+
+    CASE WHEN cr.data->>'qtd_freezers' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_freezers')::numeric END    AS qtd_freezers,
+    lower(NULLIF(cr.data->>'estado_freezers',   ''))     AS estado_freezers,
+
+    CASE WHEN cr.data->>'qtd_geladeiras' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_geladeiras')::numeric END  AS qtd_geladeiras,
+    lower(NULLIF(cr.data->>'estado_geladeiras', ''))     AS estado_geladeiras,
+
+    CASE WHEN cr.data->>'qtd_fogoes' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_fogoes')::numeric END      AS qtd_fogoes,
+    lower(NULLIF(cr.data->>'estado_fogoes',     ''))     AS estado_fogoes,
+
+    CASE WHEN cr.data->>'qtd_fornos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_fornos')::numeric END      AS qtd_fornos,
+    lower(NULLIF(cr.data->>'estado_fornos',     ''))     AS estado_fornos,
+
+    CASE WHEN cr.data->>'qtd_bebedouros' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_bebedouros')::numeric END  AS qtd_bebedouros,
+    lower(NULLIF(cr.data->>'estado_bebedouros', ''))     AS estado_bebedouros
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_rh_merendeiras (0010)
+CREATE OR REPLACE VIEW vw_censo_rh_merendeiras AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    NULLIF(cr.data->>'oferta_regular',      '')  AS oferta_regular,
+    NULLIF(cr.data->>'qualidade_merenda',   '')  AS qualidade_merenda,
+    NULLIF(cr.data->>'atende_necessidades', '')  AS atende_necessidades,
+
+    CASE WHEN cr.data->>'qtd_merendeiras_estatutaria' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_merendeiras_estatutaria')::numeric END  AS qtd_merendeiras_estatutaria,
+    CASE WHEN cr.data->>'qtd_merendeiras_terceirizada' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_merendeiras_terceirizada')::numeric END AS qtd_merendeiras_terceirizada,
+    CASE WHEN cr.data->>'qtd_merendeiras_temporaria' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_merendeiras_temporaria')::numeric END   AS qtd_merendeiras_temporaria,
+
+    NULLIF(cr.data->>'qtd_atende_necessidade_merenda',  '')  AS qtd_atende_necessidade_merenda,
+    CASE WHEN cr.data->>'quantitativo_necessario_merenda' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'quantitativo_necessario_merenda')::numeric END AS quantitativo_necessario_merenda,
+
+    NULLIF(cr.data->>'empresa_terceirizada_merenda', '')  AS empresa_terceirizada_merenda,
+    NULLIF(cr.data->>'possui_supervisor_merenda',    '')  AS possui_supervisor_merenda
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_rh_servicos_gerais (0011)
+CREATE OR REPLACE VIEW vw_censo_rh_servicos_gerais AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    CASE WHEN cr.data->>'qtd_servicos_gerais_efetivo' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_servicos_gerais_efetivo')::numeric END      AS qtd_servicos_gerais_efetivo,
+    CASE WHEN cr.data->>'qtd_servicos_gerais_temporario' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_servicos_gerais_temporario')::numeric END   AS qtd_servicos_gerais_temporario,
+    CASE WHEN cr.data->>'qtd_servicos_gerais_terceirizado' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_servicos_gerais_terceirizado')::numeric END AS qtd_servicos_gerais_terceirizado,
+
+    NULLIF(cr.data->>'qtd_atende_necessidade_sg',  '')  AS qtd_atende_necessidade_sg,
+    CASE WHEN cr.data->>'quantitativo_necessario_sg' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'quantitativo_necessario_sg')::numeric END AS quantitativo_necessario_sg,
+
+    NULLIF(cr.data->>'empresa_terceirizada_sg', '')  AS empresa_terceirizada_sg,
+    NULLIF(cr.data->>'possui_supervisor_sg',    '')  AS possui_supervisor_sg
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_servicos_terceirizados (0012)
+CREATE OR REPLACE VIEW vw_censo_servicos_terceirizados AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    -- Portaria
+    CASE WHEN cr.data->>'qtd_agentes_portaria' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_agentes_portaria')::numeric END  AS qtd_agentes_portaria,
+    NULLIF(cr.data->>'qtd_atende_necessidade_portaria', '')    AS qtd_atende_necessidade_portaria,
+    CASE WHEN cr.data->>'quantitativo_necessario_portaria' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'quantitativo_necessario_portaria')::numeric END AS quantitativo_necessario_portaria,
+    NULLIF(cr.data->>'empresa_terceirizada_portaria', '')      AS empresa_terceirizada_portaria,
+    NULLIF(cr.data->>'possui_supervisor_portaria',    '')      AS possui_supervisor_portaria,
+
+    -- Flags de terceirização por área (presença de empresa terceirizada)
+    NULLIF(cr.data->>'empresa_terceirizada_merenda',  '')      AS empresa_terceirizada_merenda,
+    NULLIF(cr.data->>'empresa_terceirizada_sg',       '')      AS empresa_terceirizada_sg,
+
+    -- Avaliações dos serviços terceirizados
+    NULLIF(cr.data->>'avaliacao_merendeiras',  '')  AS avaliacao_merendeiras,
+    NULLIF(cr.data->>'avaliacao_portaria',     '')  AS avaliacao_portaria,
+    NULLIF(cr.data->>'avaliacao_limpeza',      '')  AS avaliacao_limpeza,
+    NULLIF(cr.data->>'avaliacao_comunicacao',  '')  AS avaliacao_comunicacao,
+    NULLIF(cr.data->>'avaliacao_supervisao',   '')  AS avaliacao_supervisao
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_governanca_institucional (0016, recriada na 0027)
+CREATE OR REPLACE VIEW vw_censo_governanca_institucional AS
+WITH base AS (
+    SELECT
+        cr.id                                       AS census_id,
+        s.id                                        AS school_id,
+        s.codigo_inep,
+        s.nome_escola                               AS escola,
+        s.dre,
+        s.municipio,
+        s.zona,
+        NULLIF(cr.data->>'regularizada_cee', '')    AS regularizada_cee,
+        NULLIF(cr.data->>'conselho_escolar', '')    AS conselho_escolar,
+        NULLIF(cr.data->>'conselho_ativo', '')      AS conselho_ativo
+    FROM vw_censo_fonte cr
+    JOIN vw_censo_escolas s ON s.id = cr.school_id
+    WHERE censo_enviado(cr.status)
+)
+SELECT
+    census_id,
+    school_id,
+    codigo_inep,
+    escola,
+    dre,
+    municipio,
+    zona,
+    regularizada_cee,
+    conselho_escolar,
+    conselho_ativo,
+    -- Booleanos: NULL quando o campo é "Não informado" (não vira FALSE).
+    (regularizada_cee = 'Sim')        AS is_regularizada_cee,
+    (conselho_escolar = 'Sim')        AS has_conselho_escolar,
+    (conselho_ativo = 'Sim')          AS is_conselho_ativo,
+    (conselho_ativo = 'Parcialmente') AS is_conselho_parcialmente_ativo,
+    -- Governança completa: os três quesitos = 'Sim'. NULL em qualquer campo
+    -- propaga NULL (não conta como completa nem como crítica).
+    (regularizada_cee = 'Sim'
+     AND conselho_escolar = 'Sim'
+     AND conselho_ativo = 'Sim')      AS is_governanca_completa,
+    -- Governança crítica: pelo menos um quesito = 'Não'. Como o OR com TRUE
+    -- curto-circuita, basta um 'Não' para resultar TRUE; valores NULL não
+    -- forçam crítica (Não informado não vira Não).
+    (regularizada_cee = 'Não'
+     OR conselho_escolar = 'Não'
+     OR conselho_ativo = 'Não')       AS is_governanca_critica
+FROM base;
+
+-- =====================================================================
+-- schools — INEP da escola fundida
+-- (espelho de infra/migrations/0040_schools_inep_fundido.sql)
+-- =====================================================================
+-- A fusão libera o INEP da linha fundida e o passa à sobrevivente.
+-- =====================================================================
+
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS codigo_inep_fundido VARCHAR(20) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_schools_inep_fundido ON schools (codigo_inep_fundido) WHERE codigo_inep_fundido IS NOT NULL;
+
+UPDATE schools
+SET codigo_inep_fundido = NULLIF(codigo_inep, ''), codigo_inep = NULL
+WHERE merged_into IS NOT NULL AND codigo_inep IS NOT NULL;
+
+UPDATE schools t
+SET codigo_inep = f.codigo_inep_fundido, updated_at = NOW()
+FROM (
+    SELECT DISTINCT ON (merged_into) merged_into, codigo_inep_fundido
+    FROM schools
+    WHERE merged_into IS NOT NULL AND NULLIF(codigo_inep_fundido, '') IS NOT NULL
+    ORDER BY merged_into, id
+) f
+WHERE t.id = f.merged_into
+  AND t.merged_into IS NULL
+  AND NULLIF(t.codigo_inep, '') IS NULL
+  AND NOT EXISTS (SELECT 1 FROM schools o WHERE o.codigo_inep = f.codigo_inep_fundido);
+
+-- =====================================================================
+-- sync_sinks — chave por destino
+-- (espelho de infra/migrations/0041_sync_sinks_target.sql)
+-- =====================================================================
+-- Chave (kind, target): cada URL ou arquivo é um destino próprio.
+-- =====================================================================
+
+DO $$ BEGIN
+    IF (SELECT array_length(indkey::int2[], 1) FROM pg_index
+        WHERE indrelid = 'sync_sinks'::regclass AND indisprimary) = 1 THEN
+        ALTER TABLE sync_sinks DROP CONSTRAINT sync_sinks_pkey;
+        ALTER TABLE sync_sinks ADD CONSTRAINT sync_sinks_pkey PRIMARY KEY (kind, target);
+    END IF;
+END $$;
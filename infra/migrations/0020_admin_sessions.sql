@@ -0,0 +1,53 @@
+-- 0020_admin_sessions
+-- Sessões do painel administrativo e revogação de tokens.
+--
+-- admin_sessions: uma linha por login. Guarda o hash SHA-256 do refresh
+-- token atual (nunca o valor em claro) e do anterior, para detectar reuso de
+-- um refresh token já rotacionado — sinal de vazamento, que encerra a
+-- sessão inteira. expires_at desliza a cada renovação, limitado por
+-- absolute_expires_at. revoked_at preenchido = sessão encerrada (logout ou
+-- encerramento pelo seduc_admin); os access tokens emitidos nela deixam de
+-- valer na próxima requisição.
+--
+-- admin_revoked_tokens: lista de revogação por jti dos access tokens. Cada
+-- linha só precisa viver até a expiração do próprio token (expires_at);
+-- depois disso é removida pela limpeza periódica da API.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0020_admin_sessions.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id                   BIGSERIAL PRIMARY KEY,
+    user_id              INTEGER      NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    refresh_token_hash   CHAR(64)     NOT NULL,
+    previous_token_hash  CHAR(64)     NULL,
+    rotated_at           TIMESTAMP    NULL,
+    ip                   VARCHAR(64)  NOT NULL DEFAULT '',
+    user_agent           VARCHAR(255) NOT NULL DEFAULT '',
+    created_at           TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at           TIMESTAMP    NOT NULL,
+    absolute_expires_at  TIMESTAMP    NOT NULL,
+    revoked_at           TIMESTAMP    NULL
+);
+
+DO $$ BEGIN
+    ALTER TABLE admin_sessions
+        ADD CONSTRAINT admin_sessions_refresh_hash_uniq
+        UNIQUE (refresh_token_hash);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_user
+    ON admin_sessions (user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_previous_hash
+    ON admin_sessions (previous_token_hash) WHERE previous_token_hash IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS admin_revoked_tokens (
+    jti         VARCHAR(64) PRIMARY KEY,
+    user_id     INTEGER     NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    expires_at  TIMESTAMP   NOT NULL,
+    revoked_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_revoked_tokens_expires
+    ON admin_revoked_tokens (expires_at);
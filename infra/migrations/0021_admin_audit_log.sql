@@ -0,0 +1,31 @@
+-- 0021_admin_audit_log
+-- Trilha de auditoria das ações no painel administrativo (LGPD). Uma linha
+-- por requisição autenticada em /v1/admin/*, gravada pelo middleware
+-- auditAdminAccess (api/cmd/api/admin_audit.go): quem (user_id/username,
+-- copiados para sobreviver a renomeações e remoções), de onde (ip, via
+-- clientIP), o quê (method, route = padrão chi, path = caminho real, query
+-- = filtros da query string), resultado (status_code) e quando.
+--
+-- Registros não são alterados nem removidos pela API.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0021_admin_audit_log.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      INTEGER      NULL REFERENCES admin_users (id) ON DELETE SET NULL,
+    username     VARCHAR(64)  NOT NULL DEFAULT '',
+    role         VARCHAR(30)  NOT NULL DEFAULT '',
+    ip           VARCHAR(64)  NOT NULL DEFAULT '',
+    method       VARCHAR(10)  NOT NULL,
+    route        VARCHAR(255) NOT NULL,
+    path         VARCHAR(500) NOT NULL,
+    query        JSONB        NOT NULL DEFAULT '{}'::jsonb,
+    status_code  INTEGER      NOT NULL,
+    duration_ms  INTEGER      NOT NULL DEFAULT 0,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created_at ON admin_audit_log (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_user ON admin_audit_log (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_route ON admin_audit_log (route);
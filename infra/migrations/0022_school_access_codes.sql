@@ -0,0 +1,33 @@
+-- 0022_school_access_codes
+-- Códigos de acesso por escola para o formulário público. Cada código é
+-- vinculado a uma escola (school_id) e autoriza somente escritas daquela
+-- escola em POST /v1/census, POST /v1/schools e POST /v1/upload (header
+-- X-School-Access). O código em claro é mostrado uma única vez, na emissão
+-- pelo painel; aqui fica só o hash SHA-256 (code_hash) e os 4 últimos
+-- caracteres (code_hint) para conferência.
+--
+-- Uma escola tem no máximo um código ativo (revoked_at IS NULL): rotacionar
+-- revoga o atual e emite outro.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0022_school_access_codes.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS school_access_codes (
+    id            BIGSERIAL PRIMARY KEY,
+    school_id     INTEGER     NOT NULL REFERENCES schools (id) ON DELETE CASCADE,
+    code_hash     CHAR(64)    NOT NULL,
+    code_hint     VARCHAR(8)  NOT NULL DEFAULT '',
+    issued_by     INTEGER     NULL REFERENCES admin_users (id) ON DELETE SET NULL,
+    created_at    TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at  TIMESTAMP   NULL,
+    revoked_at    TIMESTAMP   NULL
+);
+
+DO $$ BEGIN
+    ALTER TABLE school_access_codes
+        ADD CONSTRAINT school_access_codes_hash_uniq
+        UNIQUE (code_hash);
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_school_access_codes_active
+    ON school_access_codes (school_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,24 @@
+-- 0023_rate_limit_hits
+-- Registro das tentativas contadas pelos limites de taxa (login, refresh,
+-- escrita de censo, upload). Fica no banco para que o limite valha para
+-- todas as réplicas da API e sobreviva a um deploy. Cada linha é uma
+-- tentativa aceita de uma chave (ip:<addr> ou school:<id>) num balde; a
+-- janela é deslizante e expires_at marca quando a linha deixa de contar.
+--
+-- Linhas expiradas são apagadas periodicamente pela API.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0023_rate_limit_hits.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS rate_limit_hits (
+    id          BIGSERIAL PRIMARY KEY,
+    bucket      VARCHAR(40)  NOT NULL,
+    key         VARCHAR(120) NOT NULL,
+    hit_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at  TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_hits_key
+    ON rate_limit_hits (bucket, key, hit_at);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_hits_expires
+    ON rate_limit_hits (expires_at);
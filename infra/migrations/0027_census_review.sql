@@ -0,0 +1,91 @@
+-- 0027_census_review
+-- Fluxo de revisão do censo pela DRE. census_responses.status passa a
+-- seguir a máquina de estados:
+--
+--   draft ⇄ completed (enviado) → em_revisao → devolvido | aprovado
+--                                              devolvido → completed
+--
+-- 'completed' é o estado "enviado" (submitted) e mantém o valor histórico.
+-- Cada transição de revisão entra em census_reviews com o revisor e o
+-- comentário (obrigatório na devolução).
+--
+-- censo_enviado(status) concentra o recorte "censo concluído" das
+-- análises: enviado, em revisão ou aprovado. Devolvido voltou à escola e
+-- não conta até ser reenviado. A view de governança, que filtrava
+-- status = 'completed', é recriada com o mesmo recorte.
+--
+-- O CHECK entra como NOT VALID: vale para novas escritas sem recusar
+-- linhas antigas com status fora da lista.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0027_census_review.sql e infra/init.sql.
+
+CREATE OR REPLACE FUNCTION censo_enviado(status TEXT) RETURNS BOOLEAN
+    LANGUAGE sql IMMUTABLE
+    AS $fn$ SELECT status IN ('completed', 'em_revisao', 'aprovado') $fn$;
+
+DO $$ BEGIN
+    ALTER TABLE census_responses
+        ADD CONSTRAINT census_responses_status_check
+        CHECK (status IN ('draft', 'completed', 'em_revisao', 'devolvido', 'aprovado')) NOT VALID;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE TABLE IF NOT EXISTS census_reviews (
+    id          BIGSERIAL   PRIMARY KEY,
+    census_id   INTEGER     NOT NULL REFERENCES census_responses(id) ON DELETE CASCADE,
+    from_status VARCHAR(50) NOT NULL,
+    to_status   VARCHAR(50) NOT NULL,
+    reviewer    VARCHAR(64) NOT NULL,
+    comment     TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_census_reviews_census ON census_reviews (census_id, created_at DESC);
+
+-- vw_censo_governanca_institucional passa a usar o recorte censo_enviado.
+
+CREATE OR REPLACE VIEW vw_censo_governanca_institucional AS
+WITH base AS (
+    SELECT
+        cr.id                                       AS census_id,
+        s.id                                        AS school_id,
+        s.codigo_inep,
+        s.nome_escola                               AS escola,
+        s.dre,
+        s.municipio,
+        s.zona,
+        NULLIF(cr.data->>'regularizada_cee', '')    AS regularizada_cee,
+        NULLIF(cr.data->>'conselho_escolar', '')    AS conselho_escolar,
+        NULLIF(cr.data->>'conselho_ativo', '')      AS conselho_ativo
+    FROM census_responses cr
+    JOIN schools s ON s.id = cr.school_id
+    WHERE censo_enviado(cr.status)
+)
+SELECT
+    census_id,
+    school_id,
+    codigo_inep,
+    escola,
+    dre,
+    municipio,
+    zona,
+    regularizada_cee,
+    conselho_escolar,
+    conselho_ativo,
+    -- Booleanos: NULL quando o campo é "Não informado" (não vira FALSE).
+    (regularizada_cee = 'Sim')        AS is_regularizada_cee,
+    (conselho_escolar = 'Sim')        AS has_conselho_escolar,
+    (conselho_ativo = 'Sim')          AS is_conselho_ativo,
+    (conselho_ativo = 'Parcialmente') AS is_conselho_parcialmente_ativo,
+    -- Governança completa: os três quesitos = 'Sim'. NULL em qualquer campo
+    -- propaga NULL (não conta como completa nem como crítica).
+    (regularizada_cee = 'Sim'
+     AND conselho_escolar = 'Sim'
+     AND conselho_ativo = 'Sim')      AS is_governanca_completa,
+    -- Governança crítica: pelo menos um quesito = 'Não'. Como o OR com TRUE
+    -- curto-circuita, basta um 'Não' para resultar TRUE; valores NULL não
+    -- forçam crítica (Não informado não vira Não).
+    (regularizada_cee = 'Não'
+     OR conselho_escolar = 'Não'
+     OR conselho_ativo = 'Não')       AS is_governanca_critica
+FROM base;
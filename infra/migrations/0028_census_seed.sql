@@ -0,0 +1,14 @@
+-- 0028_census_seed
+-- Pré-preenchimento do censo a partir do ano anterior. POST
+-- /v1/census/seed cria o rascunho do ano N com as respostas "estruturais"
+-- do censo N-1 (lista configurável em CENSUS_CARRY_FORWARD_KEYS).
+--
+--   - seeded_from_year: ano de origem das respostas copiadas;
+--   - seeded_fields: chaves copiadas que o diretor ainda não confirmou. Uma
+--     chave sai da lista quando uma escrita do formulário a inclui.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0028_census_seed.sql e infra/init.sql.
+
+ALTER TABLE census_responses ADD COLUMN IF NOT EXISTS seeded_from_year INTEGER NULL;
+ALTER TABLE census_responses ADD COLUMN IF NOT EXISTS seeded_fields JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,31 @@
+-- 0029_census_campaigns
+-- Campanhas do censo: cada ano tem uma janela de preenchimento (abertura e
+-- encerramento, datas inclusivas no fuso de Belém) e a versão do
+-- formulário usada na campanha. census_campaign_extensions guarda
+-- prorrogações do encerramento por DRE.
+--
+-- Com ao menos uma campanha cadastrada, o formulário só grava censos de
+-- anos com campanha e dentro da janela (prorrogação da DRE da escola
+-- incluída). Sem nenhuma campanha, as escritas seguem livres como antes.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0029_census_campaigns.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS census_campaigns (
+    year         INTEGER     PRIMARY KEY,
+    opens_on     DATE        NOT NULL,
+    closes_on    DATE        NOT NULL,
+    form_version VARCHAR(32) NOT NULL DEFAULT '',
+    created_at   TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT census_campaigns_window_check CHECK (closes_on >= opens_on)
+);
+
+CREATE TABLE IF NOT EXISTS census_campaign_extensions (
+    year       INTEGER      NOT NULL REFERENCES census_campaigns(year) ON DELETE CASCADE,
+    dre        VARCHAR(255) NOT NULL,
+    closes_on  DATE         NOT NULL,
+    reason     TEXT         NOT NULL DEFAULT '',
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (year, dre)
+);
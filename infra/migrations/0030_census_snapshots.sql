@@ -0,0 +1,114 @@
+-- 0030_census_snapshots
+-- Fotografia oficial do censo. Depois do encerramento da campanha, o
+-- "congelamento" copia todos os census_responses do ano para
+-- census_snapshots, sob um lote (census_snapshot_batches) com o hash do
+-- conteúdo. Análises e relatórios lidos com ?snapshot=<id> passam a usar
+-- o lote congelado em vez da tabela viva, e os números publicados ficam
+-- reproduzíveis.
+--
+--   - censo_snapshot_hash: SHA-256 de escola, ano, status e data de uma
+--     linha; o hash do lote encadeia os das linhas em ordem de census_id.
+--   - census_snapshots e census_snapshot_batches são imutáveis (gatilho
+--     recusa UPDATE e DELETE).
+--   - vw_censo_fonte é a fonte de censos das análises: census_responses
+--     ou, com a configuração de sessão censo.snapshot definida (SET LOCAL
+--     pela API), as linhas do lote. As views vw_censo_* deixam de ler
+--     census_responses diretamente e passam a ler vw_censo_fonte: a função
+--     censo_views_ler_fonte() reescreve as definições atuais. A 0039
+--     recria as views já lendo a fonte e remove a função.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0030_census_snapshots.sql e infra/init.sql.
+
+CREATE OR REPLACE FUNCTION censo_snapshot_hash(school_id INTEGER, year INTEGER, status TEXT, data JSONB) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE
+    AS $fn$ SELECT encode(sha256(convert_to(concat_ws('|', school_id, year, status, COALESCE(data, '{}'::jsonb)::text), 'UTF8')), 'hex') $fn$;
+
+CREATE TABLE IF NOT EXISTS census_snapshot_batches (
+    id           BIGSERIAL    PRIMARY KEY,
+    year         INTEGER      NOT NULL,
+    label        VARCHAR(120) NOT NULL DEFAULT '',
+    row_count    INTEGER      NOT NULL,
+    content_hash CHAR(64)     NOT NULL,
+    created_by   VARCHAR(64)  NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_census_snapshot_batches_year ON census_snapshot_batches (year, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS census_snapshots (
+    batch_id        BIGINT      NOT NULL REFERENCES census_snapshot_batches (id),
+    census_id       INTEGER     NOT NULL,
+    school_id       INTEGER     NOT NULL,
+    year            INTEGER     NOT NULL,
+    status          VARCHAR(50) NOT NULL,
+    data            JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    version         INTEGER     NOT NULL DEFAULT 0,
+    created_at      TIMESTAMP   NULL,
+    updated_at      TIMESTAMP   NULL,
+    sheet_synced_at TIMESTAMP   NULL,
+    content_hash    CHAR(64)    NOT NULL,
+    PRIMARY KEY (batch_id, census_id)
+);
+
+CREATE OR REPLACE FUNCTION census_snapshots_imutavel() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $fn$ BEGIN RAISE EXCEPTION 'fotografias do censo são imutáveis (%)', TG_TABLE_NAME; END $fn$;
+
+DROP TRIGGER IF EXISTS trg_census_snapshots_imutavel ON census_snapshots;
+CREATE TRIGGER trg_census_snapshots_imutavel BEFORE UPDATE OR DELETE ON census_snapshots
+    FOR EACH ROW EXECUTE FUNCTION census_snapshots_imutavel();
+
+DROP TRIGGER IF EXISTS trg_census_snapshot_batches_imutavel ON census_snapshot_batches;
+CREATE TRIGGER trg_census_snapshot_batches_imutavel BEFORE UPDATE OR DELETE ON census_snapshot_batches
+    FOR EACH ROW EXECUTE FUNCTION census_snapshots_imutavel();
+
+-- O select externo fixa os tipos das colunas (status VARCHAR(50)) para que
+-- as views dependentes possam ser recriadas sem mudança de tipo.
+CREATE OR REPLACE VIEW vw_censo_fonte AS
+SELECT
+    f.id,
+    f.school_id,
+    f.year,
+    f.status::VARCHAR(50) AS status,
+    f.data,
+    f.version,
+    f.created_at,
+    f.updated_at,
+    f.sheet_synced_at
+FROM (
+    SELECT id, school_id, year, status, data, version, created_at, updated_at, sheet_synced_at
+    FROM census_responses
+    WHERE NULLIF(current_setting('censo.snapshot', true), '') IS NULL
+    UNION ALL
+    SELECT census_id, school_id, year, status, data, version, created_at, updated_at, sheet_synced_at
+    FROM census_snapshots
+    WHERE batch_id = NULLIF(current_setting('censo.snapshot', true), '')::BIGINT
+) f;
+
+CREATE OR REPLACE FUNCTION censo_views_ler_fonte() RETURNS VOID
+    LANGUAGE plpgsql
+    AS $fn$
+DECLARE
+    v TEXT;
+    def TEXT;
+BEGIN
+    FOREACH v IN ARRAY ARRAY[
+        'vw_censo_base', 'vw_censo_direcao_escolar', 'vw_censo_coordenacao_area',
+        'vw_censo_quadro_pessoal', 'vw_censo_equipamentos_tecnologia', 'vw_censo_ambientes',
+        'vw_censo_infraestrutura_seguranca', 'vw_censo_equipamentos_merenda',
+        'vw_censo_rh_merendeiras', 'vw_censo_rh_servicos_gerais',
+        'vw_censo_servicos_terceirizados', 'vw_censo_governanca_institucional'
+    ] LOOP
+        IF to_regclass(v) IS NULL THEN
+            CONTINUE;
+        END IF;
+        def := regexp_replace(pg_get_viewdef(v::regclass, false), ';\s*$', '');
+        IF def ~ '\mcensus_responses\M' THEN
+            EXECUTE format('CREATE OR REPLACE VIEW %I AS %s', v,
+                regexp_replace(def, '\mcensus_responses\M', 'vw_censo_fonte', 'g'));
+        END IF;
+    END LOOP;
+END $fn$;
+
+SELECT censo_views_ler_fonte();
@@ -0,0 +1,38 @@
+-- 0031_schools_admin
+-- Manutenção do cadastro de escolas pelo painel: desativação lógica e
+-- fusão de linhas duplicadas.
+--
+--   - active/ended_on: escola desativada (fechada, extinta) sai da listagem
+--     pública e do universo de preenchimento, mas mantém o histórico. Uma
+--     escola inativa sempre tem data de encerramento.
+--   - merged_into: a linha foi fundida em outra escola; os censos, repasses
+--     PRODEP e resultados IDEB passaram para a sobrevivente.
+--   - updated_at: última alteração pelo painel.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0031_schools_admin.sql e infra/init.sql.
+
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS active      BOOLEAN   NOT NULL DEFAULT TRUE;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS ended_on    DATE      NULL;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS merged_into INTEGER   NULL;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS updated_at  TIMESTAMP NULL;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_merged_into_fk
+        FOREIGN KEY (merged_into) REFERENCES schools(id) ON DELETE SET NULL;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_active_ended_chk
+        CHECK ((active AND ended_on IS NULL) OR (NOT active AND ended_on IS NOT NULL));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_merged_inactive_chk
+        CHECK (merged_into IS NULL OR (NOT active AND merged_into <> id));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_schools_merged_into ON schools (merged_into) WHERE merged_into IS NOT NULL;
@@ -0,0 +1,35 @@
+-- 0032_school_import_batches
+-- Carga do cadastro oficial de escolas estaduais (cmd/import-schools).
+-- Cada execução não dry-run grava um lote com o arquivo, o hash SHA-256,
+-- as contagens e o relatório de diferenças: escolas novas, DRE ou
+-- município alterados e escolas do cadastro ausentes no arquivo.
+-- schools.import_batch_id aponta o último lote que inseriu ou atualizou a
+-- escola.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0032_school_import_batches.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS school_import_batches (
+    id                BIGSERIAL PRIMARY KEY,
+    source_file       TEXT      NOT NULL,
+    source_hash       CHAR(64)  NOT NULL,
+    rows_total        INTEGER   NOT NULL,
+    inserted          INTEGER   NOT NULL DEFAULT 0,
+    updated           INTEGER   NOT NULL DEFAULT 0,
+    unchanged         INTEGER   NOT NULL DEFAULT 0,
+    skipped           INTEGER   NOT NULL DEFAULT 0,
+    missing           INTEGER   NOT NULL DEFAULT 0,
+    dre_changed       INTEGER   NOT NULL DEFAULT 0,
+    municipio_changed INTEGER   NOT NULL DEFAULT 0,
+    report            JSONB     NOT NULL DEFAULT '{}'::jsonb,
+    created_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    notes             TEXT
+);
+
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS import_batch_id BIGINT NULL;
+
+DO $$ BEGIN
+    ALTER TABLE schools
+        ADD CONSTRAINT schools_import_batch_fk
+        FOREIGN KEY (import_batch_id) REFERENCES school_import_batches(id) ON DELETE SET NULL;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
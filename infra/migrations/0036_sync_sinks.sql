@@ -0,0 +1,38 @@
+-- 0036_sync_sinks
+-- Destinos da sincronização do censo. Além da planilha do Google Sheets, o
+-- censo pode ser exportado para arquivos CSV/JSONL e para um webhook HTTP;
+-- a API registra em sync_sinks os destinos configurados (SYNC_SINKS) a
+-- cada startup, e a gravação do censo enfileira um item de sync_outbox por
+-- destino habilitado — cada um com suas tentativas, backoff e dead-letter.
+--
+-- kind é o destino (sheets, file, webhook) e target o lugar em que ele
+-- grava (aba, arquivo, URL). A planilha fica habilitada por padrão, como
+-- antes. sync_outbox.op guarda a operação feita pelo envio concluído
+-- (append, update ou delete): o próximo envio do mesmo censo atualiza a
+-- linha em vez de acrescentar outra, e o censo devolvido à escola sai do
+-- destino.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0036_sync_sinks.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS sync_sinks (
+    kind       TEXT      PRIMARY KEY,
+    target     TEXT      NOT NULL,
+    enabled    BOOLEAN   NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO sync_sinks (kind, target)
+VALUES ('sheets', 'Base_dados')
+ON CONFLICT (kind) DO NOTHING;
+
+ALTER TABLE sync_outbox ADD COLUMN IF NOT EXISTS op TEXT NULL;
+
+DO $$ BEGIN
+    ALTER TABLE sync_outbox
+        ADD CONSTRAINT sync_outbox_op_chk CHECK (op IN ('append', 'update', 'delete'));
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+-- Último envio concluído por censo e destino.
+CREATE INDEX IF NOT EXISTS idx_sync_outbox_done
+    ON sync_outbox (census_id, kind, done_at DESC) WHERE status = 'done';
@@ -0,0 +1,30 @@
+-- 0037_school_photos
+-- Metadados das fotos enviadas pelas escolas. Antes o upload ficava em
+-- ./tmp até a conclusão do censo, e só o primeiro arquivo da escola ia para
+-- o Google Drive; agora cada foto é gravada no armazenamento configurado
+-- (PHOTO_STORE: drive, local ou s3) no momento do upload e registrada aqui.
+--
+-- storage é o armazenamento que guardou o arquivo e path a sua localização
+-- nele (id do arquivo no Drive, caminho local ou chave do objeto); url é o
+-- link de visualização, quando o armazenamento oferece um. sha256 do
+-- conteúdo evita gravar duas vezes a mesma foto da escola no mesmo ano.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0037_school_photos.sql e infra/init.sql.
+
+CREATE TABLE IF NOT EXISTS school_photos (
+    id            BIGSERIAL PRIMARY KEY,
+    school_id     INTEGER   NOT NULL REFERENCES schools(id) ON DELETE CASCADE,
+    year          INTEGER   NOT NULL,
+    storage       TEXT      NOT NULL,
+    path          TEXT      NOT NULL,
+    url           TEXT      NULL,
+    original_name TEXT      NOT NULL,
+    content_type  TEXT      NOT NULL,
+    size_bytes    BIGINT    NOT NULL CHECK (size_bytes > 0),
+    sha256        CHAR(64)  NOT NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_school_photos_sha
+    ON school_photos (school_id, year, sha256);
@@ -0,0 +1,559 @@
+-- 0039_censo_views_fonte
+-- As views vw_censo_* passam a ler, na própria definição, vw_censo_fonte
+-- (0030) no lugar de census_responses e vw_censo_escolas no lugar de
+-- schools. Antes, a função censo_views_ler_fonte() da 0030 reescrevia as
+-- definições a cada startup, fora do checksum das migrations; ela sai
+-- daqui. Uma view de censo nova ou alterada parte destas definições, numa
+-- migration nova (TestCensusViewsReadSnapshotSource confere).
+--
+-- O congelamento passa a guardar em census_snapshots também o cadastro da
+-- escola usado nos agrupamentos (dre, municipio, zona, municipio_id,
+-- dre_id, active), que entra no hash da linha (censo_snapshot_escola e o
+-- quinto argumento de censo_snapshot_hash). vw_censo_escolas é schools com
+-- esses campos trocados pelos do lote quando censo.snapshot está definido:
+-- edição, desativação ou fusão da escola depois do congelamento não mudam
+-- os números do lote. Lotes anteriores a esta migration ficam com os
+-- campos nulos; o hash deles não muda e a view usa o cadastro vivo.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0039_censo_views_fonte.sql e infra/init.sql.
+
+DROP FUNCTION IF EXISTS censo_views_ler_fonte();
+
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS dre          VARCHAR(100) NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS municipio    VARCHAR(100) NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS zona         VARCHAR(50)  NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS municipio_id INTEGER      NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS dre_id       INTEGER      NULL;
+ALTER TABLE census_snapshots ADD COLUMN IF NOT EXISTS active       BOOLEAN      NULL;
+
+CREATE INDEX IF NOT EXISTS idx_census_snapshots_school ON census_snapshots (batch_id, school_id);
+
+-- Cadastro da escola no hash da linha; NULL (lote antigo, active nulo)
+-- mantém o hash de quatro campos, já que concat_ws ignora NULL.
+CREATE OR REPLACE FUNCTION censo_snapshot_escola(dre TEXT, municipio TEXT, zona TEXT,
+                                                 municipio_id INTEGER, dre_id INTEGER, active BOOLEAN) RETURNS JSONB
+    LANGUAGE sql IMMUTABLE
+    AS $fn$ SELECT CASE WHEN active IS NULL THEN NULL ELSE jsonb_build_object(
+        'dre', dre, 'municipio', municipio, 'zona', zona,
+        'municipio_id', municipio_id, 'dre_id', dre_id, 'active', active) END $fn$;
+
+CREATE OR REPLACE FUNCTION censo_snapshot_hash(school_id INTEGER, year INTEGER, status TEXT, data JSONB, escola JSONB) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE
+    AS $fn$ SELECT encode(sha256(convert_to(concat_ws('|', school_id, year, status, COALESCE(data, '{}'::jsonb)::text, escola::text), 'UTF8')), 'hex') $fn$;
+
+-- Os casts fixam os tipos das colunas de schools, como em vw_censo_fonte.
+CREATE OR REPLACE VIEW vw_censo_escolas AS
+SELECT
+    s.id,
+    s.nome_escola,
+    s.codigo_inep,
+    (CASE WHEN sn.school_id IS NULL THEN s.municipio ELSE sn.municipio END)::VARCHAR(100) AS municipio,
+    (CASE WHEN sn.school_id IS NULL THEN s.dre ELSE sn.dre END)::VARCHAR(100)             AS dre,
+    (CASE WHEN sn.school_id IS NULL THEN s.zona ELSE sn.zona END)::VARCHAR(50)            AS zona,
+    s.endereco,
+    s.cnpj,
+    s.telefone,
+    s.email,
+    s.cep,
+    s.nome_diretor,
+    s.matricula_diretor,
+    s.contato_diretor,
+    s.turnos,
+    s.etapas_ofertadas,
+    s.modalidades_ofertadas,
+    s.created_at,
+    CASE WHEN sn.school_id IS NULL THEN s.active ELSE sn.active END                       AS active,
+    s.ended_on,
+    s.merged_into,
+    s.updated_at,
+    s.import_batch_id,
+    CASE WHEN sn.school_id IS NULL THEN s.municipio_id ELSE sn.municipio_id END           AS municipio_id,
+    CASE WHEN sn.school_id IS NULL THEN s.dre_id ELSE sn.dre_id END                       AS dre_id
+FROM schools s
+LEFT JOIN census_snapshots sn
+       ON sn.school_id = s.id
+      AND sn.active IS NOT NULL
+      AND sn.batch_id = NULLIF(current_setting('censo.snapshot', true), '')::BIGINT;
+
+-- vw_censo_base (0001)
+CREATE OR REPLACE VIEW vw_censo_base AS
+SELECT
+    -- Identificação (schools) ------------------------------------------
+    s.id                                                AS school_id,
+    s.codigo_inep,
+    s.nome_escola,
+    s.dre,
+    s.municipio,
+    s.zona,
+
+    -- Operacional (census_responses) -----------------------------------
+    cr.id                                               AS census_id,
+    cr.year,
+    cr.status,
+    cr.created_at,
+    cr.updated_at,
+    cr.sheet_synced_at,
+
+    -- Quantitativos numéricos extraídos com cast seguro ----------------
+    CASE WHEN cr.data->>'total_alunos'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'total_alunos')::numeric       END AS total_alunos,
+    CASE WHEN cr.data->>'alunos_pcd'         ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'alunos_pcd')::numeric         END AS alunos_pcd,
+    CASE WHEN cr.data->>'alunos_rural'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'alunos_rural')::numeric       END AS alunos_rural,
+    CASE WHEN cr.data->>'alunos_urbana'      ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'alunos_urbana')::numeric      END AS alunos_urbana,
+    CASE WHEN cr.data->>'qtd_salas_aula'     ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_salas_aula')::numeric     END AS qtd_salas_aula,
+    CASE WHEN cr.data->>'salas_climatizadas' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'salas_climatizadas')::numeric END AS salas_climatizadas,
+    CASE WHEN cr.data->>'turmas_manha'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'turmas_manha')::numeric       END AS turmas_manha,
+    CASE WHEN cr.data->>'turmas_tarde'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'turmas_tarde')::numeric       END AS turmas_tarde,
+    CASE WHEN cr.data->>'turmas_noite'       ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'turmas_noite')::numeric       END AS turmas_noite,
+    CASE WHEN cr.data->>'turmas_integral'    ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'turmas_integral')::numeric    END AS turmas_integral,
+
+    -- Categóricos básicos (string vazia ⇒ NULL) ------------------------
+    NULLIF(cr.data->>'tipo_predio',           '')       AS tipo_predio,
+    NULLIF(cr.data->>'possui_anexos',         '')       AS possui_anexos,
+    NULLIF(cr.data->>'situacao_estrutura',    '')       AS situacao_estrutura,
+    NULLIF(cr.data->>'muro_cerca',            '')       AS muro_cerca,
+    NULLIF(cr.data->>'perimetro_fechado',     '')       AS perimetro_fechado,
+    NULLIF(cr.data->>'rede_eletrica_atende',  '')       AS rede_eletrica_atende,
+    NULLIF(cr.data->>'cameras_funcionamento', '')       AS cameras_funcionamento
+FROM vw_censo_escolas s
+LEFT JOIN vw_censo_fonte cr ON cr.school_id = s.id;
+
+-- vw_censo_direcao_escolar (0003)
+CREATE OR REPLACE VIEW vw_censo_direcao_escolar AS
+WITH censo_data AS (
+    SELECT
+        b.school_id,
+        b.codigo_inep,
+        b.nome_escola,
+        b.dre,
+        b.municipio,
+        b.zona,
+        b.year,
+        b.status,
+        b.census_id,
+        cr.data
+    FROM vw_censo_base b
+    JOIN vw_censo_fonte cr ON cr.id = b.census_id
+)
+SELECT
+    cd.school_id,
+    cd.codigo_inep,
+    cd.nome_escola,
+    cd.dre,
+    cd.municipio,
+    cd.zona,
+    cd.year,
+    cd.status,
+    cd.census_id,
+    v.cargo,
+    v.possui,
+    v.ordem
+FROM censo_data cd
+CROSS JOIN LATERAL (
+    VALUES
+        ('Direção Escolar',             lower(cd.data->>'possui_direcao') IN ('sim', 'true', 't', '1'), 1),
+        ('Vice-Diretor Pedagógico',     lower(cd.data->>'possui_vice_pedagogico') IN ('sim', 'true', 't', '1'), 2),
+        ('Vice-Diretor Administrativo', lower(cd.data->>'possui_vice_administrativo') IN ('sim', 'true', 't', '1'), 3),
+        ('Secretário Escolar',          lower(cd.data->>'possui_secretario') IN ('sim', 'true', 't', '1'), 4),
+        ('Coordenação Pedagógica',      lower(cd.data->>'possui_coord_pedagogico') IN ('sim', 'true', 't', '1'), 5)
+) AS v(cargo, possui, ordem);
+
+-- vw_censo_coordenacao_area (0004)
+CREATE OR REPLACE VIEW vw_censo_coordenacao_area AS
+WITH censo_data AS (
+    SELECT
+        b.school_id,
+        b.codigo_inep,
+        b.nome_escola,
+        b.dre,
+        b.municipio,
+        b.zona,
+        b.year,
+        b.status,
+        b.census_id,
+        cr.data
+    FROM vw_censo_base b
+    JOIN vw_censo_fonte cr ON cr.id = b.census_id
+)
+SELECT
+    cd.school_id,
+    cd.codigo_inep,
+    cd.nome_escola,
+    cd.dre,
+    cd.municipio,
+    cd.zona,
+    cd.year,
+    cd.status,
+    cd.census_id,
+    v.area,
+    v.possui,
+    v.ordem
+FROM censo_data cd
+CROSS JOIN LATERAL (
+    VALUES
+        ('Linguagens',         lower(cd.data->>'possui_coord_area_linguagem') IN ('sim', 'true', 't', '1'), 1),
+        ('Matemática',         lower(cd.data->>'possui_coord_area_matematica') IN ('sim', 'true', 't', '1'), 2),
+        ('Ciências Humanas',   lower(cd.data->>'possui_coord_area_humanas') IN ('sim', 'true', 't', '1'), 3),
+        ('Ciências da Natureza', lower(cd.data->>'possui_coord_area_natureza') IN ('sim', 'true', 't', '1'), 4)
+) AS v(area, possui, ordem);
+
+-- vw_censo_quadro_pessoal (0005)
+CREATE OR REPLACE VIEW vw_censo_quadro_pessoal AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.year,
+    b.status,
+    b.census_id,
+    CASE WHEN cr.data->>'qtd_professores_efetivos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_professores_efetivos')::numeric
+         ELSE 0 END AS qtd_professores_efetivos,
+    CASE WHEN cr.data->>'qtd_professores_temporarios' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_professores_temporarios')::numeric
+         ELSE 0 END AS qtd_professores_temporarios,
+    CASE WHEN cr.data->>'qtd_servidores_administrativos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_servidores_administrativos')::numeric
+         ELSE 0 END AS qtd_servidores_administrativos,
+    CASE WHEN cr.data->>'qtd_professor_readaptado' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_professor_readaptado')::numeric
+         ELSE 0 END AS qtd_professor_readaptado,
+    (CASE WHEN cr.data->>'qtd_professores_efetivos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+           THEN (cr.data->>'qtd_professores_efetivos')::numeric
+           ELSE 0 END +
+     CASE WHEN cr.data->>'qtd_professores_temporarios' ~ '^-?[0-9]+(\.[0-9]+)?$'
+           THEN (cr.data->>'qtd_professores_temporarios')::numeric
+           ELSE 0 END)::numeric AS total_professores
+FROM vw_censo_base b
+JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_equipamentos_tecnologia (0006)
+CREATE OR REPLACE VIEW vw_censo_equipamentos_tecnologia AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.year,
+    b.status,
+    b.census_id,
+
+    -- Conectividade
+    lower(cr.data->>'internet_disponivel') IN ('sim', 'true', 't', '1')
+        AS internet_disponivel,
+    NULLIF(cr.data->>'provedor_internet', '')
+        AS provedor_internet,
+    NULLIF(cr.data->>'qualidade_internet', '')
+        AS qualidade_internet,
+
+    -- Parque de computadores
+    CASE WHEN cr.data->>'qtd_desktop_adm' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_desktop_adm')::numeric END AS qtd_desktop_adm,
+    CASE WHEN cr.data->>'qtd_desktop_alunos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_desktop_alunos')::numeric END AS qtd_desktop_alunos,
+    CASE WHEN cr.data->>'qtd_notebooks' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_notebooks')::numeric END AS qtd_notebooks,
+    CASE WHEN cr.data->>'qtd_chromebooks' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_chromebooks')::numeric END AS qtd_chromebooks,
+    CASE WHEN cr.data->>'qtd_computadores_inoperantes' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_computadores_inoperantes')::numeric END AS qtd_computadores_inoperantes,
+    NULLIF(cr.data->>'computadores_atendem', '')
+        AS computadores_atendem,
+
+    -- Recursos pedagógicos
+    lower(cr.data->>'possui_projetor') IN ('sim', 'true', 't', '1')
+        AS possui_projetor,
+    CASE WHEN cr.data->>'qtd_projetores' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_projetores')::numeric END AS qtd_projetores,
+    lower(cr.data->>'possui_lousa_digital') IN ('sim', 'true', 't', '1')
+        AS possui_lousa_digital
+
+FROM vw_censo_base b
+JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_ambientes (0007)
+CREATE OR REPLACE VIEW vw_censo_ambientes AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+    amb.value AS ambiente
+FROM vw_censo_base b
+INNER JOIN vw_censo_fonte cr
+        ON cr.id = b.census_id
+       AND cr.data ? 'ambientes'
+       AND jsonb_typeof(cr.data->'ambientes') = 'array'
+CROSS JOIN LATERAL jsonb_array_elements_text(cr.data->'ambientes') AS amb(value)
+WHERE amb.value IS NOT NULL
+  AND amb.value <> '';
+
+-- vw_censo_infraestrutura_seguranca (0008)
+CREATE OR REPLACE VIEW vw_censo_infraestrutura_seguranca AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    b.tipo_predio,
+    b.situacao_estrutura,
+    b.possui_anexos,
+    CASE WHEN cr.data->>'qtd_anexos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_anexos')::numeric END              AS qtd_anexos,
+    NULLIF(cr.data->>'tipo_predio_anexo',          '')           AS tipo_predio_anexo,
+
+    b.muro_cerca,
+    b.perimetro_fechado,
+
+    NULLIF(cr.data->>'quadra_coberta',             '')           AS quadra_coberta,
+    CASE WHEN cr.data->>'qtd_quadras' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_quadras')::numeric END             AS qtd_quadras,
+    NULLIF(cr.data->>'banda_fanfarra',             '')           AS banda_fanfarra,
+
+    CASE WHEN cr.data->>'banheiros_alunos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'banheiros_alunos')::numeric END        AS banheiros_alunos,
+    CASE WHEN cr.data->>'banheiros_prof' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'banheiros_prof')::numeric END          AS banheiros_prof,
+    CASE WHEN cr.data->>'banheiros_chuveiro' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'banheiros_chuveiro')::numeric END      AS banheiros_chuveiro,
+    NULLIF(cr.data->>'banheiros_vasos_funcionais', '')           AS banheiros_vasos_funcionais,
+
+    NULLIF(cr.data->>'energia',                    '')           AS energia,
+    b.rede_eletrica_atende,
+    NULLIF(cr.data->>'estrutura_climatizacao',     '')           AS estrutura_climatizacao,
+    NULLIF(cr.data->>'suporta_novos_equipamentos', '')           AS suporta_novos_equipamentos,
+
+    b.cameras_funcionamento,
+    NULLIF(cr.data->>'cameras_cobrem',             '')           AS cameras_cobrem,
+
+    NULLIF(cr.data->>'possui_guarita',             '')           AS possui_guarita,
+    NULLIF(cr.data->>'controle_portao',            '')           AS controle_portao,
+    NULLIF(cr.data->>'iluminacao_externa',         '')           AS iluminacao_externa,
+    NULLIF(cr.data->>'possui_botao_panico',        '')           AS possui_botao_panico,
+
+    NULLIF(cr.data->>'plano_evacuacao',            '')           AS plano_evacuacao,
+    NULLIF(cr.data->>'politica_bullying',          '')           AS politica_bullying
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_equipamentos_merenda (0009)
+CREATE OR REPLACE VIEW vw_censo_equipamentos_merenda AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    NULLIF(cr.data->>'condicoes_cozinha',   '')  AS condicoes_cozinha,
+    NULLIF(cr.data->>'tamanho_cozinha',     '')  AS tamanho_cozinha,
+    NULLIF(cr.data->>'possui_refeitorio',   '')  AS possui_refeitorio,
+    NULLIF(cr.data->>'refeitorio_adequado', '')  AS refeitorio_adequado,
+    NULLIF(cr.data->>'possui_balanca',      '')  AS possui_balanca,
+    NULLIF(cr.data->>'bancadas_inox',       '')  AS bancadas_inox,
+    NULLIF(cr.data->>'sistema_exaustao',    '')  AS sistema_exaustao,
+    NULLIF(cr.data->>'despensa_exclusiva',  '')  AS despensa_exclusiva,
+    NULLIF(cr.data->>'deposito_conserva',   '')  AS deposito_conserva,
+    NULLIF(cr.data->>'estoque_epi_extintor','')  AS estoque_epi_extintor,
+    NULLIF(cr.data->>'manutencao_extintores','') AS manutencao_extintores,
+
+    CASE WHEN cr.data->>'qtd_freezers' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_freezers')::numeric END    AS qtd_freezers,
+    lower(NULLIF(cr.data->>'estado_freezers',   ''))     AS estado_freezers,
+
+    CASE WHEN cr.data->>'qtd_geladeiras' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_geladeiras')::numeric END  AS qtd_geladeiras,
+    lower(NULLIF(cr.data->>'estado_geladeiras', ''))     AS estado_geladeiras,
+
+    CASE WHEN cr.data->>'qtd_fogoes' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_fogoes')::numeric END      AS qtd_fogoes,
+    lower(NULLIF(cr.data->>'estado_fogoes',     ''))     AS estado_fogoes,
+
+    CASE WHEN cr.data->>'qtd_fornos' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_fornos')::numeric END      AS qtd_fornos,
+    lower(NULLIF(cr.data->>'estado_fornos',     ''))     AS estado_fornos,
+
+    CASE WHEN cr.data->>'qtd_bebedouros' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_bebedouros')::numeric END  AS qtd_bebedouros,
+    lower(NULLIF(cr.data->>'estado_bebedouros', ''))     AS estado_bebedouros
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_rh_merendeiras (0010)
+CREATE OR REPLACE VIEW vw_censo_rh_merendeiras AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    NULLIF(cr.data->>'oferta_regular',      '')  AS oferta_regular,
+    NULLIF(cr.data->>'qualidade_merenda',   '')  AS qualidade_merenda,
+    NULLIF(cr.data->>'atende_necessidades', '')  AS atende_necessidades,
+
+    CASE WHEN cr.data->>'qtd_merendeiras_estatutaria' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_merendeiras_estatutaria')::numeric END  AS qtd_merendeiras_estatutaria,
+    CASE WHEN cr.data->>'qtd_merendeiras_terceirizada' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_merendeiras_terceirizada')::numeric END AS qtd_merendeiras_terceirizada,
+    CASE WHEN cr.data->>'qtd_merendeiras_temporaria' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_merendeiras_temporaria')::numeric END   AS qtd_merendeiras_temporaria,
+
+    NULLIF(cr.data->>'qtd_atende_necessidade_merenda',  '')  AS qtd_atende_necessidade_merenda,
+    CASE WHEN cr.data->>'quantitativo_necessario_merenda' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'quantitativo_necessario_merenda')::numeric END AS quantitativo_necessario_merenda,
+
+    NULLIF(cr.data->>'empresa_terceirizada_merenda', '')  AS empresa_terceirizada_merenda,
+    NULLIF(cr.data->>'possui_supervisor_merenda',    '')  AS possui_supervisor_merenda
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_rh_servicos_gerais (0011)
+CREATE OR REPLACE VIEW vw_censo_rh_servicos_gerais AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    CASE WHEN cr.data->>'qtd_servicos_gerais_efetivo' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_servicos_gerais_efetivo')::numeric END      AS qtd_servicos_gerais_efetivo,
+    CASE WHEN cr.data->>'qtd_servicos_gerais_temporario' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_servicos_gerais_temporario')::numeric END   AS qtd_servicos_gerais_temporario,
+    CASE WHEN cr.data->>'qtd_servicos_gerais_terceirizado' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_servicos_gerais_terceirizado')::numeric END AS qtd_servicos_gerais_terceirizado,
+
+    NULLIF(cr.data->>'qtd_atende_necessidade_sg',  '')  AS qtd_atende_necessidade_sg,
+    CASE WHEN cr.data->>'quantitativo_necessario_sg' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'quantitativo_necessario_sg')::numeric END AS quantitativo_necessario_sg,
+
+    NULLIF(cr.data->>'empresa_terceirizada_sg', '')  AS empresa_terceirizada_sg,
+    NULLIF(cr.data->>'possui_supervisor_sg',    '')  AS possui_supervisor_sg
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_servicos_terceirizados (0012)
+CREATE OR REPLACE VIEW vw_censo_servicos_terceirizados AS
+SELECT
+    b.school_id,
+    b.codigo_inep,
+    b.nome_escola,
+    b.dre,
+    b.municipio,
+    b.zona,
+    b.census_id,
+    b.year,
+    b.status,
+
+    -- Portaria
+    CASE WHEN cr.data->>'qtd_agentes_portaria' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'qtd_agentes_portaria')::numeric END  AS qtd_agentes_portaria,
+    NULLIF(cr.data->>'qtd_atende_necessidade_portaria', '')    AS qtd_atende_necessidade_portaria,
+    CASE WHEN cr.data->>'quantitativo_necessario_portaria' ~ '^-?[0-9]+(\.[0-9]+)?$'
+         THEN (cr.data->>'quantitativo_necessario_portaria')::numeric END AS quantitativo_necessario_portaria,
+    NULLIF(cr.data->>'empresa_terceirizada_portaria', '')      AS empresa_terceirizada_portaria,
+    NULLIF(cr.data->>'possui_supervisor_portaria',    '')      AS possui_supervisor_portaria,
+
+    -- Flags de terceirização por área (presença de empresa terceirizada)
+    NULLIF(cr.data->>'empresa_terceirizada_merenda',  '')      AS empresa_terceirizada_merenda,
+    NULLIF(cr.data->>'empresa_terceirizada_sg',       '')      AS empresa_terceirizada_sg,
+
+    -- Avaliações dos serviços terceirizados
+    NULLIF(cr.data->>'avaliacao_merendeiras',  '')  AS avaliacao_merendeiras,
+    NULLIF(cr.data->>'avaliacao_portaria',     '')  AS avaliacao_portaria,
+    NULLIF(cr.data->>'avaliacao_limpeza',      '')  AS avaliacao_limpeza,
+    NULLIF(cr.data->>'avaliacao_comunicacao',  '')  AS avaliacao_comunicacao,
+    NULLIF(cr.data->>'avaliacao_supervisao',   '')  AS avaliacao_supervisao
+
+FROM vw_censo_base b
+LEFT JOIN vw_censo_fonte cr ON cr.id = b.census_id;
+
+-- vw_censo_governanca_institucional (0016, recriada na 0027)
+CREATE OR REPLACE VIEW vw_censo_governanca_institucional AS
+WITH base AS (
+    SELECT
+        cr.id                                       AS census_id,
+        s.id                                        AS school_id,
+        s.codigo_inep,
+        s.nome_escola                               AS escola,
+        s.dre,
+        s.municipio,
+        s.zona,
+        NULLIF(cr.data->>'regularizada_cee', '')    AS regularizada_cee,
+        NULLIF(cr.data->>'conselho_escolar', '')    AS conselho_escolar,
+        NULLIF(cr.data->>'conselho_ativo', '')      AS conselho_ativo
+    FROM vw_censo_fonte cr
+    JOIN vw_censo_escolas s ON s.id = cr.school_id
+    WHERE censo_enviado(cr.status)
+)
+SELECT
+    census_id,
+    school_id,
+    codigo_inep,
+    escola,
+    dre,
+    municipio,
+    zona,
+    regularizada_cee,
+    conselho_escolar,
+    conselho_ativo,
+    -- Booleanos: NULL quando o campo é "Não informado" (não vira FALSE).
+    (regularizada_cee = 'Sim')        AS is_regularizada_cee,
+    (conselho_escolar = 'Sim')        AS has_conselho_escolar,
+    (conselho_ativo = 'Sim')          AS is_conselho_ativo,
+    (conselho_ativo = 'Parcialmente') AS is_conselho_parcialmente_ativo,
+    -- Governança completa: os três quesitos = 'Sim'. NULL em qualquer campo
+    -- propaga NULL (não conta como completa nem como crítica).
+    (regularizada_cee = 'Sim'
+     AND conselho_escolar = 'Sim'
+     AND conselho_ativo = 'Sim')      AS is_governanca_completa,
+    -- Governança crítica: pelo menos um quesito = 'Não'. Como o OR com TRUE
+    -- curto-circuita, basta um 'Não' para resultar TRUE; valores NULL não
+    -- forçam crítica (Não informado não vira Não).
+    (regularizada_cee = 'Não'
+     OR conselho_escolar = 'Não'
+     OR conselho_ativo = 'Não')       AS is_governanca_critica
+FROM base;
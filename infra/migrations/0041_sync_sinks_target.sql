@@ -0,0 +1,17 @@
+-- 0041_sync_sinks_target
+-- sync_sinks passa a ter chave (kind, target). Com a chave só em kind,
+-- trocar o destino de um tipo (outra URL de webhook, outro diretório)
+-- sobrescrevia o registro, e os itens pendentes do destino anterior eram
+-- reservados e entregues no novo. Agora cada destino tem sua linha: o
+-- anterior fica desabilitado e seus itens esperam na fila.
+--
+-- Migration idempotente: pode ser reaplicada a cada startup. Espelhada em
+-- infra/migrations/0041_sync_sinks_target.sql e infra/init.sql.
+
+DO $$ BEGIN
+    IF (SELECT array_length(indkey::int2[], 1) FROM pg_index
+        WHERE indrelid = 'sync_sinks'::regclass AND indisprimary) = 1 THEN
+        ALTER TABLE sync_sinks DROP CONSTRAINT sync_sinks_pkey;
+        ALTER TABLE sync_sinks ADD CONSTRAINT sync_sinks_pkey PRIMARY KEY (kind, target);
+    END IF;
+END $$;
@@ -16,7 +16,7 @@ import "./admin.css";
 
 import { API, C } from "@/components/admin/shared/constants";
 import {
-  apiFetch, saveToken, loadToken, clearToken, clearApiCache, sanitize, prefetchDashboard,
+  apiFetch, authFetch, saveSession, logoutSession, loadToken, clearToken, clearApiCache, sanitize, prefetchDashboard,
 } from "@/components/admin/shared/api";
 import { JsonModal } from "@/components/admin/shared/JsonModal";
 import { AbaTodosCensos } from "@/components/admin/AbaTodosCensos";
@@ -33,7 +33,7 @@ import { AbaSaudeOperacionalEscolas } from "@/components/admin/AbaSaudeOperacion
 import { FiltrosGlobais } from "@/components/admin/FiltrosGlobais";
 import PresentationMode from "@/components/admin/PresentationMode";
 import type {
-  CensusPage, DashboardData, DashboardFilters, FiltrosOpcoes,
+  CensusPage, CensusSnapshot, DashboardData, DashboardFilters, FiltrosOpcoes,
 } from "@/components/admin/shared/types";
 
 // ─── Login ────────────────────────────────────────────────────────────────────
@@ -45,6 +45,10 @@ function LoginForm({ onLogin }: { onLogin: (t: string) => void }) {
   const [error, setError] = useState("");
   const [status, setStatus] = useState<"idle" | "auth" | "prefetch">("idle");
   const [attempts, setAttempts] = useState(0);
+  // Segundo fator: com TOTP ativo o login devolve um pre-auth token, trocado
+  // pela sessão em /v1/admin/login/verify junto do código do aplicativo.
+  const [mfaToken, setMfaToken] = useState("");
+  const [otp, setOtp] = useState("");
   const blocked = attempts >= 5;
   const loading = status !== "idle";
 
@@ -54,13 +58,27 @@ function LoginForm({ onLogin }: { onLogin: (t: string) => void }) {
     setError(""); setStatus("auth");
     const u = sanitize(username).slice(0, 64);
     const p = sanitize(password).slice(0, 128);
-    if (!u || !p) { setError("Preencha usuário e senha."); setStatus("idle"); return; }
+    const code = sanitize(otp).slice(0, 32);
+    if (!mfaToken && (!u || !p)) { setError("Preencha usuário e senha."); setStatus("idle"); return; }
+    if (mfaToken && !code) { setError("Informe o código do aplicativo autenticador."); setStatus("idle"); return; }
     try {
-      const res = await fetch(`${API}/v1/admin/login`, { method: "POST", headers: { "Content-Type": "application/json" }, body: JSON.stringify({ username: u, password: p }) });
+      const res = mfaToken
+        ? await fetch(`${API}/v1/admin/login/verify`, { method: "POST", headers: { "Content-Type": "application/json" }, body: JSON.stringify({ mfa_token: mfaToken, code }) })
+        : await fetch(`${API}/v1/admin/login`, { method: "POST", headers: { "Content-Type": "application/json" }, body: JSON.stringify({ username: u, password: p }) });
       const json = await res.json();
-      if (!res.ok) { setAttempts((a) => a + 1); setError(json.message ?? "Credenciais inválidas."); setStatus("idle"); return; }
-      const token = (json.data as { token: string }).token;
-      saveToken(token);
+      if (!res.ok) {
+        setAttempts((a) => a + 1);
+        setError(json.message ?? "Credenciais inválidas.");
+        // Pre-auth token expirado: volta para usuário e senha.
+        if (mfaToken && res.status === 401 && json.message !== "código inválido") { setMfaToken(""); setOtp(""); }
+        setStatus("idle"); return;
+      }
+      if (json.data?.mfa_required) {
+        setMfaToken(json.data.mfa_token); setAttempts(0); setStatus("idle"); return;
+      }
+      const session = json.data as { token: string; refresh_token: string };
+      saveSession(session);
+      const token = session.token;
       setStatus("prefetch");
       await prefetchDashboard(token);
       onLogin(token);
@@ -112,7 +130,7 @@ function LoginForm({ onLogin }: { onLogin: (t: string) => void }) {
                   <input
                     type="text" autoComplete="username" maxLength={64}
                     className="login__input login__input--icon"
-                    disabled={loading || blocked} value={username}
+                    disabled={loading || blocked || !!mfaToken} value={username}
                     onChange={(e) => setUsername(e.target.value)}
                     placeholder="admin_seduc_pa" required
                   />
@@ -133,7 +151,7 @@ function LoginForm({ onLogin }: { onLogin: (t: string) => void }) {
                     type={showPwd ? "text" : "password"}
                     autoComplete="current-password" maxLength={128}
                     className="login__input login__input--icon"
-                    disabled={loading || blocked} value={password}
+                    disabled={loading || blocked || !!mfaToken} value={password}
                     onChange={(e) => setPassword(e.target.value)}
                     placeholder="••••••••••••" required
                   />
@@ -151,6 +169,22 @@ function LoginForm({ onLogin }: { onLogin: (t: string) => void }) {
                 </div>
               </label>
 
+              {/* Código do segundo fator */}
+              {mfaToken && (
+                <label className="login__field">
+                  <span className="login__label">Código do aplicativo autenticador</span>
+                  <div className="login__input-wrap">
+                    <input
+                      type="text" inputMode="numeric" autoComplete="one-time-code" maxLength={32}
+                      className="login__input" autoFocus
+                      disabled={loading || blocked} value={otp}
+                      onChange={(e) => setOtp(e.target.value)}
+                      placeholder="000000 ou código de recuperação" required
+                    />
+                  </div>
+                </label>
+              )}
+
               {error && <p className="login__error">{error}</p>}
               {blocked && <p className="login__warning">Muitas tentativas. Aguarde alguns minutos.</p>}
 
@@ -381,10 +415,12 @@ function Dashboard({ token, onLogout }: { token: string; onLogout: () => void })
   const [mobileNavOpen, setMobileNavOpen] = useState(false);
   const [filters, setFilters] = useState<DashboardFilters>({});
   const [filtrosOpcoes, setFiltrosOpcoes] = useState<FiltrosOpcoes | null>(null);
+  const [snapshots, setSnapshots] = useState<CensusSnapshot[]>([]);
   const [presentationMode, setPresentationMode] = useState(false);
   const [showMobilePresAlert, setShowMobilePresAlert] = useState(false);
 
   const logout = useCallback(() => { clearToken(); clearApiCache(); onLogout(); }, [onLogout]);
+  const signOut = useCallback(() => { void logoutSession(token); logout(); }, [token, logout]);
 
   // O endpoint legado /v1/admin/dashboard segue sendo consultado para gatear o
   // estado de carregamento/erro do painel operacional. O payload (incl. by_dre)
@@ -436,12 +472,17 @@ function Dashboard({ token, onLogout }: { token: string; onLogout: () => void })
       .then(setFiltrosOpcoes)
       .catch((e) => { if ((e as Error).message === "UNAUTHORIZED") logout(); });
   }, [filters, token, logout]);
+  useEffect(() => {
+    apiFetch<CensusSnapshot[]>("/v1/admin/snapshots", token)
+      .then(setSnapshots)
+      .catch((e) => { if ((e as Error).message === "UNAUTHORIZED") logout(); });
+  }, [token, logout]);
 
 
   async function handleSync() {
     setSyncing(true);
     try {
-      const res = await fetch(`${API}/v1/admin/sync-sheets`, { method: "POST", headers: { Authorization: `Bearer ${token}` } });
+      const res = await authFetch("/v1/admin/sync-sheets", token, { method: "POST" });
       const json = await res.json();
       alert(json.message ?? "Sync concluído.");
       loadDb();
@@ -578,7 +619,7 @@ function Dashboard({ token, onLogout }: { token: string; onLogout: () => void })
                     : <Moon size={16} />
                 }
               </button>
-              <button className="ca-icon-btn" title="Sair" onClick={logout}>
+              <button className="ca-icon-btn" title="Sair" onClick={signOut}>
                 <LogOut size={16} />
               </button>
             </div>
@@ -597,6 +638,7 @@ function Dashboard({ token, onLogout }: { token: string; onLogout: () => void })
               <div className="ca-filters-wrap">
                 <FiltrosGlobais
                   opcoes={filtrosOpcoes}
+                  snapshots={snapshots}
                   filters={filters}
                   onFiltersChange={updateFilters}
                 />
@@ -668,6 +710,8 @@ function Dashboard({ token, onLogout }: { token: string; onLogout: () => void })
                 setCensusPageNum={setCensusPageNum}
                 onView={setViewId}
                 formatDate={fmtDate}
+                token={token}
+                onReviewed={() => loadCensus()}
               />
             )}
 
@@ -14,12 +14,16 @@ import { AlunosForm } from "@/components/forms/alunos-form";
 import { GestaoForm } from "@/components/forms/gestao-form";
 import { AvaliacaoForm } from "@/components/forms/avaliacao-form";
 import { ObservacoesForm } from "@/components/forms/observacoes-form";
+import { ReviewNotice } from "@/components/forms/review-notice";
+import { SeedNotice } from "@/components/forms/seed-notice";
+import { CampaignNotice } from "@/components/forms/campaign-notice";
 
 import { Card, CardContent, CardHeader, CardTitle, CardDescription } from "@/components/ui/card";
 import { Button } from "@/components/ui/button";
 import { ConfirmationModal } from "@/components/ui/confirmation-modal";
 import { jsPDF } from "jspdf";
 import { FileText, Loader2, Menu, X } from "lucide-react";
+import { captureSchoolAccessFromURL, publicApiHeaders } from "@/lib/school-access";
 
 const STORAGE_KEY_SCHOOL_ID = "census_current_school_id";
 const STORAGE_KEY_STEP = "census_current_step";
@@ -37,6 +41,7 @@ export default function CensusPage() {
   useEffect(() => {
     const timer = setTimeout(() => {
       if (typeof window !== "undefined") {
+        captureSchoolAccessFromURL();
         const savedId = localStorage.getItem(STORAGE_KEY_SCHOOL_ID);
         const savedStep = localStorage.getItem(STORAGE_KEY_STEP);
 
@@ -111,7 +116,7 @@ export default function CensusPage() {
       const response = await fetch(`${baseUrl}/v1/${endpoint}?${idParam}=${idValue}`, {
         headers: {
           "Content-Type": "application/json",
-          "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || ""
+          ...publicApiHeaders()
         }
       });
       if (!response.ok) return null;
@@ -426,6 +431,9 @@ export default function CensusPage() {
           </aside>
 
           <main className="space-y-6">
+            {schoolId && currentStep > 0 && <CampaignNotice schoolId={schoolId} />}
+            {schoolId && currentStep > 0 && <ReviewNotice schoolId={schoolId} />}
+            {schoolId && currentStep > 0 && <SeedNotice schoolId={schoolId} step={CENSUS_STEPS[currentStep]?.id} />}
             <Card className="shadow-sm border-slate-200">
               <CardHeader className="bg-slate-50 border-b border-slate-100 pb-6 rounded-t-md">
                 <CardTitle className="text-2xl text-slate-900">{CENSUS_STEPS[currentStep]?.title || "Finalização"}</CardTitle>
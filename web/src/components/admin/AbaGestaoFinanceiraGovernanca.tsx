@@ -148,6 +148,7 @@ function buildGovernancaInstitucionalParams(filters?: DashboardFilters): string
   if (filters?.dre) p.set("dre", filters.dre);
   if (filters?.municipio) p.set("municipio", filters.municipio);
   if (filters?.zona) p.set("zona", filters.zona);
+  if (filters?.snapshot) p.set("snapshot", String(filters.snapshot));
   const s = p.toString();
   return s ? `?${s}` : "";
 }
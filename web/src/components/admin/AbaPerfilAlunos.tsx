@@ -70,6 +70,7 @@ function buildIdebParams(filters: DashboardFilters | undefined, etapa: EtapaSel)
   if (filters?.dre) p.set("dre", filters.dre);
   if (filters?.municipio) p.set("municipio", filters.municipio);
   if (filters?.zona) p.set("zona", filters.zona);
+  if (filters?.snapshot) p.set("snapshot", String(filters.snapshot));
   // Mapeamento: filtro global de Região de Integração → regiao_integracao.
   if (filters?.regiao_integracao) p.set("regiao_integracao", filters.regiao_integracao);
   if (etapa !== "todas") p.set("etapa", etapa);
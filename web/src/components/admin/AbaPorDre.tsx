@@ -18,6 +18,7 @@ function buildEndpoint(filters?: DashboardFilters): string {
   if (filters?.dre)               params.set("dre", filters.dre);
   if (filters?.municipio)         params.set("municipio", filters.municipio);
   if (filters?.zona)              params.set("zona", filters.zona);
+  if (filters?.snapshot)          params.set("snapshot", String(filters.snapshot));
   if (filters?.regiao_integracao) params.set("regiao_integracao", filters.regiao_integracao);
   return `${ENDPOINT_BASE}?${params.toString()}`;
 }
@@ -27,6 +28,25 @@ function buildEndpoint(filters?: DashboardFilters): string {
 // pelo backend). Município, Região de Integração e Zona são recortes territoriais
 // que reduzem o conjunto de escolas antes do agrupamento por DRE — quando nenhum
 // está ativo, o recorte abrange toda a rede estadual.
+// AAAA-MM-DD → DD/MM/AAAA (datas de campanha, sem fuso).
+function formatDate(iso: string): string {
+  const [y, m, d] = iso.split("-");
+  return `${d}/${m}/${y}`;
+}
+
+function prazoLabel(dias?: number): string {
+  if (dias === undefined) return "";
+  if (dias < 0) return `encerrado há ${-dias} dia(s)`;
+  if (dias === 0) return "último dia";
+  return `${dias} dia(s) restante(s)`;
+}
+
+const SITUACAO_LABEL: Record<string, string> = {
+  agendada: "Agendada",
+  aberta: "Aberta",
+  encerrada: "Encerrada",
+};
+
 function buildRecorteBadges(filters: DashboardFilters | undefined, anoReferencia?: number): string[] {
   const badges: string[] = [];
   if (anoReferencia !== undefined) badges.push(`Ano ${anoReferencia}`);
@@ -126,6 +146,18 @@ export function AbaPorDre({
           <p className="text-[11px] text-slate-400 mt-1.5">
             Município, Região de Integração e Zona reduzem o conjunto de escolas antes do agrupamento por DRE.
           </p>
+          {payload.campanha ? (
+            <p className="text-xs text-slate-600 mt-1.5">
+              Campanha {payload.campanha.year}: {formatDate(payload.campanha.opens_on)} a {formatDate(payload.campanha.closes_on)}
+              {" — "}{SITUACAO_LABEL[payload.campanha.situacao] ?? payload.campanha.situacao}
+              {payload.campanha.situacao !== "agendada" && `, ${prazoLabel(payload.campanha.dias_restantes)}`}
+              {payload.total_overdue > 0 && (
+                <span className="ml-1 font-semibold text-rose-600">· {payload.total_overdue} escola(s) atrasada(s)</span>
+              )}
+            </p>
+          ) : (
+            <p className="text-[11px] text-slate-400 mt-1.5">Sem campanha cadastrada para {payload.ano_referencia}: prazos não se aplicam.</p>
+          )}
         </div>
         <div className="ml-auto shrink-0">
           <ReportButton
@@ -149,7 +181,7 @@ export function AbaPorDre({
         <table className="w-full text-sm">
           <thead className="bg-slate-50 border-b border-slate-200">
             <tr>
-              {["DRE", "Total", "Concluídos", "Rascunhos", "Pendentes", "% Conclusão"].map((h, i) => (
+              {["DRE", "Total", "Concluídos", "Rascunhos", "Pendentes", ...(payload.campanha ? ["Prazo", "Atrasadas"] : []), "% Conclusão"].map((h, i) => (
                 <th key={i} className={`px-5 py-3 font-semibold text-slate-600 text-xs uppercase tracking-wide ${i === 0 ? "text-left" : "text-center"}`}>{h}</th>
               ))}
             </tr>
@@ -164,6 +196,17 @@ export function AbaPorDre({
                   <td className="px-5 py-3 text-center text-emerald-700 font-semibold tabular-nums">{d.completed}</td>
                   <td className="px-5 py-3 text-center text-amber-600 tabular-nums">{d.draft}</td>
                   <td className="px-5 py-3 text-center text-slate-500 tabular-nums">{d.pending}</td>
+                  {payload.campanha && (
+                    <>
+                      <td className="px-5 py-3 text-center text-slate-600 text-xs">
+                        {d.prazo && formatDate(d.prazo)}
+                        <span className={`block ${d.dias_restantes !== undefined && d.dias_restantes < 0 ? "text-rose-600" : "text-slate-400"}`}>
+                          {prazoLabel(d.dias_restantes)}
+                        </span>
+                      </td>
+                      <td className={`px-5 py-3 text-center tabular-nums ${d.overdue > 0 ? "text-rose-600 font-semibold" : "text-slate-400"}`}>{d.overdue}</td>
+                    </>
+                  )}
                   <td className="px-5 py-3">
                     <div className="flex items-center gap-3">
                       <div className="flex-1 h-2.5 bg-slate-100 rounded-full overflow-hidden">
@@ -161,6 +161,7 @@ function buildEndpoint(
   if (filters?.dre)               params.set("dre", filters.dre);
   if (filters?.municipio)         params.set("municipio", filters.municipio);
   if (filters?.zona)              params.set("zona", filters.zona);
+  if (filters?.snapshot)          params.set("snapshot", String(filters.snapshot));
   if (filters?.regiao_integracao) params.set("regiao_integracao", filters.regiao_integracao);
   if (localStatus !== "todos")      params.set("status", localStatus);
   if (localCriticidade !== "todas") params.set("criticidade_faixa", localCriticidade);
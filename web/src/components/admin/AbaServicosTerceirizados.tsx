@@ -27,6 +27,7 @@ function buildFilterParams(filters?: DashboardFilters): string {
   if (filters.dre) p.set("dre", filters.dre);
   if (filters.municipio) p.set("municipio", filters.municipio);
   if (filters.zona) p.set("zona", filters.zona);
+  if (filters.snapshot) p.set("snapshot", String(filters.snapshot));
   const s = p.toString();
   return s ? `?${s}` : "";
 }
@@ -168,6 +169,7 @@ export function AbaServicosTerceirizados({
     if (filters?.dre)               p.set("dre",               filters.dre);
     if (filters?.municipio)         p.set("municipio",         filters.municipio);
     if (filters?.zona)              p.set("zona",              filters.zona);
+    if (filters?.snapshot)          p.set("snapshot",          String(filters.snapshot));
     if (esSearch.trim())            p.set("q",                 esSearch.trim());
     p.set("page",      String(esPage));
     p.set("page_size", String(esPageSize));
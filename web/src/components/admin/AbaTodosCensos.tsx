@@ -1,12 +1,12 @@
-import React from "react";
+import React, { useEffect, useState } from "react";
 import {
   Filter, Search, Loader2, ChevronLeft, ChevronRight,
   Building2, CheckCircle2, FileText, CloudUpload,
 } from "lucide-react";
 import { CensusTable } from "./shared/CensusTable";
 import { StatCard } from "./shared/StatCard";
-import { sanitize } from "./shared/api";
-import type { CensusPage } from "./shared/types";
+import { authFetch, clearApiCache, sanitize } from "./shared/api";
+import type { CensusPage, CensusReviewAction, CensusReviewResult } from "./shared/types";
 
 const PAGE_SIZE_OPTIONS = [10, 50, 100, 1000];
 
@@ -22,6 +22,8 @@ export function AbaTodosCensos({
   setCensusPageNum,
   onView,
   formatDate,
+  token,
+  onReviewed,
 }: {
   censusPage: CensusPage | null;
   filterStatus: string;
@@ -34,6 +36,8 @@ export function AbaTodosCensos({
   setCensusPageNum: (p: number) => void;
   onView: (id: number) => void;
   formatDate: (s: string) => string;
+  token: string;
+  onReviewed: () => void;
 }) {
   const total      = censusPage?.total ?? 0;
   const totalPages = Math.max(1, Math.ceil(total / censusLimit));
@@ -41,6 +45,43 @@ export function AbaTodosCensos({
   const lastRow    = Math.min(censusPageNum * censusLimit, total);
   const summary    = censusPage?.summary;
 
+  // Revisão em lote: seleção vale para a página atual.
+  const [selected, setSelected] = useState<Set<number>>(new Set());
+  const [reviewing, setReviewing] = useState(false);
+  const [reviewMsg, setReviewMsg] = useState("");
+  useEffect(() => { setSelected(new Set()); }, [censusPage]);
+
+  function toggle(id: number) {
+    setSelected((prev) => {
+      const next = new Set(prev);
+      if (next.has(id)) next.delete(id); else next.add(id);
+      return next;
+    });
+  }
+
+  async function review(action: CensusReviewAction) {
+    let comment = "";
+    if (action === "devolver") {
+      comment = window.prompt("Motivo da devolução (visível para a escola):")?.trim() ?? "";
+      if (!comment) return;
+    }
+    setReviewing(true);
+    setReviewMsg("");
+    try {
+      const res = await authFetch("/v1/admin/census/review", token, {
+        method: "POST",
+        body: JSON.stringify({ ids: Array.from(selected), action, comment }),
+      });
+      const json = await res.json().catch(() => ({}));
+      const failed = ((json.data ?? []) as CensusReviewResult[]).filter((r) => r.error);
+      setReviewMsg([json.message, ...failed.map((r) => `#${r.id}: ${r.error}`)].filter(Boolean).join(" · "));
+      clearApiCache();
+      onReviewed();
+    } finally {
+      setReviewing(false);
+    }
+  }
+
   return (
     <div className="space-y-4">
       {/* Cards de resumo — refletem o recorte dos filtros globais (summary),
@@ -63,6 +104,9 @@ export function AbaTodosCensos({
           className="border border-slate-300 bg-white rounded-lg px-3 py-1.5 text-sm focus:outline-none focus:ring-2 focus:ring-blue-400">
           <option value="">Todos os status</option>
           <option value="completed">Concluído</option>
+          <option value="em_revisao">Em revisão</option>
+          <option value="devolvido">Devolvido</option>
+          <option value="aprovado">Aprovado</option>
           <option value="draft">Rascunho</option>
         </select>
 
@@ -86,12 +130,32 @@ export function AbaTodosCensos({
         </div>
       </div>
 
+      {/* Revisão em lote */}
+      {(selected.size > 0 || reviewMsg) && (
+        <div className="bg-white rounded-2xl border border-blue-200 p-3 shadow-sm flex flex-wrap items-center gap-2 text-sm">
+          {selected.size > 0 && (
+            <>
+              <span className="font-medium text-slate-700">{selected.size} selecionado(s):</span>
+              <button disabled={reviewing} onClick={() => review("iniciar")}
+                className="px-3 py-1.5 rounded-lg text-xs font-medium bg-blue-50 text-blue-700 border border-blue-200 hover:bg-blue-100 disabled:opacity-40">Iniciar revisão</button>
+              <button disabled={reviewing} onClick={() => review("aprovar")}
+                className="px-3 py-1.5 rounded-lg text-xs font-medium bg-teal-50 text-teal-700 border border-teal-200 hover:bg-teal-100 disabled:opacity-40">Aprovar</button>
+              <button disabled={reviewing} onClick={() => review("devolver")}
+                className="px-3 py-1.5 rounded-lg text-xs font-medium bg-rose-50 text-rose-700 border border-rose-200 hover:bg-rose-100 disabled:opacity-40">Devolver</button>
+              {reviewing && <Loader2 size={14} className="animate-spin text-slate-400" />}
+            </>
+          )}
+          {reviewMsg && <span className="text-xs text-slate-500 ml-auto">{reviewMsg}</span>}
+        </div>
+      )}
+
       {/* Tabela */}
       {censusPage === null
         ? <div className="bg-white rounded-2xl py-14 text-center text-slate-400 text-sm border border-slate-200">
             <Loader2 className="animate-spin mx-auto mb-2" size={18} />Carregando…
           </div>
-        : <CensusTable rows={censusPage.rows} onView={onView} formatDate={formatDate} />}
+        : <CensusTable rows={censusPage.rows} onView={onView} formatDate={formatDate}
+            selected={selected} onToggle={toggle} />}
 
       {/* Rodapé de paginação */}
       {censusPage !== null && (
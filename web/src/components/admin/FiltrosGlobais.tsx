@@ -3,7 +3,7 @@
 import React, { useMemo } from "react";
 import { Filter, X } from "lucide-react";
 import { C } from "./shared/constants";
-import type { DashboardFilters, FiltrosOpcoes } from "./shared/types";
+import type { CensusSnapshot, DashboardFilters, FiltrosOpcoes } from "./shared/types";
 
 const EMPTY: DashboardFilters = {};
 
@@ -47,10 +47,12 @@ function FilterSelect({
 
 export function FiltrosGlobais({
   opcoes,
+  snapshots = [],
   filters,
   onFiltersChange,
 }: {
   opcoes: FiltrosOpcoes | null;
+  snapshots?: CensusSnapshot[];
   filters: DashboardFilters;
   onFiltersChange: (f: DashboardFilters) => void;
 }) {
@@ -63,14 +65,24 @@ export function FiltrosGlobais({
     const next = { ...filters };
     if (raw === "") {
       delete next[key];
-    } else if (key === "ano") {
-      next.ano = Number(raw);
+    } else if (key === "ano" || key === "snapshot") {
+      next[key] = Number(raw);
     } else {
       (next as Record<string, string>)[key] = raw;
     }
     onFiltersChange(next);
   }
 
+  function toggleAprovados(on: boolean) {
+    const next = { ...filters };
+    if (on) next.somente_aprovados = true;
+    else delete next.somente_aprovados;
+    onFiltersChange(next);
+  }
+
+  // Com a fotografia oficial, a API usa o ano do lote no lugar do filtro de ano.
+  const snapshotAtivo = snapshots.find((s) => s.id === filters.snapshot);
+
   function clear() {
     onFiltersChange(EMPTY);
   }
@@ -134,6 +146,38 @@ export function FiltrosGlobais({
           options={opcoes?.zonas ?? []}
           onChange={(v) => set("zona", v)}
         />
+        <div className="flex flex-col gap-0.5">
+          <label className="text-[10px] font-semibold uppercase tracking-wide text-slate-400">
+            Fotografia oficial
+          </label>
+          <select
+            value={filters.snapshot ?? ""}
+            onChange={(e) => set("snapshot", e.target.value)}
+            title="Lê os censos congelados no encerramento da campanha em vez dos dados vivos"
+            className={`rounded-lg border py-1.5 pl-2.5 pr-7 text-xs focus:outline-none focus:ring-2 focus:ring-blue-400 ${
+              filters.snapshot
+                ? "border-blue-300 bg-blue-50 font-semibold text-blue-800"
+                : "border-slate-200 bg-white text-slate-700"
+            }`}
+            style={{ minWidth: 140 }}
+          >
+            <option value="">Dados vivos</option>
+            {snapshots.map((s) => (
+              <option key={s.id} value={s.id}>
+                {snapshotLabel(s)}
+              </option>
+            ))}
+          </select>
+        </div>
+        <label className="flex items-center gap-1.5 pb-1.5 text-xs font-medium text-slate-600" title="Conta apenas censos aprovados na revisão da DRE">
+          <input
+            type="checkbox"
+            checked={!!filters.somente_aprovados}
+            onChange={(e) => toggleAprovados(e.target.checked)}
+            className="h-3.5 w-3.5 rounded border-slate-300"
+          />
+          Somente aprovados
+        </label>
       </div>
 
       {/* Tags dos filtros ativos */}
@@ -154,12 +198,25 @@ export function FiltrosGlobais({
           {filters.zona && (
             <ActiveTag label={`Zona: ${filters.zona}`} onRemove={() => set("zona", "")} />
           )}
+          {filters.snapshot && (
+            <ActiveTag
+              label={`Fotografia: ${snapshotAtivo ? snapshotLabel(snapshotAtivo) : `#${filters.snapshot}`}`}
+              onRemove={() => set("snapshot", "")}
+            />
+          )}
+          {filters.somente_aprovados && (
+            <ActiveTag label="Somente aprovados" onRemove={() => toggleAprovados(false)} />
+          )}
         </div>
       )}
     </div>
   );
 }
 
+function snapshotLabel(s: CensusSnapshot): string {
+  return `${s.label || `Censo ${s.year}`} · #${s.id}`;
+}
+
 function ActiveTag({ label, onRemove }: { label: string; onRemove: () => void }) {
   return (
     <span className="inline-flex items-center gap-1 rounded-full border border-blue-200 bg-blue-50 px-2.5 py-0.5 text-[11px] font-medium text-blue-700">
@@ -4,14 +4,22 @@ import { C } from "./constants";
 import { StatusPill } from "./StatusPill";
 import type { CensusRow } from "./types";
 
+// Status que já seguem para a planilha (censo enviado).
+const SENT = new Set(["completed", "em_revisao", "aprovado"]);
+
 export function CensusTable({
   rows,
   onView,
   formatDate,
+  selected,
+  onToggle,
 }: {
   rows: CensusRow[] | null;
   onView: (id: number) => void;
   formatDate: (s: string) => string;
+  // Seleção para a revisão em lote; sem onToggle a coluna não aparece.
+  selected?: Set<number>;
+  onToggle?: (id: number) => void;
 }) {
   const safeRows = rows ?? [];
   if (!safeRows.length) return (
@@ -25,6 +33,7 @@ export function CensusTable({
         <table className="min-w-[880px]">
           <thead>
             <tr>
+              {onToggle && <th className="w-8" />}
               {["Escola","INEP","Município","DRE","Ano","Status","Planilha","Atualizado",""].map((h, i) => (
                 <th key={i} className={i >= 4 ? "text-center" : "text-left"}>{h}</th>
               ))}
@@ -33,6 +42,12 @@ export function CensusTable({
           <tbody>
             {safeRows.map((r) => (
               <tr key={r.census_id}>
+                {onToggle && (
+                  <td className="text-center">
+                    <input type="checkbox" aria-label={`Selecionar ${r.nome_escola}`}
+                      checked={selected?.has(r.census_id) ?? false} onChange={() => onToggle(r.census_id)} />
+                  </td>
+                )}
                 <td className="font-medium max-w-[200px] truncate" title={r.nome_escola}>{r.nome_escola}</td>
                 <td className="font-mono text-xs opacity-70">{r.codigo_inep}</td>
                 <td>{r.municipio}</td>
@@ -42,7 +57,7 @@ export function CensusTable({
                 <td className="text-center">
                   {r.synced
                     ? <span className="inline-flex w-7 h-7 rounded-full bg-emerald-500/10 items-center justify-center text-emerald-500"><CheckCircle2 size={15}/></span>
-                    : r.status === "completed"
+                    : SENT.has(r.status)
                     ? <span className="inline-flex w-7 h-7 rounded-full bg-amber-500/10 items-center justify-center text-amber-500"><Clock size={15}/></span>
                     : <span className="opacity-30">—</span>}
                 </td>
@@ -1,10 +1,10 @@
 "use client";
 
 import React, { useState, useEffect, useMemo } from "react";
-import { Database, X, Loader2, AlertCircle, Copy, Download } from "lucide-react";
+import { Database, X, Loader2, AlertCircle, Copy, Download, History } from "lucide-react";
 import { apiFetch } from "./api";
 import { C } from "./constants";
-import type { CensusFull } from "./types";
+import type { CensusFull, CensusRevision, CensusRevisionDiff } from "./types";
 
 function highlight(json: string): React.ReactNode[] {
   return json.split(/("(?:\\.|[^"\\])*"(?:\s*:)?|true|false|null|-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?)/g).map((tok, i) => {
@@ -17,10 +17,80 @@ function highlight(json: string): React.ReactNode[] {
   });
 }
 
+const ACTOR_LABEL: Record<CensusRevision["actor_kind"], string> = {
+  escola: "Escola (código de acesso)",
+  formulario: "Formulário",
+  admin: "Painel",
+};
+
+const showValue = (v: unknown) => (v === null || v === undefined ? "—" : typeof v === "string" ? v : JSON.stringify(v));
+
+// Histórico de escritas do censo com a diferença campo a campo de cada uma.
+function RevisionsPanel({ censusId, token }: { censusId: number; token: string }) {
+  const [revs, setRevs] = useState<CensusRevision[] | null>(null);
+  const [sel, setSel]   = useState<number | null>(null);
+  const [diff, setDiff] = useState<CensusRevisionDiff | null>(null);
+  const [err, setErr]   = useState("");
+
+  useEffect(() => {
+    apiFetch<CensusRevision[]>(`/v1/admin/census/${censusId}/revisions`, token)
+      .then((r) => { setRevs(r); if (r.length) setSel(r[r.length - 1].revision); })
+      .catch((e) => setErr((e as Error).message));
+  }, [censusId, token]);
+
+  useEffect(() => {
+    if (sel === null) return;
+    setDiff(null);
+    apiFetch<CensusRevisionDiff>(`/v1/admin/census/${censusId}/revisions/diff?to=${sel}`, token)
+      .then(setDiff).catch((e) => setErr((e as Error).message));
+  }, [censusId, token, sel]);
+
+  if (err)   return <div className="flex items-center justify-center py-20 text-rose-500"><AlertCircle size={18} className="mr-2" />{err}</div>;
+  if (!revs) return <div className="flex items-center justify-center py-20 text-slate-400"><Loader2 className="animate-spin mr-2" size={22} /> Carregando…</div>;
+  if (!revs.length) return <div className="py-20 text-center text-sm text-slate-500">Nenhuma revisão registrada para este censo.</div>;
+
+  return (
+    <div className="grid grid-cols-1 md:grid-cols-[260px_1fr] min-h-full bg-white">
+      <ul className="border-r divide-y text-sm">
+        {[...revs].reverse().map((r) => (
+          <li key={r.revision}>
+            <button onClick={() => setSel(r.revision)} className={`w-full text-left px-4 py-3 hover:bg-slate-50 ${sel === r.revision ? "bg-slate-100" : ""}`}>
+              <div className="font-medium text-slate-800">#{r.revision} · {new Date(r.created_at).toLocaleString("pt-BR")}</div>
+              <div className="text-xs text-slate-500">
+                {r.previous_status && r.previous_status !== r.status ? `${r.previous_status} → ${r.status}` : r.status} · {r.changed_fields.length} campo(s)
+              </div>
+              <div className="text-xs text-slate-400">{ACTOR_LABEL[r.actor_kind] ?? r.actor_kind}{r.actor ? ` · ${r.actor}` : ""}{r.client_ip ? ` · ${r.client_ip}` : ""}</div>
+            </button>
+          </li>
+        ))}
+      </ul>
+      <div className="p-4 overflow-auto">
+        {!diff && <div className="flex items-center justify-center py-20 text-slate-400"><Loader2 className="animate-spin mr-2" size={22} /></div>}
+        {diff && diff.changes.length === 0 && <p className="text-sm text-slate-500">Nenhum campo alterado nesta revisão.</p>}
+        {diff && diff.changes.length > 0 && (
+          <table className="w-full text-xs">
+            <thead><tr className="text-left text-slate-500 border-b"><th className="py-2 pr-3">Campo</th><th className="py-2 pr-3">Antes</th><th className="py-2">Depois</th></tr></thead>
+            <tbody>
+              {diff.changes.map((c) => (
+                <tr key={c.field} className="border-b align-top">
+                  <td className="py-2 pr-3 font-mono text-slate-700">{c.field}</td>
+                  <td className={`py-2 pr-3 ${c.change === "adicionado" ? "text-slate-400" : "text-rose-700"}`}>{showValue(c.before)}</td>
+                  <td className={`py-2 ${c.change === "removido" ? "text-slate-400" : "text-emerald-700"}`}>{showValue(c.after)}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+    </div>
+  );
+}
+
 export function JsonModal({ censusId, token, onClose }: { censusId: number; token: string; onClose: () => void }) {
   const [data, setData]     = useState<CensusFull | null>(null);
   const [err, setErr]       = useState("");
   const [copied, setCopied] = useState(false);
+  const [tab, setTab]       = useState<"json" | "history">("json");
 
   useEffect(() => {
     apiFetch<CensusFull>(`/v1/admin/census/${censusId}`, token).then(setData).catch((e) => setErr((e as Error).message));
@@ -58,15 +128,24 @@ export function JsonModal({ censusId, token, onClose }: { censusId: number; toke
               {data && <p className="text-xs text-slate-600">{data.nome_escola} · INEP {data.codigo_inep} · {data.year}</p>}
             </div>
           </div>
-          <button onClick={onClose} className="w-9 h-9 rounded-lg hover:bg-black/10 flex items-center justify-center text-slate-700">
-            <X size={20} />
-          </button>
+          <div className="flex items-center gap-2">
+            <button onClick={() => setTab(tab === "json" ? "history" : "json")} className="inline-flex items-center gap-1.5 px-3 py-1.5 rounded-lg text-sm bg-white border border-slate-200 hover:bg-slate-100 text-slate-700">
+              {tab === "json" ? <><History size={13} /> Histórico</> : <><Database size={13} /> JSON</>}
+            </button>
+            <button onClick={onClose} className="w-9 h-9 rounded-lg hover:bg-black/10 flex items-center justify-center text-slate-700">
+              <X size={20} />
+            </button>
+          </div>
         </div>
+        {tab === "history" ? (
+          <div className="flex-1 overflow-auto"><RevisionsPanel censusId={censusId} token={token} /></div>
+        ) : (
         <div className="flex-1 overflow-auto bg-slate-900">
           {!data && !err && <div className="flex items-center justify-center py-20 text-slate-400"><Loader2 className="animate-spin mr-2" size={22} /> Carregando…</div>}
           {err   && <div className="flex items-center justify-center py-20 text-rose-400"><AlertCircle size={18} className="mr-2" />{err}</div>}
           {data  && <pre className="text-xs font-mono leading-relaxed p-5 whitespace-pre-wrap break-words">{highlight(fmt)}</pre>}
         </div>
+        )}
         <div className="px-6 py-3 border-t bg-slate-50 flex items-center justify-between">
           <span className="text-xs text-slate-400">{data ? `${fmt.length.toLocaleString("pt-BR")} chars` : ""}</span>
           <div className="flex gap-2">
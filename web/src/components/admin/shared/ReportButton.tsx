@@ -8,7 +8,8 @@
 
 import React, { useState } from "react";
 import { Download, Loader2 } from "lucide-react";
-import { API, DASHBOARD_REFERENCE_YEAR } from "./constants";
+import { DASHBOARD_REFERENCE_YEAR } from "./constants";
+import { authFetch } from "./api";
 import type { DashboardFilters } from "./types";
 
 type ReportButtonProps = {
@@ -31,6 +32,7 @@ function buildReportQuery(filters?: DashboardFilters): string {
   if (filters?.dre)               p.set("dre", filters.dre);
   if (filters?.municipio)         p.set("municipio", filters.municipio);
   if (filters?.zona)              p.set("zona", filters.zona);
+  if (filters?.snapshot)          p.set("snapshot", String(filters.snapshot));
   if (filters?.regiao_integracao) p.set("regiao_integracao", filters.regiao_integracao);
 
   return p.toString();
@@ -75,9 +77,7 @@ export function ReportButton({
 
     try {
       const qs = buildReportQuery(filters);
-      const res = await fetch(`${API}/v1/admin/reports/${reportId}?${qs}`, {
-        headers: { Authorization: `Bearer ${token}` },
-      });
+      const res = await authFetch(`/v1/admin/reports/${reportId}?${qs}`, token);
 
       if (res.status === 401) {
         onUnauth();
@@ -1,14 +1,21 @@
 import React from "react";
-import { CheckCircle2, FileText } from "lucide-react";
+import { CheckCircle2, FileText, Search, Undo2, BadgeCheck } from "lucide-react";
+
+// Estados de census_responses.status: completed = enviado pela escola; os
+// demais vêm da revisão da DRE.
+const PILLS: Record<string, { label: string; cls: string; Icon: typeof FileText; pulse?: boolean }> = {
+  completed:  { label: "Concluído",  cls: "bg-emerald-50 text-emerald-700 border-emerald-200 hover:bg-emerald-100", Icon: CheckCircle2, pulse: true },
+  em_revisao: { label: "Em revisão", cls: "bg-blue-50 text-blue-700 border-blue-200 hover:bg-blue-100",             Icon: Search },
+  devolvido:  { label: "Devolvido",  cls: "bg-rose-50 text-rose-700 border-rose-200 hover:bg-rose-100",             Icon: Undo2 },
+  aprovado:   { label: "Aprovado",   cls: "bg-teal-50 text-teal-700 border-teal-200 hover:bg-teal-100",             Icon: BadgeCheck },
+  draft:      { label: "Rascunho",   cls: "bg-amber-50 text-amber-700 border-amber-200 hover:bg-amber-100",         Icon: FileText },
+};
 
 export function StatusPill({ status }: { status: string }) {
-  return status === "completed" ? (
-    <span className="inline-flex items-center gap-1 px-2 py-0.5 rounded-full text-xs font-semibold bg-emerald-50 text-emerald-700 border border-emerald-200 cursor-default hover:bg-emerald-100 transition-colors">
-      <CheckCircle2 size={11} strokeWidth={2.5} className="animate-pulse" /> Concluído
-    </span>
-  ) : (
-    <span className="inline-flex items-center gap-1 px-2 py-0.5 rounded-full text-xs font-semibold bg-amber-50 text-amber-700 border border-amber-200 cursor-default hover:bg-amber-100 transition-colors">
-      <FileText size={11} strokeWidth={2.5} /> Rascunho
+  const p = PILLS[status] ?? PILLS.draft;
+  return (
+    <span className={`inline-flex items-center gap-1 px-2 py-0.5 rounded-full text-xs font-semibold border cursor-default transition-colors ${p.cls}`}>
+      <p.Icon size={11} strokeWidth={2.5} className={p.pulse ? "animate-pulse" : undefined} /> {p.label}
     </span>
   );
 }
@@ -2,13 +2,71 @@
 // Extraídos de web/src/app/admin/page.tsx no PR de refactor estrutural —
 // nenhum comportamento alterado.
 
-import { API, TOKEN_KEY } from "./constants";
+import { API, TOKEN_KEY, REFRESH_KEY } from "./constants";
 
 export const saveToken  = (t: string) => { try { sessionStorage.setItem(TOKEN_KEY, t); } catch {} };
 export const loadToken  = (): string | null => { try { return sessionStorage.getItem(TOKEN_KEY); } catch { return null; } };
-export const clearToken = () => { try { sessionStorage.removeItem(TOKEN_KEY); } catch {} };
+export const clearToken = () => { try { sessionStorage.removeItem(TOKEN_KEY); sessionStorage.removeItem(REFRESH_KEY); } catch {} };
 export const sanitize   = (s: string) => s.replace(/[\x00-\x1F\x7F]/g, "");
 
+const saveRefreshToken = (t: string) => { try { sessionStorage.setItem(REFRESH_KEY, t); } catch {} };
+const loadRefreshToken = (): string | null => { try { return sessionStorage.getItem(REFRESH_KEY); } catch { return null; } };
+
+// Guarda o par de tokens devolvido por /v1/admin/login e /v1/admin/refresh.
+export function saveSession(data: { token: string; refresh_token?: string }) {
+  saveToken(data.token);
+  if (data.refresh_token) saveRefreshToken(data.refresh_token);
+}
+
+// O access token expira em minutos; a sessão é mantida trocando o refresh
+// token (rotativo) por um novo par. Requisições paralelas que recebem 401
+// compartilham a mesma renovação — reapresentar um refresh token já trocado
+// encerraria a sessão no servidor.
+let refreshing: Promise<string | null> | null = null;
+
+export function refreshSession(): Promise<string | null> {
+  if (refreshing) return refreshing;
+  refreshing = (async () => {
+    const refresh = loadRefreshToken();
+    if (!refresh) return null;
+    try {
+      const res = await fetch(`${API}/v1/admin/refresh`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ refresh_token: refresh }),
+      });
+      if (!res.ok) return null;
+      const data = (await res.json()).data as { token: string; refresh_token: string };
+      saveSession(data);
+      return data.token;
+    } catch {
+      return null;
+    }
+  })().finally(() => { refreshing = null; });
+  return refreshing;
+}
+
+// fetch autenticado: usa o token mais recente do storage (o recebido por
+// props pode ter sido renovado) e, num 401, renova a sessão e repete uma vez.
+export async function authFetch(path: string, token: string, opts?: RequestInit): Promise<Response> {
+  const send = (t: string) => fetch(`${API}${path}`, {
+    ...opts,
+    headers: { "Content-Type": "application/json", Authorization: `Bearer ${t}`, ...(opts?.headers ?? {}) },
+  });
+  const res = await send(loadToken() ?? token);
+  if (res.status !== 401) return res;
+  const renewed = await refreshSession();
+  return renewed ? send(renewed) : res;
+}
+
+// Encerra a sessão no servidor (revoga o token atual). Falhas são ignoradas:
+// o token local é descartado de qualquer forma.
+export async function logoutSession(token: string): Promise<void> {
+  try {
+    await fetch(`${API}/v1/admin/logout`, { method: "POST", headers: { Authorization: `Bearer ${loadToken() ?? token}` } });
+  } catch {}
+}
+
 // Cache em memória para requisições GET — evita re-fetch ao trocar de aba.
 interface CacheEntry { data: unknown; expiresAt: number }
 const apiCache = new Map<string, CacheEntry>();
@@ -38,10 +96,7 @@ export async function apiFetch<T>(path: string, token: string, opts?: RequestIni
     if (cached && cached.expiresAt > Date.now()) return cached.data as T;
   }
 
-  const res = await fetch(`${API}${path}`, {
-    ...opts,
-    headers: { "Content-Type": "application/json", Authorization: `Bearer ${token}`, ...(opts?.headers ?? {}) },
-  });
+  const res = await authFetch(path, token, opts);
   if (res.status === 401) throw new Error("UNAUTHORIZED");
   if (!res.ok) {
     const b = await res.json().catch(() => ({}));
@@ -101,13 +156,15 @@ export function buildFilterParams(filters?: DashboardFilters): string {
   if (filters.dre) p.set("dre", filters.dre);
   if (filters.municipio) p.set("municipio", filters.municipio);
   if (filters.zona) p.set("zona", filters.zona);
+  if (filters.snapshot) p.set("snapshot", String(filters.snapshot));
+  if (filters.somente_aprovados) p.set("somente_aprovados", "true");
   const s = p.toString();
   return s ? `?${s}` : "";
 }
 
 export function buildPostgresSourceLabel(filters?: DashboardFilters): string {
-  const base = "PostgreSQL · ano corrente · censos concluídos";
-  if (!filters) return base;
+  if (!filters) return "PostgreSQL · ano corrente · censos concluídos";
+  const base = `PostgreSQL · ano corrente · ${filters.somente_aprovados ? "censos aprovados" : "censos concluídos"}`;
 
   const parts: string[] = [];
   if (filters.regiao_integracao) parts.push(filters.regiao_integracao);
@@ -4,6 +4,7 @@
 
 export const API = process.env.NEXT_PUBLIC_API_URL ?? "http://localhost:8000";
 export const TOKEN_KEY = "censo_admin_token";
+export const REFRESH_KEY = "censo_admin_refresh";
 
 // Temporário: o dashboard atual está fixado no ciclo do Censo Escolar 2026.
 // Usado como fallback de `year` quando nenhum ano é escolhido nos filtros globais.
@@ -101,6 +101,44 @@ export interface CaracterizacaoInfraEducacionalPg {
 
 export interface CensusFull extends CensusRow { data: unknown; created_at: string; }
 
+// Histórico de escritas do censo (GET /v1/admin/census/{id}/revisions).
+export interface CensusRevision {
+  revision: number;
+  previous_status: string | null;
+  status: string;
+  actor_kind: "escola" | "formulario" | "admin";
+  actor?: string;
+  client_ip?: string;
+  created_at: string;
+  changed_fields: string[];
+}
+
+// Diferença campo a campo entre duas revisões (.../revisions/diff).
+export interface CensusFieldChange {
+  field: string;
+  step?: string;
+  change: "adicionado" | "removido" | "alterado";
+  before: unknown;
+  after: unknown;
+}
+
+export interface CensusRevisionDiff {
+  census_id: number;
+  from: number;
+  to: number;
+  changes: CensusFieldChange[];
+}
+
+// Revisão do censo pela DRE (POST /v1/admin/census/review).
+export type CensusReviewAction = "iniciar" | "devolver" | "aprovar";
+
+export interface CensusReviewResult {
+  id: number;
+  status?: string;
+  version?: number;
+  error?: string;
+}
+
 // Resumo do recorte global da tela "Registros do Censo". Respeita os filtros
 // globais (year, dre, municipio, zona, regiao_integracao), mas não os filtros
 // locais da listagem (status, search, page, limit).
@@ -477,6 +515,24 @@ export interface PreenchimentoDreRow {
   draft: number;
   pending: number;
   completion_percentage: number;
+  // Campanha do ano (com a prorrogação da DRE): último dia, dias até ele
+  // (negativo depois do encerramento) e escolas sem censo enviado após o prazo.
+  prazo?: string;
+  dias_restantes?: number;
+  overdue: number;
+}
+
+export type CensusCampaignSituacao = "nao_configurada" | "agendada" | "aberta" | "encerrada";
+
+export interface CensusCampaign {
+  year: number;
+  opens_on: string;
+  closes_on: string;
+  form_version: string;
+  extensions: { dre: string; closes_on: string; reason: string }[];
+  situacao: CensusCampaignSituacao;
+  prazo?: string;
+  dias_restantes?: number;
 }
 
 export interface PreenchimentoDrePayload {
@@ -485,6 +541,8 @@ export interface PreenchimentoDrePayload {
   total_completed: number;
   total_draft: number;
   total_pending: number;
+  total_overdue: number;
+  campanha: CensusCampaign | null;
   dres: PreenchimentoDreRow[];
 }
 
@@ -496,6 +554,10 @@ export interface DashboardFilters {
   dre?: string;
   municipio?: string;
   zona?: string;
+  // Conta só censos aprovados na revisão da DRE (?somente_aprovados=true).
+  somente_aprovados?: boolean;
+  // Lê a fotografia oficial congelada (?snapshot=<id>) em vez do censo vivo.
+  snapshot?: number;
 }
 
 // Filtros globais do dashboard.
@@ -703,3 +765,15 @@ export interface CaracterizacaoEscolaRow extends EscolasBaseRow {
   etapas_texto: string;
   modalidades_texto: string;
 }
+
+// Fotografia oficial do censo: lote imutável congelado após a campanha.
+// Payload de GET /v1/admin/snapshots.
+export interface CensusSnapshot {
+  id: number;
+  year: number;
+  label: string;
+  row_count: number;
+  content_hash: string;
+  created_by: string;
+  created_at: string;
+}
@@ -9,6 +9,8 @@ import { Form, FormField, FormItem, FormControl, FormLabel, FormMessage } from "
 import { NumberInput, TextInput } from "@/components/ui/form-components";
 import { Separator } from "@/components/ui/separator";
 import { useCensusPersistence } from "@/hooks/use-census-persistence";
+import { publicApiHeaders } from "@/lib/school-access";
+import { alertCensusConflict, censusWriteHeaders, rememberCensusVersion } from "@/lib/census-version";
 
 interface AlunosFormProps {
   schoolId: number;
@@ -59,7 +61,7 @@ export function AlunosForm({ schoolId, onSuccess, onBack }: AlunosFormProps) {
       try {
         const baseUrl = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000';
         const response = await fetch(`${baseUrl}/v1/census?school_id=${schoolId}`, {
-          headers: { "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" },
+          headers: { ...publicApiHeaders() },
         });
         
         if (response.ok) {
@@ -107,16 +109,19 @@ export function AlunosForm({ schoolId, onSuccess, onBack }: AlunosFormProps) {
     try {
       const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000'}/v1/census`, {
         method: "POST", 
-        headers: { "Content-Type": "application/json", "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" },
+        headers: { "Content-Type": "application/json", ...publicApiHeaders(), ...censusWriteHeaders(schoolId) },
         body: JSON.stringify({
             school_id: schoolId,
+            merge: true,
             year: new Date().getFullYear(),
             status: "draft", 
             data: data 
         }),
       });
 
+      if (await alertCensusConflict(response)) return;
       if (!response.ok) throw new Error("erro ao salvar");
+      rememberCensusVersion(schoolId, response);
       clearLocalDraft();
       onSuccess();
     } catch (error) {
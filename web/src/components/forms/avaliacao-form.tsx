@@ -8,6 +8,8 @@ import { Button } from "@/components/ui/button";
 import { Form } from "@/components/ui/form";
 import { RadioInput } from "@/components/ui/form-components";
 import { useCensusPersistence } from "@/hooks/use-census-persistence";
+import { publicApiHeaders } from "@/lib/school-access";
+import { alertCensusConflict, censusWriteHeaders, rememberCensusVersion } from "@/lib/census-version";
 
 const OPCOES = ["Ruim", "Regular", "Bom", "Excelente", "Não se aplica"];
 
@@ -51,16 +53,19 @@ export function AvaliacaoForm({ schoolId, onSuccess, onBack }: AvaliacaoFormProp
       const baseUrl = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000';
       const response = await fetch(`${baseUrl}/v1/census`, {
         method: "POST",
-        headers: { "Content-Type": "application/json", "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" },
+        headers: { "Content-Type": "application/json", ...publicApiHeaders(), ...censusWriteHeaders(schoolId) },
         body: JSON.stringify({
             school_id: schoolId,
+            merge: true,
             year: new Date().getFullYear(),
             status: "draft", 
             data: data 
         }),
       });
 
+      if (await alertCensusConflict(response)) return;
       if (!response.ok) throw new Error("erro ao salvar");
+      rememberCensusVersion(schoolId, response);
       clearLocalDraft();
       onSuccess();
     } catch (error) {
@@ -0,0 +1,61 @@
+"use client";
+
+import { useEffect, useState } from "react";
+import { CalendarClock } from "lucide-react";
+import { publicApiHeaders } from "@/lib/school-access";
+
+interface CampaignInfo {
+  year: number;
+  enforced: boolean;
+  opens_on?: string;
+  situacao: "nao_configurada" | "agendada" | "aberta" | "encerrada";
+  prazo?: string;
+  dias_restantes?: number;
+}
+
+function formatDate(iso: string): string {
+  const [y, m, d] = iso.split("-");
+  return `${d}/${m}/${y}`;
+}
+
+// Prazo da campanha do censo para a DRE da escola (GET /v1/census/campaign).
+// Fora da janela, o backend recusa as gravações; o aviso antecipa isso.
+export function CampaignNotice({ schoolId }: { schoolId: number }) {
+  const [info, setInfo] = useState<CampaignInfo | null>(null);
+
+  useEffect(() => {
+    const baseUrl = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8000";
+    fetch(`${baseUrl}/v1/census/campaign?school_id=${schoolId}`, { cache: "no-store", headers: { ...publicApiHeaders() } })
+      .then((res) => (res.ok ? res.json() : null))
+      .then((json) => setInfo(json?.data ?? null))
+      .catch(() => setInfo(null));
+  }, [schoolId]);
+
+  if (!info || !info.enforced) return null;
+
+  let text: string;
+  let tone = "border-blue-200 bg-blue-50 text-blue-800";
+  if (info.situacao === "nao_configurada") {
+    text = `Não há campanha do censo aberta para ${info.year}. As respostas não podem ser gravadas.`;
+    tone = "border-rose-200 bg-rose-50 text-rose-800";
+  } else if (info.situacao === "agendada" && info.opens_on) {
+    text = `O preenchimento do censo ${info.year} abre em ${formatDate(info.opens_on)}.`;
+  } else if (info.situacao === "encerrada" && info.prazo) {
+    text = `O prazo do censo ${info.year} encerrou em ${formatDate(info.prazo)}. Procure a sua DRE.`;
+    tone = "border-rose-200 bg-rose-50 text-rose-800";
+  } else if (info.dias_restantes !== undefined && info.dias_restantes <= 7 && info.prazo) {
+    text = info.dias_restantes === 0
+      ? "Hoje é o último dia para preencher o censo."
+      : `Faltam ${info.dias_restantes} dia(s) para o fim do prazo do censo (${formatDate(info.prazo)}).`;
+    tone = "border-amber-300 bg-amber-50 text-amber-900";
+  } else {
+    return null;
+  }
+
+  return (
+    <div className={`flex items-start gap-3 rounded-md border p-4 text-sm ${tone}`}>
+      <CalendarClock size={18} className="mt-0.5 shrink-0" />
+      <p>{text}</p>
+    </div>
+  );
+}
@@ -11,6 +11,8 @@ import { SelectInput, RadioInput, NumberInput } from "@/components/ui/form-compo
 import { Separator } from "@/components/ui/separator";
 import { useCensusPersistence } from "@/hooks/use-census-persistence";
 import { Input } from "@/components/ui/input";
+import { publicApiHeaders } from "@/lib/school-access";
+import { alertCensusConflict, censusWriteHeaders, rememberCensusVersion } from "@/lib/census-version";
 
 interface GeneralDataFormProps {
   schoolId: number;
@@ -22,6 +24,7 @@ export function GeneralDataForm({ schoolId, onSuccess, onBack }: GeneralDataForm
   const [isSaving, setIsSaving] = useState(false);
   const [isUploading, setIsUploading] = useState(false);
   const [uploadMessage, setUploadMessage] = useState("");
+  const [photoCategory, setPhotoCategory] = useState("fachada");
   const [availableTurnos, setAvailableTurnos] = useState<string[]>([]);
   
   const isInternalUpdate = useRef(false);
@@ -68,7 +71,7 @@ export function GeneralDataForm({ schoolId, onSuccess, onBack }: GeneralDataForm
         const fetchSchoolData = async () => {
             try {
                 const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000'}/v1/schools?id=${schoolId}`, {
-                    headers: { "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" },
+                    headers: { ...publicApiHeaders() },
                 });
                 if (response.ok) {
                     const json = await response.json();
@@ -169,11 +172,13 @@ export function GeneralDataForm({ schoolId, onSuccess, onBack }: GeneralDataForm
         const formData = new FormData();
         formData.append("photo", file);
         formData.append("school_id", schoolId.toString());
+        formData.append("category", photoCategory);
+        formData.append("year", new Date().getFullYear().toString());
 
         try {
             const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000'}/v1/upload`, {
                 method: "POST",
-                headers: { "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" },
+                headers: { ...publicApiHeaders() },
                 body: formData,
             });
 
@@ -246,11 +251,13 @@ export function GeneralDataForm({ schoolId, onSuccess, onBack }: GeneralDataForm
     try {
       const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000'}/v1/census`, {
         method: "POST", 
-        headers: { "Content-Type": "application/json", "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" },
-        body: JSON.stringify({ school_id: schoolId, year: new Date().getFullYear(), status: "draft", data: payload }),
+        headers: { "Content-Type": "application/json", ...publicApiHeaders(), ...censusWriteHeaders(schoolId) },
+        body: JSON.stringify({ school_id: schoolId, merge: true, year: new Date().getFullYear(), status: "draft", data: payload }),
       });
 
+      if (await alertCensusConflict(response)) return;
       if (!response.ok) throw new Error("erro ao salvar");
+      rememberCensusVersion(schoolId, response);
       clearLocalDraft();
       onSuccess();
     } catch (error) {
@@ -359,7 +366,19 @@ export function GeneralDataForm({ schoolId, onSuccess, onBack }: GeneralDataForm
             
             <div className={`p-4 border border-dashed rounded-md text-center transition-colors ${situacaoEstrutura === "Não necessita de reforma." ? "border-slate-300 bg-slate-50 opacity-70" : "border-blue-300 bg-blue-50/50"}`}>
                 <p className="text-sm text-slate-700 mb-2 font-medium">Anexar Fotos para Análise</p>
-                <div className="max-w-xs mx-auto">
+                <div className="max-w-xs mx-auto space-y-2">
+                    <select
+                        value={photoCategory}
+                        onChange={(e) => setPhotoCategory(e.target.value)}
+                        disabled={isUploadDisabled}
+                        className="w-full h-9 rounded-md border border-input bg-white px-3 text-sm"
+                    >
+                        <option value="fachada">Fachada</option>
+                        <option value="cozinha">Cozinha</option>
+                        <option value="banheiros">Banheiros</option>
+                        <option value="salas">Salas de aula</option>
+                        <option value="outras">Outras</option>
+                    </select>
                     <Input 
                         type="file" 
                         accept="image/*"
@@ -374,7 +393,7 @@ export function GeneralDataForm({ schoolId, onSuccess, onBack }: GeneralDataForm
                 )}
                 {isUploading && <p className="text-xs text-blue-600 mt-2 animate-pulse">Enviando fotos...</p>}
                 {uploadMessage && <p className={`text-xs mt-2 font-bold ${uploadMessage.includes("sucesso") ? "text-green-600" : uploadMessage.includes("falha") ? "text-orange-600" : "text-red-600"}`}>{uploadMessage}</p>}
-                {!isUploadDisabled && !uploadMessage && <p className="text-xs text-slate-400 mt-2">Você pode selecionar até 10 fotos. Elas serão salvas na pasta da escola.</p>}
+                {!isUploadDisabled && !uploadMessage && <p className="text-xs text-slate-400 mt-2">Escolha a categoria e selecione até 10 fotos. A localização (GPS) das fotos é removida no envio.</p>}
             </div>
 
             <FormField control={form.control} name="data_ultima_reforma" render={({ field }) => (
@@ -9,6 +9,8 @@ import { Form } from "@/components/ui/form";
 import { RadioInput, NumberInput, SelectInput } from "@/components/ui/form-components";
 import { Separator } from "@/components/ui/separator";
 import { useCensusPersistence } from "@/hooks/use-census-persistence";
+import { publicApiHeaders } from "@/lib/school-access";
+import { alertCensusConflict, censusWriteHeaders, rememberCensusVersion } from "@/lib/census-version";
 
 interface GestaoFormProps {
   schoolId: number;
@@ -50,11 +52,13 @@ export function GestaoForm({ schoolId, onSuccess, onBack }: GestaoFormProps) {
       const baseUrl = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000';
       const response = await fetch(`${baseUrl}/v1/census`, {
         method: "POST",
-        headers: { "Content-Type": "application/json", "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" },
-        body: JSON.stringify({ school_id: schoolId, year: new Date().getFullYear(), status: "draft", data: data }),
+        headers: { "Content-Type": "application/json", ...publicApiHeaders(), ...censusWriteHeaders(schoolId) },
+        body: JSON.stringify({ school_id: schoolId, merge: true, year: new Date().getFullYear(), status: "draft", data: data }),
       });
 
+      if (await alertCensusConflict(response)) return;
       if (!response.ok) throw new Error("erro ao salvar");
+      rememberCensusVersion(schoolId, response);
       clearLocalDraft();
       onSuccess();
     } catch (error) {
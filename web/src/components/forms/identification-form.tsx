@@ -10,6 +10,7 @@ import { Input } from "@/components/ui/input";
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select";
 import { Checkbox } from "@/components/ui/checkbox";
 import { useCensusPersistence } from "@/hooks/use-census-persistence";
+import { publicApiHeaders, saveSchoolAccess } from "@/lib/school-access";
 
 interface IdentificationFormProps {
   onSuccess: (schoolId: number) => void;
@@ -43,7 +44,7 @@ export function IdentificationForm({ onSuccess, initialId }: IdentificationFormP
         const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000'}/v1/locations`, {
           headers: {
             "Content-Type": "application/json",
-            "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || ""
+            ...publicApiHeaders()
           }
         });
         if (response.ok) {
@@ -104,13 +105,15 @@ export function IdentificationForm({ onSuccess, initialId }: IdentificationFormP
         method: "POST",
         headers: { 
           "Content-Type": "application/json",
-          "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || ""
+          ...publicApiHeaders()
         },
         body: JSON.stringify(data),
       });
 
       if (!response.ok) throw new Error(await response.text());
       const result = await response.json();
+      // Escola nova: o backend emite o código de acesso dela.
+      if (result.data.access_code) saveSchoolAccess(result.data.access_code);
       
       clearLocalDraft();
       onSuccess(result.data.id);
@@ -15,6 +15,8 @@ import {
 } from "@/components/ui/form-components";
 import { Textarea } from "@/components/ui/textarea";
 import { useCensusPersistence } from "@/hooks/use-census-persistence";
+import { publicApiHeaders } from "@/lib/school-access";
+import { alertCensusConflict, censusWriteHeaders, rememberCensusVersion } from "@/lib/census-version";
 
 interface ObservacoesFormProps {
   schoolId: number;
@@ -72,16 +74,26 @@ export function ObservacoesForm({ schoolId, onSuccess, onBack }: ObservacoesForm
       const baseUrl = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000';
       const response = await fetch(`${baseUrl}/v1/census`, {
         method: "POST",
-        headers: { "Content-Type": "application/json", "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" },
+        headers: { "Content-Type": "application/json", ...publicApiHeaders(), ...censusWriteHeaders(schoolId) },
         body: JSON.stringify({
             school_id: schoolId,
+            merge: true,
             year: new Date().getFullYear(),
             status: "completed", 
             data: data 
         }),
       });
 
+      if (response.status === 422) {
+        // Campos pendentes de outras etapas (validação do servidor).
+        const json = await response.json().catch(() => null);
+        const fields = (json?.errors || []).map((e: { field: string; message: string }) => `- ${e.field}: ${e.message}`);
+        alert(`Não foi possível finalizar: revise os campos abaixo.\n${fields.slice(0, 15).join("\n")}`);
+        return;
+      }
+      if (await alertCensusConflict(response)) return;
       if (!response.ok) throw new Error("erro ao salvar");
+      rememberCensusVersion(schoolId, response);
       clearLocalDraft();
       onSuccess();
     } catch (error) {
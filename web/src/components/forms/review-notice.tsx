@@ -0,0 +1,55 @@
+"use client";
+
+import { useEffect, useState } from "react";
+import { AlertTriangle, Lock } from "lucide-react";
+import { publicApiHeaders } from "@/lib/school-access";
+
+interface ReviewInfo {
+  status: string;
+  returns: { comment: string; created_at: string }[];
+}
+
+// Aviso da revisão da DRE no formulário: comentários de devolução para o
+// diretor corrigir, ou censo travado quando em revisão/aprovado
+// (GET /v1/census/review).
+export function ReviewNotice({ schoolId }: { schoolId: number }) {
+  const [info, setInfo] = useState<ReviewInfo | null>(null);
+
+  useEffect(() => {
+    const baseUrl = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8000";
+    fetch(`${baseUrl}/v1/census/review?school_id=${schoolId}`, { cache: "no-store", headers: { ...publicApiHeaders() } })
+      .then((res) => (res.ok ? res.json() : null))
+      .then((json) => setInfo(json?.data ?? null))
+      .catch(() => setInfo(null));
+  }, [schoolId]);
+
+  if (!info) return null;
+
+  if (info.status === "em_revisao" || info.status === "aprovado") {
+    return (
+      <div className="flex items-start gap-3 rounded-md border border-blue-200 bg-blue-50 p-4 text-sm text-blue-800">
+        <Lock size={18} className="mt-0.5 shrink-0" />
+        <p>
+          {info.status === "aprovado"
+            ? "O censo desta escola foi aprovado pela DRE e não pode mais ser alterado."
+            : "O censo desta escola está em revisão pela DRE. As alterações ficam bloqueadas até a conclusão da revisão."}
+        </p>
+      </div>
+    );
+  }
+
+  if (info.status !== "devolvido" || info.returns.length === 0) return null;
+  const last = info.returns[0];
+  return (
+    <div className="flex items-start gap-3 rounded-md border border-amber-300 bg-amber-50 p-4 text-sm text-amber-900">
+      <AlertTriangle size={18} className="mt-0.5 shrink-0" />
+      <div className="space-y-1">
+        <p className="font-semibold">Censo devolvido pela DRE para correção</p>
+        <p className="whitespace-pre-line">{last.comment}</p>
+        <p className="text-xs text-amber-700">
+          Devolvido em {new Date(last.created_at).toLocaleDateString("pt-BR")}. Corrija os pontos indicados e finalize o censo novamente.
+        </p>
+      </div>
+    </div>
+  );
+}
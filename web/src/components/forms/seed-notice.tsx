@@ -0,0 +1,86 @@
+"use client";
+
+import { useEffect, useState } from "react";
+import { Copy, History, Loader2 } from "lucide-react";
+import { Button } from "@/components/ui/button";
+import { publicApiHeaders } from "@/lib/school-access";
+import { rememberCensusVersion } from "@/lib/census-version";
+
+interface SeedInfo {
+  year: number;
+  from_year: number;
+  exists: boolean;
+  available: boolean;
+  fields: { field: string; step?: string }[];
+}
+
+// Pré-preenchimento a partir do censo do ano anterior (GET/POST
+// /v1/census/seed). Sem censo no ano, oferece copiar as respostas
+// estruturais; depois de copiar, lista os campos da etapa atual que ainda
+// precisam ser confirmados pelo diretor.
+export function SeedNotice({ schoolId, step }: { schoolId: number; step?: string }) {
+  const [info, setInfo] = useState<SeedInfo | null>(null);
+  const [seeding, setSeeding] = useState(false);
+  const baseUrl = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8000";
+
+  useEffect(() => {
+    fetch(`${baseUrl}/v1/census/seed?school_id=${schoolId}`, { cache: "no-store", headers: { ...publicApiHeaders() } })
+      .then((res) => (res.ok ? res.json() : null))
+      .then((json) => setInfo(json?.data ?? null))
+      .catch(() => setInfo(null));
+  }, [baseUrl, schoolId, step]);
+
+  const seed = async () => {
+    setSeeding(true);
+    try {
+      const response = await fetch(`${baseUrl}/v1/census/seed`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json", ...publicApiHeaders() },
+        body: JSON.stringify({ school_id: schoolId }),
+      });
+      const json = await response.json().catch(() => null);
+      if (!response.ok) {
+        alert(json?.message || "Não foi possível copiar o censo do ano anterior.");
+        return;
+      }
+      rememberCensusVersion(schoolId, response);
+      // Recarrega para os formulários lerem o rascunho semeado.
+      window.location.reload();
+    } finally {
+      setSeeding(false);
+    }
+  };
+
+  if (!info) return null;
+
+  if (!info.exists) {
+    if (!info.available) return null;
+    return (
+      <div className="flex flex-col gap-3 rounded-md border border-blue-200 bg-blue-50 p-4 text-sm text-blue-800 sm:flex-row sm:items-center sm:justify-between">
+        <div className="flex items-start gap-3">
+          <History size={18} className="mt-0.5 shrink-0" />
+          <p>
+            Esta escola respondeu o censo de {info.from_year}. Copie {info.fields.length} resposta(s) sobre a estrutura
+            da escola e apenas confirme o que não mudou.
+          </p>
+        </div>
+        <Button type="button" variant="outline" size="sm" onClick={seed} disabled={seeding} className="shrink-0">
+          {seeding ? <Loader2 size={16} className="animate-spin" /> : <Copy size={16} />}
+          Copiar do ano anterior
+        </Button>
+      </div>
+    );
+  }
+
+  const pending = info.fields.filter((f) => f.step === step);
+  if (pending.length === 0) return null;
+  return (
+    <div className="flex items-start gap-3 rounded-md border border-amber-300 bg-amber-50 p-4 text-sm text-amber-900">
+      <History size={18} className="mt-0.5 shrink-0" />
+      <div className="space-y-1">
+        <p className="font-semibold">Respostas copiadas do censo de {info.from_year}</p>
+        <p>Confira e salve esta etapa para confirmar: {pending.map((f) => f.field).join(", ")}.</p>
+      </div>
+    </div>
+  );
+}
@@ -1,5 +1,7 @@
 import { useEffect, useState, useRef } from "react";
 import { UseFormReset, FieldValues } from "react-hook-form";
+import { publicApiHeaders } from "@/lib/school-access";
+import { rememberCensusVersion } from "@/lib/census-version";
 
 export function useCensusPersistence<T extends FieldValues>(
   schoolId: number | null | undefined,
@@ -40,11 +42,12 @@ export function useCensusPersistence<T extends FieldValues>(
                   "Pragma": "no-cache",
                   // Envia a chave pública para passar pelo gate X-API-Key do
                   // backend quando ele estiver ativado (PUBLIC_API_KEY setado).
-                  "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || ""
+                  ...publicApiHeaders()
               }
           });
           
           if (response.ok) {
+            if (endpoint === "census") rememberCensusVersion(schoolId, response);
             const json = await response.json();
             
             if (json.data) {
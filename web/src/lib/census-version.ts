@@ -0,0 +1,47 @@
+// Versão do censo para a concorrência otimista.
+// GET e POST /v1/census devolvem a versão no header ETag; as gravações a
+// reenviam em If-Match. Se outra aba ou dispositivo gravou um valor
+// diferente no mesmo campo nesse meio-tempo, o backend responde 409 com os
+// campos em conflito em vez de sobrescrever. A versão fica no
+// sessionStorage: cada aba tem a sua.
+
+function storageKey(schoolId: number): string {
+  return `census_version_${schoolId}`;
+}
+
+export function rememberCensusVersion(schoolId: number | null | undefined, response: Response): void {
+  if (!schoolId || typeof window === "undefined") return;
+  const etag = response.headers.get("ETag");
+  if (!etag) return;
+  try { sessionStorage.setItem(storageKey(schoolId), etag); } catch {}
+}
+
+// Headers de uma gravação no censo: If-Match com a última versão vista.
+export function censusWriteHeaders(schoolId: number | null | undefined): Record<string, string> {
+  if (!schoolId || typeof window === "undefined") return {};
+  try {
+    const etag = sessionStorage.getItem(storageKey(schoolId));
+    return etag ? { "If-Match": etag } : {};
+  } catch {
+    return {};
+  }
+}
+
+// Trata o 409: conflito de versão (avisa quais campos foram alterados em
+// outro lugar) ou censo travado pela revisão da DRE. Devolve true quando a
+// resposta era um 409 (e já foi tratada).
+export async function alertCensusConflict(response: Response): Promise<boolean> {
+  if (response.status !== 409) return false;
+  const json = await response.json().catch(() => null);
+  const fields = (json?.errors || []).map((e: { field: string }) => `- ${e.field}`);
+  if (!fields.length && json?.message) {
+    alert(json.message);
+    return true;
+  }
+  alert(
+    "O censo foi alterado em outra aba ou dispositivo enquanto você preenchia." +
+      (fields.length ? `\nCampos em conflito:\n${fields.slice(0, 15).join("\n")}` : "") +
+      "\nRecarregue a página para ver os valores atuais antes de salvar."
+  );
+  return true;
+}
@@ -0,0 +1,40 @@
+// Código de acesso da escola para o formulário público.
+// O diretor recebe um link mágico (…/?acesso=XXXX-XXXX-XXXX) emitido pelo
+// painel. O código é guardado no navegador e enviado no header
+// X-School-Access em todas as chamadas públicas — o backend só aceita
+// escritas da escola vinculada a ele.
+
+const STORAGE_KEY_SCHOOL_ACCESS = "census_school_access";
+
+// Lê ?acesso= da URL, guarda o código e o remove da barra de endereço para
+// que não fique no histórico nem seja compartilhado por engano.
+export function captureSchoolAccessFromURL(): void {
+  if (typeof window === "undefined") return;
+  const url = new URL(window.location.href);
+  const code = url.searchParams.get("acesso")?.trim();
+  if (!code) return;
+  try { localStorage.setItem(STORAGE_KEY_SCHOOL_ACCESS, code); } catch {}
+  url.searchParams.delete("acesso");
+  window.history.replaceState(null, "", url.pathname + url.search + url.hash);
+}
+
+// Guarda o código devolvido pelo cadastro de uma escola nova (POST
+// /v1/schools com INEP ainda não cadastrado).
+export function saveSchoolAccess(code: string): void {
+  if (typeof window === "undefined" || !code) return;
+  try { localStorage.setItem(STORAGE_KEY_SCHOOL_ACCESS, code); } catch {}
+}
+
+export function loadSchoolAccess(): string {
+  if (typeof window === "undefined") return "";
+  try { return localStorage.getItem(STORAGE_KEY_SCHOOL_ACCESS) ?? ""; } catch { return ""; }
+}
+
+// Headers comuns das chamadas públicas: chave X-API-Key (gate opcional do
+// backend, PUBLIC_API_KEY) e código de acesso da escola, quando houver.
+export function publicApiHeaders(): Record<string, string> {
+  const headers: Record<string, string> = { "X-API-Key": process.env.NEXT_PUBLIC_API_KEY || "" };
+  const access = loadSchoolAccess();
+  if (access) headers["X-School-Access"] = access;
+  return headers;
+}